@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/uncord-chat/uncord-server/internal/admin"
+	"github.com/uncord-chat/uncord-server/internal/config"
+	"github.com/uncord-chat/uncord-server/internal/member"
+	"github.com/uncord-chat/uncord-server/internal/permission"
+	"github.com/uncord-chat/uncord-server/internal/postgres"
+	servercfg "github.com/uncord-chat/uncord-server/internal/server"
+	"github.com/uncord-chat/uncord-server/internal/user"
+	"github.com/uncord-chat/uncord-server/internal/valkey"
+)
+
+// command is an operator subcommand that runs in place of the server and exits.
+type command struct {
+	name    string
+	aliases []string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the operator subcommands in the order they are shown by "uncord help".
+var commands = []command{
+	{name: "migrate", usage: "migrate up|down|status",
+		summary: "apply pending migrations, roll back the latest one, or list their state", run: runMigrate},
+	{name: "create-user", usage: "create-user --email EMAIL --username NAME [--verified] [--member]",
+		summary: "create an account; the password is read from standard input", run: runCreateUser},
+	{name: "reset-password", usage: "reset-password USER",
+		summary: "set a new password, read from standard input, and sign the user out everywhere", run: runResetPassword},
+	{name: "transfer-ownership", aliases: []string{"grant-owner"}, usage: "transfer-ownership USER",
+		summary: "make an active member the server owner", run: runTransferOwnership},
+	{name: "reset-mfa", usage: "reset-mfa USER",
+		summary: "disable MFA for a user who lost their authenticator and sign them out everywhere", run: runResetMFA},
+	{name: "purge-user", usage: "purge-user --yes USER",
+		summary: "delete an account and record deletion tombstones", run: runPurgeUser},
+	{name: "rebuild-permission-cache", usage: "rebuild-permission-cache",
+		summary: "make every running server drop its cached permissions", run: runRebuildPermissionCache},
+	{name: "export", usage: "export [--credentials] FILE",
+		summary: "write a backup archive of the community; credentials are left out unless asked for", run: runExport},
+	{name: "import", usage: "import FILE",
+		summary: "restore a backup archive into an instance that holds nothing but the first-run seed", run: runImport},
+	{name: "reindex-search", aliases: []string{"reindex"}, usage: "reindex-search [--fresh] [--batch-size N]",
+		summary: "rebuild the Typesense search index in the foreground", run: runReindex},
+}
+
+// lookupCommand returns the subcommand with the given name or alias.
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+		for _, alias := range c.aliases {
+			if alias == name {
+				return c, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+// printUsage writes the list of subcommands. USER is a user ID or email address.
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: uncord [command]")
+	_, _ = fmt.Fprintln(w, "\nWithout a command, uncord runs the server. Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", c.usage, c.summary)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(w, "\nUSER is a user ID or email address. Commands read the same environment as the server.")
+}
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: uncord migrate up|down|status")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dsn := cfg.DatabaseURL.Expose()
+	switch args[0] {
+	case "up":
+		return postgres.Migrate(dsn, log.Logger)
+	case "down":
+		version, err := postgres.MigrateDown(ctx, dsn)
+		if err != nil {
+			return err
+		}
+		if version == 0 {
+			log.Info().Msg("No migrations to roll back")
+		} else {
+			log.Info().Int64("version", version).Msg("Rolled back migration")
+		}
+		return nil
+	case "status":
+		states, err := postgres.MigrationStatus(ctx, dsn)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "VERSION\tMIGRATION\tAPPLIED AT")
+		for _, s := range states {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.UTC().Format("2006-01-02 15:04:05 MST")
+			}
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown migrate action %q; want up, down, or status", args[0])
+	}
+}
+
+// runCreateUser implements the "create-user" subcommand.
+func runCreateUser(args []string) error {
+	fs := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	email := fs.String("email", "", "email address of the new account")
+	username := fs.String("username", "", "username of the new account")
+	verified := fs.Bool("verified", false, "mark the email address as verified")
+	asMember := fs.Bool("member", false, "add the user as an active member, skipping onboarding")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *username == "" || fs.NArg() != 0 {
+		return errors.New("usage: uncord create-user --email EMAIL --username NAME [--verified] [--member]")
+	}
+
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		return err
+	}
+	return withAdmin(func(ctx context.Context, svc *admin.Service) error {
+		id, err := svc.CreateUser(ctx, admin.CreateUserParams{
+			Email: *email, Username: *username, Password: password, Verified: *verified, Member: *asMember,
+		})
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Println(id)
+		return nil
+	})
+}
+
+// runResetPassword implements the "reset-password" subcommand.
+func runResetPassword(args []string) error {
+	ref, err := userArg("reset-password", args)
+	if err != nil {
+		return err
+	}
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		return err
+	}
+	return withAdmin(func(ctx context.Context, svc *admin.Service) error {
+		u, err := svc.ResolveUser(ctx, ref)
+		if err != nil {
+			return err
+		}
+		return svc.ResetPassword(ctx, u.ID, password)
+	})
+}
+
+// runTransferOwnership implements the "transfer-ownership" subcommand.
+func runTransferOwnership(args []string) error {
+	ref, err := userArg("transfer-ownership", args)
+	if err != nil {
+		return err
+	}
+	return withAdmin(func(ctx context.Context, svc *admin.Service) error {
+		u, err := svc.ResolveUser(ctx, ref)
+		if err != nil {
+			return err
+		}
+		_, err = svc.TransferOwnership(ctx, u.ID)
+		return err
+	})
+}
+
+// runResetMFA implements the "reset-mfa" subcommand.
+func runResetMFA(args []string) error {
+	ref, err := userArg("reset-mfa", args)
+	if err != nil {
+		return err
+	}
+	return withAdmin(func(ctx context.Context, svc *admin.Service) error {
+		u, err := svc.ResolveUser(ctx, ref)
+		if err != nil {
+			return err
+		}
+		return svc.ResetMFA(ctx, u.ID)
+	})
+}
+
+// runPurgeUser implements the "purge-user" subcommand. Purging cannot be undone, so --yes is required.
+func runPurgeUser(args []string) error {
+	fs := flag.NewFlagSet("purge-user", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm that the account should be permanently deleted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: uncord purge-user --yes USER")
+	}
+	if !*yes {
+		return errors.New("purging deletes the account permanently; pass --yes to confirm")
+	}
+	ref := fs.Arg(0)
+	return withAdmin(func(ctx context.Context, svc *admin.Service) error {
+		u, err := svc.ResolveUser(ctx, ref)
+		if err != nil {
+			return err
+		}
+		return svc.PurgeUser(ctx, u.ID)
+	})
+}
+
+// runRebuildPermissionCache implements the "rebuild-permission-cache" subcommand.
+func runRebuildPermissionCache(args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: uncord rebuild-permission-cache")
+	}
+	return withAdmin(func(ctx context.Context, svc *admin.Service) error {
+		return svc.RebuildPermissionCache(ctx)
+	})
+}
+
+// userArg returns the single USER argument of a subcommand.
+func userArg(name string, args []string) (string, error) {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		return "", fmt.Errorf("usage: uncord %s USER", name)
+	}
+	return args[0], nil
+}
+
+// readPassword reads a password from the first line of r. Reading it from standard input keeps it out of the process
+// list and shell history.
+func readPassword(r io.Reader) (string, error) {
+	if f, ok := r.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			_, _ = fmt.Fprint(os.Stderr, "Password: ")
+		}
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password given on standard input")
+	}
+	return password, nil
+}
+
+// withAdmin loads the configuration, connects to PostgreSQL and Valkey, and calls fn with an admin service.
+func withAdmin(fn func(ctx context.Context, svc *admin.Service) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgres.Connect(ctx, cfg.DatabaseURL.Expose(), cfg.DatabaseMaxConn, cfg.DatabaseMinConn)
+	if err != nil {
+		return fmt.Errorf("connect postgres: %w", err)
+	}
+	defer db.Close()
+
+	rdb, err := valkey.Connect(ctx, cfg.ValkeyURL.Expose(), cfg.ValkeyDialTimeout)
+	if err != nil {
+		return fmt.Errorf("connect valkey: %w", err)
+	}
+	defer func() { _ = rdb.Close() }()
+
+	svc := admin.NewService(user.NewPGRepository(db), member.NewPGRepository(db), servercfg.NewPGRepository(db), rdb,
+		permission.NewPublisher(rdb), cfg, log.Logger)
+	return fn(ctx, svc)
+}
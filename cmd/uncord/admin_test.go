@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLookupCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"migrate", "migrate", true},
+		{"grant-owner", "transfer-ownership", true},
+		{"reindex", "reindex-search", true},
+		{"serve", "", false},
+	}
+	for _, tt := range tests {
+		cmd, ok := lookupCommand(tt.name)
+		if ok != tt.wantOK || cmd.name != tt.want {
+			t.Errorf("lookupCommand(%q) = %q, %v; want %q, %v", tt.name, cmd.name, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPrintUsageListsEveryCommand(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	printUsage(&buf)
+	for _, c := range commands {
+		if !strings.Contains(buf.String(), c.usage) {
+			t.Errorf("usage does not list %q", c.name)
+		}
+	}
+}
+
+func TestReadPassword(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"hunter2hunter2\n", "hunter2hunter2", false},
+		{"windows line\r\nignored", "windows line", false},
+		{"no newline", "no newline", false},
+		{"\n", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := readPassword(strings.NewReader(tt.input))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("readPassword(%q) = %q, %v; want %q, wantErr %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestUserArg(t *testing.T) {
+	t.Parallel()
+
+	if got, err := userArg("reset-mfa", []string{"user@example.com"}); err != nil || got != "user@example.com" {
+		t.Errorf("userArg() = %q, %v", got, err)
+	}
+	for _, args := range [][]string{nil, {"a", "b"}, {"--yes"}} {
+		if _, err := userArg("reset-mfa", args); err == nil {
+			t.Errorf("userArg(%v) expected a usage error", args)
+		}
+	}
+}
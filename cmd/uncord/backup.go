@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/uncord-chat/uncord-server/internal/backup"
+	"github.com/uncord-chat/uncord-server/internal/config"
+	"github.com/uncord-chat/uncord-server/internal/media"
+	"github.com/uncord-chat/uncord-server/internal/permission"
+	"github.com/uncord-chat/uncord-server/internal/postgres"
+	"github.com/uncord-chat/uncord-server/internal/valkey"
+)
+
+// runExport implements the "export" subcommand. The archive is written to a temporary file next to FILE and renamed
+// into place once complete, so an interrupted export never leaves a truncated archive under the requested name.
+func runExport(args []string) (err error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	credentials := fs.Bool("credentials", false,
+		"include password hashes, MFA secrets, recovery codes, email addresses, and phone numbers")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: uncord export [--credentials] FILE")
+	}
+	path := fs.Arg(0)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgres.Connect(ctx, cfg.DatabaseURL.Expose(), cfg.DatabaseMaxConn, cfg.DatabaseMinConn)
+	if err != nil {
+		return fmt.Errorf("connect postgres: %w", err)
+	}
+	defer db.Close()
+
+	storage, closeStorage, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".uncord-export-*.zip")
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	manifest, err := backup.NewExporter(db, storage, log.Logger).Export(ctx, tmp,
+		backup.ExportOptions{Credentials: *credentials})
+	if err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("move archive into place: %w", err)
+	}
+
+	log.Info().Str("path", path).Int("tables", len(manifest.Tables)).Int64("files", manifest.Blobs.Count).
+		Int64("bytes", manifest.Blobs.Bytes).Bool("credentials", manifest.Credentials).Msg("Server exported")
+	if len(manifest.Blobs.Missing) > 0 {
+		log.Warn().Strs("keys", manifest.Blobs.Missing).Msg("Some stored files were missing and are not in the archive")
+	}
+	return nil
+}
+
+// runImport implements the "import" subcommand. It migrates the database, restores the archive, and invalidates the
+// permission caches of any server that ran against the instance before.
+func runImport(args []string) error {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		return errors.New("usage: uncord import FILE")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat archive: %w", err)
+	}
+
+	if err := postgres.Migrate(cfg.DatabaseURL.Expose(), log.Logger); err != nil {
+		return fmt.Errorf("run migrations: %w", err)
+	}
+	db, err := postgres.Connect(ctx, cfg.DatabaseURL.Expose(), cfg.DatabaseMaxConn, cfg.DatabaseMinConn)
+	if err != nil {
+		return fmt.Errorf("connect postgres: %w", err)
+	}
+	defer db.Close()
+
+	rdb, err := valkey.Connect(ctx, cfg.ValkeyURL.Expose(), cfg.ValkeyDialTimeout)
+	if err != nil {
+		return fmt.Errorf("connect valkey: %w", err)
+	}
+	defer func() { _ = rdb.Close() }()
+
+	storage, closeStorage, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	manifest, err := backup.NewImporter(db, storage, cfg, log.Logger).Import(ctx, f, info.Size())
+	if err != nil {
+		return err
+	}
+	if err := permission.NewPublisher(rdb).InvalidateAll(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to invalidate permission caches; restart running servers")
+	}
+
+	log.Info().Str("server", manifest.ServerName).Time("exported_at", manifest.CreatedAt).
+		Int("tables", len(manifest.Tables)).Int64("files", manifest.Blobs.Count).Msg("Server imported")
+	if manifest.Credentials {
+		log.Info().Msg("MFA secrets only decrypt if MFA_ENCRYPTION_KEY matches the exporting server")
+	} else {
+		log.Warn().Msg("The archive has no credentials or email addresses; set passwords with " +
+			"\"uncord reset-password\" before users can sign in as <user id>@redacted.invalid and set their address")
+	}
+	if cfg.TypesenseEnabled() {
+		log.Info().Msg("Run \"uncord reindex-search\" to make the imported messages searchable")
+	}
+	return nil
+}
+
+// openStorage opens the configured storage backend for operator commands. They only read and write files, so URL
+// signing is not configured. The returned function releases the backend.
+func openStorage(cfg *config.Config) (media.StorageProvider, func(), error) {
+	switch cfg.StorageBackend {
+	case "local":
+		localStorage, err := media.NewLocalStorage(cfg.StorageLocalPath, cfg.ServerURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("initialise local storage: %w", err)
+		}
+		return localStorage, func() { _ = localStorage.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported storage backend: %q", cfg.StorageBackend)
+	}
+}
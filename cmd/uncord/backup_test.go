@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBackupCommandsRejectBadArguments(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		run  func([]string) error
+		args []string
+	}{
+		{"export without file", runExport, nil},
+		{"export with two files", runExport, []string{"a.zip", "b.zip"}},
+		{"import without file", runImport, nil},
+		{"import with flag", runImport, []string{"--yes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.run(tt.args)
+			if err == nil || !strings.HasPrefix(err.Error(), "usage: uncord ") {
+				t.Errorf("error = %v, want a usage error", err)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"html/template"
@@ -30,6 +32,7 @@ import (
 
 	apierrors "github.com/uncord-chat/uncord-protocol/errors"
 
+	"github.com/uncord-chat/uncord-server/internal/api"
 	"github.com/uncord-chat/uncord-server/internal/attachment"
 	"github.com/uncord-chat/uncord-server/internal/audit"
 	"github.com/uncord-chat/uncord-server/internal/auth"
@@ -43,21 +46,26 @@ import (
 	"github.com/uncord-chat/uncord-server/internal/email"
 	"github.com/uncord-chat/uncord-server/internal/emoji"
 	"github.com/uncord-chat/uncord-server/internal/gateway"
+	"github.com/uncord-chat/uncord-server/internal/health"
 	"github.com/uncord-chat/uncord-server/internal/httputil"
 	"github.com/uncord-chat/uncord-server/internal/invite"
 	"github.com/uncord-chat/uncord-server/internal/media"
 	"github.com/uncord-chat/uncord-server/internal/member"
 	"github.com/uncord-chat/uncord-server/internal/message"
+	"github.com/uncord-chat/uncord-server/internal/metrics"
 	"github.com/uncord-chat/uncord-server/internal/onboarding"
+	"github.com/uncord-chat/uncord-server/internal/outbox"
 	"github.com/uncord-chat/uncord-server/internal/permission"
 	"github.com/uncord-chat/uncord-server/internal/postgres"
 	"github.com/uncord-chat/uncord-server/internal/presence"
 	"github.com/uncord-chat/uncord-server/internal/reaction"
 	"github.com/uncord-chat/uncord-server/internal/readstate"
+	"github.com/uncord-chat/uncord-server/internal/reindex"
 	"github.com/uncord-chat/uncord-server/internal/role"
 	servercfg "github.com/uncord-chat/uncord-server/internal/server"
 	"github.com/uncord-chat/uncord-server/internal/settingsync"
 	"github.com/uncord-chat/uncord-server/internal/thread"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
 	"github.com/uncord-chat/uncord-server/internal/typesense"
 	"github.com/uncord-chat/uncord-server/internal/user"
 	"github.com/uncord-chat/uncord-server/internal/valkey"
@@ -70,6 +78,19 @@ var (
 	date    = "unknown"
 )
 
+const (
+	// healthCheckTimeout bounds a whole readiness check run. Probes still running when it expires report unavailable.
+	healthCheckTimeout = 3 * time.Second
+
+	// smtpHealthCacheFor is how long an SMTP probe result is reused, so that frequent readiness polling does not open a
+	// new mail server connection every time.
+	smtpHealthCacheFor = 30 * time.Second
+
+	// storageHealthCacheFor is how long a storage probe result is reused. Each probe writes, reads, and deletes a file,
+	// which unauthenticated readiness polling must not be able to trigger on every request.
+	storageHealthCacheFor = 30 * time.Second
+)
+
 // server holds the shared dependencies used by route handlers and middleware. All fields are injected during startup in
 // run() and remain read-only for the lifetime of the process.
 type server struct {
@@ -95,22 +116,44 @@ type server struct {
 	dmRepo           dm.Repository
 	e2eeRepo         e2ee.Repository
 	storage          media.StorageProvider
+	scanner          media.Scanner
 	permStore        *permission.PGStore
 	permResolver     *permission.Resolver
 	permPublisher    *permission.Publisher
-	typesenseIndexer *typesense.Indexer
+	searchOutbox     outbox.Repository
+	outboxWorker     *outbox.Worker
+	reindexer        *reindex.Runner
 	gatewayPublisher *gateway.Publisher
 	gatewayHub       *gateway.Hub
 	presenceStore    *presence.Store
 	settingSyncRepo  settingsync.Repository
 	auditRepo        audit.Repository
 	auditLogger      *audit.Logger
+	metrics          *metrics.Registry
+	readiness        *health.Checker
 	verifyPageTmpl   *template.Template
 }
 
 func main() {
 	log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
 
+	if len(os.Args) > 1 {
+		switch name := os.Args[1]; name {
+		case "help", "-h", "-help", "--help":
+			printUsage(os.Stdout)
+		default:
+			cmd, ok := lookupCommand(name)
+			if !ok {
+				printUsage(os.Stderr)
+				os.Exit(2)
+			}
+			if err := cmd.run(os.Args[2:]); err != nil {
+				log.Fatal().Err(err).Str("command", cmd.name).Msg("Command failed")
+			}
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		log.Fatal().Err(err).Msg("Server stopped")
 	}
@@ -126,6 +169,7 @@ func run() error {
 		log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).
 			With().Timestamp().Logger()
 	}
+	log.Logger = log.Logger.Hook(tracing.LogHook{})
 
 	log.Info().
 		Str("version", version).
@@ -140,6 +184,28 @@ func run() error {
 
 	ctx := context.Background()
 
+	// Install the OpenTelemetry tracer provider before any instrumented client is created. Spans still buffered when
+	// run() returns are flushed by the deferred shutdown, after the database and Valkey clients have been closed.
+	if cfg.TracingEnabled {
+		shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+			Endpoint:       cfg.TracingEndpoint,
+			SampleRatio:    cfg.TracingSampleRatio,
+			ServiceName:    cfg.TracingServiceName,
+			ServiceVersion: version,
+			Environment:    cfg.ServerEnv,
+		})
+		if err != nil {
+			return fmt.Errorf("set up tracing: %w", err)
+		}
+		defer func() {
+			flushCtx, flushCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+			defer flushCancel()
+			if err := shutdownTracing(flushCtx); err != nil {
+				log.Warn().Err(err).Msg("Failed to flush traces")
+			}
+		}()
+	}
+
 	// Connect PostgreSQL
 	db, err := postgres.Connect(ctx, cfg.DatabaseURL.Expose(), cfg.DatabaseMaxConn, cfg.DatabaseMinConn)
 	if err != nil {
@@ -177,20 +243,27 @@ func run() error {
 
 	// Typesense collection (best-effort). Search is non-essential; the server runs without it but message search will
 	// be unavailable. The searchAvailable flag is logged in the startup summary so operators immediately see whether
-	// search is degraded.
-	var searchAvailable bool
-	result, err := typesense.EnsureMessagesCollection(ctx, cfg.TypesenseURL, cfg.TypesenseAPIKey.Expose(), cfg.TypesenseTimeout)
-	if err != nil {
-		log.Warn().Err(err).Msg("Typesense collection setup failed; message search will be unavailable")
-	} else {
-		searchAvailable = true
-		switch result {
-		case typesense.ResultCreated:
-			log.Info().Msg("Typesense messages collection created")
-		case typesense.ResultRecreated:
-			log.Warn().Msg("Typesense messages collection recreated due to schema change")
-		case typesense.ResultUnchanged:
-			log.Info().Msg("Typesense messages collection already exists")
+	// search is degraded. A new or outdated collection is repopulated by a background reindex started further below.
+	// The PostgreSQL backend searches the messages table directly and needs no setup.
+	searchAvailable := !cfg.TypesenseEnabled()
+	var needsReindex bool
+	if cfg.TypesenseEnabled() {
+		result, err := typesense.EnsureMessagesCollection(ctx, cfg.TypesenseURL, cfg.TypesenseAPIKey.Expose(),
+			cfg.TypesenseTimeout)
+		if err != nil {
+			log.Warn().Err(err).Msg("Typesense collection setup failed; message search will be unavailable")
+		} else {
+			searchAvailable = true
+			switch result {
+			case typesense.ResultCreated:
+				log.Info().Msg("Typesense messages collection created")
+				needsReindex = !firstRun
+			case typesense.ResultOutdated:
+				log.Warn().Msg("Typesense messages collection is outdated; rebuilding it in the background")
+				needsReindex = true
+			case typesense.ResultUnchanged:
+				log.Info().Msg("Typesense messages collection already exists")
+			}
 		}
 	}
 
@@ -223,10 +296,10 @@ func run() error {
 	})
 
 	// The purge goroutine is started below after the attachment repository is initialised, because orphan attachment
-	// cleanup needs access to the repo and storage provider.
-	startPurgeGoroutine := func(attachRepo *attachment.PGRepository, storage media.StorageProvider) {
+	// cleanup needs access to the repo and storage provider, and E2EE key maintenance needs the gateway publisher.
+	startPurgeGoroutine := func(attachRepo *attachment.PGRepository, storage media.StorageProvider, keys *e2ee.Maintainer) {
 		safeGo(&wg, func() {
-			purgeExpiredData(subCtx, userRepo, attachRepo, storage, cfg)
+			purgeExpiredData(subCtx, userRepo, attachRepo, storage, keys, cfg)
 
 			ticker := time.NewTicker(cfg.DataCleanupInterval)
 			defer ticker.Stop()
@@ -235,7 +308,7 @@ func run() error {
 				case <-subCtx.Done():
 					return
 				case <-ticker.C:
-					purgeExpiredData(subCtx, userRepo, attachRepo, storage, cfg)
+					purgeExpiredData(subCtx, userRepo, attachRepo, storage, keys, cfg)
 				}
 			}
 		})
@@ -267,8 +340,9 @@ func run() error {
 
 	// SMTP client for transactional email (verification, password reset, etc.)
 	var emailSender auth.Sender
+	var emailClient *email.Client
 	if cfg.SMTPConfigured() {
-		emailClient := email.NewClient(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword.Expose(), cfg.SMTPFrom, verificationTmpl)
+		emailClient = email.NewClient(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword.Expose(), cfg.SMTPFrom, verificationTmpl)
 		if err := emailClient.Ping(ctx); err != nil {
 			log.Warn().Err(err).Msg("SMTP connection test failed. Verification emails may not be delivered.")
 		} else {
@@ -291,12 +365,33 @@ func run() error {
 			return fmt.Errorf("initialise local storage: %w", storageErr)
 		}
 		defer func() { _ = localStorage.Close() }()
+		if cfg.MediaSignedURLs {
+			secret, decodeErr := hex.DecodeString(cfg.ServerSecret.Expose())
+			if decodeErr != nil {
+				return fmt.Errorf("decode server secret: %w", decodeErr)
+			}
+			localStorage.SignURLs(media.NewURLSigner(secret, cfg.MediaURLTTL))
+		}
 		storage = localStorage
 		log.Info().Str("path", cfg.StorageLocalPath).Msg("Local file storage initialised")
 	default:
 		return fmt.Errorf("unsupported storage backend: %q", cfg.StorageBackend)
 	}
 
+	// Initialise the upload scanner. Uploads fail closed when clamd is unreachable, so an unhealthy daemon at startup is
+	// logged loudly but does not prevent the server from starting.
+	var scanner media.Scanner = media.NoopScanner{}
+	if cfg.ScanningEnabled() {
+		clamav := media.NewClamAVScanner(cfg.ClamAVAddress, cfg.ClamAVTimeout)
+		if pingErr := clamav.Ping(ctx); pingErr != nil {
+			log.Warn().Err(pingErr).Str("address", cfg.ClamAVAddress).
+				Msg("ClamAV is unreachable; uploads will be rejected until it recovers")
+		} else {
+			log.Info().Str("address", cfg.ClamAVAddress).Msg("ClamAV scanner connected")
+		}
+		scanner = clamav
+	}
+
 	// Initialise remaining repositories and services
 	serverRepo := servercfg.NewPGRepository(db)
 	channelRepo := channel.NewPGRepository(db)
@@ -308,21 +403,45 @@ func run() error {
 	messageRepo := message.NewPGRepository(db)
 	threadRepo := thread.NewPGRepository(db)
 	attachmentRepo := attachment.NewPGRepository(db)
+	if !cfg.TypesenseEnabled() {
+		// The PostgreSQL search backend reads the messages table directly, so there is no index to keep in sync.
+		messageRepo.DisableSearchOutbox()
+		threadRepo.DisableSearchOutbox()
+		attachmentRepo.DisableSearchOutbox()
+	}
 	emojiRepo := emoji.NewPGRepository(db)
 	reactionRepo := reaction.NewPGRepository(db)
 	readStateRepo := readstate.NewPGRepository(db)
 	dmRepo := dm.NewPGRepository(db)
 	e2eeRepo := e2ee.NewPGRepository(db, cfg.E2EEMaxDevicesPerUser)
-	typesenseIndexer := typesense.NewIndexer(cfg.TypesenseURL, cfg.TypesenseAPIKey.Expose(), cfg.TypesenseTimeout)
-	gatewayPub := gateway.NewPublisher(rdb, log.Logger, cfg.GatewayPublishWorkers, cfg.GatewayPublishQueueSize, cfg.GatewayPublishTimeout)
+	searchOutbox := outbox.NewPGRepository(db)
+	searchIndexer := typesense.NewIndexer(cfg.TypesenseURL, cfg.TypesenseAPIKey.Expose(), cfg.TypesenseTimeout)
+	outboxWorker := outbox.NewWorker(searchOutbox, messageRepo, searchIndexer,
+		cfg.SearchOutboxBatchSize, cfg.SearchOutboxMaxAttempts, cfg.SearchOutboxPollInterval, log.Logger)
+	reindexer := reindex.NewRunner(messageRepo,
+		typesense.NewCollections(cfg.TypesenseURL, cfg.TypesenseAPIKey.Expose(), cfg.TypesenseTimeout),
+		rdb, reindex.DefaultBatchSize, log.Logger)
+	gatewayPub := gateway.NewPublisher(rdb, log.Logger, cfg.GatewayPublishWorkers, cfg.GatewayPublishQueueSize,
+		cfg.GatewayPublishTimeout, gateway.NewDelivery(cfg))
 	presenceStore := presence.NewStore(rdb)
 	settingSyncRepo := settingsync.NewPGRepository(db)
 	auditRepo := audit.NewPGRepository(db)
 	auditLogger := audit.NewLogger(auditRepo, log.Logger)
-	startPurgeGoroutine(attachmentRepo, storage)
+	keyMaintainer := e2ee.NewMaintainer(e2eeRepo, dmRepo, gatewayPub, cfg.E2EESPKRotationPeriod,
+		cfg.E2EEStaleDeviceTimeout, log.Logger)
+	startPurgeGoroutine(attachmentRepo, storage, keyMaintainer)
 
-	// Start thumbnail worker with reconnection.
-	thumbWorker := media.NewThumbnailWorker(rdb, storage, attachmentRepo, log.Logger)
+	// Discover ffmpeg for video and audio processing (optional). Without it, image thumbnails still work but video
+	// poster frames, durations, waveforms, and AVIF thumbnails are skipped.
+	ffmpeg, err := media.DiscoverFFmpeg(cfg.FFmpegPath, cfg.FFprobePath)
+	if err != nil {
+		log.Warn().Err(err).Msg("ffmpeg not found; video and audio attachment processing is disabled")
+	} else {
+		log.Info().Msg("ffmpeg found; video and audio attachment processing enabled")
+	}
+
+	// Start media worker with reconnection.
+	thumbWorker := media.NewThumbnailWorker(rdb, storage, attachmentRepo, ffmpeg, log.Logger)
 	thumbWorker.EnsureStream(subCtx)
 	safeGo(&wg, func() {
 		runWithBackoff(subCtx, "thumbnail-worker", thumbWorker.Run)
@@ -330,6 +449,24 @@ func run() error {
 	safeGo(&wg, func() {
 		runWithBackoff(subCtx, "gateway-publisher", gatewayPub.Run)
 	})
+
+	// Start the search outbox worker with reconnection. Queued index updates accumulate in PostgreSQL while Typesense
+	// is unavailable and are applied once it recovers.
+	if cfg.TypesenseEnabled() {
+		safeGo(&wg, func() {
+			runWithBackoff(subCtx, "search-outbox-worker", outboxWorker.Run)
+		})
+	}
+
+	// Backfill the search index when its collection was just created or replaced. Another instance may already be
+	// rebuilding it, in which case this one leaves the work to it.
+	if needsReindex {
+		if err := reindexer.Start(reindex.Options{}); errors.Is(err, reindex.ErrAlreadyRunning) {
+			log.Info().Msg("Search reindex already running on another instance")
+		} else if err != nil {
+			log.Warn().Err(err).Msg("Failed to start search reindex")
+		}
+	}
 	authService, err := auth.NewService(userRepo, rdb, cfg, blocklist, emailSender, serverRepo, permPublisher, log.Logger)
 	if err != nil {
 		return fmt.Errorf("create auth service: %w", err)
@@ -347,6 +484,7 @@ func run() error {
 		Channels:       channelRepo,
 		Roles:          roleRepo,
 		Members:        memberRepo,
+		MemberLookup:   memberRepo,
 		ReadStates:     readStateRepo,
 		Presence:       presenceStore,
 		Publisher:      gatewayPub,
@@ -358,7 +496,45 @@ func run() error {
 		runWithBackoff(subCtx, "gateway-hub", gatewayHub.Run)
 	})
 
-	app := newFiberApp(cfg)
+	// Readiness checks. Typesense and SMTP are only checked when configured, and SMTP results are cached because each
+	// probe opens an authenticated connection to the mail server.
+	latestMigration, err := postgres.LatestMigration()
+	if err != nil {
+		return fmt.Errorf("read embedded migrations: %w", err)
+	}
+	readiness := health.New(healthCheckTimeout)
+	readiness.Add(health.Check{Name: "postgres", Critical: true, Probe: health.Ping(db)})
+	readiness.Add(health.Check{Name: "migrations", Critical: true, Probe: health.Migrations(
+		func(ctx context.Context) (int64, error) { return postgres.AppliedMigration(ctx, db) }, latestMigration)})
+	readiness.Add(health.Check{Name: "valkey", Critical: true, Probe: health.Ping(redisPinger{client: rdb})})
+	readiness.Add(health.Check{Name: "gateway_hub", Critical: true, Probe: health.GatewayHub(gatewayHub)})
+	readiness.Add(health.Check{Name: "gateway_publisher", Critical: true, Probe: health.GatewayPublisher(gatewayPub)})
+	readiness.Add(health.Check{Name: "storage", CacheFor: storageHealthCacheFor,
+		Probe: health.StorageWritable(storage)})
+	readiness.Add(health.Check{Name: "media_jobs",
+		Probe: health.Backlog(thumbWorker, cfg.HealthMediaBacklogThreshold)})
+	if cfg.TypesenseEnabled() {
+		readiness.Add(health.Check{Name: "typesense", Probe: health.Ping(searchIndexer)})
+	}
+	if emailClient != nil {
+		readiness.Add(health.Check{Name: "smtp", CacheFor: smtpHealthCacheFor, Probe: health.Ping(emailClient)})
+	}
+
+	// Prometheus metrics (optional). Component counters are read when the registry is scraped. The search outbox
+	// worker only runs with the Typesense backend.
+	var metricsRegistry *metrics.Registry
+	if cfg.MetricsEnabled {
+		metricsRegistry = metrics.New()
+		metricsRegistry.RegisterDBPool(db)
+		metricsRegistry.RegisterGateway(gatewayHub, gatewayPub, sessionStore)
+		metricsRegistry.RegisterPermissionCache(permResolver)
+		metricsRegistry.RegisterMediaJobs(thumbWorker)
+		if cfg.TypesenseEnabled() {
+			metricsRegistry.RegisterSearchOutbox(outboxWorker)
+		}
+	}
+
+	app := newFiberApp(cfg, metricsRegistry)
 
 	// Register routes
 	srv := &server{
@@ -383,21 +559,38 @@ func run() error {
 		dmRepo:           dmRepo,
 		e2eeRepo:         e2eeRepo,
 		storage:          storage,
+		scanner:          scanner,
 		authService:      authService,
 		permStore:        permStore,
 		permResolver:     permResolver,
 		permPublisher:    permPublisher,
-		typesenseIndexer: typesenseIndexer,
+		searchOutbox:     searchOutbox,
+		outboxWorker:     outboxWorker,
+		reindexer:        reindexer,
 		gatewayPublisher: gatewayPub,
 		gatewayHub:       gatewayHub,
 		presenceStore:    presenceStore,
 		settingSyncRepo:  settingSyncRepo,
 		auditRepo:        auditRepo,
 		auditLogger:      auditLogger,
+		metrics:          metricsRegistry,
+		readiness:        readiness,
 		verifyPageTmpl:   verifyPageTmpl,
 	}
 	srv.registerRoutes(app)
 
+	// With METRICS_ADDR set, metrics are served on their own listener so they can be bound to a private interface.
+	var metricsApp *fiber.App
+	if metricsRegistry != nil && cfg.MetricsAddr != "" {
+		metricsApp = serveMetrics(cfg.MetricsAddr, metricsRegistry.Handler(cfg.MetricsToken.Expose()))
+	}
+
+	// With ADMIN_ADDR set, operator endpoints that act on this node are served on their own token-protected listener.
+	var adminApp *fiber.App
+	if cfg.AdminAddr != "" {
+		adminApp = serveAdmin(cfg.AdminAddr, cfg.AdminToken.Expose(), gatewayHub)
+	}
+
 	// Graceful shutdown: the signal goroutine drains in-flight HTTP requests via app.ShutdownWithContext. A sync.Once
 	// ensures the shutdown path executes exactly once regardless of whether a signal or a Listen error triggers it
 	// first. All remaining cleanup happens sequentially in the main goroutine after Listen returns, eliminating races
@@ -413,6 +606,16 @@ func run() error {
 		if err := app.ShutdownWithContext(shutdownCtx); err != nil {
 			log.Error().Err(err).Msg("Server shutdown error")
 		}
+		if metricsApp != nil {
+			if err := metricsApp.ShutdownWithContext(shutdownCtx); err != nil {
+				log.Error().Err(err).Msg("Metrics server shutdown error")
+			}
+		}
+		if adminApp != nil {
+			if err := adminApp.ShutdownWithContext(shutdownCtx); err != nil {
+				log.Error().Err(err).Msg("Admin server shutdown error")
+			}
+		}
 	}
 
 	go func() {
@@ -421,11 +624,36 @@ func run() error {
 		shutdownOnce.Do(shutdownApp)
 	}()
 
+	// SIGUSR1 drains the gateway ahead of a deploy: new connections are refused and connected clients are moved to
+	// other nodes in batches. The server keeps running until it is stopped as usual. SIGUSR2 cancels the drain. The
+	// admin listener offers the same controls over HTTP.
+	drain := make(chan os.Signal, 1)
+	signal.Notify(drain, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(drain)
+	go func() {
+		for sig := range drain {
+			switch {
+			case sig == syscall.SIGUSR2:
+				if !gatewayHub.StopDrain() {
+					log.Info().Msg("Gateway is not draining")
+				}
+			case !gatewayHub.StartDrain():
+				log.Info().Msg("Gateway is already draining")
+			}
+		}
+	}()
+
 	// Startup summary: log the status of optional services so operators can immediately see what is degraded.
 	log.Info().
 		Bool("search", searchAvailable).
+		Str("search_backend", cfg.SearchBackend).
 		Bool("email", cfg.SMTPConfigured()).
+		Bool("ffmpeg", ffmpeg != nil).
+		Bool("scanning", cfg.ScanningEnabled()).
 		Str("storage", cfg.StorageBackend).
+		Bool("signed_media_urls", cfg.MediaSignedURLs).
+		Bool("metrics", cfg.MetricsEnabled).
+		Bool("tracing", cfg.TracingEnabled).
 		Msg("Service status")
 
 	// Listen
@@ -455,13 +683,15 @@ func run() error {
 	//
 	// 1. Shut down the gateway hub (sends Reconnect frames to connected clients).
 	// 2. Cancel the background service context so goroutines begin exiting.
-	// 3. Wait for all goroutines to stop (with a grace timeout).
-	// 4. run() returns and deferred Close calls release the database pool, Valkey client, and storage provider.
+	// 3. Wait for all goroutines to stop (with a grace timeout). A running search reindex stops after its current batch
+	//    and resumes from its checkpoint on the next start.
+	// 4. run() returns and deferred Close calls release the database pool, Valkey client, and storage provider, then
+	//    buffered trace spans are flushed.
 	gatewayHub.Shutdown()
 	subCancel()
 
 	waitDone := make(chan struct{})
-	go func() { wg.Wait(); close(waitDone) }()
+	go func() { reindexer.Stop(); wg.Wait(); close(waitDone) }()
 	select {
 	case <-waitDone:
 	case <-time.After(cfg.ShutdownGraceTimeout):
@@ -475,8 +705,9 @@ func run() error {
 	return nil
 }
 
-// newFiberApp creates the Fiber application with the global error handler and middleware stack.
-func newFiberApp(cfg *config.Config) *fiber.App {
+// newFiberApp creates the Fiber application with the global error handler and middleware stack. When m is non-nil,
+// every request is recorded in its HTTP latency histogram. With tracing enabled, every request starts a server span.
+func newFiberApp(cfg *config.Config, m *metrics.Registry) *fiber.App {
 	app := fiber.New(fiber.Config{
 		AppName:   "Uncord",
 		BodyLimit: cfg.BodyLimitBytes(),
@@ -491,7 +722,7 @@ func newFiberApp(cfg *config.Config) *fiber.App {
 				msg = e.Message
 				apiCode = fiberStatusToAPICode(e.Code)
 			} else {
-				log.Error().Err(err).
+				log.Error().Ctx(c.Context()).Err(err).
 					Str("method", c.Method()).
 					Str("path", c.Path()).
 					Msg("Unhandled error")
@@ -506,10 +737,16 @@ func newFiberApp(cfg *config.Config) *fiber.App {
 	})
 
 	app.Use(requestid.New())
+	if cfg.TracingEnabled {
+		app.Use(tracing.Middleware(cfg.TracingTrustParent))
+	}
+	if m != nil {
+		app.Use(m.Middleware())
+	}
 	if cfg.LogHealthRequests {
 		app.Use(httputil.RequestLogger(log.Logger))
 	} else {
-		app.Use(httputil.RequestLogger(log.Logger, "/api/v1/health"))
+		app.Use(httputil.RequestLogger(log.Logger, "/api/v1/health", "/api/v1/health/live", "/api/v1/health/ready"))
 	}
 	// CORS runs before the timeout so that preflight OPTIONS responses are not subject to the request deadline.
 	corsConfig := cors.Config{
@@ -562,6 +799,40 @@ func newFiberApp(cfg *config.Config) *fiber.App {
 	return app
 }
 
+// serveMetrics starts a dedicated listener on addr that serves only the metrics endpoint. The returned app is shut down
+// alongside the main server. A listen failure is logged rather than fatal because metrics are not essential.
+func serveMetrics(addr string, handler fiber.Handler) *fiber.App {
+	app := fiber.New(fiber.Config{AppName: "Uncord metrics"})
+	app.Get("/metrics", handler)
+	go func() {
+		log.Info().Str("addr", addr).Msg("Metrics listening")
+		if err := app.Listen(addr, fiber.ListenConfig{DisableStartupMessage: true}); err != nil {
+			log.Error().Err(err).Msg("Metrics listener failed")
+		}
+	}()
+	return app
+}
+
+// serveAdmin starts a dedicated listener on addr for operator endpoints that act on this node only, such as gateway
+// drain. Every request must present the admin token. The returned app is shut down alongside the main server.
+func serveAdmin(addr, token string, hub *gateway.Hub) *fiber.App {
+	app := fiber.New(fiber.Config{AppName: "Uncord admin"})
+	app.Use(api.RequireAdminToken(token))
+
+	drainHandler := api.NewGatewayDrainHandler(hub, log.Logger)
+	app.Post("/gateway/drain", drainHandler.Start)
+	app.Delete("/gateway/drain", drainHandler.Stop)
+	app.Get("/gateway/drain", drainHandler.Status)
+
+	go func() {
+		log.Info().Str("addr", addr).Msg("Admin listening")
+		if err := app.Listen(addr, fiber.ListenConfig{DisableStartupMessage: true}); err != nil {
+			log.Error().Err(err).Msg("Admin listener failed")
+		}
+	}()
+	return app
+}
+
 // loadTemplates loads optional email and page templates from the data directory. Returns nil templates when dataDir is
 // empty, which causes the application to use compiled-in defaults. Templates are trusted input from the server operator;
 // html/template auto-escapes rendered values but does not guard against arbitrary actions in the template definitions.
@@ -595,14 +866,17 @@ func loadTemplates(dataDir string) (verification *template.Template, verifyPage
 	return verification, verifyPage, nil
 }
 
-// redisPinger adapts *redis.Client to the api.Pinger interface.
+// redisPinger adapts *redis.Client to the health.Pinger interface.
 type redisPinger struct{ client *redis.Client }
 
 func (p redisPinger) Ping(ctx context.Context) error { return p.client.Ping(ctx).Err() }
 
-// purgeExpiredData deletes stale login attempts, deletion tombstones, and orphaned attachments. Each call logs the
-// outcome so operators can monitor retention enforcement.
-func purgeExpiredData(ctx context.Context, repo user.Repository, attachRepo attachment.Repository, storage media.StorageProvider, cfg *config.Config) {
+// purgeExpiredData deletes stale login attempts, deletion tombstones, and orphaned attachments, and applies the E2EE key
+// lifecycle policy. Each call logs the outcome so operators can monitor retention enforcement.
+func purgeExpiredData(ctx context.Context, repo user.Repository, attachRepo attachment.Repository, storage media.StorageProvider, keys *e2ee.Maintainer, cfg *config.Config) {
+	ctx, span := tracing.Tracer().Start(ctx, "purge expired data")
+	defer span.End()
+
 	deleted, err := repo.PurgeLoginAttempts(ctx, time.Now().Add(-cfg.LoginAttemptRetention))
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to purge expired login attempts")
@@ -620,19 +894,22 @@ func purgeExpiredData(ctx context.Context, repo user.Repository, attachRepo atta
 		}
 	}
 
-	// Purge orphaned attachments (uploaded but never linked to a message).
+	// Purge orphaned attachments (uploaded but never linked to a message) and abandoned resumable upload sessions.
 	orphanKeys, err := attachRepo.PurgeOrphans(ctx, time.Now().Add(-cfg.AttachmentOrphanTTL))
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to purge orphaned attachments")
 	} else if len(orphanKeys) > 0 {
 		for _, key := range orphanKeys {
-			if delErr := storage.Delete(ctx, key); delErr != nil {
+			if delErr := media.DeleteWithVariants(ctx, storage, key); delErr != nil {
 				log.Warn().Err(delErr).Str("key", key).Msg("Failed to delete orphaned attachment file")
 			}
 		}
 		log.Info().Int("deleted", len(orphanKeys)).Dur("ttl", cfg.AttachmentOrphanTTL).
 			Msg("Purged orphaned attachment files")
 	}
+
+	// Prompt signed pre-key rotation, remove unused devices and delete message keys nobody can decrypt with.
+	keys.Sweep(ctx)
 }
 
 // safeGo starts a goroutine via wg.Go with panic recovery. If the goroutine panics, the panic value and a stack trace
@@ -687,13 +964,13 @@ func runWithBackoff(ctx context.Context, name string, fn func(context.Context) e
 }
 
 // jsonBodyLimit returns middleware that rejects non-multipart requests whose Content-Length exceeds maxBytes. Multipart
-// requests (file uploads) are exempt because they are governed by the global Fiber body limit and per-handler size
-// validation. Requests without a Content-Length header are allowed through; Fiber's global limit still applies when
-// reading the body.
+// requests (file uploads) and raw application/octet-stream bodies (resumable upload chunks) are exempt because they are
+// governed by the global Fiber body limit and per-handler size validation. Requests without a Content-Length header are
+// allowed through; Fiber's global limit still applies when reading the body.
 func jsonBodyLimit(maxBytes int) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		ct := c.Get("Content-Type")
-		if strings.HasPrefix(ct, "multipart/") {
+		if strings.HasPrefix(ct, "multipart/") || strings.HasPrefix(ct, fiber.MIMEOctetStream) {
 			return c.Next()
 		}
 		if cl := c.Request().Header.ContentLength(); cl > 0 && cl > maxBytes {
@@ -754,13 +1031,31 @@ func fiberStatusToAPICode(status int) apierrors.Code {
 }
 
 // serveMediaFile returns a Fiber handler that serves stored files by storage key. Content type is derived from the
-// storage key extension, falling back to application/octet-stream for unrecognised extensions.
-func serveMediaFile(storage media.StorageProvider) fiber.Handler {
+// storage key extension, falling back to application/octet-stream for unrecognised extensions. When signer is non-nil,
+// private keys (attachments and their thumbnails) are only served with a valid, unexpired signature and are marked
+// privately cacheable until the signature expires. Encrypted DM attachments are never served here (see
+// media.IsRestrictedKey). Storage keys are immutable, so the ETag is derived from the key rather than the file contents.
+//
+// Resized variants are not served here because this route is unauthenticated and every variant is a full image decode;
+// requests carrying variant parameters are rejected in favour of serveMediaVariant.
+func serveMediaFile(storage media.StorageProvider, signer *media.URLSigner) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		key := c.Params("*")
-		if key == "" {
-			return fiber.ErrNotFound
+		cacheControl, err := authoriseMediaKey(c, signer, key, "public")
+		if err != nil {
+			return err
+		}
+		if c.Query("width") != "" || c.Query("height") != "" || c.Query("format") != "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Resized images are served from /api/v1/media")
 		}
+
+		etag := mediaETag(key)
+		c.Set("ETag", etag)
+		c.Set("Cache-Control", cacheControl)
+		if c.Get(fiber.HeaderIfNoneMatch) == etag {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+
 		rc, err := storage.Get(c.Context(), key)
 		if err != nil {
 			return fiber.ErrNotFound
@@ -774,7 +1069,76 @@ func serveMediaFile(storage media.StorageProvider) fiber.Handler {
 		}
 		c.Set("Content-Type", contentType)
 		c.Set("X-Content-Type-Options", "nosniff")
-		c.Set("Cache-Control", "public, max-age=31536000, immutable")
 		return c.SendStream(rc)
 	}
 }
+
+// serveMediaVariant returns a Fiber handler that serves a resized or transcoded rendition of the image at the storage
+// key, generating it on first request and caching it in storage by variants. It must be mounted behind authentication
+// and a rate limiter. The width, height, and format query parameters are collapsed into one of the fixed
+// media.VariantSizes boxes, which bounds how many variants a file can have. Private keys need the same signature as
+// on /media.
+func serveMediaVariant(signer *media.URLSigner, variants *media.VariantStore) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		key := c.Params("*")
+		cacheControl, err := authoriseMediaKey(c, signer, key, "private")
+		if err != nil {
+			return err
+		}
+		if media.IsVariantKey(key) || !media.IsResizableContentType(mime.TypeByExtension(filepath.Ext(key))) {
+			return fiber.NewError(fiber.StatusBadRequest, "This file cannot be resized")
+		}
+		v, err := media.ParseVariant(c.Query("width"), c.Query("height"), c.Query("format"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest,
+				"Width and height must be positive integers and format one of webp, jpeg, or png")
+		}
+
+		etag := mediaETag(v.Key(key))
+		c.Set("ETag", etag)
+		c.Set("Cache-Control", cacheControl)
+		if c.Get(fiber.HeaderIfNoneMatch) == etag {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+
+		data, err := variants.Get(c.Context(), key, v)
+		switch {
+		case errors.Is(err, media.ErrStorageKeyNotFound):
+			return fiber.ErrNotFound
+		case errors.Is(err, media.ErrImageTooLarge):
+			return fiber.NewError(fiber.StatusBadRequest, "Image is too large to resize")
+		case errors.Is(err, media.ErrImageUndecodable):
+			return fiber.NewError(fiber.StatusBadRequest, "This file cannot be resized")
+		case err != nil:
+			log.Error().Err(err).Str("key", key).Msg("Failed to generate image variant")
+			return fiber.ErrInternalServerError
+		}
+
+		c.Set("Content-Type", v.Format.ContentType())
+		c.Set("X-Content-Type-Options", "nosniff")
+		return c.Send(data)
+	}
+}
+
+// authoriseMediaKey checks that key may be served and returns the Cache-Control value for the response. Restricted keys
+// are reported as missing. When signer is non-nil, private keys need a valid, unexpired signature and may be cached
+// privately until it expires; any other key may be cached for a year with the given visibility ("public" or "private").
+func authoriseMediaKey(c fiber.Ctx, signer *media.URLSigner, key, visibility string) (string, error) {
+	if key == "" || media.IsRestrictedKey(key) {
+		return "", fiber.ErrNotFound
+	}
+	if signer != nil && media.IsPrivateKey(key) {
+		expiresAt, err := signer.Verify(key, c.Query(media.SignedURLExpiresParam), c.Query(media.SignedURLSignatureParam))
+		if err != nil {
+			return "", fiber.ErrForbidden
+		}
+		return fmt.Sprintf("private, max-age=%d", int(time.Until(expiresAt).Seconds())), nil
+	}
+	return visibility + ", max-age=31536000, immutable", nil
+}
+
+// mediaETag returns a strong ETag for an immutable storage key.
+func mediaETag(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"image"
+	"image/png"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	apierrors "github.com/uncord-chat/uncord-protocol/errors"
 
 	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/media"
 )
 
 // TestUnknownRouteReturns404 verifies that requests to undefined paths receive a 404 JSON response. Fiber v3 treats
@@ -184,3 +190,132 @@ func TestFiberStatusToAPICode(t *testing.T) {
 		})
 	}
 }
+
+func TestServeMediaFile_SignedURLs(t *testing.T) {
+	t.Parallel()
+
+	store, err := media.NewLocalStorage(t.TempDir(), "http://localhost:8080")
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	store.SignURLs(media.NewURLSigner([]byte("0123456789abcdef0123456789abcdef"), time.Hour))
+
+	for _, key := range []string{"attachments/c/f.txt", "avatars/u/a.webp"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("data")); err != nil {
+			t.Fatalf("Put(%q) error: %v", key, err)
+		}
+	}
+
+	app := fiber.New()
+	app.Get("/media/*", serveMediaFile(store, store.Signer()))
+
+	signed := strings.TrimPrefix(store.URL("attachments/c/f.txt"), "http://localhost:8080")
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantCache  string
+	}{
+		{"signed attachment", signed, fiber.StatusOK, "private"},
+		{"unsigned attachment", "/media/attachments/c/f.txt", fiber.StatusForbidden, ""},
+		{"signature for another key", strings.Replace(signed, "f.txt", "g.txt", 1), fiber.StatusForbidden, ""},
+		{"public avatar", "/media/avatars/u/a.webp", fiber.StatusOK, "public"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequestWithContext(context.Background(), http.MethodGet, tt.path, nil))
+			if err != nil {
+				t.Fatalf("app.Test() error: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantCache != "" && !strings.HasPrefix(resp.Header.Get("Cache-Control"), tt.wantCache) {
+				t.Errorf("Cache-Control = %q, want prefix %q", resp.Header.Get("Cache-Control"), tt.wantCache)
+			}
+		})
+	}
+}
+
+func TestServeMediaVariant(t *testing.T) {
+	t.Parallel()
+
+	store, err := media.NewLocalStorage(t.TempDir(), "http://localhost:8080")
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	img := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode PNG: %v", err)
+	}
+	if err := store.Put(context.Background(), "attachments/c/f.png", &buf); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	if err := store.Put(context.Background(), "attachments/c/f.txt", strings.NewReader("text")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/media/*", serveMediaFile(store, nil))
+	app.Get("/api/v1/media/*", serveMediaVariant(nil, media.NewVariantStore(store)))
+
+	get := func(path, etag string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	resp := get("/api/v1/media/attachments/c/f.png?width=200&format=jpeg", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("Content-Type = %q, want image/jpeg", ct)
+	}
+	cfg, _, err := image.DecodeConfig(resp.Body)
+	if err != nil {
+		t.Fatalf("decode variant: %v", err)
+	}
+	// 200 is snapped up to the 256 box.
+	if cfg.Width != 256 || cfg.Height != 128 {
+		t.Errorf("variant dimensions = %dx%d, want 256x128", cfg.Width, cfg.Height)
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag header is empty")
+	}
+	if resp := get("/api/v1/media/attachments/c/f.png?width=256&format=jpg", etag); resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("conditional status = %d, want %d", resp.StatusCode, fiber.StatusNotModified)
+	}
+	if resp := get("/api/v1/media/attachments/c/f.png?width=0", ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("invalid width status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+	if resp := get("/api/v1/media/attachments/c/f.txt?width=64", ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("non-image status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+	if resp := get("/api/v1/media/attachments/c/missing.png?width=64", ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("missing source status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+	// height=100 snaps to the same 256 box as width=200, so it is the same variant.
+	if resp := get("/api/v1/media/attachments/c/f.png?height=100&format=jpeg", etag); resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("same box status = %d, want %d", resp.StatusCode, fiber.StatusNotModified)
+	}
+	// The unauthenticated route does not resize.
+	if resp := get("/media/attachments/c/f.png?width=64", ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("unauthenticated variant status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+}
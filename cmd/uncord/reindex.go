@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/uncord-chat/uncord-server/internal/config"
+	"github.com/uncord-chat/uncord-server/internal/message"
+	"github.com/uncord-chat/uncord-server/internal/postgres"
+	"github.com/uncord-chat/uncord-server/internal/reindex"
+	"github.com/uncord-chat/uncord-server/internal/typesense"
+	"github.com/uncord-chat/uncord-server/internal/valkey"
+)
+
+// runReindex implements the "reindex-search" subcommand, which rebuilds the search index in the foreground and exits.
+// It shares the lock and checkpoint with reindexes started by the server, so an interrupted run (Ctrl+C or a crash) is
+// resumed by the next invocation unless --fresh is given.
+func runReindex(args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	fresh := fs.Bool("fresh", false, "discard the checkpoint of an interrupted run and start over")
+	batchSize := fs.Int("batch-size", reindex.DefaultBatchSize, "number of messages imported per request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if !cfg.TypesenseEnabled() {
+		return fmt.Errorf("SEARCH_BACKEND is %q; only the Typesense index can be rebuilt", cfg.SearchBackend)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgres.Connect(ctx, cfg.DatabaseURL.Expose(), cfg.DatabaseMaxConn, cfg.DatabaseMinConn)
+	if err != nil {
+		return fmt.Errorf("connect postgres: %w", err)
+	}
+	defer db.Close()
+
+	rdb, err := valkey.Connect(ctx, cfg.ValkeyURL.Expose(), cfg.ValkeyDialTimeout)
+	if err != nil {
+		return fmt.Errorf("connect valkey: %w", err)
+	}
+	defer func() { _ = rdb.Close() }()
+
+	runner := reindex.NewRunner(message.NewPGRepository(db),
+		typesense.NewCollections(cfg.TypesenseURL, cfg.TypesenseAPIKey.Expose(), cfg.TypesenseTimeout),
+		rdb, *batchSize, log.Logger)
+	defer runner.Stop()
+
+	if err := runner.Run(ctx, reindex.Options{Fresh: *fresh}); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Info().Msg("Search reindex interrupted; run the command again to resume")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -11,6 +11,9 @@ import (
 
 	"github.com/uncord-chat/uncord-server/internal/api"
 	"github.com/uncord-chat/uncord-server/internal/auth"
+	"github.com/uncord-chat/uncord-server/internal/backup"
+	"github.com/uncord-chat/uncord-server/internal/channel"
+	"github.com/uncord-chat/uncord-server/internal/emoji"
 	"github.com/uncord-chat/uncord-server/internal/media"
 	"github.com/uncord-chat/uncord-server/internal/member"
 	"github.com/uncord-chat/uncord-server/internal/page"
@@ -50,6 +53,17 @@ func (s *server) uploadLimiter() fiber.Handler {
 	})
 }
 
+// mediaVariantLimiter returns a per-user rate limiter for resized image requests. Each cache miss decodes and resizes
+// the source image, so this is kept separate from, and tighter than, the general API limit.
+func (s *server) mediaVariantLimiter() fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:          s.cfg.RateLimitMediaVariantCount,
+		Expiration:   time.Duration(s.cfg.RateLimitMediaVariantWindowSeconds) * time.Second,
+		KeyGenerator: userKeyGenerator,
+		LimitReached: rateLimitReached,
+	})
+}
+
 // channelMsgLimiter returns a per-user-per-channel rate limiter for message creation.
 func (s *server) channelMsgLimiter() fiber.Handler {
 	return limiter.New(limiter.Config{
@@ -86,8 +100,21 @@ func (s *server) registerRoutes(app *fiber.App) {
 
 	// === HEALTH CHECK ===
 
-	health := api.NewHealthHandler(s.db, redisPinger{client: s.rdb})
-	app.Get("/api/v1/health", health.Health)
+	// Liveness only reports that the process is serving requests. Readiness checks every dependency but, being public,
+	// returns only the overall status; /api/v1/health is kept as an alias of it for existing deployments. The
+	// per-component report (errors, schema versions, client counts, queue depths) is restricted to the server owner.
+	healthHandler := api.NewHealthHandler(s.readiness, s.serverRepo, log.Logger)
+	app.Get("/api/v1/health/live", healthHandler.Live)
+	app.Get("/api/v1/health/ready", healthHandler.Ready)
+	app.Get("/api/v1/health/report", requireAuth, healthHandler.Report)
+	app.Get("/api/v1/health", healthHandler.Ready)
+
+	// === METRICS ===
+
+	// Prometheus metrics are served here unless METRICS_ADDR moves them to a separate listener.
+	if s.metrics != nil && s.cfg.MetricsAddr == "" {
+		app.Get("/metrics", s.metrics.Handler(s.cfg.MetricsToken.Expose()))
+	}
 
 	// === AUTH ROUTES ===
 
@@ -152,7 +179,7 @@ func (s *server) registerRoutes(app *fiber.App) {
 	// === E2EE DEVICE AND KEY ROUTES ===
 
 	// E2EE device and key management (under /api/v1/users, inherits auth + verified)
-	e2eeHandler := api.NewE2EEHandler(s.e2eeRepo, s.dmRepo, s.gatewayPublisher, s.cfg.E2EEOPKLowThreshold, log.Logger)
+	e2eeHandler := api.NewE2EEHandler(s.e2eeRepo, s.authService, s.dmRepo, s.gatewayPublisher, s.cfg.E2EEOPKLowThreshold, log.Logger)
 	userGroup.Post("/@me/devices", e2eeHandler.RegisterDevice)
 	userGroup.Get("/@me/devices", e2eeHandler.ListDevices)
 	userGroup.Delete("/@me/devices/:deviceID", e2eeHandler.RemoveDevice)
@@ -160,12 +187,16 @@ func (s *server) registerRoutes(app *fiber.App) {
 	userGroup.Put("/@me/devices/:deviceID/signed-pre-key", e2eeHandler.UploadSignedPreKey)
 	userGroup.Post("/@me/devices/:deviceID/one-time-pre-keys", e2eeHandler.UploadOneTimePreKeys)
 	userGroup.Get("/@me/devices/:deviceID/one-time-pre-keys/count", e2eeHandler.GetKeyCount)
+	userGroup.Put("/@me/devices/:deviceID/signature", e2eeHandler.SignDevice)
+	userGroup.Put("/@me/master-key", e2eeHandler.UploadMasterKey)
 	userGroup.Get("/:userID/keys", e2eeHandler.FetchKeyBundle)
+	userGroup.Get("/:userID/master-key", e2eeHandler.GetMasterKey)
 
 	// === DIRECT MESSAGE ROUTES ===
 
 	// DM channel management (under /api/v1/users/@me/channels)
-	dmHandler := api.NewDMHandler(s.dmRepo, s.messageRepo, s.e2eeRepo, s.gatewayPublisher, s.cfg.MaxMessageLength, log.Logger)
+	dmHandler := api.NewDMHandler(s.dmRepo, s.messageRepo, s.e2eeRepo, s.attachmentRepo, s.storage, s.gatewayPublisher,
+		s.cfg.MaxMessageLength, s.cfg.MaxAttachmentsPerMessage, s.cfg.MaxUploadSizeBytes(), log.Logger)
 	userGroup.Post("/@me/channels", dmHandler.CreateDMChannel)
 	userGroup.Get("/@me/channels", dmHandler.ListDMChannels)
 
@@ -178,6 +209,8 @@ func (s *server) registerRoutes(app *fiber.App) {
 	dmGroup.Delete("/:channelID/participants/:userID", dmHandler.RequireParticipant, dmHandler.RemoveParticipant)
 	dmGroup.Get("/:channelID/messages", dmHandler.RequireParticipant, dmHandler.ListMessages)
 	dmGroup.Post("/:channelID/messages", dmHandler.RequireParticipant, dmHandler.SendMessage)
+	dmGroup.Post("/:channelID/attachments", s.uploadLimiter(), dmHandler.RequireParticipant, dmHandler.UploadAttachment)
+	dmGroup.Get("/:channelID/attachments/:attachmentID", dmHandler.RequireParticipant, dmHandler.GetAttachment)
 
 	// === SERVER CONFIG ROUTES ===
 
@@ -210,6 +243,34 @@ func (s *server) registerRoutes(app *fiber.App) {
 		permission.RequireServerPermission(s.permResolver, permissions.ViewAuditLog),
 		auditHandler.List)
 
+	// === SEARCH ADMINISTRATION ROUTES ===
+
+	// Search reindex and outbox routes (requires active membership and ManageServer permission). Both manage the
+	// Typesense index, so they only exist when Typesense is the search backend.
+	if s.cfg.TypesenseEnabled() {
+		reindexHandler := api.NewReindexHandler(s.reindexer, s.auditLogger, log.Logger)
+		serverGroup.Post("/search/reindex", requireActiveMember,
+			permission.RequireServerPermission(s.permResolver, permissions.ManageServer),
+			reindexHandler.Start)
+		serverGroup.Get("/search/reindex", requireActiveMember,
+			permission.RequireServerPermission(s.permResolver, permissions.ManageServer),
+			reindexHandler.Status)
+		searchOutboxHandler := api.NewSearchOutboxHandler(s.searchOutbox, s.outboxWorker, log.Logger)
+		serverGroup.Get("/search/outbox", requireActiveMember,
+			permission.RequireServerPermission(s.permResolver, permissions.ManageServer),
+			searchOutboxHandler.Status)
+		serverGroup.Post("/search/outbox/requeue", requireActiveMember,
+			permission.RequireServerPermission(s.permResolver, permissions.ManageServer),
+			searchOutboxHandler.Requeue)
+	}
+
+	// === BACKUP ROUTES ===
+
+	// Server export (requires active membership; the handler restricts it to the server owner)
+	backupHandler := api.NewBackupHandler(backup.NewExporter(s.db, s.storage, log.Logger), s.serverRepo,
+		s.auditLogger, log.Logger)
+	serverGroup.Get("/export", requireActiveMember, backupHandler.Export)
+
 	// === EMOJI ROUTES ===
 
 	// Emoji routes (under /api/v1/server/emoji, all require active membership)
@@ -272,17 +333,37 @@ func (s *server) registerRoutes(app *fiber.App) {
 
 	// Attachment upload route (nested under channels, inherits active requirement)
 	attachmentHandler := api.NewAttachmentHandler(
-		s.attachmentRepo, s.storage, s.rdb, s.cfg.MaxUploadSizeBytes(), log.Logger)
+		s.attachmentRepo, s.storage, s.scanner, s.rdb, s.gatewayPublisher, s.auditLogger, s.cfg.MaxUploadSizeBytes(), s.cfg.MaxUploadChunkSizeBytes(),
+		s.cfg.AttachmentOrphanTTL, log.Logger)
 	channelGroup.Post("/:channelID/attachments",
 		s.uploadLimiter(),
 		permission.RequirePermission(s.permResolver, permissions.AttachFiles),
 		attachmentHandler.Upload)
 
+	// Resumable upload routes. Only session creation counts against the upload rate limit; individual chunks fall under
+	// the general API limit so that a large file is not throttled part-way through.
+	channelGroup.Post("/:channelID/attachments/uploads",
+		s.uploadLimiter(),
+		permission.RequirePermission(s.permResolver, permissions.AttachFiles),
+		attachmentHandler.CreateUpload)
+	channelGroup.Get("/:channelID/attachments/uploads/:uploadID",
+		permission.RequirePermission(s.permResolver, permissions.AttachFiles),
+		attachmentHandler.GetUpload)
+	channelGroup.Put("/:channelID/attachments/uploads/:uploadID",
+		permission.RequirePermission(s.permResolver, permissions.AttachFiles),
+		attachmentHandler.UploadChunk)
+	channelGroup.Post("/:channelID/attachments/uploads/:uploadID/complete",
+		permission.RequirePermission(s.permResolver, permissions.AttachFiles),
+		attachmentHandler.CompleteUpload)
+	channelGroup.Delete("/:channelID/attachments/uploads/:uploadID",
+		permission.RequirePermission(s.permResolver, permissions.AttachFiles),
+		attachmentHandler.CancelUpload)
+
 	// === MESSAGE ROUTES ===
 
 	// Message routes (nested under channels for list and create, inherits active requirement)
 	messageHandler := api.NewMessageHandler(
-		s.messageRepo, s.attachmentRepo, s.reactionRepo, s.storage, s.permResolver, s.typesenseIndexer,
+		s.messageRepo, s.attachmentRepo, s.reactionRepo, s.storage, s.permResolver,
 		s.gatewayPublisher, s.presenceStore, s.cfg.MaxMessageLength, s.cfg.MaxAttachmentsPerMessage, s.auditLogger, log.Logger)
 	channelGroup.Get("/:channelID/messages",
 		permission.RequirePermission(s.permResolver, permissions.ViewChannels|permissions.ReadMessageHistory),
@@ -291,6 +372,9 @@ func (s *server) registerRoutes(app *fiber.App) {
 		s.channelMsgLimiter(), s.globalMsgLimiter(),
 		permission.RequirePermission(s.permResolver, permissions.SendMessages),
 		messageHandler.CreateMessage)
+	channelGroup.Get("/:channelID/messages/:messageID/attachments",
+		permission.RequirePermission(s.permResolver, permissions.ViewChannels|permissions.ReadMessageHistory),
+		messageHandler.ListAttachments)
 
 	// === TYPING INDICATOR ROUTES ===
 
@@ -365,12 +449,27 @@ func (s *server) registerRoutes(app *fiber.App) {
 	// === SEARCH ROUTES ===
 
 	// Search routes (require active membership)
-	searcher := search.NewTypesenseSearcher(s.cfg.TypesenseURL, s.cfg.TypesenseAPIKey.Expose(), s.cfg.TypesenseTimeout)
+	var searcher search.Searcher
+	switch s.cfg.SearchBackend {
+	case "postgres":
+		searcher = search.NewPGSearcher(s.db)
+	default:
+		searcher = search.NewTypesenseSearcher(s.cfg.TypesenseURL, s.cfg.TypesenseAPIKey.Expose(), s.cfg.TypesenseTimeout)
+	}
 	searchService := search.NewService(s.channelRepo, s.permResolver, searcher, log.Logger)
 	searchHandler := api.NewSearchHandler(searchService, log.Logger)
 	app.Get("/api/v1/search/messages", requireAuth, requireCSRF, requireVerifiedEmail, requireActiveMember,
 		searchHandler.SearchMessages)
 
+	// Typeahead lookups run on PostgreSQL trigram indexes whichever search backend is configured.
+	typeahead := search.NewTypeahead(member.NewPGRepository(s.db), channel.NewPGRepository(s.db),
+		emoji.NewPGRepository(s.db), s.channelRepo, s.permResolver, s.permResolver)
+	typeaheadHandler := api.NewTypeaheadHandler(typeahead, s.storage, log.Logger)
+	searchGroup := app.Group("/api/v1/search", requireAuth, requireCSRF, requireVerifiedEmail, requireActiveMember)
+	searchGroup.Get("/members", typeaheadHandler.SearchMembers)
+	searchGroup.Get("/channels", typeaheadHandler.SearchChannels)
+	searchGroup.Get("/emoji", typeaheadHandler.SearchEmoji)
+
 	// === CATEGORY ROUTES ===
 
 	// Category routes (server group routes need per-route active, standalone group requires active)
@@ -481,11 +580,22 @@ func (s *server) registerRoutes(app *fiber.App) {
 
 	// === MEDIA SERVING ===
 
-	// Public media file serving (outside /api/v1/, no auth required). The UUID component of each storage key provides
-	// sufficient entropy to prevent guessing. Path traversal is handled by os.Root inside LocalStorage, which rejects
-	// any key that would escape the storage directory (including via symbolic links).
-	if _, ok := s.storage.(*media.LocalStorage); ok {
-		app.Get("/media/*", serveMediaFile(s.storage))
+	// Media file serving (outside /api/v1/, no session auth). Avatars, banners, and emoji are public and rely on the UUID
+	// component of each storage key to prevent guessing. With MEDIA_SIGNED_URLS enabled, attachments and thumbnails
+	// additionally require a short-lived signature. The signature is a bearer capability, not a permission check: the
+	// storage provider signs every attachment URL it builds, and anyone holding one can fetch the file until it expires.
+	// What keeps URLs from unauthorised users is that they only appear in responses and gateway events for channels the
+	// recipient can view; signing bounds how long a leaked URL, or one kept after losing access, stays usable. Clients
+	// renew expired URLs from the message attachments route. Path traversal is handled by os.Root inside
+	// LocalStorage, which rejects any key that would escape the storage directory (including via symbolic links).
+	//
+	// Resized images are generated on demand, which costs a full decode per cache miss, so they are served from
+	// /api/v1/media behind authentication (the access cookie is scoped to /api) and their own per-user rate limit.
+	// Private keys still need a valid signature there. Variants are cached in storage.
+	if local, ok := s.storage.(*media.LocalStorage); ok {
+		app.Get("/media/*", serveMediaFile(local, local.Signer()))
+		app.Get("/api/v1/media/*", requireAuth, s.mediaVariantLimiter(),
+			serveMediaVariant(local.Signer(), media.NewVariantStore(local)))
 	}
 
 	// === GATEWAY ===
@@ -0,0 +1,300 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/models"
+
+	"github.com/uncord-chat/uncord-server/internal/auth"
+	"github.com/uncord-chat/uncord-server/internal/config"
+	"github.com/uncord-chat/uncord-server/internal/member"
+	"github.com/uncord-chat/uncord-server/internal/permission"
+	"github.com/uncord-chat/uncord-server/internal/server"
+	"github.com/uncord-chat/uncord-server/internal/user"
+)
+
+// Sentinel errors for operator actions.
+var (
+	ErrAlreadyOwner   = errors.New("user is already the server owner")
+	ErrNotActive      = errors.New("user is not an active member")
+	ErrServerOwner    = errors.New("the server owner cannot be purged; transfer ownership first")
+	ErrMFANotEnabled  = errors.New("user does not have MFA enabled")
+	ErrUserNotFound   = errors.New("no user matches that ID or email address")
+	ErrInvalidUserRef = errors.New("user must be given as a user ID or email address")
+)
+
+// Users is the subset of user.Repository used by operator actions. Satisfied by *user.PGRepository.
+type Users interface {
+	Create(ctx context.Context, params user.CreateParams) (uuid.UUID, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+	GetByEmail(ctx context.Context, email string) (*user.Credentials, error)
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, hash string) error
+	DisableMFA(ctx context.Context, userID uuid.UUID) error
+	DeleteWithTombstones(ctx context.Context, id uuid.UUID, tombstones []user.Tombstone) error
+}
+
+// Members is the subset of member.Repository used by operator actions. Satisfied by *member.PGRepository.
+type Members interface {
+	GetStatus(ctx context.Context, userID uuid.UUID) (string, error)
+	CreatePending(ctx context.Context, userID uuid.UUID) (*member.WithProfile, error)
+	Activate(ctx context.Context, userID uuid.UUID, autoRoles []uuid.UUID) (*member.WithProfile, error)
+}
+
+// Servers is the subset of server.Repository used by operator actions. Satisfied by *server.PGRepository.
+type Servers interface {
+	Get(ctx context.Context) (*server.Config, error)
+	SetOwner(ctx context.Context, userID uuid.UUID) (*server.Config, error)
+}
+
+// PermissionInvalidator drops cached permissions on every running server. Satisfied by *permission.Publisher.
+type PermissionInvalidator interface {
+	InvalidateUser(ctx context.Context, userID uuid.UUID) error
+	InvalidateAll(ctx context.Context) error
+}
+
+var (
+	_ Users                 = (*user.PGRepository)(nil)
+	_ Members               = (*member.PGRepository)(nil)
+	_ Servers               = (*server.PGRepository)(nil)
+	_ PermissionInvalidator = (*permission.Publisher)(nil)
+)
+
+// Service carries out operator actions.
+type Service struct {
+	users   Users
+	members Members
+	servers Servers
+	rdb     *redis.Client
+	perms   PermissionInvalidator
+	cfg     *config.Config
+	log     zerolog.Logger
+}
+
+// NewService creates a service for operator actions. rdb is used to revoke refresh tokens.
+func NewService(users Users, members Members, servers Servers, rdb *redis.Client, perms PermissionInvalidator, cfg *config.Config, logger zerolog.Logger) *Service {
+	return &Service{
+		users:   users,
+		members: members,
+		servers: servers,
+		rdb:     rdb,
+		perms:   perms,
+		cfg:     cfg,
+		log:     logger,
+	}
+}
+
+// ResolveUser finds a user by ID or email address, so that operators can name accounts the way they are reported.
+func (s *Service) ResolveUser(ctx context.Context, ref string) (*user.User, error) {
+	id, err := uuid.Parse(ref)
+	if err != nil {
+		email, _, emailErr := auth.ValidateEmail(ref)
+		if emailErr != nil {
+			return nil, ErrInvalidUserRef
+		}
+		creds, credErr := s.users.GetByEmail(ctx, email)
+		if credErr != nil {
+			return nil, lookupError(credErr)
+		}
+		id = creds.ID
+	}
+
+	u, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+	return u, nil
+}
+
+// lookupError maps user.ErrNotFound to ErrUserNotFound and wraps other errors.
+func lookupError(err error) error {
+	if errors.Is(err, user.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	return fmt.Errorf("look up user: %w", err)
+}
+
+// CreateUserParams groups the inputs for CreateUser.
+type CreateUserParams struct {
+	Email    string
+	Username string
+	Password string
+
+	// Verified marks the email address as verified, so the account can log in without a verification email.
+	Verified bool
+
+	// Member adds the user to the server as an active member with the @everyone role, skipping onboarding.
+	Member bool
+}
+
+// CreateUser validates the inputs and creates an account. Tombstones of deleted accounts are not checked, so an
+// operator can restore an account that was deleted by mistake.
+func (s *Service) CreateUser(ctx context.Context, params CreateUserParams) (uuid.UUID, error) {
+	email, _, err := auth.ValidateEmail(params.Email)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := auth.ValidateUsername(params.Username); err != nil {
+		return uuid.Nil, err
+	}
+	hash, err := s.hashPassword(params.Password)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	userID, err := s.users.Create(ctx, user.CreateParams{
+		Email:         email,
+		Username:      params.Username,
+		PasswordHash:  hash,
+		EmailVerified: params.Verified,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("create user: %w", err)
+	}
+
+	if params.Member {
+		if _, err := s.members.CreatePending(ctx, userID); err != nil {
+			return userID, fmt.Errorf("add member: %w", err)
+		}
+		if _, err := s.members.Activate(ctx, userID, nil); err != nil {
+			return userID, fmt.Errorf("activate member: %w", err)
+		}
+	}
+
+	s.log.Info().Str("user_id", userID.String()).Bool("member", params.Member).Msg("User created by operator")
+	return userID, nil
+}
+
+// ResetPassword replaces the user's password and revokes every session, so that anyone holding the old password is
+// signed out.
+func (s *Service) ResetPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdatePasswordHash(ctx, userID, hash); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	if err := auth.RevokeAllRefreshTokens(ctx, s.rdb, userID); err != nil {
+		return err
+	}
+
+	s.log.Info().Str("user_id", userID.String()).Msg("Password reset by operator")
+	return nil
+}
+
+// ResetMFA disables MFA for a user who has lost their authenticator and recovery codes, and revokes every session.
+func (s *Service) ResetMFA(ctx context.Context, userID uuid.UUID) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return lookupError(err)
+	}
+	if !u.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := s.users.DisableMFA(ctx, userID); err != nil {
+		return fmt.Errorf("disable MFA: %w", err)
+	}
+	if err := auth.RevokeAllRefreshTokens(ctx, s.rdb, userID); err != nil {
+		return err
+	}
+
+	s.log.Info().Str("user_id", userID.String()).Msg("MFA reset by operator")
+	return nil
+}
+
+// TransferOwnership makes an active member the server owner and returns the previous owner's ID. Both users'
+// permission caches are invalidated because the owner bypasses every permission check.
+func (s *Service) TransferOwnership(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	current, err := s.servers.Get(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("get server config: %w", err)
+	}
+	if current.OwnerID == userID {
+		return uuid.Nil, ErrAlreadyOwner
+	}
+
+	status, err := s.members.GetStatus(ctx, userID)
+	if errors.Is(err, member.ErrNotFound) || (err == nil && status != models.MemberStatusActive) {
+		return uuid.Nil, ErrNotActive
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("get member status: %w", err)
+	}
+
+	if _, err := s.servers.SetOwner(ctx, userID); err != nil {
+		return uuid.Nil, fmt.Errorf("set owner: %w", err)
+	}
+	for _, id := range []uuid.UUID{current.OwnerID, userID} {
+		if err := s.perms.InvalidateUser(ctx, id); err != nil {
+			s.log.Warn().Err(err).Str("user_id", id.String()).
+				Msg("Failed to invalidate permission cache after ownership transfer")
+		}
+	}
+
+	s.log.Info().Str("previous_owner_id", current.OwnerID.String()).Str("owner_id", userID.String()).
+		Msg("Server ownership transferred by operator")
+	return current.OwnerID, nil
+}
+
+// PurgeUser deletes an account as if the user had deleted it themselves, recording the same tombstones, and revokes
+// their sessions. The server owner cannot be purged.
+func (s *Service) PurgeUser(ctx context.Context, userID uuid.UUID) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return lookupError(err)
+	}
+
+	cfg, err := s.servers.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("get server config: %w", err)
+	}
+	if cfg.OwnerID == userID {
+		return ErrServerOwner
+	}
+
+	tombstones, err := auth.DeletionTombstones(u.Email, u.Username, s.cfg)
+	if err != nil {
+		return err
+	}
+	if err := s.users.DeleteWithTombstones(ctx, userID, tombstones); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+
+	if err := auth.RevokeAllRefreshTokens(ctx, s.rdb, userID); err != nil {
+		s.log.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to revoke refresh tokens after purge")
+	}
+	if err := s.perms.InvalidateUser(ctx, userID); err != nil {
+		s.log.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to invalidate permission cache after purge")
+	}
+
+	s.log.Info().Str("user_id", userID.String()).Msg("User purged by operator")
+	return nil
+}
+
+// RebuildPermissionCache tells every running server to drop its cached permissions, so that they are recomputed from
+// the database on next use.
+func (s *Service) RebuildPermissionCache(ctx context.Context) error {
+	if err := s.perms.InvalidateAll(ctx); err != nil {
+		return fmt.Errorf("invalidate permission caches: %w", err)
+	}
+	s.log.Info().Msg("Permission caches invalidated by operator")
+	return nil
+}
+
+// hashPassword validates a password and hashes it with the configured Argon2id parameters.
+func (s *Service) hashPassword(password string) (string, error) {
+	if err := auth.ValidatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := auth.HashPassword(password, s.cfg.Argon2Memory, s.cfg.Argon2Iterations, s.cfg.Argon2Parallelism,
+		s.cfg.Argon2SaltLength, s.cfg.Argon2KeyLength)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return hash, nil
+}
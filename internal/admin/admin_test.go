@@ -0,0 +1,370 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/models"
+
+	"github.com/uncord-chat/uncord-server/internal/auth"
+	"github.com/uncord-chat/uncord-server/internal/config"
+	"github.com/uncord-chat/uncord-server/internal/member"
+	"github.com/uncord-chat/uncord-server/internal/server"
+	"github.com/uncord-chat/uncord-server/internal/user"
+)
+
+const testServerSecret = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// fakeUsers implements Users in memory.
+type fakeUsers struct {
+	users      map[uuid.UUID]*user.User
+	hashes     map[uuid.UUID]string
+	tombstones []user.Tombstone
+}
+
+func newFakeUsers() *fakeUsers {
+	return &fakeUsers{users: make(map[uuid.UUID]*user.User), hashes: make(map[uuid.UUID]string)}
+}
+
+func (f *fakeUsers) add(email, username string, mfa bool) uuid.UUID {
+	id := uuid.New()
+	f.users[id] = &user.User{ID: id, Email: email, Username: username, MFAEnabled: mfa}
+	return id
+}
+
+func (f *fakeUsers) Create(_ context.Context, params user.CreateParams) (uuid.UUID, error) {
+	for _, u := range f.users {
+		if u.Email == params.Email || u.Username == params.Username {
+			return uuid.Nil, user.ErrAlreadyExists
+		}
+	}
+	id := uuid.New()
+	f.users[id] = &user.User{ID: id, Email: params.Email, Username: params.Username, EmailVerified: params.EmailVerified}
+	f.hashes[id] = params.PasswordHash
+	return id, nil
+}
+
+func (f *fakeUsers) GetByID(_ context.Context, id uuid.UUID) (*user.User, error) {
+	u, ok := f.users[id]
+	if !ok {
+		return nil, user.ErrNotFound
+	}
+	cp := *u
+	return &cp, nil
+}
+
+func (f *fakeUsers) GetByEmail(_ context.Context, email string) (*user.Credentials, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return &user.Credentials{User: *u, PasswordHash: f.hashes[u.ID]}, nil
+		}
+	}
+	return nil, user.ErrNotFound
+}
+
+func (f *fakeUsers) UpdatePasswordHash(_ context.Context, id uuid.UUID, hash string) error {
+	f.hashes[id] = hash
+	return nil
+}
+
+func (f *fakeUsers) DisableMFA(_ context.Context, id uuid.UUID) error {
+	f.users[id].MFAEnabled = false
+	return nil
+}
+
+func (f *fakeUsers) DeleteWithTombstones(_ context.Context, id uuid.UUID, tombstones []user.Tombstone) error {
+	delete(f.users, id)
+	f.tombstones = append(f.tombstones, tombstones...)
+	return nil
+}
+
+// fakeMembers implements Members in memory.
+type fakeMembers struct {
+	status map[uuid.UUID]string
+}
+
+func (f *fakeMembers) GetStatus(_ context.Context, id uuid.UUID) (string, error) {
+	s, ok := f.status[id]
+	if !ok {
+		return "", member.ErrNotFound
+	}
+	return s, nil
+}
+
+func (f *fakeMembers) CreatePending(_ context.Context, id uuid.UUID) (*member.WithProfile, error) {
+	f.status[id] = models.MemberStatusPending
+	return &member.WithProfile{UserID: id}, nil
+}
+
+func (f *fakeMembers) Activate(_ context.Context, id uuid.UUID, _ []uuid.UUID) (*member.WithProfile, error) {
+	if f.status[id] != models.MemberStatusPending {
+		return nil, member.ErrNotPending
+	}
+	f.status[id] = models.MemberStatusActive
+	return &member.WithProfile{UserID: id}, nil
+}
+
+// fakeServers implements Servers in memory.
+type fakeServers struct {
+	ownerID uuid.UUID
+}
+
+func (f *fakeServers) Get(context.Context) (*server.Config, error) {
+	return &server.Config{OwnerID: f.ownerID}, nil
+}
+
+func (f *fakeServers) SetOwner(_ context.Context, id uuid.UUID) (*server.Config, error) {
+	f.ownerID = id
+	return &server.Config{OwnerID: id}, nil
+}
+
+// fakePerms records invalidations.
+type fakePerms struct {
+	users []uuid.UUID
+	all   int
+}
+
+func (f *fakePerms) InvalidateUser(_ context.Context, id uuid.UUID) error {
+	f.users = append(f.users, id)
+	return nil
+}
+
+func (f *fakePerms) InvalidateAll(context.Context) error {
+	f.all++
+	return nil
+}
+
+type testEnv struct {
+	svc     *Service
+	users   *fakeUsers
+	members *fakeMembers
+	servers *fakeServers
+	perms   *fakePerms
+	rdb     *redis.Client
+	ownerID uuid.UUID
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	users := newFakeUsers()
+	ownerID := users.add("owner@example.com", "owner", false)
+	env := &testEnv{
+		users:   users,
+		members: &fakeMembers{status: map[uuid.UUID]string{ownerID: models.MemberStatusActive}},
+		servers: &fakeServers{ownerID: ownerID},
+		perms:   &fakePerms{},
+		rdb:     rdb,
+		ownerID: ownerID,
+	}
+	cfg := &config.Config{
+		Argon2Memory:               64 * 1024,
+		Argon2Iterations:           1, // fast for tests
+		Argon2Parallelism:          1,
+		Argon2SaltLength:           16,
+		Argon2KeyLength:            32,
+		ServerSecret:               config.NewSecret(testServerSecret),
+		DeletionTombstoneUsernames: true,
+	}
+	env.svc = NewService(users, env.members, env.servers, rdb, env.perms, cfg, zerolog.Nop())
+	return env
+}
+
+func (e *testEnv) login(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	token, err := auth.CreateRefreshToken(context.Background(), e.rdb, userID, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	return token
+}
+
+func TestResolveUser(t *testing.T) {
+	t.Parallel()
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	for _, ref := range []string{env.ownerID.String(), "Owner@Example.com"} {
+		u, err := env.svc.ResolveUser(ctx, ref)
+		if err != nil || u.ID != env.ownerID {
+			t.Errorf("ResolveUser(%q) = %v, %v; want the owner", ref, u, err)
+		}
+	}
+	if _, err := env.svc.ResolveUser(ctx, uuid.NewString()); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("ResolveUser(unknown ID) error = %v, want %v", err, ErrUserNotFound)
+	}
+	if _, err := env.svc.ResolveUser(ctx, "nobody@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("ResolveUser(unknown email) error = %v, want %v", err, ErrUserNotFound)
+	}
+	if _, err := env.svc.ResolveUser(ctx, "owner"); !errors.Is(err, ErrInvalidUserRef) {
+		t.Errorf("ResolveUser(username) error = %v, want %v", err, ErrInvalidUserRef)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	t.Parallel()
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	id, err := env.svc.CreateUser(ctx, CreateUserParams{
+		Email: "New@Example.com", Username: "newbie", Password: "correct horse", Verified: true, Member: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	created := env.users.users[id]
+	if created.Email != "new@example.com" || !created.EmailVerified {
+		t.Errorf("created user = %+v, want a normalised, verified email", created)
+	}
+	if ok, _ := auth.VerifyPassword("correct horse", env.users.hashes[id]); !ok {
+		t.Error("created user's password does not verify")
+	}
+	if env.members.status[id] != models.MemberStatusActive {
+		t.Errorf("member status = %q, want %q", env.members.status[id], models.MemberStatusActive)
+	}
+
+	tests := []struct {
+		name    string
+		params  CreateUserParams
+		wantErr error
+	}{
+		{"invalid email", CreateUserParams{Email: "nope", Username: "valid", Password: "password1"}, auth.ErrInvalidEmail},
+		{"invalid username", CreateUserParams{Email: "a@example.com", Username: "x", Password: "password1"},
+			auth.ErrUsernameLength},
+		{"short password", CreateUserParams{Email: "a@example.com", Username: "valid", Password: "short"},
+			auth.ErrPasswordTooShort},
+		{"taken", CreateUserParams{Email: "owner@example.com", Username: "other", Password: "password1"},
+			user.ErrAlreadyExists},
+	}
+	for _, tt := range tests {
+		if _, err := env.svc.CreateUser(ctx, tt.params); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: CreateUser() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResetPassword_RevokesSessions(t *testing.T) {
+	t.Parallel()
+	env := newTestEnv(t)
+	ctx := context.Background()
+	token := env.login(t, env.ownerID)
+
+	if err := env.svc.ResetPassword(ctx, env.ownerID, "a brand new password"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if ok, _ := auth.VerifyPassword("a brand new password", env.users.hashes[env.ownerID]); !ok {
+		t.Error("new password does not verify")
+	}
+	if _, err := auth.ValidateRefreshToken(ctx, env.rdb, token); err == nil {
+		t.Error("refresh token still valid after password reset")
+	}
+
+	if err := env.svc.ResetPassword(ctx, env.ownerID, "short"); !errors.Is(err, auth.ErrPasswordTooShort) {
+		t.Errorf("ResetPassword(short) error = %v, want %v", err, auth.ErrPasswordTooShort)
+	}
+}
+
+func TestResetMFA(t *testing.T) {
+	t.Parallel()
+	env := newTestEnv(t)
+	ctx := context.Background()
+	id := env.users.add("mfa@example.com", "mfa_user", true)
+	token := env.login(t, id)
+
+	if err := env.svc.ResetMFA(ctx, id); err != nil {
+		t.Fatalf("ResetMFA() error = %v", err)
+	}
+	if env.users.users[id].MFAEnabled {
+		t.Error("MFA still enabled after reset")
+	}
+	if _, err := auth.ValidateRefreshToken(ctx, env.rdb, token); err == nil {
+		t.Error("refresh token still valid after MFA reset")
+	}
+	if err := env.svc.ResetMFA(ctx, id); !errors.Is(err, ErrMFANotEnabled) {
+		t.Errorf("second ResetMFA() error = %v, want %v", err, ErrMFANotEnabled)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	t.Parallel()
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	active := env.users.add("active@example.com", "active", false)
+	env.members.status[active] = models.MemberStatusActive
+	pending := env.users.add("pending@example.com", "pending", false)
+	env.members.status[pending] = models.MemberStatusPending
+	outsider := env.users.add("outsider@example.com", "outsider", false)
+
+	for _, id := range []uuid.UUID{pending, outsider} {
+		if _, err := env.svc.TransferOwnership(ctx, id); !errors.Is(err, ErrNotActive) {
+			t.Errorf("TransferOwnership(non-member) error = %v, want %v", err, ErrNotActive)
+		}
+	}
+	if _, err := env.svc.TransferOwnership(ctx, env.ownerID); !errors.Is(err, ErrAlreadyOwner) {
+		t.Errorf("TransferOwnership(owner) error = %v, want %v", err, ErrAlreadyOwner)
+	}
+
+	previous, err := env.svc.TransferOwnership(ctx, active)
+	if err != nil {
+		t.Fatalf("TransferOwnership() error = %v", err)
+	}
+	if previous != env.ownerID || env.servers.ownerID != active {
+		t.Errorf("owner = %s (previous %s), want %s (previous %s)", env.servers.ownerID, previous, active, env.ownerID)
+	}
+	if len(env.perms.users) != 2 {
+		t.Errorf("invalidated %d users' permissions, want both owners", len(env.perms.users))
+	}
+}
+
+func TestPurgeUser(t *testing.T) {
+	t.Parallel()
+	env := newTestEnv(t)
+	ctx := context.Background()
+	id := env.users.add("leaving@example.com", "leaving", false)
+	token := env.login(t, id)
+
+	if err := env.svc.PurgeUser(ctx, env.ownerID); !errors.Is(err, ErrServerOwner) {
+		t.Errorf("PurgeUser(owner) error = %v, want %v", err, ErrServerOwner)
+	}
+
+	if err := env.svc.PurgeUser(ctx, id); err != nil {
+		t.Fatalf("PurgeUser() error = %v", err)
+	}
+	if _, ok := env.users.users[id]; ok {
+		t.Error("user still exists after purge")
+	}
+	if len(env.users.tombstones) != 2 {
+		t.Errorf("recorded %d tombstones, want email and username", len(env.users.tombstones))
+	}
+	if _, err := auth.ValidateRefreshToken(ctx, env.rdb, token); err == nil {
+		t.Error("refresh token still valid after purge")
+	}
+	if len(env.perms.users) != 1 || env.perms.users[0] != id {
+		t.Errorf("invalidated permissions of %v, want %s", env.perms.users, id)
+	}
+	if err := env.svc.PurgeUser(ctx, id); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("second PurgeUser() error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestRebuildPermissionCache(t *testing.T) {
+	t.Parallel()
+	env := newTestEnv(t)
+
+	if err := env.svc.RebuildPermissionCache(context.Background()); err != nil {
+		t.Fatalf("RebuildPermissionCache() error = %v", err)
+	}
+	if env.perms.all != 1 {
+		t.Errorf("InvalidateAll called %d times, want 1", env.perms.all)
+	}
+}
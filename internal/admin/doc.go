@@ -0,0 +1,6 @@
+// Package admin implements the account and recovery actions that operators run from the command line: creating users,
+// resetting passwords and MFA, transferring server ownership, purging accounts, and flushing permission caches. The
+// actions bypass the authorization checks of the HTTP API, so they are only reachable through the uncord binary with
+// direct access to the database, and each one revokes sessions or invalidates caches so that running servers pick up
+// the change.
+package admin
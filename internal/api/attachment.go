@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"image"
@@ -12,6 +13,7 @@ import (
 	"mime"
 	"path/filepath"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v3"
@@ -20,35 +22,56 @@ import (
 	"github.com/rs/zerolog"
 	apierrors "github.com/uncord-chat/uncord-protocol/errors"
 	"github.com/uncord-chat/uncord-protocol/models"
+	"github.com/uncord-chat/uncord-protocol/permissions"
 
 	"github.com/uncord-chat/uncord-server/internal/attachment"
+	"github.com/uncord-chat/uncord-server/internal/audit"
+	"github.com/uncord-chat/uncord-server/internal/gateway"
 	"github.com/uncord-chat/uncord-server/internal/httputil"
 	"github.com/uncord-chat/uncord-server/internal/media"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
 )
 
 // AttachmentHandler serves file upload endpoints.
 type AttachmentHandler struct {
-	attachments  attachment.Repository
-	storage      media.StorageProvider
-	rdb          *redis.Client
-	maxSizeBytes int64
-	log          zerolog.Logger
+	attachments   attachment.Repository
+	storage       media.StorageProvider
+	scanner       media.Scanner
+	rdb           *redis.Client
+	gateway       *gateway.Publisher
+	auditLogger   *audit.Logger
+	maxSizeBytes  int64
+	maxChunkBytes int64
+	uploadTTL     time.Duration
+	log           zerolog.Logger
 }
 
-// NewAttachmentHandler creates a new attachment handler.
+// NewAttachmentHandler creates a new attachment handler. Every uploaded file is passed through scanner before it can be
+// linked to a message. maxChunkBytes bounds a single resumable upload chunk and uploadTTL is how long a resumable upload
+// session may go without receiving a chunk before it is abandoned.
 func NewAttachmentHandler(
 	attachments attachment.Repository,
 	storage media.StorageProvider,
+	scanner media.Scanner,
 	rdb *redis.Client,
+	gw *gateway.Publisher,
+	auditLogger *audit.Logger,
 	maxSizeBytes int64,
+	maxChunkBytes int64,
+	uploadTTL time.Duration,
 	logger zerolog.Logger,
 ) *AttachmentHandler {
 	return &AttachmentHandler{
-		attachments:  attachments,
-		storage:      storage,
-		rdb:          rdb,
-		maxSizeBytes: maxSizeBytes,
-		log:          logger,
+		attachments:   attachments,
+		storage:       storage,
+		scanner:       scanner,
+		rdb:           rdb,
+		gateway:       gw,
+		auditLogger:   auditLogger,
+		maxSizeBytes:  maxSizeBytes,
+		maxChunkBytes: maxChunkBytes,
+		uploadTTL:     uploadTTL,
+		log:           logger,
 	}
 }
 
@@ -115,7 +138,7 @@ func (h *AttachmentHandler) Upload(c fiber.Ctx) error {
 		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
 
-	a, err := h.attachments.Create(c.Context(), attachment.CreateParams{
+	return h.createAttachment(c, attachment.CreateParams{
 		ChannelID:   channelID,
 		UploaderID:  userID,
 		Filename:    sanitiseFilename(fh.Filename),
@@ -125,46 +148,157 @@ func (h *AttachmentHandler) Upload(c fiber.Ctx) error {
 		Width:       width,
 		Height:      height,
 	})
+}
+
+// createAttachment scans the file already written to params.StorageKey and, if it is clean, records the pending
+// attachment and responds with it. Scanning fails closed: when the scanner cannot reach a verdict the file is removed
+// and the upload is rejected so that no unscanned file can be linked to a message. Infected files are deleted and
+// recorded as quarantined, and moderators are notified.
+func (h *AttachmentHandler) createAttachment(c fiber.Ctx, params attachment.CreateParams) error {
+	result, err := h.scanStored(c.Context(), params.StorageKey)
+	if err != nil {
+		_ = h.storage.Delete(c.Context(), params.StorageKey)
+		h.log.Error().Err(err).Str("key", params.StorageKey).Msg("Failed to scan uploaded file")
+		return httputil.Fail(c, fiber.StatusServiceUnavailable, apierrors.ServiceUnavailable,
+			"File scanning is temporarily unavailable. Please try again later.")
+	}
+	if result.Infected {
+		return h.quarantine(c, params, result.Signature)
+	}
+
+	params.ScanStatus = attachment.ScanClean
+	a, err := h.attachments.Create(c.Context(), params)
 	if err != nil {
 		// Best-effort cleanup of the stored file.
-		_ = h.storage.Delete(c.Context(), storageKey)
+		_ = h.storage.Delete(c.Context(), params.StorageKey)
 		h.log.Error().Err(err).Msg("Failed to create attachment record")
 		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
 
-	// Enqueue thumbnail generation for images (best-effort). Uses context.Background because Fiber recycles the
-	// request context after the handler returns.
-	if media.IsImageContentType(contentType) && h.rdb != nil {
-		go func() {
+	h.enqueueMediaJobs(c.Context(), a)
+
+	return httputil.SuccessStatus(c, fiber.StatusCreated, toAttachmentModel(a, h.storage))
+}
+
+// scanStored streams the stored file at key through the configured scanner.
+func (h *AttachmentHandler) scanStored(ctx context.Context, key string) (media.ScanResult, error) {
+	rc, err := h.storage.Get(ctx, key)
+	if err != nil {
+		return media.ScanResult{}, fmt.Errorf("open file for scanning: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
+	return h.scanner.Scan(ctx, rc)
+}
+
+// quarantine handles an upload the scanner flagged as infected. The file is deleted immediately so it can never be
+// served, while the attachment row is kept with a quarantined status (which LinkToMessage refuses) so the upload remains
+// visible to moderators until the orphan purge removes it. Moderators holding ManageMessages in the channel are
+// notified over the gateway and the rejection is written to the audit log.
+func (h *AttachmentHandler) quarantine(c fiber.Ctx, params attachment.CreateParams, signature string) error {
+	_ = h.storage.Delete(c.Context(), params.StorageKey)
+
+	params.ScanStatus = attachment.ScanQuarantined
+	params.ScanSignature = &signature
+	a, err := h.attachments.Create(c.Context(), params)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to record quarantined attachment")
+	}
+
+	h.log.Warn().Str("signature", signature).Stringer("uploader_id", params.UploaderID).
+		Stringer("channel_id", params.ChannelID).Str("filename", params.Filename).
+		Msg("Uploaded file rejected by malware scanner")
+
+	if a != nil {
+		if h.gateway != nil {
+			h.gateway.EnqueuePermitted(c.Context(), gateway.AttachmentQuarantined, quarantineEvent{
+				AttachmentID: a.ID.String(),
+				ChannelID:    a.ChannelID.String(),
+				UploaderID:   a.UploaderID.String(),
+				Filename:     a.Filename,
+				Signature:    signature,
+			}, permissions.ManageMessages)
+		}
+		if h.auditLogger != nil {
+			go h.auditLogger.Record(context.Background(), audit.Entry{
+				ActorID: audit.UUIDPtr(params.UploaderID), Action: audit.AttachmentQuarantine,
+				TargetType: audit.Ptr("attachment"), TargetID: audit.UUIDPtr(a.ID),
+				Changes: audit.MarshalChanges(map[string]string{
+					"channel_id": a.ChannelID.String(),
+					"filename":   a.Filename,
+					"signature":  signature,
+				}),
+			})
+		}
+	}
+
+	return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+		"This file was rejected by the malware scanner")
+}
+
+// quarantineEvent is the payload of the ATTACHMENT_QUARANTINED gateway event sent to moderators.
+type quarantineEvent struct {
+	AttachmentID string `json:"attachment_id"`
+	ChannelID    string `json:"channel_id"`
+	UploaderID   string `json:"uploader_id"`
+	Filename     string `json:"filename"`
+	Signature    string `json:"signature"`
+}
+
+// enqueueMediaJobs enqueues media processing (thumbnails, poster frames, probing, waveforms) for the attachment's content
+// type (best-effort). The request context is detached because Fiber recycles it after the handler returns.
+func (h *AttachmentHandler) enqueueMediaJobs(ctx context.Context, a *attachment.Attachment) {
+	kinds := media.JobsForContentType(a.ContentType)
+	if len(kinds) == 0 || h.rdb == nil {
+		return
+	}
+	ctx = tracing.Detach(ctx)
+	go func() {
+		for _, kind := range kinds {
 			job := media.ThumbnailJob{
+				Kind:         kind,
 				AttachmentID: a.ID.String(),
-				StorageKey:   storageKey,
-				ContentType:  contentType,
+				StorageKey:   a.StorageKey,
+				ContentType:  a.ContentType,
 			}
-			if err := media.EnqueueThumbnail(context.Background(), h.rdb, job); err != nil {
-				h.log.Warn().Err(err).Str("attachment_id", a.ID.String()).Msg("Failed to enqueue thumbnail job")
+			if err := media.EnqueueThumbnail(ctx, h.rdb, job); err != nil {
+				h.log.Warn().Err(err).Str("attachment_id", a.ID.String()).Str("kind", string(kind)).
+					Msg("Failed to enqueue media job")
 			}
-		}()
-	}
+		}
+	}()
+}
 
-	return httputil.SuccessStatus(c, fiber.StatusCreated, toAttachmentModel(a, h.storage))
+// attachmentModel extends the protocol attachment with the media metadata extracted by the background media worker.
+// The protocol module does not carry these fields yet, so they are added here as optional properties that existing
+// clients ignore. For videos, ThumbnailURL points at the poster frame.
+type attachmentModel struct {
+	models.Attachment
+	DurationMS *int    `json:"duration_ms,omitempty"`
+	Waveform   *string `json:"waveform,omitempty"`
 }
 
-// toAttachmentModel converts an internal attachment to the protocol response type.
-func toAttachmentModel(a *attachment.Attachment, storage media.StorageProvider) models.Attachment {
-	result := models.Attachment{
-		ID:          a.ID.String(),
-		Filename:    a.Filename,
-		URL:         storage.URL(a.StorageKey),
-		Size:        a.SizeBytes,
-		ContentType: a.ContentType,
-		Width:       a.Width,
-		Height:      a.Height,
+// toAttachmentModel converts an internal attachment to the response type. The waveform is base64-encoded.
+func toAttachmentModel(a *attachment.Attachment, storage media.StorageProvider) attachmentModel {
+	result := attachmentModel{
+		Attachment: models.Attachment{
+			ID:          a.ID.String(),
+			Filename:    a.Filename,
+			URL:         storage.URL(a.StorageKey),
+			Size:        a.SizeBytes,
+			ContentType: a.ContentType,
+			Width:       a.Width,
+			Height:      a.Height,
+		},
+		DurationMS: a.DurationMS,
 	}
 	if a.ThumbnailKey != nil {
 		url := storage.URL(*a.ThumbnailKey)
 		result.ThumbnailURL = &url
 	}
+	if len(a.Waveform) > 0 {
+		encoded := base64.StdEncoding.EncodeToString(a.Waveform)
+		result.Waveform = &encoded
+	}
 	return result
 }
 
@@ -3,12 +3,18 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -54,15 +60,74 @@ func (s *fakeStorageForUpload) URL(key string) string {
 	return "http://localhost:8080/media/" + key
 }
 
+// fakeScanner returns a fixed verdict and records the bytes it was asked to scan.
+type fakeScanner struct {
+	result  media.ScanResult
+	err     error
+	scanned []byte
+}
+
+func (s *fakeScanner) Scan(_ context.Context, r io.Reader) (media.ScanResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return media.ScanResult{}, err
+	}
+	s.scanned = data
+	return s.result, s.err
+}
+
 func testUploadApp(t *testing.T, repo attachment.Repository, storage media.StorageProvider, maxSize int64, userID uuid.UUID) *fiber.App {
 	t.Helper()
-	handler := NewAttachmentHandler(repo, storage, nil, maxSize, zerolog.Nop())
+	return testScanningUploadApp(t, repo, storage, media.NoopScanner{}, maxSize, userID)
+}
+
+func testScanningUploadApp(t *testing.T, repo attachment.Repository, storage media.StorageProvider, scanner media.Scanner, maxSize int64, userID uuid.UUID) *fiber.App {
+	t.Helper()
+	handler := NewAttachmentHandler(repo, storage, scanner, nil, nil, nil, maxSize, maxSize, time.Hour, zerolog.Nop())
 	app := fiber.New(fiber.Config{BodyLimit: int(maxSize) + 1024*1024})
 	app.Use(fakeAuth(userID))
 	app.Post("/channels/:channelID/attachments", handler.Upload)
 	return app
 }
 
+func testResumableUploadApp(t *testing.T, repo attachment.Repository, storage media.StorageProvider, maxSize, maxChunk int64, userID uuid.UUID) *fiber.App {
+	t.Helper()
+	handler := NewAttachmentHandler(repo, storage, media.NoopScanner{}, nil, nil, nil, maxSize, maxChunk, time.Hour,
+		zerolog.Nop())
+	app := fiber.New(fiber.Config{BodyLimit: int(maxSize) + 1024*1024})
+	app.Use(fakeAuth(userID))
+	app.Post("/channels/:channelID/attachments/uploads", handler.CreateUpload)
+	app.Get("/channels/:channelID/attachments/uploads/:uploadID", handler.GetUpload)
+	app.Put("/channels/:channelID/attachments/uploads/:uploadID", handler.UploadChunk)
+	app.Post("/channels/:channelID/attachments/uploads/:uploadID/complete", handler.CompleteUpload)
+	app.Delete("/channels/:channelID/attachments/uploads/:uploadID", handler.CancelUpload)
+	return app
+}
+
+// createTestUpload starts a resumable upload session for content and returns its ID.
+func createTestUpload(t *testing.T, app *fiber.App, channelID uuid.UUID, filename string, content []byte) string {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	reqBody := fmt.Sprintf(`{"filename":%q,"size":%d,"sha256":%q}`, filename, len(content), hex.EncodeToString(sum[:]))
+	resp := doReq(t, app, jsonReq(http.MethodPost, "/channels/"+channelID.String()+"/attachments/uploads", reqBody))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("create upload status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusCreated, body)
+	}
+	var u uploadModel
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &u); err != nil {
+		t.Fatalf("unmarshal upload: %v", err)
+	}
+	return u.ID
+}
+
+func chunkReq(url string, offset int, chunk []byte) *http.Request {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, url, bytes.NewReader(chunk))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+	return req
+}
+
 func multipartFileReq(t *testing.T, url, filename string, content []byte) *http.Request {
 	t.Helper()
 	var buf bytes.Buffer
@@ -129,6 +194,80 @@ func TestUpload_Success(t *testing.T) {
 	}
 }
 
+func TestUpload_ScannedClean(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	scanner := &fakeScanner{}
+	app := testScanningUploadApp(t, repo, storage, scanner, 10*1024*1024, uuid.New())
+
+	content := []byte("fake jpeg data")
+	resp := doReq(t, app, multipartFileReq(t, "/channels/"+uuid.New().String()+"/attachments", "photo.jpg", content))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusCreated, body)
+	}
+	if !bytes.Equal(scanner.scanned, content) {
+		t.Errorf("scanned = %q, want %q", scanner.scanned, content)
+	}
+	if len(repo.attachments) != 1 || repo.attachments[0].ScanStatus != attachment.ScanClean {
+		t.Fatalf("attachments = %+v, want one clean attachment", repo.attachments)
+	}
+}
+
+func TestUpload_Infected(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	scanner := &fakeScanner{result: media.ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}}
+	app := testScanningUploadApp(t, repo, storage, scanner, 10*1024*1024, uuid.New())
+
+	resp := doReq(t, app, multipartFileReq(t, "/channels/"+uuid.New().String()+"/attachments", "tool.txt",
+		[]byte("not really malware")))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusBadRequest, body)
+	}
+	env := parseError(t, body)
+	if env.Error.Code != "VALIDATION_ERROR" {
+		t.Errorf("error code = %q, want VALIDATION_ERROR", env.Error.Code)
+	}
+	if len(storage.files) != 0 {
+		t.Errorf("storage files = %d, want 0 (infected file should be deleted)", len(storage.files))
+	}
+	if len(repo.attachments) != 1 {
+		t.Fatalf("attachments = %d, want 1 quarantined record", len(repo.attachments))
+	}
+	a := repo.attachments[0]
+	if a.ScanStatus != attachment.ScanQuarantined {
+		t.Errorf("scan status = %q, want %q", a.ScanStatus, attachment.ScanQuarantined)
+	}
+	if a.ScanSignature == nil || *a.ScanSignature != "Eicar-Test-Signature" {
+		t.Errorf("scan signature = %v, want Eicar-Test-Signature", a.ScanSignature)
+	}
+}
+
+func TestUpload_ScannerUnavailable(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	scanner := &fakeScanner{err: media.ErrScannerUnavailable}
+	app := testScanningUploadApp(t, repo, storage, scanner, 10*1024*1024, uuid.New())
+
+	resp := doReq(t, app, multipartFileReq(t, "/channels/"+uuid.New().String()+"/attachments", "photo.jpg",
+		[]byte("fake jpeg data")))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusServiceUnavailable, body)
+	}
+	if len(storage.files) != 0 {
+		t.Errorf("storage files = %d, want 0 (unscanned file should be deleted)", len(storage.files))
+	}
+	if len(repo.attachments) != 0 {
+		t.Errorf("attachments = %d, want 0", len(repo.attachments))
+	}
+}
+
 func TestUpload_UnsupportedContentType(t *testing.T) {
 	t.Parallel()
 	repo := newFakeAttachmentRepo()
@@ -231,6 +370,229 @@ func TestUpload_MissingFile(t *testing.T) {
 	}
 }
 
+func TestResumableUpload_Success(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	channelID := uuid.New()
+	userID := uuid.New()
+	app := testResumableUploadApp(t, repo, storage, 1024, 8, userID)
+
+	content := []byte("a long recording!")
+	uploadID := createTestUpload(t, app, channelID, "talk.txt", content)
+	uploadURL := "/channels/" + channelID.String() + "/attachments/uploads/" + uploadID
+
+	for offset := 0; offset < len(content); offset += 8 {
+		end := min(offset+8, len(content))
+		resp := doReq(t, app, chunkReq(uploadURL, offset, content[offset:end]))
+		body := readBody(t, resp)
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("chunk at %d status = %d, want %d; body: %s", offset, resp.StatusCode, fiber.StatusOK, body)
+		}
+		var u uploadModel
+		if err := json.Unmarshal(parseSuccess(t, body).Data, &u); err != nil {
+			t.Fatalf("unmarshal upload: %v", err)
+		}
+		if u.Offset != int64(end) {
+			t.Errorf("offset after chunk = %d, want %d", u.Offset, end)
+		}
+	}
+
+	resp := doReq(t, app, jsonReq(http.MethodPost, uploadURL+"/complete", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("complete status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusCreated, body)
+	}
+	var att struct {
+		Filename string `json:"filename"`
+		Size     int64  `json:"size"`
+	}
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &att); err != nil {
+		t.Fatalf("unmarshal attachment: %v", err)
+	}
+	if att.Filename != "talk.txt" || att.Size != int64(len(content)) {
+		t.Errorf("attachment = %+v, want talk.txt of %d bytes", att, len(content))
+	}
+
+	if len(repo.attachments) != 1 {
+		t.Fatalf("attachments = %d, want 1", len(repo.attachments))
+	}
+	if got := storage.files[repo.attachments[0].StorageKey]; !bytes.Equal(got, content) {
+		t.Errorf("assembled file = %q, want %q", got, content)
+	}
+	for key := range storage.files {
+		if strings.HasPrefix(key, "uploads/") {
+			t.Errorf("chunk %q was not deleted after completion", key)
+		}
+	}
+	if len(repo.uploads) != 0 {
+		t.Error("upload session was not deleted after completion")
+	}
+}
+
+func TestResumableUpload_OffsetMismatch(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	channelID := uuid.New()
+	userID := uuid.New()
+	app := testResumableUploadApp(t, repo, storage, 1024, 8, userID)
+
+	uploadID := createTestUpload(t, app, channelID, "notes.txt", []byte("0123456789"))
+	uploadURL := "/channels/" + channelID.String() + "/attachments/uploads/" + uploadID
+
+	resp := doReq(t, app, chunkReq(uploadURL, 4, []byte("4567")))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusConflict, body)
+	}
+
+	resp = doReq(t, app, httptest.NewRequestWithContext(context.Background(), http.MethodGet, uploadURL, nil))
+	body = readBody(t, resp)
+	var u uploadModel
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &u); err != nil {
+		t.Fatalf("unmarshal upload: %v", err)
+	}
+	if u.Offset != 0 {
+		t.Errorf("offset = %d, want 0", u.Offset)
+	}
+}
+
+func TestResumableUpload_ChunkTooLarge(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	channelID := uuid.New()
+	userID := uuid.New()
+	app := testResumableUploadApp(t, repo, storage, 1024, 4, userID)
+
+	uploadID := createTestUpload(t, app, channelID, "notes.txt", []byte("0123456789"))
+	uploadURL := "/channels/" + channelID.String() + "/attachments/uploads/" + uploadID
+
+	resp := doReq(t, app, chunkReq(uploadURL, 0, []byte("01234")))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusBadRequest, body)
+	}
+	env := parseError(t, body)
+	if env.Error.Code != string(apierrors.PayloadTooLarge) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.PayloadTooLarge)
+	}
+}
+
+func TestResumableUpload_DeclaredSizeTooLarge(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	channelID := uuid.New()
+	userID := uuid.New()
+	app := testResumableUploadApp(t, repo, storage, 16, 8, userID)
+
+	reqBody := `{"filename":"big.txt","size":17,"sha256":"` + strings.Repeat("0", 64) + `"}`
+	resp := doReq(t, app, jsonReq(http.MethodPost, "/channels/"+channelID.String()+"/attachments/uploads", reqBody))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusBadRequest, body)
+	}
+	env := parseError(t, body)
+	if env.Error.Code != string(apierrors.PayloadTooLarge) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.PayloadTooLarge)
+	}
+}
+
+func TestResumableUpload_ChecksumMismatch(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	channelID := uuid.New()
+	userID := uuid.New()
+	app := testResumableUploadApp(t, repo, storage, 1024, 16, userID)
+
+	uploadID := createTestUpload(t, app, channelID, "notes.txt", []byte("expected"))
+	uploadURL := "/channels/" + channelID.String() + "/attachments/uploads/" + uploadID
+
+	resp := doReq(t, app, chunkReq(uploadURL, 0, []byte("tampered")))
+	_ = readBody(t, resp)
+
+	resp = doReq(t, app, jsonReq(http.MethodPost, uploadURL+"/complete", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusBadRequest, body)
+	}
+	if len(repo.attachments) != 0 {
+		t.Error("attachment was created despite checksum mismatch")
+	}
+	if len(storage.files) != 0 {
+		t.Errorf("storage still holds %d files after checksum mismatch", len(storage.files))
+	}
+}
+
+func TestResumableUpload_CompleteIncomplete(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	channelID := uuid.New()
+	userID := uuid.New()
+	app := testResumableUploadApp(t, repo, storage, 1024, 4, userID)
+
+	uploadID := createTestUpload(t, app, channelID, "notes.txt", []byte("0123456789"))
+	uploadURL := "/channels/" + channelID.String() + "/attachments/uploads/" + uploadID
+
+	resp := doReq(t, app, chunkReq(uploadURL, 0, []byte("0123")))
+	_ = readBody(t, resp)
+
+	resp = doReq(t, app, jsonReq(http.MethodPost, uploadURL+"/complete", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusBadRequest, body)
+	}
+	if len(repo.uploads) != 1 {
+		t.Error("incomplete upload session should be kept so the client can resume")
+	}
+}
+
+func TestResumableUpload_OtherUsersSession(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	channelID := uuid.New()
+	owner := testResumableUploadApp(t, repo, storage, 1024, 8, uuid.New())
+	other := testResumableUploadApp(t, repo, storage, 1024, 8, uuid.New())
+
+	uploadID := createTestUpload(t, owner, channelID, "notes.txt", []byte("secret"))
+	uploadURL := "/channels/" + channelID.String() + "/attachments/uploads/" + uploadID
+
+	resp := doReq(t, other, chunkReq(uploadURL, 0, []byte("secret")))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusNotFound, body)
+	}
+}
+
+func TestResumableUpload_Cancel(t *testing.T) {
+	t.Parallel()
+	repo := newFakeAttachmentRepo()
+	storage := newFakeStorageForUpload()
+	channelID := uuid.New()
+	userID := uuid.New()
+	app := testResumableUploadApp(t, repo, storage, 1024, 4, userID)
+
+	uploadID := createTestUpload(t, app, channelID, "notes.txt", []byte("0123456789"))
+	uploadURL := "/channels/" + channelID.String() + "/attachments/uploads/" + uploadID
+
+	resp := doReq(t, app, chunkReq(uploadURL, 0, []byte("0123")))
+	_ = readBody(t, resp)
+
+	resp = doReq(t, app, httptest.NewRequestWithContext(context.Background(), http.MethodDelete, uploadURL, nil))
+	_ = readBody(t, resp)
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusNoContent)
+	}
+	if len(repo.uploads) != 0 || len(storage.files) != 0 {
+		t.Error("cancel should remove the session and its chunks")
+	}
+}
+
 func TestSanitiseFilename(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/attachment"
+	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/media"
+)
+
+// uploadOffsetHeader carries the byte offset a chunk starts at. It must equal the session's current offset, which
+// clients can read back from the status endpoint after a dropped connection.
+const uploadOffsetHeader = "Upload-Offset"
+
+// createUploadRequest is the body of a resumable upload session creation request. The protocol module has no type for
+// resumable uploads yet, so the request and response shapes are defined here.
+type createUploadRequest struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// uploadModel describes a resumable upload session to the client.
+type uploadModel struct {
+	ID           string `json:"id"`
+	Filename     string `json:"filename"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Offset       int64  `json:"offset"`
+	MaxChunkSize int64  `json:"max_chunk_size"`
+	ExpiresAt    string `json:"expires_at"`
+}
+
+// CreateUpload handles POST /api/v1/channels/:channelID/attachments/uploads. It starts a resumable upload session for a
+// file of the declared size and SHA-256 checksum.
+func (h *AttachmentHandler) CreateUpload(c fiber.Ctx) error {
+	channelID, ok := httputil.ParseUUIDParam(c, "channelID", apierrors.InvalidChannelID)
+	if !ok {
+		return nil
+	}
+
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	var body createUploadRequest
+	if err := c.Bind().Body(&body); err != nil {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidBody, "Invalid request body")
+	}
+
+	filename := sanitiseFilename(strings.TrimSpace(body.Filename))
+	if filename == "" || filename == "." || filename == "/" {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError, "Filename is required")
+	}
+	if body.Size < 1 {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError, "Size must be at least 1 byte")
+	}
+	if body.Size > h.maxSizeBytes {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.PayloadTooLarge,
+			fmt.Sprintf("File size exceeds the maximum of %d MB", h.maxSizeBytes/(1024*1024)))
+	}
+	checksum := strings.ToLower(strings.TrimSpace(body.SHA256))
+	if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+			"SHA-256 checksum must be 64 hexadecimal characters")
+	}
+
+	contentType := detectContentType(body.ContentType, filename)
+	if !media.IsAllowedContentType(contentType) {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.UnsupportedContentType,
+			"This file type is not allowed")
+	}
+
+	u, err := h.attachments.CreateUpload(c.Context(), attachment.CreateUploadParams{
+		ChannelID:   channelID,
+		UploaderID:  userID,
+		Filename:    filename,
+		ContentType: contentType,
+		SizeBytes:   body.Size,
+		SHA256:      checksum,
+	})
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to create upload session")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	return httputil.SuccessStatus(c, fiber.StatusCreated, h.toUploadModel(u))
+}
+
+// GetUpload handles GET /api/v1/channels/:channelID/attachments/uploads/:uploadID. Clients call it after an interrupted
+// chunk to learn the offset to resume from.
+func (h *AttachmentHandler) GetUpload(c fiber.Ctx) error {
+	u, ok, err := h.loadUpload(c)
+	if !ok {
+		return err
+	}
+	return httputil.Success(c, h.toUploadModel(u))
+}
+
+// UploadChunk handles PUT /api/v1/channels/:channelID/attachments/uploads/:uploadID. The raw request body is one chunk
+// and the Upload-Offset header states where in the file it starts.
+func (h *AttachmentHandler) UploadChunk(c fiber.Ctx) error {
+	u, ok, err := h.loadUpload(c)
+	if !ok {
+		return err
+	}
+
+	offset, err := strconv.ParseInt(c.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+			"The Upload-Offset header must be a non-negative integer")
+	}
+
+	chunk := c.Body()
+	size := int64(len(chunk))
+	if size == 0 {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError, "Chunk must not be empty")
+	}
+	if size > h.maxChunkBytes {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.PayloadTooLarge,
+			fmt.Sprintf("Chunk size exceeds the maximum of %d MB", h.maxChunkBytes/(1024*1024)))
+	}
+	if offset != u.ReceivedBytes {
+		return httputil.Fail(c, fiber.StatusConflict, apierrors.ValidationError,
+			fmt.Sprintf("Chunk offset %d does not match the upload offset %d", offset, u.ReceivedBytes))
+	}
+	if offset+size > u.SizeBytes {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.PayloadTooLarge,
+			"Chunk extends beyond the declared file size")
+	}
+
+	chunkKey := fmt.Sprintf("uploads/%s/%s", u.ID.String(), uuid.New().String())
+	if err := h.storage.Put(c.Context(), chunkKey, bytes.NewReader(chunk)); err != nil {
+		h.log.Error().Err(err).Msg("Failed to write upload chunk to storage")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	updated, err := h.attachments.AppendUploadChunk(c.Context(), u.ID, u.UploaderID, offset, size, chunkKey)
+	if err != nil {
+		// The chunk was not recorded, so nothing will reference the stored file.
+		_ = h.storage.Delete(c.Context(), chunkKey)
+		switch {
+		case errors.Is(err, attachment.ErrUploadNotFound):
+			return httputil.Fail(c, fiber.StatusNotFound, apierrors.NotFound, "Upload session not found")
+		case errors.Is(err, attachment.ErrUploadOffsetMismatch):
+			return httputil.Fail(c, fiber.StatusConflict, apierrors.ValidationError,
+				"Chunk offset no longer matches the upload offset")
+		default:
+			h.log.Error().Err(err).Msg("Failed to record upload chunk")
+			return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+		}
+	}
+
+	return httputil.Success(c, h.toUploadModel(updated))
+}
+
+// CompleteUpload handles POST /api/v1/channels/:channelID/attachments/uploads/:uploadID/complete. It assembles the
+// chunks into a single file, verifies the declared SHA-256 checksum, and scans and records the file exactly like one
+// uploaded in a single request.
+func (h *AttachmentHandler) CompleteUpload(c fiber.Ctx) error {
+	u, ok, err := h.loadUpload(c)
+	if !ok {
+		return err
+	}
+	if !u.Complete() {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+			fmt.Sprintf("Upload is incomplete: received %d of %d bytes", u.ReceivedBytes, u.SizeBytes))
+	}
+
+	chunkKeys, err := h.attachments.ListUploadChunks(c.Context(), u.ID)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to list upload chunks")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	ext := media.ExtensionFromFilename(u.Filename)
+	storageKey := fmt.Sprintf("attachments/%s/%s%s", u.ChannelID.String(), uuid.New().String(), ext)
+
+	hasher := sha256.New()
+	assembled := &chunkReader{ctx: c.Context(), storage: h.storage, keys: chunkKeys}
+	counted := &countingReader{r: io.TeeReader(assembled, hasher)}
+	err = h.storage.Put(c.Context(), storageKey, counted)
+	_ = assembled.Close()
+	if err != nil {
+		_ = h.storage.Delete(c.Context(), storageKey)
+		h.log.Error().Err(err).Str("upload_id", u.ID.String()).Msg("Failed to assemble upload")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	// Claim the session before creating the attachment so that concurrent completion requests cannot both succeed.
+	// Whichever request loses the race finds the session gone and discards its copy of the file.
+	if _, err := h.attachments.DeleteUpload(c.Context(), u.ID, u.UploaderID); err != nil {
+		_ = h.storage.Delete(c.Context(), storageKey)
+		if errors.Is(err, attachment.ErrUploadNotFound) {
+			return httputil.Fail(c, fiber.StatusNotFound, apierrors.NotFound, "Upload session not found")
+		}
+		h.log.Error().Err(err).Msg("Failed to delete upload session")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+	h.deleteKeys(chunkKeys)
+
+	// A checksum or size mismatch means the chunks were corrupted in transit. The session has already been discarded
+	// because its contents cannot be repaired in place; the client must start a new upload.
+	if counted.n != u.SizeBytes || hex.EncodeToString(hasher.Sum(nil)) != u.SHA256 {
+		_ = h.storage.Delete(c.Context(), storageKey)
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+			"Uploaded data does not match the declared SHA-256 checksum")
+	}
+
+	width, height := h.imageDimensions(c.Context(), storageKey, u.ContentType)
+
+	return h.createAttachment(c, attachment.CreateParams{
+		ChannelID:   u.ChannelID,
+		UploaderID:  u.UploaderID,
+		Filename:    u.Filename,
+		ContentType: u.ContentType,
+		SizeBytes:   u.SizeBytes,
+		StorageKey:  storageKey,
+		Width:       width,
+		Height:      height,
+	})
+}
+
+// CancelUpload handles DELETE /api/v1/channels/:channelID/attachments/uploads/:uploadID. It discards the session and
+// any chunks received so far.
+func (h *AttachmentHandler) CancelUpload(c fiber.Ctx) error {
+	u, ok, err := h.loadUpload(c)
+	if !ok {
+		return err
+	}
+
+	chunkKeys, err := h.attachments.DeleteUpload(c.Context(), u.ID, u.UploaderID)
+	if err != nil {
+		if errors.Is(err, attachment.ErrUploadNotFound) {
+			return httputil.Fail(c, fiber.StatusNotFound, apierrors.NotFound, "Upload session not found")
+		}
+		h.log.Error().Err(err).Msg("Failed to delete upload session")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+	h.deleteKeys(chunkKeys)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// loadUpload resolves the upload session named by the route for the authenticated user. Sessions in a different channel
+// and sessions that have outlived the upload TTL are reported as not found; the latter are removed by the periodic
+// orphan purge. When ok is false the response has already been written and err is what the handler should return.
+func (h *AttachmentHandler) loadUpload(c fiber.Ctx) (u *attachment.Upload, ok bool, err error) {
+	channelID, valid := httputil.ParseUUIDParam(c, "channelID", apierrors.InvalidChannelID)
+	if !valid {
+		return nil, false, nil
+	}
+	uploadID, valid := httputil.ParseUUIDParam(c, "uploadID", apierrors.ValidationError)
+	if !valid {
+		return nil, false, nil
+	}
+
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return nil, false, err
+	}
+
+	u, err = h.attachments.GetUpload(c.Context(), uploadID, userID)
+	if err != nil {
+		if errors.Is(err, attachment.ErrUploadNotFound) {
+			return nil, false, httputil.Fail(c, fiber.StatusNotFound, apierrors.NotFound, "Upload session not found")
+		}
+		h.log.Error().Err(err).Msg("Failed to load upload session")
+		return nil, false, httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError,
+			"An internal error occurred")
+	}
+	if u.ChannelID != channelID || time.Since(u.UpdatedAt) > h.uploadTTL {
+		return nil, false, httputil.Fail(c, fiber.StatusNotFound, apierrors.NotFound, "Upload session not found")
+	}
+	return u, true, nil
+}
+
+// imageDimensions reads the dimensions of a stored image (best-effort). Returns nil dimensions for non-image content or
+// when the header cannot be decoded.
+func (h *AttachmentHandler) imageDimensions(ctx context.Context, key, contentType string) (width, height *int) {
+	if !media.IsImageContentType(contentType) {
+		return nil, nil
+	}
+	rc, err := h.storage.Get(ctx, key)
+	if err != nil {
+		return nil, nil
+	}
+	defer func() { _ = rc.Close() }()
+
+	cfg, _, err := image.DecodeConfig(rc)
+	if err != nil {
+		return nil, nil
+	}
+	w, ht := cfg.Width, cfg.Height
+	return &w, &ht
+}
+
+// deleteKeys removes stored chunk files (best-effort). Anything left behind is unreferenced and harmless.
+func (h *AttachmentHandler) deleteKeys(keys []string) {
+	for _, key := range keys {
+		if err := h.storage.Delete(context.Background(), key); err != nil {
+			h.log.Warn().Err(err).Str("key", key).Msg("Failed to delete upload chunk")
+		}
+	}
+}
+
+// toUploadModel converts an upload session to its response type.
+func (h *AttachmentHandler) toUploadModel(u *attachment.Upload) uploadModel {
+	return uploadModel{
+		ID:           u.ID.String(),
+		Filename:     u.Filename,
+		ContentType:  u.ContentType,
+		Size:         u.SizeBytes,
+		Offset:       u.ReceivedBytes,
+		MaxChunkSize: h.maxChunkBytes,
+		ExpiresAt:    u.UpdatedAt.Add(h.uploadTTL).UTC().Format(time.RFC3339),
+	}
+}
+
+// chunkReader reads stored upload chunks back to back as a single stream, opening each chunk only when the previous
+// one is exhausted so that at most one storage handle is open at a time.
+type chunkReader struct {
+	ctx     context.Context
+	storage media.StorageProvider
+	keys    []string
+	cur     io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := r.storage.Get(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("open upload chunk %s: %w", r.keys[0], err)
+			}
+			r.cur = rc
+			r.keys = r.keys[1:]
+		}
+
+		n, err := r.cur.Read(p)
+		if errors.Is(err, io.EOF) {
+			_ = r.cur.Close()
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close releases the chunk currently being read, if any.
+func (r *chunkReader) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	err := r.cur.Close()
+	r.cur = nil
+	return err
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -243,6 +243,8 @@ func mapAuthServiceError(c fiber.Ctx, err error, log zerolog.Logger, handler str
 		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidEmail, "Unable to register with the provided email")
 	case errors.Is(err, auth.ErrInvalidCredentials):
 		return httputil.Fail(c, fiber.StatusUnauthorized, apierrors.InvalidCredentials, err.Error())
+	case errors.Is(err, auth.ErrMFARequired):
+		return httputil.Fail(c, fiber.StatusUnauthorized, apierrors.MFARequired, err.Error())
 	case errors.Is(err, auth.ErrInvalidMFACode):
 		return httputil.Fail(c, fiber.StatusUnauthorized, apierrors.InvalidMFACode, err.Error())
 	case errors.Is(err, auth.ErrMFANotEnabled):
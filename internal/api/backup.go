@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/audit"
+	"github.com/uncord-chat/uncord-server/internal/backup"
+	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/server"
+)
+
+// BackupExporter writes community snapshots. Satisfied by *backup.Exporter.
+type BackupExporter interface {
+	Export(ctx context.Context, w io.Writer, opts backup.ExportOptions) (*backup.Manifest, error)
+}
+
+var _ BackupExporter = (*backup.Exporter)(nil)
+
+// BackupHandler serves the server export endpoint.
+type BackupHandler struct {
+	exporter    BackupExporter
+	servers     server.Repository
+	auditLogger *audit.Logger
+	exporting   atomic.Bool
+	log         zerolog.Logger
+}
+
+// NewBackupHandler creates a new backup handler.
+func NewBackupHandler(exporter BackupExporter, servers server.Repository, auditLogger *audit.Logger,
+	logger zerolog.Logger) *BackupHandler {
+	return &BackupHandler{exporter: exporter, servers: servers, auditLogger: auditLogger, log: logger}
+}
+
+// Export handles GET /api/v1/server/export by streaming a backup archive of the community. Only the server owner may
+// export. Archives made through the API never include credentials or contact details, so accounts restored from one
+// need their passwords reset and their email addresses set again. Each export holds a database snapshot open until the
+// download finishes, so only one runs at a time.
+func (h *BackupHandler) Export(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	srv, err := h.servers.Get(c)
+	if err != nil {
+		h.log.Error().Err(err).Str("handler", "backup").Msg("get server config failed")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+	if srv.OwnerID != userID {
+		return httputil.Fail(c, fiber.StatusForbidden, apierrors.OwnerOnly, "Only the server owner can export the server")
+	}
+
+	if !h.exporting.CompareAndSwap(false, true) {
+		return httputil.Fail(c, fiber.StatusConflict, apierrors.AlreadyExists, "A server export is already running")
+	}
+
+	if h.auditLogger != nil {
+		go h.auditLogger.Record(context.Background(), audit.Entry{
+			ActorID: audit.UUIDPtr(userID), Action: audit.ServerExport,
+			TargetType: audit.Ptr("server"),
+		})
+	}
+
+	filename := "uncord-backup-" + time.Now().UTC().Format("20060102T150405Z") + ".zip"
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer h.exporting.Store(false)
+		// The response outlives the request context, so the export runs on its own. A client that disconnects makes
+		// the next write fail, which ends the export.
+		manifest, err := h.exporter.Export(context.Background(), w, backup.ExportOptions{})
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			h.log.Error().Err(err).Msg("Server export failed")
+			return
+		}
+		h.log.Info().Int64("files", manifest.Blobs.Count).Int("missing_files", len(manifest.Blobs.Missing)).
+			Msg("Server export complete")
+	})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/backup"
+	"github.com/uncord-chat/uncord-server/internal/server"
+)
+
+// fakeBackupExporter implements BackupExporter for handler tests.
+type fakeBackupExporter struct {
+	opts  *backup.ExportOptions
+	calls int
+	err   error
+}
+
+func (e *fakeBackupExporter) Export(_ context.Context, w io.Writer, opts backup.ExportOptions) (*backup.Manifest,
+	error) {
+	e.calls++
+	e.opts = &opts
+	if e.err != nil {
+		return nil, e.err
+	}
+	_, _ = io.WriteString(w, "archive")
+	return &backup.Manifest{}, nil
+}
+
+func testBackupApp(t *testing.T, exporter BackupExporter, ownerID, userID uuid.UUID) (*fiber.App, *BackupHandler) {
+	t.Helper()
+	servers := &fakeServerRepo{cfg: &server.Config{ID: uuid.New(), Name: "Test", OwnerID: ownerID}}
+	handler := NewBackupHandler(exporter, servers, nil, zerolog.Nop())
+	app := fiber.New()
+	app.Get("/server/export", fakeAuth(userID), handler.Export)
+	return app, handler
+}
+
+func TestBackupExport_Owner(t *testing.T) {
+	t.Parallel()
+	owner := uuid.New()
+	exporter := &fakeBackupExporter{}
+	app, handler := testBackupApp(t, exporter, owner, owner)
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/server/export", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", resp.StatusCode, fiber.StatusOK, body)
+	}
+	if string(body) != "archive" {
+		t.Errorf("body = %q, want the archive", body)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentType); got != "application/zip" {
+		t.Errorf("Content-Type = %q, want application/zip", got)
+	}
+	got := resp.Header.Get(fiber.HeaderContentDisposition)
+	if !strings.HasPrefix(got, `attachment; filename="uncord-backup-`) {
+		t.Errorf("Content-Disposition = %q, want an attachment", got)
+	}
+	if exporter.opts == nil || exporter.opts.Credentials {
+		t.Errorf("export options = %+v, want credentials excluded", exporter.opts)
+	}
+	if handler.exporting.Load() {
+		t.Error("export still marked as running after it finished")
+	}
+}
+
+func TestBackupExport_NotOwner(t *testing.T) {
+	t.Parallel()
+	exporter := &fakeBackupExporter{}
+	app, _ := testBackupApp(t, exporter, uuid.New(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/server/export", ""))
+	env := parseError(t, readBody(t, resp))
+
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+	if env.Error.Code != string(apierrors.OwnerOnly) {
+		t.Errorf("code = %q, want %q", env.Error.Code, apierrors.OwnerOnly)
+	}
+	if exporter.calls != 0 {
+		t.Errorf("exporter called %d times, want 0", exporter.calls)
+	}
+}
+
+func TestBackupExport_AlreadyRunning(t *testing.T) {
+	t.Parallel()
+	owner := uuid.New()
+	exporter := &fakeBackupExporter{}
+	app, handler := testBackupApp(t, exporter, owner, owner)
+	handler.exporting.Store(true)
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/server/export", ""))
+	env := parseError(t, readBody(t, resp))
+
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusConflict)
+	}
+	if env.Error.Code != string(apierrors.AlreadyExists) {
+		t.Errorf("code = %q, want %q", env.Error.Code, apierrors.AlreadyExists)
+	}
+	if exporter.calls != 0 {
+		t.Errorf("exporter called %d times, want 0", exporter.calls)
+	}
+}
+
+func TestBackupExport_FailureReleasesLock(t *testing.T) {
+	t.Parallel()
+	owner := uuid.New()
+	exporter := &fakeBackupExporter{err: errors.New("snapshot failed")}
+	app, handler := testBackupApp(t, exporter, owner, owner)
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/server/export", ""))
+	_ = readBody(t, resp)
+
+	if handler.exporting.Load() {
+		t.Error("export still marked as running after it failed")
+	}
+}
@@ -207,7 +207,7 @@ func (h *ChannelHandler) CreateChannel(c fiber.Ctx) error {
 
 	result := ch.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ChannelCreate, result)
+		h.gateway.Enqueue(c.Context(), events.ChannelCreate, result)
 	}
 
 	return httputil.SuccessStatus(c, fiber.StatusCreated, result)
@@ -293,7 +293,7 @@ func (h *ChannelHandler) UpdateChannel(c fiber.Ctx) error {
 
 	result := ch.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ChannelUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.ChannelUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -323,7 +323,7 @@ func (h *ChannelHandler) DeleteChannel(c fiber.Ctx) error {
 	}
 
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ChannelDelete, models.ChannelDeleteData{ID: id.String()})
+		h.gateway.Enqueue(c.Context(), events.ChannelDelete, models.ChannelDeleteData{ID: id.String()})
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -12,39 +13,54 @@ import (
 	"github.com/uncord-chat/uncord-protocol/events"
 	"github.com/uncord-chat/uncord-protocol/models"
 
+	"github.com/uncord-chat/uncord-server/internal/attachment"
 	"github.com/uncord-chat/uncord-server/internal/dm"
 	"github.com/uncord-chat/uncord-server/internal/e2ee"
 	"github.com/uncord-chat/uncord-server/internal/gateway"
 	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/media"
 	"github.com/uncord-chat/uncord-server/internal/message"
 )
 
 // DMHandler serves DM channel endpoints.
 type DMHandler struct {
-	dms        dm.Repository
-	messages   message.Repository
-	e2eeKeys   e2ee.Repository
-	gateway    *gateway.Publisher
-	maxContent int
-	log        zerolog.Logger
+	dms            dm.Repository
+	messages       message.Repository
+	e2eeKeys       e2ee.Repository
+	attachments    attachment.Repository
+	storage        media.StorageProvider
+	gateway        *gateway.Publisher
+	maxContent     int
+	maxAttachments int
+	maxUploadBytes int64
+	log            zerolog.Logger
 }
 
-// NewDMHandler creates a new DM handler.
+// NewDMHandler creates a new DM handler. maxAttachments bounds the attachments on one message and maxUploadBytes the
+// size of one encrypted attachment.
 func NewDMHandler(
 	dms dm.Repository,
 	messages message.Repository,
 	e2eeKeys e2ee.Repository,
+	attachments attachment.Repository,
+	storage media.StorageProvider,
 	gw *gateway.Publisher,
 	maxContent int,
+	maxAttachments int,
+	maxUploadBytes int64,
 	logger zerolog.Logger,
 ) *DMHandler {
 	return &DMHandler{
-		dms:        dms,
-		messages:   messages,
-		e2eeKeys:   e2eeKeys,
-		gateway:    gw,
-		maxContent: maxContent,
-		log:        logger,
+		dms:            dms,
+		messages:       messages,
+		e2eeKeys:       e2eeKeys,
+		attachments:    attachments,
+		storage:        storage,
+		gateway:        gw,
+		maxContent:     maxContent,
+		maxAttachments: maxAttachments,
+		maxUploadBytes: maxUploadBytes,
+		log:            logger,
 	}
 }
 
@@ -118,7 +134,7 @@ func (h *DMHandler) CreateDMChannel(c fiber.Ctx) error {
 		}
 		participantUserIDs := participantUserIDs(participants)
 		if h.gateway != nil {
-			h.gateway.EnqueueTargeted(events.DMChannelCreate, dmChannelToModel(ch), participantUserIDs)
+			h.gateway.EnqueueTargeted(c.Context(), events.DMChannelCreate, dmChannelToModel(ch), participantUserIDs)
 		}
 
 		return httputil.SuccessStatus(c, fiber.StatusCreated, dmChannelToModel(ch))
@@ -139,7 +155,7 @@ func (h *DMHandler) CreateDMChannel(c fiber.Ctx) error {
 	}
 
 	if h.gateway != nil {
-		h.gateway.EnqueueTargeted(events.DMChannelCreate, dmChannelToModel(ch), []uuid.UUID{userID, recipientID})
+		h.gateway.EnqueueTargeted(c.Context(), events.DMChannelCreate, dmChannelToModel(ch), []uuid.UUID{userID, recipientID})
 	}
 
 	return httputil.SuccessStatus(c, fiber.StatusCreated, dmChannelToModel(ch))
@@ -289,7 +305,7 @@ func (h *DMHandler) ListMessages(c fiber.Ctx) error {
 	// Resolve the requesting device for per-device message key delivery.
 	deviceRowID := h.resolveDeviceRowID(c)
 
-	result := make([]models.Message, len(messages))
+	result := make([]dmMessageModel, len(messages))
 	msgIDs := make([]uuid.UUID, len(messages))
 	for i := range messages {
 		msgIDs[i] = messages[i].ID
@@ -304,8 +320,14 @@ func (h *DMHandler) ListMessages(c fiber.Ctx) error {
 		}
 	}
 
+	attachMap, err := h.attachments.ListDMByMessages(c, msgIDs)
+	if err != nil {
+		h.log.Error().Err(err).Msg("fetch dm attachments batch failed")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
 	for i := range messages {
-		result[i] = dmMessageToModel(&messages[i], keyMap)
+		result[i] = dmMessageToModel(&messages[i], keyMap, attachMap[messages[i].ID])
 	}
 	return httputil.Success(c, result)
 }
@@ -321,14 +343,32 @@ func (h *DMHandler) SendMessage(c fiber.Ctx) error {
 		return nil
 	}
 
-	var body models.CreateDMMessageRequest
+	var body createDMMessageRequest
 	if err := c.Bind().Body(&body); err != nil {
 		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidBody, "Invalid request body")
 	}
 
+	if len(body.AttachmentIDs) > h.maxAttachments {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+			fmt.Sprintf("Too many attachments (maximum %d)", h.maxAttachments))
+	}
+	var attachmentIDs []uuid.UUID
+	for _, raw := range body.AttachmentIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError, "Invalid attachment_ids format")
+		}
+		attachmentIDs = append(attachmentIDs, id)
+	}
+
+	// Content is required only when no attachments are provided.
 	content, err := message.ValidateContent(body.Content, h.maxContent)
 	if err != nil {
-		return mapMessageError(c, err, h.log)
+		if errors.Is(err, message.ErrEmptyContent) && len(attachmentIDs) > 0 {
+			content = ""
+		} else {
+			return mapMessageError(c, err, h.log)
+		}
 	}
 
 	var replyToID *uuid.UUID
@@ -351,6 +391,15 @@ func (h *DMHandler) SendMessage(c fiber.Ctx) error {
 		return mapMessageError(c, err, h.log)
 	}
 
+	// Link pending encrypted attachments to the new message.
+	var linked []attachment.DMAttachment
+	if len(attachmentIDs) > 0 {
+		linked, err = h.attachments.LinkDMToMessage(c, attachmentIDs, msg.ID, channelID, userID)
+		if err != nil {
+			return mapAttachmentError(c, err, h.log)
+		}
+	}
+
 	// Store per-device encrypted message keys if provided.
 	if len(body.MessageKeys) > 0 && h.e2eeKeys != nil {
 		keys := make(map[uuid.UUID][]byte, len(body.MessageKeys))
@@ -372,6 +421,8 @@ func (h *DMHandler) SendMessage(c fiber.Ctx) error {
 		}
 	}
 
+	result := dmMessageToModel(msg, nil, linked)
+
 	// Dispatch to participants.
 	if h.gateway != nil {
 		participants, pErr := h.dms.ListParticipants(c, channelID)
@@ -379,15 +430,15 @@ func (h *DMHandler) SendMessage(c fiber.Ctx) error {
 			h.log.Error().Err(pErr).Msg("list participants for dm message create event failed")
 		}
 		targets := participantUserIDs(participants)
-		msgModel := dmMessageToModel(msg, nil)
-		h.gateway.EnqueueTargeted(events.DMMessageCreate, msgModel, targets)
+		h.gateway.EnqueueTargeted(c.Context(), events.DMMessageCreate, result, targets)
 	}
 
-	return httputil.SuccessStatus(c, fiber.StatusCreated, dmMessageToModel(msg, nil))
+	return httputil.SuccessStatus(c, fiber.StatusCreated, result)
 }
 
 // resolveDeviceRowID extracts the server-assigned device row ID from the X-Device-ID header. The header carries the
-// client-generated device UUID, which is resolved to the server-assigned row PK via the user_devices table.
+// client-generated device UUID, which is resolved to the server-assigned row PK via the user_devices table. A resolved
+// device is recorded as in use.
 func (h *DMHandler) resolveDeviceRowID(c fiber.Ctx) *uuid.UUID {
 	raw := c.Get("X-Device-ID")
 	if raw == "" {
@@ -405,6 +456,9 @@ func (h *DMHandler) resolveDeviceRowID(c fiber.Ctx) *uuid.UUID {
 	if err != nil {
 		return nil
 	}
+	if err := h.e2eeKeys.TouchDevice(c, dev.ID); err != nil {
+		h.log.Warn().Err(err).Msg("touch device failed")
+	}
 	return &dev.ID
 }
 
@@ -455,9 +509,9 @@ func dmChannelToModel(ch *dm.Channel) dmChannelModel {
 	return m
 }
 
-// dmMessageToModel converts a message to a protocol model, attaching the requesting device's encrypted key if
-// available. The keyMap is keyed by message ID.
-func dmMessageToModel(m *message.Message, keyMap map[uuid.UUID][]byte) models.Message {
+// dmMessageToModel converts a message to a response model with its encrypted attachments, attaching the requesting
+// device's encrypted key if available. The keyMap is keyed by message ID.
+func dmMessageToModel(m *message.Message, keyMap map[uuid.UUID][]byte, attachments []attachment.DMAttachment) dmMessageModel {
 	var replyToID *string
 	if m.ReplyToID != nil {
 		s := m.ReplyToID.String()
@@ -478,14 +532,13 @@ func dmMessageToModel(m *message.Message, keyMap map[uuid.UUID][]byte) models.Me
 			DisplayName: m.AuthorDisplayName,
 			AvatarKey:   m.AuthorAvatarKey,
 		},
-		Content:     m.Content,
-		Attachments: []models.Attachment{},
-		Reactions:   []models.ReactionSummary{},
-		ReplyToID:   replyToID,
-		Pinned:      m.Pinned,
-		Encrypted:   m.Encrypted,
-		EditedAt:    editedAt,
-		CreatedAt:   m.CreatedAt.Format(time.RFC3339),
+		Content:   m.Content,
+		Reactions: []models.ReactionSummary{},
+		ReplyToID: replyToID,
+		Pinned:    m.Pinned,
+		Encrypted: m.Encrypted,
+		EditedAt:  editedAt,
+		CreatedAt: m.CreatedAt.Format(time.RFC3339),
 	}
 
 	if key, ok := keyMap[m.ID]; ok {
@@ -495,7 +548,11 @@ func dmMessageToModel(m *message.Message, keyMap map[uuid.UUID][]byte) models.Me
 		}}
 	}
 
-	return msg
+	modelAttachments := make([]dmAttachmentModel, len(attachments))
+	for i := range attachments {
+		modelAttachments[i] = toDMAttachmentModel(&attachments[i])
+	}
+	return dmMessageModel{Message: msg, Attachments: modelAttachments}
 }
 
 // participantUserIDs extracts user IDs from a slice of participants.
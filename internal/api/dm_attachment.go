@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+	"github.com/uncord-chat/uncord-protocol/models"
+
+	"github.com/uncord-chat/uncord-server/internal/attachment"
+	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/media"
+)
+
+// dmAttachmentModel describes an end-to-end encrypted DM attachment to the client. The filename, content type, and file
+// key are inside EncryptedMetadata, which is returned exactly as the uploader sent it, so the protocol Attachment type
+// does not fit. URL is the participant-only download endpoint rather than a /media URL.
+type dmAttachmentModel struct {
+	ID                string `json:"id"`
+	URL               string `json:"url"`
+	Size              int64  `json:"size"`
+	Encrypted         bool   `json:"encrypted"`
+	EncryptedMetadata string `json:"encrypted_metadata"`
+	CreatedAt         string `json:"created_at"`
+}
+
+// dmMessageModel is the DM message response type. It embeds the protocol message and shadows its Attachments field
+// with dmAttachmentModel.
+type dmMessageModel struct {
+	models.Message
+	Attachments []dmAttachmentModel `json:"attachments"`
+}
+
+// createDMMessageRequest is the body of a DM message send request. The protocol module's request has no attachment IDs,
+// so they are added here.
+type createDMMessageRequest struct {
+	models.CreateDMMessageRequest
+	AttachmentIDs []string `json:"attachment_ids"`
+}
+
+// UploadAttachment handles POST /api/v1/dm/:channelID/attachments. The multipart form carries the client-encrypted file
+// in "file" and the encrypted metadata, base64-encoded, in "metadata". The server cannot read either, so the file is
+// stored as it arrives: its content type is not detected, it is not scanned or thumbnailed, and no media jobs run. The
+// attachment stays pending, and visible only to the uploader, until it is linked to a message.
+func (h *DMHandler) UploadAttachment(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+	channelID, ok := httputil.ParseUUIDParam(c, "channelID", apierrors.InvalidChannelID)
+	if !ok {
+		return nil
+	}
+
+	metadata, err := base64.RawStdEncoding.DecodeString(c.FormValue("metadata"))
+	if err != nil || len(metadata) == 0 {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+			"metadata must be non-empty base64-encoded encrypted metadata")
+	}
+	if len(metadata) > attachment.MaxEncryptedMetadataBytes {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+			fmt.Sprintf("Encrypted metadata exceeds the maximum of %d bytes", attachment.MaxEncryptedMetadataBytes))
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidBody, "Missing file field in multipart form")
+	}
+	if fh.Size > h.maxUploadBytes {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.PayloadTooLarge,
+			fmt.Sprintf("File size exceeds the maximum of %d MB", h.maxUploadBytes/(1024*1024)))
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to open uploaded dm attachment")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+	defer func() { _ = f.Close() }()
+
+	// No extension: the key must not suggest a content type for a file the server cannot read.
+	storageKey := fmt.Sprintf("%s%s/%s", media.DMAttachmentKeyPrefix, channelID.String(), uuid.New().String())
+	if err := h.storage.Put(c.Context(), storageKey, f); err != nil {
+		h.log.Error().Err(err).Msg("Failed to write dm attachment to storage")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	a, err := h.attachments.CreateDM(c, attachment.CreateDMParams{
+		DMChannelID:       channelID,
+		UploaderID:        userID,
+		SizeBytes:         fh.Size,
+		StorageKey:        storageKey,
+		EncryptedMetadata: metadata,
+	})
+	if err != nil {
+		// Best-effort cleanup of the stored file.
+		_ = h.storage.Delete(c.Context(), storageKey)
+		h.log.Error().Err(err).Msg("Failed to create dm attachment record")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	return httputil.SuccessStatus(c, fiber.StatusCreated, toDMAttachmentModel(a))
+}
+
+// GetAttachment handles GET /api/v1/dm/:channelID/attachments/:attachmentID. It streams the encrypted file to a
+// participant of the DM channel. A pending attachment is only served to its uploader.
+func (h *DMHandler) GetAttachment(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+	channelID, ok := httputil.ParseUUIDParam(c, "channelID", apierrors.InvalidChannelID)
+	if !ok {
+		return nil
+	}
+	attachmentID, ok := httputil.ParseUUIDParam(c, "attachmentID", apierrors.UnknownAttachment)
+	if !ok {
+		return nil
+	}
+
+	a, err := h.attachments.GetDM(c, attachmentID, channelID)
+	if errors.Is(err, attachment.ErrNotFound) || (err == nil && a.MessageID == nil && a.UploaderID != userID) {
+		return httputil.Fail(c, fiber.StatusNotFound, apierrors.UnknownAttachment, "Attachment not found")
+	}
+	if err != nil {
+		h.log.Error().Err(err).Msg("get dm attachment failed")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	rc, err := h.storage.Get(c.Context(), a.StorageKey)
+	if err != nil {
+		h.log.Error().Err(err).Str("key", a.StorageKey).Msg("Failed to open dm attachment")
+		return httputil.Fail(c, fiber.StatusNotFound, apierrors.UnknownAttachment, "Attachment not found")
+	}
+
+	// Whether the requester may read the file depends on their current participation, so it is not cached.
+	c.Set("Content-Type", "application/octet-stream")
+	c.Set("Content-Disposition", "attachment")
+	c.Set("X-Content-Type-Options", "nosniff")
+	c.Set("Cache-Control", "private, no-store")
+	return c.SendStream(rc)
+}
+
+// toDMAttachmentModel converts an internal DM attachment to the response type. The encrypted metadata is
+// base64-encoded.
+func toDMAttachmentModel(a *attachment.DMAttachment) dmAttachmentModel {
+	return dmAttachmentModel{
+		ID:                a.ID.String(),
+		URL:               fmt.Sprintf("/api/v1/dm/%s/attachments/%s", a.DMChannelID, a.ID),
+		Size:              a.SizeBytes,
+		Encrypted:         true,
+		EncryptedMetadata: base64.RawStdEncoding.EncodeToString(a.EncryptedMetadata),
+		CreatedAt:         a.CreatedAt.Format(time.RFC3339),
+	}
+}
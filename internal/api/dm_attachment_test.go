@@ -0,0 +1,294 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/uncord-chat/uncord-server/internal/dm"
+	"github.com/uncord-chat/uncord-server/internal/media"
+)
+
+// fakeDMRepo implements the dm.Repository methods used by the DM attachment routes. Calling any other method panics.
+type fakeDMRepo struct {
+	dm.Repository
+	participants map[uuid.UUID][]uuid.UUID
+}
+
+func (r *fakeDMRepo) IsParticipant(_ context.Context, channelID, userID uuid.UUID) (bool, error) {
+	for _, id := range r.participants[channelID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeDMRepo) ListParticipants(_ context.Context, channelID uuid.UUID) ([]dm.Participant, error) {
+	var result []dm.Participant
+	for _, id := range r.participants[channelID] {
+		result = append(result, dm.Participant{UserID: id})
+	}
+	return result, nil
+}
+
+// dmAttachmentFixture holds the repositories shared by the apps of several DM participants.
+type dmAttachmentFixture struct {
+	dms         *fakeDMRepo
+	messages    *fakeMessageRepo
+	attachments *fakeAttachmentRepo
+	storage     *fakeStorageForUpload
+	channelID   uuid.UUID
+	alice, bob  uuid.UUID
+}
+
+func newDMAttachmentFixture() *dmAttachmentFixture {
+	f := &dmAttachmentFixture{
+		messages:    newFakeMessageRepo(),
+		attachments: newFakeAttachmentRepo(),
+		storage:     newFakeStorageForUpload(),
+		channelID:   uuid.New(),
+		alice:       uuid.New(),
+		bob:         uuid.New(),
+	}
+	f.dms = &fakeDMRepo{participants: map[uuid.UUID][]uuid.UUID{f.channelID: {f.alice, f.bob}}}
+	return f
+}
+
+// app returns an app that authenticates every request as userID.
+func (f *dmAttachmentFixture) app(t *testing.T, userID uuid.UUID) *fiber.App {
+	t.Helper()
+	handler := NewDMHandler(f.dms, f.messages, nil, f.attachments, f.storage, nil, 4000, 2, 1024*1024, zerolog.Nop())
+	app := fiber.New()
+	app.Use(fakeAuth(userID))
+	app.Get("/dm/:channelID/messages", handler.RequireParticipant, handler.ListMessages)
+	app.Post("/dm/:channelID/messages", handler.RequireParticipant, handler.SendMessage)
+	app.Post("/dm/:channelID/attachments", handler.RequireParticipant, handler.UploadAttachment)
+	app.Get("/dm/:channelID/attachments/:attachmentID", handler.RequireParticipant, handler.GetAttachment)
+	return app
+}
+
+func encryptedUploadReq(t *testing.T, url string, content []byte, metadata string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if metadata != "" {
+		if err := writer.WriteField("metadata", metadata); err != nil {
+			t.Fatalf("write metadata field: %v", err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", "blob")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		t.Fatalf("write file content: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, url, &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// upload stores an encrypted attachment as userID and returns the response model.
+func (f *dmAttachmentFixture) upload(t *testing.T, userID uuid.UUID, content, metadata []byte) dmAttachmentModel {
+	t.Helper()
+	url := "/dm/" + f.channelID.String() + "/attachments"
+	req := encryptedUploadReq(t, url, content, base64.RawStdEncoding.EncodeToString(metadata))
+	resp := doReq(t, f.app(t, userID), req)
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("upload status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusCreated, body)
+	}
+	var a dmAttachmentModel
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &a); err != nil {
+		t.Fatalf("unmarshal dm attachment: %v", err)
+	}
+	return a
+}
+
+func TestDMUploadAttachment_StoresOpaqueBlob(t *testing.T) {
+	t.Parallel()
+	f := newDMAttachmentFixture()
+
+	// Content that would be sniffed as an image if the server looked at it.
+	content := []byte("\x89PNG\r\n\x1a\nciphertext")
+	metadata := []byte{0x00, 0xff, 0x10, 0x80}
+	a := f.upload(t, f.alice, content, metadata)
+
+	if !a.Encrypted {
+		t.Error("encrypted = false, want true")
+	}
+	if a.Size != int64(len(content)) {
+		t.Errorf("size = %d, want %d", a.Size, len(content))
+	}
+	if a.EncryptedMetadata != base64.RawStdEncoding.EncodeToString(metadata) {
+		t.Errorf("encrypted_metadata = %q, want the uploaded metadata unchanged", a.EncryptedMetadata)
+	}
+	want := fmt.Sprintf("/api/v1/dm/%s/attachments/%s", f.channelID, a.ID)
+	if a.URL != want {
+		t.Errorf("url = %q, want %q", a.URL, want)
+	}
+
+	if len(f.attachments.dmAttachments) != 1 {
+		t.Fatalf("stored %d dm attachments, want 1", len(f.attachments.dmAttachments))
+	}
+	key := f.attachments.dmAttachments[0].StorageKey
+	if !media.IsRestrictedKey(key) || strings.Contains(key[len(media.DMAttachmentKeyPrefix):], ".") {
+		t.Errorf("storage key = %q, want a restricted key without an extension", key)
+	}
+	if !bytes.Equal(f.storage.files[key], content) {
+		t.Error("stored file differs from the uploaded bytes")
+	}
+	if len(f.attachments.attachments) != 0 {
+		t.Error("the upload was also recorded as a server channel attachment")
+	}
+}
+
+func TestDMUploadAttachment_RequiresMetadata(t *testing.T) {
+	t.Parallel()
+	f := newDMAttachmentFixture()
+	url := "/dm/" + f.channelID.String() + "/attachments"
+
+	for _, metadata := range []string{"", "not base64!"} {
+		resp := doReq(t, f.app(t, f.alice), encryptedUploadReq(t, url, []byte("ciphertext"), metadata))
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("metadata %q: status = %d, want %d", metadata, resp.StatusCode, fiber.StatusBadRequest)
+		}
+	}
+	if len(f.storage.files) != 0 {
+		t.Error("a file was stored for a rejected upload")
+	}
+}
+
+func TestDMUploadAttachment_NonParticipant(t *testing.T) {
+	t.Parallel()
+	f := newDMAttachmentFixture()
+	url := "/dm/" + f.channelID.String() + "/attachments"
+	req := encryptedUploadReq(t, url, []byte("ciphertext"), base64.RawStdEncoding.EncodeToString([]byte("meta")))
+
+	resp := doReq(t, f.app(t, uuid.New()), req)
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+	if len(f.storage.files) != 0 {
+		t.Error("a file was stored for a non-participant")
+	}
+}
+
+func TestDMGetAttachment_Access(t *testing.T) {
+	t.Parallel()
+	f := newDMAttachmentFixture()
+	content := []byte("ciphertext")
+	a := f.upload(t, f.alice, content, []byte("meta"))
+	url := "/dm/" + f.channelID.String() + "/attachments/" + a.ID
+
+	// A pending attachment is only visible to its uploader.
+	resp := doReq(t, f.app(t, f.bob), httptest.NewRequestWithContext(context.Background(), http.MethodGet, url, nil))
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("pending attachment as other participant: status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+
+	msgID := uuid.New()
+	f.attachments.dmAttachments[0].MessageID = &msgID
+
+	resp = doReq(t, f.app(t, f.bob), httptest.NewRequestWithContext(context.Background(), http.MethodGet, url, nil))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("linked attachment as participant: status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if !bytes.Equal(body, content) {
+		t.Errorf("body = %q, want %q", body, content)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("Content-Type = %q, want application/octet-stream", ct)
+	}
+	if resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("X-Content-Type-Options is not nosniff")
+	}
+
+	resp = doReq(t, f.app(t, uuid.New()), httptest.NewRequestWithContext(context.Background(), http.MethodGet, url, nil))
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("non-participant: status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+
+	// An attachment is not reachable through another DM channel, even one the requester belongs to.
+	other := uuid.New()
+	f.dms.participants[other] = []uuid.UUID{f.bob}
+	otherURL := "/dm/" + other.String() + "/attachments/" + a.ID
+	resp = doReq(t, f.app(t, f.bob), httptest.NewRequestWithContext(context.Background(), http.MethodGet, otherURL, nil))
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("other channel: status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+}
+
+func TestDMSendMessage_LinksAttachments(t *testing.T) {
+	t.Parallel()
+	f := newDMAttachmentFixture()
+	a := f.upload(t, f.alice, []byte("ciphertext"), []byte("meta"))
+	url := "/dm/" + f.channelID.String() + "/messages"
+
+	reqBody := fmt.Sprintf(`{"attachment_ids":[%q]}`, a.ID)
+	resp := doReq(t, f.app(t, f.alice), jsonReq(http.MethodPost, url, reqBody))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("send status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusCreated, body)
+	}
+	var msg dmMessageModel
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &msg); err != nil {
+		t.Fatalf("unmarshal dm message: %v", err)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].ID != a.ID || !msg.Attachments[0].Encrypted {
+		t.Fatalf("attachments = %+v, want the encrypted upload", msg.Attachments)
+	}
+
+	resp = doReq(t, f.app(t, f.bob), jsonReq(http.MethodGet, url, ""))
+	body = readBody(t, resp)
+	var listed []dmMessageModel
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &listed); err != nil {
+		t.Fatalf("unmarshal dm messages: %v", err)
+	}
+	if len(listed) != 1 || len(listed[0].Attachments) != 1 {
+		t.Fatalf("listed messages = %+v, want one message with one attachment", listed)
+	}
+	if listed[0].Attachments[0].EncryptedMetadata != a.EncryptedMetadata {
+		t.Errorf("listed encrypted_metadata = %q, want %q", listed[0].Attachments[0].EncryptedMetadata,
+			a.EncryptedMetadata)
+	}
+
+	// A linked attachment cannot be attached to a second message.
+	resp = doReq(t, f.app(t, f.alice), jsonReq(http.MethodPost, url, reqBody))
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("relink: status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+}
+
+func TestDMSendMessage_RejectsOtherUploadersAttachment(t *testing.T) {
+	t.Parallel()
+	f := newDMAttachmentFixture()
+	a := f.upload(t, f.alice, []byte("ciphertext"), []byte("meta"))
+	url := "/dm/" + f.channelID.String() + "/messages"
+
+	reqBody := fmt.Sprintf(`{"attachment_ids":[%q]}`, a.ID)
+	resp := doReq(t, f.app(t, f.bob), jsonReq(http.MethodPost, url, reqBody))
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+	if f.attachments.dmAttachments[0].MessageID != nil {
+		t.Error("another participant's upload was linked")
+	}
+}
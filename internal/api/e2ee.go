@@ -12,15 +12,60 @@ import (
 	"github.com/uncord-chat/uncord-protocol/events"
 	"github.com/uncord-chat/uncord-protocol/models"
 
+	"github.com/uncord-chat/uncord-server/internal/auth"
 	"github.com/uncord-chat/uncord-server/internal/dm"
 	"github.com/uncord-chat/uncord-server/internal/e2ee"
 	"github.com/uncord-chat/uncord-server/internal/gateway"
 	"github.com/uncord-chat/uncord-server/internal/httputil"
 )
 
+// uploadMasterKeyRequest is the body for PUT /api/v1/users/@me/master-key. Password, and Code when the user has MFA
+// enabled, are only required to replace an existing key.
+type uploadMasterKeyRequest struct {
+	PublicKey string `json:"public_key"`
+	Password  string `json:"password,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// signDeviceRequest is the body for PUT /api/v1/users/@me/devices/:deviceID/signature. Signature is the master key's
+// Ed25519 signature over the device's e2ee.DeviceSignaturePayload.
+type signDeviceRequest struct {
+	Signature string `json:"signature"`
+}
+
+// masterKeyResponse describes a user's master signing key.
+type masterKeyResponse struct {
+	UserID    string `json:"user_id"`
+	PublicKey string `json:"public_key"`
+	CreatedAt string `json:"created_at"`
+}
+
+// deviceKeyBundleResponse extends the protocol's device key bundle with the device's cross-signing state.
+type deviceKeyBundleResponse struct {
+	models.DeviceKeyBundleResponse
+	DeviceSignature string `json:"device_signature,omitempty"`
+	Verification    string `json:"verification"`
+}
+
+// userKeyBundleResponse extends the protocol's user key bundle with the user's master signing key, so that clients can
+// check device signatures themselves rather than trusting the server's verification status.
+type userKeyBundleResponse struct {
+	UserID    string                    `json:"user_id"`
+	MasterKey string                    `json:"master_key,omitempty"`
+	Devices   []deviceKeyBundleResponse `json:"devices"`
+}
+
+// deviceVerificationChangedData is the payload of a DEVICE_VERIFICATION_CHANGED dispatch.
+type deviceVerificationChangedData struct {
+	UserID       string `json:"user_id"`
+	DeviceID     string `json:"device_id"`
+	Verification string `json:"verification"`
+}
+
 // E2EEHandler serves device and key management endpoints for end-to-end encryption.
 type E2EEHandler struct {
 	keys         e2ee.Repository
+	auth         *auth.Service
 	dms          dm.Repository
 	gateway      *gateway.Publisher
 	opkThreshold int
@@ -28,9 +73,10 @@ type E2EEHandler struct {
 }
 
 // NewE2EEHandler creates a new E2EE handler.
-func NewE2EEHandler(keys e2ee.Repository, dms dm.Repository, gw *gateway.Publisher, opkThreshold int, logger zerolog.Logger) *E2EEHandler {
+func NewE2EEHandler(keys e2ee.Repository, authService *auth.Service, dms dm.Repository, gw *gateway.Publisher, opkThreshold int, logger zerolog.Logger) *E2EEHandler {
 	return &E2EEHandler{
 		keys:         keys,
+		auth:         authService,
 		dms:          dms,
 		gateway:      gw,
 		opkThreshold: opkThreshold,
@@ -124,6 +170,8 @@ func (h *E2EEHandler) RemoveDevice(c fiber.Ctx) error {
 		return h.mapE2EEError(c, err)
 	}
 
+	h.notifyDevicesRemoved(c, userID, deviceID)
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -156,6 +204,7 @@ func (h *E2EEHandler) UpdateIdentityKey(c fiber.Ctx) error {
 	if err != nil {
 		return h.mapE2EEError(c, err)
 	}
+	h.touchDevice(c, dev.ID)
 
 	if _, err := h.keys.UpdateIdentityKey(c, dev.ID, identityKey); err != nil {
 		return h.mapE2EEError(c, err)
@@ -163,6 +212,13 @@ func (h *E2EEHandler) UpdateIdentityKey(c fiber.Ctx) error {
 
 	h.notifyIdentityKeyChanged(c, userID, deviceID, identityKey)
 
+	// The device's signature covered the old identity key and was deleted with it.
+	if _, err := h.keys.GetMasterKey(c, userID); err == nil {
+		h.notifyVerificationChanged(c, userID, e2ee.VerificationUnverified, deviceID)
+	} else if !errors.Is(err, e2ee.ErrMasterKeyNotFound) {
+		h.log.Warn().Err(err).Msg("get master key for verification notification failed")
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -203,6 +259,7 @@ func (h *E2EEHandler) UploadSignedPreKey(c fiber.Ctx) error {
 	if err != nil {
 		return h.mapE2EEError(c, err)
 	}
+	h.touchDevice(c, dev.ID)
 
 	if err := h.keys.UploadSignedPreKey(c, e2ee.UploadSignedPreKeyParams{
 		DeviceRowID: dev.ID,
@@ -250,6 +307,7 @@ func (h *E2EEHandler) UploadOneTimePreKeys(c fiber.Ctx) error {
 	if err != nil {
 		return h.mapE2EEError(c, err)
 	}
+	h.touchDevice(c, dev.ID)
 
 	if err := h.keys.UploadOneTimePreKeys(c, dev.ID, keys); err != nil {
 		return h.mapE2EEError(c, err)
@@ -274,6 +332,7 @@ func (h *E2EEHandler) GetKeyCount(c fiber.Ctx) error {
 	if err != nil {
 		return h.mapE2EEError(c, err)
 	}
+	h.touchDevice(c, dev.ID)
 
 	count, err := h.keys.CountOneTimePreKeys(c, dev.ID)
 	if err != nil {
@@ -284,6 +343,123 @@ func (h *E2EEHandler) GetKeyCount(c fiber.Ctx) error {
 	return httputil.Success(c, models.KeyCountResponse{Count: count})
 }
 
+// UploadMasterKey handles PUT /api/v1/users/@me/master-key. Replacing an existing master key leaves every device
+// unverified until it is signed with the new key, so it requires re-authentication: otherwise a stolen session could
+// swap in its own key and sign a device it added.
+func (h *E2EEHandler) UploadMasterKey(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	var body uploadMasterKeyRequest
+	if err := c.Bind().Body(&body); err != nil {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidBody, "Invalid request body")
+	}
+
+	publicKey, err := base64.RawStdEncoding.DecodeString(body.PublicKey)
+	if err != nil {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidKeyMaterial, "Invalid base64 encoding for public_key")
+	}
+	if err := e2ee.ValidatePublicKey(publicKey); err != nil {
+		return h.mapE2EEError(c, err)
+	}
+
+	replace := body.Password != ""
+	if replace {
+		if err := h.auth.Reauthenticate(c, userID, body.Password, body.Code); err != nil {
+			return mapAuthServiceError(c, err, h.log, "e2ee")
+		}
+	}
+
+	key, replaced, err := h.keys.SetMasterKey(c, userID, publicKey, replace)
+	if err != nil {
+		return h.mapE2EEError(c, err)
+	}
+
+	if replaced {
+		devices, lErr := h.keys.ListDevices(c, userID)
+		if lErr != nil {
+			h.log.Warn().Err(lErr).Msg("list devices for verification notification failed")
+		}
+		deviceIDs := make([]uuid.UUID, len(devices))
+		for i := range devices {
+			deviceIDs[i] = devices[i].DeviceID
+		}
+		h.notifyVerificationChanged(c, userID, e2ee.VerificationUnverified, deviceIDs...)
+	}
+
+	return httputil.Success(c, masterKeyToResponse(key))
+}
+
+// GetMasterKey handles GET /api/v1/users/:userID/master-key.
+func (h *E2EEHandler) GetMasterKey(c fiber.Ctx) error {
+	targetUserID, ok := httputil.ParseUUIDParam(c, "userID", apierrors.ValidationError)
+	if !ok {
+		return nil
+	}
+
+	key, err := h.keys.GetMasterKey(c, targetUserID)
+	if err != nil {
+		return h.mapE2EEError(c, err)
+	}
+
+	return httputil.Success(c, masterKeyToResponse(key))
+}
+
+// SignDevice handles PUT /api/v1/users/@me/devices/:deviceID/signature. The signature is checked against the user's
+// current master key and the device's current identity key before it is stored.
+func (h *E2EEHandler) SignDevice(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID, ok := httputil.ParseUUIDParam(c, "deviceID", apierrors.ValidationError)
+	if !ok {
+		return nil
+	}
+
+	var body signDeviceRequest
+	if err := c.Bind().Body(&body); err != nil {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidBody, "Invalid request body")
+	}
+
+	signature, err := base64.RawStdEncoding.DecodeString(body.Signature)
+	if err != nil {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidKeyMaterial, "Invalid base64 encoding for signature")
+	}
+	if err := e2ee.ValidateSignature(signature); err != nil {
+		return h.mapE2EEError(c, err)
+	}
+
+	dev, err := h.keys.GetDeviceByDeviceID(c, userID, deviceID)
+	if err != nil {
+		return h.mapE2EEError(c, err)
+	}
+	master, err := h.keys.GetMasterKey(c, userID)
+	if err != nil {
+		return h.mapE2EEError(c, err)
+	}
+
+	if err := e2ee.VerifyDeviceSignature(master.PublicKey, userID, deviceID, dev.IdentityKey, signature); err != nil {
+		return h.mapE2EEError(c, err)
+	}
+
+	if err := h.keys.SignDevice(c, e2ee.SignDeviceParams{
+		DeviceRowID: dev.ID,
+		MasterKeyID: master.ID,
+		IdentityKey: dev.IdentityKey,
+		Signature:   signature,
+	}); err != nil {
+		return h.mapE2EEError(c, err)
+	}
+
+	h.notifyVerificationChanged(c, userID, e2ee.VerificationVerified, deviceID)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // FetchKeyBundle handles GET /api/v1/users/:userID/keys.
 func (h *E2EEHandler) FetchKeyBundle(c fiber.Ctx) error {
 	targetUserID, ok := httputil.ParseUUIDParam(c, "userID", apierrors.ValidationError)
@@ -305,7 +481,7 @@ func (h *E2EEHandler) FetchKeyBundle(c fiber.Ctx) error {
 				continue
 			}
 			if count <= h.opkThreshold {
-				h.gateway.EnqueueTargeted(events.KeyBundleLow, models.KeyBundleLowData{
+				h.gateway.EnqueueTargeted(c.Context(), events.KeyBundleLow, models.KeyBundleLowData{
 					DeviceID:  db.Device.DeviceID.String(),
 					Remaining: count,
 				}, []uuid.UUID{targetUserID})
@@ -331,18 +507,76 @@ func (h *E2EEHandler) notifyIdentityKeyChanged(c fiber.Ctx, userID, deviceID uui
 		return
 	}
 
-	h.gateway.EnqueueTargeted(events.IdentityKeyChanged, models.IdentityKeyChangedData{
+	h.gateway.EnqueueTargeted(c.Context(), events.IdentityKeyChanged, models.IdentityKeyChangedData{
 		UserID:      userID.String(),
 		DeviceID:    deviceID.String(),
 		IdentityKey: base64.RawStdEncoding.EncodeToString(identityKey),
 	}, peers)
 }
 
+// notifyVerificationChanged fires a DEVICE_VERIFICATION_CHANGED event for each of the given devices to the user and all
+// of their DM peers.
+func (h *E2EEHandler) notifyVerificationChanged(c fiber.Ctx, userID uuid.UUID, verification string, deviceIDs ...uuid.UUID) {
+	if h.gateway == nil || h.dms == nil || len(deviceIDs) == 0 {
+		return
+	}
+
+	peers, err := h.dms.ListDMPeers(c, userID)
+	if err != nil {
+		h.log.Warn().Err(err).Msg("list dm peers for verification notification failed")
+		return
+	}
+	targets := append(peers, userID)
+
+	for _, deviceID := range deviceIDs {
+		h.gateway.EnqueueTargeted(c.Context(), gateway.DeviceVerificationChanged, deviceVerificationChangedData{
+			UserID:       userID.String(),
+			DeviceID:     deviceID.String(),
+			Verification: verification,
+		}, targets)
+	}
+}
+
+// notifyDevicesRemoved fires a USER_DEVICES_UPDATE event to the user and all of their DM peers, so that clients stop
+// encrypting message keys for the removed device.
+func (h *E2EEHandler) notifyDevicesRemoved(c fiber.Ctx, userID, deviceID uuid.UUID) {
+	if h.gateway == nil || h.dms == nil {
+		return
+	}
+
+	peers, err := h.dms.ListDMPeers(c, userID)
+	if err != nil {
+		h.log.Warn().Err(err).Msg("list dm peers for device removal notification failed")
+		return
+	}
+
+	h.gateway.EnqueueTargeted(c.Context(), gateway.UserDevicesUpdate, e2ee.UserDevicesUpdateData{
+		UserID:           userID.String(),
+		RemovedDeviceIDs: []string{deviceID.String()},
+	}, append(peers, userID))
+}
+
+// touchDevice records that a device is in use. A failure is only logged, since it at worst delays the device's removal
+// as stale.
+func (h *E2EEHandler) touchDevice(c fiber.Ctx, deviceRowID uuid.UUID) {
+	if err := h.keys.TouchDevice(c, deviceRowID); err != nil {
+		h.log.Warn().Err(err).Msg("touch device failed")
+	}
+}
+
 // mapE2EEError converts e2ee-layer errors to appropriate HTTP responses.
 func (h *E2EEHandler) mapE2EEError(c fiber.Ctx, err error) error {
 	switch {
-	case errors.Is(err, e2ee.ErrInvalidKeyLength), errors.Is(err, e2ee.ErrInvalidSignature):
+	case errors.Is(err, e2ee.ErrInvalidKeyLength), errors.Is(err, e2ee.ErrInvalidSignature),
+		errors.Is(err, e2ee.ErrSignatureMismatch):
 		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidKeyMaterial, err.Error())
+	case errors.Is(err, e2ee.ErrMasterKeyExists):
+		return httputil.Fail(c, fiber.StatusConflict, apierrors.AlreadyExists,
+			"A different master key already exists; provide your password to replace it")
+	case errors.Is(err, e2ee.ErrSignatureStale):
+		return httputil.Fail(c, fiber.StatusConflict, apierrors.ValidationError, err.Error())
+	case errors.Is(err, e2ee.ErrMasterKeyNotFound):
+		return httputil.Fail(c, fiber.StatusNotFound, apierrors.NotFound, "Master key not found")
 	case errors.Is(err, e2ee.ErrBatchTooLarge):
 		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.OPKBatchTooLarge, err.Error())
 	case errors.Is(err, e2ee.ErrDuplicateKeyID), errors.Is(err, e2ee.ErrDuplicateDevice):
@@ -373,10 +607,20 @@ func deviceToModel(d *e2ee.Device) models.Device {
 	return m
 }
 
-// bundleToModel converts an internal user key bundle to a protocol response model.
-func bundleToModel(b *e2ee.UserKeyBundle) models.UserKeyBundleResponse {
-	devices := make([]models.DeviceKeyBundleResponse, len(b.Devices))
-	for i, db := range b.Devices {
+// masterKeyToResponse converts an internal master key to a response model.
+func masterKeyToResponse(k *e2ee.MasterKey) masterKeyResponse {
+	return masterKeyResponse{
+		UserID:    k.UserID.String(),
+		PublicKey: base64.RawStdEncoding.EncodeToString(k.PublicKey),
+		CreatedAt: k.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// bundleToModel converts an internal user key bundle to a response model, with each device's verification status.
+func bundleToModel(b *e2ee.UserKeyBundle) userKeyBundleResponse {
+	devices := make([]deviceKeyBundleResponse, len(b.Devices))
+	for i := range b.Devices {
+		db := &b.Devices[i]
 		resp := models.DeviceKeyBundleResponse{
 			DeviceID:       db.Device.DeviceID.String(),
 			IdentityKey:    base64.RawStdEncoding.EncodeToString(db.Device.IdentityKey),
@@ -389,10 +633,20 @@ func bundleToModel(b *e2ee.UserKeyBundle) models.UserKeyBundleResponse {
 			opk := base64.RawStdEncoding.EncodeToString(db.OneTimePreKey.PublicKey)
 			resp.OneTimeKey = &opk
 		}
-		devices[i] = resp
+		devices[i] = deviceKeyBundleResponse{
+			DeviceKeyBundleResponse: resp,
+			Verification:            db.Verification(b.MasterKey),
+		}
+		if db.DeviceSignature != nil {
+			devices[i].DeviceSignature = base64.RawStdEncoding.EncodeToString(db.DeviceSignature)
+		}
 	}
-	return models.UserKeyBundleResponse{
+	result := userKeyBundleResponse{
 		UserID:  b.UserID.String(),
 		Devices: devices,
 	}
+	if b.MasterKey != nil {
+		result.MasterKey = base64.RawStdEncoding.EncodeToString(b.MasterKey.PublicKey)
+	}
+	return result
 }
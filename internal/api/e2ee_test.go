@@ -0,0 +1,178 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/auth"
+	"github.com/uncord-chat/uncord-server/internal/disposable"
+	"github.com/uncord-chat/uncord-server/internal/e2ee"
+	"github.com/uncord-chat/uncord-server/internal/permission"
+)
+
+// fakeMasterKeyRepo implements the master key methods of e2ee.Repository. The embedded interface is nil, so any other
+// call panics.
+type fakeMasterKeyRepo struct {
+	e2ee.Repository
+	keys map[uuid.UUID][]byte
+}
+
+func (r *fakeMasterKeyRepo) SetMasterKey(_ context.Context, userID uuid.UUID, publicKey []byte,
+	replace bool) (*e2ee.MasterKey, bool, error) {
+	old, exists := r.keys[userID]
+	changed := exists && !bytes.Equal(old, publicKey)
+	if changed && !replace {
+		return nil, false, e2ee.ErrMasterKeyExists
+	}
+	r.keys[userID] = publicKey
+	return &e2ee.MasterKey{ID: uuid.New(), UserID: userID, PublicKey: publicKey, CreatedAt: time.Now()}, changed, nil
+}
+
+func (r *fakeMasterKeyRepo) ListDevices(context.Context, uuid.UUID) ([]e2ee.Device, error) {
+	return nil, nil
+}
+
+// testMasterKeyApp registers a user and serves UploadMasterKey for them. It returns the auth service so that tests can
+// enable MFA for the user.
+func testMasterKeyApp(t *testing.T) (*fiber.App, *fakeMasterKeyRepo, *auth.Service, uuid.UUID) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	bl := disposable.NewBlocklist("", false, 10*time.Second, zerolog.Nop())
+	permPub := permission.NewPublisher(rdb)
+	svc, err := auth.NewService(newFakeRepo(), rdb, testAuthConfig(), bl, nil, &fakeServerRepo{}, permPub, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	result, err := svc.Register(t.Context(), auth.RegisterRequest{
+		Email:    "keys@example.com",
+		Username: "keysuser",
+		Password: "strongpassword",
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	userID, err := uuid.Parse(result.User.ID)
+	if err != nil {
+		t.Fatalf("Parse user ID: %v", err)
+	}
+
+	repo := &fakeMasterKeyRepo{keys: make(map[uuid.UUID][]byte)}
+	handler := NewE2EEHandler(repo, svc, nil, nil, 0, zerolog.Nop())
+
+	app := fiber.New()
+	app.Put("/master-key", fakeAuth(userID), handler.UploadMasterKey)
+	return app, repo, svc, userID
+}
+
+func masterKeyBody(key byte, extra string) string {
+	return `{"public_key":"` + base64.RawStdEncoding.EncodeToString(bytes.Repeat([]byte{key}, 32)) + `"` + extra + `}`
+}
+
+func TestUploadMasterKey_FirstUploadNeedsNoPassword(t *testing.T) {
+	t.Parallel()
+	app, repo, _, userID := testMasterKeyApp(t)
+
+	resp := doReq(t, app, jsonReq(http.MethodPut, "/master-key", masterKeyBody(1, "")))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", resp.StatusCode, fiber.StatusOK, body)
+	}
+	if repo.keys[userID] == nil {
+		t.Error("master key was not stored")
+	}
+
+	// Uploading the same key again is a no-op and needs no password either.
+	resp = doReq(t, app, jsonReq(http.MethodPut, "/master-key", masterKeyBody(1, "")))
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("repeat upload status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+}
+
+func TestUploadMasterKey_ReplaceRequiresPassword(t *testing.T) {
+	t.Parallel()
+	app, repo, _, userID := testMasterKeyApp(t)
+	doReq(t, app, jsonReq(http.MethodPut, "/master-key", masterKeyBody(1, "")))
+
+	resp := doReq(t, app, jsonReq(http.MethodPut, "/master-key", masterKeyBody(2, "")))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusConflict)
+	}
+	if env := parseError(t, body); env.Error.Code != string(apierrors.AlreadyExists) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.AlreadyExists)
+	}
+
+	resp = doReq(t, app, jsonReq(http.MethodPut, "/master-key", masterKeyBody(2, `,"password":"wrongpassword"`)))
+	body = readBody(t, resp)
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusUnauthorized)
+	}
+	if env := parseError(t, body); env.Error.Code != string(apierrors.InvalidCredentials) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.InvalidCredentials)
+	}
+	if repo.keys[userID][0] != 1 {
+		t.Error("master key was replaced without re-authentication")
+	}
+
+	resp = doReq(t, app, jsonReq(http.MethodPut, "/master-key", masterKeyBody(2, `,"password":"strongpassword"`)))
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if repo.keys[userID][0] != 2 {
+		t.Error("master key was not replaced after re-authentication")
+	}
+}
+
+func TestUploadMasterKey_ReplaceRequiresMFACode(t *testing.T) {
+	t.Parallel()
+	app, repo, svc, userID := testMasterKeyApp(t)
+	doReq(t, app, jsonReq(http.MethodPut, "/master-key", masterKeyBody(1, "")))
+
+	setup, err := svc.BeginMFASetup(t.Context(), userID, "strongpassword")
+	if err != nil {
+		t.Fatalf("BeginMFASetup() error = %v", err)
+	}
+	code, err := totp.GenerateCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("totp.GenerateCode() error = %v", err)
+	}
+	if _, err := svc.ConfirmMFASetup(t.Context(), userID, code); err != nil {
+		t.Fatalf("ConfirmMFASetup() error = %v", err)
+	}
+
+	resp := doReq(t, app, jsonReq(http.MethodPut, "/master-key", masterKeyBody(2, `,"password":"strongpassword"`)))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusUnauthorized)
+	}
+	if env := parseError(t, body); env.Error.Code != string(apierrors.MFARequired) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.MFARequired)
+	}
+	if repo.keys[userID][0] != 1 {
+		t.Error("master key was replaced without an MFA code")
+	}
+
+	resp = doReq(t, app, jsonReq(http.MethodPut, "/master-key",
+		masterKeyBody(2, `,"password":"strongpassword","code":"`+code+`"`)))
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if repo.keys[userID][0] != 2 {
+		t.Error("master key was not replaced after re-authentication")
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/uncord-chat/uncord-server/internal/gateway"
 	"github.com/uncord-chat/uncord-server/internal/httputil"
 	"github.com/uncord-chat/uncord-server/internal/media"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
 )
 
 // EmojiHandler serves custom emoji endpoints.
@@ -146,7 +147,7 @@ func (h *EmojiHandler) CreateEmoji(c fiber.Ctx) error {
 		})
 	}
 
-	h.publishEmojiUpdate()
+	h.publishEmojiUpdate(c.Context())
 
 	return httputil.SuccessStatus(c, fiber.StatusCreated, created.ToModel(h.storage.URL(created.StorageKey)))
 }
@@ -184,7 +185,7 @@ func (h *EmojiHandler) UpdateEmoji(c fiber.Ctx) error {
 		})
 	}
 
-	h.publishEmojiUpdate()
+	h.publishEmojiUpdate(c.Context())
 
 	return httputil.Success(c, updated.ToModel(h.storage.URL(updated.StorageKey)))
 }
@@ -217,23 +218,24 @@ func (h *EmojiHandler) DeleteEmoji(c fiber.Ctx) error {
 		})
 	}
 
-	if delErr := h.storage.Delete(c.Context(), existing.StorageKey); delErr != nil {
+	if delErr := media.DeleteWithVariants(c.Context(), h.storage, existing.StorageKey); delErr != nil {
 		h.log.Warn().Err(delErr).Str("key", existing.StorageKey).Msg("Failed to delete emoji file")
 	}
 
-	h.publishEmojiUpdate()
+	h.publishEmojiUpdate(c.Context())
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
 // publishEmojiUpdate sends an EMOJI_UPDATE gateway event with the full current emoji list. The DB query runs in its own
 // goroutine to avoid blocking the HTTP response; the resulting event is published via the bounded worker pool.
-func (h *EmojiHandler) publishEmojiUpdate() {
+func (h *EmojiHandler) publishEmojiUpdate(ctx context.Context) {
 	if h.gateway == nil {
 		return
 	}
+	ctx = tracing.Detach(ctx)
 	go func() {
-		list, err := h.emoji.List(context.Background())
+		list, err := h.emoji.List(ctx)
 		if err != nil {
 			h.log.Warn().Err(err).Msg("Failed to load emoji list for gateway event")
 			return
@@ -242,7 +244,7 @@ func (h *EmojiHandler) publishEmojiUpdate() {
 		for i := range list {
 			emojiModels[i] = list[i].ToModel(h.storage.URL(list[i].StorageKey))
 		}
-		h.gateway.Enqueue(events.EmojiUpdate, models.EmojiUpdateData{Emoji: emojiModels})
+		h.gateway.Enqueue(ctx, events.EmojiUpdate, models.EmojiUpdateData{Emoji: emojiModels})
 	}()
 }
 
@@ -1,8 +1,14 @@
 package api
 
 import (
+	"crypto/subtle"
+
 	"github.com/gofiber/contrib/v3/websocket"
 	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/httputil"
 
 	"github.com/uncord-chat/uncord-server/internal/gateway"
 )
@@ -17,12 +23,94 @@ func NewGatewayHandler(hub *gateway.Hub) *GatewayHandler {
 	return &GatewayHandler{hub: hub}
 }
 
-// Upgrade handles GET /api/v1/gateway. It upgrades the HTTP connection to a WebSocket and hands it to the Hub.
+// Upgrade handles GET /api/v1/gateway. It upgrades the HTTP connection to a WebSocket and hands it to the Hub. The
+// optional encoding query parameter selects the frame encoding ("json" or "msgpack"). Clients that offer the
+// permessage-deflate extension have it negotiated during the upgrade. A draining node answers 503 so that the client
+// connects to another node.
 func (h *GatewayHandler) Upgrade(c fiber.Ctx) error {
 	if !websocket.IsWebSocketUpgrade(c) {
 		return fiber.ErrUpgradeRequired
 	}
+	enc, err := gateway.ParseEncoding(c.Query("encoding"))
+	if err != nil {
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError,
+			"Encoding must be one of json or msgpack")
+	}
+	if h.hub.Draining() {
+		return httputil.Fail(c, fiber.StatusServiceUnavailable, apierrors.ServiceUnavailable,
+			"This node is draining; reconnect to another node")
+	}
 	return websocket.New(func(conn *websocket.Conn) {
-		h.hub.ServeWebSocket(conn.Conn)
-	})(c)
+		h.hub.ServeWebSocket(conn.Conn, enc)
+	}, websocket.Config{EnableCompression: true})(c)
+}
+
+// GatewayDrainer drains the gateway connections of the node it runs on. Satisfied by *gateway.Hub.
+type GatewayDrainer interface {
+	StartDrain() bool
+	StopDrain() bool
+	Draining() bool
+	ClientCount() int
+}
+
+var _ GatewayDrainer = (*gateway.Hub)(nil)
+
+// GatewayDrainHandler serves the gateway drain endpoints. They act on the node that runs them, so they are served on the
+// node-local admin listener (ADMIN_ADDR) rather than on the public API, which reaches an arbitrary node through the load
+// balancer.
+type GatewayDrainHandler struct {
+	drainer GatewayDrainer
+	log     zerolog.Logger
+}
+
+// NewGatewayDrainHandler creates a new gateway drain handler.
+func NewGatewayDrainHandler(drainer GatewayDrainer, logger zerolog.Logger) *GatewayDrainHandler {
+	return &GatewayDrainHandler{drainer: drainer, log: logger}
+}
+
+// gatewayDrainResponse is the JSON shape returned by the drain endpoints. Clients is the number of connections still
+// open on the node.
+type gatewayDrainResponse struct {
+	Draining bool `json:"draining"`
+	Clients  int  `json:"clients"`
+}
+
+// Start handles POST /gateway/drain on the admin listener. The drain runs in the background; the node is expected to be
+// restarted once its client count reaches zero.
+func (h *GatewayDrainHandler) Start(c fiber.Ctx) error {
+	if !h.drainer.StartDrain() {
+		return httputil.Fail(c, fiber.StatusConflict, apierrors.AlreadyExists, "The gateway is already draining")
+	}
+	h.log.Info().Str("remote_addr", c.IP()).Msg("Gateway drain requested")
+	return httputil.SuccessStatus(c, fiber.StatusAccepted, h.status())
+}
+
+// Stop handles DELETE /gateway/drain on the admin listener. It cancels a running drain; clients already migrated stay
+// on the nodes they reconnected to.
+func (h *GatewayDrainHandler) Stop(c fiber.Ctx) error {
+	if !h.drainer.StopDrain() {
+		return httputil.Fail(c, fiber.StatusNotFound, apierrors.NotFound, "The gateway is not draining")
+	}
+	h.log.Info().Str("remote_addr", c.IP()).Msg("Gateway drain cancel requested")
+	return httputil.Success(c, h.status())
+}
+
+// Status handles GET /gateway/drain on the admin listener.
+func (h *GatewayDrainHandler) Status(c fiber.Ctx) error {
+	return httputil.Success(c, h.status())
+}
+
+func (h *GatewayDrainHandler) status() gatewayDrainResponse {
+	return gatewayDrainResponse{Draining: h.drainer.Draining(), Clients: h.drainer.ClientCount()}
+}
+
+// RequireAdminToken rejects requests to the admin listener that do not carry "Authorization: Bearer <token>".
+func RequireAdminToken(token string) fiber.Handler {
+	want := []byte("Bearer " + token)
+	return func(c fiber.Ctx) error {
+		if token == "" || subtle.ConstantTimeCompare([]byte(c.Get(fiber.HeaderAuthorization)), want) != 1 {
+			return httputil.Fail(c, fiber.StatusUnauthorized, apierrors.Unauthorised, "A valid admin token is required")
+		}
+		return c.Next()
+	}
 }
@@ -2,11 +2,14 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
 )
 
 func TestUpgradeRejectsNonWebSocket(t *testing.T) {
@@ -28,3 +31,168 @@ func TestUpgradeRejectsNonWebSocket(t *testing.T) {
 		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUpgradeRequired)
 	}
 }
+
+func TestUpgradeRejectsUnsupportedEncoding(t *testing.T) {
+	t.Parallel()
+
+	handler := NewGatewayHandler(nil)
+
+	app := fiber.New()
+	app.Get("/api/v1/gateway", handler.Upgrade)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v1/gateway?encoding=etf", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	resp := doReq(t, app, req)
+	body := readBody(t, resp)
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d; body = %s", resp.StatusCode, http.StatusBadRequest, body)
+	}
+	if env := parseError(t, body); env.Error.Code != string(apierrors.ValidationError) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.ValidationError)
+	}
+}
+
+// fakeDrainer implements GatewayDrainer for handler tests.
+type fakeDrainer struct {
+	draining bool
+	clients  int
+}
+
+func (d *fakeDrainer) StartDrain() bool {
+	if d.draining {
+		return false
+	}
+	d.draining = true
+	return true
+}
+
+func (d *fakeDrainer) StopDrain() bool {
+	if !d.draining {
+		return false
+	}
+	d.draining = false
+	return true
+}
+
+func (d *fakeDrainer) Draining() bool   { return d.draining }
+func (d *fakeDrainer) ClientCount() int { return d.clients }
+
+// testDrainApp serves the drain endpoints behind the admin token, as the admin listener does.
+func testDrainApp(drainer GatewayDrainer) *fiber.App {
+	handler := NewGatewayDrainHandler(drainer, zerolog.Nop())
+	app := fiber.New()
+	app.Use(RequireAdminToken("admin-token"))
+	app.Post("/gateway/drain", handler.Start)
+	app.Delete("/gateway/drain", handler.Stop)
+	app.Get("/gateway/drain", handler.Status)
+	return app
+}
+
+func adminReq(method string) *http.Request {
+	req := jsonReq(method, "/gateway/drain", "")
+	req.Header.Set("Authorization", "Bearer admin-token")
+	return req
+}
+
+func decodeDrainStatus(t *testing.T, body []byte) gatewayDrainResponse {
+	t.Helper()
+	var got gatewayDrainResponse
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+	return got
+}
+
+func TestGatewayDrain(t *testing.T) {
+	t.Parallel()
+
+	drainer := &fakeDrainer{clients: 7}
+	app := testDrainApp(drainer)
+
+	resp := doReq(t, app, adminReq(http.MethodGet))
+	if got := decodeDrainStatus(t, readBody(t, resp)); got.Draining || got.Clients != 7 {
+		t.Errorf("status before drain = %+v, want not draining with 7 clients", got)
+	}
+
+	resp = doReq(t, app, adminReq(http.MethodPost))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusAccepted {
+		t.Fatalf("status = %d, want %d; body = %s", resp.StatusCode, fiber.StatusAccepted, body)
+	}
+	if got := decodeDrainStatus(t, body); !got.Draining {
+		t.Errorf("response = %+v, want draining", got)
+	}
+
+	resp = doReq(t, app, adminReq(http.MethodPost))
+	body = readBody(t, resp)
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("second drain status = %d, want %d", resp.StatusCode, fiber.StatusConflict)
+	}
+	if env := parseError(t, body); env.Error.Code != string(apierrors.AlreadyExists) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.AlreadyExists)
+	}
+}
+
+func TestGatewayDrainCancel(t *testing.T) {
+	t.Parallel()
+
+	drainer := &fakeDrainer{draining: true}
+	app := testDrainApp(drainer)
+
+	resp := doReq(t, app, adminReq(http.MethodDelete))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d; body = %s", resp.StatusCode, fiber.StatusOK, body)
+	}
+	if got := decodeDrainStatus(t, body); got.Draining {
+		t.Errorf("response = %+v, want not draining", got)
+	}
+
+	resp = doReq(t, app, adminReq(http.MethodDelete))
+	body = readBody(t, resp)
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("second cancel status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+	if env := parseError(t, body); env.Error.Code != string(apierrors.NotFound) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.NotFound)
+	}
+}
+
+func TestGatewayDrainRequiresAdminToken(t *testing.T) {
+	t.Parallel()
+
+	drainer := &fakeDrainer{}
+	app := testDrainApp(drainer)
+
+	for _, header := range []string{"", "Bearer wrong-token", "admin-token"} {
+		req := jsonReq(http.MethodPost, "/gateway/drain", "")
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp := doReq(t, app, req)
+		body := readBody(t, resp)
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want %d", header, resp.StatusCode, fiber.StatusUnauthorized)
+		}
+		if env := parseError(t, body); env.Error.Code != string(apierrors.Unauthorised) {
+			t.Errorf("Authorization %q: error code = %q, want %q", header, env.Error.Code, apierrors.Unauthorised)
+		}
+	}
+	if drainer.draining {
+		t.Error("drain started without a valid admin token")
+	}
+
+	// An empty configured token never matches, even a bare "Bearer " header.
+	app = fiber.New()
+	app.Use(RequireAdminToken(""))
+	app.Get("/", func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	req := jsonReq(http.MethodGet, "/", "")
+	req.Header.Set("Authorization", "Bearer ")
+	if resp := doReq(t, app, req); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("empty token: status = %d, want %d", resp.StatusCode, fiber.StatusUnauthorized)
+	}
+}
@@ -2,64 +2,76 @@ package api
 
 import (
 	"context"
-	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
 
+	"github.com/uncord-chat/uncord-server/internal/health"
 	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/server"
 )
 
-// Pinger checks connectivity to a backing service.
-type Pinger interface {
-	Ping(ctx context.Context) error
+// ReadinessChecker runs the server's readiness checks. Satisfied by *health.Checker.
+type ReadinessChecker interface {
+	Run(ctx context.Context) health.Report
 }
 
-// HealthHandler serves the health check endpoint.
+// HealthHandler serves the liveness and readiness endpoints.
 type HealthHandler struct {
-	db     Pinger
-	valkey Pinger
+	checker ReadinessChecker
+	servers server.Repository
+	log     zerolog.Logger
 }
 
 // NewHealthHandler creates a new health check handler.
-func NewHealthHandler(db, valkey Pinger) *HealthHandler {
-	return &HealthHandler{db: db, valkey: valkey}
+func NewHealthHandler(checker ReadinessChecker, servers server.Repository, logger zerolog.Logger) *HealthHandler {
+	return &HealthHandler{checker: checker, servers: servers, log: logger}
 }
 
-// healthResponse is the JSON structure returned by the health endpoint, wrapped in the standard data envelope.
-type healthResponse struct {
-	Status   string `json:"status"`
-	Postgres string `json:"postgres"`
-	Valkey   string `json:"valkey"`
+// statusResponse is the JSON structure returned by the public health endpoints, wrapped in the standard data envelope.
+type statusResponse struct {
+	Status health.Status `json:"status"`
 }
 
-// Health pings PostgreSQL and Valkey, returning component status.
-func (h *HealthHandler) Health(c fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(c, 3*time.Second)
-	defer cancel()
+// Live reports that the process is running and able to serve requests. It checks no dependencies, so an orchestrator
+// restarting instances that fail it does not restart every instance when a shared dependency goes down.
+func (h *HealthHandler) Live(c fiber.Ctx) error {
+	return httputil.Success(c, statusResponse{Status: health.StatusOK})
+}
 
-	pgStatus := "ok"
-	if err := h.db.Ping(ctx); err != nil {
-		pgStatus = "unavailable"
+// Ready runs the readiness checks and returns only the overall status. It responds 503 when a critical component is
+// unavailable. A degraded server still responds 200 because it can serve most traffic. Component errors, versions, and
+// queue depths describe the deployment to anyone who can reach the endpoint, so they are left to Report.
+func (h *HealthHandler) Ready(c fiber.Ctx) error {
+	report := h.checker.Run(c.Context())
+	status := fiber.StatusOK
+	if report.Status == health.StatusUnavailable {
+		status = fiber.StatusServiceUnavailable
 	}
+	return httputil.SuccessStatus(c, status, statusResponse{Status: report.Status})
+}
 
-	vkStatus := "ok"
-	if err := h.valkey.Ping(ctx); err != nil {
-		vkStatus = "unavailable"
+// Report handles GET /api/v1/health/report by running the readiness checks and returning the status, latency, and
+// details of every component. Only the server owner may read it. It responds with the same status codes as Ready.
+func (h *HealthHandler) Report(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
 	}
 
-	overall := "ok"
-	if pgStatus != "ok" || vkStatus != "ok" {
-		overall = "degraded"
+	srv, err := h.servers.Get(c)
+	if err != nil {
+		h.log.Error().Err(err).Str("handler", "health").Msg("get server config failed")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
-
-	resp := healthResponse{
-		Status:   overall,
-		Postgres: pgStatus,
-		Valkey:   vkStatus,
+	if srv.OwnerID != userID {
+		return httputil.Fail(c, fiber.StatusForbidden, apierrors.OwnerOnly, "Only the server owner can view the health report")
 	}
 
-	if overall != "ok" {
-		return httputil.SuccessStatus(c, fiber.StatusServiceUnavailable, resp)
+	report := h.checker.Run(c.Context())
+	if report.Status == health.StatusUnavailable {
+		return httputil.SuccessStatus(c, fiber.StatusServiceUnavailable, report)
 	}
-	return httputil.Success(c, resp)
+	return httputil.Success(c, report)
 }
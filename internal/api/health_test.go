@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/health"
+	"github.com/uncord-chat/uncord-server/internal/server"
+)
+
+// fakeReadinessChecker implements ReadinessChecker for handler tests.
+type fakeReadinessChecker struct {
+	report health.Report
+	calls  int
+}
+
+func (c *fakeReadinessChecker) Run(context.Context) health.Report {
+	c.calls++
+	return c.report
+}
+
+func testHealthApp(checker ReadinessChecker, ownerID, userID uuid.UUID) *fiber.App {
+	servers := &fakeServerRepo{cfg: &server.Config{ID: uuid.New(), Name: "Test", OwnerID: ownerID}}
+	handler := NewHealthHandler(checker, servers, zerolog.Nop())
+	app := fiber.New()
+	app.Get("/health/live", handler.Live)
+	app.Get("/health/ready", handler.Ready)
+	app.Get("/health/report", fakeAuth(userID), handler.Report)
+	return app
+}
+
+// testHealthReport returns a report with a single postgres component in the given state.
+func testHealthReport(status health.Status) health.Report {
+	return health.Report{
+		Status: status,
+		Components: map[string]health.Component{
+			"postgres": {Status: status, Critical: true, LatencyMS: 1.5, Error: "dial tcp 10.0.0.5:5432: refused"},
+		},
+	}
+}
+
+func TestHealthLive_ChecksNoDependencies(t *testing.T) {
+	t.Parallel()
+	checker := &fakeReadinessChecker{report: health.Report{Status: health.StatusUnavailable}}
+	app := testHealthApp(checker, uuid.New(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/health/live", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if checker.calls != 0 {
+		t.Errorf("liveness ran the readiness checks %d times, want 0", checker.calls)
+	}
+	var got statusResponse
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+	if got.Status != health.StatusOK {
+		t.Errorf("status = %q, want %q", got.Status, health.StatusOK)
+	}
+}
+
+func TestHealthReady(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		status     health.Status
+		wantStatus int
+	}{
+		{"ok", health.StatusOK, fiber.StatusOK},
+		{"degraded", health.StatusDegraded, fiber.StatusOK},
+		{"unavailable", health.StatusUnavailable, fiber.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app := testHealthApp(&fakeReadinessChecker{report: testHealthReport(tt.status)}, uuid.New(), uuid.New())
+
+			resp := doReq(t, app, jsonReq(http.MethodGet, "/health/ready", ""))
+			body := readBody(t, resp)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+				t.Fatalf("unmarshal data: %v", err)
+			}
+			if len(got) != 1 || got["status"] != string(tt.status) {
+				t.Errorf("data = %v, want only the overall status %q", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestHealthReport_Owner(t *testing.T) {
+	t.Parallel()
+	owner := uuid.New()
+	app := testHealthApp(&fakeReadinessChecker{report: testHealthReport(health.StatusUnavailable)}, owner, owner)
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/health/report", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusServiceUnavailable)
+	}
+	var got health.Report
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+	pg := got.Components["postgres"]
+	if got.Status != health.StatusUnavailable || !pg.Critical || pg.LatencyMS != 1.5 || pg.Error == "" {
+		t.Errorf("report = %+v", got)
+	}
+}
+
+func TestHealthReport_NotOwner(t *testing.T) {
+	t.Parallel()
+	checker := &fakeReadinessChecker{report: testHealthReport(health.StatusOK)}
+	app := testHealthApp(checker, uuid.New(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/health/report", ""))
+	env := parseError(t, readBody(t, resp))
+
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+	if env.Error.Code != string(apierrors.OwnerOnly) {
+		t.Errorf("code = %q, want %q", env.Error.Code, apierrors.OwnerOnly)
+	}
+	if checker.calls != 0 {
+		t.Errorf("checks ran %d times, want 0", checker.calls)
+	}
+}
@@ -87,7 +87,7 @@ func (h *ImageUploadHandler) UploadUserAvatar(c fiber.Ctx) error {
 	}
 
 	if current.AvatarKey != nil {
-		if delErr := h.storage.Delete(c.Context(), *current.AvatarKey); delErr != nil {
+		if delErr := media.DeleteWithVariants(c.Context(), h.storage, *current.AvatarKey); delErr != nil {
 			h.log.Warn().Err(delErr).Str("key", *current.AvatarKey).Msg("Failed to delete old avatar file")
 		}
 	}
@@ -116,7 +116,7 @@ func (h *ImageUploadHandler) DeleteUserAvatar(c fiber.Ctx) error {
 		return h.mapUserError(c, err)
 	}
 
-	if delErr := h.storage.Delete(c.Context(), oldKey); delErr != nil {
+	if delErr := media.DeleteWithVariants(c.Context(), h.storage, oldKey); delErr != nil {
 		h.log.Warn().Err(delErr).Str("key", oldKey).Msg("Failed to delete avatar file")
 	}
 
@@ -154,7 +154,7 @@ func (h *ImageUploadHandler) UploadUserBanner(c fiber.Ctx) error {
 	}
 
 	if current.BannerKey != nil {
-		if delErr := h.storage.Delete(c.Context(), *current.BannerKey); delErr != nil {
+		if delErr := media.DeleteWithVariants(c.Context(), h.storage, *current.BannerKey); delErr != nil {
 			h.log.Warn().Err(delErr).Str("key", *current.BannerKey).Msg("Failed to delete old banner file")
 		}
 	}
@@ -183,7 +183,7 @@ func (h *ImageUploadHandler) DeleteUserBanner(c fiber.Ctx) error {
 		return h.mapUserError(c, err)
 	}
 
-	if delErr := h.storage.Delete(c.Context(), oldKey); delErr != nil {
+	if delErr := media.DeleteWithVariants(c.Context(), h.storage, oldKey); delErr != nil {
 		h.log.Warn().Err(delErr).Str("key", oldKey).Msg("Failed to delete banner file")
 	}
 
@@ -216,14 +216,14 @@ func (h *ImageUploadHandler) UploadServerIcon(c fiber.Ctx) error {
 	}
 
 	if current.IconKey != nil {
-		if delErr := h.storage.Delete(c.Context(), *current.IconKey); delErr != nil {
+		if delErr := media.DeleteWithVariants(c.Context(), h.storage, *current.IconKey); delErr != nil {
 			h.log.Warn().Err(delErr).Str("key", *current.IconKey).Msg("Failed to delete old server icon file")
 		}
 	}
 
 	result := updated.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ServerUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.ServerUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -245,13 +245,13 @@ func (h *ImageUploadHandler) DeleteServerIcon(c fiber.Ctx) error {
 		return h.mapServerError(c, err)
 	}
 
-	if delErr := h.storage.Delete(c.Context(), oldKey); delErr != nil {
+	if delErr := media.DeleteWithVariants(c.Context(), h.storage, oldKey); delErr != nil {
 		h.log.Warn().Err(delErr).Str("key", oldKey).Msg("Failed to delete server icon file")
 	}
 
 	result := updated.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ServerUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.ServerUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -283,14 +283,14 @@ func (h *ImageUploadHandler) UploadServerBanner(c fiber.Ctx) error {
 	}
 
 	if current.BannerKey != nil {
-		if delErr := h.storage.Delete(c.Context(), *current.BannerKey); delErr != nil {
+		if delErr := media.DeleteWithVariants(c.Context(), h.storage, *current.BannerKey); delErr != nil {
 			h.log.Warn().Err(delErr).Str("key", *current.BannerKey).Msg("Failed to delete old server banner file")
 		}
 	}
 
 	result := updated.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ServerUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.ServerUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -312,13 +312,13 @@ func (h *ImageUploadHandler) DeleteServerBanner(c fiber.Ctx) error {
 		return h.mapServerError(c, err)
 	}
 
-	if delErr := h.storage.Delete(c.Context(), oldKey); delErr != nil {
+	if delErr := media.DeleteWithVariants(c.Context(), h.storage, oldKey); delErr != nil {
 		h.log.Warn().Err(delErr).Str("key", oldKey).Msg("Failed to delete server banner file")
 	}
 
 	result := updated.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ServerUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.ServerUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -79,7 +79,7 @@ func (h *InviteHandler) CreateInvite(c fiber.Ctx) error {
 
 	result := toInviteModel(inv)
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.InviteCreate, result)
+		h.gateway.Enqueue(c.Context(), events.InviteCreate, result)
 	}
 
 	return httputil.SuccessStatus(c, fiber.StatusCreated, result)
@@ -136,7 +136,7 @@ func (h *InviteHandler) DeleteInvite(c fiber.Ctx) error {
 	}
 
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.InviteDelete, models.InviteDeleteData{Code: code})
+		h.gateway.Enqueue(c.Context(), events.InviteDelete, models.InviteDeleteData{Code: code})
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
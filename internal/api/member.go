@@ -175,7 +175,7 @@ func (h *MemberHandler) UpdateSelf(c fiber.Ctx) error {
 	}
 
 	result := updated.ToModel()
-	h.publishMemberUpdate(result)
+	h.publishMemberUpdate(c.Context(), result)
 	return httputil.Success(c, result)
 }
 
@@ -194,7 +194,7 @@ func (h *MemberHandler) Leave(c fiber.Ctx) error {
 		return h.mapMemberError(c, err)
 	}
 
-	h.publishMemberRemove(userID.String())
+	h.publishMemberRemove(c.Context(), userID.String())
 	h.invalidateUser(c, userID)
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -244,7 +244,7 @@ func (h *MemberHandler) UpdateMember(c fiber.Ctx) error {
 	}
 
 	result := updated.ToModel()
-	h.publishMemberUpdate(result)
+	h.publishMemberUpdate(c.Context(), result)
 	return httputil.Success(c, result)
 }
 
@@ -278,7 +278,7 @@ func (h *MemberHandler) KickMember(c fiber.Ctx) error {
 		})
 	}
 
-	h.publishMemberRemove(targetID.String())
+	h.publishMemberRemove(c.Context(), targetID.String())
 	h.invalidateUser(c, targetID)
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -328,7 +328,7 @@ func (h *MemberHandler) SetTimeout(c fiber.Ctx) error {
 	}
 
 	result := updated.ToModel()
-	h.publishMemberUpdate(result)
+	h.publishMemberUpdate(c.Context(), result)
 	h.invalidateUser(c, targetID)
 	return httputil.Success(c, result)
 }
@@ -358,7 +358,7 @@ func (h *MemberHandler) ClearTimeout(c fiber.Ctx) error {
 	}
 
 	result := updated.ToModel()
-	h.publishMemberUpdate(result)
+	h.publishMemberUpdate(c.Context(), result)
 	h.invalidateUser(c, targetID)
 	return httputil.Success(c, result)
 }
@@ -408,7 +408,7 @@ func (h *MemberHandler) BanMember(c fiber.Ctx) error {
 		})
 	}
 
-	h.publishMemberRemove(targetID.String())
+	h.publishMemberRemove(c.Context(), targetID.String())
 	h.invalidateUser(c, targetID)
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -534,7 +534,7 @@ func (h *MemberHandler) AssignRole(c fiber.Ctx) error {
 	}
 
 	result := updated.ToModel()
-	h.publishMemberUpdate(result)
+	h.publishMemberUpdate(c.Context(), result)
 	return httputil.Success(c, result)
 }
 
@@ -591,7 +591,7 @@ func (h *MemberHandler) RemoveRole(c fiber.Ctx) error {
 
 	if h.gateway != nil {
 		if updated, err := h.members.GetByUserID(c, targetID); err == nil {
-			h.publishMemberUpdate(updated.ToModel())
+			h.publishMemberUpdate(c.Context(), updated.ToModel())
 		}
 	}
 
@@ -653,16 +653,16 @@ func (h *MemberHandler) mapGuardError(c fiber.Ctx, err error) error {
 }
 
 // publishMemberUpdate fires a best-effort MEMBER_UPDATE gateway event via the bounded worker pool.
-func (h *MemberHandler) publishMemberUpdate(m models.Member) {
+func (h *MemberHandler) publishMemberUpdate(ctx context.Context, m models.Member) {
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MemberUpdate, m)
+		h.gateway.Enqueue(ctx, events.MemberUpdate, m)
 	}
 }
 
 // publishMemberRemove fires a best-effort MEMBER_REMOVE gateway event via the bounded worker pool.
-func (h *MemberHandler) publishMemberRemove(userID string) {
+func (h *MemberHandler) publishMemberRemove(ctx context.Context, userID string) {
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MemberRemove, models.MemberRemoveData{UserID: userID})
+		h.gateway.Enqueue(ctx, events.MemberRemove, models.MemberRemoveData{UserID: userID})
 	}
 }
 
@@ -23,7 +23,7 @@ import (
 	"github.com/uncord-chat/uncord-server/internal/permission"
 	"github.com/uncord-chat/uncord-server/internal/presence"
 	"github.com/uncord-chat/uncord-server/internal/reaction"
-	"github.com/uncord-chat/uncord-server/internal/typesense"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
 )
 
 // MessageHandler serves message endpoints.
@@ -33,7 +33,6 @@ type MessageHandler struct {
 	reactions      reaction.Repository
 	storage        media.StorageProvider
 	resolver       *permission.Resolver
-	indexer        *typesense.Indexer
 	gateway        *gateway.Publisher
 	presence       *presence.Store
 	maxContent     int
@@ -49,7 +48,6 @@ func NewMessageHandler(
 	reactions reaction.Repository,
 	storage media.StorageProvider,
 	resolver *permission.Resolver,
-	indexer *typesense.Indexer,
 	gw *gateway.Publisher,
 	presenceStore *presence.Store,
 	maxContent int,
@@ -63,7 +61,6 @@ func NewMessageHandler(
 		reactions:      reactions,
 		storage:        storage,
 		resolver:       resolver,
-		indexer:        indexer,
 		gateway:        gw,
 		presence:       presenceStore,
 		maxContent:     maxContent,
@@ -126,7 +123,7 @@ func (h *MessageHandler) ListMessages(c fiber.Ctx) error {
 		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
 
-	result := make([]models.Message, len(messages))
+	result := make([]messageModel, len(messages))
 	for i := range messages {
 		result[i] = buildMessageModel(&messages[i], messageEnrichment{
 			Attachments: attachmentMap[messages[i].ID],
@@ -213,34 +210,22 @@ func (h *MessageHandler) CreateMessage(c fiber.Ctx) error {
 	// Newly created messages have no reactions yet.
 	result := buildMessageModel(msg, messageEnrichment{Attachments: linked}, h.storage)
 
-	// Best-effort Typesense indexing. Uses context.Background because Fiber recycles the request context after the
-	// handler returns.
-	if h.indexer != nil {
-		go func() {
-			if err := h.indexer.IndexMessage(
-				context.Background(), msg.ID.String(), msg.Content, msg.AuthorID.String(),
-				msg.ChannelID.String(), msg.CreatedAt.Unix(),
-			); err != nil {
-				h.log.Warn().Err(err).Str("message_id", msg.ID.String()).Msg("Typesense index failed")
-			}
-		}()
-	}
-
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MessageCreate, result)
+		h.gateway.Enqueue(c.Context(), events.MessageCreate, result)
 	}
 
 	// Clear the typing indicator now that the message has been sent. The Valkey ClearTyping call requires its own
 	// goroutine because it must not block the HTTP response, but the subsequent publish uses the bounded worker pool.
 	if h.presence != nil && h.gateway != nil {
+		ctx := tracing.Detach(c.Context())
 		go func() {
-			existed, cErr := h.presence.ClearTyping(context.Background(), channelID, userID)
+			existed, cErr := h.presence.ClearTyping(ctx, channelID, userID)
 			if cErr != nil {
 				h.log.Warn().Err(cErr).Msg("Failed to clear typing on message send")
 				return
 			}
 			if existed {
-				h.gateway.Enqueue(events.TypingStop, models.TypingStopData{
+				h.gateway.Enqueue(ctx, events.TypingStop, models.TypingStopData{
 					ChannelID: channelID.String(),
 					UserID:    userID.String(),
 				})
@@ -251,6 +236,41 @@ func (h *MessageHandler) CreateMessage(c fiber.Ctx) error {
 	return httputil.SuccessStatus(c, fiber.StatusCreated, result)
 }
 
+// ListAttachments handles GET /api/v1/channels/:channelID/messages/:messageID/attachments. It returns the message's
+// attachments with freshly signed URLs, so that a client holding URLs that have expired, such as those in a gateway
+// event or a replayed session, can renew them without refetching the channel history.
+func (h *MessageHandler) ListAttachments(c fiber.Ctx) error {
+	channelID, ok := httputil.ParseUUIDParam(c, "channelID", apierrors.InvalidChannelID)
+	if !ok {
+		return nil
+	}
+	messageID, ok := httputil.ParseUUIDParam(c, "messageID", apierrors.InvalidMessageID)
+	if !ok {
+		return nil
+	}
+
+	// The route's permission check covers channelID, so a message in any other channel is reported as missing.
+	msg, err := h.messages.GetByID(c, messageID)
+	if err != nil {
+		return mapMessageError(c, err, h.log)
+	}
+	if msg.ChannelID != channelID {
+		return mapMessageError(c, message.ErrNotFound, h.log)
+	}
+
+	attachments, err := h.attachments.ListByMessage(c, msg.ID)
+	if err != nil {
+		h.log.Error().Err(err).Str("handler", "message").Msg("list message attachments failed")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	result := make([]attachmentModel, len(attachments))
+	for i := range attachments {
+		result[i] = toAttachmentModel(&attachments[i], h.storage)
+	}
+	return httputil.Success(c, result)
+}
+
 // EditMessage handles PATCH /api/v1/messages/:messageID.
 func (h *MessageHandler) EditMessage(c fiber.Ctx) error {
 	messageID, ok := httputil.ParseUUIDParam(c, "messageID", apierrors.InvalidMessageID)
@@ -311,18 +331,8 @@ func (h *MessageHandler) EditMessage(c fiber.Ctx) error {
 		MyReactions: userReactions[msg.ID],
 	}, h.storage)
 
-	// Best-effort Typesense upsert. Uses context.Background because Fiber recycles the request context after the
-	// handler returns.
-	if h.indexer != nil {
-		go func() {
-			if err := h.indexer.UpdateMessage(context.Background(), msg.ID.String(), msg.Content); err != nil {
-				h.log.Warn().Err(err).Str("message_id", msg.ID.String()).Msg("Typesense upsert failed")
-			}
-		}()
-	}
-
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MessageUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.MessageUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -370,18 +380,8 @@ func (h *MessageHandler) DeleteMessage(c fiber.Ctx) error {
 		})
 	}
 
-	// Best-effort Typesense deletion. Uses context.Background because Fiber recycles the request context after the
-	// handler returns.
-	if h.indexer != nil {
-		go func() {
-			if err := h.indexer.DeleteMessage(context.Background(), messageID.String()); err != nil {
-				h.log.Warn().Err(err).Str("message_id", messageID.String()).Msg("Typesense delete failed")
-			}
-		}()
-	}
-
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MessageDelete, models.MessageDeleteData{
+		h.gateway.Enqueue(c.Context(), events.MessageDelete, models.MessageDeleteData{
 			ID:        messageID.String(),
 			ChannelID: existing.ChannelID.String(),
 		})
@@ -398,9 +398,16 @@ type messageEnrichment struct {
 	MyReactions map[string]bool
 }
 
-// buildMessageModel converts an internal message to a protocol response model. This is a package-level function so
-// that multiple handlers (MessageHandler, ThreadHandler, PinHandler) can reuse the same conversion logic.
-func buildMessageModel(m *message.Message, e messageEnrichment, storage media.StorageProvider) models.Message {
+// messageModel is the message response type. It embeds the protocol message and shadows its Attachments field with the
+// server's attachmentModel so that media metadata not yet in the protocol module is included on the wire.
+type messageModel struct {
+	models.Message
+	Attachments []attachmentModel `json:"attachments"`
+}
+
+// buildMessageModel converts an internal message to a response model. This is a package-level function so that
+// multiple handlers (MessageHandler, ThreadHandler, PinHandler) can reuse the same conversion logic.
+func buildMessageModel(m *message.Message, e messageEnrichment, storage media.StorageProvider) messageModel {
 	var replyToID *string
 	if m.ReplyToID != nil {
 		s := m.ReplyToID.String()
@@ -417,7 +424,7 @@ func buildMessageModel(m *message.Message, e messageEnrichment, storage media.St
 		editedAt = &s
 	}
 
-	modelAttachments := make([]models.Attachment, len(e.Attachments))
+	modelAttachments := make([]attachmentModel, len(e.Attachments))
 	for i := range e.Attachments {
 		modelAttachments[i] = toAttachmentModel(&e.Attachments[i], storage)
 	}
@@ -441,24 +448,26 @@ func buildMessageModel(m *message.Message, e messageEnrichment, storage media.St
 		}
 	}
 
-	return models.Message{
-		ID:        m.ID.String(),
-		ChannelID: m.ChannelID.String(),
-		Author: models.MemberUser{
-			ID:          m.AuthorID.String(),
-			Username:    m.AuthorUsername,
-			DisplayName: m.AuthorDisplayName,
-			AvatarKey:   m.AuthorAvatarKey,
+	return messageModel{
+		Message: models.Message{
+			ID:        m.ID.String(),
+			ChannelID: m.ChannelID.String(),
+			Author: models.MemberUser{
+				ID:          m.AuthorID.String(),
+				Username:    m.AuthorUsername,
+				DisplayName: m.AuthorDisplayName,
+				AvatarKey:   m.AuthorAvatarKey,
+			},
+			Content:   m.Content,
+			Reactions: modelReactions,
+			ReplyToID: replyToID,
+			ThreadID:  threadID,
+			Pinned:    m.Pinned,
+			Encrypted: m.Encrypted,
+			EditedAt:  editedAt,
+			CreatedAt: m.CreatedAt.Format(time.RFC3339),
 		},
-		Content:     m.Content,
 		Attachments: modelAttachments,
-		Reactions:   modelReactions,
-		ReplyToID:   replyToID,
-		ThreadID:    threadID,
-		Pinned:      m.Pinned,
-		Encrypted:   m.Encrypted,
-		EditedAt:    editedAt,
-		CreatedAt:   m.CreatedAt.Format(time.RFC3339),
 	}
 }
 
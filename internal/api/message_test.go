@@ -202,25 +202,33 @@ func seedMessage(repo *fakeMessageRepo, channelID, authorID uuid.UUID) *message.
 
 // fakeAttachmentRepo implements attachment.Repository for handler tests.
 type fakeAttachmentRepo struct {
-	attachments []attachment.Attachment
+	attachments   []attachment.Attachment
+	dmAttachments []attachment.DMAttachment
+	uploads       map[uuid.UUID]*attachment.Upload
+	chunks        map[uuid.UUID][]string
 }
 
 func newFakeAttachmentRepo() *fakeAttachmentRepo {
-	return &fakeAttachmentRepo{}
+	return &fakeAttachmentRepo{
+		uploads: make(map[uuid.UUID]*attachment.Upload),
+		chunks:  make(map[uuid.UUID][]string),
+	}
 }
 
 func (r *fakeAttachmentRepo) Create(_ context.Context, params attachment.CreateParams) (*attachment.Attachment, error) {
 	a := attachment.Attachment{
-		ID:          uuid.New(),
-		ChannelID:   params.ChannelID,
-		UploaderID:  params.UploaderID,
-		Filename:    params.Filename,
-		ContentType: params.ContentType,
-		SizeBytes:   params.SizeBytes,
-		StorageKey:  params.StorageKey,
-		Width:       params.Width,
-		Height:      params.Height,
-		CreatedAt:   time.Now(),
+		ID:            uuid.New(),
+		ChannelID:     params.ChannelID,
+		UploaderID:    params.UploaderID,
+		Filename:      params.Filename,
+		ContentType:   params.ContentType,
+		SizeBytes:     params.SizeBytes,
+		StorageKey:    params.StorageKey,
+		Width:         params.Width,
+		Height:        params.Height,
+		ScanStatus:    params.ScanStatus,
+		ScanSignature: params.ScanSignature,
+		CreatedAt:     time.Now(),
 	}
 	r.attachments = append(r.attachments, a)
 	return &a, nil
@@ -240,7 +248,7 @@ func (r *fakeAttachmentRepo) LinkToMessage(_ context.Context, ids []uuid.UUID, m
 	for _, id := range ids {
 		for i := range r.attachments {
 			a := &r.attachments[i]
-			if a.ID == id && a.UploaderID == uploaderID && a.MessageID == nil {
+			if a.ID == id && a.UploaderID == uploaderID && a.MessageID == nil && a.ScanStatus != attachment.ScanQuarantined {
 				a.MessageID = &messageID
 				result = append(result, *a)
 			}
@@ -284,6 +292,130 @@ func (r *fakeAttachmentRepo) SetThumbnailKey(_ context.Context, id uuid.UUID, ke
 	return attachment.ErrNotFound
 }
 
+func (r *fakeAttachmentRepo) SetMediaMetadata(_ context.Context, id uuid.UUID, meta attachment.MediaMetadata) error {
+	for i := range r.attachments {
+		if r.attachments[i].ID == id {
+			if meta.DurationMS != nil {
+				r.attachments[i].DurationMS = meta.DurationMS
+			}
+			if len(meta.Waveform) > 0 {
+				r.attachments[i].Waveform = meta.Waveform
+			}
+			return nil
+		}
+	}
+	return attachment.ErrNotFound
+}
+
+func (r *fakeAttachmentRepo) CreateUpload(_ context.Context, params attachment.CreateUploadParams) (*attachment.Upload, error) {
+	now := time.Now()
+	u := &attachment.Upload{
+		ID:          uuid.New(),
+		ChannelID:   params.ChannelID,
+		UploaderID:  params.UploaderID,
+		Filename:    params.Filename,
+		ContentType: params.ContentType,
+		SizeBytes:   params.SizeBytes,
+		SHA256:      params.SHA256,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	r.uploads[u.ID] = u
+	cp := *u
+	return &cp, nil
+}
+
+func (r *fakeAttachmentRepo) GetUpload(_ context.Context, id uuid.UUID, uploaderID uuid.UUID) (*attachment.Upload, error) {
+	u, ok := r.uploads[id]
+	if !ok || u.UploaderID != uploaderID {
+		return nil, attachment.ErrUploadNotFound
+	}
+	cp := *u
+	return &cp, nil
+}
+
+func (r *fakeAttachmentRepo) AppendUploadChunk(_ context.Context, id uuid.UUID, uploaderID uuid.UUID, offset, size int64, storageKey string) (*attachment.Upload, error) {
+	u, ok := r.uploads[id]
+	if !ok || u.UploaderID != uploaderID {
+		return nil, attachment.ErrUploadNotFound
+	}
+	if u.ReceivedBytes != offset || offset+size > u.SizeBytes {
+		return nil, attachment.ErrUploadOffsetMismatch
+	}
+	u.ReceivedBytes += size
+	u.UpdatedAt = time.Now()
+	r.chunks[id] = append(r.chunks[id], storageKey)
+	cp := *u
+	return &cp, nil
+}
+
+func (r *fakeAttachmentRepo) ListUploadChunks(_ context.Context, id uuid.UUID) ([]string, error) {
+	return r.chunks[id], nil
+}
+
+func (r *fakeAttachmentRepo) DeleteUpload(_ context.Context, id uuid.UUID, uploaderID uuid.UUID) ([]string, error) {
+	u, ok := r.uploads[id]
+	if !ok || u.UploaderID != uploaderID {
+		return nil, attachment.ErrUploadNotFound
+	}
+	keys := r.chunks[id]
+	delete(r.uploads, id)
+	delete(r.chunks, id)
+	return keys, nil
+}
+
+func (r *fakeAttachmentRepo) CreateDM(_ context.Context, params attachment.CreateDMParams) (*attachment.DMAttachment, error) {
+	a := attachment.DMAttachment{
+		ID:                uuid.New(),
+		DMChannelID:       params.DMChannelID,
+		UploaderID:        params.UploaderID,
+		SizeBytes:         params.SizeBytes,
+		StorageKey:        params.StorageKey,
+		EncryptedMetadata: params.EncryptedMetadata,
+		CreatedAt:         time.Now(),
+	}
+	r.dmAttachments = append(r.dmAttachments, a)
+	return &a, nil
+}
+
+func (r *fakeAttachmentRepo) GetDM(_ context.Context, id uuid.UUID, dmChannelID uuid.UUID) (*attachment.DMAttachment, error) {
+	for i := range r.dmAttachments {
+		if r.dmAttachments[i].ID == id && r.dmAttachments[i].DMChannelID == dmChannelID {
+			return &r.dmAttachments[i], nil
+		}
+	}
+	return nil, attachment.ErrNotFound
+}
+
+func (r *fakeAttachmentRepo) LinkDMToMessage(_ context.Context, attachmentIDs []uuid.UUID, messageID, dmChannelID, uploaderID uuid.UUID) ([]attachment.DMAttachment, error) {
+	var linked []*attachment.DMAttachment
+	for _, id := range attachmentIDs {
+		a, err := r.GetDM(context.Background(), id, dmChannelID)
+		if err != nil || a.UploaderID != uploaderID || a.MessageID != nil {
+			return nil, attachment.ErrNotFound
+		}
+		linked = append(linked, a)
+	}
+	result := make([]attachment.DMAttachment, len(linked))
+	for i, a := range linked {
+		a.MessageID = &messageID
+		result[i] = *a
+	}
+	return result, nil
+}
+
+func (r *fakeAttachmentRepo) ListDMByMessages(_ context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]attachment.DMAttachment, error) {
+	result := make(map[uuid.UUID][]attachment.DMAttachment)
+	for _, a := range r.dmAttachments {
+		for _, id := range messageIDs {
+			if a.MessageID != nil && *a.MessageID == id {
+				result[id] = append(result[id], a)
+			}
+		}
+	}
+	return result, nil
+}
+
 func (r *fakeAttachmentRepo) PurgeOrphans(_ context.Context, _ time.Time) ([]string, error) {
 	return nil, nil
 }
@@ -333,7 +465,7 @@ func testMessageAppWithAttachments(
 		t.Fatalf("NewLocalStorage() error: %v", err)
 	}
 	t.Cleanup(func() { _ = storage.Close() })
-	handler := NewMessageHandler(repo, attachRepo, &fakeReactionRepo{}, storage, resolver, nil, nil, nil, testMaxContent, 10, nil, zerolog.Nop())
+	handler := NewMessageHandler(repo, attachRepo, &fakeReactionRepo{}, storage, resolver, nil, nil, testMaxContent, 10, nil, zerolog.Nop())
 	app := fiber.New()
 
 	app.Use(fakeAuth(userID))
@@ -341,6 +473,7 @@ func testMessageAppWithAttachments(
 	// Channel-scoped routes
 	app.Get("/channels/:channelID/messages", handler.ListMessages)
 	app.Post("/channels/:channelID/messages", handler.CreateMessage)
+	app.Get("/channels/:channelID/messages/:messageID/attachments", handler.ListAttachments)
 
 	// Message-scoped routes
 	app.Patch("/messages/:messageID", handler.EditMessage)
@@ -915,6 +1048,56 @@ func TestCreateMessage_WithAttachments(t *testing.T) {
 	}
 }
 
+func TestListAttachments(t *testing.T) {
+	t.Parallel()
+	msgRepo := newFakeMessageRepo()
+	attRepo := newFakeAttachmentRepo()
+	channelID := uuid.New()
+	userID := uuid.New()
+
+	msg, err := msgRepo.Create(context.Background(), message.CreateParams{ChannelID: channelID, AuthorID: userID})
+	if err != nil {
+		t.Fatalf("create message: %v", err)
+	}
+	att, err := attRepo.Create(context.Background(), attachment.CreateParams{
+		ChannelID:   channelID,
+		UploaderID:  userID,
+		Filename:    "photo.jpg",
+		ContentType: "image/jpeg",
+		SizeBytes:   1024,
+		StorageKey:  "attachments/" + channelID.String() + "/abc.jpg",
+	})
+	if err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+	if _, err := attRepo.LinkToMessage(context.Background(), []uuid.UUID{att.ID}, msg.ID, userID); err != nil {
+		t.Fatalf("link attachment: %v", err)
+	}
+
+	app := testMessageAppWithAttachments(t, msgRepo, attRepo, allowAllResolver(), userID)
+
+	url := "/channels/" + channelID.String() + "/messages/" + msg.ID.String() + "/attachments"
+	resp := doReq(t, app, jsonReq(http.MethodGet, url, ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusOK, body)
+	}
+	var got []attachmentModel
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+		t.Fatalf("unmarshal attachments: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != att.ID.String() || got[0].URL == "" {
+		t.Errorf("attachments = %+v, want the linked attachment with a URL", got)
+	}
+
+	// The permission check covers the channel in the path, so a message in another channel is not found.
+	otherURL := "/channels/" + uuid.New().String() + "/messages/" + msg.ID.String() + "/attachments"
+	resp = doReq(t, app, jsonReq(http.MethodGet, otherURL, ""))
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("other channel status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+}
+
 func TestCreateMessage_AttachmentOnly(t *testing.T) {
 	t.Parallel()
 	msgRepo := newFakeMessageRepo()
@@ -203,7 +203,7 @@ func (h *OnboardingHandler) AcceptOnboarding(c fiber.Ctx) error {
 
 	result := m.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MemberAdd, result)
+		h.gateway.Enqueue(c.Context(), events.MemberAdd, result)
 	}
 
 	return httputil.Success(c, result)
@@ -343,7 +343,7 @@ func (h *OnboardingHandler) GetOnboardingStatus(c fiber.Ctx) error {
 		}
 		result := m.ToModel()
 		if h.gateway != nil {
-			h.gateway.Enqueue(events.MemberAdd, result)
+			h.gateway.Enqueue(c.Context(), events.MemberAdd, result)
 		}
 		return httputil.Success(c, models.OnboardingStatusResponse{Step: models.OnboardingStepComplete})
 	}
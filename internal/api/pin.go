@@ -9,7 +9,6 @@ import (
 	"github.com/rs/zerolog"
 	apierrors "github.com/uncord-chat/uncord-protocol/errors"
 	"github.com/uncord-chat/uncord-protocol/events"
-	"github.com/uncord-chat/uncord-protocol/models"
 	"github.com/uncord-chat/uncord-protocol/permissions"
 
 	"github.com/uncord-chat/uncord-server/internal/attachment"
@@ -102,7 +101,7 @@ func (h *PinHandler) PinMessage(c fiber.Ctx) error {
 	}
 
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MessageUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.MessageUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -153,7 +152,7 @@ func (h *PinHandler) UnpinMessage(c fiber.Ctx) error {
 	}
 
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MessageUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.MessageUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -197,7 +196,7 @@ func (h *PinHandler) ListPins(c fiber.Ctx) error {
 		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
 
-	result := make([]models.Message, len(messages))
+	result := make([]messageModel, len(messages))
 	for i := range messages {
 		result[i] = buildMessageModel(&messages[i], messageEnrichment{
 			Attachments: attachmentMap[messages[i].ID],
@@ -208,24 +207,24 @@ func (h *PinHandler) ListPins(c fiber.Ctx) error {
 	return httputil.Success(c, result)
 }
 
-// fullMessageModel loads attachments and reactions for a single message and returns the protocol model.
-func (h *PinHandler) fullMessageModel(c fiber.Ctx, msg *message.Message, userID uuid.UUID) (models.Message, error) {
+// fullMessageModel loads attachments and reactions for a single message and returns the response model.
+func (h *PinHandler) fullMessageModel(c fiber.Ctx, msg *message.Message, userID uuid.UUID) (messageModel, error) {
 	attachments, err := h.attachments.ListByMessage(c, msg.ID)
 	if err != nil {
 		h.log.Error().Err(err).Str("handler", "pin").Msg("list message attachments failed")
-		return models.Message{}, httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+		return messageModel{}, httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
 
 	msgIDs := []uuid.UUID{msg.ID}
 	reactionMap, err := h.reactions.SummariesByMessages(c, msgIDs)
 	if err != nil {
 		h.log.Error().Err(err).Str("handler", "pin").Msg("load message reactions failed")
-		return models.Message{}, httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+		return messageModel{}, httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
 	userReactions, err := h.reactions.UserReactionsByMessages(c, msgIDs, userID)
 	if err != nil {
 		h.log.Error().Err(err).Str("handler", "pin").Msg("load user reactions failed")
-		return models.Message{}, httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+		return messageModel{}, httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
 
 	return buildMessageModel(msg, messageEnrichment{
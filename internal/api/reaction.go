@@ -102,7 +102,7 @@ func (h *ReactionHandler) AddReaction(c fiber.Ctx) error {
 		if emojiUnicode != nil {
 			data.EmojiUnicode = emojiUnicode
 		}
-		h.gateway.Enqueue(events.ReactionAdd, data)
+		h.gateway.Enqueue(c.Context(), events.ReactionAdd, data)
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -147,7 +147,7 @@ func (h *ReactionHandler) RemoveReaction(c fiber.Ctx) error {
 		if emojiUnicode != nil {
 			data.EmojiUnicode = emojiUnicode
 		}
-		h.gateway.Enqueue(events.ReactionRemove, data)
+		h.gateway.Enqueue(c.Context(), events.ReactionRemove, data)
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
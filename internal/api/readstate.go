@@ -60,7 +60,7 @@ func (h *ReadStateHandler) Ack(c fiber.Ctx) error {
 	}
 
 	if h.gateway != nil {
-		h.gateway.EnqueueTargeted(events.MessageAck, &models.MessageAckData{
+		h.gateway.EnqueueTargeted(c.Context(), events.MessageAck, &models.MessageAckData{
 			ChannelID: channelID.String(),
 			MessageID: messageID.String(),
 		}, []uuid.UUID{userID})
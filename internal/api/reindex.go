@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/audit"
+	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/reindex"
+)
+
+// Reindexer starts and reports on search reindex runs. Satisfied by *reindex.Runner.
+type Reindexer interface {
+	Start(opts reindex.Options) error
+	Status(ctx context.Context) (*reindex.Progress, error)
+}
+
+// ReindexHandler serves the search reindex administration endpoints.
+type ReindexHandler struct {
+	reindexer   Reindexer
+	auditLogger *audit.Logger
+	log         zerolog.Logger
+}
+
+// NewReindexHandler creates a new search reindex handler.
+func NewReindexHandler(reindexer Reindexer, auditLogger *audit.Logger, logger zerolog.Logger) *ReindexHandler {
+	return &ReindexHandler{reindexer: reindexer, auditLogger: auditLogger, log: logger}
+}
+
+// reindexStatusResponse is the JSON shape returned by the reindex endpoints. Progress is null until the first run.
+type reindexStatusResponse struct {
+	Progress *reindex.Progress `json:"progress"`
+}
+
+// Start handles POST /api/v1/server/search/reindex. The reindex runs in the background; pass fresh=true to discard the
+// checkpoint of an interrupted run instead of resuming it.
+func (h *ReindexHandler) Start(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+
+	opts := reindex.Options{Fresh: c.Query("fresh") == "true"}
+	if err := h.reindexer.Start(opts); err != nil {
+		if errors.Is(err, reindex.ErrAlreadyRunning) {
+			return httputil.Fail(c, fiber.StatusConflict, apierrors.AlreadyExists, "A search reindex is already running")
+		}
+		h.log.Error().Err(err).Msg("Failed to start search reindex")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	if h.auditLogger != nil {
+		go h.auditLogger.Record(context.Background(), audit.Entry{
+			ActorID: audit.UUIDPtr(userID), Action: audit.SearchReindex,
+			TargetType: audit.Ptr("server"),
+		})
+	}
+
+	progress, err := h.reindexer.Status(c)
+	if err != nil {
+		h.log.Warn().Err(err).Msg("Failed to read search reindex status")
+	}
+	return httputil.SuccessStatus(c, fiber.StatusAccepted, reindexStatusResponse{Progress: progress})
+}
+
+// Status handles GET /api/v1/server/search/reindex.
+func (h *ReindexHandler) Status(c fiber.Ctx) error {
+	progress, err := h.reindexer.Status(c)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to read search reindex status")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+	return httputil.Success(c, reindexStatusResponse{Progress: progress})
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/reindex"
+)
+
+// fakeReindexer implements Reindexer for handler tests.
+type fakeReindexer struct {
+	progress  *reindex.Progress
+	startErr  error
+	statusErr error
+	started   []reindex.Options
+}
+
+func (r *fakeReindexer) Start(opts reindex.Options) error {
+	if r.startErr != nil {
+		return r.startErr
+	}
+	r.started = append(r.started, opts)
+	return nil
+}
+
+func (r *fakeReindexer) Status(context.Context) (*reindex.Progress, error) {
+	return r.progress, r.statusErr
+}
+
+func testReindexApp(t *testing.T, reindexer Reindexer) *fiber.App {
+	t.Helper()
+	handler := NewReindexHandler(reindexer, nil, zerolog.Nop())
+	app := fiber.New()
+	app.Use(fakeAuth(uuid.New()))
+	app.Post("/search/reindex", handler.Start)
+	app.Get("/search/reindex", handler.Status)
+	return app
+}
+
+func TestReindexStart(t *testing.T) {
+	t.Parallel()
+	reindexer := &fakeReindexer{progress: &reindex.Progress{Collection: "messages_1", Running: true}}
+	app := testReindexApp(t, reindexer)
+
+	resp := doReq(t, app, jsonReq(http.MethodPost, "/search/reindex?fresh=true", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusAccepted {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusAccepted)
+	}
+	if len(reindexer.started) != 1 || !reindexer.started[0].Fresh {
+		t.Errorf("started = %+v, want one fresh run", reindexer.started)
+	}
+	var got reindexStatusResponse
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+	if got.Progress == nil || got.Progress.Collection != "messages_1" || !got.Progress.Running {
+		t.Errorf("progress = %+v, want the running collection", got.Progress)
+	}
+}
+
+func TestReindexStart_AlreadyRunning(t *testing.T) {
+	t.Parallel()
+	app := testReindexApp(t, &fakeReindexer{startErr: reindex.ErrAlreadyRunning})
+
+	resp := doReq(t, app, jsonReq(http.MethodPost, "/search/reindex", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusConflict)
+	}
+	env := parseError(t, body)
+	if env.Error.Code != string(apierrors.AlreadyExists) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.AlreadyExists)
+	}
+}
+
+func TestReindexStatus_NoRuns(t *testing.T) {
+	t.Parallel()
+	app := testReindexApp(t, &fakeReindexer{})
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/reindex", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	var got reindexStatusResponse
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+	if got.Progress != nil {
+		t.Errorf("progress = %+v, want nil", got.Progress)
+	}
+}
+
+func TestReindexStatus_Error(t *testing.T) {
+	t.Parallel()
+	app := testReindexApp(t, &fakeReindexer{statusErr: errors.New("valkey unavailable")})
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/reindex", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusInternalServerError)
+	}
+	env := parseError(t, body)
+	if env.Error.Code != string(apierrors.InternalError) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.InternalError)
+	}
+}
@@ -95,7 +95,7 @@ func (h *RoleHandler) CreateRole(c fiber.Ctx) error {
 
 	result := created.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.RoleCreate, result)
+		h.gateway.Enqueue(c.Context(), events.RoleCreate, result)
 	}
 
 	if h.perms != nil {
@@ -181,7 +181,7 @@ func (h *RoleHandler) UpdateRole(c fiber.Ctx) error {
 
 	result := updated.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.RoleUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.RoleUpdate, result)
 	}
 
 	if h.perms != nil {
@@ -233,7 +233,7 @@ func (h *RoleHandler) DeleteRole(c fiber.Ctx) error {
 	}
 
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.RoleDelete, models.RoleDeleteData{ID: id.String()})
+		h.gateway.Enqueue(c.Context(), events.RoleDelete, models.RoleDeleteData{ID: id.String()})
 	}
 
 	if h.perms != nil {
@@ -98,7 +98,7 @@ func (h *SearchHandler) mapSearchError(c fiber.Ctx, err error) error {
 	switch {
 	case errors.Is(err, search.ErrEmptyQuery):
 		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError, err.Error())
-	case errors.Is(err, search.ErrInvalidFilter):
+	case errors.Is(err, search.ErrInvalidFilter), errors.Is(err, search.ErrInvalidQueryFilter):
 		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError, err.Error())
 	case errors.Is(err, search.ErrSearchUnavailable):
 		return httputil.Fail(c, fiber.StatusServiceUnavailable, apierrors.SearchUnavailable, err.Error())
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/outbox"
+)
+
+// OutboxCounters reports the cumulative totals of the local outbox worker. Satisfied by *outbox.Worker.
+type OutboxCounters interface {
+	Counters() outbox.Counters
+}
+
+// SearchOutboxHandler serves the search outbox administration endpoints.
+type SearchOutboxHandler struct {
+	repo   outbox.Repository
+	worker OutboxCounters
+	log    zerolog.Logger
+}
+
+// NewSearchOutboxHandler creates a new search outbox handler. worker may be nil, in which case counters are omitted.
+func NewSearchOutboxHandler(repo outbox.Repository, worker OutboxCounters, logger zerolog.Logger) *SearchOutboxHandler {
+	return &SearchOutboxHandler{repo: repo, worker: worker, log: logger}
+}
+
+// searchOutboxStatusResponse is the JSON shape returned by GET /api/v1/server/search/outbox. OldestPendingAgeSeconds
+// measures how far the index lags behind the database; it is zero when nothing is pending.
+type searchOutboxStatusResponse struct {
+	Pending                 int64            `json:"pending"`
+	DeadLettered            int64            `json:"dead_lettered"`
+	OldestPendingAgeSeconds int64            `json:"oldest_pending_age_seconds"`
+	Worker                  *outbox.Counters `json:"worker,omitempty"`
+}
+
+// requeueResponse is the JSON shape returned by POST /api/v1/server/search/outbox/requeue.
+type requeueResponse struct {
+	Requeued int64 `json:"requeued"`
+}
+
+// Status handles GET /api/v1/server/search/outbox.
+func (h *SearchOutboxHandler) Status(c fiber.Ctx) error {
+	stats, err := h.repo.Stats(c)
+	if err != nil {
+		h.log.Error().Err(err).Str("handler", "search_outbox").Msg("load outbox stats failed")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+
+	resp := searchOutboxStatusResponse{Pending: stats.Pending, DeadLettered: stats.DeadLettered}
+	if stats.OldestPending != nil {
+		resp.OldestPendingAgeSeconds = int64(time.Since(*stats.OldestPending).Seconds())
+	}
+	if h.worker != nil {
+		counters := h.worker.Counters()
+		resp.Worker = &counters
+	}
+	return httputil.Success(c, resp)
+}
+
+// Requeue handles POST /api/v1/server/search/outbox/requeue. Every dead-lettered index update is retried immediately.
+func (h *SearchOutboxHandler) Requeue(c fiber.Ctx) error {
+	n, err := h.repo.RequeueDead(c)
+	if err != nil {
+		h.log.Error().Err(err).Str("handler", "search_outbox").Msg("requeue dead outbox rows failed")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+	return httputil.Success(c, requeueResponse{Requeued: n})
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/outbox"
+)
+
+// fakeOutboxRepo implements outbox.Repository for handler tests.
+type fakeOutboxRepo struct {
+	stats    outbox.Stats
+	requeued int64
+	err      error
+}
+
+func (r *fakeOutboxRepo) Claim(context.Context, int, time.Duration) ([]outbox.Entry, error) {
+	return nil, nil
+}
+
+func (r *fakeOutboxRepo) Complete(context.Context, []uuid.UUID) error { return nil }
+
+func (r *fakeOutboxRepo) Fail(context.Context, uuid.UUID, string, time.Time, bool) error { return nil }
+
+func (r *fakeOutboxRepo) Stats(context.Context) (outbox.Stats, error) { return r.stats, r.err }
+
+func (r *fakeOutboxRepo) RequeueDead(context.Context) (int64, error) { return r.requeued, r.err }
+
+type fakeOutboxCounters struct{ counters outbox.Counters }
+
+func (f fakeOutboxCounters) Counters() outbox.Counters { return f.counters }
+
+func testSearchOutboxApp(t *testing.T, repo outbox.Repository, worker OutboxCounters) *fiber.App {
+	t.Helper()
+	handler := NewSearchOutboxHandler(repo, worker, zerolog.Nop())
+	app := fiber.New()
+	app.Use(fakeAuth(uuid.New()))
+	app.Get("/search/outbox", handler.Status)
+	app.Post("/search/outbox/requeue", handler.Requeue)
+	return app
+}
+
+func TestSearchOutboxStatus(t *testing.T) {
+	t.Parallel()
+	oldest := time.Now().Add(-90 * time.Second)
+	repo := &fakeOutboxRepo{stats: outbox.Stats{Pending: 3, DeadLettered: 1, OldestPending: &oldest}}
+	worker := fakeOutboxCounters{counters: outbox.Counters{Applied: 10, Failed: 2, DeadLettered: 1}}
+	app := testSearchOutboxApp(t, repo, worker)
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/outbox", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	var got searchOutboxStatusResponse
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+	if got.Pending != 3 || got.DeadLettered != 1 {
+		t.Errorf("pending/dead = %d/%d, want 3/1", got.Pending, got.DeadLettered)
+	}
+	if got.OldestPendingAgeSeconds < 90 {
+		t.Errorf("oldest_pending_age_seconds = %d, want at least 90", got.OldestPendingAgeSeconds)
+	}
+	if got.Worker == nil || got.Worker.Applied != 10 {
+		t.Errorf("worker = %+v, want the worker counters", got.Worker)
+	}
+}
+
+func TestSearchOutboxStatus_Error(t *testing.T) {
+	t.Parallel()
+	app := testSearchOutboxApp(t, &fakeOutboxRepo{err: errors.New("db unavailable")}, nil)
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/outbox", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusInternalServerError)
+	}
+	env := parseError(t, body)
+	if env.Error.Code != string(apierrors.InternalError) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.InternalError)
+	}
+}
+
+func TestSearchOutboxRequeue(t *testing.T) {
+	t.Parallel()
+	app := testSearchOutboxApp(t, &fakeOutboxRepo{requeued: 4}, nil)
+
+	resp := doReq(t, app, jsonReq(http.MethodPost, "/search/outbox/requeue", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	var got requeueResponse
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &got); err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+	if got.Requeued != 4 {
+		t.Errorf("requeued = %d, want 4", got.Requeued)
+	}
+}
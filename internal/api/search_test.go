@@ -171,6 +171,24 @@ func TestSearchMessages_InvalidAuthorID(t *testing.T) {
 	}
 }
 
+func TestSearchMessages_InvalidQueryFilter(t *testing.T) {
+	t.Parallel()
+	repo := newFakeChannelRepo()
+	searcher := &fakeSearcher{}
+	app := testSearchApp(t, repo, searcher, allowAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/messages?q=test+has:banana", ""))
+	body := readBody(t, resp)
+
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+	env := parseError(t, body)
+	if env.Error.Code != string(apierrors.ValidationError) {
+		t.Errorf("error code = %q, want %q", env.Error.Code, apierrors.ValidationError)
+	}
+}
+
 func TestSearchMessages_SearchUnavailable(t *testing.T) {
 	t.Parallel()
 	repo := newFakeChannelRepo()
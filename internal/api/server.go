@@ -89,7 +89,7 @@ func (h *ServerHandler) Update(c fiber.Ctx) error {
 
 	result := cfg.ToModel()
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ServerUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.ServerUpdate, result)
 	}
 
 	return httputil.Success(c, result)
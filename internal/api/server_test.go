@@ -74,6 +74,14 @@ func (r *fakeServerRepo) ClearBannerKey(_ context.Context) (*server.Config, erro
 	return r.cfg, nil
 }
 
+func (r *fakeServerRepo) SetOwner(_ context.Context, userID uuid.UUID) (*server.Config, error) {
+	if r.cfg == nil {
+		return nil, server.ErrNotFound
+	}
+	r.cfg.OwnerID = userID
+	return r.cfg, nil
+}
+
 func seedServerConfig() *fakeServerRepo {
 	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 	iconKey := "icon-abc"
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -24,6 +23,7 @@ import (
 	"github.com/uncord-chat/uncord-server/internal/presence"
 	"github.com/uncord-chat/uncord-server/internal/reaction"
 	"github.com/uncord-chat/uncord-server/internal/thread"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
 )
 
 // ThreadHandler serves thread endpoints.
@@ -126,7 +126,7 @@ func (h *ThreadHandler) CreateThread(c fiber.Ctx) error {
 	result := toThreadModel(t)
 
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ThreadCreate, result)
+		h.gateway.Enqueue(c.Context(), events.ThreadCreate, result)
 	}
 
 	return httputil.SuccessStatus(c, fiber.StatusCreated, result)
@@ -245,7 +245,7 @@ func (h *ThreadHandler) UpdateThread(c fiber.Ctx) error {
 	result := toThreadModel(updated)
 
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.ThreadUpdate, result)
+		h.gateway.Enqueue(c.Context(), events.ThreadUpdate, result)
 	}
 
 	return httputil.Success(c, result)
@@ -319,7 +319,7 @@ func (h *ThreadHandler) ListThreadMessages(c fiber.Ctx) error {
 		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
 	}
 
-	result := make([]models.Message, len(messages))
+	result := make([]messageModel, len(messages))
 	for i := range messages {
 		result[i] = buildMessageModel(&messages[i], messageEnrichment{
 			Attachments: attachmentMap[messages[i].ID],
@@ -425,18 +425,19 @@ func (h *ThreadHandler) CreateThreadMessage(c fiber.Ctx) error {
 	result := buildMessageModel(msg, messageEnrichment{Attachments: linked}, h.storage)
 
 	if h.gateway != nil {
-		h.gateway.Enqueue(events.MessageCreate, result)
+		h.gateway.Enqueue(c.Context(), events.MessageCreate, result)
 	}
 
 	if h.presence != nil && h.gateway != nil {
+		ctx := tracing.Detach(c.Context())
 		go func() {
-			existed, cErr := h.presence.ClearTyping(context.Background(), t.ChannelID, userID)
+			existed, cErr := h.presence.ClearTyping(ctx, t.ChannelID, userID)
 			if cErr != nil {
 				h.log.Warn().Err(cErr).Msg("Failed to clear typing on thread message send")
 				return
 			}
 			if existed {
-				h.gateway.Enqueue(events.TypingStop, models.TypingStopData{
+				h.gateway.Enqueue(ctx, events.TypingStop, models.TypingStopData{
 					ChannelID: t.ChannelID.String(),
 					UserID:    userID.String(),
 				})
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+	"github.com/uncord-chat/uncord-protocol/models"
+
+	"github.com/uncord-chat/uncord-server/internal/httputil"
+	"github.com/uncord-chat/uncord-server/internal/media"
+	"github.com/uncord-chat/uncord-server/internal/search"
+)
+
+// TypeaheadHandler serves the member, channel and custom emoji lookup endpoints used for autocompletion.
+type TypeaheadHandler struct {
+	typeahead *search.Typeahead
+	storage   media.StorageProvider
+	log       zerolog.Logger
+}
+
+// NewTypeaheadHandler creates a new typeahead handler. The storage provider builds the public URLs of emoji images.
+func NewTypeaheadHandler(typeahead *search.Typeahead, storage media.StorageProvider,
+	logger zerolog.Logger) *TypeaheadHandler {
+	return &TypeaheadHandler{typeahead: typeahead, storage: storage, log: logger}
+}
+
+// SearchMembers handles GET /api/v1/search/members. The optional channel_id parameter restricts results to members who
+// can view that channel, which is what a mention typeahead in that channel needs.
+func (h *TypeaheadHandler) SearchMembers(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+	limit, ok := h.limit(c)
+	if !ok {
+		return nil
+	}
+	channelID := c.Query("channel_id")
+	if channelID != "" {
+		if _, err := uuid.Parse(channelID); err != nil {
+			return httputil.Fail(c, fiber.StatusBadRequest, apierrors.InvalidChannelID, "Invalid channel_id format")
+		}
+	}
+
+	found, err := h.typeahead.Members(c, userID, c.Query("q"), channelID, limit)
+	if err != nil {
+		return h.mapTypeaheadError(c, err)
+	}
+	result := make([]models.Member, len(found))
+	for i := range found {
+		result[i] = found[i].ToModel()
+	}
+	return httputil.Success(c, result)
+}
+
+// SearchChannels handles GET /api/v1/search/channels. Only channels the caller can view are returned.
+func (h *TypeaheadHandler) SearchChannels(c fiber.Ctx) error {
+	userID, err := httputil.UserID(c)
+	if err != nil {
+		return err
+	}
+	limit, ok := h.limit(c)
+	if !ok {
+		return nil
+	}
+
+	found, err := h.typeahead.Channels(c, userID, c.Query("q"), limit)
+	if err != nil {
+		return h.mapTypeaheadError(c, err)
+	}
+	result := make([]models.Channel, len(found))
+	for i := range found {
+		result[i] = found[i].ToModel()
+	}
+	return httputil.Success(c, result)
+}
+
+// SearchEmoji handles GET /api/v1/search/emoji.
+func (h *TypeaheadHandler) SearchEmoji(c fiber.Ctx) error {
+	limit, ok := h.limit(c)
+	if !ok {
+		return nil
+	}
+
+	found, err := h.typeahead.Emoji(c, c.Query("q"), limit)
+	if err != nil {
+		return h.mapTypeaheadError(c, err)
+	}
+	result := make([]models.Emoji, len(found))
+	for i := range found {
+		result[i] = found[i].ToModel(h.storage.URL(found[i].StorageKey))
+	}
+	return httputil.Success(c, result)
+}
+
+// limit parses and clamps the limit query parameter. It returns false after writing an error response.
+func (h *TypeaheadHandler) limit(c fiber.Ctx) (int, bool) {
+	limit, ok := httputil.ParseIntQuery(c, "limit")
+	if !ok {
+		return 0, false
+	}
+	return search.ClampTypeaheadLimit(limit), true
+}
+
+func (h *TypeaheadHandler) mapTypeaheadError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, search.ErrEmptyQuery):
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError, "The q parameter is required")
+	case errors.Is(err, search.ErrInvalidFilter):
+		return httputil.Fail(c, fiber.StatusBadRequest, apierrors.ValidationError, err.Error())
+	default:
+		h.log.Error().Err(err).Str("handler", "typeahead").Msg("unhandled typeahead error")
+		return httputil.Fail(c, fiber.StatusInternalServerError, apierrors.InternalError, "An internal error occurred")
+	}
+}
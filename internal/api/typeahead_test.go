@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+	"github.com/uncord-chat/uncord-protocol/models"
+
+	"github.com/uncord-chat/uncord-server/internal/channel"
+	"github.com/uncord-chat/uncord-server/internal/emoji"
+	"github.com/uncord-chat/uncord-server/internal/member"
+	"github.com/uncord-chat/uncord-server/internal/search"
+)
+
+// fakeTypeaheadFinder implements the search finder interfaces for handler tests.
+type fakeTypeaheadFinder struct {
+	members  []member.WithProfile
+	emoji    []emoji.Emoji
+	channels *fakeChannelRepo
+	err      error
+	limit    int
+}
+
+func (f *fakeTypeaheadFinder) Search(_ context.Context, _ string, limit int) ([]member.WithProfile, error) {
+	f.limit = limit
+	return f.members, f.err
+}
+
+type fakeTypeaheadChannelFinder struct{ *fakeTypeaheadFinder }
+
+func (f fakeTypeaheadChannelFinder) Search(_ context.Context, _ string, ids []uuid.UUID,
+	_ int) ([]channel.Channel, error) {
+	var out []channel.Channel
+	for _, ch := range f.channels.channels {
+		for _, id := range ids {
+			if ch.ID == id {
+				out = append(out, ch)
+			}
+		}
+	}
+	return out, f.err
+}
+
+type fakeTypeaheadEmojiFinder struct{ *fakeTypeaheadFinder }
+
+func (f fakeTypeaheadEmojiFinder) Search(_ context.Context, _ string, _ int) ([]emoji.Emoji, error) {
+	return f.emoji, f.err
+}
+
+func testTypeaheadApp(t *testing.T, finder *fakeTypeaheadFinder, resolver search.PermissionFilter,
+	userID uuid.UUID) *fiber.App {
+	t.Helper()
+	if finder.channels == nil {
+		finder.channels = newFakeChannelRepo()
+	}
+	ta := search.NewTypeahead(finder, fakeTypeaheadChannelFinder{finder}, fakeTypeaheadEmojiFinder{finder},
+		finder.channels, resolver, allowAllResolver())
+	handler := NewTypeaheadHandler(ta, newFakeStorageForUpload(), zerolog.Nop())
+
+	app := fiber.New()
+	app.Use(fakeAuth(userID))
+	app.Get("/search/members", handler.SearchMembers)
+	app.Get("/search/channels", handler.SearchChannels)
+	app.Get("/search/emoji", handler.SearchEmoji)
+	return app
+}
+
+func TestSearchMembers_Success(t *testing.T) {
+	t.Parallel()
+	finder := &fakeTypeaheadFinder{members: []member.WithProfile{{UserID: uuid.New(), Username: "alice"}}}
+	app := testTypeaheadApp(t, finder, allowAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/members?q=ali", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusOK, body)
+	}
+
+	var members []models.Member
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &members); err != nil {
+		t.Fatalf("unmarshal members: %v", err)
+	}
+	if len(members) != 1 || members[0].User.Username != "alice" {
+		t.Errorf("members = %+v, want alice", members)
+	}
+	if finder.limit != search.DefaultTypeaheadLimit {
+		t.Errorf("limit = %d, want %d", finder.limit, search.DefaultTypeaheadLimit)
+	}
+}
+
+func TestSearchMembers_LimitClamped(t *testing.T) {
+	t.Parallel()
+	finder := &fakeTypeaheadFinder{}
+	app := testTypeaheadApp(t, finder, allowAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/members?q=ali&limit=500", ""))
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if finder.limit != search.MaxTypeaheadLimit {
+		t.Errorf("limit = %d, want %d", finder.limit, search.MaxTypeaheadLimit)
+	}
+
+	var members []models.Member
+	if err := json.Unmarshal(parseSuccess(t, readBody(t, resp)).Data, &members); err != nil || members == nil {
+		t.Errorf("data = %v (err %v), want an empty array", members, err)
+	}
+}
+
+func TestSearchMembers_EmptyQuery(t *testing.T) {
+	t.Parallel()
+	app := testTypeaheadApp(t, &fakeTypeaheadFinder{}, allowAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/members", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+	if code := parseError(t, body).Error.Code; code != string(apierrors.ValidationError) {
+		t.Errorf("error code = %q, want %q", code, apierrors.ValidationError)
+	}
+}
+
+func TestSearchMembers_InvalidChannelID(t *testing.T) {
+	t.Parallel()
+	app := testTypeaheadApp(t, &fakeTypeaheadFinder{}, allowAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/members?q=ali&channel_id=nope", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+	if code := parseError(t, body).Error.Code; code != string(apierrors.InvalidChannelID) {
+		t.Errorf("error code = %q, want %q", code, apierrors.InvalidChannelID)
+	}
+}
+
+func TestSearchMembers_InternalError(t *testing.T) {
+	t.Parallel()
+	app := testTypeaheadApp(t, &fakeTypeaheadFinder{err: errors.New("db down")}, allowAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/members?q=ali", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusInternalServerError)
+	}
+	if code := parseError(t, body).Error.Code; code != string(apierrors.InternalError) {
+		t.Errorf("error code = %q, want %q", code, apierrors.InternalError)
+	}
+}
+
+func TestSearchChannels_Success(t *testing.T) {
+	t.Parallel()
+	repo := newFakeChannelRepo()
+	ch := seedChannel(repo)
+	app := testTypeaheadApp(t, &fakeTypeaheadFinder{channels: repo}, allowAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/channels?q=gen", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusOK, body)
+	}
+
+	var channels []models.Channel
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &channels); err != nil {
+		t.Fatalf("unmarshal channels: %v", err)
+	}
+	if len(channels) != 1 || channels[0].ID != ch.ID.String() {
+		t.Errorf("channels = %+v, want %s", channels, ch.ID)
+	}
+}
+
+func TestSearchChannels_HiddenChannelsExcluded(t *testing.T) {
+	t.Parallel()
+	repo := newFakeChannelRepo()
+	seedChannel(repo)
+	app := testTypeaheadApp(t, &fakeTypeaheadFinder{channels: repo}, denyAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/channels?q=gen", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+
+	var channels []models.Channel
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &channels); err != nil {
+		t.Fatalf("unmarshal channels: %v", err)
+	}
+	if len(channels) != 0 {
+		t.Errorf("channels = %+v, want none", channels)
+	}
+}
+
+func TestSearchEmoji_Success(t *testing.T) {
+	t.Parallel()
+	finder := &fakeTypeaheadFinder{emoji: []emoji.Emoji{{ID: uuid.New(), Name: "party", StorageKey: "emoji/party.png"}}}
+	app := testTypeaheadApp(t, finder, allowAllResolver(), uuid.New())
+
+	resp := doReq(t, app, jsonReq(http.MethodGet, "/search/emoji?q=par", ""))
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", resp.StatusCode, fiber.StatusOK, body)
+	}
+
+	var result []models.Emoji
+	if err := json.Unmarshal(parseSuccess(t, body).Data, &result); err != nil {
+		t.Fatalf("unmarshal emoji: %v", err)
+	}
+	if len(result) != 1 || result[0].URL != "http://localhost:8080/media/emoji/party.png" {
+		t.Errorf("emoji = %+v, want party with its storage URL", result)
+	}
+}
@@ -51,7 +51,7 @@ func (h *TypingHandler) StartTyping(c fiber.Ctx) error {
 	}
 
 	if created && h.gateway != nil {
-		h.gateway.Enqueue(events.TypingStart, models.TypingStartData{
+		h.gateway.Enqueue(c.Context(), events.TypingStart, models.TypingStartData{
 			ChannelID: channelID.String(),
 			UserID:    userID.String(),
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -81,7 +81,7 @@ func (h *TypingHandler) StopTyping(c fiber.Ctx) error {
 	}
 
 	if existed && h.gateway != nil {
-		h.gateway.Enqueue(events.TypingStop, models.TypingStopData{
+		h.gateway.Enqueue(c.Context(), events.TypingStop, models.TypingStopData{
 			ChannelID: channelID.String(),
 			UserID:    userID.String(),
 		})
@@ -8,23 +8,40 @@ import (
 	"github.com/google/uuid"
 )
 
-// ErrNotFound is returned when one or more attachments could not be found or are not available for linking.
-var ErrNotFound = errors.New("one or more attachments not found or not available for linking")
+// Sentinel errors for attachment operations.
+var (
+	ErrNotFound             = errors.New("one or more attachments not found or not available for linking")
+	ErrUploadNotFound       = errors.New("upload session not found")
+	ErrUploadOffsetMismatch = errors.New("chunk offset does not match the upload session offset")
+)
+
+// ScanStatus is the malware scan verdict recorded for an attachment.
+type ScanStatus string
+
+// Scan status values. Only clean attachments can be linked to a message.
+const (
+	ScanClean       ScanStatus = "clean"
+	ScanQuarantined ScanStatus = "quarantined"
+)
 
 // Attachment holds the fields read from the database for a message attachment.
 type Attachment struct {
-	ID           uuid.UUID
-	MessageID    *uuid.UUID
-	ChannelID    uuid.UUID
-	UploaderID   uuid.UUID
-	Filename     string
-	ContentType  string
-	SizeBytes    int64
-	StorageKey   string
-	Width        *int
-	Height       *int
-	ThumbnailKey *string
-	CreatedAt    time.Time
+	ID            uuid.UUID
+	MessageID     *uuid.UUID
+	ChannelID     uuid.UUID
+	UploaderID    uuid.UUID
+	Filename      string
+	ContentType   string
+	SizeBytes     int64
+	StorageKey    string
+	Width         *int
+	Height        *int
+	ThumbnailKey  *string
+	DurationMS    *int
+	Waveform      []byte
+	ScanStatus    ScanStatus
+	ScanSignature *string
+	CreatedAt     time.Time
 }
 
 // CreateParams groups the inputs for inserting a new pending attachment record.
@@ -37,6 +54,74 @@ type CreateParams struct {
 	StorageKey  string
 	Width       *int
 	Height      *int
+
+	// ScanStatus defaults to ScanClean when empty. ScanSignature names the matched signature for quarantined files.
+	ScanStatus    ScanStatus
+	ScanSignature *string
+}
+
+// Upload holds the state of a resumable upload session. Chunks are appended in order, so ReceivedBytes is both the
+// number of bytes stored so far and the offset the next chunk must start at.
+type Upload struct {
+	ID            uuid.UUID
+	ChannelID     uuid.UUID
+	UploaderID    uuid.UUID
+	Filename      string
+	ContentType   string
+	SizeBytes     int64
+	SHA256        string
+	ReceivedBytes int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Complete reports whether every declared byte of the upload has been received.
+func (u *Upload) Complete() bool {
+	return u.ReceivedBytes == u.SizeBytes
+}
+
+// CreateUploadParams groups the inputs for starting a resumable upload session.
+type CreateUploadParams struct {
+	ChannelID   uuid.UUID
+	UploaderID  uuid.UUID
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	SHA256      string
+}
+
+// MediaMetadata holds the media properties extracted by the media worker and recorded by Repository.SetMediaMetadata.
+type MediaMetadata struct {
+	Width      *int
+	Height     *int
+	DurationMS *int
+	Waveform   []byte
+}
+
+// MaxEncryptedMetadataBytes is the largest encrypted metadata blob a DM attachment may carry.
+const MaxEncryptedMetadataBytes = 8192
+
+// DMAttachment holds the fields read from the database for an end-to-end encrypted DM attachment. The file was
+// encrypted by the uploading client, so the server knows only its size; EncryptedMetadata is opaque to the server and
+// returned exactly as it was uploaded.
+type DMAttachment struct {
+	ID                uuid.UUID
+	MessageID         *uuid.UUID
+	DMChannelID       uuid.UUID
+	UploaderID        uuid.UUID
+	SizeBytes         int64
+	StorageKey        string
+	EncryptedMetadata []byte
+	CreatedAt         time.Time
+}
+
+// CreateDMParams groups the inputs for inserting a new pending DM attachment record.
+type CreateDMParams struct {
+	DMChannelID       uuid.UUID
+	UploaderID        uuid.UUID
+	SizeBytes         int64
+	StorageKey        string
+	EncryptedMetadata []byte
 }
 
 // Repository defines the data-access contract for attachment operations.
@@ -48,8 +133,8 @@ type Repository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*Attachment, error)
 
 	// LinkToMessage atomically assigns the given attachment IDs to a message. Only pending attachments (message_id IS
-	// NULL) owned by uploaderID are linked. Returns ErrNotFound if any ID is missing, already linked, or belongs to a
-	// different user.
+	// NULL) owned by uploaderID that passed the malware scan are linked. Returns ErrNotFound if any ID is missing,
+	// already linked, quarantined, or belongs to a different user.
 	LinkToMessage(ctx context.Context, attachmentIDs []uuid.UUID, messageID uuid.UUID, uploaderID uuid.UUID) ([]Attachment, error)
 
 	// ListByMessage returns all attachments linked to the given message, ordered by creation time.
@@ -61,8 +146,47 @@ type Repository interface {
 	// SetThumbnailKey records the storage key of a generated thumbnail.
 	SetThumbnailKey(ctx context.Context, id uuid.UUID, thumbnailKey string) error
 
-	// PurgeOrphans deletes pending attachments older than the given threshold and returns their storage keys (including
-	// thumbnail keys) so the caller can remove the files.
+	// SetMediaMetadata records the dimensions, duration, and waveform extracted by the media worker. Nil fields and an
+	// empty waveform leave the stored values unchanged.
+	SetMediaMetadata(ctx context.Context, id uuid.UUID, meta MediaMetadata) error
+
+	// CreateUpload starts a new resumable upload session with no bytes received.
+	CreateUpload(ctx context.Context, params CreateUploadParams) (*Upload, error)
+
+	// GetUpload returns the upload session with the given ID. Returns ErrUploadNotFound if the session does not exist or
+	// belongs to a different user.
+	GetUpload(ctx context.Context, id uuid.UUID, uploaderID uuid.UUID) (*Upload, error)
+
+	// AppendUploadChunk records a stored chunk of size bytes starting at offset and advances the session. Returns
+	// ErrUploadOffsetMismatch if offset is not the session's current offset, which happens when a chunk is retried after
+	// it was already recorded or two chunks race.
+	AppendUploadChunk(ctx context.Context, id uuid.UUID, uploaderID uuid.UUID, offset, size int64, storageKey string) (*Upload, error)
+
+	// ListUploadChunks returns the storage keys of the chunks recorded for an upload session, ordered by offset.
+	ListUploadChunks(ctx context.Context, id uuid.UUID) ([]string, error)
+
+	// DeleteUpload removes an upload session and returns the storage keys of its chunks so the caller can remove the
+	// files. Returns ErrUploadNotFound if the session does not exist or belongs to a different user.
+	DeleteUpload(ctx context.Context, id uuid.UUID, uploaderID uuid.UUID) ([]string, error)
+
+	// CreateDM inserts a new pending DM attachment (message_id is NULL).
+	CreateDM(ctx context.Context, params CreateDMParams) (*DMAttachment, error)
+
+	// GetDM returns a single DM attachment by ID. Returns ErrNotFound if it does not exist or belongs to a different DM
+	// channel.
+	GetDM(ctx context.Context, id uuid.UUID, dmChannelID uuid.UUID) (*DMAttachment, error)
+
+	// LinkDMToMessage atomically assigns the given DM attachment IDs to a message. Only pending attachments uploaded by
+	// uploaderID to the same DM channel are linked. Returns ErrNotFound if any ID is missing, already linked, or belongs
+	// to a different user or channel.
+	LinkDMToMessage(ctx context.Context, attachmentIDs []uuid.UUID, messageID, dmChannelID, uploaderID uuid.UUID) ([]DMAttachment, error)
+
+	// ListDMByMessages returns DM attachments for multiple messages in a single query, keyed by message ID.
+	ListDMByMessages(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]DMAttachment, error)
+
+	// PurgeOrphans deletes pending attachments (including DM attachments) and upload sessions that have not been touched
+	// since the given threshold and returns their storage keys (including thumbnail and chunk keys) so the caller can
+	// remove the files.
 	PurgeOrphans(ctx context.Context, olderThan time.Time) ([]string, error)
 }
 
@@ -16,6 +16,36 @@ func TestSentinelErrors(t *testing.T) {
 	if ErrNotFound.Error() == "" {
 		t.Error("ErrNotFound should have a non-empty message")
 	}
+	if errors.Is(ErrUploadNotFound, ErrNotFound) {
+		t.Error("ErrUploadNotFound should be distinct from ErrNotFound")
+	}
+	if errors.Is(ErrUploadOffsetMismatch, ErrUploadNotFound) {
+		t.Error("ErrUploadOffsetMismatch should be distinct from ErrUploadNotFound")
+	}
+}
+
+func TestUploadComplete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		size     int64
+		received int64
+		want     bool
+	}{
+		{"nothing received", 100, 0, false},
+		{"partially received", 100, 60, false},
+		{"fully received", 100, 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			u := Upload{SizeBytes: tt.size, ReceivedBytes: tt.received}
+			if got := u.Complete(); got != tt.want {
+				t.Errorf("Complete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
 }
 
 func TestCreateParamsZeroValue(t *testing.T) {
@@ -62,21 +92,28 @@ func TestScanAttachment(t *testing.T) {
 	width := 1920
 	height := 1080
 	thumbnail := "thumb/abc.webp"
+	duration := 12500
+	waveform := []byte{0, 64, 128, 255}
+	signature := "Eicar-Test-Signature"
 
 	row := &fakeRow{
 		a: Attachment{
-			ID:           id,
-			MessageID:    &msgID,
-			ChannelID:    chanID,
-			UploaderID:   uploaderID,
-			Filename:     "photo.png",
-			ContentType:  "image/png",
-			SizeBytes:    204800,
-			StorageKey:   "uploads/photo.png",
-			Width:        &width,
-			Height:       &height,
-			ThumbnailKey: &thumbnail,
-			CreatedAt:    now,
+			ID:            id,
+			MessageID:     &msgID,
+			ChannelID:     chanID,
+			UploaderID:    uploaderID,
+			Filename:      "photo.png",
+			ContentType:   "image/png",
+			SizeBytes:     204800,
+			StorageKey:    "uploads/photo.png",
+			Width:         &width,
+			Height:        &height,
+			ThumbnailKey:  &thumbnail,
+			DurationMS:    &duration,
+			Waveform:      waveform,
+			ScanStatus:    ScanQuarantined,
+			ScanSignature: &signature,
+			CreatedAt:     now,
 		},
 	}
 
@@ -108,6 +145,18 @@ func TestScanAttachment(t *testing.T) {
 	if a.ThumbnailKey == nil || *a.ThumbnailKey != thumbnail {
 		t.Errorf("ThumbnailKey = %v, want %q", a.ThumbnailKey, thumbnail)
 	}
+	if a.DurationMS == nil || *a.DurationMS != duration {
+		t.Errorf("DurationMS = %v, want %d", a.DurationMS, duration)
+	}
+	if string(a.Waveform) != string(waveform) {
+		t.Errorf("Waveform = %v, want %v", a.Waveform, waveform)
+	}
+	if a.ScanStatus != ScanQuarantined {
+		t.Errorf("ScanStatus = %q, want %q", a.ScanStatus, ScanQuarantined)
+	}
+	if a.ScanSignature == nil || *a.ScanSignature != signature {
+		t.Errorf("ScanSignature = %v, want %q", a.ScanSignature, signature)
+	}
 	if !a.CreatedAt.Equal(now) {
 		t.Errorf("CreatedAt = %v, want %v", a.CreatedAt, now)
 	}
@@ -145,6 +194,12 @@ func TestScanAttachment_NilOptionalFields(t *testing.T) {
 	if a.ThumbnailKey != nil {
 		t.Errorf("ThumbnailKey = %v, want nil", a.ThumbnailKey)
 	}
+	if a.DurationMS != nil {
+		t.Errorf("DurationMS = %v, want nil", a.DurationMS)
+	}
+	if a.Waveform != nil {
+		t.Errorf("Waveform = %v, want nil", a.Waveform)
+	}
 }
 
 func TestScanAttachment_Error(t *testing.T) {
@@ -250,9 +305,9 @@ func (r *fakeRow) Scan(dest ...any) error {
 		return r.err
 	}
 
-	// scanAttachment scans 12 columns: id, message_id, channel_id, uploader_id, filename, content_type, size_bytes,
-	// storage_key, width, height, thumbnail_key, created_at.
-	if len(dest) != 12 {
+	// scanAttachment scans 16 columns: id, message_id, channel_id, uploader_id, filename, content_type, size_bytes,
+	// storage_key, width, height, thumbnail_key, duration_ms, waveform, scan_status, scan_signature, created_at.
+	if len(dest) != 16 {
 		return errors.New("unexpected number of scan destinations")
 	}
 	*dest[0].(*uuid.UUID) = r.a.ID
@@ -266,7 +321,11 @@ func (r *fakeRow) Scan(dest ...any) error {
 	*dest[8].(**int) = r.a.Width
 	*dest[9].(**int) = r.a.Height
 	*dest[10].(**string) = r.a.ThumbnailKey
-	*dest[11].(*time.Time) = r.a.CreatedAt
+	*dest[11].(**int) = r.a.DurationMS
+	*dest[12].(*[]byte) = r.a.Waveform
+	*dest[13].(*ScanStatus) = r.a.ScanStatus
+	*dest[14].(**string) = r.a.ScanSignature
+	*dest[15].(*time.Time) = r.a.CreatedAt
 	return nil
 }
 
@@ -297,7 +356,7 @@ func (r *fakeRows) Scan(dest ...any) error {
 	a := r.attachments[r.pos]
 	r.pos++
 
-	if len(dest) != 12 {
+	if len(dest) != 16 {
 		return errors.New("unexpected number of scan destinations")
 	}
 	*dest[0].(*uuid.UUID) = a.ID
@@ -311,7 +370,11 @@ func (r *fakeRows) Scan(dest ...any) error {
 	*dest[8].(**int) = a.Width
 	*dest[9].(**int) = a.Height
 	*dest[10].(**string) = a.ThumbnailKey
-	*dest[11].(*time.Time) = a.CreatedAt
+	*dest[11].(**int) = a.DurationMS
+	*dest[12].(*[]byte) = a.Waveform
+	*dest[13].(*ScanStatus) = a.ScanStatus
+	*dest[14].(**string) = a.ScanSignature
+	*dest[15].(*time.Time) = a.CreatedAt
 	return nil
 }
 
@@ -1,4 +1,8 @@
 // Package attachment manages file attachments associated with messages. Attachments are created in a pending state and
 // linked to a message in a separate step, allowing uploads to complete before the message is sent. The Repository
 // interface provides methods for creation, linkage, thumbnail storage, and orphan cleanup.
+//
+// Attachments to end-to-end encrypted direct messages are stored separately as DMAttachment records. Their files are
+// encrypted by the client before upload, so they are not scanned, thumbnailed, or indexed, and their metadata is kept
+// as an opaque blob that only the DM participants' clients can decrypt.
 package attachment
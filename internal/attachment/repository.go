@@ -9,14 +9,24 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
+	"github.com/uncord-chat/uncord-server/internal/postgres"
 )
 
 const selectColumns = `id, message_id, channel_id, uploader_id, filename, content_type,
-size_bytes, storage_key, width, height, thumbnail_key, created_at`
+size_bytes, storage_key, width, height, thumbnail_key, duration_ms, waveform, scan_status, scan_signature, created_at`
+
+const selectDMColumns = `id, message_id, dm_channel_id, uploader_id, size_bytes, storage_key, encrypted_metadata,
+created_at`
+
+const selectUploadColumns = `id, channel_id, uploader_id, filename, content_type, size_bytes, sha256, received_bytes,
+created_at, updated_at`
 
 // PGRepository implements Repository using PostgreSQL.
 type PGRepository struct {
-	db *pgxpool.Pool
+	db             *pgxpool.Pool
+	noSearchOutbox bool
 }
 
 // NewPGRepository creates a new PostgreSQL-backed attachment repository.
@@ -24,14 +34,25 @@ func NewPGRepository(db *pgxpool.Pool) *PGRepository {
 	return &PGRepository{db: db}
 }
 
+// DisableSearchOutbox stops attachment links from enqueueing search index updates for their messages. See
+// message.PGRepository.DisableSearchOutbox.
+func (r *PGRepository) DisableSearchOutbox() {
+	r.noSearchOutbox = true
+}
+
 // Create inserts a new pending attachment record with message_id NULL.
 func (r *PGRepository) Create(ctx context.Context, params CreateParams) (*Attachment, error) {
+	status := params.ScanStatus
+	if status == "" {
+		status = ScanClean
+	}
 	row := r.db.QueryRow(ctx,
-		`INSERT INTO message_attachments (channel_id, uploader_id, filename, content_type, size_bytes, storage_key, width, height)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`INSERT INTO message_attachments (channel_id, uploader_id, filename, content_type, size_bytes, storage_key, width,
+		                                  height, scan_status, scan_signature)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		 RETURNING `+selectColumns,
 		params.ChannelID, params.UploaderID, params.Filename, params.ContentType,
-		params.SizeBytes, params.StorageKey, params.Width, params.Height,
+		params.SizeBytes, params.StorageKey, params.Width, params.Height, status, params.ScanSignature,
 	)
 	return scanAttachment(row)
 }
@@ -51,35 +72,49 @@ func (r *PGRepository) GetByID(ctx context.Context, id uuid.UUID) (*Attachment,
 	return a, nil
 }
 
-// LinkToMessage atomically assigns the given attachment IDs to a message. Only pending attachments owned by uploaderID
-// are linked. Returns ErrNotFound if the number of updated rows does not match the number of requested IDs.
+// LinkToMessage atomically assigns the given attachment IDs to a message. Only pending, clean attachments owned by
+// uploaderID are linked. Returns ErrNotFound if the number of updated rows does not match the number of requested IDs.
+// Attachments appear in the message's search document, so the message is queued for reindexing in the same transaction.
 func (r *PGRepository) LinkToMessage(ctx context.Context, attachmentIDs []uuid.UUID, messageID uuid.UUID, uploaderID uuid.UUID) ([]Attachment, error) {
-	rows, err := r.db.Query(ctx,
-		`UPDATE message_attachments
-		 SET message_id = $1
-		 WHERE id = ANY($2) AND uploader_id = $3 AND message_id IS NULL
-		 RETURNING `+selectColumns,
-		messageID, attachmentIDs, uploaderID,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("link attachments to message: %w", err)
-	}
-	defer rows.Close()
-
 	var result []Attachment
-	for rows.Next() {
-		a, err := scanAttachment(rows)
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			`UPDATE message_attachments
+			 SET message_id = $1
+			 WHERE id = ANY($2) AND uploader_id = $3 AND message_id IS NULL AND scan_status = 'clean'
+			 RETURNING `+selectColumns,
+			messageID, attachmentIDs, uploaderID,
+		)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("link attachments to message: %w", err)
 		}
-		result = append(result, *a)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate linked attachments: %w", err)
-	}
+		defer rows.Close()
 
-	if len(result) != len(attachmentIDs) {
-		return nil, ErrNotFound
+		for rows.Next() {
+			a, err := scanAttachment(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, *a)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate linked attachments: %w", err)
+		}
+
+		if len(result) != len(attachmentIDs) {
+			return ErrNotFound
+		}
+		ids := []uuid.UUID{messageID}
+		if err := message.Touch(ctx, tx, ids); err != nil {
+			return err
+		}
+		if r.noSearchOutbox {
+			return nil
+		}
+		return message.EnqueueIndex(ctx, tx, ids)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return result, nil
 }
@@ -143,34 +178,332 @@ func (r *PGRepository) SetThumbnailKey(ctx context.Context, id uuid.UUID, thumbn
 	return nil
 }
 
-// PurgeOrphans deletes pending attachments older than the given threshold and returns their storage keys (including
-// thumbnail keys) for file cleanup.
-func (r *PGRepository) PurgeOrphans(ctx context.Context, olderThan time.Time) ([]string, error) {
+// SetMediaMetadata records media properties for the given attachment. Each column is only overwritten when the
+// corresponding field is set, so probe and waveform jobs can complete in either order.
+func (r *PGRepository) SetMediaMetadata(ctx context.Context, id uuid.UUID, meta MediaMetadata) error {
+	var waveform []byte
+	if len(meta.Waveform) > 0 {
+		waveform = meta.Waveform
+	}
+	tag, err := r.db.Exec(ctx,
+		`UPDATE message_attachments
+		 SET width = COALESCE($1, width),
+		     height = COALESCE($2, height),
+		     duration_ms = COALESCE($3, duration_ms),
+		     waveform = COALESCE($4, waveform)
+		 WHERE id = $5`,
+		meta.Width, meta.Height, meta.DurationMS, waveform, id,
+	)
+	if err != nil {
+		return fmt.Errorf("set media metadata: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateUpload inserts a new resumable upload session.
+func (r *PGRepository) CreateUpload(ctx context.Context, params CreateUploadParams) (*Upload, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO attachment_uploads (channel_id, uploader_id, filename, content_type, size_bytes, sha256)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+selectUploadColumns,
+		params.ChannelID, params.UploaderID, params.Filename, params.ContentType, params.SizeBytes, params.SHA256,
+	)
+	u, err := scanUpload(row)
+	if err != nil {
+		return nil, fmt.Errorf("create upload: %w", err)
+	}
+	return u, nil
+}
+
+// GetUpload returns the upload session with the given ID owned by uploaderID.
+func (r *PGRepository) GetUpload(ctx context.Context, id uuid.UUID, uploaderID uuid.UUID) (*Upload, error) {
+	row := r.db.QueryRow(ctx,
+		"SELECT "+selectUploadColumns+" FROM attachment_uploads WHERE id = $1 AND uploader_id = $2",
+		id, uploaderID,
+	)
+	u, err := scanUpload(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("query upload by id: %w", err)
+	}
+	return u, nil
+}
+
+// AppendUploadChunk advances the session offset and records the chunk in a single transaction. The offset condition in
+// the UPDATE serialises concurrent appends: only one chunk can claim a given offset.
+func (r *PGRepository) AppendUploadChunk(ctx context.Context, id uuid.UUID, uploaderID uuid.UUID, offset, size int64, storageKey string) (*Upload, error) {
+	var u *Upload
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx,
+			`UPDATE attachment_uploads
+			 SET received_bytes = received_bytes + $1, updated_at = NOW()
+			 WHERE id = $2 AND uploader_id = $3 AND received_bytes = $4 AND received_bytes + $1 <= size_bytes
+			 RETURNING `+selectUploadColumns,
+			size, id, uploaderID, offset,
+		)
+		var err error
+		u, err = scanUpload(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if err := tx.QueryRow(ctx,
+				"SELECT EXISTS(SELECT 1 FROM attachment_uploads WHERE id = $1 AND uploader_id = $2)", id, uploaderID,
+			).Scan(&exists); err != nil {
+				return fmt.Errorf("check upload exists: %w", err)
+			}
+			if !exists {
+				return ErrUploadNotFound
+			}
+			return ErrUploadOffsetMismatch
+		}
+		if err != nil {
+			return fmt.Errorf("advance upload offset: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO attachment_upload_chunks (upload_id, offset_bytes, size_bytes, storage_key)
+			 VALUES ($1, $2, $3, $4)`,
+			id, offset, size, storageKey,
+		); err != nil {
+			return fmt.Errorf("insert upload chunk: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ListUploadChunks returns the storage keys of the chunks recorded for an upload session, ordered by offset.
+func (r *PGRepository) ListUploadChunks(ctx context.Context, id uuid.UUID) ([]string, error) {
 	rows, err := r.db.Query(ctx,
-		`DELETE FROM message_attachments
-		 WHERE message_id IS NULL AND created_at < $1
-		 RETURNING storage_key, thumbnail_key`,
-		olderThan,
+		"SELECT storage_key FROM attachment_upload_chunks WHERE upload_id = $1 ORDER BY offset_bytes",
+		id,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("purge orphan attachments: %w", err)
+		return nil, fmt.Errorf("query upload chunks: %w", err)
 	}
 	defer rows.Close()
+	return collectKeys(rows)
+}
 
+// DeleteUpload removes an upload session owned by uploaderID and returns its chunk storage keys. The session row is
+// locked before the chunks are read so a concurrent append cannot record a chunk that the caller never learns about.
+// The chunk rows themselves are removed by the foreign key cascade.
+func (r *PGRepository) DeleteUpload(ctx context.Context, id uuid.UUID, uploaderID uuid.UUID) ([]string, error) {
 	var keys []string
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		var lockedID uuid.UUID
+		err := tx.QueryRow(ctx,
+			"SELECT id FROM attachment_uploads WHERE id = $1 AND uploader_id = $2 FOR UPDATE", id, uploaderID,
+		).Scan(&lockedID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUploadNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("lock upload: %w", err)
+		}
+
+		rows, err := tx.Query(ctx,
+			"SELECT storage_key FROM attachment_upload_chunks WHERE upload_id = $1 ORDER BY offset_bytes", id,
+		)
+		if err != nil {
+			return fmt.Errorf("query upload chunks: %w", err)
+		}
+		keys, err = collectKeys(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM attachment_uploads WHERE id = $1", id); err != nil {
+			return fmt.Errorf("delete upload: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateDM inserts a new pending DM attachment record with message_id NULL.
+func (r *PGRepository) CreateDM(ctx context.Context, params CreateDMParams) (*DMAttachment, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO dm_attachments (dm_channel_id, uploader_id, size_bytes, storage_key, encrypted_metadata)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+selectDMColumns,
+		params.DMChannelID, params.UploaderID, params.SizeBytes, params.StorageKey, params.EncryptedMetadata,
+	)
+	return scanDMAttachment(row)
+}
+
+// GetDM returns a single DM attachment by ID, provided it belongs to the given DM channel.
+func (r *PGRepository) GetDM(ctx context.Context, id uuid.UUID, dmChannelID uuid.UUID) (*DMAttachment, error) {
+	row := r.db.QueryRow(ctx,
+		"SELECT "+selectDMColumns+" FROM dm_attachments WHERE id = $1 AND dm_channel_id = $2", id, dmChannelID,
+	)
+	a, err := scanDMAttachment(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("query dm attachment by id: %w", err)
+	}
+	return a, nil
+}
+
+// LinkDMToMessage atomically assigns the given DM attachment IDs to a message. Only pending attachments uploaded by
+// uploaderID to dmChannelID are linked. Returns ErrNotFound if the number of updated rows does not match the number of
+// requested IDs. DM messages are not indexed for search, so unlike LinkToMessage nothing is queued for reindexing.
+func (r *PGRepository) LinkDMToMessage(ctx context.Context, attachmentIDs []uuid.UUID, messageID, dmChannelID, uploaderID uuid.UUID) ([]DMAttachment, error) {
+	var result []DMAttachment
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			`UPDATE dm_attachments
+			 SET message_id = $1
+			 WHERE id = ANY($2) AND dm_channel_id = $3 AND uploader_id = $4 AND message_id IS NULL
+			 RETURNING `+selectDMColumns,
+			messageID, attachmentIDs, dmChannelID, uploaderID,
+		)
+		if err != nil {
+			return fmt.Errorf("link dm attachments to message: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			a, err := scanDMAttachment(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, *a)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate linked dm attachments: %w", err)
+		}
+
+		if len(result) != len(attachmentIDs) {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListDMByMessages returns DM attachments for multiple messages in a single query, keyed by message ID.
+func (r *PGRepository) ListDMByMessages(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]DMAttachment, error) {
+	if len(messageIDs) == 0 {
+		return map[uuid.UUID][]DMAttachment{}, nil
+	}
+
+	rows, err := r.db.Query(ctx,
+		"SELECT "+selectDMColumns+" FROM dm_attachments WHERE message_id = ANY($1) ORDER BY created_at",
+		messageIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query dm attachments by messages: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[uuid.UUID][]DMAttachment, len(messageIDs))
 	for rows.Next() {
-		var storageKey string
-		var thumbnailKey *string
-		if err := rows.Scan(&storageKey, &thumbnailKey); err != nil {
-			return nil, fmt.Errorf("scan orphan key: %w", err)
+		a, err := scanDMAttachment(rows)
+		if err != nil {
+			return nil, err
 		}
-		keys = append(keys, storageKey)
-		if thumbnailKey != nil {
-			keys = append(keys, *thumbnailKey)
+		if a.MessageID != nil {
+			result[*a.MessageID] = append(result[*a.MessageID], *a)
 		}
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate orphan keys: %w", err)
+		return nil, fmt.Errorf("iterate dm attachments: %w", err)
+	}
+	return result, nil
+}
+
+// PurgeOrphans deletes pending attachments and DM attachments older than the given threshold, along with upload sessions
+// that have not received a chunk since the threshold, and returns their storage keys (including thumbnail and chunk
+// keys) for file cleanup.
+func (r *PGRepository) PurgeOrphans(ctx context.Context, olderThan time.Time) ([]string, error) {
+	var keys []string
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			`DELETE FROM message_attachments
+			 WHERE message_id IS NULL AND created_at < $1
+			 RETURNING storage_key, thumbnail_key`,
+			olderThan,
+		)
+		if err != nil {
+			return fmt.Errorf("purge orphan attachments: %w", err)
+		}
+		for rows.Next() {
+			var storageKey string
+			var thumbnailKey *string
+			if err := rows.Scan(&storageKey, &thumbnailKey); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan orphan key: %w", err)
+			}
+			keys = append(keys, storageKey)
+			if thumbnailKey != nil {
+				keys = append(keys, *thumbnailKey)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate orphan keys: %w", err)
+		}
+
+		rows, err = tx.Query(ctx,
+			`DELETE FROM dm_attachments
+			 WHERE message_id IS NULL AND created_at < $1
+			 RETURNING storage_key`,
+			olderThan,
+		)
+		if err != nil {
+			return fmt.Errorf("purge orphan dm attachments: %w", err)
+		}
+		dmKeys, err := collectKeys(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		keys = append(keys, dmKeys...)
+
+		// Abandoned upload sessions. The chunk keys are read before the sessions are deleted because the cascade that
+		// removes the chunk rows cannot return them.
+		rows, err = tx.Query(ctx,
+			`SELECT c.storage_key
+			 FROM attachment_upload_chunks c
+			 JOIN attachment_uploads u ON u.id = c.upload_id
+			 WHERE u.updated_at < $1
+			 FOR UPDATE OF u`,
+			olderThan,
+		)
+		if err != nil {
+			return fmt.Errorf("query abandoned upload chunks: %w", err)
+		}
+		chunkKeys, err := collectKeys(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		keys = append(keys, chunkKeys...)
+
+		if _, err := tx.Exec(ctx, "DELETE FROM attachment_uploads WHERE updated_at < $1", olderThan); err != nil {
+			return fmt.Errorf("purge abandoned uploads: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return keys, nil
 }
@@ -179,7 +512,8 @@ func scanAttachment(row pgx.Row) (*Attachment, error) {
 	var a Attachment
 	err := row.Scan(
 		&a.ID, &a.MessageID, &a.ChannelID, &a.UploaderID, &a.Filename, &a.ContentType,
-		&a.SizeBytes, &a.StorageKey, &a.Width, &a.Height, &a.ThumbnailKey, &a.CreatedAt,
+		&a.SizeBytes, &a.StorageKey, &a.Width, &a.Height, &a.ThumbnailKey, &a.DurationMS, &a.Waveform,
+		&a.ScanStatus, &a.ScanSignature, &a.CreatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("scan attachment: %w", err)
@@ -187,6 +521,18 @@ func scanAttachment(row pgx.Row) (*Attachment, error) {
 	return &a, nil
 }
 
+func scanDMAttachment(row pgx.Row) (*DMAttachment, error) {
+	var a DMAttachment
+	err := row.Scan(
+		&a.ID, &a.MessageID, &a.DMChannelID, &a.UploaderID, &a.SizeBytes, &a.StorageKey, &a.EncryptedMetadata,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scan dm attachment: %w", err)
+	}
+	return &a, nil
+}
+
 func collectAttachments(rows pgx.Rows) ([]Attachment, error) {
 	var result []Attachment
 	for rows.Next() {
@@ -201,3 +547,30 @@ func collectAttachments(rows pgx.Rows) ([]Attachment, error) {
 	}
 	return result, nil
 }
+
+func scanUpload(row pgx.Row) (*Upload, error) {
+	var u Upload
+	err := row.Scan(
+		&u.ID, &u.ChannelID, &u.UploaderID, &u.Filename, &u.ContentType, &u.SizeBytes, &u.SHA256, &u.ReceivedBytes,
+		&u.CreatedAt, &u.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func collectKeys(rows pgx.Rows) ([]string, error) {
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("scan storage key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate storage keys: %w", err)
+	}
+	return keys, nil
+}
@@ -43,6 +43,8 @@ const (
 	MessagePin    ActionType = "message.pin"
 	MessageUnpin  ActionType = "message.unpin"
 
+	AttachmentQuarantine ActionType = "attachment.quarantine"
+
 	InviteCreate ActionType = "invite.create"
 	InviteDelete ActionType = "invite.delete"
 
@@ -50,7 +52,9 @@ const (
 	EmojiUpdate ActionType = "emoji.update"
 	EmojiDelete ActionType = "emoji.delete"
 
-	ServerUpdate ActionType = "server.update"
+	ServerUpdate  ActionType = "server.update"
+	ServerExport  ActionType = "server.export"
+	SearchReindex ActionType = "search.reindex"
 
 	OnboardingUpdate ActionType = "onboarding.update"
 
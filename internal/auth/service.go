@@ -587,23 +587,8 @@ func (s *Service) DisableMFA(ctx context.Context, userID uuid.UUID, password, co
 		return ErrMFANotEnabled
 	}
 
-	if !s.config.MFAConfigured() {
-		return ErrMFANotConfigured
-	}
-
-	secret, err := DecryptTOTPSecret(*creds.MFASecret, s.config.MFAEncryptionKey.Expose())
-	if err != nil {
-		return fmt.Errorf("decrypt MFA secret: %w", err)
-	}
-
-	if !totp.Validate(code, secret) {
-		// The code did not match TOTP; try recovery codes.
-		if err := s.tryRecoveryCode(ctx, userID, code); err != nil {
-			if errors.Is(err, ErrInvalidMFACode) {
-				return ErrInvalidMFACode
-			}
-			return fmt.Errorf("try recovery code during MFA disable: %w", err)
-		}
+	if err := s.verifyMFACode(ctx, creds, code); err != nil {
+		return err
 	}
 
 	if err := s.users.DisableMFA(ctx, userID); err != nil {
@@ -677,6 +662,32 @@ func (s *Service) VerifyUserPassword(ctx context.Context, userID uuid.UUID, pass
 	return nil
 }
 
+// Reauthenticate confirms the identity of an already logged-in user before a sensitive change: the password must match
+// and, when the user has MFA enabled, so must the MFA code (TOTP or recovery code). ErrMFARequired is returned when MFA
+// is enabled and no code was given.
+func (s *Service) Reauthenticate(ctx context.Context, userID uuid.UUID, password, code string) error {
+	creds, err := s.users.GetCredentialsByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("get credentials for re-authentication: %w", err)
+	}
+
+	match, err := VerifyPassword(password, creds.PasswordHash)
+	if err != nil {
+		return fmt.Errorf("verify password for re-authentication: %w", err)
+	}
+	if !match {
+		return ErrInvalidCredentials
+	}
+
+	if !creds.MFAEnabled || creds.MFASecret == nil {
+		return nil
+	}
+	if code == "" {
+		return ErrMFARequired
+	}
+	return s.verifyMFACode(ctx, creds, code)
+}
+
 // DeleteAccount verifies the user's password, checks that the user is not the server owner, computes HMAC tombstones
 // for the email (always) and optionally the username, atomically deletes the user and inserts tombstones, and performs
 // best-effort cleanup of refresh tokens and permission caches.
@@ -702,26 +713,9 @@ func (s *Service) DeleteAccount(ctx context.Context, userID uuid.UUID, password
 		return ErrServerOwner
 	}
 
-	tombstones := make([]user.Tombstone, 0, 2)
-
-	emailHMAC, err := HMACIdentifier(creds.Email, s.config.ServerSecret.Expose())
+	tombstones, err := DeletionTombstones(creds.Email, creds.Username, s.config)
 	if err != nil {
-		return fmt.Errorf("compute email HMAC: %w", err)
-	}
-	tombstones = append(tombstones, user.Tombstone{
-		IdentifierType: user.TombstoneEmail,
-		HMACHash:       emailHMAC,
-	})
-
-	if s.config.DeletionTombstoneUsernames {
-		usernameHMAC, err := HMACIdentifier(strings.ToLower(creds.Username), s.config.ServerSecret.Expose())
-		if err != nil {
-			return fmt.Errorf("compute username HMAC: %w", err)
-		}
-		tombstones = append(tombstones, user.Tombstone{
-			IdentifierType: user.TombstoneUsername,
-			HMACHash:       usernameHMAC,
-		})
+		return err
 	}
 
 	if err := s.users.DeleteWithTombstones(ctx, userID, tombstones); err != nil {
@@ -740,6 +734,34 @@ func (s *Service) DeleteAccount(ctx context.Context, userID uuid.UUID, password
 	return nil
 }
 
+// DeletionTombstones computes the tombstones recorded when an account is deleted: an HMAC of the email address always,
+// and of the lowercased username when DELETION_TOMBSTONE_USERNAMES is enabled.
+func DeletionTombstones(email, username string, cfg *config.Config) ([]user.Tombstone, error) {
+	tombstones := make([]user.Tombstone, 0, 2)
+
+	emailHMAC, err := HMACIdentifier(email, cfg.ServerSecret.Expose())
+	if err != nil {
+		return nil, fmt.Errorf("compute email HMAC: %w", err)
+	}
+	tombstones = append(tombstones, user.Tombstone{
+		IdentifierType: user.TombstoneEmail,
+		HMACHash:       emailHMAC,
+	})
+
+	if cfg.DeletionTombstoneUsernames {
+		usernameHMAC, err := HMACIdentifier(strings.ToLower(username), cfg.ServerSecret.Expose())
+		if err != nil {
+			return nil, fmt.Errorf("compute username HMAC: %w", err)
+		}
+		tombstones = append(tombstones, user.Tombstone{
+			IdentifierType: user.TombstoneUsername,
+			HMACHash:       usernameHMAC,
+		})
+	}
+
+	return tombstones, nil
+}
+
 // completeMFALogin issues tokens and builds an Result with MFAEnabled set to true.
 func (s *Service) completeMFALogin(ctx context.Context, creds *user.Credentials) (*Result, error) {
 	tokens, err := s.issueTokens(ctx, creds.ID, creds.EmailVerified)
@@ -754,6 +776,31 @@ func (s *Service) completeMFALogin(ctx context.Context, creds *user.Credentials)
 	}, nil
 }
 
+// verifyMFACode checks the code against the user's TOTP secret and, when it does not match, against their unused
+// recovery codes. It returns ErrInvalidMFACode when neither matches.
+func (s *Service) verifyMFACode(ctx context.Context, creds *user.Credentials, code string) error {
+	if !s.config.MFAConfigured() {
+		return ErrMFANotConfigured
+	}
+
+	secret, err := DecryptTOTPSecret(*creds.MFASecret, s.config.MFAEncryptionKey.Expose())
+	if err != nil {
+		return fmt.Errorf("decrypt MFA secret: %w", err)
+	}
+
+	if totp.Validate(code, secret) {
+		return nil
+	}
+	// The code did not match TOTP; try recovery codes.
+	if err := s.tryRecoveryCode(ctx, creds.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return ErrInvalidMFACode
+		}
+		return fmt.Errorf("try recovery code: %w", err)
+	}
+	return nil
+}
+
 // tryRecoveryCode checks the provided code against all unused recovery codes for the user. If a match is found, the
 // code is marked as used and nil is returned. Otherwise, ErrInvalidMFACode is returned. The function always evaluates
 // every stored code to prevent timing side channels from revealing how many unused codes remain.
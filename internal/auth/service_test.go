@@ -291,6 +291,11 @@ func (r *fakeServerRepo) ClearBannerKey(_ context.Context) (*server.Config, erro
 	return &server.Config{ID: uuid.New(), Name: "Test Server", OwnerID: r.ownerID}, nil
 }
 
+func (r *fakeServerRepo) SetOwner(_ context.Context, userID uuid.UUID) (*server.Config, error) {
+	r.ownerID = userID
+	return &server.Config{ID: uuid.New(), Name: "Test Server", OwnerID: r.ownerID}, nil
+}
+
 func testConfig() *config.Config {
 	return &config.Config{
 		ServerName:                 "Test Server",
@@ -1450,6 +1455,58 @@ func TestServiceRegenerateRecoveryCodesNotEnabled(t *testing.T) {
 	}
 }
 
+func TestServiceReauthenticateWithoutMFA(t *testing.T) {
+	t.Parallel()
+	repo := newFakeRepository()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	_, err := svc.Register(ctx, RegisterRequest{
+		Email:    "alice@example.com",
+		Username: "alice",
+		Password: "strongpassword",
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	userID := repo.users["alice@example.com"].ID
+
+	if err := svc.Reauthenticate(ctx, userID, "strongpassword", ""); err != nil {
+		t.Errorf("Reauthenticate() error = %v", err)
+	}
+	if err := svc.Reauthenticate(ctx, userID, "wrongpassword", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Reauthenticate() error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestServiceReauthenticateWithMFA(t *testing.T) {
+	t.Parallel()
+	repo := newFakeRepository()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	secret := registerMFAUser(t, svc, repo)
+	userID := repo.users["alice@example.com"].ID
+
+	if err := svc.Reauthenticate(ctx, userID, "strongpassword", ""); !errors.Is(err, ErrMFARequired) {
+		t.Errorf("Reauthenticate() without code error = %v, want ErrMFARequired", err)
+	}
+	if err := svc.Reauthenticate(ctx, userID, "strongpassword", "000000"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("Reauthenticate() with wrong code error = %v, want ErrInvalidMFACode", err)
+	}
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("totp.GenerateCode() error = %v", err)
+	}
+	if err := svc.Reauthenticate(ctx, userID, "wrongpassword", code); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Reauthenticate() with wrong password error = %v, want ErrInvalidCredentials", err)
+	}
+	if err := svc.Reauthenticate(ctx, userID, "strongpassword", code); err != nil {
+		t.Errorf("Reauthenticate() error = %v", err)
+	}
+}
+
 func TestServiceBeginMFASetupNotConfigured(t *testing.T) {
 	t.Parallel()
 	repo := newFakeRepository()
@@ -0,0 +1,213 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxRowBytes bounds a single JSON Lines row. The largest rows are messages, whose content is limited far below this.
+const maxRowBytes = 16 << 20
+
+// archiveWriter writes the entries of a backup archive. Zip entries are written with data descriptors, so neither
+// tables nor stored files need to be buffered to learn their size up front.
+type archiveWriter struct {
+	zw      *zip.Writer
+	created time.Time
+}
+
+// newArchiveWriter returns an archiveWriter that writes to w.
+func newArchiveWriter(w io.Writer) *archiveWriter {
+	return &archiveWriter{zw: zip.NewWriter(w), created: time.Now()}
+}
+
+// create starts a new entry. Table files are deflated; stored files are mostly compressed media already and are
+// stored as-is.
+func (a *archiveWriter) create(name string, method uint16) (io.Writer, error) {
+	w, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: a.created})
+	if err != nil {
+		return nil, fmt.Errorf("create archive entry %s: %w", name, err)
+	}
+	return w, nil
+}
+
+// writeManifest writes the manifest entry.
+func (a *archiveWriter) writeManifest(m *Manifest) error {
+	w, err := a.create(manifestPath, zip.Deflate)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// Close writes the zip central directory. It does not close the underlying writer.
+func (a *archiveWriter) Close() error {
+	if err := a.zw.Close(); err != nil {
+		return fmt.Errorf("finish archive: %w", err)
+	}
+	return nil
+}
+
+// tableFile returns the path of a table's JSON Lines file inside an archive.
+func tableFile(name string) string {
+	return tablesDir + name + ".jsonl"
+}
+
+// validBlobKey reports whether key can be stored in an archive and restored to storage: a relative, clean, slash
+// separated path that cannot climb out of the blobs directory.
+func validBlobKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key {
+		return false
+	}
+	return !slices.Contains(strings.Split(key, "/"), "..")
+}
+
+// archive is an opened, validated backup archive.
+type archive struct {
+	manifest Manifest
+	tables   map[string]*zip.File
+	blobs    []*zip.File
+}
+
+// openArchive reads the manifest of the archive in r and checks that it can be restored by this binary: the format
+// version matches, every table is known and has its file, and every stored file has a safe key.
+func openArchive(r io.ReaderAt, size int64) (*archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorruptArchive, err)
+	}
+
+	a := &archive{tables: make(map[string]*zip.File)}
+	var manifest *zip.File
+	for _, f := range zr.File {
+		switch {
+		case f.Name == manifestPath:
+			manifest = f
+		case strings.HasPrefix(f.Name, tablesDir):
+			a.tables[f.Name] = f
+		case strings.HasPrefix(f.Name, blobsDir):
+			if !validBlobKey(strings.TrimPrefix(f.Name, blobsDir)) {
+				return nil, fmt.Errorf("%w: unsafe file name %q", ErrCorruptArchive, f.Name)
+			}
+			a.blobs = append(a.blobs, f)
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: %s is missing", ErrCorruptArchive, manifestPath)
+	}
+	if err := readManifest(manifest, &a.manifest); err != nil {
+		return nil, err
+	}
+
+	if a.manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("%w: archive is version %d, this binary reads version %d", ErrUnsupportedFormat,
+			a.manifest.FormatVersion, FormatVersion)
+	}
+	for _, tm := range a.manifest.Tables {
+		if !slices.ContainsFunc(tables, func(t table) bool { return t.name == tm.Name }) {
+			return nil, fmt.Errorf("%w: unknown table %q", ErrCorruptArchive, tm.Name)
+		}
+		if _, ok := a.tables[tableFile(tm.Name)]; !ok {
+			return nil, fmt.Errorf("%w: %s is missing", ErrCorruptArchive, tableFile(tm.Name))
+		}
+	}
+	if int64(len(a.blobs)) != a.manifest.Blobs.Count {
+		return nil, fmt.Errorf("%w: manifest lists %d stored files, archive holds %d", ErrCorruptArchive,
+			a.manifest.Blobs.Count, len(a.blobs))
+	}
+	return a, nil
+}
+
+// readManifest decodes the manifest entry f into m.
+func readManifest(f *zip.File, m *Manifest) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: open manifest: %w", ErrCorruptArchive, err)
+	}
+	defer func() { _ = rc.Close() }()
+	if err := json.NewDecoder(rc).Decode(m); err != nil {
+		return fmt.Errorf("%w: decode manifest: %w", ErrCorruptArchive, err)
+	}
+	return nil
+}
+
+// tableManifest returns the manifest entry of the named table, or false when the archive does not include it.
+func (a *archive) tableManifest(name string) (TableManifest, bool) {
+	for _, tm := range a.manifest.Tables {
+		if tm.Name == name {
+			return tm, true
+		}
+	}
+	return TableManifest{}, false
+}
+
+// eachBatch calls fn with the rows of the named table as JSON arrays of up to size rows, ready to be passed to
+// json_populate_recordset, and returns the number of rows read.
+func (a *archive) eachBatch(name string, size int, fn func(batch []byte) error) (int64, error) {
+	f, ok := a.tables[tableFile(name)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is missing", ErrCorruptArchive, tableFile(name))
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("%w: open %s: %w", ErrCorruptArchive, f.Name, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	var (
+		total int64
+		n     int
+		batch bytes.Buffer
+	)
+	flush := func() error {
+		if n == 0 {
+			return nil
+		}
+		batch.WriteByte(']')
+		err := fn(batch.Bytes())
+		batch.Reset()
+		n = 0
+		return err
+	}
+
+	sc := bufio.NewScanner(rc)
+	sc.Buffer(make([]byte, 0, 64<<10), maxRowBytes)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return total, fmt.Errorf("%w: %s row %d is not valid JSON", ErrCorruptArchive, f.Name, total+1)
+		}
+		if n == 0 {
+			batch.WriteByte('[')
+		} else {
+			batch.WriteByte(',')
+		}
+		batch.Write(line)
+		n++
+		total++
+		if n == size {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return total, fmt.Errorf("%w: read %s: %w", ErrCorruptArchive, f.Name, err)
+	}
+	return total, flush()
+}
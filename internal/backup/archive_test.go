@@ -0,0 +1,213 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testArchive describes the contents of an archive built by buildArchive.
+type testArchive struct {
+	manifest Manifest
+	files    map[string]string
+}
+
+// buildArchive writes an archive holding the given manifest and files and returns its bytes.
+func buildArchive(t *testing.T, a testArchive) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	aw := newArchiveWriter(&buf)
+	for name, content := range a.files {
+		w, err := aw.create(name, zip.Deflate)
+		if err != nil {
+			t.Fatalf("create() error: %v", err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatalf("WriteString() error: %v", err)
+		}
+	}
+	if err := aw.writeManifest(&a.manifest); err != nil {
+		t.Fatalf("writeManifest() error: %v", err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// validArchive returns an archive with three roles and one stored file.
+func validArchive() testArchive {
+	return testArchive{
+		manifest: Manifest{
+			FormatVersion: FormatVersion,
+			SchemaVersion: 1,
+			ServerName:    "Test",
+			Tables:        []TableManifest{{Name: "roles", Columns: []string{"id", "name"}, Rows: 3}},
+			Blobs:         BlobManifest{Count: 1, Bytes: 5},
+		},
+		files: map[string]string{
+			tableFile("roles"): `{"id":"a","name":"one"}` + "\n" + `{"id":"b","name":"two"}` + "\n" +
+				`{"id":"c","name":"three"}` + "\n",
+			blobsDir + "emoji/smile.png": "image",
+		},
+	}
+}
+
+func openBytes(data []byte) (*archive, error) {
+	return openArchive(bytes.NewReader(data), int64(len(data)))
+}
+
+func TestOpenArchive(t *testing.T) {
+	t.Parallel()
+	a, err := openBytes(buildArchive(t, validArchive()))
+	if err != nil {
+		t.Fatalf("openArchive() error: %v", err)
+	}
+	if a.manifest.ServerName != "Test" {
+		t.Errorf("ServerName = %q, want %q", a.manifest.ServerName, "Test")
+	}
+	if len(a.blobs) != 1 || a.blobs[0].Name != blobsDir+"emoji/smile.png" {
+		t.Errorf("blobs = %v, want the one stored file", a.blobs)
+	}
+	if tm, ok := a.tableManifest("roles"); !ok || tm.Rows != 3 {
+		t.Errorf("tableManifest(roles) = %+v, %v; want 3 rows", tm, ok)
+	}
+	if _, ok := a.tableManifest("users"); ok {
+		t.Error("tableManifest(users) found a table the archive does not hold")
+	}
+}
+
+func TestOpenArchiveRejects(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		modify func(a *testArchive)
+		want   error
+	}{
+		{"newer format", func(a *testArchive) { a.manifest.FormatVersion = FormatVersion + 1 }, ErrUnsupportedFormat},
+		{"unknown table", func(a *testArchive) {
+			a.manifest.Tables = append(a.manifest.Tables, TableManifest{Name: "dm_channels"})
+			a.files[tableFile("dm_channels")] = ""
+		}, ErrCorruptArchive},
+		{"missing table file", func(a *testArchive) { delete(a.files, tableFile("roles")) }, ErrCorruptArchive},
+		{"unsafe blob key", func(a *testArchive) { a.files[blobsDir+"../../etc/passwd"] = "x" }, ErrCorruptArchive},
+		{"blob count mismatch", func(a *testArchive) { a.manifest.Blobs.Count = 2 }, ErrCorruptArchive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := validArchive()
+			tt.modify(&a)
+			if _, err := openBytes(buildArchive(t, a)); !errors.Is(err, tt.want) {
+				t.Errorf("openArchive() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenArchiveRejectsMissingManifest(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if _, err := openBytes(buf.Bytes()); !errors.Is(err, ErrCorruptArchive) {
+		t.Errorf("openArchive() error = %v, want ErrCorruptArchive", err)
+	}
+}
+
+func TestOpenArchiveRejectsNonZip(t *testing.T) {
+	t.Parallel()
+	if _, err := openBytes([]byte("not an archive")); !errors.Is(err, ErrCorruptArchive) {
+		t.Errorf("openArchive() error = %v, want ErrCorruptArchive", err)
+	}
+}
+
+func TestEachBatch(t *testing.T) {
+	t.Parallel()
+	a, err := openBytes(buildArchive(t, validArchive()))
+	if err != nil {
+		t.Fatalf("openArchive() error: %v", err)
+	}
+
+	var batches [][]map[string]string
+	n, err := a.eachBatch("roles", 2, func(batch []byte) error {
+		var rows []map[string]string
+		if err := json.Unmarshal(batch, &rows); err != nil {
+			t.Fatalf("batch %s is not a JSON array: %v", batch, err)
+		}
+		batches = append(batches, rows)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("eachBatch() error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("eachBatch() rows = %d, want 3", n)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Fatalf("batches = %v, want sizes 2 and 1", batches)
+	}
+	if batches[1][0]["name"] != "three" {
+		t.Errorf("last row = %v, want the third role", batches[1][0])
+	}
+}
+
+func TestEachBatchRejectsInvalidRow(t *testing.T) {
+	t.Parallel()
+	a := validArchive()
+	a.files[tableFile("roles")] = `{"id":"a"}` + "\n" + `{"id":` + "\n"
+	opened, err := openBytes(buildArchive(t, a))
+	if err != nil {
+		t.Fatalf("openArchive() error: %v", err)
+	}
+	_, err = opened.eachBatch("roles", 10, func([]byte) error { return nil })
+	if !errors.Is(err, ErrCorruptArchive) || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("eachBatch() error = %v, want ErrCorruptArchive naming row 2", err)
+	}
+}
+
+func TestEachBatchStopsOnError(t *testing.T) {
+	t.Parallel()
+	a, err := openBytes(buildArchive(t, validArchive()))
+	if err != nil {
+		t.Fatalf("openArchive() error: %v", err)
+	}
+	boom := errors.New("boom")
+	calls := 0
+	_, err = a.eachBatch("roles", 1, func([]byte) error {
+		calls++
+		return boom
+	})
+	if !errors.Is(err, boom) || calls != 1 {
+		t.Errorf("eachBatch() error = %v after %d calls, want boom after 1", err, calls)
+	}
+}
+
+func TestValidBlobKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"attachments/abc/file.png", true},
+		{"avatar.webp", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../secret", false},
+		{"a/../../b", false},
+		{"a//b", false},
+		{"a/./b", false},
+		{`a\b`, false},
+	}
+	for _, tt := range tests {
+		if got := validBlobKey(tt.key); got != tt.want {
+			t.Errorf("validBlobKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
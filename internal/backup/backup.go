@@ -0,0 +1,143 @@
+package backup
+
+import (
+	"errors"
+	"time"
+)
+
+// FormatVersion is the archive layout written by Export. Import rejects archives with any other version, so a change to
+// the layout (not to the tables, which the schema version covers) must bump it.
+const FormatVersion = 1
+
+// Paths inside an archive.
+const (
+	manifestPath = "manifest.json"
+	tablesDir    = "tables/"
+	blobsDir     = "blobs/"
+)
+
+// Sentinel errors returned by Export and Import.
+var (
+	ErrUnsupportedFormat = errors.New("unsupported backup format version")
+	ErrSchemaTooNew      = errors.New("backup was taken from a newer database schema than this binary supports")
+	ErrCorruptArchive    = errors.New("backup archive is corrupt")
+	ErrNotEmpty          = errors.New("the target instance already holds community data")
+)
+
+// Manifest describes the contents of an archive. It is written last, once row counts and copied files are known.
+type Manifest struct {
+	FormatVersion int             `json:"format_version"`
+	SchemaVersion int64           `json:"schema_version"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ServerName    string          `json:"server_name"`
+	Credentials   bool            `json:"credentials"`
+	Tables        []TableManifest `json:"tables"`
+	Blobs         BlobManifest    `json:"blobs"`
+}
+
+// TableManifest records one exported table: the columns present in every row of its JSON Lines file, in order, and the
+// number of rows.
+type TableManifest struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int64    `json:"rows"`
+}
+
+// BlobManifest summarises the stored files copied into the archive. Missing lists keys that rows refer to but that were
+// absent from storage at export time; their rows are restored all the same.
+type BlobManifest struct {
+	Count   int64    `json:"count"`
+	Bytes   int64    `json:"bytes"`
+	Missing []string `json:"missing,omitempty"`
+}
+
+// table describes how one table is exported and restored.
+type table struct {
+	name string
+
+	// order is the ORDER BY clause that makes the export deterministic.
+	order string
+
+	// where restricts the export to rows belonging to the community, for tables that also hold DM data or rows that
+	// refer to it.
+	where string
+
+	// deferred columns are left NULL when rows are inserted and filled in by a second pass once every row of the table
+	// exists, for self-references and references into tables restored later.
+	deferred []string
+
+	// secret columns are left out of archives made without credentials, and secretTable leaves out the whole table.
+	secret      []string
+	secretTable bool
+
+	// personal columns hold contact details. Archives made without credentials replace each with its SQL expression
+	// rather than leaving it out, because the schema requires some of them (users.email is NOT NULL and UNIQUE).
+	personal map[string]string
+}
+
+// redactedEmail is the expression that replaces users.email in archives made without credentials. It is unique per
+// account and uses a reserved domain, so restored accounts cannot receive mail until an operator sets a real address.
+const redactedEmail = "id::text || '@redacted.invalid'"
+
+// serverMessage restricts a message reference to messages in server channels, excluding DMs.
+const serverMessage = "message_id IN (SELECT id FROM messages WHERE channel_id IN (SELECT id FROM channels))"
+
+// tables lists the exported tables in an order that satisfies every foreign key, so Import restores them front to back.
+var tables = []table{
+	{name: "users", order: "id", secret: []string{"password_hash", "mfa_secret", "mfa_enabled"},
+		personal: map[string]string{"email": redactedEmail, "email_verified": "false", "phone": "NULL",
+			"phone_verified": "false"}},
+	{name: "mfa_recovery_codes", order: "id", secretTable: true},
+	{name: "server_config", order: "id"},
+	{name: "roles", order: "position, id"},
+	{name: "members", order: "user_id"},
+	{name: "member_roles", order: "user_id, role_id"},
+	{name: "categories", order: "position, id"},
+	{name: "channels", order: "position, id"},
+	{name: "permission_overrides", order: "id"},
+	{name: "custom_emoji", order: "id"},
+	{name: "messages", order: "created_at, id", where: "channel_id IN (SELECT id FROM channels)",
+		deferred: []string{"reply_to_id", "thread_id"}},
+	{name: "threads", order: "created_at, id"},
+	{name: "message_attachments", order: "created_at, id", where: "message_id IS NOT NULL"},
+	{name: "reactions", order: "created_at, id", where: serverMessage},
+	{name: "channel_read_states", order: "user_id, channel_id"},
+	{name: "onboarding_config", order: "id"},
+	{name: "document_acceptances", order: "user_id, slug"},
+	{name: "invites", order: "created_at, id"},
+	{name: "webhooks", order: "created_at, id"},
+	{name: "bans", order: "user_id"},
+	{name: "automod_rules", order: "created_at, id"},
+	{name: "reports", order: "created_at, id", where: "message_id IS NULL OR " + serverMessage},
+	{name: "audit_log", order: "created_at, id"},
+	{name: "deletion_tombstones", order: "id"},
+}
+
+// excludedTables lists the tables deliberately left out of a community snapshot. Every table in the schema must appear
+// in either tables or excludedTables, which a test enforces so that new tables are not silently dropped from backups.
+var excludedTables = []string{
+	// Direct messages and end-to-end encryption state belong to users, not to the community.
+	"dm_channels", "dm_participants", "dm_message_keys", "dm_attachments", "user_devices", "e2ee_signed_pre_keys",
+	"e2ee_one_time_pre_keys", "e2ee_master_keys", "e2ee_device_signatures", "user_synced_settings",
+	// Anti-abuse telemetry and short-lived tokens.
+	"user_ip_log", "device_fingerprints", "abuse_flags", "login_attempts", "email_verifications",
+	// Work in progress that does not survive a move: resumable uploads and the search outbox, which a reindex replaces.
+	"attachment_uploads", "attachment_upload_chunks", "search_outbox",
+	// Plugin registrations belong to the deployment rather than the community and are made again on the new host.
+	"registered_plugins",
+}
+
+// blobKeysQuery lists every storage key referenced by an exported row.
+const blobKeysQuery = `
+SELECT key FROM (
+    SELECT storage_key AS key FROM message_attachments WHERE message_id IS NOT NULL AND scan_status = 'clean'
+    UNION SELECT thumbnail_key FROM message_attachments WHERE message_id IS NOT NULL AND scan_status = 'clean'
+    UNION SELECT storage_key FROM custom_emoji
+    UNION SELECT avatar_key FROM users
+    UNION SELECT banner_key FROM users
+    UNION SELECT icon_key FROM server_config
+    UNION SELECT banner_key FROM server_config
+    UNION SELECT avatar_key FROM webhooks
+) k
+WHERE key IS NOT NULL AND key <> ''
+ORDER BY key`
@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/uncord-chat/uncord-server/internal/postgres/migrations"
+)
+
+var (
+	createTableRe = regexp.MustCompile(`(?s)CREATE TABLE (\w+) \((.*?)\n\);`)
+	columnRefRe   = regexp.MustCompile(`(?m)^\s+(\w+)\s+UUID[^,\n]*REFERENCES (\w+)\(`)
+	alterRefRe    = regexp.MustCompile(`ALTER TABLE (\w+) ADD CONSTRAINT \w+\s+FOREIGN KEY \((\w+)\) REFERENCES (\w+)\(`)
+)
+
+// foreignKey is a column of one table that references another.
+type foreignKey struct {
+	table, column, references string
+}
+
+// readSchema returns every table created by the up migrations and every foreign key between them.
+func readSchema(t *testing.T) ([]string, []foreignKey) {
+	t.Helper()
+	names, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("Glob() error: %v", err)
+	}
+	var created []string
+	var keys []foreignKey
+	for _, name := range names {
+		data, err := fs.ReadFile(migrations.FS, name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error: %v", name, err)
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		for _, m := range createTableRe.FindAllStringSubmatch(up, -1) {
+			created = append(created, m[1])
+			for _, ref := range columnRefRe.FindAllStringSubmatch(m[2], -1) {
+				keys = append(keys, foreignKey{table: m[1], column: ref[1], references: ref[2]})
+			}
+		}
+		for _, m := range alterRefRe.FindAllStringSubmatch(up, -1) {
+			keys = append(keys, foreignKey{table: m[1], column: m[2], references: m[3]})
+		}
+	}
+	if len(created) == 0 || len(keys) == 0 {
+		t.Fatal("no tables or foreign keys found in the migrations")
+	}
+	return created, keys
+}
+
+func tableIndex(name string) int {
+	return slices.IndexFunc(tables, func(t table) bool { return t.name == name })
+}
+
+func TestTablesCoverSchema(t *testing.T) {
+	t.Parallel()
+	created, _ := readSchema(t)
+
+	for _, name := range created {
+		exported, excluded := tableIndex(name) >= 0, slices.Contains(excludedTables, name)
+		if exported == excluded {
+			t.Errorf("table %s must be listed in exactly one of tables and excludedTables", name)
+		}
+	}
+	for _, tbl := range tables {
+		if !slices.Contains(created, tbl.name) {
+			t.Errorf("exported table %s does not exist in the schema", tbl.name)
+		}
+		if tbl.order == "" {
+			t.Errorf("exported table %s has no ORDER BY clause", tbl.name)
+		}
+	}
+}
+
+func TestTablesOrderSatisfiesForeignKeys(t *testing.T) {
+	t.Parallel()
+	_, keys := readSchema(t)
+
+	for _, fk := range keys {
+		from, to := tableIndex(fk.table), tableIndex(fk.references)
+		if from < 0 {
+			continue
+		}
+		if to < 0 {
+			t.Errorf("%s.%s references %s, which is not exported", fk.table, fk.column, fk.references)
+			continue
+		}
+		if to >= from && !slices.Contains(tables[from].deferred, fk.column) {
+			t.Errorf("%s.%s references %s, which is restored later; reorder tables or defer the column", fk.table,
+				fk.column, fk.references)
+		}
+	}
+}
+
+func TestSecretColumnsAreNotOrderColumns(t *testing.T) {
+	t.Parallel()
+	for _, tbl := range tables {
+		for _, col := range tbl.secret {
+			if strings.Contains(tbl.order, col) {
+				t.Errorf("table %s orders by secret column %s, which archives without credentials omit", tbl.name, col)
+			}
+		}
+		for col := range tbl.personal {
+			if strings.Contains(tbl.order, col) {
+				t.Errorf("table %s orders by personal column %s, which archives without credentials redact", tbl.name,
+					col)
+			}
+		}
+	}
+}
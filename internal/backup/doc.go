@@ -0,0 +1,17 @@
+// Package backup exports a community to a portable archive and restores it into an empty instance. An archive is a zip
+// file holding a versioned manifest, one JSON Lines file per table, and the stored files the rows refer to
+// (attachments, thumbnails, emoji, avatars, banners, and server icons) copied byte for byte out of the StorageProvider.
+// Tables are read inside a single repeatable-read transaction, so the archive is a consistent snapshot even while the
+// server is running.
+//
+// Direct messages, E2EE key material, synced settings, anti-abuse telemetry, pending uploads, and other transient state
+// are not part of a community snapshot and are never exported. Archives made without credentials also omit password
+// hashes, MFA secrets, and recovery codes, and replace email addresses and phone numbers with placeholders; restored
+// accounts then receive an unusable password and an undeliverable address until an operator resets them.
+//
+// Every row keeps its UUID, which is globally unique, so references need no rewriting when an archive moves to another
+// host or storage backend. IDs are never remapped, which means an archive cannot be merged into a community in use:
+// Import refuses to run unless the target holds nothing beyond the first-run seed, which it replaces. The one reference
+// cycle in the schema (messages point at threads, which point at their parent message) and message replies are
+// restored in a second pass once every message exists.
+package backup
@@ -0,0 +1,238 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/uncord-chat/uncord-server/internal/media"
+	"github.com/uncord-chat/uncord-server/internal/postgres"
+)
+
+// columnsQuery lists the columns of a table that can be written, skipping generated columns, which PostgreSQL recomputes
+// on insert.
+const columnsQuery = `
+SELECT column_name
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = $1 AND is_generated = 'NEVER'
+ORDER BY ordinal_position`
+
+// ExportOptions controls what an archive contains.
+type ExportOptions struct {
+	// Credentials includes password hashes, MFA secrets, MFA recovery codes, email addresses, and phone numbers, so that
+	// restored accounts can sign in with their old passwords and receive mail. Leave it unset for archives that leave the
+	// operator's hands.
+	Credentials bool
+}
+
+// Exporter writes community snapshots.
+type Exporter struct {
+	db      *pgxpool.Pool
+	storage media.StorageProvider
+	log     zerolog.Logger
+}
+
+// NewExporter creates a new Exporter.
+func NewExporter(db *pgxpool.Pool, storage media.StorageProvider, logger zerolog.Logger) *Exporter {
+	return &Exporter{db: db, storage: storage, log: logger}
+}
+
+// Export writes an archive of the community to w and returns its manifest. Every table is read in one read-only,
+// repeatable-read transaction, so the archive reflects a single point in time. A failed export leaves w holding an
+// incomplete archive that Import rejects.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, opts ExportOptions) (*Manifest, error) {
+	tx, err := e.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("begin snapshot: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	m := &Manifest{FormatVersion: FormatVersion, CreatedAt: time.Now().UTC(), Credentials: opts.Credentials}
+	if m.SchemaVersion, err = postgres.AppliedMigration(ctx, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRow(ctx, "SELECT name FROM server_config LIMIT 1").Scan(&m.ServerName); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("the server has not been initialised; there is nothing to export")
+		}
+		return nil, fmt.Errorf("query server name: %w", err)
+	}
+
+	aw := newArchiveWriter(w)
+	for _, t := range tables {
+		if t.secretTable && !opts.Credentials {
+			continue
+		}
+		tm, err := exportTable(ctx, tx, aw, t, opts.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		m.Tables = append(m.Tables, tm)
+		e.log.Debug().Str("table", t.name).Int64("rows", tm.Rows).Msg("Exported table")
+	}
+
+	if m.Blobs, err = e.exportBlobs(ctx, tx, aw); err != nil {
+		return nil, err
+	}
+	if err := aw.writeManifest(m); err != nil {
+		return nil, err
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// exportTable writes the rows of t to its JSON Lines file, one row_to_json object per line.
+func exportTable(ctx context.Context, tx pgx.Tx, aw *archiveWriter, t table, credentials bool) (TableManifest,
+	error) {
+	cols, err := writableColumns(ctx, tx, t.name)
+	if err != nil {
+		return TableManifest{}, err
+	}
+	if !credentials {
+		cols = slices.DeleteFunc(cols, func(c string) bool { return slices.Contains(t.secret, c) })
+	}
+
+	out, err := aw.create(tableFile(t.name), zip.Deflate)
+	if err != nil {
+		return TableManifest{}, err
+	}
+	rows, err := tx.Query(ctx, selectSQL(t, cols, !credentials))
+	if err != nil {
+		return TableManifest{}, fmt.Errorf("query %s: %w", t.name, err)
+	}
+	defer rows.Close()
+
+	tm := TableManifest{Name: t.name, Columns: cols}
+	for rows.Next() {
+		var line []byte
+		if err := rows.Scan(&line); err != nil {
+			return TableManifest{}, fmt.Errorf("scan %s: %w", t.name, err)
+		}
+		if _, err := out.Write(append(line, '\n')); err != nil {
+			return TableManifest{}, fmt.Errorf("write %s: %w", t.name, err)
+		}
+		tm.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return TableManifest{}, fmt.Errorf("read %s: %w", t.name, err)
+	}
+	return tm, nil
+}
+
+// exportBlobs copies every stored file referenced by an exported row into the archive. Files missing from storage are
+// recorded in the manifest rather than failing the export, since a lost file should not prevent the rest of the
+// community from being saved.
+func (e *Exporter) exportBlobs(ctx context.Context, tx pgx.Tx, aw *archiveWriter) (BlobManifest, error) {
+	rows, err := tx.Query(ctx, blobKeysQuery)
+	if err != nil {
+		return BlobManifest{}, fmt.Errorf("query storage keys: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return BlobManifest{}, fmt.Errorf("collect storage keys: %w", err)
+	}
+
+	var bm BlobManifest
+	for _, key := range keys {
+		if !validBlobKey(key) {
+			e.log.Warn().Str("key", key).Msg("Skipping stored file with an unsafe key")
+			bm.Missing = append(bm.Missing, key)
+			continue
+		}
+		n, err := e.copyBlob(ctx, aw, key)
+		if errors.Is(err, media.ErrStorageKeyNotFound) {
+			e.log.Warn().Str("key", key).Msg("Stored file is missing; exporting its rows without it")
+			bm.Missing = append(bm.Missing, key)
+			continue
+		}
+		if err != nil {
+			return BlobManifest{}, err
+		}
+		bm.Count++
+		bm.Bytes += n
+	}
+	return bm, nil
+}
+
+// copyBlob streams the stored file at key into the archive and returns its size.
+func (e *Exporter) copyBlob(ctx context.Context, aw *archiveWriter, key string) (int64, error) {
+	rc, err := e.storage.Get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("open stored file %s: %w", key, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	out, err := aw.create(blobsDir+key, zip.Store)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, rc)
+	if err != nil {
+		return 0, fmt.Errorf("copy stored file %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// writableColumns returns the columns of table that are not generated, in table order.
+func writableColumns(ctx context.Context, q querier, table string) ([]string, error) {
+	rows, err := q.Query(ctx, columnsQuery, table)
+	if err != nil {
+		return nil, fmt.Errorf("query columns of %s: %w", table, err)
+	}
+	cols, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("collect columns of %s: %w", table, err)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+	return cols, nil
+}
+
+// selectSQL builds the query that reads the rows of t as JSON text, restricted to cols. When redact is set, personal
+// columns are replaced by their redaction expressions.
+func selectSQL(t table, cols []string, redact bool) string {
+	var b strings.Builder
+	b.WriteString("SELECT row_to_json(r)::text FROM (SELECT ")
+	for i, c := range cols {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if expr, ok := t.personal[c]; ok && redact {
+			b.WriteString(expr + " AS ")
+		}
+		b.WriteString(pgx.Identifier{c}.Sanitize())
+	}
+	b.WriteString(" FROM ")
+	b.WriteString(pgx.Identifier{t.name}.Sanitize())
+	if t.where != "" {
+		b.WriteString(" WHERE ")
+		b.WriteString(t.where)
+	}
+	b.WriteString(") r ORDER BY ")
+	b.WriteString(t.order)
+	return b.String()
+}
+
+// columnList quotes cols and joins them with commas, qualifying each with prefix when it is not empty.
+func columnList(prefix string, cols []string) string {
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = pgx.Identifier{c}.Sanitize()
+		if prefix != "" {
+			quoted[i] = prefix + "." + quoted[i]
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
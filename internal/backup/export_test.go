@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/uncord-chat/uncord-server/internal/media"
+)
+
+func newTestStorage(t *testing.T) *media.LocalStorage {
+	t.Helper()
+	store, err := media.NewLocalStorage(t.TempDir(), "http://localhost:8080")
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestSelectSQL(t *testing.T) {
+	t.Parallel()
+	got := selectSQL(table{name: "messages", order: "created_at, id", where: "channel_id IS NOT NULL"},
+		[]string{"id", "content"}, false)
+	want := `SELECT row_to_json(r)::text FROM (SELECT "id", "content" FROM "messages" WHERE channel_id IS NOT NULL) r ` +
+		`ORDER BY created_at, id`
+	if got != want {
+		t.Errorf("selectSQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	got = selectSQL(table{name: "roles", order: "position"}, []string{"id"}, false)
+	if strings.Contains(got, "WHERE") {
+		t.Errorf("selectSQL() = %s, want no WHERE clause", got)
+	}
+
+	users := table{name: "users", order: "id", personal: map[string]string{"email": redactedEmail}}
+	got = selectSQL(users, []string{"id", "email"}, true)
+	want = `SELECT row_to_json(r)::text FROM (SELECT "id", id::text || '@redacted.invalid' AS "email" FROM "users") r ` +
+		`ORDER BY id`
+	if got != want {
+		t.Errorf("selectSQL(redact) =\n%s\nwant\n%s", got, want)
+	}
+	if got = selectSQL(users, []string{"id", "email"}, false); strings.Contains(got, "redacted") {
+		t.Errorf("selectSQL() = %s, want the email column unredacted", got)
+	}
+}
+
+func TestColumnList(t *testing.T) {
+	t.Parallel()
+	if got := columnList("", []string{"id", "user"}); got != `"id", "user"` {
+		t.Errorf("columnList() = %s", got)
+	}
+	if got := columnList("r", []string{"id"}); got != `r."id"` {
+		t.Errorf("columnList(r) = %s", got)
+	}
+}
+
+func TestCopyBlob(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newTestStorage(t)
+	if err := store.Put(ctx, "emoji/smile.png", strings.NewReader("image")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	e := NewExporter(nil, store, zerolog.Nop())
+
+	var buf bytes.Buffer
+	aw := newArchiveWriter(&buf)
+	n, err := e.copyBlob(ctx, aw, "emoji/smile.png")
+	if err != nil {
+		t.Fatalf("copyBlob() error: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("copyBlob() = %d bytes, want 5", n)
+	}
+	if _, err := e.copyBlob(ctx, aw, "emoji/missing.png"); !errors.Is(err, media.ErrStorageKeyNotFound) {
+		t.Errorf("copyBlob(missing) error = %v, want ErrStorageKeyNotFound", err)
+	}
+	if err := aw.writeManifest(&Manifest{FormatVersion: FormatVersion, Blobs: BlobManifest{Count: 1}}); err != nil {
+		t.Fatalf("writeManifest() error: %v", err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	a, err := openBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("openArchive() error: %v", err)
+	}
+	rc, err := a.blobs[0].Open()
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer func() { _ = rc.Close() }()
+	if got, _ := io.ReadAll(rc); string(got) != "image" {
+		t.Errorf("archived file = %q, want %q", got, "image")
+	}
+}
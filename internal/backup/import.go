@@ -0,0 +1,303 @@
+package backup
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/uncord-chat/uncord-server/internal/auth"
+	"github.com/uncord-chat/uncord-server/internal/config"
+	"github.com/uncord-chat/uncord-server/internal/media"
+	"github.com/uncord-chat/uncord-server/internal/postgres"
+)
+
+// importBatchSize is the number of rows inserted per statement.
+const importBatchSize = 500
+
+// seedRows lists the tables that tell a freshly initialised instance apart from one in use, with the number of rows
+// first-run initialisation creates in each. Import truncates every exported table, and the DM tables with them through
+// their foreign keys, and restores rows under their archived IDs without remapping them, so it only runs when none of
+// these holds more than the seed: anything more would be destroyed, or would have to be merged with the archive.
+var seedRows = []seedTable{
+	{"users", 1},
+	{"roles", 1},
+	{"channels", 2},
+	{"categories", 0},
+	{"messages", 0},
+	{"message_attachments", 0},
+	{"custom_emoji", 0},
+	{"invites", 0},
+	{"dm_channels", 0},
+}
+
+// Importer restores community snapshots.
+type Importer struct {
+	db      *pgxpool.Pool
+	storage media.StorageProvider
+	cfg     *config.Config
+	log     zerolog.Logger
+}
+
+// NewImporter creates a new Importer. The configuration supplies the password hashing parameters used for accounts
+// restored from archives made without credentials.
+func NewImporter(db *pgxpool.Pool, storage media.StorageProvider, cfg *config.Config, logger zerolog.Logger) *Importer {
+	return &Importer{db: db, storage: storage, cfg: cfg, log: logger}
+}
+
+// Import restores the archive in r into the database and storage and returns its manifest. The database must already
+// be migrated. Stored files are written first and the rows are inserted in a single transaction afterwards, so a failed
+// import leaves the database untouched and can simply be retried. Returns ErrNotEmpty when the instance holds more than
+// the first-run seed, which is replaced by the archive's contents.
+func (im *Importer) Import(ctx context.Context, r io.ReaderAt, size int64) (*Manifest, error) {
+	a, err := openArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := postgres.LatestMigration()
+	if err != nil {
+		return nil, err
+	}
+	if a.manifest.SchemaVersion > latest {
+		return nil, fmt.Errorf("%w: archive is at migration %d, this binary knows up to %d", ErrSchemaTooNew,
+			a.manifest.SchemaVersion, latest)
+	}
+
+	if err := checkEmpty(ctx, im.db); err != nil {
+		return nil, err
+	}
+
+	var placeholder string
+	if !a.manifest.Credentials {
+		if placeholder, err = im.placeholderHash(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := im.restoreBlobs(ctx, a); err != nil {
+		return nil, err
+	}
+
+	err = postgres.WithTx(ctx, im.db, func(tx pgx.Tx) error {
+		// Checked again inside the transaction in case the server was started while the files were copied.
+		if err := checkEmpty(ctx, tx); err != nil {
+			return err
+		}
+		names := make([]string, len(tables))
+		for i, t := range tables {
+			names[i] = pgx.Identifier{t.name}.Sanitize()
+		}
+		if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(names, ", ")+" CASCADE"); err != nil {
+			return fmt.Errorf("clear first-run seed: %w", err)
+		}
+
+		for _, t := range tables {
+			tm, ok := a.tableManifest(t.name)
+			if !ok {
+				continue
+			}
+			if err := restoreTable(ctx, tx, a, t, tm, placeholder); err != nil {
+				return err
+			}
+			im.log.Debug().Str("table", t.name).Int64("rows", tm.Rows).Msg("Restored table")
+		}
+		for _, t := range tables {
+			tm, ok := a.tableManifest(t.name)
+			if !ok || len(t.deferred) == 0 {
+				continue
+			}
+			if err := restoreDeferred(ctx, tx, a, t, tm); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a.manifest, nil
+}
+
+// querier runs queries against a pool or inside a transaction. Satisfied by *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// seedTable is the number of rows first-run initialisation creates in a table.
+type seedTable struct {
+	table string
+	rows  int64
+}
+
+// checkEmpty returns ErrNotEmpty unless every table in seedRows holds at most the first-run seed.
+func checkEmpty(ctx context.Context, q querier) error {
+	counts := make([]string, len(seedRows))
+	for i, sr := range seedRows {
+		counts[i] = "(SELECT COUNT(*) FROM " + pgx.Identifier{sr.table}.Sanitize() + ")"
+	}
+	got := make([]int64, len(seedRows))
+	dest := make([]any, len(seedRows))
+	for i := range got {
+		dest[i] = &got[i]
+	}
+	if err := q.QueryRow(ctx, "SELECT "+strings.Join(counts, ", ")).Scan(dest...); err != nil {
+		return fmt.Errorf("check target is empty: %w", err)
+	}
+
+	var found []string
+	for i, sr := range seedRows {
+		if got[i] > sr.rows {
+			found = append(found, fmt.Sprintf("%d rows in %s", got[i], sr.table))
+		}
+	}
+	if len(found) > 0 {
+		return fmt.Errorf("%w: found %s", ErrNotEmpty, strings.Join(found, ", "))
+	}
+	return nil
+}
+
+// restoreBlobs writes every stored file in the archive to storage under its original key.
+func (im *Importer) restoreBlobs(ctx context.Context, a *archive) error {
+	for _, f := range a.blobs {
+		key := strings.TrimPrefix(f.Name, blobsDir)
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%w: open %s: %w", ErrCorruptArchive, f.Name, err)
+		}
+		err = im.storage.Put(ctx, key, rc)
+		_ = rc.Close()
+		if err != nil {
+			return fmt.Errorf("restore stored file %s: %w", key, err)
+		}
+	}
+	im.log.Debug().Int("files", len(a.blobs)).Msg("Restored stored files")
+	return nil
+}
+
+// placeholderHash returns the hash of a random password nobody knows, given to accounts restored without their
+// credentials so that they cannot sign in until an operator resets their password.
+func (im *Importer) placeholderHash() (string, error) {
+	hash, err := auth.HashPassword(rand.Text(), im.cfg.Argon2Memory, im.cfg.Argon2Iterations, im.cfg.Argon2Parallelism,
+		im.cfg.Argon2SaltLength, im.cfg.Argon2KeyLength)
+	if err != nil {
+		return "", fmt.Errorf("hash placeholder password: %w", err)
+	}
+	return hash, nil
+}
+
+// restoreTable inserts the rows of t from the archive. Columns the archive has but the database no longer does are
+// dropped, and columns the database gained since the export take their defaults, so archives remain restorable after
+// later migrations.
+func restoreTable(ctx context.Context, tx pgx.Tx, a *archive, t table, tm TableManifest, placeholder string) error {
+	target, err := writableColumns(ctx, tx, t.name)
+	if err != nil {
+		return err
+	}
+	cols := insertColumns(tm.Columns, target, t.deferred)
+
+	query := insertSQL(t.name, cols, "")
+	args := []any{nil}
+	if t.name == "users" && placeholder != "" && !slices.Contains(cols, "password_hash") {
+		query = insertSQL(t.name, cols, "password_hash")
+		args = append(args, placeholder)
+	}
+
+	n, err := a.eachBatch(t.name, importBatchSize, func(batch []byte) error {
+		args[0] = string(batch)
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("restore %s: %w", t.name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if n != tm.Rows {
+		return fmt.Errorf("%w: manifest lists %d rows in %s, archive holds %d", ErrCorruptArchive, tm.Rows, t.name, n)
+	}
+	return nil
+}
+
+// restoreDeferred fills in the deferred columns of t once every row exists. User triggers are disabled for the update
+// so that updated_at keeps its archived value instead of being bumped to the time of the import.
+func restoreDeferred(ctx context.Context, tx pgx.Tx, a *archive, t table, tm TableManifest) error {
+	target, err := writableColumns(ctx, tx, t.name)
+	if err != nil {
+		return err
+	}
+	var cols []string
+	for _, c := range t.deferred {
+		if slices.Contains(tm.Columns, c) && slices.Contains(target, c) {
+			cols = append(cols, c)
+		}
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+
+	name := pgx.Identifier{t.name}.Sanitize()
+	if _, err := tx.Exec(ctx, "ALTER TABLE "+name+" DISABLE TRIGGER USER"); err != nil {
+		return fmt.Errorf("disable triggers on %s: %w", t.name, err)
+	}
+	query := deferredSQL(t.name, cols)
+	_, err = a.eachBatch(t.name, importBatchSize, func(batch []byte) error {
+		if _, err := tx.Exec(ctx, query, string(batch)); err != nil {
+			return fmt.Errorf("restore references in %s: %w", t.name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "ALTER TABLE "+name+" ENABLE TRIGGER USER"); err != nil {
+		return fmt.Errorf("enable triggers on %s: %w", t.name, err)
+	}
+	return nil
+}
+
+// insertColumns returns the archived columns that the target table still has, in archive order, minus deferred ones.
+func insertColumns(archived, target, deferred []string) []string {
+	var cols []string
+	for _, c := range archived {
+		if slices.Contains(target, c) && !slices.Contains(deferred, c) {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// insertSQL builds the statement that inserts a JSON array of rows ($1) into table. When fill is not empty, that column
+// is set to $2 for every row.
+func insertSQL(table string, cols []string, fill string) string {
+	name := pgx.Identifier{table}.Sanitize()
+	into, from := columnList("", cols), columnList("r", cols)
+	if fill != "" {
+		into += ", " + pgx.Identifier{fill}.Sanitize()
+		from += ", $2"
+	}
+	return "INSERT INTO " + name + " (" + into + ") SELECT " + from +
+		" FROM json_populate_recordset(NULL::" + name + ", $1::json) AS r"
+}
+
+// deferredSQL builds the statement that sets cols from a JSON array of rows ($1), matching rows by id. Rows whose
+// deferred columns are all NULL are skipped.
+func deferredSQL(table string, cols []string) string {
+	name := pgx.Identifier{table}.Sanitize()
+	set := make([]string, len(cols))
+	notNull := make([]string, len(cols))
+	for i, c := range cols {
+		q := pgx.Identifier{c}.Sanitize()
+		set[i] = q + " = r." + q
+		notNull[i] = "r." + q + " IS NOT NULL"
+	}
+	return "UPDATE " + name + " AS t SET " + strings.Join(set, ", ") +
+		" FROM json_populate_recordset(NULL::" + name + ", $1::json) AS r" +
+		" WHERE t.id = r.id AND (" + strings.Join(notNull, " OR ") + ")"
+}
@@ -0,0 +1,117 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+func TestInsertColumns(t *testing.T) {
+	t.Parallel()
+	archived := []string{"id", "content", "reply_to_id", "dropped"}
+	target := []string{"id", "content", "reply_to_id", "added"}
+	got := insertColumns(archived, target, []string{"reply_to_id"})
+	if want := []string{"id", "content"}; !slices.Equal(got, want) {
+		t.Errorf("insertColumns() = %v, want %v", got, want)
+	}
+}
+
+func TestInsertSQL(t *testing.T) {
+	t.Parallel()
+	got := insertSQL("roles", []string{"id", "name"}, "")
+	want := `INSERT INTO "roles" ("id", "name") SELECT r."id", r."name" FROM json_populate_recordset(NULL::"roles", ` +
+		`$1::json) AS r`
+	if got != want {
+		t.Errorf("insertSQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	got = insertSQL("users", []string{"id"}, "password_hash")
+	want = `INSERT INTO "users" ("id", "password_hash") SELECT r."id", $2 FROM json_populate_recordset(NULL::"users", ` +
+		`$1::json) AS r`
+	if got != want {
+		t.Errorf("insertSQL(fill) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDeferredSQL(t *testing.T) {
+	t.Parallel()
+	got := deferredSQL("messages", []string{"reply_to_id", "thread_id"})
+	want := `UPDATE "messages" AS t SET "reply_to_id" = r."reply_to_id", "thread_id" = r."thread_id" ` +
+		`FROM json_populate_recordset(NULL::"messages", $1::json) AS r ` +
+		`WHERE t.id = r.id AND (r."reply_to_id" IS NOT NULL OR r."thread_id" IS NOT NULL)`
+	if got != want {
+		t.Errorf("deferredSQL() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRestoreBlobs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	a, err := openBytes(buildArchive(t, validArchive()))
+	if err != nil {
+		t.Fatalf("openArchive() error: %v", err)
+	}
+	store := newTestStorage(t)
+	im := NewImporter(nil, store, nil, zerolog.Nop())
+
+	if err := im.restoreBlobs(ctx, a); err != nil {
+		t.Fatalf("restoreBlobs() error: %v", err)
+	}
+	rc, err := store.Get(ctx, "emoji/smile.png")
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	defer func() { _ = rc.Close() }()
+	if got, _ := io.ReadAll(rc); string(got) != "image" {
+		t.Errorf("restored file = %q, want %q", got, "image")
+	}
+}
+
+// fakeCountQuerier answers the checkEmpty query with fixed row counts, in seedRows order.
+type fakeCountQuerier struct {
+	counts []int64
+}
+
+func (q fakeCountQuerier) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (q fakeCountQuerier) QueryRow(context.Context, string, ...any) pgx.Row {
+	return fakeCountRow(q)
+}
+
+type fakeCountRow fakeCountQuerier
+
+func (r fakeCountRow) Scan(dest ...any) error {
+	for i, d := range dest {
+		*d.(*int64) = r.counts[i]
+	}
+	return nil
+}
+
+func TestCheckEmpty(t *testing.T) {
+	t.Parallel()
+	seed := make([]int64, len(seedRows))
+	for i, sr := range seedRows {
+		seed[i] = sr.rows
+	}
+	if err := checkEmpty(context.Background(), fakeCountQuerier{counts: seed}); err != nil {
+		t.Errorf("checkEmpty(first-run seed) error = %v, want nil", err)
+	}
+
+	withInvite := slices.Clone(seed)
+	withInvite[slices.IndexFunc(seedRows, func(sr seedTable) bool { return sr.table == "invites" })]++
+	err := checkEmpty(context.Background(), fakeCountQuerier{counts: withInvite})
+	if !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("checkEmpty(with invite) error = %v, want ErrNotEmpty", err)
+	}
+	if !strings.Contains(err.Error(), "1 rows in invites") {
+		t.Errorf("checkEmpty() error = %q, want it to name the invites table", err)
+	}
+}
@@ -48,6 +48,42 @@ func (r *PGRepository) List(ctx context.Context) ([]Channel, error) {
 	return channels, nil
 }
 
+// Search returns up to limit channels among ids whose name contains query or closely resembles it. Exact matches rank
+// first, then prefix matches, then everything else; within each group the channels with the most recent messages come
+// first.
+func (r *PGRepository) Search(ctx context.Context, query string, ids []uuid.UUID, limit int) ([]Channel, error) {
+	escaped := postgres.EscapeLike(query)
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf(
+			`SELECT %s FROM channels c
+			 WHERE c.id = ANY($1) AND (c.name ILIKE $2 OR $3 <%% c.name)
+			 ORDER BY CASE WHEN lower(c.name) = lower($3) THEN 0 WHEN c.name ILIKE $4 THEN 1 ELSE 2 END,
+			          (SELECT max(m.created_at) FROM messages m
+			           WHERE m.channel_id = c.id AND m.deleted_at IS NULL) DESC NULLS LAST,
+			          word_similarity($3, c.name) DESC,
+			          c.position
+			 LIMIT $5`, selectColumns),
+		ids, "%"+escaped+"%", query, escaped+"%", limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []Channel
+	for rows.Next() {
+		ch, err := scanChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, *ch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate channels: %w", err)
+	}
+	return channels, nil
+}
+
 // GetByID returns the channel matching the given ID.
 func (r *PGRepository) GetByID(ctx context.Context, id uuid.UUID) (*Channel, error) {
 	row := r.db.QueryRow(ctx,
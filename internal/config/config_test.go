@@ -21,14 +21,17 @@ func TestLoadDefaults(t *testing.T) {
 		"ABUSE_DISPOSABLE_EMAIL_BLOCKLIST_ENABLED", "ABUSE_DISPOSABLE_EMAIL_BLOCKLIST_URL",
 		"ABUSE_DISPOSABLE_EMAIL_BLOCKLIST_REFRESH_INTERVAL", "ABUSE_DISPOSABLE_EMAIL_BLOCKLIST_TIMEOUT",
 		"VALKEY_DIAL_TIMEOUT",
-		"TYPESENSE_URL", "TYPESENSE_API_KEY", "TYPESENSE_TIMEOUT",
+		"SEARCH_BACKEND", "TYPESENSE_URL", "TYPESENSE_API_KEY", "TYPESENSE_TIMEOUT",
+		"SEARCH_OUTBOX_POLL_INTERVAL", "SEARCH_OUTBOX_BATCH_SIZE", "SEARCH_OUTBOX_MAX_ATTEMPTS",
 		"INIT_OWNER_EMAIL", "INIT_OWNER_USERNAME", "INIT_OWNER_PASSWORD",
 		"ONBOARDING_OPEN_JOIN", "ONBOARDING_REQUIRE_EMAIL_VERIFICATION",
 		"ONBOARDING_MIN_ACCOUNT_AGE", "ONBOARDING_REQUIRE_PHONE", "ONBOARDING_REQUIRE_CAPTCHA",
-		"MAX_UPLOAD_SIZE_MB", "MAX_AVATAR_SIZE_MB", "MAX_AVATAR_DIMENSION", "MAX_BANNER_WIDTH", "MAX_BANNER_HEIGHT",
-		"STORAGE_BACKEND", "STORAGE_LOCAL_PATH",
+		"MAX_UPLOAD_SIZE_MB", "MAX_UPLOAD_CHUNK_SIZE_MB", "MAX_AVATAR_SIZE_MB", "MAX_AVATAR_DIMENSION", "MAX_BANNER_WIDTH", "MAX_BANNER_HEIGHT",
+		"STORAGE_BACKEND", "STORAGE_LOCAL_PATH", "MEDIA_SIGNED_URLS", "MEDIA_URL_TTL",
 		"MAX_ATTACHMENTS_PER_MESSAGE", "ATTACHMENT_ORPHAN_TTL",
+		"FFMPEG_PATH", "FFPROBE_PATH", "SCAN_BACKEND", "CLAMAV_ADDRESS", "CLAMAV_TIMEOUT",
 		"RATE_LIMIT_UPLOAD_COUNT", "RATE_LIMIT_UPLOAD_WINDOW_SECONDS",
+		"RATE_LIMIT_MEDIA_VARIANT_COUNT", "RATE_LIMIT_MEDIA_VARIANT_WINDOW_SECONDS",
 		"MAX_CHANNELS", "MAX_CATEGORIES",
 		"SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "SMTP_FROM",
 		"SERVER_SECRET", "DELETION_TOMBSTONE_USERNAMES", "DELETION_TOMBSTONE_RETENTION",
@@ -37,11 +40,19 @@ func TestLoadDefaults(t *testing.T) {
 		"GATEWAY_SESSION_TTL_SECONDS",
 		"GATEWAY_REPLAY_BUFFER_SIZE", "GATEWAY_MAX_CONNECTIONS",
 		"GATEWAY_PUBLISH_WORKERS", "GATEWAY_PUBLISH_QUEUE_SIZE", "GATEWAY_PUBLISH_TIMEOUT",
+		"GATEWAY_SHARDED_PUBSUB", "GATEWAY_DELIVERY", "GATEWAY_STREAM_MAX_LEN",
+		"GATEWAY_DRAIN_BATCH_SIZE", "GATEWAY_DRAIN_BATCH_INTERVAL",
 		"RATE_LIMIT_WS_COUNT", "RATE_LIMIT_WS_WINDOW_SECONDS",
+		"RATE_LIMIT_MEMBER_REQUEST_COUNT", "RATE_LIMIT_MEMBER_REQUEST_WINDOW_SECONDS",
 		"RATE_LIMIT_MSG_COUNT", "RATE_LIMIT_MSG_WINDOW_SECONDS",
 		"RATE_LIMIT_MSG_GLOBAL_COUNT", "RATE_LIMIT_MSG_GLOBAL_WINDOW_SECONDS",
 		"REQUEST_TIMEOUT",
 		"SHUTDOWN_TIMEOUT", "SHUTDOWN_GRACE_TIMEOUT",
+		"HEALTH_MEDIA_BACKLOG_THRESHOLD",
+		"METRICS_ENABLED", "METRICS_TOKEN", "METRICS_ADDR",
+		"ADMIN_ADDR", "ADMIN_TOKEN",
+		"TRACING_ENABLED", "TRACING_OTLP_ENDPOINT", "TRACING_SAMPLE_RATIO", "TRACING_SERVICE_NAME",
+		"TRACING_TRUST_PARENT",
 	}
 	for _, k := range keys {
 		t.Setenv(k, "")
@@ -130,10 +141,56 @@ func TestLoadDefaults(t *testing.T) {
 		t.Errorf("ValkeyDialTimeout = %v, want 5s", cfg.ValkeyDialTimeout)
 	}
 
+	// Search defaults
+	if cfg.SearchBackend != "typesense" {
+		t.Errorf("SearchBackend = %q, want %q", cfg.SearchBackend, "typesense")
+	}
+	if !cfg.TypesenseEnabled() {
+		t.Error("TypesenseEnabled() = false, want true")
+	}
+
 	// Typesense defaults
 	if cfg.TypesenseTimeout != 30*time.Second {
 		t.Errorf("TypesenseTimeout = %v, want 30s", cfg.TypesenseTimeout)
 	}
+	if cfg.SearchOutboxPollInterval != time.Second {
+		t.Errorf("SearchOutboxPollInterval = %v, want 1s", cfg.SearchOutboxPollInterval)
+	}
+	if cfg.SearchOutboxBatchSize != 100 {
+		t.Errorf("SearchOutboxBatchSize = %d, want 100", cfg.SearchOutboxBatchSize)
+	}
+	if cfg.SearchOutboxMaxAttempts != 10 {
+		t.Errorf("SearchOutboxMaxAttempts = %d, want 10", cfg.SearchOutboxMaxAttempts)
+	}
+
+	// Metrics defaults
+	if cfg.MetricsEnabled {
+		t.Error("MetricsEnabled = true, want false")
+	}
+	if cfg.MetricsAddr != "" {
+		t.Errorf("MetricsAddr = %q, want empty", cfg.MetricsAddr)
+	}
+	if cfg.AdminAddr != "" {
+		t.Errorf("AdminAddr = %q, want empty", cfg.AdminAddr)
+	}
+	if cfg.HealthMediaBacklogThreshold != 1000 {
+		t.Errorf("HealthMediaBacklogThreshold = %d, want 1000", cfg.HealthMediaBacklogThreshold)
+	}
+	if cfg.TracingEnabled {
+		t.Error("TracingEnabled = true, want false")
+	}
+	if cfg.TracingEndpoint != "http://localhost:4318" {
+		t.Errorf("TracingEndpoint = %q, want %q", cfg.TracingEndpoint, "http://localhost:4318")
+	}
+	if cfg.TracingSampleRatio != 0.1 {
+		t.Errorf("TracingSampleRatio = %v, want 0.1", cfg.TracingSampleRatio)
+	}
+	if cfg.TracingServiceName != "uncord-server" {
+		t.Errorf("TracingServiceName = %q, want %q", cfg.TracingServiceName, "uncord-server")
+	}
+	if cfg.TracingTrustParent {
+		t.Error("TracingTrustParent = true, want false")
+	}
 
 	// Onboarding defaults
 	if cfg.OnboardingOpenJoin {
@@ -180,6 +237,21 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.GatewayPublishTimeout != 5*time.Second {
 		t.Errorf("GatewayPublishTimeout = %v, want 5s", cfg.GatewayPublishTimeout)
 	}
+	if cfg.GatewayShardedPubSub {
+		t.Error("GatewayShardedPubSub = true, want false")
+	}
+	if cfg.GatewayDelivery != "pubsub" || cfg.GatewayStreamDelivery() {
+		t.Errorf("GatewayDelivery = %q, want \"pubsub\"", cfg.GatewayDelivery)
+	}
+	if cfg.GatewayStreamMaxLen != 100000 {
+		t.Errorf("GatewayStreamMaxLen = %d, want 100000", cfg.GatewayStreamMaxLen)
+	}
+	if cfg.GatewayDrainBatchSize != 100 {
+		t.Errorf("GatewayDrainBatchSize = %d, want 100", cfg.GatewayDrainBatchSize)
+	}
+	if cfg.GatewayDrainBatchInterval != time.Second {
+		t.Errorf("GatewayDrainBatchInterval = %v, want 1s", cfg.GatewayDrainBatchInterval)
+	}
 
 	// Rate limit defaults
 	if cfg.RateLimitAPIRequests != 60 {
@@ -194,6 +266,12 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.RateLimitWSWindowSeconds != 60 {
 		t.Errorf("RateLimitWSWindowSeconds = %d, want 60", cfg.RateLimitWSWindowSeconds)
 	}
+	if cfg.RateLimitMemberReqCount != 10 {
+		t.Errorf("RateLimitMemberReqCount = %d, want 10", cfg.RateLimitMemberReqCount)
+	}
+	if cfg.RateLimitMemberReqWindowSeconds != 60 {
+		t.Errorf("RateLimitMemberReqWindowSeconds = %d, want 60", cfg.RateLimitMemberReqWindowSeconds)
+	}
 	if cfg.RateLimitMsgCount != 5 {
 		t.Errorf("RateLimitMsgCount = %d, want 5", cfg.RateLimitMsgCount)
 	}
@@ -211,6 +289,9 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.MaxUploadSizeMB != 100 {
 		t.Errorf("MaxUploadSizeMB = %d, want 100", cfg.MaxUploadSizeMB)
 	}
+	if cfg.MaxUploadChunkSizeMB != 8 {
+		t.Errorf("MaxUploadChunkSizeMB = %d, want 8", cfg.MaxUploadChunkSizeMB)
+	}
 	if cfg.MaxAvatarSizeMB != 8 {
 		t.Errorf("MaxAvatarSizeMB = %d, want 8", cfg.MaxAvatarSizeMB)
 	}
@@ -239,6 +320,30 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.AttachmentOrphanTTL != time.Hour {
 		t.Errorf("AttachmentOrphanTTL = %v, want 1h", cfg.AttachmentOrphanTTL)
 	}
+	if cfg.FFmpegPath != "ffmpeg" {
+		t.Errorf("FFmpegPath = %q, want %q", cfg.FFmpegPath, "ffmpeg")
+	}
+	if cfg.FFprobePath != "ffprobe" {
+		t.Errorf("FFprobePath = %q, want %q", cfg.FFprobePath, "ffprobe")
+	}
+	if cfg.MediaSignedURLs {
+		t.Error("MediaSignedURLs = true, want false")
+	}
+	if cfg.MediaURLTTL != time.Hour {
+		t.Errorf("MediaURLTTL = %v, want %v", cfg.MediaURLTTL, time.Hour)
+	}
+	if cfg.ScanBackend != "none" {
+		t.Errorf("ScanBackend = %q, want %q", cfg.ScanBackend, "none")
+	}
+	if cfg.ClamAVAddress != "clamav:3310" {
+		t.Errorf("ClamAVAddress = %q, want %q", cfg.ClamAVAddress, "clamav:3310")
+	}
+	if cfg.ClamAVTimeout != 60*time.Second {
+		t.Errorf("ClamAVTimeout = %v, want %v", cfg.ClamAVTimeout, 60*time.Second)
+	}
+	if cfg.ScanningEnabled() {
+		t.Error("ScanningEnabled() = true, want false by default")
+	}
 
 	// Upload rate limit defaults
 	if cfg.RateLimitUploadCount != 10 {
@@ -247,6 +352,12 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.RateLimitUploadWindowSeconds != 60 {
 		t.Errorf("RateLimitUploadWindowSeconds = %d, want 60", cfg.RateLimitUploadWindowSeconds)
 	}
+	if cfg.RateLimitMediaVariantCount != 60 {
+		t.Errorf("RateLimitMediaVariantCount = %d, want 60", cfg.RateLimitMediaVariantCount)
+	}
+	if cfg.RateLimitMediaVariantWindowSeconds != 60 {
+		t.Errorf("RateLimitMediaVariantWindowSeconds = %d, want 60", cfg.RateLimitMediaVariantWindowSeconds)
+	}
 
 	// Entity limit defaults
 	if cfg.MaxChannels != 500 {
@@ -286,6 +397,14 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.DataCleanupInterval != 12*time.Hour {
 		t.Errorf("DataCleanupInterval = %v, want 12h", cfg.DataCleanupInterval)
 	}
+
+	// E2EE key maintenance defaults
+	if cfg.E2EESPKRotationPeriod != 720*time.Hour {
+		t.Errorf("E2EESPKRotationPeriod = %v, want 720h", cfg.E2EESPKRotationPeriod)
+	}
+	if cfg.E2EEStaleDeviceTimeout != 2160*time.Hour {
+		t.Errorf("E2EEStaleDeviceTimeout = %v, want 2160h", cfg.E2EEStaleDeviceTimeout)
+	}
 }
 
 func TestLoadValidationRequiresJWTSecret(t *testing.T) {
@@ -476,6 +595,29 @@ func TestMaxUploadSizeBytes(t *testing.T) {
 	}
 }
 
+func TestMaxUploadChunkSizeBytes(t *testing.T) {
+	cfg := &Config{MaxUploadChunkSizeMB: 8}
+	want := int64(8) * 1024 * 1024
+	if got := cfg.MaxUploadChunkSizeBytes(); got != want {
+		t.Errorf("MaxUploadChunkSizeBytes() = %d, want %d", got, want)
+	}
+}
+
+func TestLoadValidationUploadChunkExceedsUploadSize(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+	t.Setenv("SERVER_SECRET", testServerSecret)
+	t.Setenv("MAX_UPLOAD_SIZE_MB", "4")
+	t.Setenv("MAX_UPLOAD_CHUNK_SIZE_MB", "8")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() returned nil error, want validation error for MAX_UPLOAD_CHUNK_SIZE_MB > MAX_UPLOAD_SIZE_MB")
+	}
+	if !strings.Contains(err.Error(), "MAX_UPLOAD_CHUNK_SIZE_MB must not exceed MAX_UPLOAD_SIZE_MB") {
+		t.Errorf("error %q does not mention MAX_UPLOAD_CHUNK_SIZE_MB", err.Error())
+	}
+}
+
 func TestMaxAvatarSizeBytes(t *testing.T) {
 	cfg := &Config{MaxAvatarSizeMB: 8}
 	want := int64(8) * 1024 * 1024
@@ -808,11 +950,13 @@ func TestLoadValidationDeletionTombstoneRetentionNegative(t *testing.T) {
 		Argon2Iterations:                3,
 		Argon2Parallelism:               2,
 		MaxUploadSizeMB:                 100,
+		MaxUploadChunkSizeMB:            8,
 		MaxAvatarSizeMB:                 8,
 		MaxAvatarDimension:              1080,
 		MaxBannerWidth:                  1920,
 		MaxBannerHeight:                 480,
 		StorageBackend:                  "local",
+		ScanBackend:                     "none",
 		StorageLocalPath:                "/data/uncord/media",
 		MaxAttachmentsPerMessage:        10,
 		AttachmentOrphanTTL:             time.Hour,
@@ -826,6 +970,8 @@ func TestLoadValidationDeletionTombstoneRetentionNegative(t *testing.T) {
 		RateLimitAuthWindowSeconds:      300,
 		RateLimitWSCount:                120,
 		RateLimitWSWindowSeconds:        60,
+		RateLimitMemberReqCount:         10,
+		RateLimitMemberReqWindowSeconds: 60,
 		RateLimitMsgCount:               5,
 		RateLimitMsgWindowSeconds:       5,
 		RateLimitMsgGlobalCount:         30,
@@ -837,6 +983,10 @@ func TestLoadValidationDeletionTombstoneRetentionNegative(t *testing.T) {
 		DataCleanupInterval:             12 * time.Hour,
 		MaxMessageLength:                4000,
 		RequestTimeout:                  30 * time.Second,
+		SearchBackend:                   "typesense",
+		SearchOutboxPollInterval:        time.Second,
+		SearchOutboxBatchSize:           100,
+		SearchOutboxMaxAttempts:         10,
 	}
 	err := cfg.validate()
 	if err == nil {
@@ -863,6 +1013,274 @@ func TestSMTPConfigured(t *testing.T) {
 	}
 }
 
+func TestLoadValidationScanBackend(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+	t.Setenv("SERVER_SECRET", testServerSecret)
+	t.Setenv("SCAN_BACKEND", "virustotal")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() returned nil error, want validation error for SCAN_BACKEND")
+	}
+	if !strings.Contains(err.Error(), "SCAN_BACKEND must be") {
+		t.Errorf("error %q does not mention SCAN_BACKEND", err.Error())
+	}
+}
+
+func TestLoadScanBackendClamAV(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+	t.Setenv("SERVER_SECRET", testServerSecret)
+	t.Setenv("TYPESENSE_API_KEY", "test-typesense-key")
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com")
+	t.Setenv("SCAN_BACKEND", "clamav")
+	t.Setenv("CLAMAV_ADDRESS", "/run/clamav/clamd.sock")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.ScanningEnabled() {
+		t.Error("ScanningEnabled() = false, want true")
+	}
+	if cfg.ClamAVAddress != "/run/clamav/clamd.sock" {
+		t.Errorf("ClamAVAddress = %q, want %q", cfg.ClamAVAddress, "/run/clamav/clamd.sock")
+	}
+}
+
+func TestLoadValidationMediaURLTTL(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+	t.Setenv("SERVER_SECRET", testServerSecret)
+	t.Setenv("MEDIA_SIGNED_URLS", "true")
+	t.Setenv("MEDIA_URL_TTL", "30s")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() returned nil error, want validation error for MEDIA_URL_TTL")
+	}
+	if !strings.Contains(err.Error(), "MEDIA_URL_TTL") {
+		t.Errorf("error %q does not mention MEDIA_URL_TTL", err.Error())
+	}
+}
+
+func TestLoadValidationSearchBackend(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+	t.Setenv("SERVER_SECRET", testServerSecret)
+	t.Setenv("SEARCH_BACKEND", "elasticsearch")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() returned nil error, want validation error for SEARCH_BACKEND")
+	}
+	if !strings.Contains(err.Error(), "SEARCH_BACKEND must be") {
+		t.Errorf("error %q does not mention SEARCH_BACKEND", err.Error())
+	}
+}
+
+func TestLoadSearchBackendPostgres(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+	t.Setenv("SERVER_SECRET", testServerSecret)
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com")
+	t.Setenv("SEARCH_BACKEND", "postgres")
+
+	// The Typesense API key is left at its default: it is not required when Typesense is not used.
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.TypesenseEnabled() {
+		t.Error("TypesenseEnabled() = true, want false")
+	}
+}
+
+func TestLoadValidationMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "public without token",
+			env:     map[string]string{"METRICS_ENABLED": "true"},
+			wantErr: "METRICS_TOKEN or METRICS_ADDR is required",
+		},
+		{
+			name: "public with token",
+			env:  map[string]string{"METRICS_ENABLED": "true", "METRICS_TOKEN": "scrape-token"},
+		},
+		{
+			name: "separate address",
+			env:  map[string]string{"METRICS_ENABLED": "true", "METRICS_ADDR": "127.0.0.1:9801"},
+		},
+		{
+			name:    "invalid address",
+			env:     map[string]string{"METRICS_ENABLED": "true", "METRICS_ADDR": "9801"},
+			wantErr: "METRICS_ADDR must be",
+		},
+		{
+			name: "disabled",
+			env:  map[string]string{"METRICS_ADDR": "9801"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+			t.Setenv("SERVER_SECRET", testServerSecret)
+			t.Setenv("TYPESENSE_API_KEY", "test-typesense-key")
+			t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadValidationAdmin(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name: "with token",
+			env:  map[string]string{"ADMIN_ADDR": "127.0.0.1:9802", "ADMIN_TOKEN": "admin-token"},
+		},
+		{
+			name:    "without token",
+			env:     map[string]string{"ADMIN_ADDR": "127.0.0.1:9802"},
+			wantErr: "ADMIN_TOKEN is required",
+		},
+		{
+			name:    "invalid address",
+			env:     map[string]string{"ADMIN_ADDR": "9802", "ADMIN_TOKEN": "admin-token"},
+			wantErr: "ADMIN_ADDR must be",
+		},
+		{
+			name: "disabled",
+			env:  map[string]string{"ADMIN_TOKEN": "admin-token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+			t.Setenv("SERVER_SECRET", testServerSecret)
+			t.Setenv("TYPESENSE_API_KEY", "test-typesense-key")
+			t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadValidationTracing(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{"TRACING_ENABLED": "true"},
+		},
+		{
+			name: "custom endpoint and ratio",
+			env: map[string]string{
+				"TRACING_ENABLED": "true", "TRACING_OTLP_ENDPOINT": "https://otel.example.com:4318",
+				"TRACING_SAMPLE_RATIO": "1",
+			},
+		},
+		{
+			name:    "endpoint without scheme",
+			env:     map[string]string{"TRACING_ENABLED": "true", "TRACING_OTLP_ENDPOINT": "otel-collector:4318"},
+			wantErr: "TRACING_OTLP_ENDPOINT must be",
+		},
+		{
+			name:    "ratio above one",
+			env:     map[string]string{"TRACING_ENABLED": "true", "TRACING_SAMPLE_RATIO": "1.5"},
+			wantErr: "TRACING_SAMPLE_RATIO must be between 0 and 1",
+		},
+		{
+			name:    "ratio not a number",
+			env:     map[string]string{"TRACING_SAMPLE_RATIO": "all"},
+			wantErr: "invalid value for TRACING_SAMPLE_RATIO",
+		},
+		{
+			name: "disabled",
+			env:  map[string]string{"TRACING_OTLP_ENDPOINT": "otel-collector:4318", "TRACING_SAMPLE_RATIO": "2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+			t.Setenv("SERVER_SECRET", testServerSecret)
+			t.Setenv("TYPESENSE_API_KEY", "test-typesense-key")
+			t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadValidationSearchOutbox(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+	}{
+		{"SEARCH_OUTBOX_POLL_INTERVAL", "10ms"},
+		{"SEARCH_OUTBOX_BATCH_SIZE", "0"},
+		{"SEARCH_OUTBOX_MAX_ATTEMPTS", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+			t.Setenv("SERVER_SECRET", testServerSecret)
+			t.Setenv(tt.key, tt.value)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("Load() returned nil error, want validation error for %s", tt.key)
+			}
+			if !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("error %q does not mention %s", err.Error(), tt.key)
+			}
+		})
+	}
+}
+
 func TestLoadValidationStorageBackend(t *testing.T) {
 	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
 	t.Setenv("SERVER_SECRET", testServerSecret)
@@ -918,8 +1336,13 @@ func TestLoadGatewayOverrides(t *testing.T) {
 	t.Setenv("GATEWAY_PUBLISH_QUEUE_SIZE", "2048")
 	t.Setenv("GATEWAY_IDENTIFY_TIMEOUT", "45s")
 	t.Setenv("GATEWAY_PUBLISH_TIMEOUT", "10s")
+	t.Setenv("GATEWAY_SHARDED_PUBSUB", "true")
+	t.Setenv("GATEWAY_DRAIN_BATCH_SIZE", "25")
+	t.Setenv("GATEWAY_DRAIN_BATCH_INTERVAL", "250ms")
 	t.Setenv("RATE_LIMIT_WS_COUNT", "60")
 	t.Setenv("RATE_LIMIT_WS_WINDOW_SECONDS", "30")
+	t.Setenv("RATE_LIMIT_MEMBER_REQUEST_COUNT", "3")
+	t.Setenv("RATE_LIMIT_MEMBER_REQUEST_WINDOW_SECONDS", "15")
 
 	cfg, err := Load()
 	if err != nil {
@@ -943,6 +1366,12 @@ func TestLoadGatewayOverrides(t *testing.T) {
 	if cfg.RateLimitWSWindowSeconds != 30 {
 		t.Errorf("RateLimitWSWindowSeconds = %d, want 30", cfg.RateLimitWSWindowSeconds)
 	}
+	if cfg.RateLimitMemberReqCount != 3 {
+		t.Errorf("RateLimitMemberReqCount = %d, want 3", cfg.RateLimitMemberReqCount)
+	}
+	if cfg.RateLimitMemberReqWindowSeconds != 15 {
+		t.Errorf("RateLimitMemberReqWindowSeconds = %d, want 15", cfg.RateLimitMemberReqWindowSeconds)
+	}
 	if cfg.GatewayPublishWorkers != 8 {
 		t.Errorf("GatewayPublishWorkers = %d, want 8", cfg.GatewayPublishWorkers)
 	}
@@ -955,6 +1384,116 @@ func TestLoadGatewayOverrides(t *testing.T) {
 	if cfg.GatewayPublishTimeout != 10*time.Second {
 		t.Errorf("GatewayPublishTimeout = %v, want 10s", cfg.GatewayPublishTimeout)
 	}
+	if !cfg.GatewayShardedPubSub {
+		t.Error("GatewayShardedPubSub = false, want true")
+	}
+	if cfg.GatewayDrainBatchSize != 25 {
+		t.Errorf("GatewayDrainBatchSize = %d, want 25", cfg.GatewayDrainBatchSize)
+	}
+	if cfg.GatewayDrainBatchInterval != 250*time.Millisecond {
+		t.Errorf("GatewayDrainBatchInterval = %v, want 250ms", cfg.GatewayDrainBatchInterval)
+	}
+}
+
+func TestLoadGatewayStreamDelivery(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+	t.Setenv("SERVER_SECRET", testServerSecret)
+	t.Setenv("TYPESENSE_API_KEY", "test-typesense-key")
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com")
+	t.Setenv("GATEWAY_DELIVERY", "stream")
+	t.Setenv("GATEWAY_STREAM_MAX_LEN", "5000")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if !cfg.GatewayStreamDelivery() {
+		t.Errorf("GatewayStreamDelivery() = false for GATEWAY_DELIVERY=%q, want true", cfg.GatewayDelivery)
+	}
+	if cfg.GatewayStreamMaxLen != 5000 {
+		t.Errorf("GatewayStreamMaxLen = %d, want 5000", cfg.GatewayStreamMaxLen)
+	}
+}
+
+func TestLoadValidationE2EEKeyMaintenance(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"rotation period too short", map[string]string{"E2EE_SPK_ROTATION_PERIOD": "30m"}, "E2EE_SPK_ROTATION_PERIOD"},
+		{"stale timeout too short", map[string]string{"E2EE_STALE_DEVICE_TIMEOUT": "1h"}, "E2EE_STALE_DEVICE_TIMEOUT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+			t.Setenv("SERVER_SECRET", testServerSecret)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("Load() returned nil error, want validation error for %s", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not mention %s", err.Error(), tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("zero disables", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+		t.Setenv("SERVER_SECRET", testServerSecret)
+		t.Setenv("TYPESENSE_API_KEY", "test-typesense-key")
+		t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com")
+		t.Setenv("E2EE_SPK_ROTATION_PERIOD", "0")
+		t.Setenv("E2EE_STALE_DEVICE_TIMEOUT", "0")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned unexpected error: %v", err)
+		}
+		if cfg.E2EESPKRotationPeriod != 0 || cfg.E2EEStaleDeviceTimeout != 0 {
+			t.Errorf("rotation period = %v, stale timeout = %v, want both 0", cfg.E2EESPKRotationPeriod,
+				cfg.E2EEStaleDeviceTimeout)
+		}
+	})
+}
+
+func TestLoadValidationGatewayDeliveryAndDrain(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"unknown mode", map[string]string{"GATEWAY_DELIVERY": "kafka"}, "GATEWAY_DELIVERY"},
+		{"stream shorter than replay buffer", map[string]string{
+			"GATEWAY_DELIVERY": "stream", "GATEWAY_STREAM_MAX_LEN": "500",
+		}, "GATEWAY_STREAM_MAX_LEN"},
+		{"drain batch size", map[string]string{"GATEWAY_DRAIN_BATCH_SIZE": "0"}, "GATEWAY_DRAIN_BATCH_SIZE"},
+		{"drain batch interval", map[string]string{"GATEWAY_DRAIN_BATCH_INTERVAL": "1ms"}, "GATEWAY_DRAIN_BATCH_INTERVAL"},
+		{"stream with sharded pub/sub", map[string]string{
+			"GATEWAY_DELIVERY": "stream", "GATEWAY_SHARDED_PUBSUB": "true",
+		}, "GATEWAY_SHARDED_PUBSUB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+			t.Setenv("SERVER_SECRET", testServerSecret)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load()
+			if err == nil {
+				t.Fatalf("Load() returned nil error, want validation error for %s", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not mention %s", err.Error(), tt.wantErr)
+			}
+		})
+	}
 }
 
 func TestLoadValidationGatewayIdentifyTimeoutTooLow(t *testing.T) {
@@ -1146,3 +1685,17 @@ func TestLoadValidationRequestTimeoutTooLow(t *testing.T) {
 		t.Errorf("error %q does not mention REQUEST_TIMEOUT", err.Error())
 	}
 }
+
+func TestLoadValidationHealthMediaBacklogThresholdTooLow(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret-for-defaults-minimum-32")
+	t.Setenv("SERVER_SECRET", testServerSecret)
+	t.Setenv("HEALTH_MEDIA_BACKLOG_THRESHOLD", "0")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() returned nil error, want validation error for HEALTH_MEDIA_BACKLOG_THRESHOLD < 1")
+	}
+	if !strings.Contains(err.Error(), "HEALTH_MEDIA_BACKLOG_THRESHOLD must be at least 1") {
+		t.Errorf("error %q does not mention HEALTH_MEDIA_BACKLOG_THRESHOLD", err.Error())
+	}
+}
@@ -2,4 +2,21 @@
 // server stores public key material (identity keys, signed pre-keys, one-time pre-keys) and delivers it to clients on
 // request so they can establish pairwise X3DH sessions. It never sees plaintext message content. All encryption and
 // decryption is performed client-side.
+//
+// Cross-signing, modelled on Matrix, lets peers detect a device the server injected. Each user may upload an Ed25519
+// master signing key and sign the identity key of every device they recognise. Key bundles carry the master key, each
+// device's signature and the resulting verification status; clients that have confirmed the master key out of band
+// check the signatures themselves. Replacing the master key or a device's identity key discards the affected
+// signatures, and every change is announced to the user's DM peers with DEVICE_VERIFICATION_CHANGED. Replacing the
+// master key also requires the user's password, and their MFA code when MFA is enabled, so a stolen session cannot swap
+// in its own key and sign a device it added.
+//
+// The Maintainer enforces the key lifecycle. Devices whose signed pre-key has outlived the rotation period are sent
+// KEY_ROTATION_REQUIRED until they upload a new one, devices that have not used their keys within the stale timeout are
+// removed and their owner's DM peers sent USER_DEVICES_UPDATE, and message keys for deleted messages or for devices
+// whose owner has left the DM channel are deleted.
+//
+// Attachments to encrypted DMs are encrypted by the client too, with the file key and file details carried in an
+// encrypted metadata blob. The server stores both as uploaded (see attachment.DMAttachment) and serves the file only to
+// participants of the DM channel, through the DM attachment endpoint rather than /media.
 package e2ee
@@ -2,6 +2,7 @@ package e2ee
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"time"
@@ -22,6 +23,10 @@ var (
 	ErrBatchTooLarge        = errors.New("one-time pre-key batch exceeds the maximum size")
 	ErrDuplicateDevice      = errors.New("device already registered for this user")
 	ErrMaxDevicesReached    = errors.New("user has reached the maximum number of devices")
+	ErrMasterKeyNotFound    = errors.New("user has not uploaded a master signing key")
+	ErrSignatureMismatch    = errors.New("signature does not verify against the master signing key")
+	ErrSignatureStale       = errors.New("device identity key or master signing key changed while signing")
+	ErrMasterKeyExists      = errors.New("user already has a different master signing key")
 )
 
 // Key material size constants.
@@ -33,7 +38,27 @@ const (
 	MaxDevicesPerUser = 5   // Default maximum devices per user.
 )
 
-// Device represents a registered device with its long-term X25519 identity key.
+// Verification statuses of a device in a key bundle.
+const (
+	// VerificationVerified means the device's identity key is signed by the user's current master signing key.
+	VerificationVerified = "verified"
+	// VerificationUnverified means the user has a master signing key but has not signed the device with it.
+	VerificationUnverified = "unverified"
+	// VerificationNoMasterKey means the user has not uploaded a master signing key, so none of their devices can be
+	// verified.
+	VerificationNoMasterKey = "no_master_key"
+)
+
+// deviceSignatureContext prefixes every signed device payload so that a device signature cannot be mistaken for a
+// signature over anything else.
+const deviceSignatureContext = "uncord-device-signature-v1"
+
+// DeviceActivityResolution is how stale a device's recorded last activity may become before TouchDevice writes it
+// again. Recording every request would turn each key fetch into a row update.
+const DeviceActivityResolution = time.Hour
+
+// Device represents a registered device with its long-term X25519 identity key. LastSeenAt is when the device last
+// used its keys, recorded to within DeviceActivityResolution.
 type Device struct {
 	ID          uuid.UUID
 	UserID      uuid.UUID
@@ -42,6 +67,15 @@ type Device struct {
 	IdentityKey []byte
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	LastSeenAt  time.Time
+}
+
+// MasterKey is a user's Ed25519 master signing key, with which the user's clients vouch for each of the user's devices.
+type MasterKey struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	PublicKey []byte
+	CreatedAt time.Time
 }
 
 // SignedPreKey holds a medium-term X25519 public key signed by the device's identity key.
@@ -65,16 +99,41 @@ type OneTimePreKey struct {
 }
 
 // DeviceKeyBundle holds the key material for a single device, consumed during X3DH session establishment.
+// DeviceSignature is the master key's signature over the device, nil when the device has not been signed.
 type DeviceKeyBundle struct {
-	Device        Device
-	SignedPreKey  SignedPreKey
-	OneTimePreKey *OneTimePreKey
+	Device          Device
+	SignedPreKey    SignedPreKey
+	OneTimePreKey   *OneTimePreKey
+	DeviceSignature []byte
+}
+
+// Verification returns the device's verification status against the user's master signing key, which may be nil.
+func (b *DeviceKeyBundle) Verification(master *MasterKey) string {
+	switch {
+	case master == nil:
+		return VerificationNoMasterKey
+	case b.DeviceSignature != nil &&
+		VerifyDeviceSignature(master.PublicKey, b.Device.UserID, b.Device.DeviceID, b.Device.IdentityKey,
+			b.DeviceSignature) == nil:
+		return VerificationVerified
+	default:
+		return VerificationUnverified
+	}
 }
 
-// UserKeyBundle holds key bundles for all ready devices of a user.
+// StaleSignedPreKey identifies a device whose active signed pre-key is due for rotation.
+type StaleSignedPreKey struct {
+	UserID    uuid.UUID
+	DeviceID  uuid.UUID
+	KeyID     int
+	CreatedAt time.Time
+}
+
+// UserKeyBundle holds key bundles for all ready devices of a user, and the user's master signing key if they have one.
 type UserKeyBundle struct {
-	UserID  uuid.UUID
-	Devices []DeviceKeyBundle
+	UserID    uuid.UUID
+	MasterKey *MasterKey
+	Devices   []DeviceKeyBundle
 }
 
 // RegisterDeviceParams groups the inputs for registering a new device.
@@ -93,6 +152,15 @@ type UploadSignedPreKeyParams struct {
 	Signature   []byte
 }
 
+// SignDeviceParams groups the inputs for storing a master key signature over a device. IdentityKey and MasterKeyID are
+// the values the signature was verified against; the signature is only stored while both are still current.
+type SignDeviceParams struct {
+	DeviceRowID uuid.UUID
+	MasterKeyID uuid.UUID
+	IdentityKey []byte
+	Signature   []byte
+}
+
 // UploadOPKParams holds a single one-time pre-key for batch upload.
 type UploadOPKParams struct {
 	KeyID     int
@@ -115,6 +183,32 @@ func ValidateSignature(sig []byte) error {
 	return nil
 }
 
+// DeviceSignaturePayload returns the bytes a master signing key signs to vouch for a device: the context string
+// "uncord-device-signature-v1" followed by the 16-byte user ID, the 16-byte client-assigned device ID and the device's
+// 32-byte identity key. Binding the IDs stops a signature from being replayed for another user's or device's key.
+func DeviceSignaturePayload(userID, deviceID uuid.UUID, identityKey []byte) []byte {
+	payload := make([]byte, 0, len(deviceSignatureContext)+2*len(uuid.UUID{})+len(identityKey))
+	payload = append(payload, deviceSignatureContext...)
+	payload = append(payload, userID[:]...)
+	payload = append(payload, deviceID[:]...)
+	return append(payload, identityKey...)
+}
+
+// VerifyDeviceSignature checks that signature is the master key's Ed25519 signature over the device's
+// DeviceSignaturePayload.
+func VerifyDeviceSignature(masterKey []byte, userID, deviceID uuid.UUID, identityKey, signature []byte) error {
+	if err := ValidatePublicKey(masterKey); err != nil {
+		return err
+	}
+	if err := ValidateSignature(signature); err != nil {
+		return err
+	}
+	if !ed25519.Verify(masterKey, DeviceSignaturePayload(userID, deviceID, identityKey), signature) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
 // ValidateOPKBatch checks that the batch is non-empty and does not exceed MaxOPKBatch, and that each key is valid.
 func ValidateOPKBatch(keys []UploadOPKParams, maxBatch int) error {
 	if len(keys) == 0 {
@@ -143,6 +237,17 @@ type Repository interface {
 	RemoveDevice(ctx context.Context, deviceRowID uuid.UUID) error
 	// UpdateIdentityKey replaces a device's identity key and returns the updated device.
 	UpdateIdentityKey(ctx context.Context, deviceRowID uuid.UUID, identityKey []byte) (*Device, error)
+	// SetMasterKey stores the user's master signing key. A different existing key, and with it every device signature,
+	// is only replaced when replace is set; otherwise ErrMasterKeyExists is returned.
+	SetMasterKey(ctx context.Context, userID uuid.UUID, publicKey []byte, replace bool) (key *MasterKey, replaced bool, err error)
+	// GetMasterKey returns the user's master signing key.
+	GetMasterKey(ctx context.Context, userID uuid.UUID) (*MasterKey, error)
+	// SignDevice stores a master key signature over a device, replacing any previous one.
+	SignDevice(ctx context.Context, params SignDeviceParams) error
+	// TouchDevice records that a device is in use.
+	TouchDevice(ctx context.Context, deviceRowID uuid.UUID) error
+	// PruneStaleDevices deletes the devices last seen before the cutoff and returns them.
+	PruneStaleDevices(ctx context.Context, lastSeenBefore time.Time) ([]Device, error)
 
 	// UploadSignedPreKey deactivates the current active signed pre-key and stores a new one.
 	UploadSignedPreKey(ctx context.Context, params UploadSignedPreKeyParams) error
@@ -150,6 +255,8 @@ type Repository interface {
 	UploadOneTimePreKeys(ctx context.Context, deviceRowID uuid.UUID, keys []UploadOPKParams) error
 	// CountOneTimePreKeys returns the number of unused one-time pre-keys for a device.
 	CountOneTimePreKeys(ctx context.Context, deviceRowID uuid.UUID) (int, error)
+	// ListStaleSignedPreKeys returns the devices whose active signed pre-key was uploaded before the cutoff.
+	ListStaleSignedPreKeys(ctx context.Context, createdBefore time.Time) ([]StaleSignedPreKey, error)
 
 	// FetchUserKeyBundle fetches key bundles for all ready devices of a user, atomically consuming one OPK per device.
 	FetchUserKeyBundle(ctx context.Context, targetUserID uuid.UUID) (*UserKeyBundle, error)
@@ -160,6 +267,8 @@ type Repository interface {
 	GetMessageKeyForDevice(ctx context.Context, messageID, deviceRowID uuid.UUID) ([]byte, error)
 	// GetMessageKeysBatch returns encrypted keys for multiple messages for a single device.
 	GetMessageKeysBatch(ctx context.Context, messageIDs []uuid.UUID, deviceRowID uuid.UUID) (map[uuid.UUID][]byte, error)
+	// PurgeOrphanedMessageKeys deletes message keys that no device can use any more and returns how many were deleted.
+	PurgeOrphanedMessageKeys(ctx context.Context) (int64, error)
 }
 
 var _ Repository = (*PGRepository)(nil)
@@ -1,7 +1,11 @@
 package e2ee
 
 import (
+	"crypto/ed25519"
+	"errors"
 	"testing"
+
+	"github.com/google/uuid"
 )
 
 func TestValidatePublicKey(t *testing.T) {
@@ -119,3 +123,78 @@ func TestValidateOPKBatch(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyDeviceSignature(t *testing.T) {
+	masterPub, masterPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate master key: %v", err)
+	}
+	userID, deviceID := uuid.New(), uuid.New()
+	identityKey := make([]byte, KeyLength)
+	identityKey[0] = 1
+	signature := ed25519.Sign(masterPriv, DeviceSignaturePayload(userID, deviceID, identityKey))
+
+	otherIdentityKey := make([]byte, KeyLength)
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate other key: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		masterKey   []byte
+		userID      uuid.UUID
+		deviceID    uuid.UUID
+		identityKey []byte
+		signature   []byte
+		wantErr     error
+	}{
+		{"valid", masterPub, userID, deviceID, identityKey, signature, nil},
+		{"different master key", otherPub, userID, deviceID, identityKey, signature, ErrSignatureMismatch},
+		{"different identity key", masterPub, userID, deviceID, otherIdentityKey, signature, ErrSignatureMismatch},
+		{"different device", masterPub, userID, uuid.New(), identityKey, signature, ErrSignatureMismatch},
+		{"different user", masterPub, uuid.New(), deviceID, identityKey, signature, ErrSignatureMismatch},
+		{"short signature", masterPub, userID, deviceID, identityKey, signature[:32], ErrInvalidSignature},
+		{"short master key", masterPub[:16], userID, deviceID, identityKey, signature, ErrInvalidKeyLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyDeviceSignature(tt.masterKey, tt.userID, tt.deviceID, tt.identityKey, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyDeviceSignature() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeviceKeyBundleVerification(t *testing.T) {
+	masterPub, masterPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate master key: %v", err)
+	}
+	dev := Device{UserID: uuid.New(), DeviceID: uuid.New(), IdentityKey: make([]byte, KeyLength)}
+	master := &MasterKey{UserID: dev.UserID, PublicKey: masterPub}
+	signature := ed25519.Sign(masterPriv, DeviceSignaturePayload(dev.UserID, dev.DeviceID, dev.IdentityKey))
+
+	tests := []struct {
+		name      string
+		master    *MasterKey
+		signature []byte
+		want      string
+	}{
+		{"no master key", nil, signature, VerificationNoMasterKey},
+		{"unsigned", master, nil, VerificationUnverified},
+		{"signed", master, signature, VerificationVerified},
+		{"bad signature", master, make([]byte, SignatureLength), VerificationUnverified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := DeviceKeyBundle{Device: dev, DeviceSignature: tt.signature}
+			if got := b.Verification(tt.master); got != tt.want {
+				t.Errorf("Verification() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package e2ee
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/events"
+
+	"github.com/uncord-chat/uncord-server/internal/gateway"
+)
+
+// Notifier delivers gateway events to specific users. Satisfied by *gateway.Publisher.
+type Notifier interface {
+	EnqueueTargeted(ctx context.Context, eventType events.DispatchEvent, data any, targets []uuid.UUID)
+}
+
+// PeerLister lists the users who share a DM channel with a user. Satisfied by dm.Repository.
+type PeerLister interface {
+	ListDMPeers(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// KeyRotationRequiredData is the payload of a KEY_ROTATION_REQUIRED dispatch.
+type KeyRotationRequiredData struct {
+	DeviceID       string `json:"device_id"`
+	SignedPreKeyID int    `json:"signed_pre_key_id"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// UserDevicesUpdateData is the payload of a USER_DEVICES_UPDATE dispatch. RemovedDeviceIDs are client-assigned device
+// IDs, as in key bundles.
+type UserDevicesUpdateData struct {
+	UserID           string   `json:"user_id"`
+	RemovedDeviceIDs []string `json:"removed_device_ids"`
+}
+
+// Maintainer applies the key lifecycle policy: it prompts devices to rotate signed pre-keys that have reached the
+// rotation period, removes devices that have gone unused for the stale timeout, and deletes message keys nobody can use
+// any more. A zero rotation period or stale timeout disables that part of the policy.
+type Maintainer struct {
+	keys           Repository
+	peers          PeerLister
+	notifier       Notifier
+	rotationPeriod time.Duration
+	staleTimeout   time.Duration
+	log            zerolog.Logger
+}
+
+// NewMaintainer returns a Maintainer. The notifier may be nil, in which case the policy is applied without notifying
+// anyone.
+func NewMaintainer(keys Repository, peers PeerLister, notifier Notifier, rotationPeriod, staleTimeout time.Duration,
+	logger zerolog.Logger) *Maintainer {
+	return &Maintainer{
+		keys:           keys,
+		peers:          peers,
+		notifier:       notifier,
+		rotationPeriod: rotationPeriod,
+		staleTimeout:   staleTimeout,
+		log:            logger,
+	}
+}
+
+// Sweep applies the policy once. Failures are logged rather than returned so that one failing step does not prevent
+// the others; Sweep is designed to be called periodically by the retention cleanup goroutine.
+func (m *Maintainer) Sweep(ctx context.Context) {
+	now := time.Now()
+	if m.staleTimeout > 0 {
+		m.pruneStaleDevices(ctx, now.Add(-m.staleTimeout))
+	}
+	if m.rotationPeriod > 0 {
+		m.requestRotations(ctx, now.Add(-m.rotationPeriod))
+	}
+
+	deleted, err := m.keys.PurgeOrphanedMessageKeys(ctx)
+	if err != nil {
+		m.log.Warn().Err(err).Msg("Failed to purge orphaned message keys")
+	} else if deleted > 0 {
+		m.log.Info().Int64("deleted", deleted).Msg("Purged orphaned message keys")
+	}
+}
+
+// pruneStaleDevices removes the devices last seen before the cutoff and sends each affected user, and their DM peers,
+// a USER_DEVICES_UPDATE listing the user's removed devices.
+func (m *Maintainer) pruneStaleDevices(ctx context.Context, cutoff time.Time) {
+	devices, err := m.keys.PruneStaleDevices(ctx, cutoff)
+	if err != nil {
+		m.log.Warn().Err(err).Msg("Failed to prune stale devices")
+		return
+	}
+	if len(devices) == 0 {
+		return
+	}
+	m.log.Info().Int("deleted", len(devices)).Dur("timeout", m.staleTimeout).Msg("Pruned stale devices")
+	if m.notifier == nil {
+		return
+	}
+
+	var users []uuid.UUID
+	removed := make(map[uuid.UUID][]string)
+	for _, d := range devices {
+		if _, ok := removed[d.UserID]; !ok {
+			users = append(users, d.UserID)
+		}
+		removed[d.UserID] = append(removed[d.UserID], d.DeviceID.String())
+	}
+
+	for _, userID := range users {
+		peers, err := m.peers.ListDMPeers(ctx, userID)
+		if err != nil {
+			m.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to list DM peers for device removal")
+			continue
+		}
+		m.notifier.EnqueueTargeted(ctx, gateway.UserDevicesUpdate, UserDevicesUpdateData{
+			UserID:           userID.String(),
+			RemovedDeviceIDs: removed[userID],
+		}, append(peers, userID))
+	}
+}
+
+// requestRotations sends KEY_ROTATION_REQUIRED to the owner of every device whose active signed pre-key was uploaded
+// before the cutoff. The request repeats on every sweep until the device uploads a new signed pre-key, so a device that
+// was offline when it was first sent still receives it.
+func (m *Maintainer) requestRotations(ctx context.Context, cutoff time.Time) {
+	stale, err := m.keys.ListStaleSignedPreKeys(ctx, cutoff)
+	if err != nil {
+		m.log.Warn().Err(err).Msg("Failed to list signed pre-keys due for rotation")
+		return
+	}
+	if len(stale) == 0 || m.notifier == nil {
+		return
+	}
+
+	for _, k := range stale {
+		m.notifier.EnqueueTargeted(ctx, gateway.KeyRotationRequired, KeyRotationRequiredData{
+			DeviceID:       k.DeviceID.String(),
+			SignedPreKeyID: k.KeyID,
+			CreatedAt:      k.CreatedAt.Format(time.RFC3339),
+		}, []uuid.UUID{k.UserID})
+	}
+	m.log.Info().Int("devices", len(stale)).Dur("period", m.rotationPeriod).Msg("Requested signed pre-key rotation")
+}
@@ -0,0 +1,161 @@
+package e2ee
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/events"
+
+	"github.com/uncord-chat/uncord-server/internal/gateway"
+)
+
+// fakeKeys implements the Repository methods used by Maintainer. Calling any other method panics.
+type fakeKeys struct {
+	Repository
+	stale        []Device
+	staleSPKs    []StaleSignedPreKey
+	orphans      int64
+	pruneErr     error
+	pruneCutoff  time.Time
+	rotateCutoff time.Time
+	purged       bool
+}
+
+func (f *fakeKeys) PruneStaleDevices(_ context.Context, lastSeenBefore time.Time) ([]Device, error) {
+	f.pruneCutoff = lastSeenBefore
+	return f.stale, f.pruneErr
+}
+
+func (f *fakeKeys) ListStaleSignedPreKeys(_ context.Context, createdBefore time.Time) ([]StaleSignedPreKey, error) {
+	f.rotateCutoff = createdBefore
+	return f.staleSPKs, nil
+}
+
+func (f *fakeKeys) PurgeOrphanedMessageKeys(context.Context) (int64, error) {
+	f.purged = true
+	return f.orphans, nil
+}
+
+type fakePeers map[uuid.UUID][]uuid.UUID
+
+func (f fakePeers) ListDMPeers(_ context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return f[userID], nil
+}
+
+type sentEvent struct {
+	eventType events.DispatchEvent
+	data      any
+	targets   []uuid.UUID
+}
+
+type fakeNotifier struct {
+	sent []sentEvent
+}
+
+func (f *fakeNotifier) EnqueueTargeted(_ context.Context, eventType events.DispatchEvent, data any, targets []uuid.UUID) {
+	f.sent = append(f.sent, sentEvent{eventType: eventType, data: data, targets: targets})
+}
+
+func TestMaintainerPrunesStaleDevices(t *testing.T) {
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	phone, laptop := uuid.New(), uuid.New()
+	keys := &fakeKeys{stale: []Device{
+		{UserID: alice, DeviceID: phone},
+		{UserID: alice, DeviceID: laptop},
+	}}
+	notifier := &fakeNotifier{}
+	m := NewMaintainer(keys, fakePeers{alice: {bob, carol}}, notifier, 0, 90*24*time.Hour, zerolog.Nop())
+
+	before := time.Now()
+	m.Sweep(context.Background())
+	after := time.Now()
+
+	const timeout = 90 * 24 * time.Hour
+	if keys.pruneCutoff.Before(before.Add(-timeout)) || keys.pruneCutoff.After(after.Add(-timeout)) {
+		t.Errorf("prune cutoff = %v, want 90 days before the sweep", keys.pruneCutoff)
+	}
+	if !keys.rotateCutoff.IsZero() {
+		t.Error("signed pre-keys were checked with rotation disabled")
+	}
+	if !keys.purged {
+		t.Error("orphaned message keys were not purged")
+	}
+
+	if len(notifier.sent) != 1 {
+		t.Fatalf("sent %d events, want 1 for the single affected user", len(notifier.sent))
+	}
+	ev := notifier.sent[0]
+	if ev.eventType != gateway.UserDevicesUpdate {
+		t.Errorf("event type = %s, want %s", ev.eventType, gateway.UserDevicesUpdate)
+	}
+	data, ok := ev.data.(UserDevicesUpdateData)
+	if !ok {
+		t.Fatalf("event data has type %T, want UserDevicesUpdateData", ev.data)
+	}
+	if data.UserID != alice.String() {
+		t.Errorf("UserID = %s, want %s", data.UserID, alice)
+	}
+	got := append([]string(nil), data.RemovedDeviceIDs...)
+	want := []string{phone.String(), laptop.String()}
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("RemovedDeviceIDs = %v, want %v", got, want)
+	}
+	if len(ev.targets) != 3 {
+		t.Errorf("targets = %v, want the user and both peers", ev.targets)
+	}
+}
+
+func TestMaintainerRequestsRotation(t *testing.T) {
+	userID, deviceID := uuid.New(), uuid.New()
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	keys := &fakeKeys{staleSPKs: []StaleSignedPreKey{
+		{UserID: userID, DeviceID: deviceID, KeyID: 7, CreatedAt: created},
+	}}
+	notifier := &fakeNotifier{}
+	m := NewMaintainer(keys, fakePeers{}, notifier, 30*24*time.Hour, 0, zerolog.Nop())
+
+	m.Sweep(context.Background())
+
+	if !keys.pruneCutoff.IsZero() {
+		t.Error("stale devices were pruned with the stale timeout disabled")
+	}
+	if len(notifier.sent) != 1 {
+		t.Fatalf("sent %d events, want 1", len(notifier.sent))
+	}
+	ev := notifier.sent[0]
+	if ev.eventType != gateway.KeyRotationRequired {
+		t.Errorf("event type = %s, want %s", ev.eventType, gateway.KeyRotationRequired)
+	}
+	want := KeyRotationRequiredData{DeviceID: deviceID.String(), SignedPreKeyID: 7, CreatedAt: "2026-01-02T03:04:05Z"}
+	if ev.data != want {
+		t.Errorf("data = %+v, want %+v", ev.data, want)
+	}
+	if len(ev.targets) != 1 || ev.targets[0] != userID {
+		t.Errorf("targets = %v, want only the device owner", ev.targets)
+	}
+}
+
+func TestMaintainerContinuesAfterPruneFailure(t *testing.T) {
+	keys := &fakeKeys{pruneErr: errors.New("database unavailable")}
+	notifier := &fakeNotifier{}
+	m := NewMaintainer(keys, fakePeers{}, notifier, time.Hour, 24*time.Hour, zerolog.Nop())
+
+	m.Sweep(context.Background())
+
+	if keys.rotateCutoff.IsZero() {
+		t.Error("signed pre-keys were not checked after the prune failed")
+	}
+	if !keys.purged {
+		t.Error("orphaned message keys were not purged after the prune failed")
+	}
+	if len(notifier.sent) != 0 {
+		t.Errorf("sent %d events, want none", len(notifier.sent))
+	}
+}
@@ -1,9 +1,11 @@
 package e2ee
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -41,9 +43,9 @@ func (r *PGRepository) RegisterDevice(ctx context.Context, params RegisterDevice
 		err := tx.QueryRow(ctx,
 			`INSERT INTO user_devices (user_id, device_id, label, identity_key)
 			 VALUES ($1, $2, $3, $4)
-			 RETURNING id, user_id, device_id, label, identity_key, created_at, updated_at`,
+			 RETURNING id, user_id, device_id, label, identity_key, created_at, updated_at, last_seen_at`,
 			params.UserID, params.DeviceID, params.Label, params.IdentityKey,
-		).Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt)
+		).Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt, &d.LastSeenAt)
 		if err != nil {
 			if postgres.IsUniqueViolation(err) {
 				return ErrDuplicateDevice
@@ -61,7 +63,7 @@ func (r *PGRepository) RegisterDevice(ctx context.Context, params RegisterDevice
 // ListDevices returns all registered devices for a user, ordered by creation time.
 func (r *PGRepository) ListDevices(ctx context.Context, userID uuid.UUID) ([]Device, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, user_id, device_id, label, identity_key, created_at, updated_at
+		`SELECT id, user_id, device_id, label, identity_key, created_at, updated_at, last_seen_at
 		 FROM user_devices WHERE user_id = $1 ORDER BY created_at`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("list devices: %w", err)
@@ -71,7 +73,8 @@ func (r *PGRepository) ListDevices(ctx context.Context, userID uuid.UUID) ([]Dev
 	var devices []Device
 	for rows.Next() {
 		var d Device
-		if err := rows.Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		if err := rows.Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt,
+			&d.LastSeenAt); err != nil {
 			return nil, fmt.Errorf("scan device: %w", err)
 		}
 		devices = append(devices, d)
@@ -86,9 +89,9 @@ func (r *PGRepository) ListDevices(ctx context.Context, userID uuid.UUID) ([]Dev
 func (r *PGRepository) GetDeviceByDeviceID(ctx context.Context, userID, deviceID uuid.UUID) (*Device, error) {
 	var d Device
 	err := r.db.QueryRow(ctx,
-		`SELECT id, user_id, device_id, label, identity_key, created_at, updated_at
+		`SELECT id, user_id, device_id, label, identity_key, created_at, updated_at, last_seen_at
 		 FROM user_devices WHERE user_id = $1 AND device_id = $2`, userID, deviceID,
-	).Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt)
+	).Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt, &d.LastSeenAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrDeviceNotFound
@@ -110,21 +113,155 @@ func (r *PGRepository) RemoveDevice(ctx context.Context, deviceRowID uuid.UUID)
 	return nil
 }
 
-// UpdateIdentityKey replaces a device's identity key and returns the updated device.
+// UpdateIdentityKey replaces a device's identity key and returns the updated device. The device's master key signature
+// covered the old identity key, so it is deleted in the same transaction.
 func (r *PGRepository) UpdateIdentityKey(ctx context.Context, deviceRowID uuid.UUID, identityKey []byte) (*Device, error) {
 	var d Device
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			`UPDATE user_devices SET identity_key = $1 WHERE id = $2
+			 RETURNING id, user_id, device_id, label, identity_key, created_at, updated_at, last_seen_at`,
+			identityKey, deviceRowID,
+		).Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt, &d.LastSeenAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrDeviceNotFound
+			}
+			return fmt.Errorf("update identity key: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM e2ee_device_signatures WHERE device_id = $1`, deviceRowID); err != nil {
+			return fmt.Errorf("delete device signature: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// SetMasterKey stores the user's master signing key. Uploading the key the user already has changes nothing and
+// reports replaced as false. A different key replaces the old one only when replace is set, and deleting the old row
+// cascades to every device signature it made. Otherwise, and when a concurrent first upload wins the insert,
+// ErrMasterKeyExists is returned.
+func (r *PGRepository) SetMasterKey(ctx context.Context, userID uuid.UUID, publicKey []byte, replace bool) (*MasterKey, bool, error) {
+	var (
+		k        MasterKey
+		replaced bool
+	)
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			`SELECT id, user_id, public_key, created_at FROM e2ee_master_keys WHERE user_id = $1 FOR UPDATE`, userID,
+		).Scan(&k.ID, &k.UserID, &k.PublicKey, &k.CreatedAt)
+		switch {
+		case err == nil && bytes.Equal(k.PublicKey, publicKey):
+			return nil
+		case err == nil && !replace:
+			return ErrMasterKeyExists
+		case err == nil:
+			if _, err := tx.Exec(ctx, `DELETE FROM e2ee_master_keys WHERE id = $1`, k.ID); err != nil {
+				return fmt.Errorf("delete master key: %w", err)
+			}
+			replaced = true
+		case !errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("get master key: %w", err)
+		}
+
+		err = tx.QueryRow(ctx,
+			`INSERT INTO e2ee_master_keys (user_id, public_key) VALUES ($1, $2)
+			 ON CONFLICT (user_id) DO NOTHING
+			 RETURNING id, user_id, public_key, created_at`, userID, publicKey,
+		).Scan(&k.ID, &k.UserID, &k.PublicKey, &k.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// A concurrent first upload stored its key after the lookup above found none.
+			return ErrMasterKeyExists
+		}
+		if err != nil {
+			return fmt.Errorf("insert master key: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &k, replaced, nil
+}
+
+// GetMasterKey returns the user's master signing key, or ErrMasterKeyNotFound if they have not uploaded one.
+func (r *PGRepository) GetMasterKey(ctx context.Context, userID uuid.UUID) (*MasterKey, error) {
+	var k MasterKey
 	err := r.db.QueryRow(ctx,
-		`UPDATE user_devices SET identity_key = $1 WHERE id = $2
-		 RETURNING id, user_id, device_id, label, identity_key, created_at, updated_at`,
-		identityKey, deviceRowID,
-	).Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt)
+		`SELECT id, user_id, public_key, created_at FROM e2ee_master_keys WHERE user_id = $1`, userID,
+	).Scan(&k.ID, &k.UserID, &k.PublicKey, &k.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrDeviceNotFound
+			return nil, ErrMasterKeyNotFound
 		}
-		return nil, fmt.Errorf("update identity key: %w", err)
+		return nil, fmt.Errorf("get master key: %w", err)
 	}
-	return &d, nil
+	return &k, nil
+}
+
+// SignDevice stores a master key signature over a device, replacing any previous one. The signature is only stored
+// while the master key and the device's identity key are still those it was verified against; otherwise SignDevice
+// returns ErrSignatureStale.
+func (r *PGRepository) SignDevice(ctx context.Context, params SignDeviceParams) error {
+	tag, err := r.db.Exec(ctx,
+		`INSERT INTO e2ee_device_signatures (device_id, master_key_id, signature)
+		 SELECT d.id, m.id, $4
+		 FROM user_devices d
+		 JOIN e2ee_master_keys m ON m.user_id = d.user_id
+		 WHERE d.id = $1 AND m.id = $2 AND d.identity_key = $3
+		 ON CONFLICT (device_id) DO UPDATE
+		     SET master_key_id = EXCLUDED.master_key_id, signature = EXCLUDED.signature, created_at = NOW()`,
+		params.DeviceRowID, params.MasterKeyID, params.IdentityKey, params.Signature)
+	if err != nil {
+		return fmt.Errorf("sign device: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSignatureStale
+	}
+	return nil
+}
+
+// TouchDevice sets a device's last activity to now, unless the recorded activity is less than DeviceActivityResolution
+// old.
+func (r *PGRepository) TouchDevice(ctx context.Context, deviceRowID uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE user_devices SET last_seen_at = NOW()
+		 WHERE id = $1 AND last_seen_at < NOW() - make_interval(secs => $2)`,
+		deviceRowID, DeviceActivityResolution.Seconds())
+	if err != nil {
+		return fmt.Errorf("touch device: %w", err)
+	}
+	return nil
+}
+
+// PruneStaleDevices deletes every device last seen before the cutoff, cascading to its keys, and returns the deleted
+// devices so that their owners' peers can be told.
+func (r *PGRepository) PruneStaleDevices(ctx context.Context, lastSeenBefore time.Time) ([]Device, error) {
+	rows, err := r.db.Query(ctx,
+		`DELETE FROM user_devices WHERE last_seen_at < $1
+		 RETURNING id, user_id, device_id, label, identity_key, created_at, updated_at, last_seen_at`, lastSeenBefore)
+	if err != nil {
+		return nil, fmt.Errorf("prune stale devices: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []Device
+	for rows.Next() {
+		var d Device
+		if err := rows.Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt, &d.UpdatedAt,
+			&d.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("scan pruned device: %w", err)
+		}
+		devices = append(devices, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("prune stale devices: %w", err)
+	}
+	return devices, nil
 }
 
 // UploadSignedPreKey deactivates the current active signed pre-key for the device and stores a new one as active.
@@ -184,6 +321,33 @@ func (r *PGRepository) CountOneTimePreKeys(ctx context.Context, deviceRowID uuid
 	return count, nil
 }
 
+// ListStaleSignedPreKeys returns every device whose active signed pre-key was uploaded before the cutoff, oldest first.
+func (r *PGRepository) ListStaleSignedPreKeys(ctx context.Context, createdBefore time.Time) ([]StaleSignedPreKey, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT d.user_id, d.device_id, s.key_id, s.created_at
+		 FROM e2ee_signed_pre_keys s
+		 JOIN user_devices d ON d.id = s.device_id
+		 WHERE s.active = true AND s.created_at < $1
+		 ORDER BY s.created_at`, createdBefore)
+	if err != nil {
+		return nil, fmt.Errorf("list stale signed pre-keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []StaleSignedPreKey
+	for rows.Next() {
+		var k StaleSignedPreKey
+		if err := rows.Scan(&k.UserID, &k.DeviceID, &k.KeyID, &k.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan stale signed pre-key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list stale signed pre-keys: %w", err)
+	}
+	return keys, nil
+}
+
 // FetchUserKeyBundle fetches key bundles for all ready devices of a user. The entire operation runs inside a single
 // transaction to provide a consistent snapshot and serialise OPK consumption. For each device that has an active signed
 // pre-key, it atomically consumes one OPK using DELETE ... LIMIT 1 with FOR UPDATE SKIP LOCKED. Devices without an
@@ -192,21 +356,31 @@ func (r *PGRepository) FetchUserKeyBundle(ctx context.Context, targetUserID uuid
 	var bundle *UserKeyBundle
 	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx,
-			`SELECT id, user_id, device_id, label, identity_key, created_at, updated_at
-			 FROM user_devices WHERE user_id = $1 ORDER BY created_at`, targetUserID)
+			`SELECT d.id, d.user_id, d.device_id, d.label, d.identity_key, d.created_at, d.updated_at, d.last_seen_at,
+			        s.signature
+			 FROM user_devices d
+			 LEFT JOIN e2ee_device_signatures s ON s.device_id = d.id
+			 WHERE d.user_id = $1 ORDER BY d.created_at`, targetUserID)
 		if err != nil {
 			return fmt.Errorf("list devices: %w", err)
 		}
 		defer rows.Close()
 
-		var devices []Device
+		var (
+			devices    []Device
+			signatures [][]byte
+		)
 		for rows.Next() {
-			var d Device
+			var (
+				d   Device
+				sig []byte
+			)
 			if err := rows.Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Label, &d.IdentityKey, &d.CreatedAt,
-				&d.UpdatedAt); err != nil {
+				&d.UpdatedAt, &d.LastSeenAt, &sig); err != nil {
 				return fmt.Errorf("scan device: %w", err)
 			}
 			devices = append(devices, d)
+			signatures = append(signatures, sig)
 		}
 		if err := rows.Err(); err != nil {
 			return fmt.Errorf("iterate devices: %w", err)
@@ -215,8 +389,19 @@ func (r *PGRepository) FetchUserKeyBundle(ctx context.Context, targetUserID uuid
 			return ErrNoDevices
 		}
 
+		var master *MasterKey
+		var k MasterKey
+		err = tx.QueryRow(ctx,
+			`SELECT id, user_id, public_key, created_at FROM e2ee_master_keys WHERE user_id = $1`, targetUserID,
+		).Scan(&k.ID, &k.UserID, &k.PublicKey, &k.CreatedAt)
+		if err == nil {
+			master = &k
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("get master key: %w", err)
+		}
+
 		var bundles []DeviceKeyBundle
-		for _, dev := range devices {
+		for i, dev := range devices {
 			var spk SignedPreKey
 			err := tx.QueryRow(ctx,
 				`SELECT id, device_id, key_id, public_key, signature, active, created_at
@@ -230,8 +415,9 @@ func (r *PGRepository) FetchUserKeyBundle(ctx context.Context, targetUserID uuid
 			}
 
 			b := DeviceKeyBundle{
-				Device:       dev,
-				SignedPreKey: spk,
+				Device:          dev,
+				SignedPreKey:    spk,
+				DeviceSignature: signatures[i],
 			}
 
 			var opk OneTimePreKey
@@ -257,8 +443,9 @@ func (r *PGRepository) FetchUserKeyBundle(ctx context.Context, targetUserID uuid
 		}
 
 		bundle = &UserKeyBundle{
-			UserID:  targetUserID,
-			Devices: bundles,
+			UserID:    targetUserID,
+			MasterKey: master,
+			Devices:   bundles,
 		}
 		return nil
 	})
@@ -335,3 +522,20 @@ func (r *PGRepository) GetMessageKeysBatch(ctx context.Context, messageIDs []uui
 	}
 	return result, nil
 }
+
+// PurgeOrphanedMessageKeys deletes message keys that can no longer be used: those of deleted messages, and those held by
+// devices whose owner has since left the message's DM channel. Keys of deleted devices and messages are already removed
+// by cascade.
+func (r *PGRepository) PurgeOrphanedMessageKeys(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM dm_message_keys k
+		 USING messages m, user_devices d
+		 WHERE k.message_id = m.id AND k.device_id = d.id
+		   AND (m.deleted_at IS NOT NULL
+		        OR NOT EXISTS (SELECT 1 FROM dm_participants p
+		                       WHERE p.dm_channel_id = m.channel_id AND p.user_id = d.user_id))`)
+	if err != nil {
+		return 0, fmt.Errorf("purge orphaned message keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -42,7 +42,7 @@ func NewClient(host string, port int, username, password, from string, verificat
 }
 
 // Ping verifies that the SMTP server is reachable and accepts authentication (if credentials are configured). It is
-// intended for startup health checks and logs a warning on failure rather than preventing startup.
+// used for the startup check, which logs a warning on failure rather than preventing startup, and for readiness checks.
 func (c *Client) Ping(ctx context.Context) error {
 	client, err := c.dial(ctx)
 	if err != nil {
@@ -96,6 +96,39 @@ func (r *PGRepository) List(ctx context.Context) ([]Emoji, error) {
 	return result, nil
 }
 
+// Search returns up to limit custom emoji whose name contains query or closely resembles it. Exact matches rank first,
+// then prefix matches, then everything else; within each group the emoji most recently used as a reaction come first.
+func (r *PGRepository) Search(ctx context.Context, query string, limit int) ([]Emoji, error) {
+	escaped := postgres.EscapeLike(query)
+	rows, err := r.db.Query(ctx,
+		`SELECT `+selectColumns+` FROM custom_emoji e
+		 WHERE e.name ILIKE $1 OR $2 <% e.name
+		 ORDER BY CASE WHEN lower(e.name) = lower($2) THEN 0 WHEN e.name ILIKE $3 THEN 1 ELSE 2 END,
+		          (SELECT max(r.created_at) FROM reactions r WHERE r.emoji_id = e.id) DESC NULLS LAST,
+		          word_similarity($2, e.name) DESC,
+		          e.name
+		 LIMIT $4`,
+		"%"+escaped+"%", query, escaped+"%", limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search emoji: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Emoji
+	for rows.Next() {
+		e, err := scanEmoji(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate emoji: %w", err)
+	}
+	return result, nil
+}
+
 // UpdateName changes the name of an existing emoji. Returns ErrNotFound if the ID does not exist, or ErrNameTaken if
 // the new name conflicts with another emoji.
 func (r *PGRepository) UpdateName(ctx context.Context, id uuid.UUID, name string) (*Emoji, error) {
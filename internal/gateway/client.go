@@ -24,13 +24,26 @@ const (
 	writeWait = 10 * time.Second
 )
 
+// outbound is a serialised frame waiting in a client's send channel. compress records whether stream compression had
+// been negotiated when the frame was queued, so frames queued before Identify are never compressed even if the write
+// pump reaches them afterwards.
+type outbound struct {
+	data     []byte
+	compress bool
+}
+
 // Client represents a single WebSocket connection. Each client runs two goroutines (readPump and writePump) and
 // communicates with the Hub via its send channel and callback methods.
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
-	log  zerolog.Logger
+	hub      *Hub
+	conn     *websocket.Conn
+	send     chan outbound
+	encoding Encoding
+	log      zerolog.Logger
+
+	// compressor is set at most once, when Identify or Resume negotiates stream compression, and is used only by
+	// writePump.
+	compressor atomic.Pointer[streamCompressor]
 
 	// done is closed to signal that the client is shutting down. The send channel is never closed directly; writePump
 	// and enqueue both select on done to detect termination, avoiding send-on-closed-channel panics that would
@@ -39,24 +52,33 @@ type Client struct {
 	closeOnce sync.Once
 
 	// Session state, protected by mu. Fields are written during Identify/Resume and read by the Hub during dispatch.
-	mu         sync.RWMutex
-	userID     uuid.UUID
-	sessionID  string
-	seq        atomic.Int64
-	identified bool
-
-	// Rate limiting state (only accessed from readPump, no mutex needed).
-	eventCount  int
-	windowStart time.Time
+	// unsubscribed also changes when the client sends OpcodeChannelSubscriptions.
+	mu           sync.RWMutex
+	userID       uuid.UUID
+	sessionID    string
+	seq          atomic.Int64
+	identified   bool
+	intents      Intent
+	unsubscribed map[uuid.UUID]struct{}
+	// stream maps the client's sequence numbers to event stream positions. It is nil without stream delivery.
+	stream *StreamCursor
+
+	// Rate limiting state (only accessed from readPump, no mutex needed). Member requests have their own, tighter
+	// budget because each one queries the database.
+	eventCount        int
+	windowStart       time.Time
+	memberReqCount    int
+	memberReqWinStart time.Time
 }
 
-func newClient(hub *Hub, conn *websocket.Conn, logger zerolog.Logger) *Client {
+func newClient(hub *Hub, conn *websocket.Conn, enc Encoding, logger zerolog.Logger) *Client {
 	return &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 256),
-		done: make(chan struct{}),
-		log:  logger,
+		hub:      hub,
+		conn:     conn,
+		send:     make(chan outbound, 256),
+		encoding: enc,
+		done:     make(chan struct{}),
+		log:      logger,
 	}
 }
 
@@ -88,6 +110,51 @@ func (c *Client) IsIdentified() bool {
 	return c.identified
 }
 
+// subscriptions returns a copy of the client's event filter for saving with its session.
+func (c *Client) subscriptions() Subscriptions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	subs := Subscriptions{Intents: c.intents, Unsubscribed: make([]uuid.UUID, 0, len(c.unsubscribed))}
+	for id := range c.unsubscribed {
+		subs.Unsubscribed = append(subs.Unsubscribed, id)
+	}
+	return subs
+}
+
+// hasIntent reports whether the client declared intent.
+func (c *Client) hasIntent(intent Intent) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.intents&intent != 0
+}
+
+// setSubscriptionsLocked replaces the client's event filter. The caller must hold c.mu for writing.
+func (c *Client) setSubscriptionsLocked(subs Subscriptions) {
+	c.intents = subs.Intents
+	c.unsubscribed = make(map[uuid.UUID]struct{}, len(subs.Unsubscribed))
+	for _, id := range subs.Unsubscribed {
+		c.unsubscribed[id] = struct{}{}
+	}
+}
+
+// wants reports whether the client's intents and channel subscriptions admit an event. channelID is uuid.Nil for
+// events that are not channel-scoped, and subject is the user the event is about, if any.
+func (c *Client) wants(eventType events.DispatchEvent, channelID, subject uuid.UUID) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if need := eventIntent(eventType); need != 0 && c.intents&need == 0 {
+		if need != IntentMembers || subject != c.userID {
+			return false
+		}
+	}
+	if channelID != uuid.Nil {
+		if _, ok := c.unsubscribed[channelID]; ok {
+			return false
+		}
+	}
+	return true
+}
+
 // nextSeq increments and returns the next sequence number for a dispatch event.
 func (c *Client) nextSeq() int64 {
 	return c.seq.Add(1)
@@ -98,6 +165,50 @@ func (c *Client) currentSeq() int64 {
 	return c.seq.Load()
 }
 
+// markStream records that the dispatch numbered seq came from the event stream entry id. At least the most recent
+// GatewayReplayBufferSize marks are kept; once twice that many accumulate, the older half is folded into the cursor's
+// base, so the copy is paid once per buffer's worth of events rather than on every event.
+func (c *Client) markStream(seq int64, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stream == nil {
+		return
+	}
+	c.stream.Marks = append(c.stream.Marks, StreamMark{Seq: seq, ID: id})
+	keep := c.hub.cfg.GatewayReplayBufferSize
+	if over := len(c.stream.Marks) - keep; over > keep {
+		c.stream.Base = c.stream.Marks[over-1]
+		c.stream.Marks = append(c.stream.Marks[:0], c.stream.Marks[over:]...)
+	}
+}
+
+// streamSeen reports whether the client's stream cursor is already at or past the event stream entry id. A client
+// resumed from a session saved on another node may be ahead of this node's Hub, which must not send it the entries in
+// between a second time.
+func (c *Client) streamSeen(id string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.stream == nil {
+		return false
+	}
+	last := c.stream.Base.ID
+	if n := len(c.stream.Marks); n > 0 {
+		last = c.stream.Marks[n-1].ID
+	}
+	return !streamIDLess(last, id)
+}
+
+// streamCursor returns a copy of the client's stream cursor for saving with its session, or nil without stream
+// delivery.
+func (c *Client) streamCursor() *StreamCursor {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.stream == nil {
+		return nil
+	}
+	return &StreamCursor{Base: c.stream.Base, Marks: append([]StreamMark(nil), c.stream.Marks...)}
+}
+
 // readPump reads messages from the WebSocket connection and routes them by opcode. It runs in its own goroutine and
 // is responsible for closing the connection when the read loop exits.
 func (c *Client) readPump() {
@@ -135,9 +246,9 @@ func (c *Client) readPump() {
 			return
 		}
 
-		var frame events.Frame
-		if err := json.Unmarshal(message, &frame); err != nil {
-			c.closeWithCode(CloseDecodeError, "invalid JSON")
+		frame, err := c.encoding.decode(message)
+		if err != nil {
+			c.closeWithCode(CloseDecodeError, "invalid "+string(c.encoding))
 			return
 		}
 
@@ -152,6 +263,12 @@ func (c *Client) readPump() {
 		case events.OpcodeResume:
 			identifyTimer.Stop()
 			c.handleResume(frame.Data)
+		case OpcodeChannelSubscriptions:
+			c.handleChannelSubscriptions(frame.Data)
+		case OpcodeRequestMembers:
+			if !c.handleRequestMembers(frame.Data) {
+				return
+			}
 		case events.OpcodeDispatch, events.OpcodeReconnect, events.OpcodeInvalidSession,
 			events.OpcodeHello, events.OpcodeHeartbeatACK:
 			// Server-to-client opcodes are never valid in a client message.
@@ -164,13 +281,17 @@ func (c *Client) readPump() {
 // writePump writes messages from the send channel to the WebSocket connection. It runs in its own goroutine and exits
 // when done is closed. Any messages remaining in the send buffer are drained before returning.
 func (c *Client) writePump() {
-	defer func() { _ = c.conn.Close() }()
+	defer func() {
+		_ = c.conn.Close()
+		if z := c.compressor.Load(); z != nil {
+			_ = z.Close()
+		}
+	}()
 
 	for {
 		select {
 		case msg := <-c.send:
-			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			if err := c.write(msg); err != nil {
 				c.log.Debug().Err(err).Msg("WebSocket write error")
 				return
 			}
@@ -179,8 +300,7 @@ func (c *Client) writePump() {
 			for {
 				select {
 				case msg := <-c.send:
-					_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-					if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					if err := c.write(msg); err != nil {
 						return
 					}
 				default:
@@ -191,12 +311,50 @@ func (c *Client) writePump() {
 	}
 }
 
+// write sends one queued frame, compressing it first when it was queued after stream compression was negotiated.
+// Compressed frames are always binary messages and skip permessage-deflate, which would only spend CPU recompressing
+// them.
+func (c *Client) write(msg outbound) error {
+	data, messageType := msg.data, c.encoding.messageType()
+	if z := c.compressor.Load(); msg.compress && z != nil {
+		var err error
+		if data, err = z.compress(data); err != nil {
+			return err
+		}
+		messageType = websocket.BinaryMessage
+		c.conn.EnableWriteCompression(false)
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// negotiateCompression enables stream compression for every frame queued from now on. It must be called from readPump
+// before the Identify or Resume response is queued.
+func (c *Client) negotiateCompression(name string) bool {
+	scheme, err := ParseCompression(name)
+	if err != nil {
+		c.closeWithCode(CloseDecodeError, "unsupported compression")
+		return false
+	}
+	if scheme == CompressionNone {
+		return true
+	}
+	z, err := newStreamCompressor(scheme)
+	if err != nil {
+		c.log.Error().Err(err).Msg("Failed to create stream compressor")
+		c.closeWithCode(CloseUnknownError, "internal error")
+		return false
+	}
+	c.compressor.Store(z)
+	return true
+}
+
 // handleHeartbeat responds with a HeartbeatACK and resets the read deadline. For identified clients, the heartbeat
 // also refreshes the presence TTL so the key does not expire while the connection is alive.
 func (c *Client) handleHeartbeat(heartbeatInterval time.Duration) {
 	_ = c.conn.SetReadDeadline(time.Now().Add(heartbeatInterval + heartbeatInterval/2))
 
-	ack, err := NewHeartbeatACKFrame()
+	ack, err := NewHeartbeatACKFrame(c.encoding)
 	if err != nil {
 		c.log.Error().Err(err).Msg("Failed to build heartbeat ACK")
 		return
@@ -210,6 +368,27 @@ func (c *Client) handleHeartbeat(heartbeatInterval time.Duration) {
 	}
 }
 
+// identifyPayload is the op 2 Identify payload together with the optional transport compression and intents the
+// client requests. Omitting intents selects AllIntents.
+type identifyPayload struct {
+	models.IdentifyData
+	Compress string  `json:"compress,omitempty"`
+	Intents  *Intent `json:"intents,omitempty"`
+}
+
+// presenceUpdatePayload is the op 3 PresenceUpdate payload together with the optional custom status and activities.
+type presenceUpdatePayload struct {
+	models.PresenceUpdateRequest
+	presence.Rich
+}
+
+// resumePayload is the op 6 Resume payload together with the optional transport compression the client requests.
+// Compression state does not survive a reconnect, so a resuming client negotiates it afresh.
+type resumePayload struct {
+	models.ResumeData
+	Compress string `json:"compress,omitempty"`
+}
+
 // handleIdentify processes an op 2 Identify payload.
 func (c *Client) handleIdentify(data json.RawMessage) {
 	if c.IsIdentified() {
@@ -217,7 +396,7 @@ func (c *Client) handleIdentify(data json.RawMessage) {
 		return
 	}
 
-	var id models.IdentifyData
+	var id identifyPayload
 	if err := json.Unmarshal(data, &id); err != nil {
 		c.closeWithCode(CloseDecodeError, "invalid identify payload")
 		return
@@ -227,8 +406,19 @@ func (c *Client) handleIdentify(data json.RawMessage) {
 		c.closeWithCode(CloseAuthFailed, "token required")
 		return
 	}
+	intents := AllIntents
+	if id.Intents != nil {
+		if err := validIntents(*id.Intents); err != nil {
+			c.closeWithCode(CloseInvalidIntents, "invalid intents")
+			return
+		}
+		intents = *id.Intents
+	}
+	if !c.negotiateCompression(id.Compress) {
+		return
+	}
 
-	c.hub.handleIdentify(c, id.Token)
+	c.hub.handleIdentify(c, id.Token, intents)
 }
 
 // handleResume processes an op 6 Resume payload.
@@ -238,7 +428,7 @@ func (c *Client) handleResume(data json.RawMessage) {
 		return
 	}
 
-	var r models.ResumeData
+	var r resumePayload
 	if err := json.Unmarshal(data, &r); err != nil {
 		c.closeWithCode(CloseDecodeError, "invalid resume payload")
 		return
@@ -248,18 +438,96 @@ func (c *Client) handleResume(data json.RawMessage) {
 		c.closeWithCode(CloseAuthFailed, "token and session_id required")
 		return
 	}
+	if !c.negotiateCompression(r.Compress) {
+		return
+	}
+
+	c.hub.handleResume(c, r.ResumeData)
+}
+
+// handleChannelSubscriptions processes an OpcodeChannelSubscriptions payload. Unsubscribing is applied after
+// subscribing, so a channel listed in both ends up unsubscribed.
+func (c *Client) handleChannelSubscriptions(data json.RawMessage) {
+	if !c.IsIdentified() {
+		c.closeWithCode(CloseNotAuthenticated, "not identified")
+		return
+	}
+
+	var req channelSubscriptionData
+	if err := json.Unmarshal(data, &req); err != nil {
+		c.closeWithCode(CloseDecodeError, "invalid channel subscriptions payload")
+		return
+	}
+	subscribe, err := parseChannelIDs(req.Subscribe)
+	if err != nil {
+		c.closeWithCode(CloseDecodeError, "invalid channel ID")
+		return
+	}
+	unsubscribe, err := parseChannelIDs(req.Unsubscribe)
+	if err != nil {
+		c.closeWithCode(CloseDecodeError, "invalid channel ID")
+		return
+	}
+
+	c.mu.Lock()
+	for _, id := range subscribe {
+		delete(c.unsubscribed, id)
+	}
+	for _, id := range unsubscribe {
+		c.unsubscribed[id] = struct{}{}
+	}
+	tooMany := len(c.unsubscribed) > maxUnsubscribedChannels
+	c.mu.Unlock()
+
+	if tooMany {
+		c.closeWithCode(CloseDecodeError, "too many unsubscribed channels")
+	}
+}
+
+// parseChannelIDs parses a list of channel IDs, failing on the first invalid one.
+func parseChannelIDs(ids []string) ([]uuid.UUID, error) {
+	parsed := make([]uuid.UUID, len(ids))
+	for i, id := range ids {
+		var err error
+		if parsed[i], err = uuid.Parse(id); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// handleRequestMembers processes an OpcodeRequestMembers payload. It returns false if the connection was closed, either
+// because the payload is invalid or because the client exceeded its member request budget.
+func (c *Client) handleRequestMembers(data json.RawMessage) bool {
+	if !c.IsIdentified() {
+		c.closeWithCode(CloseNotAuthenticated, "not identified")
+		return false
+	}
+	if c.memberRequestsLimited() {
+		c.closeWithCode(CloseRateLimited, "member request rate limit exceeded")
+		return false
+	}
+
+	req, err := parseMemberRequest(data)
+	if err != nil {
+		c.log.Debug().Err(err).Msg("Invalid request members payload")
+		c.closeWithCode(CloseDecodeError, "invalid request members payload")
+		return false
+	}
 
-	c.hub.handleResume(c, r)
+	c.hub.handleRequestMembers(c, req)
+	return true
 }
 
-// handlePresenceUpdate processes an op 3 PresenceUpdate payload.
+// handlePresenceUpdate processes an op 3 PresenceUpdate payload. Each update replaces the user's rich presence, so
+// omitting the custom status or activities clears them.
 func (c *Client) handlePresenceUpdate(data json.RawMessage) {
 	if !c.IsIdentified() {
 		c.closeWithCode(CloseNotAuthenticated, "not identified")
 		return
 	}
 
-	var req models.PresenceUpdateRequest
+	var req presenceUpdatePayload
 	if err := json.Unmarshal(data, &req); err != nil {
 		c.closeWithCode(CloseDecodeError, "invalid presence payload")
 		return
@@ -270,7 +538,13 @@ func (c *Client) handlePresenceUpdate(data json.RawMessage) {
 		return
 	}
 
-	c.hub.handlePresenceUpdate(c, req.Status)
+	if err := req.Rich.Validate(time.Now()); err != nil {
+		c.log.Debug().Err(err).Msg("Invalid rich presence")
+		c.closeWithCode(CloseDecodeError, "invalid rich presence")
+		return
+	}
+
+	c.hub.handlePresenceUpdate(c, req.Status, req.Rich)
 }
 
 // enqueue sends a message to the client's write channel. If the client has already been shut down the message is
@@ -284,7 +558,7 @@ func (c *Client) enqueue(msg []byte) {
 	}
 
 	select {
-	case c.send <- msg:
+	case c.send <- outbound{data: msg, compress: c.compressor.Load() != nil}:
 	case <-c.done:
 	default:
 		c.log.Warn().Msg("Client send buffer full, closing connection")
@@ -300,6 +574,18 @@ func (c *Client) closeWithCode(code int, reason string) {
 	_ = c.conn.Close()
 }
 
+// memberRequestsLimited returns true if the client has exceeded the configured member request rate limit.
+func (c *Client) memberRequestsLimited() bool {
+	now := time.Now()
+	window := time.Duration(c.hub.cfg.RateLimitMemberReqWindowSeconds) * time.Second
+	if now.Sub(c.memberReqWinStart) > window {
+		c.memberReqCount = 0
+		c.memberReqWinStart = now
+	}
+	c.memberReqCount++
+	return c.memberReqCount > c.hub.cfg.RateLimitMemberReqCount
+}
+
 // rateLimited returns true if the client has exceeded the configured message rate limit.
 func (c *Client) rateLimited() bool {
 	now := time.Now()
@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -12,14 +13,17 @@ import (
 	"github.com/fasthttp/websocket"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
+	"github.com/shamaton/msgpack/v3"
+	"github.com/uncord-chat/uncord-protocol/events"
+	"github.com/uncord-chat/uncord-protocol/models"
 
 	"github.com/uncord-chat/uncord-server/internal/config"
 )
 
-// TestIdentifyTimeout verifies that a client that connects but never sends an Identify or Resume frame is
-// disconnected with CloseNotAuthenticated after the configured identify timeout expires.
-func TestIdentifyTimeout(t *testing.T) {
-	t.Parallel()
+// dialTestGateway starts a hub behind a test WebSocket server and dials it with the given encoding. The hub disconnects
+// clients that have not identified within identifyTimeout.
+func dialTestGateway(t *testing.T, enc Encoding, identifyTimeout time.Duration) *websocket.Conn {
+	t.Helper()
 
 	mr := miniredis.RunT(t)
 	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
@@ -27,7 +31,7 @@ func TestIdentifyTimeout(t *testing.T) {
 
 	cfg := &config.Config{
 		GatewayHeartbeatIntervalMS: 20000,
-		GatewayIdentifyTimeout:     200 * time.Millisecond,
+		GatewayIdentifyTimeout:     identifyTimeout,
 		GatewaySessionTTL:          5 * time.Minute,
 		GatewayReplayBufferSize:    10,
 		GatewayMaxConnections:      10,
@@ -49,7 +53,7 @@ func TestIdentifyTimeout(t *testing.T) {
 			t.Errorf("upgrade: %v", err)
 			return
 		}
-		hub.ServeWebSocket(conn)
+		hub.ServeWebSocket(conn, enc)
 	}))
 	t.Cleanup(srv.Close)
 
@@ -58,10 +62,19 @@ func TestIdentifyTimeout(t *testing.T) {
 	if err != nil {
 		t.Fatalf("dial: %v", err)
 	}
-	defer func() { _ = conn.Close() }()
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// TestIdentifyTimeout verifies that a client that connects but never sends an Identify or Resume frame is
+// disconnected with CloseNotAuthenticated after the configured identify timeout expires.
+func TestIdentifyTimeout(t *testing.T) {
+	t.Parallel()
+
+	conn := dialTestGateway(t, EncodingJSON, 200*time.Millisecond)
 
 	// Read the Hello frame that the server sends immediately after upgrade.
-	_, _, err = conn.ReadMessage()
+	_, _, err := conn.ReadMessage()
 	if err != nil {
 		t.Fatalf("reading Hello frame: %v", err)
 	}
@@ -83,3 +96,76 @@ func TestIdentifyTimeout(t *testing.T) {
 		t.Errorf("close code = %d, want %d (CloseNotAuthenticated)", closeErr.Code, CloseNotAuthenticated)
 	}
 }
+
+// TestMsgpackConnection verifies that a MessagePack connection receives binary MessagePack frames and that its own
+// MessagePack frames are understood.
+func TestMsgpackConnection(t *testing.T) {
+	t.Parallel()
+
+	conn := dialTestGateway(t, EncodingMsgpack, 5*time.Second)
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("set read deadline: %v", err)
+	}
+
+	messageType, raw, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading Hello frame: %v", err)
+	}
+	if messageType != websocket.BinaryMessage {
+		t.Errorf("Hello message type = %d, want BinaryMessage", messageType)
+	}
+	hello, err := EncodingMsgpack.decode(raw)
+	if err != nil {
+		t.Fatalf("decode Hello frame: %v", err)
+	}
+	var data models.HelloData
+	if err := json.Unmarshal(hello.Data, &data); err != nil {
+		t.Fatalf("unmarshal hello data: %v", err)
+	}
+	if hello.Op != events.OpcodeHello || data.HeartbeatInterval != 20000 {
+		t.Errorf("Hello = op %d interval %d, want op %d interval 20000", hello.Op, data.HeartbeatInterval,
+			events.OpcodeHello)
+	}
+
+	heartbeat, err := msgpack.Marshal(map[string]any{"op": int(events.OpcodeHeartbeat)})
+	if err != nil {
+		t.Fatalf("msgpack.Marshal() error: %v", err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, heartbeat); err != nil {
+		t.Fatalf("send heartbeat: %v", err)
+	}
+	messageType, raw, err = conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading heartbeat ACK: %v", err)
+	}
+	ack, err := EncodingMsgpack.decode(raw)
+	if err != nil {
+		t.Fatalf("decode heartbeat ACK: %v", err)
+	}
+	if messageType != websocket.BinaryMessage || ack.Op != events.OpcodeHeartbeatACK {
+		t.Errorf("reply = type %d op %d, want a binary HeartbeatACK", messageType, ack.Op)
+	}
+}
+
+// TestMsgpackConnectionRejectsJSON verifies that a MessagePack connection closes with CloseDecodeError when the client
+// sends JSON.
+func TestMsgpackConnectionRejectsJSON(t *testing.T) {
+	t.Parallel()
+
+	conn := dialTestGateway(t, EncodingMsgpack, 5*time.Second)
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("set read deadline: %v", err)
+	}
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("reading Hello frame: %v", err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"op":1}`)); err != nil {
+		t.Fatalf("send heartbeat: %v", err)
+	}
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseDecodeError {
+		t.Errorf("read error = %v, want close code %d (CloseDecodeError)", err, CloseDecodeError)
+	}
+}
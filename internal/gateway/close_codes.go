@@ -14,6 +14,7 @@ const (
 	CloseInvalidSequence      = 4007
 	CloseRateLimited          = 4008
 	CloseSessionTimedOut      = 4009
+	CloseInvalidIntents       = 4013
 )
 
 // Sentinel errors for gateway failure modes. Each maps to a close code above.
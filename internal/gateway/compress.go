@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is a transport compression scheme negotiated in the Identify or Resume payload. Stream schemes compress
+// every frame sent after the negotiation with one context shared across the connection, so repeated keys and IDs in
+// later frames compress against earlier ones. Each frame is flushed to a byte boundary and sent as its own binary
+// message; a client feeds the messages in order into a single decompressor.
+type Compression string
+
+const (
+	// CompressionNone sends frames uncompressed, leaving WebSocket permessage-deflate as the only compression.
+	CompressionNone Compression = ""
+	// CompressionZlibStream compresses frames with one zlib stream. Every message ends with the sync flush marker
+	// 00 00 ff ff.
+	CompressionZlibStream Compression = "zlib-stream"
+	// CompressionZstdStream compresses frames with one zstd stream, flushing a complete block after every frame.
+	CompressionZstdStream Compression = "zstd-stream"
+)
+
+// zstdWindowSize bounds the memory each zstd-stream connection holds on both ends.
+const zstdWindowSize = 1 << 20
+
+// ErrUnsupportedCompression is returned by ParseCompression for a scheme the gateway does not implement.
+var ErrUnsupportedCompression = errors.New("unsupported gateway compression")
+
+// ParseCompression returns the Compression named by s. An empty string selects CompressionNone.
+func ParseCompression(s string) (Compression, error) {
+	switch Compression(s) {
+	case CompressionNone, CompressionZlibStream, CompressionZstdStream:
+		return Compression(s), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedCompression, s)
+	}
+}
+
+// flushWriter is a compressing writer that can emit everything written so far without ending the stream. Satisfied by
+// *zlib.Writer and *zstd.Encoder.
+type flushWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// streamCompressor compresses consecutive frames of one connection with a shared context. It is not safe for
+// concurrent use; the client's write pump is its only caller.
+type streamCompressor struct {
+	buf bytes.Buffer
+	w   flushWriter
+}
+
+// newStreamCompressor returns a compressor for c, which must not be CompressionNone.
+func newStreamCompressor(c Compression) (*streamCompressor, error) {
+	s := &streamCompressor{}
+	switch c {
+	case CompressionZlibStream:
+		s.w = zlib.NewWriter(&s.buf)
+	case CompressionZstdStream:
+		enc, err := zstd.NewWriter(&s.buf, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(zstdWindowSize),
+			zstd.WithLowerEncoderMem(true))
+		if err != nil {
+			return nil, fmt.Errorf("create zstd encoder: %w", err)
+		}
+		s.w = enc
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCompression, c)
+	}
+	return s, nil
+}
+
+// compress appends frame to the stream and returns the compressed bytes that carry it. The returned slice is only
+// valid until the next call.
+func (s *streamCompressor) compress(frame []byte) ([]byte, error) {
+	s.buf.Reset()
+	if _, err := s.w.Write(frame); err != nil {
+		return nil, fmt.Errorf("compress frame: %w", err)
+	}
+	if err := s.w.Flush(); err != nil {
+		return nil, fmt.Errorf("flush frame: %w", err)
+	}
+	return s.buf.Bytes(), nil
+}
+
+// Close releases the compressor's resources.
+func (s *streamCompressor) Close() error {
+	return s.w.Close()
+}
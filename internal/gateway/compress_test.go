@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestParseCompression(t *testing.T) {
+	t.Parallel()
+
+	for _, in := range []string{"", "zlib-stream", "zstd-stream"} {
+		if got, err := ParseCompression(in); err != nil || string(got) != in {
+			t.Errorf("ParseCompression(%q) = %q, %v; want %q", in, got, err, in)
+		}
+	}
+	for _, in := range []string{"zlib", "gzip", "ZSTD-STREAM"} {
+		if _, err := ParseCompression(in); !errors.Is(err, ErrUnsupportedCompression) {
+			t.Errorf("ParseCompression(%q) error = %v, want ErrUnsupportedCompression", in, err)
+		}
+	}
+}
+
+// streamFrames returns frames that share most of their content, as consecutive dispatches do.
+func streamFrames() [][]byte {
+	frames := make([][]byte, 5)
+	for i := range frames {
+		frames[i] = fmt.Appendf(nil, `{"op":0,"s":%d,"t":"MESSAGE_CREATE","d":{"channel_id":"0195d1c4-7a3b-7c1e-9f00-`+
+			`5f2a1b3c4d5e","content":"message number %d"}}`, i+1, i)
+	}
+	return frames
+}
+
+// decodeStream feeds compressed messages one at a time into a single decompressor opened by open, and checks that
+// each message alone yields exactly its frame. A decompressor that needs bytes from a later message fails the test
+// instead of hanging.
+func decodeStream(t *testing.T, open func(io.Reader) (io.Reader, error), messages, frames [][]byte) {
+	t.Helper()
+	pr, pw := io.Pipe()
+	t.Cleanup(func() { _ = pw.Close() })
+
+	opened := make(chan struct {
+		r   io.Reader
+		err error
+	}, 1)
+	go func() {
+		r, err := open(pr)
+		opened <- struct {
+			r   io.Reader
+			err error
+		}{r, err}
+	}()
+
+	var r io.Reader
+	for i, msg := range messages {
+		go func() { _, _ = pw.Write(msg) }()
+		if r == nil {
+			o := <-opened
+			if o.err != nil {
+				t.Fatalf("open decompressor: %v", o.err)
+			}
+			r = o.r
+		}
+
+		got := make([]byte, len(frames[i]))
+		done := make(chan error, 1)
+		go func() {
+			_, err := io.ReadFull(r, got)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("frame %d: read: %v", i, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("frame %d could not be decoded from the messages received so far", i)
+		}
+		if !bytes.Equal(got, frames[i]) {
+			t.Fatalf("frame %d = %s, want %s", i, got, frames[i])
+		}
+	}
+}
+
+// compressAll runs frames through a new compressor for c and returns a copy of each message it produces.
+func compressAll(t *testing.T, c Compression, frames [][]byte) [][]byte {
+	t.Helper()
+	z, err := newStreamCompressor(c)
+	if err != nil {
+		t.Fatalf("newStreamCompressor(%q) error: %v", c, err)
+	}
+	t.Cleanup(func() { _ = z.Close() })
+
+	messages := make([][]byte, len(frames))
+	for i, f := range frames {
+		out, err := z.compress(f)
+		if err != nil {
+			t.Fatalf("compress() error: %v", err)
+		}
+		messages[i] = bytes.Clone(out)
+	}
+	return messages
+}
+
+func TestZlibStreamRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	frames := streamFrames()
+	messages := compressAll(t, CompressionZlibStream, frames)
+	for i, msg := range messages {
+		if !bytes.HasSuffix(msg, []byte{0x00, 0x00, 0xff, 0xff}) {
+			t.Errorf("message %d does not end with the zlib sync flush marker", i)
+		}
+	}
+	decodeStream(t, func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }, messages, frames)
+}
+
+func TestZstdStreamRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	frames := streamFrames()
+	messages := compressAll(t, CompressionZstdStream, frames)
+	decodeStream(t, func(r io.Reader) (io.Reader, error) {
+		return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	}, messages, frames)
+}
+
+// TestStreamCompressionSharesContext verifies that later frames compress against earlier ones, which is the point of
+// a stream scheme over compressing each frame independently.
+func TestStreamCompressionSharesContext(t *testing.T) {
+	t.Parallel()
+
+	frames := streamFrames()
+	for _, c := range []Compression{CompressionZlibStream, CompressionZstdStream} {
+		messages := compressAll(t, c, frames)
+		first, last := len(messages[0]), len(messages[len(messages)-1])
+		if last >= first {
+			t.Errorf("%s: last message is %d bytes, want fewer than the first (%d)", c, last, first)
+		}
+	}
+}
+
+func TestNewStreamCompressorRejectsNone(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newStreamCompressor(CompressionNone); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Errorf("newStreamCompressor(none) error = %v, want ErrUnsupportedCompression", err)
+	}
+}
@@ -3,9 +3,49 @@
 // have multiple concurrent connections (one per browser tab). The package also integrates presence tracking and typing
 // indicators.
 //
+// Frames are JSON text messages unless the client connects with encoding=msgpack, which switches both directions to
+// MessagePack binary messages with the same field names. A client may also request transport compression in its
+// Identify or Resume payload ("zlib-stream" or "zstd-stream"); every frame queued afterwards is compressed with one
+// context shared across the connection and sent as a binary message. The replay buffer always stores JSON, so a session
+// may be resumed with a different encoding or compression than it was identified with. Clients that negotiate neither
+// still benefit from WebSocket permessage-deflate when they offer it during the upgrade.
+//
+// Identify may also declare intents, which select the families of server events (messages, reactions, typing, presence,
+// members) the client wants, and a client may unsubscribe from individual channels with OpcodeChannelSubscriptions.
+// Both filters are applied while the Hub collects a dispatch's recipients, before any permission is resolved, and are
+// saved with the session so that a resumed connection keeps them.
+//
+// READY carries at most GATEWAY_READY_MEMBER_LIMIT members. Clients fetch the rest with OpcodeRequestMembers, by name
+// prefix or by user ID, and receive the matches in MEMBERS_CHUNK dispatches sent only to the requesting connection.
+// Member requests have their own per-connection rate limit because each one queries the database.
+//
+// A PresenceUpdate may carry a custom status and activities alongside the status. They are stored with the status,
+// replaced by each update, and included in PRESENCE_UPDATE, READY and MEMBERS_CHUNK presences. A custom status with an
+// expiry is cleared by the Hub that accepted it once the expiry passes; until then every Hub hides it from lookups.
+//
+// Events are routed through per-channel and per-user pub/sub topics rather than one shared channel, so that a node only
+// receives the events its own clients may be sent. Each Hub subscribes to the topics of its connected users and of the
+// channels they can view, adding and dropping topics as users connect and disconnect, and recomputes the channel topics
+// when permission cache invalidations or channel changes arrive. Events that are not about a channel or addressed to
+// particular users still go to the shared events channel. With GATEWAY_SHARDED_PUBSUB the topics use sharded pub/sub,
+// which a Valkey cluster keeps on the shard that owns each topic.
+//
+// With GATEWAY_DELIVERY=stream, events are instead appended to one capped Valkey stream that every Hub reads from the
+// last entry it dispatched, so events published while a Hub's connection to Valkey is down are delivered late rather
+// than lost. Each connection records which stream entries produced its sequence numbers, and a resuming session reads
+// the events it missed back from the stream instead of from a per-session replay buffer. A session can be resumed as
+// long as the stream still holds its position, whichever node it last connected to.
+//
+// Before a deploy an operator can drain a node by sending the process SIGUSR1, or with POST /gateway/drain on the
+// node's token-protected admin listener (ADMIN_ADDR), and cancel the drain with SIGUSR2 or DELETE. Drain is not exposed
+// on the public API, which reaches an arbitrary node through the load balancer. A draining Hub refuses new connections
+// and reports itself unready, saves every session, then sends Reconnect to its clients in batches of
+// GATEWAY_DRAIN_BATCH_SIZE, GATEWAY_DRAIN_BATCH_INTERVAL apart, so that they resume on other nodes without the burst of
+// reconnects a shutdown causes.
+//
 // The Publisher uses a bounded in-memory queue to decouple HTTP handlers from Valkey pub/sub latency. When the queue is
 // full, new events are silently dropped (with a warning log) rather than applying back-pressure to callers. This is an
 // intentional trade-off: in a chat system, momentary event loss under extreme load is preferable to blocking request
-// handlers. Operators should monitor the "Gateway publish queue full" warning and tune the queue size and worker count
-// if drops become frequent.
+// handlers. Stream delivery makes the opposite choice and publishes on the caller's goroutine instead. Operators should
+// monitor the "Gateway publish queue full" warning and tune the queue size and worker count if drops become frequent.
 package gateway
@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// StartDrain puts the Hub into drain mode and migrates its clients to other nodes in the background. It reports false
+// if the Hub was already draining. The node is expected to be shut down once the drain finishes; StopDrain cancels a
+// drain that is no longer wanted.
+func (h *Hub) StartDrain() bool {
+	if !h.draining.CompareAndSwap(false, true) {
+		return false
+	}
+	go h.drain(h.drainGen.Add(1))
+	return true
+}
+
+// StopDrain takes the Hub out of drain mode, so that it accepts connections again and migrates no more clients. Clients
+// already migrated stay on the nodes they reconnected to. It reports false if the Hub was not draining.
+func (h *Hub) StopDrain() bool {
+	if !h.draining.CompareAndSwap(true, false) {
+		return false
+	}
+	h.drainGen.Add(1)
+	h.log.Info().Msg("Gateway drain cancelled")
+	return true
+}
+
+// Draining reports whether the Hub is draining. A draining Hub refuses new connections.
+func (h *Hub) Draining() bool {
+	return h.draining.Load()
+}
+
+// drain persists the session of every connected client, then sends Reconnect to the clients in batches of
+// GatewayDrainBatchSize, GatewayDrainBatchInterval apart, so that they resume on other nodes gradually rather than all
+// at once. Clients that finish identifying while the drain runs are migrated after the rest. Presence is left as it is,
+// since the users are expected to be back within moments on another node. The drain stops early once StopDrain is
+// called, which it detects by gen no longer being the current drain.
+func (h *Hub) drain(gen uint64) {
+	started := time.Now()
+	clients := h.registered()
+	h.log.Info().Int("clients", len(clients)).Msg("Gateway drain started")
+
+	// Saving every session first means none is lost if the process stops before its batch is reached. Each client's
+	// session is saved again as it is migrated, with the events it was sent in the meantime.
+	for _, c := range clients {
+		h.saveSession(c)
+	}
+
+	migrated := 0
+	for len(clients) > 0 {
+		if !h.draining.Load() || h.drainGen.Load() != gen {
+			h.log.Info().Int("migrated", migrated).Dur("duration", time.Since(started)).Msg("Gateway drain stopped")
+			return
+		}
+		batch := clients[:min(h.cfg.GatewayDrainBatchSize, len(clients))]
+		clients = clients[len(batch):]
+		for _, c := range batch {
+			if h.migrate(c) {
+				migrated++
+			}
+		}
+		if len(clients) == 0 {
+			clients = h.registered()
+		}
+		if len(clients) > 0 {
+			time.Sleep(h.cfg.GatewayDrainBatchInterval)
+		}
+	}
+
+	h.log.Info().Int("migrated", migrated).Dur("duration", time.Since(started)).Msg("Gateway drain complete")
+}
+
+// registered returns every client registered with the Hub.
+func (h *Hub) registered() []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0, h.totalClientsLocked())
+	for _, cs := range h.clients {
+		clients = append(clients, cs...)
+	}
+	return clients
+}
+
+// migrate detaches client from the Hub, saves its session with its final sequence number, and asks it to reconnect so
+// that it resumes on another node. It reports false if the client had already disconnected.
+func (h *Hub) migrate(client *Client) bool {
+	// With stream delivery, detaching under streamMu ensures no event is still being dispatched to the client when its
+	// stream cursor is saved.
+	if h.stream {
+		h.streamMu.Lock()
+	}
+	_, ok := h.detach(client)
+	if h.stream {
+		h.streamMu.Unlock()
+	}
+	if !ok {
+		return false
+	}
+
+	h.saveSession(client)
+	if frame, err := NewReconnectFrame(client.encoding); err == nil {
+		client.enqueue(frame)
+	}
+	// writePump sends the queued frames, Reconnect last, before it closes the connection.
+	client.closeSend()
+	return true
+}
+
+// refuseDraining closes a connection that arrived while the Hub is draining, asking the client to try again later so
+// that it reconnects to another node.
+func refuseDraining(conn *websocket.Conn) {
+	msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "server draining")
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+	_ = conn.Close()
+}
@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/events"
+)
+
+func TestDrainMigratesClientsInBatches(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	cfg := testConfig()
+	cfg.GatewayDrainBatchSize = 2
+	cfg.GatewayDrainBatchInterval = 20 * time.Millisecond
+	sessions := NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize)
+	hub := NewHub(HubDeps{RDB: rdb, Cfg: cfg, Sessions: sessions, Logger: zerolog.Nop()})
+
+	clients := make([]*Client, 5)
+	for i := range clients {
+		clients[i] = identifiedClient(hub, AllIntents)
+		clients[i].seq.Store(int64(i + 1))
+		if err := hub.register(clients[i]); err != nil {
+			t.Fatalf("register() error = %v", err)
+		}
+	}
+
+	if !hub.StartDrain() {
+		t.Fatal("StartDrain() = false on the first call, want true")
+	}
+	if hub.StartDrain() {
+		t.Error("StartDrain() = true while already draining, want false")
+	}
+	if !hub.Draining() {
+		t.Error("Draining() = false after StartDrain, want true")
+	}
+
+	// Five clients in batches of two take three batches, with a pause after each of the first two.
+	started := time.Now()
+	waitFor(t, "clients to be migrated", func() bool { return hub.ClientCount() == 0 })
+	if elapsed := time.Since(started); elapsed < 2*cfg.GatewayDrainBatchInterval {
+		t.Errorf("drain took %v, want at least %v", elapsed, 2*cfg.GatewayDrainBatchInterval)
+	}
+
+	for i, c := range clients {
+		select {
+		case <-c.done:
+		default:
+			t.Errorf("client %d is still open after the drain", i)
+		}
+		select {
+		case msg := <-c.send:
+			var f events.Frame
+			if err := json.Unmarshal(msg.data, &f); err != nil {
+				t.Fatalf("unmarshal frame: %v", err)
+			}
+			if f.Op != events.OpcodeReconnect {
+				t.Errorf("client %d frame opcode = %d, want Reconnect", i, f.Op)
+			}
+		default:
+			t.Errorf("client %d was not sent Reconnect", i)
+		}
+
+		session, err := sessions.Load(ctx, c.SessionID())
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if session.UserID != c.UserID() || session.LastSeq != int64(i+1) {
+			t.Errorf("client %d session = user %s, seq %d; want %s, %d",
+				i, session.UserID, session.LastSeq, c.UserID(), i+1)
+		}
+	}
+}
+
+func TestDrainingHubRefusesConnections(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	hub := NewHub(HubDeps{RDB: rdb, Cfg: testConfig(), Logger: zerolog.Nop()})
+	hub.StartDrain()
+
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		hub.ServeWebSocket(conn, EncodingJSON)
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseTryAgainLater {
+		t.Errorf("ReadMessage() error = %v, want close code %d before Hello", err, websocket.CloseTryAgainLater)
+	}
+}
+
+func TestStopDrainCancelsMigration(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+
+	cfg := testConfig()
+	cfg.GatewayDrainBatchSize = 1
+	cfg.GatewayDrainBatchInterval = 50 * time.Millisecond
+	sessions := NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize)
+	hub := NewHub(HubDeps{RDB: rdb, Cfg: cfg, Sessions: sessions, Logger: zerolog.Nop()})
+
+	for range 3 {
+		if err := hub.register(identifiedClient(hub, AllIntents)); err != nil {
+			t.Fatalf("register() error = %v", err)
+		}
+	}
+
+	if hub.StopDrain() {
+		t.Error("StopDrain() = true while not draining, want false")
+	}
+	hub.StartDrain()
+	waitFor(t, "the first batch to be migrated", func() bool { return hub.ClientCount() == 2 })
+	if !hub.StopDrain() {
+		t.Fatal("StopDrain() = false while draining, want true")
+	}
+	if hub.Draining() {
+		t.Error("Draining() = true after StopDrain, want false")
+	}
+
+	// The cancelled drain wakes after its interval and stops without migrating the next batch.
+	time.Sleep(3 * cfg.GatewayDrainBatchInterval)
+	if n := hub.ClientCount(); n != 2 {
+		t.Errorf("ClientCount() = %d after the drain was cancelled, want 2", n)
+	}
+
+	if !hub.StartDrain() {
+		t.Fatal("StartDrain() = false after StopDrain, want true")
+	}
+	waitFor(t, "the remaining clients to be migrated", func() bool { return hub.ClientCount() == 0 })
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fasthttp/websocket"
+	"github.com/shamaton/msgpack/v3"
+	"github.com/uncord-chat/uncord-protocol/events"
+)
+
+// Encoding is the wire format of gateway frames. A client chooses it with the encoding query parameter when it
+// connects, and it applies to every frame sent in either direction for the life of the connection.
+type Encoding string
+
+const (
+	// EncodingJSON sends frames as JSON text messages. It is the default.
+	EncodingJSON Encoding = "json"
+	// EncodingMsgpack sends frames as MessagePack binary messages. Frames keep the JSON field names (op, s, t, d) and
+	// payloads keep their JSON shape, so a client decodes both encodings into the same structures.
+	EncodingMsgpack Encoding = "msgpack"
+)
+
+// ErrUnsupportedEncoding is returned by ParseEncoding for an encoding the gateway does not implement.
+var ErrUnsupportedEncoding = errors.New("unsupported gateway encoding")
+
+// ParseEncoding returns the Encoding named by s. An empty string selects EncodingJSON.
+func ParseEncoding(s string) (Encoding, error) {
+	switch Encoding(s) {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingMsgpack:
+		return EncodingMsgpack, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedEncoding, s)
+	}
+}
+
+// messageType returns the WebSocket message type that carries uncompressed frames in this encoding.
+func (e Encoding) messageType() int {
+	if e == EncodingMsgpack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// encode serialises f in this encoding.
+func (e Encoding) encode(f events.Frame) ([]byte, error) {
+	if e != EncodingMsgpack {
+		return json.Marshal(f)
+	}
+
+	m := map[string]any{"op": int(f.Op)}
+	if f.Seq != nil {
+		m["s"] = *f.Seq
+	}
+	if f.Type != nil {
+		m["t"] = string(*f.Type)
+	}
+	if len(f.Data) > 0 {
+		d, err := jsonToValue(f.Data)
+		if err != nil {
+			return nil, fmt.Errorf("convert frame data: %w", err)
+		}
+		m["d"] = d
+	}
+	return msgpack.Marshal(m)
+}
+
+// decode parses a frame received in this encoding. MessagePack frames are converted so that the returned frame's Data
+// holds JSON, letting opcode handlers decode payloads the same way for both encodings.
+func (e Encoding) decode(msg []byte) (events.Frame, error) {
+	var f events.Frame
+	if e != EncodingMsgpack {
+		err := json.Unmarshal(msg, &f)
+		return f, err
+	}
+
+	var v any
+	if err := msgpack.Unmarshal(msg, &v); err != nil {
+		return f, err
+	}
+	v, err := normaliseMsgpack(v)
+	if err != nil {
+		return f, err
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return f, err
+	}
+	err = json.Unmarshal(raw, &f)
+	return f, err
+}
+
+// fromJSON re-encodes a serialised JSON frame in this encoding. The replay buffer stores JSON frames, so a session
+// resumed over a MessagePack connection converts each one as it is replayed.
+func (e Encoding) fromJSON(frame []byte) ([]byte, error) {
+	if e != EncodingMsgpack {
+		return frame, nil
+	}
+	var f events.Frame
+	if err := json.Unmarshal(frame, &f); err != nil {
+		return nil, fmt.Errorf("decode stored frame: %w", err)
+	}
+	return e.encode(f)
+}
+
+// jsonToValue decodes JSON into plain Go values for MessagePack encoding. Numbers without a fraction or exponent become
+// integers rather than floats, so that IDs, counts, and bit sets keep their exact values.
+func jsonToValue(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertNumbers(v), nil
+}
+
+func convertNumbers(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		for k, e := range t {
+			t[k] = convertNumbers(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = convertNumbers(e)
+		}
+	}
+	return v
+}
+
+// normaliseMsgpack converts the maps produced by the MessagePack decoder, which are keyed by interface values, into
+// string-keyed maps that encoding/json can marshal. A map with a non-string key cannot be expressed as JSON and is
+// rejected.
+func normaliseMsgpack(v any) (any, error) {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key %v is not a string", k)
+			}
+			n, err := normaliseMsgpack(e)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = n
+		}
+		return m, nil
+	case []any:
+		for i, e := range t {
+			n, err := normaliseMsgpack(e)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = n
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fasthttp/websocket"
+	"github.com/shamaton/msgpack/v3"
+	"github.com/uncord-chat/uncord-protocol/events"
+)
+
+func TestParseEncoding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    Encoding
+		wantErr bool
+	}{
+		{"", EncodingJSON, false},
+		{"json", EncodingJSON, false},
+		{"msgpack", EncodingMsgpack, false},
+		{"etf", "", true},
+		{"JSON", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEncoding(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrUnsupportedEncoding) {
+				t.Errorf("ParseEncoding(%q) error = %v, want ErrUnsupportedEncoding", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseEncoding(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestEncodingMessageType(t *testing.T) {
+	t.Parallel()
+
+	if got := EncodingJSON.messageType(); got != websocket.TextMessage {
+		t.Errorf("EncodingJSON.messageType() = %d, want TextMessage", got)
+	}
+	if got := EncodingMsgpack.messageType(); got != websocket.BinaryMessage {
+		t.Errorf("EncodingMsgpack.messageType() = %d, want BinaryMessage", got)
+	}
+}
+
+// frameBuilders returns one frame of every kind, built in enc.
+func frameBuilders(t *testing.T, enc Encoding) map[string][]byte {
+	t.Helper()
+	payload := json.RawMessage(`{"channel_id":"c1","content":"hello","nonce":null,"flags":4,"ratio":0.5,` +
+		`"mentions":["u1","u2"],"embed":{"title":"t","fields":[]}}`)
+	build := map[string]func() ([]byte, error){
+		"hello":           func() ([]byte, error) { return NewHelloFrame(enc, 45000) },
+		"heartbeat ack":   func() ([]byte, error) { return NewHeartbeatACKFrame(enc) },
+		"dispatch":        func() ([]byte, error) { return NewDispatchFrame(enc, 42, events.MessageCreate, payload) },
+		"ephemeral":       func() ([]byte, error) { return NewEphemeralDispatchFrame(enc, events.TypingStart, payload) },
+		"reconnect":       func() ([]byte, error) { return NewReconnectFrame(enc) },
+		"invalid session": func() ([]byte, error) { return NewInvalidSessionFrame(enc, true) },
+	}
+	frames := make(map[string][]byte, len(build))
+	for name, fn := range build {
+		raw, err := fn()
+		if err != nil {
+			t.Fatalf("build %s frame in %s: %v", name, enc, err)
+		}
+		frames[name] = raw
+	}
+	return frames
+}
+
+// decodeJSONValue decodes JSON into a generic value so that payloads can be compared regardless of key order.
+func decodeJSONValue(t *testing.T, data []byte) any {
+	t.Helper()
+	if len(data) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+	return v
+}
+
+// TestEncodingRoundTrip verifies that every frame decodes to the same envelope and payload in both encodings.
+func TestEncodingRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	jsonFrames := frameBuilders(t, EncodingJSON)
+	msgpackFrames := frameBuilders(t, EncodingMsgpack)
+
+	for name, raw := range jsonFrames {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			want, err := EncodingJSON.decode(raw)
+			if err != nil {
+				t.Fatalf("EncodingJSON.decode() error: %v", err)
+			}
+			got, err := EncodingMsgpack.decode(msgpackFrames[name])
+			if err != nil {
+				t.Fatalf("EncodingMsgpack.decode() error: %v", err)
+			}
+
+			if got.Op != want.Op {
+				t.Errorf("Op = %d, want %d", got.Op, want.Op)
+			}
+			if !reflect.DeepEqual(got.Seq, want.Seq) {
+				t.Errorf("Seq = %v, want %v", got.Seq, want.Seq)
+			}
+			if !reflect.DeepEqual(got.Type, want.Type) {
+				t.Errorf("Type = %v, want %v", got.Type, want.Type)
+			}
+			if g, w := decodeJSONValue(t, got.Data), decodeJSONValue(t, want.Data); !reflect.DeepEqual(g, w) {
+				t.Errorf("Data = %s, want %s", got.Data, want.Data)
+			}
+		})
+	}
+}
+
+// TestMsgpackKeepsIntegers verifies that whole numbers are encoded as MessagePack integers, not floats, so that clients
+// decoding into integer fields receive exact values.
+func TestMsgpackKeepsIntegers(t *testing.T) {
+	t.Parallel()
+
+	raw, err := NewDispatchFrame(EncodingMsgpack, 7, events.MessageCreate,
+		json.RawMessage(`{"permissions":9007199254740993,"ratio":1.5}`))
+	if err != nil {
+		t.Fatalf("NewDispatchFrame() error: %v", err)
+	}
+
+	var frame struct {
+		Op   int    `msgpack:"op"`
+		Seq  int64  `msgpack:"s"`
+		Type string `msgpack:"t"`
+		Data struct {
+			Permissions int64   `msgpack:"permissions"`
+			Ratio       float64 `msgpack:"ratio"`
+		} `msgpack:"d"`
+	}
+	if err := msgpack.Unmarshal(raw, &frame); err != nil {
+		t.Fatalf("msgpack.Unmarshal() error: %v", err)
+	}
+	if frame.Op != int(events.OpcodeDispatch) || frame.Seq != 7 || frame.Type != string(events.MessageCreate) {
+		t.Errorf("envelope = %d/%d/%q, want 0/7/%q", frame.Op, frame.Seq, frame.Type, events.MessageCreate)
+	}
+	if frame.Data.Permissions != 9007199254740993 {
+		t.Errorf("permissions = %d, want 9007199254740993", frame.Data.Permissions)
+	}
+	if frame.Data.Ratio != 1.5 {
+		t.Errorf("ratio = %v, want 1.5", frame.Data.Ratio)
+	}
+}
+
+func TestMsgpackDecodeClientFrame(t *testing.T) {
+	t.Parallel()
+
+	raw, err := msgpack.Marshal(map[string]any{
+		"op": int(events.OpcodeIdentify),
+		"d":  map[string]any{"token": "abc", "compress": "zstd-stream"},
+	})
+	if err != nil {
+		t.Fatalf("msgpack.Marshal() error: %v", err)
+	}
+
+	f, err := EncodingMsgpack.decode(raw)
+	if err != nil {
+		t.Fatalf("decode() error: %v", err)
+	}
+	if f.Op != events.OpcodeIdentify {
+		t.Errorf("Op = %d, want %d", f.Op, events.OpcodeIdentify)
+	}
+	var id identifyPayload
+	if err := json.Unmarshal(f.Data, &id); err != nil {
+		t.Fatalf("unmarshal identify payload: %v", err)
+	}
+	if id.Token != "abc" || id.Compress != string(CompressionZstdStream) {
+		t.Errorf("identify = %+v, want token abc with zstd-stream", id)
+	}
+}
+
+func TestMsgpackDecodeRejects(t *testing.T) {
+	t.Parallel()
+
+	nonStringKey, err := msgpack.Marshal(map[int]any{1: "x"})
+	if err != nil {
+		t.Fatalf("msgpack.Marshal() error: %v", err)
+	}
+	for name, raw := range map[string][]byte{
+		"non-string key": nonStringKey,
+		"truncated":      {0x81, 0xa2, 'o'},
+		"json":           []byte(`{"op":1}`),
+	} {
+		if _, err := EncodingMsgpack.decode(raw); err == nil {
+			t.Errorf("decode(%s) succeeded, want an error", name)
+		}
+	}
+}
+
+// TestFromJSON verifies that replayed JSON frames are converted to the connection's encoding.
+func TestFromJSON(t *testing.T) {
+	t.Parallel()
+
+	stored, err := NewDispatchFrame(EncodingJSON, 3, events.MessageDelete, json.RawMessage(`{"id":"m1"}`))
+	if err != nil {
+		t.Fatalf("NewDispatchFrame() error: %v", err)
+	}
+
+	same, err := EncodingJSON.fromJSON(stored)
+	if err != nil || string(same) != string(stored) {
+		t.Errorf("EncodingJSON.fromJSON() = %s, %v; want the stored frame unchanged", same, err)
+	}
+
+	converted, err := EncodingMsgpack.fromJSON(stored)
+	if err != nil {
+		t.Fatalf("EncodingMsgpack.fromJSON() error: %v", err)
+	}
+	f, err := EncodingMsgpack.decode(converted)
+	if err != nil {
+		t.Fatalf("decode() error: %v", err)
+	}
+	if f.Seq == nil || *f.Seq != 3 || f.Type == nil || *f.Type != events.MessageDelete {
+		t.Errorf("envelope = %v/%v, want 3/%q", f.Seq, f.Type, events.MessageDelete)
+	}
+	if string(f.Data) != `{"id":"m1"}` {
+		t.Errorf("Data = %s, want {\"id\":\"m1\"}", f.Data)
+	}
+}
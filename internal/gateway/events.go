@@ -0,0 +1,31 @@
+package gateway
+
+import "github.com/uncord-chat/uncord-protocol/events"
+
+// Dispatch events emitted by this server that the protocol module does not define yet. Clients that do not recognise an
+// event type ignore it, so these can ship ahead of the protocol.
+const (
+	// AttachmentQuarantined notifies moderators that an uploaded file was rejected by the malware scanner. It is
+	// published with the ManageMessages permission so only moderators of the channel receive it.
+	AttachmentQuarantined events.DispatchEvent = "ATTACHMENT_QUARANTINED"
+	// MembersChunk answers an OpcodeRequestMembers frame. It is sent only to the requesting connection.
+	MembersChunk events.DispatchEvent = "MEMBERS_CHUNK"
+	// KeyRotationRequired asks a user's device to upload a new signed pre-key because its active one has reached the
+	// rotation period. It is sent to the device's owner.
+	KeyRotationRequired events.DispatchEvent = "KEY_ROTATION_REQUIRED"
+	// UserDevicesUpdate tells a user's DM peers, and the user, that devices were removed from the user's account, so
+	// that clients stop encrypting message keys for them.
+	UserDevicesUpdate events.DispatchEvent = "USER_DEVICES_UPDATE"
+	// DeviceVerificationChanged tells a user's DM peers, and the user, that one of the user's devices became verified
+	// or unverified against their master signing key.
+	DeviceVerificationChanged events.DispatchEvent = "DEVICE_VERIFICATION_CHANGED"
+)
+
+// Opcodes handled by this server that the protocol module does not define yet.
+const (
+	// OpcodeRequestMembers requests members by name prefix or user ID, answered with MembersChunk dispatches
+	// (client → server).
+	OpcodeRequestMembers events.Opcode = 8
+	// OpcodeChannelSubscriptions subscribes to or unsubscribes from the events of individual channels (client → server).
+	OpcodeChannelSubscriptions events.Opcode = 12
+)
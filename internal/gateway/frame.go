@@ -9,26 +9,26 @@ import (
 )
 
 // NewHelloFrame returns a serialised Hello frame with the given heartbeat interval in milliseconds.
-func NewHelloFrame(heartbeatIntervalMS int) ([]byte, error) {
+func NewHelloFrame(enc Encoding, heartbeatIntervalMS int) ([]byte, error) {
 	data, err := json.Marshal(models.HelloData{HeartbeatInterval: heartbeatIntervalMS})
 	if err != nil {
 		return nil, fmt.Errorf("marshal hello data: %w", err)
 	}
-	return json.Marshal(events.Frame{
+	return enc.encode(events.Frame{
 		Op:   events.OpcodeHello,
 		Data: data,
 	})
 }
 
 // NewHeartbeatACKFrame returns a serialised HeartbeatACK frame.
-func NewHeartbeatACKFrame() ([]byte, error) {
-	return json.Marshal(events.Frame{Op: events.OpcodeHeartbeatACK})
+func NewHeartbeatACKFrame(enc Encoding) ([]byte, error) {
+	return enc.encode(events.Frame{Op: events.OpcodeHeartbeatACK})
 }
 
 // NewDispatchFrame returns a serialised Dispatch frame with the given sequence number, event type, and raw data
 // payload. The sequence number and event type are included in the frame envelope.
-func NewDispatchFrame(seq int64, eventType events.DispatchEvent, data json.RawMessage) ([]byte, error) {
-	return json.Marshal(events.Frame{
+func NewDispatchFrame(enc Encoding, seq int64, eventType events.DispatchEvent, data json.RawMessage) ([]byte, error) {
+	return enc.encode(events.Frame{
 		Op:   events.OpcodeDispatch,
 		Seq:  &seq,
 		Type: &eventType,
@@ -38,8 +38,8 @@ func NewDispatchFrame(seq int64, eventType events.DispatchEvent, data json.RawMe
 
 // NewEphemeralDispatchFrame returns a serialised Dispatch frame without a sequence number. Ephemeral events (such as
 // TYPING_START) are not added to the replay buffer and will not be replayed on resume.
-func NewEphemeralDispatchFrame(eventType events.DispatchEvent, data json.RawMessage) ([]byte, error) {
-	return json.Marshal(events.Frame{
+func NewEphemeralDispatchFrame(enc Encoding, eventType events.DispatchEvent, data json.RawMessage) ([]byte, error) {
+	return enc.encode(events.Frame{
 		Op:   events.OpcodeDispatch,
 		Type: &eventType,
 		Data: data,
@@ -47,18 +47,18 @@ func NewEphemeralDispatchFrame(eventType events.DispatchEvent, data json.RawMess
 }
 
 // NewReconnectFrame returns a serialised Reconnect frame instructing the client to reconnect.
-func NewReconnectFrame() ([]byte, error) {
-	return json.Marshal(events.Frame{Op: events.OpcodeReconnect})
+func NewReconnectFrame(enc Encoding) ([]byte, error) {
+	return enc.encode(events.Frame{Op: events.OpcodeReconnect})
 }
 
 // NewInvalidSessionFrame returns a serialised InvalidSession frame. The resumable flag indicates whether the client
 // should attempt to resume or must re-identify.
-func NewInvalidSessionFrame(resumable bool) ([]byte, error) {
+func NewInvalidSessionFrame(enc Encoding, resumable bool) ([]byte, error) {
 	data, err := json.Marshal(resumable)
 	if err != nil {
 		return nil, fmt.Errorf("marshal invalid session data: %w", err)
 	}
-	return json.Marshal(events.Frame{
+	return enc.encode(events.Frame{
 		Op:   events.OpcodeInvalidSession,
 		Data: data,
 	})
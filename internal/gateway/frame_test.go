@@ -11,7 +11,7 @@ import (
 func TestNewHelloFrame(t *testing.T) {
 	t.Parallel()
 
-	raw, err := NewHelloFrame(45000)
+	raw, err := NewHelloFrame(EncodingJSON, 45000)
 	if err != nil {
 		t.Fatalf("NewHelloFrame() error = %v", err)
 	}
@@ -42,7 +42,7 @@ func TestNewHelloFrame(t *testing.T) {
 func TestNewHeartbeatACKFrame(t *testing.T) {
 	t.Parallel()
 
-	raw, err := NewHeartbeatACKFrame()
+	raw, err := NewHeartbeatACKFrame(EncodingJSON)
 	if err != nil {
 		t.Fatalf("NewHeartbeatACKFrame() error = %v", err)
 	}
@@ -63,7 +63,7 @@ func TestNewDispatchFrame(t *testing.T) {
 	t.Parallel()
 
 	payload := json.RawMessage(`{"channel_id":"abc","content":"hello"}`)
-	raw, err := NewDispatchFrame(42, events.MessageCreate, payload)
+	raw, err := NewDispatchFrame(EncodingJSON, 42, events.MessageCreate, payload)
 	if err != nil {
 		t.Fatalf("NewDispatchFrame() error = %v", err)
 	}
@@ -98,7 +98,7 @@ func TestNewEphemeralDispatchFrame(t *testing.T) {
 	t.Parallel()
 
 	payload := json.RawMessage(`{"channel_id":"c1","user_id":"u1"}`)
-	raw, err := NewEphemeralDispatchFrame(events.TypingStart, payload)
+	raw, err := NewEphemeralDispatchFrame(EncodingJSON, events.TypingStart, payload)
 	if err != nil {
 		t.Fatalf("NewEphemeralDispatchFrame() error = %v", err)
 	}
@@ -121,7 +121,7 @@ func TestNewEphemeralDispatchFrame(t *testing.T) {
 func TestNewReconnectFrame(t *testing.T) {
 	t.Parallel()
 
-	raw, err := NewReconnectFrame()
+	raw, err := NewReconnectFrame(EncodingJSON)
 	if err != nil {
 		t.Fatalf("NewReconnectFrame() error = %v", err)
 	}
@@ -148,7 +148,7 @@ func TestNewInvalidSessionFrame(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			raw, err := NewInvalidSessionFrame(tt.resumable)
+			raw, err := NewInvalidSessionFrame(EncodingJSON, tt.resumable)
 			if err != nil {
 				t.Fatalf("NewInvalidSessionFrame(%v) error = %v", tt.resumable, err)
 			}
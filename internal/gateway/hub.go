@@ -3,8 +3,10 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fasthttp/websocket"
@@ -14,6 +16,8 @@ import (
 	"github.com/uncord-chat/uncord-protocol/events"
 	"github.com/uncord-chat/uncord-protocol/models"
 	"github.com/uncord-chat/uncord-protocol/permissions"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/uncord-chat/uncord-server/internal/auth"
@@ -26,6 +30,7 @@ import (
 	"github.com/uncord-chat/uncord-server/internal/readstate"
 	"github.com/uncord-chat/uncord-server/internal/role"
 	servercfg "github.com/uncord-chat/uncord-server/internal/server"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
 	"github.com/uncord-chat/uncord-server/internal/user"
 )
 
@@ -40,6 +45,7 @@ type HubDeps struct {
 	Channels       channel.Repository
 	Roles          role.Repository
 	Members        member.Repository
+	MemberLookup   MemberLookup
 	ReadStates     readstate.Repository
 	Presence       *presence.Store
 	Publisher      *Publisher
@@ -64,16 +70,59 @@ type Hub struct {
 	channels       channel.Repository
 	roles          role.Repository
 	members        member.Repository
+	memberLookup   MemberLookup
 	readStates     readstate.Repository
 	presence       *presence.Store
 	publisher      *Publisher
 	onboardingRepo onboarding.Repository
 	documentStore  *onboarding.DocumentStore
 	log            zerolog.Logger
+
+	// topics tracks the pub/sub topics the local clients need. Permission and channel changes queue a recomputation of
+	// the users' visible channels, batched under refreshMu until refreshTimer fires.
+	topics       *topicRouter
+	refreshMu    sync.Mutex
+	refreshAll   bool
+	refreshUsers map[uuid.UUID]struct{}
+	refreshTimer *time.Timer
+
+	// stream is true with stream delivery. streamMu serialises dispatching stream entries with registering and resuming
+	// clients, and streamPos is the ID of the last entry dispatched. streamPos outlives Run, so that a Hub whose
+	// connection to Valkey fails carries on from where it stopped once Run is restarted.
+	stream    bool
+	streamMu  sync.Mutex
+	streamPos string
+
+	// dispatched counts events received from pub/sub; delivered counts the frames they fanned out to.
+	dispatched atomic.Int64
+	delivered  atomic.Int64
+
+	// subscribed is true while Run holds a confirmed subscription to the events channel.
+	subscribed atomic.Bool
+
+	// draining is set by StartDrain and cleared by StopDrain. drainGen identifies the current drain so that a drain
+	// goroutine stops once its drain is cancelled, even if another drain has started since.
+	draining atomic.Bool
+	drainGen atomic.Uint64
+
+	// statusExpiry holds a timer for each user whose custom status, set through this Hub, has an expiry. expiryMu
+	// guards it.
+	expiryMu     sync.Mutex
+	statusExpiry map[uuid.UUID]*time.Timer
+}
+
+// DispatchStats are cumulative totals of the events a Hub has dispatched since it was created. Delivered divided by
+// Events is the average fan-out per event.
+type DispatchStats struct {
+	Events    int64
+	Delivered int64
 }
 
 // NewHub creates a new gateway hub.
 func NewHub(d HubDeps) *Hub {
+	logger := d.Logger.With().Str("component", "gateway").Logger()
+	sharded := d.Cfg != nil && d.Cfg.GatewayShardedPubSub
+	stream := d.Cfg != nil && d.Cfg.GatewayStreamDelivery()
 	return &Hub{
 		clients:        make(map[uuid.UUID][]*Client),
 		rdb:            d.RDB,
@@ -85,21 +134,42 @@ func NewHub(d HubDeps) *Hub {
 		channels:       d.Channels,
 		roles:          d.Roles,
 		members:        d.Members,
+		memberLookup:   d.MemberLookup,
 		readStates:     d.ReadStates,
 		presence:       d.Presence,
 		publisher:      d.Publisher,
 		onboardingRepo: d.OnboardingRepo,
 		documentStore:  d.DocumentStore,
-		log:            d.Logger.With().Str("component", "gateway").Logger(),
+		log:            logger,
+		topics:         newTopicRouter(sharded, stream || d.Resolver == nil || d.Channels == nil, logger),
+		refreshUsers:   make(map[uuid.UUID]struct{}),
+		stream:         stream,
+		statusExpiry:   make(map[uuid.UUID]*time.Timer),
 	}
 }
 
-// Run subscribes to the gateway events pub/sub channel and dispatches events to connected clients. It blocks until the
-// context is cancelled or the subscription fails.
+// Run subscribes to the gateway events pub/sub channel, the topics of connected users and the channels they can view,
+// and the permission invalidation channel, and dispatches events to connected clients. With stream delivery it reads
+// the event stream instead. It blocks until the context is cancelled or the subscription fails.
 func (h *Hub) Run(ctx context.Context) error {
-	sub := h.rdb.Subscribe(ctx, eventsChannel)
+	if h.stream {
+		return h.runStream(ctx)
+	}
+
+	sub := h.rdb.Subscribe(ctx, eventsChannel, permission.InvalidateChannel)
 	defer func() { _ = sub.Close() }()
 
+	// Wait for the subscription to be confirmed so that Subscribed only reports true once events can be received.
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribe to gateway events: %w", err)
+	}
+	if err := h.topics.attach(ctx, sub); err != nil {
+		return fmt.Errorf("subscribe to gateway topics: %w", err)
+	}
+	defer h.topics.detach()
+	h.subscribed.Store(true)
+	defer h.subscribed.Store(false)
+
 	h.log.Info().Msg("Gateway hub subscribed to event channel")
 
 	ch := sub.Channel()
@@ -111,17 +181,26 @@ func (h *Hub) Run(ctx context.Context) error {
 			if !ok {
 				return nil
 			}
+			if msg.Channel == permission.InvalidateChannel {
+				h.handleInvalidation(msg.Payload)
+				continue
+			}
 			h.handlePubSubEvent(ctx, msg.Payload)
 		}
 	}
 }
 
-// ServeWebSocket initialises a new client for an upgraded WebSocket connection. It sends the Hello frame and starts
-// the client's read and write pumps.
-func (h *Hub) ServeWebSocket(conn *websocket.Conn) {
-	client := newClient(h, conn, h.log)
+// ServeWebSocket initialises a new client for an upgraded WebSocket connection that exchanges frames in the given
+// encoding. It sends the Hello frame and starts the client's read and write pumps. A draining Hub closes the connection
+// instead.
+func (h *Hub) ServeWebSocket(conn *websocket.Conn, enc Encoding) {
+	if h.draining.Load() {
+		refuseDraining(conn)
+		return
+	}
+	client := newClient(h, conn, enc, h.log)
 
-	hello, err := NewHelloFrame(h.cfg.GatewayHeartbeatIntervalMS)
+	hello, err := NewHelloFrame(enc, h.cfg.GatewayHeartbeatIntervalMS)
 	if err != nil {
 		h.log.Error().Err(err).Msg("Failed to build Hello frame")
 		_ = conn.Close()
@@ -129,7 +208,7 @@ func (h *Hub) ServeWebSocket(conn *websocket.Conn) {
 	}
 
 	_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
-	if err := conn.WriteMessage(websocket.TextMessage, hello); err != nil {
+	if err := conn.WriteMessage(enc.messageType(), hello); err != nil {
 		h.log.Debug().Err(err).Msg("Failed to send Hello frame")
 		_ = conn.Close()
 		return
@@ -151,6 +230,7 @@ func (h *Hub) register(client *Client) error {
 
 	userID := client.UserID()
 	h.clients[userID] = append(h.clients[userID], client)
+	h.topics.addUser(userID)
 	h.log.Debug().Stringer("user_id", userID).Int("total", h.totalClientsLocked()).Msg("Client registered")
 	return nil
 }
@@ -168,7 +248,33 @@ func (h *Hub) totalClientsLocked() int {
 // unregister removes a client from the Hub and persists its session for future resume. Presence is only cleared after
 // a delay if the user has no remaining connections.
 func (h *Hub) unregister(client *Client) {
+	hasRemaining, ok := h.detach(client)
+	if !ok {
+		return
+	}
+	userID := client.UserID()
+	client.closeSend()
+
+	if client.IsIdentified() {
+		h.saveSession(client)
+
+		if h.presence != nil && !hasRemaining {
+			h.offlineWg.Add(1)
+			go func() {
+				defer h.offlineWg.Done()
+				h.delayedOffline(userID)
+			}()
+		}
+	}
+
+	h.log.Debug().Stringer("user_id", userID).Msg("Client unregistered")
+}
+
+// detach removes client from the Hub's registry so that no further events are dispatched to it. It reports whether the
+// client's user has other connections to the Hub, and false for ok if the client was not registered.
+func (h *Hub) detach(client *Client) (hasRemaining, ok bool) {
 	h.mu.Lock()
+	defer h.mu.Unlock()
 
 	userID := client.UserID()
 	cs := h.clients[userID]
@@ -181,8 +287,7 @@ func (h *Hub) unregister(client *Client) {
 		}
 	}
 	if idx == -1 {
-		h.mu.Unlock()
-		return
+		return false, false
 	}
 
 	last := len(cs) - 1
@@ -192,32 +297,23 @@ func (h *Hub) unregister(client *Client) {
 
 	if len(cs) == 0 {
 		delete(h.clients, userID)
+		h.topics.removeUser(userID)
 	} else {
 		h.clients[userID] = cs
 	}
-	hasRemaining := len(cs) > 0
-	identified := client.IsIdentified()
-	h.mu.Unlock()
-
-	client.closeSend()
-
-	if identified {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := h.sessions.Save(ctx, client.SessionID(), userID, client.currentSeq()); err != nil {
-			h.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to save session on disconnect")
-		}
+	return len(cs) > 0, true
+}
 
-		if h.presence != nil && !hasRemaining {
-			h.offlineWg.Add(1)
-			go func() {
-				defer h.offlineWg.Done()
-				h.delayedOffline(userID)
-			}()
-		}
+// saveSession persists an identified client's session so that it can be resumed.
+func (h *Hub) saveSession(client *Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	userID := client.UserID()
+	subs := client.subscriptions()
+	cursor := client.streamCursor()
+	if err := h.sessions.Save(ctx, client.SessionID(), userID, client.currentSeq(), subs, cursor); err != nil {
+		h.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to save session on disconnect")
 	}
-
-	h.log.Debug().Stringer("user_id", userID).Msg("Client unregistered")
 }
 
 // delayedOffline waits for the configured offline grace period then publishes an offline presence event if the user
@@ -236,6 +332,7 @@ func (h *Hub) delayedOffline(userID uuid.UUID) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	h.cancelStatusExpiry(userID)
 	if err := h.presence.Delete(ctx, userID); err != nil {
 		h.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to delete presence on delayed offline")
 	}
@@ -259,8 +356,8 @@ func (h *Hub) authenticateToken(ctx context.Context, token string) (uuid.UUID, e
 }
 
 // handleIdentify authenticates a client using a gateway ticket or JWT token, assembles the READY payload, and registers
-// the client.
-func (h *Hub) handleIdentify(client *Client, token string) {
+// the client with the intents it declared.
+func (h *Hub) handleIdentify(client *Client, token string, intents Intent) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -278,7 +375,7 @@ func (h *Hub) handleIdentify(client *Client, token string) {
 		}
 	}
 
-	readyData, err := h.assembleReady(ctx, userID)
+	readyData, err := h.assembleReady(ctx, userID, intents)
 	if err != nil {
 		h.log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to assemble READY payload")
 		client.closeWithCode(CloseUnknownError, "internal error")
@@ -291,14 +388,20 @@ func (h *Hub) handleIdentify(client *Client, token string) {
 	client.mu.Lock()
 	client.userID = userID
 	client.sessionID = sessionID
+	client.setSubscriptionsLocked(Subscriptions{Intents: intents})
 	client.identified = true
 	client.mu.Unlock()
 
-	if err := h.register(client); err != nil {
+	register := h.register
+	if h.stream {
+		register = h.registerAtStreamPos
+	}
+	if err := register(client); err != nil {
 		h.log.Warn().Err(err).Msg("Failed to register client")
 		client.closeWithCode(CloseUnknownError, "registration failed")
 		return
 	}
+	h.refreshTopics(ctx, userID)
 
 	readyPayload, err := json.Marshal(readyData)
 	if err != nil {
@@ -308,7 +411,7 @@ func (h *Hub) handleIdentify(client *Client, token string) {
 	}
 
 	seq := client.nextSeq()
-	frame, err := NewDispatchFrame(seq, events.Ready, readyPayload)
+	frame, err := NewDispatchFrame(client.encoding, seq, events.Ready, readyPayload)
 	if err != nil {
 		h.log.Error().Err(err).Msg("Failed to build READY frame")
 		client.closeWithCode(CloseUnknownError, "internal error")
@@ -316,9 +419,10 @@ func (h *Hub) handleIdentify(client *Client, token string) {
 	}
 	client.enqueue(frame)
 
-	// Broadcast the presence change to other connected clients.
+	// Broadcast the presence change to other connected clients, with any rich presence set by the user's other
+	// connections.
 	if h.presence != nil {
-		h.publishPresence(ctx, userID, presence.StatusOnline)
+		h.publishStoredPresence(ctx, userID)
 	}
 
 	h.log.Info().Stringer("user_id", userID).Str("session_id", sessionID).Msg("Client identified")
@@ -339,7 +443,7 @@ func (h *Hub) handleResume(client *Client, data models.ResumeData) {
 	session, err := h.sessions.Load(ctx, data.SessionID)
 	if err != nil {
 		h.log.Debug().Err(err).Str("session_id", data.SessionID).Msg("Session not found for resume")
-		if frame, fErr := NewInvalidSessionFrame(false); fErr == nil {
+		if frame, fErr := NewInvalidSessionFrame(client.encoding, false); fErr == nil {
 			client.enqueue(frame)
 		}
 		return
@@ -347,7 +451,7 @@ func (h *Hub) handleResume(client *Client, data models.ResumeData) {
 
 	if session.UserID != tokenUserID {
 		h.log.Debug().Msg("Resume user ID does not match token")
-		if frame, fErr := NewInvalidSessionFrame(false); fErr == nil {
+		if frame, fErr := NewInvalidSessionFrame(client.encoding, false); fErr == nil {
 			client.enqueue(frame)
 		}
 		return
@@ -356,49 +460,74 @@ func (h *Hub) handleResume(client *Client, data models.ResumeData) {
 	if data.Seq > session.LastSeq {
 		h.log.Debug().Int64("client_seq", data.Seq).Int64("server_seq", session.LastSeq).
 			Msg("Resume sequence ahead of server")
-		if frame, fErr := NewInvalidSessionFrame(false); fErr == nil {
+		if frame, fErr := NewInvalidSessionFrame(client.encoding, false); fErr == nil {
 			client.enqueue(frame)
 		}
 		return
 	}
 
-	// Replay missed events.
-	missed, err := h.sessions.Replay(ctx, data.SessionID, data.Seq)
-	if err != nil {
-		h.log.Warn().Err(err).Msg("Failed to load replay buffer")
-		if frame, fErr := NewInvalidSessionFrame(false); fErr == nil {
-			client.enqueue(frame)
+	// Replay missed events, from the event stream with stream delivery and from the session's replay buffer otherwise.
+	var replayed int
+	if h.stream {
+		replayed, err = h.resumeFromStream(ctx, client, tokenUserID, data, session)
+		if errors.Is(err, ErrMaxConnections) {
+			h.log.Warn().Err(err).Msg("Failed to register resumed client")
+			client.closeWithCode(CloseUnknownError, "registration failed")
+			return
+		}
+		if err != nil {
+			h.log.Debug().Err(err).Str("session_id", data.SessionID).Msg("Session cannot be resumed from stream")
+			if frame, fErr := NewInvalidSessionFrame(client.encoding, false); fErr == nil {
+				client.enqueue(frame)
+			}
+			return
+		}
+	} else {
+		missed, err := h.sessions.Replay(ctx, data.SessionID, data.Seq)
+		if err != nil {
+			h.log.Warn().Err(err).Msg("Failed to load replay buffer")
+			if frame, fErr := NewInvalidSessionFrame(client.encoding, false); fErr == nil {
+				client.enqueue(frame)
+			}
+			return
 		}
-		return
-	}
 
-	client.mu.Lock()
-	client.userID = tokenUserID
-	client.sessionID = data.SessionID
-	client.seq.Store(session.LastSeq)
-	client.identified = true
-	client.mu.Unlock()
+		client.mu.Lock()
+		client.userID = tokenUserID
+		client.sessionID = data.SessionID
+		client.seq.Store(session.LastSeq)
+		client.setSubscriptionsLocked(session.Subscriptions)
+		client.identified = true
+		client.mu.Unlock()
+
+		if err := h.register(client); err != nil {
+			h.log.Warn().Err(err).Msg("Failed to register resumed client")
+			client.closeWithCode(CloseUnknownError, "registration failed")
+			return
+		}
 
-	if err := h.register(client); err != nil {
-		h.log.Warn().Err(err).Msg("Failed to register resumed client")
-		client.closeWithCode(CloseUnknownError, "registration failed")
-		return
+		// The replay buffer holds JSON frames, which are converted for clients using another encoding.
+		for _, payload := range missed {
+			frame, fErr := client.encoding.fromJSON(payload)
+			if fErr != nil {
+				h.log.Warn().Err(fErr).Str("session_id", data.SessionID).Msg("Skipping undecodable replay frame")
+				continue
+			}
+			client.enqueue(frame)
+			replayed++
+		}
 	}
+	h.refreshTopics(ctx, tokenUserID)
 
 	// Clean up the persisted session now that the client is back.
 	if err := h.sessions.Delete(ctx, data.SessionID); err != nil {
 		h.log.Warn().Err(err).Msg("Failed to delete session after resume")
 	}
 
-	// Send missed events.
-	for _, payload := range missed {
-		client.enqueue(payload)
-	}
-
 	// Send RESUMED dispatch.
 	seq := client.nextSeq()
 	resumedData, _ := json.Marshal(struct{}{})
-	frame, err := NewDispatchFrame(seq, events.Resumed, resumedData)
+	frame, err := NewDispatchFrame(client.encoding, seq, events.Resumed, resumedData)
 	if err != nil {
 		h.log.Error().Err(err).Msg("Failed to build RESUMED frame")
 		client.closeWithCode(CloseUnknownError, "internal error")
@@ -415,7 +544,7 @@ func (h *Hub) handleResume(client *Client, data models.ResumeData) {
 			if pErr := h.presence.Set(ctx, tokenUserID, presence.StatusOnline); pErr != nil {
 				h.log.Warn().Err(pErr).Stringer("user_id", tokenUserID).Msg("Failed to restore presence on resume")
 			} else {
-				h.publishPresence(ctx, tokenUserID, presence.StatusOnline)
+				h.publishStoredPresence(ctx, tokenUserID)
 			}
 		} else {
 			_ = h.presence.Refresh(ctx, tokenUserID)
@@ -423,12 +552,14 @@ func (h *Hub) handleResume(client *Client, data models.ResumeData) {
 	}
 
 	h.log.Info().Stringer("user_id", tokenUserID).Str("session_id", data.SessionID).
-		Int("replayed", len(missed)).Msg("Client resumed")
+		Int("replayed", replayed).Msg("Client resumed")
 }
 
-// handlePresenceUpdate processes a client's opcode 3 presence update. It validates the status, stores it in Valkey,
-// and publishes a PRESENCE_UPDATE dispatch. Invisible status is stored truthfully but broadcast as offline.
-func (h *Hub) handlePresenceUpdate(client *Client, status string) {
+// handlePresenceUpdate processes a client's opcode 3 presence update. It stores the status and rich presence in
+// Valkey, replacing any rich presence set before, and publishes a PRESENCE_UPDATE dispatch. Invisible status is stored
+// truthfully but broadcast as offline, without the rich presence. A custom status with an expiry is cleared when it
+// expires.
+func (h *Hub) handlePresenceUpdate(client *Client, status string, rich presence.Rich) {
 	if h.presence == nil {
 		return
 	}
@@ -437,29 +568,38 @@ func (h *Hub) handlePresenceUpdate(client *Client, status string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := h.presence.Set(ctx, userID, status); err != nil {
+	if err := h.presence.Update(ctx, userID, status, rich); err != nil {
 		h.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to set presence")
 		return
 	}
 
-	broadcastStatus := status
-	if status == presence.StatusInvisible {
-		broadcastStatus = presence.StatusOffline
+	var expiresAt *time.Time
+	if rich.CustomStatus != nil {
+		expiresAt = rich.CustomStatus.ExpiresAt
 	}
-	h.publishPresence(ctx, userID, broadcastStatus)
+	h.scheduleStatusExpiry(userID, expiresAt)
+
+	state := presence.State{PresenceState: models.PresenceState{UserID: userID.String(), Status: status}, Rich: rich}
+	h.publishState(ctx, state)
 }
 
-// publishPresence publishes a PRESENCE_UPDATE dispatch event to the gateway events channel.
+// publishPresence publishes a PRESENCE_UPDATE dispatch event to the gateway events channel for a status without rich
+// presence.
 func (h *Hub) publishPresence(ctx context.Context, userID uuid.UUID, status string) {
+	h.publishState(ctx, presence.State{PresenceState: models.PresenceState{UserID: userID.String(), Status: status}})
+}
+
+// publishState publishes a PRESENCE_UPDATE dispatch event to the gateway events channel. An invisible user is
+// published as offline, without their rich presence.
+func (h *Hub) publishState(ctx context.Context, state presence.State) {
 	if h.publisher == nil {
 		return
 	}
-	data := models.PresenceUpdateData{
-		UserID: userID.String(),
-		Status: status,
+	if state.Status == presence.StatusInvisible {
+		state = presence.State{PresenceState: models.PresenceState{UserID: state.UserID, Status: presence.StatusOffline}}
 	}
-	if err := h.publisher.Publish(ctx, events.PresenceUpdate, data); err != nil {
-		h.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to publish presence update")
+	if err := h.publisher.Publish(ctx, events.PresenceUpdate, state); err != nil {
+		h.log.Warn().Err(err).Str("user_id", state.UserID).Msg("Failed to publish presence update")
 	}
 }
 
@@ -479,69 +619,154 @@ func ephemeralEvent(eventType events.DispatchEvent) bool {
 	return eventType == events.TypingStart || eventType == events.TypingStop
 }
 
-// channelScoped extracts the channel_id from an event payload for permission filtering.
-type channelScoped struct {
-	ChannelID string `json:"channel_id"`
+// dispatchEvent is a decoded gateway event envelope together with the fields of its payload that dispatch filtering
+// inspects.
+type dispatchEvent struct {
+	env       envelope
+	eventType events.DispatchEvent
+	data      json.RawMessage
+	// targets holds env.Targets for lookup, and is nil for events delivered to every eligible client.
+	targets       map[uuid.UUID]struct{}
+	channelID     uuid.UUID
+	channelScoped bool
+	subject       uuid.UUID
 }
 
-// handlePubSubEvent processes a single event from the Valkey pub/sub channel and dispatches it to connected clients.
-func (h *Hub) handlePubSubEvent(ctx context.Context, payload string) {
+// decodeEvent decodes a gateway event envelope.
+func decodeEvent(payload string) (*dispatchEvent, error) {
 	var env envelope
 	if err := json.Unmarshal([]byte(payload), &env); err != nil {
-		h.log.Warn().Err(err).Msg("Invalid gateway event envelope")
-		return
+		return nil, fmt.Errorf("invalid gateway event envelope: %w", err)
 	}
 
-	eventType := events.DispatchEvent(env.Type)
-
 	// The envelope uses an untyped Data field because the pub/sub channel carries heterogeneous event types.
 	// Re-marshalling to json.RawMessage lets the hub forward events without knowing their full shape, at the cost of
 	// one extra marshal round-trip per event. A typed approach would couple the hub to every protocol event definition.
 	rawData, err := json.Marshal(env.Data)
 	if err != nil {
-		h.log.Warn().Err(err).Msg("Failed to re-marshal event data")
-		return
+		return nil, fmt.Errorf("re-marshal event data: %w", err)
 	}
 
-	// Check if this is a channel-scoped event.
-	var scoped channelScoped
-	_ = json.Unmarshal(rawData, &scoped)
+	ev := &dispatchEvent{env: env, eventType: events.DispatchEvent(env.Type), data: rawData}
+	if len(env.Targets) > 0 {
+		ev.targets = make(map[uuid.UUID]struct{}, len(env.Targets))
+		for _, t := range env.Targets {
+			ev.targets[t] = struct{}{}
+		}
+	}
 
-	var channelID uuid.UUID
-	var isChannelScoped bool
-	if scoped.ChannelID != "" {
-		if parsed, pErr := uuid.Parse(scoped.ChannelID); pErr == nil {
-			channelID = parsed
-			isChannelScoped = true
+	// Check if this is a channel-scoped event, and which user it is about.
+	var scope eventScope
+	_ = json.Unmarshal(rawData, &scope)
+	if scope.ChannelID != "" {
+		if parsed, pErr := uuid.Parse(scope.ChannelID); pErr == nil {
+			ev.channelID = parsed
+			ev.channelScoped = true
 		}
 	}
+	ev.subject = scope.subject()
+	return ev, nil
+}
 
-	// When the envelope carries target user IDs, deliver only to those users. Otherwise broadcast to all identified
-	// clients and apply channel-scoped permission filtering.
-	h.mu.RLock()
-	var targets []*Client
-	if len(env.Targets) > 0 {
-		targetSet := make(map[uuid.UUID]struct{}, len(env.Targets))
-		for _, t := range env.Targets {
-			targetSet[t] = struct{}{}
+// addressedTo reports whether c is an identified client the event may be sent to before permissions are considered:
+// one of the event's targets if it has any, and with intents and channel subscriptions that admit the event.
+func (ev *dispatchEvent) addressedTo(c *Client) bool {
+	if ev.targets != nil {
+		if _, ok := ev.targets[c.UserID()]; !ok {
+			return false
 		}
-		for uid, cs := range h.clients {
-			if _, ok := targetSet[uid]; !ok {
-				continue
+	}
+	return c.IsIdentified() && c.wants(ev.eventType, ev.channelID, ev.subject)
+}
+
+// permitted returns the clients allowed to see the event. Channel-scoped events require ViewChannels in the channel.
+// Events published with a required permission are further restricted to users holding it (in the channel, or
+// server-wide for events without a channel). Targeted events bypass permission filtering because the sender already
+// determined the recipient set. Permission results are cached per user so that multiple connections from the same user
+// do not trigger redundant resolver calls.
+func (h *Hub) permitted(ctx context.Context, ev *dispatchEvent, clients []*Client) []*Client {
+	if ev.targets != nil || (!ev.channelScoped && ev.env.Permission == 0) {
+		return clients
+	}
+	if h.resolver == nil {
+		if ev.env.Permission != 0 {
+			// Without a resolver the recipients of a permission-restricted event cannot be determined.
+			return nil
+		}
+		return clients
+	}
+
+	permCache := make(map[uuid.UUID]bool)
+	permitted := make([]*Client, 0, len(clients))
+	for _, c := range clients {
+		uid := c.UserID()
+		allowed, cached := permCache[uid]
+		if !cached {
+			var pErr error
+			if ev.channelScoped {
+				allowed, pErr = h.resolver.HasPermission(ctx, uid, ev.channelID, permissions.ViewChannels|ev.env.Permission)
+			} else {
+				allowed, pErr = h.resolver.HasServerPermission(ctx, uid, ev.env.Permission)
 			}
-			for _, c := range cs {
-				if c.IsIdentified() {
-					targets = append(targets, c)
-				}
+			if pErr != nil {
+				h.log.Warn().Err(pErr).Stringer("user_id", uid).Msg("Permission check failed during dispatch")
+				continue
 			}
+			permCache[uid] = allowed
 		}
-	} else {
-		targets = make([]*Client, 0, h.totalClientsLocked())
-		for _, cs := range h.clients {
-			for _, c := range cs {
-				if c.IsIdentified() {
-					targets = append(targets, c)
-				}
+		if allowed {
+			permitted = append(permitted, c)
+		}
+	}
+	return permitted
+}
+
+// handlePubSubEvent processes a single event from the Valkey pub/sub channel and dispatches it to connected clients.
+func (h *Hub) handlePubSubEvent(ctx context.Context, payload string) {
+	h.handleEvent(ctx, payload, "")
+}
+
+// handleEvent dispatches a gateway event to connected clients. streamID is the ID of the event stream entry that
+// carried the event with stream delivery, and empty for events received over pub/sub. The caller must hold h.streamMu
+// when streamID is set.
+func (h *Hub) handleEvent(ctx context.Context, payload, streamID string) {
+	ev, err := decodeEvent(payload)
+	if err != nil {
+		h.log.Warn().Err(err).Msg("Invalid gateway event")
+		return
+	}
+	h.dispatched.Add(1)
+
+	// A channel that is created, deleted, or moved may change which channels the local users can view.
+	switch ev.eventType {
+	case events.ChannelCreate, events.ChannelUpdate, events.ChannelDelete:
+		h.scheduleTopicRefresh(true)
+	}
+
+	// Events published within a trace are dispatched under a consumer span of that trace. The gap between the publish
+	// span and this one is the delivery latency.
+	if ev.env.Trace != nil {
+		source := eventsChannel
+		if streamID != "" {
+			source = eventStream
+		}
+		var span trace.Span
+		ctx, span = tracing.Tracer().Start(tracing.Extract(ctx, ev.env.Trace), "gateway dispatch "+ev.env.Type,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(semconv.MessagingDestinationName(source)),
+		)
+		defer span.End()
+	}
+
+	// When the envelope carries target user IDs, deliver only to those users. Otherwise broadcast to all identified
+	// clients. Clients whose intents or channel subscriptions exclude the event are skipped here, before any permission
+	// is resolved for them.
+	h.mu.RLock()
+	targets := make([]*Client, 0, len(ev.targets))
+	for _, cs := range h.clients {
+		for _, c := range cs {
+			if ev.addressedTo(c) {
+				targets = append(targets, c)
 			}
 		}
 	}
@@ -550,55 +775,68 @@ func (h *Hub) handlePubSubEvent(ctx context.Context, payload string) {
 	if len(targets) == 0 {
 		return
 	}
+	if targets = h.permitted(ctx, ev, targets); len(targets) == 0 {
+		return
+	}
 
-	// For channel-scoped events, filter by ViewChannels permission. Targeted events bypass permission filtering
-	// because the sender already determined the recipient set. Permission results are cached per user so that
-	// multiple connections from the same user do not trigger redundant resolver calls.
-	if isChannelScoped && h.resolver != nil && len(env.Targets) == 0 {
-		permCache := make(map[uuid.UUID]bool)
-		permitted := make([]*Client, 0, len(targets))
+	// Ephemeral events (e.g. TYPING_START) are sent without a sequence number and are not stored in the replay buffer.
+	// The frame is built once per encoding in use among the targets.
+	if ephemeralEvent(ev.eventType) {
+		frames := make(map[Encoding][]byte, 1)
 		for _, c := range targets {
-			uid := c.UserID()
-			allowed, cached := permCache[uid]
-			if !cached {
-				var pErr error
-				allowed, pErr = h.resolver.HasPermission(ctx, uid, channelID, permissions.ViewChannels)
-				if pErr != nil {
-					h.log.Warn().Err(pErr).Stringer("user_id", uid).Msg("Permission check failed during dispatch")
-					continue
+			frame, ok := frames[c.encoding]
+			if !ok {
+				var fErr error
+				if frame, fErr = NewEphemeralDispatchFrame(c.encoding, ev.eventType, ev.data); fErr != nil {
+					h.log.Warn().Err(fErr).Msg("Failed to build ephemeral dispatch frame")
+					return
 				}
-				permCache[uid] = allowed
-			}
-			if allowed {
-				permitted = append(permitted, c)
+				frames[c.encoding] = frame
 			}
+			c.enqueue(frame)
 		}
-		targets = permitted
+		h.delivered.Add(int64(len(targets)))
+		return
 	}
 
-	// Ephemeral events (e.g. TYPING_START) are sent without a sequence number and are not stored in the replay buffer.
-	if ephemeralEvent(eventType) {
-		frame, fErr := NewEphemeralDispatchFrame(eventType, rawData)
-		if fErr != nil {
-			h.log.Warn().Err(fErr).Msg("Failed to build ephemeral dispatch frame")
-			return
-		}
+	// With stream delivery the event is marked on each client's stream cursor, from which a resume reads it back.
+	if streamID != "" {
 		for _, c := range targets {
+			if c.streamSeen(streamID) {
+				continue
+			}
+			seq := c.nextSeq()
+			frame, fErr := NewDispatchFrame(c.encoding, seq, ev.eventType, ev.data)
+			if fErr != nil {
+				h.log.Warn().Err(fErr).Msg("Failed to build dispatch frame")
+				continue
+			}
 			c.enqueue(frame)
+			c.markStream(seq, streamID)
+			h.delivered.Add(1)
 		}
 		return
 	}
 
-	// Build and send a sequenced dispatch frame per client and append to the replay buffer.
+	// Build and send a sequenced dispatch frame per client and append to the replay buffer. The replay buffer always
+	// holds JSON so that a session can be resumed over a connection using a different encoding.
 	for _, c := range targets {
 		seq := c.nextSeq()
-		frame, fErr := NewDispatchFrame(seq, eventType, rawData)
+		frame, fErr := NewDispatchFrame(EncodingJSON, seq, ev.eventType, ev.data)
 		if fErr != nil {
 			h.log.Warn().Err(fErr).Msg("Failed to build dispatch frame")
 			continue
 		}
 
-		c.enqueue(frame)
+		wire := frame
+		if c.encoding != EncodingJSON {
+			if wire, fErr = NewDispatchFrame(c.encoding, seq, ev.eventType, ev.data); fErr != nil {
+				h.log.Warn().Err(fErr).Msg("Failed to build dispatch frame")
+				continue
+			}
+		}
+		c.enqueue(wire)
+		h.delivered.Add(1)
 
 		// Append to the replay buffer (best-effort). The session ID is only available for identified clients.
 		if sid := c.SessionID(); sid != "" {
@@ -609,9 +847,16 @@ func (h *Hub) handlePubSubEvent(ctx context.Context, payload string) {
 	}
 }
 
+// readyData is the READY payload. Its presences carry each user's rich presence, which models.PresenceState lacks.
+type readyData struct {
+	models.ReadyData
+	Presences []presence.State `json:"presences"`
+}
+
 // assembleReady queries the database for all state needed by a newly connected client. Independent queries run
-// concurrently to reduce latency; presence lookup runs afterwards because it depends on the member list.
-func (h *Hub) assembleReady(ctx context.Context, userID uuid.UUID) (*models.ReadyData, error) {
+// concurrently to reduce latency; presence lookup runs afterwards because it depends on the member list. Members and
+// presences are only loaded for clients that declared the matching intents.
+func (h *Hub) assembleReady(ctx context.Context, userID uuid.UUID, intents Intent) (*readyData, error) {
 	var (
 		u          *user.User
 		srv        *servercfg.Config
@@ -659,14 +904,16 @@ func (h *Hub) assembleReady(ctx context.Context, userID uuid.UUID) (*models.Read
 		return nil
 	})
 
-	g.Go(func() error {
-		var err error
-		ms, err = h.members.List(gCtx, nil, h.cfg.GatewayReadyMemberLimit)
-		if err != nil {
-			return fmt.Errorf("list members: %w", err)
-		}
-		return nil
-	})
+	if intents&(IntentMembers|IntentPresence) != 0 {
+		g.Go(func() error {
+			var err error
+			ms, err = h.members.List(gCtx, nil, h.cfg.GatewayReadyMemberLimit)
+			if err != nil {
+				return fmt.Errorf("list members: %w", err)
+			}
+			return nil
+		})
+	}
 
 	if h.readStates != nil {
 		g.Go(func() error {
@@ -684,8 +931,8 @@ func (h *Hub) assembleReady(ctx context.Context, userID uuid.UUID) (*models.Read
 	}
 
 	// Presence lookup depends on the member list and must run after the concurrent phase.
-	var presences []models.PresenceState
-	if h.presence != nil {
+	var presences []presence.State
+	if h.presence != nil && intents&IntentPresence != 0 {
 		memberIDs := make([]uuid.UUID, len(ms))
 		for i := range ms {
 			memberIDs[i] = ms[i].UserID
@@ -712,15 +959,17 @@ func (h *Hub) assembleReady(ctx context.Context, userID uuid.UUID) (*models.Read
 		}
 	}
 
-	return &models.ReadyData{
-		User:       u.ToModel(),
-		Server:     srv.ToModel(),
-		Channels:   channelSliceToModels(chs),
-		Roles:      roleSliceToModels(rs),
-		Members:    memberSliceToModels(ms),
-		Presences:  presences,
-		ReadStates: readStateSliceToModels(readStates),
-		Onboarding: onboardingCfg,
+	return &readyData{
+		ReadyData: models.ReadyData{
+			User:       u.ToModel(),
+			Server:     srv.ToModel(),
+			Channels:   channelSliceToModels(chs),
+			Roles:      roleSliceToModels(rs),
+			Members:    readyMembers(ms, intents),
+			ReadStates: readStateSliceToModels(readStates),
+			Onboarding: onboardingCfg,
+		},
+		Presences: presences,
 	}, nil
 }
 
@@ -738,10 +987,9 @@ func (h *Hub) Shutdown() {
 		cancel()
 	}
 
-	reconnect, _ := NewReconnectFrame()
 	for userID, cs := range h.clients {
 		for _, client := range cs {
-			if reconnect != nil {
+			if reconnect, err := NewReconnectFrame(client.encoding); err == nil {
 				client.enqueue(reconnect)
 			}
 			client.closeSend()
@@ -757,6 +1005,13 @@ func (h *Hub) Shutdown() {
 
 	h.mu.Unlock()
 
+	h.expiryMu.Lock()
+	for userID, timer := range h.statusExpiry {
+		timer.Stop()
+		delete(h.statusExpiry, userID)
+	}
+	h.expiryMu.Unlock()
+
 	h.log.Info().Msg("Waiting for delayed offline goroutines")
 	h.offlineWg.Wait()
 	h.log.Info().Msg("Gateway hub shutdown complete")
@@ -769,6 +1024,17 @@ func (h *Hub) ClientCount() int {
 	return h.totalClientsLocked()
 }
 
+// Subscribed reports whether the hub is currently subscribed to the gateway events channel. It is false before Run
+// starts and while Run is being restarted after a failure.
+func (h *Hub) Subscribed() bool {
+	return h.subscribed.Load()
+}
+
+// DispatchStats returns the cumulative number of events dispatched and frames delivered to clients.
+func (h *Hub) DispatchStats() DispatchStats {
+	return DispatchStats{Events: h.dispatched.Load(), Delivered: h.delivered.Load()}
+}
+
 // Slice conversion helpers that delegate to each domain type's ToModel() method.
 
 func channelSliceToModels(chs []channel.Channel) []models.Channel {
@@ -787,6 +1053,15 @@ func roleSliceToModels(rs []role.Role) []models.Role {
 	return result
 }
 
+// readyMembers returns the READY member list. Clients with the presence intent but not the members intent still need
+// the members loaded to look up their presences, but are not sent them.
+func readyMembers(ms []member.WithProfile, intents Intent) []models.Member {
+	if intents&IntentMembers == 0 {
+		return []models.Member{}
+	}
+	return memberSliceToModels(ms)
+}
+
 func memberSliceToModels(ms []member.WithProfile) []models.Member {
 	result := make([]models.Member, len(ms))
 	for i := range ms {
@@ -122,6 +122,10 @@ func (r *fakeServerRepo) ClearBannerKey(_ context.Context) (*servercfg.Config, e
 	return r.cfg, nil
 }
 
+func (r *fakeServerRepo) SetOwner(_ context.Context, _ uuid.UUID) (*servercfg.Config, error) {
+	return r.cfg, nil
+}
+
 // fakeChannelRepo implements channel.Repository for testing.
 type fakeChannelRepo struct {
 	channels []channel.Channel
@@ -201,16 +205,20 @@ func (r *fakeMemberRepo) GetByUserIDAnyStatus(context.Context, uuid.UUID) (*memb
 
 func testConfig() *config.Config {
 	return &config.Config{
-		GatewayHeartbeatIntervalMS: 20000,
-		GatewayOfflineDelayMS:      3000,
-		GatewayIdentifyTimeout:     30 * time.Second,
-		GatewaySessionTTL:          5 * time.Minute,
-		GatewayReplayBufferSize:    100,
-		GatewayMaxConnections:      10,
-		RateLimitWSCount:           120,
-		RateLimitWSWindowSeconds:   60,
-		JWTSecret:                  config.NewSecret("test-secret-for-defaults-minimum-32"),
-		ServerURL:                  "http://localhost:8080",
+		GatewayHeartbeatIntervalMS:      20000,
+		GatewayOfflineDelayMS:           3000,
+		GatewayIdentifyTimeout:          30 * time.Second,
+		GatewaySessionTTL:               5 * time.Minute,
+		GatewayReplayBufferSize:         100,
+		GatewayMaxConnections:           10,
+		RateLimitWSCount:                120,
+		RateLimitWSWindowSeconds:        60,
+		RateLimitMemberReqCount:         10,
+		RateLimitMemberReqWindowSeconds: 60,
+		GatewayDrainBatchSize:           100,
+		GatewayDrainBatchInterval:       10 * time.Millisecond,
+		JWTSecret:                       config.NewSecret("test-secret-for-defaults-minimum-32"),
+		ServerURL:                       "http://localhost:8080",
 	}
 }
 
@@ -253,7 +261,7 @@ func TestAssembleReady(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	ready, err := hub.assembleReady(ctx, userID)
+	ready, err := hub.assembleReady(ctx, userID, AllIntents)
 	if err != nil {
 		t.Fatalf("assembleReady() error = %v", err)
 	}
@@ -289,7 +297,7 @@ func TestHandlePubSubEventBroadcast(t *testing.T) {
 	userID := uuid.New()
 	client := &Client{
 		hub:  hub,
-		send: make(chan []byte, 256),
+		send: make(chan outbound, 256),
 		done: make(chan struct{}),
 		log:  zerolog.Nop(),
 	}
@@ -312,7 +320,7 @@ func TestHandlePubSubEventBroadcast(t *testing.T) {
 	select {
 	case msg := <-client.send:
 		var f events.Frame
-		if err := json.Unmarshal(msg, &f); err != nil {
+		if err := json.Unmarshal(msg.data, &f); err != nil {
 			t.Fatalf("unmarshal frame: %v", err)
 		}
 		if f.Op != events.OpcodeDispatch {
@@ -329,6 +337,48 @@ func TestHandlePubSubEventBroadcast(t *testing.T) {
 	}
 }
 
+func TestHandlePubSubEventPermissionRequiresResolver(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	cfg := testConfig()
+	sessions := NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize)
+
+	hub := NewHub(HubDeps{RDB: rdb, Cfg: cfg, Sessions: sessions, Logger: zerolog.Nop()})
+
+	userID := uuid.New()
+	client := &Client{
+		hub:  hub,
+		send: make(chan outbound, 256),
+		done: make(chan struct{}),
+		log:  zerolog.Nop(),
+	}
+	client.mu.Lock()
+	client.userID = userID
+	client.sessionID = "test-session"
+	client.identified = true
+	client.mu.Unlock()
+
+	hub.mu.Lock()
+	hub.clients[userID] = []*Client{client}
+	hub.mu.Unlock()
+
+	// A permission-restricted event must not fall back to broadcasting when recipients cannot be checked.
+	env := envelope{
+		Type:       string(AttachmentQuarantined),
+		Data:       map[string]string{"attachment_id": "a-1"},
+		Permission: permissions.ManageMessages,
+	}
+	payload, _ := json.Marshal(env)
+
+	hub.handlePubSubEvent(context.Background(), string(payload))
+
+	select {
+	case msg := <-client.send:
+		t.Fatalf("permission-restricted event was delivered without a resolver: %s", msg.data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestRegisterMultipleConnectionsSameUser(t *testing.T) {
 	t.Parallel()
 	_, rdb := newTestRedis(t)
@@ -339,7 +389,7 @@ func TestRegisterMultipleConnectionsSameUser(t *testing.T) {
 
 	userID := uuid.New()
 
-	first := &Client{hub: hub, send: make(chan []byte, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	first := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
 	first.mu.Lock()
 	first.userID = userID
 	first.sessionID = "session-1"
@@ -350,7 +400,7 @@ func TestRegisterMultipleConnectionsSameUser(t *testing.T) {
 		t.Fatalf("register(first) error = %v", err)
 	}
 
-	second := &Client{hub: hub, send: make(chan []byte, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	second := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
 	second.mu.Lock()
 	second.userID = userID
 	second.sessionID = "session-2"
@@ -392,7 +442,7 @@ func TestRegisterMaxConnections(t *testing.T) {
 
 	// Register one client.
 	uid1 := uuid.New()
-	c1 := &Client{hub: hub, send: make(chan []byte, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	c1 := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
 	c1.mu.Lock()
 	c1.userID = uid1
 	c1.sessionID = "s1"
@@ -404,7 +454,7 @@ func TestRegisterMaxConnections(t *testing.T) {
 
 	// A second user should be rejected.
 	uid2 := uuid.New()
-	c2 := &Client{hub: hub, send: make(chan []byte, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	c2 := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
 	c2.mu.Lock()
 	c2.userID = uid2
 	c2.sessionID = "s2"
@@ -571,10 +621,11 @@ func TestAssembleReadyWithPresences(t *testing.T) {
 	sessions := NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize)
 	presenceStore := presence.NewStore(rdb)
 
-	// Set user as online before assembling READY.
+	// Set user as online, with a custom status, before assembling READY.
 	ctx := context.Background()
-	if err := presenceStore.Set(ctx, userID, "online"); err != nil {
-		t.Fatalf("presence.Set() error = %v", err)
+	rich := presence.Rich{CustomStatus: &presence.CustomStatus{Text: "Working"}}
+	if err := presenceStore.Update(ctx, userID, "online", rich); err != nil {
+		t.Fatalf("presence.Update() error = %v", err)
 	}
 
 	hub := NewHub(HubDeps{
@@ -592,7 +643,7 @@ func TestAssembleReadyWithPresences(t *testing.T) {
 		Logger:   zerolog.Nop(),
 	})
 
-	ready, err := hub.assembleReady(ctx, userID)
+	ready, err := hub.assembleReady(ctx, userID, AllIntents)
 	if err != nil {
 		t.Fatalf("assembleReady() error = %v", err)
 	}
@@ -602,6 +653,24 @@ func TestAssembleReadyWithPresences(t *testing.T) {
 	if ready.Presences[0].UserID != userID.String() {
 		t.Errorf("Presences[0].UserID = %q, want %q", ready.Presences[0].UserID, userID.String())
 	}
+	if cs := ready.Presences[0].CustomStatus; cs == nil || cs.Text != "Working" {
+		t.Errorf("Presences[0].CustomStatus = %+v, want Working", cs)
+	}
+
+	// The rich presence replaces the protocol's presences in the encoded payload.
+	raw, err := json.Marshal(ready)
+	if err != nil {
+		t.Fatalf("marshal READY: %v", err)
+	}
+	var decoded struct {
+		Presences []map[string]any `json:"presences"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal READY: %v", err)
+	}
+	if len(decoded.Presences) != 1 || decoded.Presences[0]["custom_status"] == nil {
+		t.Errorf("encoded presences = %v, want one with a custom status", decoded.Presences)
+	}
 }
 
 func TestHandlePubSubEventEphemeral(t *testing.T) {
@@ -627,13 +696,14 @@ func TestHandlePubSubEventEphemeral(t *testing.T) {
 			userID := uuid.New()
 			client := &Client{
 				hub:  hub,
-				send: make(chan []byte, 256),
+				send: make(chan outbound, 256),
 				done: make(chan struct{}),
 				log:  zerolog.Nop(),
 			}
 			client.mu.Lock()
 			client.userID = userID
 			client.sessionID = "test-session"
+			client.intents = AllIntents
 			client.identified = true
 			client.mu.Unlock()
 
@@ -654,7 +724,7 @@ func TestHandlePubSubEventEphemeral(t *testing.T) {
 			select {
 			case msg := <-client.send:
 				var f events.Frame
-				if err := json.Unmarshal(msg, &f); err != nil {
+				if err := json.Unmarshal(msg.data, &f); err != nil {
 					t.Fatalf("unmarshal frame: %v", err)
 				}
 				if f.Op != events.OpcodeDispatch {
@@ -688,14 +758,14 @@ func TestMultiClientDispatch(t *testing.T) {
 	userID := uuid.New()
 
 	// Register two clients for the same user to simulate multiple browser tabs.
-	c1 := &Client{hub: hub, send: make(chan []byte, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	c1 := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
 	c1.mu.Lock()
 	c1.userID = userID
 	c1.sessionID = "tab-1"
 	c1.identified = true
 	c1.mu.Unlock()
 
-	c2 := &Client{hub: hub, send: make(chan []byte, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	c2 := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
 	c2.mu.Lock()
 	c2.userID = userID
 	c2.sessionID = "tab-2"
@@ -715,7 +785,7 @@ func TestMultiClientDispatch(t *testing.T) {
 		select {
 		case msg := <-c.send:
 			var f events.Frame
-			if err := json.Unmarshal(msg, &f); err != nil {
+			if err := json.Unmarshal(msg.data, &f); err != nil {
 				t.Fatalf("unmarshal frame: %v", err)
 			}
 			if f.Op != events.OpcodeDispatch {
@@ -742,14 +812,14 @@ func TestUnregisterPartial(t *testing.T) {
 
 	userID := uuid.New()
 
-	c1 := &Client{hub: hub, send: make(chan []byte, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	c1 := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
 	c1.mu.Lock()
 	c1.userID = userID
 	c1.sessionID = "partial-1"
 	c1.identified = true
 	c1.mu.Unlock()
 
-	c2 := &Client{hub: hub, send: make(chan []byte, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	c2 := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
 	c2.mu.Lock()
 	c2.userID = userID
 	c2.sessionID = "partial-2"
@@ -852,7 +922,7 @@ func TestAssembleReadyWithOnboarding(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	ready, err := hub.assembleReady(ctx, userID)
+	ready, err := hub.assembleReady(ctx, userID, AllIntents)
 	if err != nil {
 		t.Fatalf("assembleReady() error = %v", err)
 	}
@@ -900,7 +970,7 @@ func TestHubConcurrentRegisterUnregisterDispatch(t *testing.T) {
 			userID := uuid.New()
 			client := &Client{
 				hub:  hub,
-				send: make(chan []byte, 256),
+				send: make(chan outbound, 256),
 				done: make(chan struct{}),
 				log:  zerolog.Nop(),
 			}
@@ -953,7 +1023,7 @@ func TestHubConcurrentMultiClient(t *testing.T) {
 
 			client := &Client{
 				hub:  hub,
-				send: make(chan []byte, 256),
+				send: make(chan outbound, 256),
 				done: make(chan struct{}),
 				log:  zerolog.Nop(),
 			}
@@ -999,7 +1069,7 @@ func benchmarkDispatch(b *testing.B, clientCount int) {
 		userID := uuid.New()
 		client := &Client{
 			hub:  hub,
-			send: make(chan []byte, 256),
+			send: make(chan outbound, 256),
 			done: make(chan struct{}),
 			log:  zerolog.Nop(),
 		}
@@ -1067,7 +1137,7 @@ func TestAssembleReadyNilOnboardingDeps(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	ready, err := hub.assembleReady(ctx, userID)
+	ready, err := hub.assembleReady(ctx, userID, AllIntents)
 	if err != nil {
 		t.Fatalf("assembleReady() error = %v", err)
 	}
@@ -1075,3 +1145,36 @@ func TestAssembleReadyNilOnboardingDeps(t *testing.T) {
 		t.Errorf("Onboarding = %v, want nil when deps are nil", ready.Onboarding)
 	}
 }
+
+func TestHubSubscribed(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	cfg := testConfig()
+	sessions := NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize)
+	hub := NewHub(HubDeps{RDB: rdb, Cfg: cfg, Sessions: sessions, Logger: zerolog.Nop()})
+
+	if hub.Subscribed() {
+		t.Fatal("Subscribed() = true before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = hub.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !hub.Subscribed() {
+		if time.Now().After(deadline) {
+			t.Fatal("Subscribed() = false while Run is subscribed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	if hub.Subscribed() {
+		t.Error("Subscribed() = true after Run returned")
+	}
+}
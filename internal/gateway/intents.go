@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/uncord-chat/uncord-protocol/events"
+)
+
+// Intent is a bit in the intents field of the Identify payload. Each intent selects a family of server events; a client
+// that leaves one out is never sent those events, so a backgrounded mobile client can drop typing and presence traffic
+// entirely. Events outside every family (channel, role, and server changes, direct messages, read state, and key
+// management) are always delivered.
+type Intent uint32
+
+const (
+	// IntentMessages selects MESSAGE_CREATE, MESSAGE_UPDATE, and MESSAGE_DELETE.
+	IntentMessages Intent = 1 << iota
+	// IntentReactions selects REACTION_ADD and REACTION_REMOVE.
+	IntentReactions
+	// IntentTyping selects TYPING_START and TYPING_STOP.
+	IntentTyping
+	// IntentPresence selects PRESENCE_UPDATE and the presences in READY.
+	IntentPresence
+	// IntentMembers selects MEMBER_ADD, MEMBER_UPDATE, and MEMBER_REMOVE for other users, and the members in READY.
+	// A client always receives the member events about its own user.
+	IntentMembers
+	// IntentVoice is reserved for voice state events, which the server does not emit yet.
+	IntentVoice
+
+	// AllIntents is every intent. It applies when Identify omits the intents field.
+	AllIntents = IntentMessages | IntentReactions | IntentTyping | IntentPresence | IntentMembers | IntentVoice
+)
+
+// maxUnsubscribedChannels caps how many channels a single connection may unsubscribe from, bounding the memory each
+// connection holds for its filter.
+const maxUnsubscribedChannels = 1000
+
+// validIntents returns an error if i has bits outside AllIntents.
+func validIntents(i Intent) error {
+	if i&^AllIntents != 0 {
+		return fmt.Errorf("unknown intent bits %#x", uint32(i&^AllIntents))
+	}
+	return nil
+}
+
+// eventIntent returns the intent that selects eventType, or 0 if the event is delivered regardless of intents.
+func eventIntent(eventType events.DispatchEvent) Intent {
+	switch eventType {
+	case events.MessageCreate, events.MessageUpdate, events.MessageDelete:
+		return IntentMessages
+	case events.ReactionAdd, events.ReactionRemove:
+		return IntentReactions
+	case events.TypingStart, events.TypingStop:
+		return IntentTyping
+	case events.PresenceUpdate:
+		return IntentPresence
+	case events.MemberAdd, events.MemberUpdate, events.MemberRemove:
+		return IntentMembers
+	default:
+		return 0
+	}
+}
+
+// Subscriptions is the event filter a client declared for its session: its intents and the channels it unsubscribed
+// from with OpcodeChannelSubscriptions. It is saved with the session so that a resumed connection keeps filtering the
+// same way.
+type Subscriptions struct {
+	Intents      Intent
+	Unsubscribed []uuid.UUID
+}
+
+// channelSubscriptionData is the payload of an OpcodeChannelSubscriptions frame. Every connection starts subscribed to
+// all channels; unsubscribing from a channel stops the channel-scoped events in it (messages, reactions, typing, and
+// the like) until the client subscribes again. Subscribing never grants access to a channel the user cannot view.
+type channelSubscriptionData struct {
+	Subscribe   []string `json:"subscribe"`
+	Unsubscribe []string `json:"unsubscribe"`
+}
+
+// eventScope holds the fields of an event payload that dispatch filtering inspects. Member events identify their
+// subject either by user_id (MEMBER_REMOVE) or by a nested user object (MEMBER_ADD, MEMBER_UPDATE).
+type eventScope struct {
+	ChannelID string `json:"channel_id"`
+	UserID    string `json:"user_id"`
+	User      struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+// subject returns the user an event is about, or uuid.Nil if the payload names none.
+func (s eventScope) subject() uuid.UUID {
+	id := s.UserID
+	if id == "" {
+		id = s.User.ID
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil
+	}
+	return parsed
+}
@@ -0,0 +1,253 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/events"
+
+	"github.com/uncord-chat/uncord-server/internal/member"
+	servercfg "github.com/uncord-chat/uncord-server/internal/server"
+	"github.com/uncord-chat/uncord-server/internal/user"
+)
+
+func TestValidIntents(t *testing.T) {
+	t.Parallel()
+
+	for _, i := range []Intent{0, IntentMessages, IntentTyping | IntentPresence, AllIntents} {
+		if err := validIntents(i); err != nil {
+			t.Errorf("validIntents(%#x) error = %v, want nil", uint32(i), err)
+		}
+	}
+	if err := validIntents(AllIntents + 1); err == nil {
+		t.Error("validIntents() accepted an unknown bit")
+	}
+}
+
+func TestEventIntent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		event events.DispatchEvent
+		want  Intent
+	}{
+		{events.MessageCreate, IntentMessages},
+		{events.MessageDelete, IntentMessages},
+		{events.ReactionAdd, IntentReactions},
+		{events.TypingStart, IntentTyping},
+		{events.TypingStop, IntentTyping},
+		{events.PresenceUpdate, IntentPresence},
+		{events.MemberUpdate, IntentMembers},
+		{events.ChannelUpdate, 0},
+		{events.DMMessageCreate, 0},
+		{events.MessageAck, 0},
+	}
+	for _, tt := range tests {
+		if got := eventIntent(tt.event); got != tt.want {
+			t.Errorf("eventIntent(%s) = %#x, want %#x", tt.event, uint32(got), uint32(tt.want))
+		}
+	}
+}
+
+func TestEventScopeSubject(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	tests := []struct {
+		name    string
+		payload string
+		want    uuid.UUID
+	}{
+		{"user_id", `{"user_id":"` + id.String() + `"}`, id},
+		{"nested user", `{"user":{"id":"` + id.String() + `"},"roles":[]}`, id},
+		{"none", `{"channel_id":"` + uuid.NewString() + `"}`, uuid.Nil},
+		{"invalid", `{"user_id":"not-a-uuid"}`, uuid.Nil},
+	}
+	for _, tt := range tests {
+		var s eventScope
+		if err := json.Unmarshal([]byte(tt.payload), &s); err != nil {
+			t.Fatalf("%s: unmarshal: %v", tt.name, err)
+		}
+		if got := s.subject(); got != tt.want {
+			t.Errorf("%s: subject() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// identifiedClient returns a client of hub that is identified as a new user with the given intents.
+func identifiedClient(hub *Hub, intents Intent) *Client {
+	c := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	c.mu.Lock()
+	c.userID = uuid.New()
+	c.sessionID = "session-" + c.userID.String()
+	c.setSubscriptionsLocked(Subscriptions{Intents: intents})
+	c.identified = true
+	c.mu.Unlock()
+	return c
+}
+
+func TestClientWants(t *testing.T) {
+	t.Parallel()
+
+	channelID := uuid.New()
+	c := identifiedClient(nil, IntentMessages)
+	c.unsubscribed[channelID] = struct{}{}
+
+	tests := []struct {
+		name    string
+		event   events.DispatchEvent
+		channel uuid.UUID
+		subject uuid.UUID
+		want    bool
+	}{
+		{"declared intent", events.MessageCreate, uuid.New(), uuid.Nil, true},
+		{"missing intent", events.TypingStart, uuid.New(), uuid.Nil, false},
+		{"unsubscribed channel", events.MessageCreate, channelID, uuid.Nil, false},
+		{"event without intent", events.ChannelUpdate, uuid.Nil, uuid.Nil, true},
+		{"unsubscribed channel without intent", events.MessageAck, channelID, uuid.Nil, false},
+		{"other member", events.MemberUpdate, uuid.Nil, uuid.New(), false},
+		{"own member", events.MemberUpdate, uuid.Nil, c.UserID(), true},
+	}
+	for _, tt := range tests {
+		if got := c.wants(tt.event, tt.channel, tt.subject); got != tt.want {
+			t.Errorf("%s: wants() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandleChannelSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	a, b, keep := uuid.New(), uuid.New(), uuid.New()
+	c := identifiedClient(nil, AllIntents)
+	c.unsubscribed[keep] = struct{}{}
+
+	send := func(req channelSubscriptionData) {
+		t.Helper()
+		data, err := json.Marshal(req)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		c.handleChannelSubscriptions(data)
+	}
+
+	send(channelSubscriptionData{Unsubscribe: []string{a.String(), b.String()}})
+	send(channelSubscriptionData{Subscribe: []string{a.String()}})
+
+	subs := c.subscriptions()
+	got := make(map[uuid.UUID]bool, len(subs.Unsubscribed))
+	for _, id := range subs.Unsubscribed {
+		got[id] = true
+	}
+	if len(got) != 2 || !got[b] || !got[keep] {
+		t.Errorf("unsubscribed = %v, want %v and %v", subs.Unsubscribed, b, keep)
+	}
+}
+
+// TestHandlePubSubEventFiltersBeforeDelivery verifies that intents and channel subscriptions decide which clients
+// receive a broadcast.
+func TestHandlePubSubEventFiltersBeforeDelivery(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	cfg := testConfig()
+	sessions := NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize)
+	hub := NewHub(HubDeps{RDB: rdb, Cfg: cfg, Sessions: sessions, Logger: zerolog.Nop()})
+
+	all := identifiedClient(hub, AllIntents)
+	quiet := identifiedClient(hub, IntentMessages)
+	muted := identifiedClient(hub, AllIntents)
+	channelID := uuid.New()
+	muted.unsubscribed[channelID] = struct{}{}
+
+	hub.mu.Lock()
+	for _, c := range []*Client{all, quiet, muted} {
+		hub.clients[c.UserID()] = []*Client{c}
+	}
+	hub.mu.Unlock()
+
+	publish := func(eventType events.DispatchEvent, data any) {
+		t.Helper()
+		payload, err := json.Marshal(envelope{Type: string(eventType), Data: data})
+		if err != nil {
+			t.Fatalf("marshal envelope: %v", err)
+		}
+		hub.handlePubSubEvent(context.Background(), string(payload))
+	}
+
+	// Typing reaches clients with the typing intent that are subscribed to the channel.
+	publish(events.TypingStart, map[string]string{"channel_id": channelID.String(), "user_id": uuid.NewString()})
+	// A member update about the quiet client's own user reaches it despite the missing members intent.
+	publish(events.MemberUpdate, map[string]any{"user": map[string]string{"id": quiet.UserID().String()}})
+
+	wantTypes := map[*Client][]events.DispatchEvent{
+		all:   {events.TypingStart, events.MemberUpdate},
+		quiet: {events.MemberUpdate},
+		muted: {events.MemberUpdate},
+	}
+	for c, want := range wantTypes {
+		var got []events.DispatchEvent
+		for len(got) < len(want) {
+			select {
+			case msg := <-c.send:
+				var f events.Frame
+				if err := json.Unmarshal(msg.data, &f); err != nil {
+					t.Fatalf("unmarshal frame: %v", err)
+				}
+				got = append(got, *f.Type)
+			case <-time.After(time.Second):
+				t.Fatalf("client received %v, want %v", got, want)
+			}
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("client received %v, want %v", got, want)
+				break
+			}
+		}
+		select {
+		case msg := <-c.send:
+			t.Errorf("client received an unexpected frame: %s", msg.data)
+		default:
+		}
+	}
+}
+
+func TestAssembleReadyOmitsMembersWithoutIntents(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+
+	userID := uuid.New()
+	cfg := testConfig()
+	members := &fakeMemberRepo{members: []member.WithProfile{{UserID: userID, Username: "testuser", Status: "active"}}}
+	hub := NewHub(HubDeps{
+		RDB:      rdb,
+		Cfg:      cfg,
+		Sessions: NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize),
+		Users:    &fakeUserRepo{user: &user.User{ID: userID, Username: "testuser"}},
+		Server:   &fakeServerRepo{cfg: &servercfg.Config{ID: uuid.New(), Name: "Test Server", OwnerID: userID}},
+		Channels: &fakeChannelRepo{},
+		Roles:    &fakeRoleRepo{},
+		Members:  members,
+		Logger:   zerolog.Nop(),
+	})
+
+	ready, err := hub.assembleReady(context.Background(), userID, IntentMessages|IntentPresence)
+	if err != nil {
+		t.Fatalf("assembleReady() error = %v", err)
+	}
+	if ready.Members == nil || len(ready.Members) != 0 {
+		t.Errorf("Members = %v, want an empty list without the members intent", ready.Members)
+	}
+
+	ready, err = hub.assembleReady(context.Background(), userID, IntentMessages)
+	if err != nil {
+		t.Fatalf("assembleReady() error = %v", err)
+	}
+	if len(ready.Members) != 0 || len(ready.Presences) != 0 {
+		t.Errorf("READY = %d members, %d presences; want none", len(ready.Members), len(ready.Presences))
+	}
+}
@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uncord-chat/uncord-protocol/models"
+
+	"github.com/uncord-chat/uncord-server/internal/member"
+	"github.com/uncord-chat/uncord-server/internal/presence"
+)
+
+const (
+	// maxMemberRequestUserIDs caps how many user IDs a single OpcodeRequestMembers frame may name.
+	maxMemberRequestUserIDs = 100
+	// maxMemberRequestLimit caps how many members a query request returns. A limit of 0 selects this maximum.
+	maxMemberRequestLimit = 1000
+	// maxMemberQueryLength caps the length in bytes of a query prefix. No username, display name, or nickname is longer.
+	maxMemberQueryLength = 100
+	// maxMemberRequestNonceLength caps the length in bytes of the nonce echoed back in each chunk.
+	maxMemberRequestNonceLength = 32
+	// memberChunkSize is the number of members sent in each MEMBERS_CHUNK dispatch.
+	memberChunkSize = 100
+	// memberRequestTimeout bounds the database and presence lookups for one request.
+	memberRequestTimeout = 5 * time.Second
+)
+
+// MemberLookup finds members by name prefix or user ID. Satisfied by *member.PGRepository.
+type MemberLookup interface {
+	ListByPrefix(ctx context.Context, prefix string, limit int) ([]member.WithProfile, error)
+	ListByUserIDs(ctx context.Context, userIDs []uuid.UUID) ([]member.WithProfile, error)
+}
+
+// requestMembersData is the payload of an OpcodeRequestMembers frame. Exactly one of Query and UserIDs must be set; an
+// empty query matches every member up to the limit.
+type requestMembersData struct {
+	Query     *string  `json:"query"`
+	UserIDs   []string `json:"user_ids"`
+	Limit     int      `json:"limit"`
+	Presences bool     `json:"presences"`
+	Nonce     string   `json:"nonce"`
+}
+
+// memberRequest is a validated OpcodeRequestMembers payload. userIDs is nil for a query request.
+type memberRequest struct {
+	query     string
+	userIDs   []uuid.UUID
+	limit     int
+	presences bool
+	nonce     string
+}
+
+// membersChunkData is the payload of a MEMBERS_CHUNK dispatch. A request is answered with chunk_count chunks, at
+// least one even when nothing matched, each carrying the request's nonce. Presences is only present when the request
+// asked for it and the connection declared IntentPresence; offline and invisible members are absent from it. NotFound
+// lists the requested user IDs that are not members and is only set on the first chunk.
+type membersChunkData struct {
+	Members    []models.Member  `json:"members"`
+	Presences  []presence.State `json:"presences,omitempty"`
+	NotFound   []string         `json:"not_found,omitempty"`
+	ChunkIndex int              `json:"chunk_index"`
+	ChunkCount int              `json:"chunk_count"`
+	Nonce      string           `json:"nonce,omitempty"`
+}
+
+// parseMemberRequest decodes and validates an OpcodeRequestMembers payload.
+func parseMemberRequest(data json.RawMessage) (memberRequest, error) {
+	var req requestMembersData
+	if err := json.Unmarshal(data, &req); err != nil {
+		return memberRequest{}, err
+	}
+	if (req.Query == nil) == (req.UserIDs == nil) {
+		return memberRequest{}, errors.New("exactly one of query and user_ids is required")
+	}
+	if len(req.Nonce) > maxMemberRequestNonceLength {
+		return memberRequest{}, errors.New("nonce too long")
+	}
+	if req.Limit < 0 {
+		return memberRequest{}, errors.New("limit must not be negative")
+	}
+
+	out := memberRequest{limit: req.Limit, presences: req.Presences, nonce: req.Nonce}
+	if out.limit == 0 || out.limit > maxMemberRequestLimit {
+		out.limit = maxMemberRequestLimit
+	}
+	if req.Query != nil {
+		if len(*req.Query) > maxMemberQueryLength {
+			return memberRequest{}, errors.New("query too long")
+		}
+		out.query = *req.Query
+		return out, nil
+	}
+
+	if len(req.UserIDs) == 0 || len(req.UserIDs) > maxMemberRequestUserIDs {
+		return memberRequest{}, fmt.Errorf("user_ids must name between 1 and %d users", maxMemberRequestUserIDs)
+	}
+	out.userIDs = make([]uuid.UUID, 0, len(req.UserIDs))
+	seen := make(map[uuid.UUID]struct{}, len(req.UserIDs))
+	for _, s := range req.UserIDs {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return memberRequest{}, err
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out.userIDs = append(out.userIDs, id)
+	}
+	return out, nil
+}
+
+// handleRequestMembers answers a member request with MEMBERS_CHUNK dispatches. The chunks are ephemeral: they are sent
+// only to the requesting connection, carry no sequence number, and are not replayed on resume. A failed lookup is
+// logged and leaves the request unanswered rather than closing the connection.
+func (h *Hub) handleRequestMembers(client *Client, req memberRequest) {
+	if h.memberLookup == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), memberRequestTimeout)
+	defer cancel()
+
+	var (
+		ms       []member.WithProfile
+		notFound []string
+		err      error
+	)
+	if req.userIDs != nil {
+		ms, err = h.memberLookup.ListByUserIDs(ctx, req.userIDs)
+		notFound = missingMembers(req.userIDs, ms)
+	} else {
+		ms, err = h.memberLookup.ListByPrefix(ctx, req.query, req.limit)
+	}
+	if err != nil {
+		h.log.Warn().Err(err).Stringer("user_id", client.UserID()).Msg("Failed to look up requested members")
+		return
+	}
+
+	withPresences := req.presences && h.presence != nil && client.hasIntent(IntentPresence)
+	chunkCount := max((len(ms)+memberChunkSize-1)/memberChunkSize, 1)
+	for i := range chunkCount {
+		chunk := ms[min(i*memberChunkSize, len(ms)):min((i+1)*memberChunkSize, len(ms))]
+		data := membersChunkData{
+			Members:    memberSliceToModels(chunk),
+			ChunkIndex: i,
+			ChunkCount: chunkCount,
+			Nonce:      req.nonce,
+		}
+		if i == 0 {
+			data.NotFound = notFound
+		}
+		if withPresences && len(chunk) > 0 {
+			ids := make([]uuid.UUID, len(chunk))
+			for j := range chunk {
+				ids[j] = chunk[j].UserID
+			}
+			if data.Presences, err = h.presence.GetMany(ctx, ids); err != nil {
+				h.log.Warn().Err(err).Msg("Failed to get presences for member chunk")
+			}
+		}
+
+		raw, err := json.Marshal(data)
+		if err != nil {
+			h.log.Error().Err(err).Msg("Failed to marshal member chunk")
+			return
+		}
+		frame, err := NewEphemeralDispatchFrame(client.encoding, MembersChunk, raw)
+		if err != nil {
+			h.log.Error().Err(err).Msg("Failed to build member chunk frame")
+			return
+		}
+		client.enqueue(frame)
+	}
+}
+
+// missingMembers returns the requested IDs that have no entry in found.
+func missingMembers(requested []uuid.UUID, found []member.WithProfile) []string {
+	present := make(map[uuid.UUID]struct{}, len(found))
+	for i := range found {
+		present[found[i].UserID] = struct{}{}
+	}
+	var missing []string
+	for _, id := range requested {
+		if _, ok := present[id]; !ok {
+			missing = append(missing, id.String())
+		}
+	}
+	return missing
+}
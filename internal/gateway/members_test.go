@@ -0,0 +1,228 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/events"
+
+	"github.com/uncord-chat/uncord-server/internal/member"
+	"github.com/uncord-chat/uncord-server/internal/presence"
+)
+
+// fakeMemberLookup serves member requests from a fixed list.
+type fakeMemberLookup struct {
+	members []member.WithProfile
+}
+
+func (f *fakeMemberLookup) ListByPrefix(_ context.Context, prefix string, limit int) ([]member.WithProfile, error) {
+	var out []member.WithProfile
+	for _, m := range f.members {
+		if strings.HasPrefix(m.Username, prefix) && len(out) < limit {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeMemberLookup) ListByUserIDs(_ context.Context, ids []uuid.UUID) ([]member.WithProfile, error) {
+	var out []member.WithProfile
+	for _, m := range f.members {
+		for _, id := range ids {
+			if m.UserID == id {
+				out = append(out, m)
+			}
+		}
+	}
+	return out, nil
+}
+
+func TestParseMemberRequest(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	tooMany := make([]string, maxMemberRequestUserIDs+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewString()
+	}
+	tooManyJSON, err := json.Marshal(tooMany)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	valid := []struct {
+		payload   string
+		wantLimit int
+		wantIDs   int
+	}{
+		{`{"query":""}`, maxMemberRequestLimit, 0},
+		{`{"query":"ali","limit":50,"nonce":"n1"}`, 50, 0},
+		{`{"query":"ali","limit":5000}`, maxMemberRequestLimit, 0},
+		{`{"user_ids":["` + id.String() + `","` + id.String() + `"]}`, maxMemberRequestLimit, 1},
+	}
+	for _, tt := range valid {
+		req, err := parseMemberRequest(json.RawMessage(tt.payload))
+		if err != nil {
+			t.Errorf("parseMemberRequest(%s) error = %v", tt.payload, err)
+			continue
+		}
+		if req.limit != tt.wantLimit || len(req.userIDs) != tt.wantIDs {
+			t.Errorf("parseMemberRequest(%s) = limit %d, %d IDs; want %d, %d",
+				tt.payload, req.limit, len(req.userIDs), tt.wantLimit, tt.wantIDs)
+		}
+	}
+
+	invalid := []string{
+		`{}`,
+		`{"query":"a","user_ids":["` + id.String() + `"]}`,
+		`{"user_ids":[]}`,
+		`{"user_ids":["not-a-uuid"]}`,
+		`{"user_ids":` + string(tooManyJSON) + `}`,
+		`{"query":"a","limit":-1}`,
+		`{"query":"` + strings.Repeat("a", maxMemberQueryLength+1) + `"}`,
+		`{"query":"a","nonce":"` + strings.Repeat("n", maxMemberRequestNonceLength+1) + `"}`,
+		`[]`,
+	}
+	for _, payload := range invalid {
+		if _, err := parseMemberRequest(json.RawMessage(payload)); err == nil {
+			t.Errorf("parseMemberRequest(%.60s) succeeded, want an error", payload)
+		}
+	}
+}
+
+// readChunks reads n MEMBERS_CHUNK frames from c.
+func readChunks(t *testing.T, c *Client, n int) []membersChunkData {
+	t.Helper()
+	chunks := make([]membersChunkData, 0, n)
+	for range n {
+		select {
+		case msg := <-c.send:
+			var f events.Frame
+			if err := json.Unmarshal(msg.data, &f); err != nil {
+				t.Fatalf("unmarshal frame: %v", err)
+			}
+			if f.Type == nil || *f.Type != MembersChunk || f.Seq != nil {
+				t.Fatalf("frame = %s, want an unsequenced %s dispatch", msg.data, MembersChunk)
+			}
+			var chunk membersChunkData
+			if err := json.Unmarshal(f.Data, &chunk); err != nil {
+				t.Fatalf("unmarshal chunk: %v", err)
+			}
+			chunks = append(chunks, chunk)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d chunks, want %d", len(chunks), n)
+		}
+	}
+	select {
+	case msg := <-c.send:
+		t.Fatalf("received an unexpected frame: %s", msg.data)
+	default:
+	}
+	return chunks
+}
+
+func TestHandleRequestMembersChunksQuery(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+
+	ms := make([]member.WithProfile, 250)
+	for i := range ms {
+		ms[i] = member.WithProfile{UserID: uuid.New(), Username: fmt.Sprintf("user%03d", i), Status: "active"}
+	}
+	store := presence.NewStore(rdb)
+	if err := store.Set(context.Background(), ms[0].UserID, presence.StatusOnline); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	hub := NewHub(HubDeps{
+		RDB:          rdb,
+		Cfg:          testConfig(),
+		Presence:     store,
+		MemberLookup: &fakeMemberLookup{members: ms},
+		Logger:       zerolog.Nop(),
+	})
+
+	c := identifiedClient(hub, AllIntents)
+	hub.handleRequestMembers(c, memberRequest{query: "user", limit: maxMemberRequestLimit, presences: true, nonce: "n"})
+
+	chunks := readChunks(t, c, 3)
+	total := 0
+	for i, chunk := range chunks {
+		if chunk.ChunkIndex != i || chunk.ChunkCount != 3 || chunk.Nonce != "n" {
+			t.Errorf("chunk %d = index %d of %d, nonce %q; want %d of 3, nonce n",
+				i, chunk.ChunkIndex, chunk.ChunkCount, chunk.Nonce, i)
+		}
+		total += len(chunk.Members)
+	}
+	if total != len(ms) {
+		t.Errorf("received %d members, want %d", total, len(ms))
+	}
+	if len(chunks[0].Presences) != 1 || chunks[0].Presences[0].UserID != ms[0].UserID.String() {
+		t.Errorf("first chunk presences = %v, want only %s", chunks[0].Presences, ms[0].UserID)
+	}
+
+	// Without the presence intent, presences are left out even when requested.
+	quiet := identifiedClient(hub, IntentMessages)
+	hub.handleRequestMembers(quiet, memberRequest{query: "user000", limit: 10, presences: true})
+	if chunk := readChunks(t, quiet, 1)[0]; len(chunk.Members) != 1 || chunk.Presences != nil {
+		t.Errorf("chunk = %d members, presences %v; want 1 member and no presences", len(chunk.Members), chunk.Presences)
+	}
+}
+
+func TestHandleRequestMembersByUserID(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+
+	known := member.WithProfile{UserID: uuid.New(), Username: "alice", Status: "active"}
+	unknown := uuid.New()
+	hub := NewHub(HubDeps{
+		RDB:          rdb,
+		Cfg:          testConfig(),
+		MemberLookup: &fakeMemberLookup{members: []member.WithProfile{known}},
+		Logger:       zerolog.Nop(),
+	})
+
+	c := identifiedClient(hub, AllIntents)
+	hub.handleRequestMembers(c, memberRequest{userIDs: []uuid.UUID{known.UserID, unknown}, nonce: "lookup"})
+
+	chunk := readChunks(t, c, 1)[0]
+	if len(chunk.Members) != 1 || chunk.Members[0].User.ID != known.UserID.String() {
+		t.Errorf("members = %v, want only %s", chunk.Members, known.UserID)
+	}
+	if len(chunk.NotFound) != 1 || chunk.NotFound[0] != unknown.String() {
+		t.Errorf("not_found = %v, want [%s]", chunk.NotFound, unknown)
+	}
+
+	// A request that matches nothing is still answered so the client can resolve its nonce.
+	hub.handleRequestMembers(c, memberRequest{query: "zzz", limit: 10, nonce: "empty"})
+	if chunk := readChunks(t, c, 1)[0]; len(chunk.Members) != 0 || chunk.ChunkCount != 1 || chunk.Nonce != "empty" {
+		t.Errorf("empty chunk = %+v, want one empty chunk with nonce empty", chunk)
+	}
+}
+
+func TestMemberRequestsLimited(t *testing.T) {
+	t.Parallel()
+
+	cfg := testConfig()
+	cfg.RateLimitMemberReqCount = 2
+	c := &Client{hub: &Hub{cfg: cfg}}
+
+	for i := range 2 {
+		if c.memberRequestsLimited() {
+			t.Fatalf("request %d was rate limited, want allowed", i+1)
+		}
+	}
+	if !c.memberRequestsLimited() {
+		t.Error("third request was allowed, want rate limited")
+	}
+
+	c.memberReqWinStart = time.Now().Add(-time.Duration(cfg.RateLimitMemberReqWindowSeconds+1) * time.Second)
+	if c.memberRequestsLimited() {
+		t.Error("request after the window elapsed was rate limited, want allowed")
+	}
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// scheduleStatusExpiry arranges for the user's custom status to be cleared at expiresAt, replacing any expiry
+// scheduled before. A nil expiresAt only cancels the previous one. The timer belongs to the Hub that handled the update;
+// other Hubs still hide the status once it has expired, since the presence store leaves out expired custom statuses.
+func (h *Hub) scheduleStatusExpiry(userID uuid.UUID, expiresAt *time.Time) {
+	h.expiryMu.Lock()
+	defer h.expiryMu.Unlock()
+
+	if timer, ok := h.statusExpiry[userID]; ok {
+		timer.Stop()
+		delete(h.statusExpiry, userID)
+	}
+	if expiresAt == nil {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(*expiresAt), func() {
+		h.expiryMu.Lock()
+		if h.statusExpiry[userID] == timer {
+			delete(h.statusExpiry, userID)
+		}
+		h.expiryMu.Unlock()
+		h.expireCustomStatus(userID)
+	})
+	h.statusExpiry[userID] = timer
+}
+
+// cancelStatusExpiry stops the user's pending custom status expiry, if any.
+func (h *Hub) cancelStatusExpiry(userID uuid.UUID) {
+	h.scheduleStatusExpiry(userID, nil)
+}
+
+// expireCustomStatus clears the user's custom status if it has expired and broadcasts their presence without it.
+func (h *Hub) expireCustomStatus(userID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cleared, err := h.presence.ClearExpiredCustomStatus(ctx, userID, time.Now())
+	if err != nil {
+		h.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to clear expired custom status")
+		return
+	}
+	if cleared {
+		h.publishStoredPresence(ctx, userID)
+	}
+}
+
+// publishStoredPresence publishes a PRESENCE_UPDATE dispatch with the user's presence as it is stored.
+func (h *Hub) publishStoredPresence(ctx context.Context, userID uuid.UUID) {
+	state, err := h.presence.GetState(ctx, userID)
+	if err != nil {
+		h.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to get presence for update")
+		return
+	}
+	h.publishState(ctx, state)
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/events"
+
+	"github.com/uncord-chat/uncord-server/internal/presence"
+)
+
+// presenceUpdates subscribes to the events channel and returns a function that reads the next PRESENCE_UPDATE.
+func presenceUpdates(t *testing.T, rdb *redis.Client) func() presence.State {
+	t.Helper()
+	sub := rdb.Subscribe(context.Background(), eventsChannel)
+	t.Cleanup(func() { _ = sub.Close() })
+	if _, err := sub.Receive(context.Background()); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	return func() presence.State {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		msg, err := sub.ReceiveMessage(ctx)
+		if err != nil {
+			t.Fatalf("receive message: %v", err)
+		}
+		var env struct {
+			Type string         `json:"t"`
+			Data presence.State `json:"d"`
+		}
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+			t.Fatalf("unmarshal payload: %v", err)
+		}
+		if env.Type != string(events.PresenceUpdate) {
+			t.Fatalf("event type = %q, want %q", env.Type, events.PresenceUpdate)
+		}
+		return env.Data
+	}
+}
+
+func TestHandlePresenceUpdateRich(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	store := presence.NewStore(rdb)
+	hub := NewHub(HubDeps{
+		RDB:       rdb,
+		Cfg:       testConfig(),
+		Presence:  store,
+		Publisher: NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{}),
+		Logger:    zerolog.Nop(),
+	})
+	next := presenceUpdates(t, rdb)
+	c := identifiedClient(hub, AllIntents)
+
+	expiresAt := time.Now().Add(100 * time.Millisecond)
+	rich := presence.Rich{
+		CustomStatus: &presence.CustomStatus{Text: "Out to lunch", Emoji: "🥪", ExpiresAt: &expiresAt},
+		Activities:   []presence.Activity{{Type: presence.ActivityListening, Name: "Radio", Details: "News"}},
+	}
+	hub.handlePresenceUpdate(c, presence.StatusDND, rich)
+
+	got := next()
+	if got.UserID != c.UserID().String() || got.Status != presence.StatusDND {
+		t.Errorf("update = %s %s, want %s dnd", got.UserID, got.Status, c.UserID())
+	}
+	if got.CustomStatus == nil || got.CustomStatus.Text != "Out to lunch" || len(got.Activities) != 1 {
+		t.Fatalf("update rich presence = %+v, want the custom status and one activity", got.Rich)
+	}
+
+	// Once the custom status expires it is cleared and the presence is broadcast again, keeping the activity.
+	got = next()
+	if got.CustomStatus != nil || len(got.Activities) != 1 || got.Status != presence.StatusDND {
+		t.Errorf("update after expiry = %+v, want dnd with the activity and no custom status", got)
+	}
+	state, err := store.GetState(context.Background(), c.UserID())
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if state.CustomStatus != nil {
+		t.Errorf("stored custom status = %+v after expiry, want none", state.CustomStatus)
+	}
+}
+
+func TestHandlePresenceUpdateInvisibleHidesRich(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	hub := NewHub(HubDeps{
+		RDB:       rdb,
+		Cfg:       testConfig(),
+		Presence:  presence.NewStore(rdb),
+		Publisher: NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{}),
+		Logger:    zerolog.Nop(),
+	})
+	next := presenceUpdates(t, rdb)
+	c := identifiedClient(hub, AllIntents)
+
+	rich := presence.Rich{Activities: []presence.Activity{{Type: presence.ActivityPlaying, Name: "Chess"}}}
+	hub.handlePresenceUpdate(c, presence.StatusInvisible, rich)
+
+	if got := next(); got.Status != presence.StatusOffline || !got.Rich.Empty() {
+		t.Errorf("update = %+v, want offline without rich presence", got)
+	}
+}
+
+func TestScheduleStatusExpiryReplacesTimer(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	hub := NewHub(HubDeps{RDB: rdb, Cfg: testConfig(), Presence: presence.NewStore(rdb), Logger: zerolog.Nop()})
+	c := identifiedClient(hub, AllIntents)
+
+	later := time.Now().Add(time.Hour)
+	hub.scheduleStatusExpiry(c.UserID(), &later)
+	hub.scheduleStatusExpiry(c.UserID(), &later)
+	if n := len(hub.statusExpiry); n != 1 {
+		t.Errorf("%d expiry timers pending, want 1", n)
+	}
+	hub.cancelStatusExpiry(c.UserID())
+	if n := len(hub.statusExpiry); n != 0 {
+		t.Errorf("%d expiry timers pending after cancel, want 0", n)
+	}
+}
@@ -12,64 +12,144 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"github.com/uncord-chat/uncord-protocol/events"
+	"github.com/uncord-chat/uncord-protocol/permissions"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/uncord-chat/uncord-server/internal/config"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
 )
 
 const eventsChannel = "uncord.gateway.events"
 
+// eventStream is the Valkey stream that carries every gateway event when stream delivery is enabled. Each entry holds
+// one JSON envelope in its streamField field.
+const (
+	eventStream = "uncord.gateway.stream"
+	streamField = "e"
+)
+
+// Delivery selects how gateway events travel from Publishers to Hubs. The zero value publishes to unsharded pub/sub
+// topics.
+type Delivery struct {
+	// Sharded publishes channel and user topics with SPUBLISH.
+	Sharded bool
+	// StreamMaxLen, when positive, appends every event to eventStream instead of publishing it, trimming the stream to
+	// approximately this many entries.
+	StreamMaxLen int64
+}
+
+// NewDelivery returns the delivery mode selected by cfg.
+func NewDelivery(cfg *config.Config) Delivery {
+	d := Delivery{Sharded: cfg.GatewayShardedPubSub}
+	if cfg.GatewayStreamDelivery() {
+		d.StreamMaxLen = int64(cfg.GatewayStreamMaxLen)
+	}
+	return d
+}
+
+// stream reports whether events are delivered through eventStream.
+func (d Delivery) stream() bool {
+	return d.StreamMaxLen > 0
+}
+
 // envelope is the JSON structure published to the gateway events channel. When Targets is non-empty, the hub delivers
-// the event only to the listed user IDs instead of broadcasting to all identified clients.
+// the event only to the listed user IDs instead of broadcasting to all identified clients. When Permission is non-zero,
+// the hub delivers the event only to users holding that permission, in the event's channel for channel-scoped events
+// and server-wide otherwise. Trace carries the W3C trace context of the publish so that the dispatch joins the trace of
+// the request that caused it.
 type envelope struct {
-	Type    string      `json:"t"`
-	Data    any         `json:"d"`
-	Targets []uuid.UUID `json:"targets,omitempty"`
+	Type       string                 `json:"t"`
+	Data       any                    `json:"d"`
+	Targets    []uuid.UUID            `json:"targets,omitempty"`
+	Permission permissions.Permission `json:"perm,omitempty"`
+	Trace      map[string]string      `json:"tc,omitempty"`
 }
 
 // job is a single gateway event waiting to be published by a worker.
 type job struct {
-	eventType events.DispatchEvent
-	data      any
-	targets   []uuid.UUID
+	eventType  events.DispatchEvent
+	data       any
+	targets    []uuid.UUID
+	permission permissions.Permission
+	trace      map[string]string
+	enqueued   time.Time
 }
 
-// Publisher serialises dispatch events and publishes them to a Valkey pub/sub channel for consumption by the gateway.
-// It maintains an internal worker pool so that callers can enqueue events without blocking.
+// Publisher serialises dispatch events and publishes them to Valkey pub/sub for consumption by the gateway, routing
+// each event to the topic of its channel, the topics of its target users, or the shared events channel. With stream
+// delivery it appends every event to the event stream instead. It maintains an internal worker pool so that callers
+// can enqueue events without blocking.
 type Publisher struct {
-	rdb     *redis.Client
-	log     zerolog.Logger
-	queue   chan job
-	workers int
-	timeout time.Duration
-	dropped atomic.Int64
-	closed  atomic.Bool
+	rdb       *redis.Client
+	delivery  Delivery
+	log       zerolog.Logger
+	queue     chan job
+	workers   int
+	timeout   time.Duration
+	published atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+	running   atomic.Bool
+	closed    atomic.Bool
+}
+
+// PublisherStats is a snapshot of the publisher's queue and its cumulative totals.
+type PublisherStats struct {
+	QueueDepth    int
+	QueueCapacity int
+	Published     int64
+	Failed        int64
+	Dropped       int64
 }
 
 // NewPublisher creates a new gateway event publisher with a bounded worker pool. The workers parameter controls how many
 // goroutines consume from the internal queue. The queueSize parameter sets the channel buffer length. The
-// publishTimeout parameter caps how long each Valkey publish may take before being abandoned.
-func NewPublisher(rdb *redis.Client, logger zerolog.Logger, workers, queueSize int, publishTimeout time.Duration) *Publisher {
+// publishTimeout parameter caps how long each Valkey publish may take before being abandoned. The delivery parameter
+// selects pub/sub or stream delivery; with sharded pub/sub the shared events channel still uses PUBLISH.
+func NewPublisher(rdb *redis.Client, logger zerolog.Logger, workers, queueSize int, publishTimeout time.Duration,
+	delivery Delivery) *Publisher {
 	return &Publisher{
-		rdb:     rdb,
-		log:     logger,
-		queue:   make(chan job, queueSize),
-		workers: workers,
-		timeout: publishTimeout,
+		rdb:      rdb,
+		delivery: delivery,
+		log:      logger,
+		queue:    make(chan job, queueSize),
+		workers:  workers,
+		timeout:  publishTimeout,
 	}
 }
 
-// Enqueue submits a gateway event for asynchronous publication. If the internal queue is full the event is dropped and a
-// warning is logged. This method is safe to call from any goroutine.
-func (p *Publisher) Enqueue(eventType events.DispatchEvent, data any) {
-	p.enqueue(job{eventType: eventType, data: data})
+// Enqueue submits a gateway event for asynchronous publication. If the internal queue is full the event is dropped and
+// a warning is logged, except with stream delivery, where it is published synchronously instead. Only the trace context
+// of ctx is retained, so a request context may be passed even though the event is published after the request
+// completes. This method is safe to call from any goroutine.
+func (p *Publisher) Enqueue(ctx context.Context, eventType events.DispatchEvent, data any) {
+	p.enqueue(ctx, job{eventType: eventType, data: data})
 }
 
 // EnqueueTargeted submits a gateway event that will only be delivered to the specified user IDs. If the internal queue
 // is full the event is dropped and a warning is logged.
-func (p *Publisher) EnqueueTargeted(eventType events.DispatchEvent, data any, targets []uuid.UUID) {
-	p.enqueue(job{eventType: eventType, data: data, targets: targets})
+func (p *Publisher) EnqueueTargeted(ctx context.Context, eventType events.DispatchEvent, data any, targets []uuid.UUID) {
+	p.enqueue(ctx, job{eventType: eventType, data: data, targets: targets})
+}
+
+// EnqueuePermitted submits a gateway event that will only be delivered to users holding perm. For channel-scoped events
+// the permission is checked in the event's channel; otherwise it is checked server-wide. If the internal queue is full
+// the event is dropped and a warning is logged.
+func (p *Publisher) EnqueuePermitted(ctx context.Context, eventType events.DispatchEvent, data any, perm permissions.Permission) {
+	p.enqueue(ctx, job{eventType: eventType, data: data, permission: perm})
 }
 
-func (p *Publisher) enqueue(j job) {
+// enqueue queues j for a worker. With stream delivery an event that cannot be queued is published on the caller's
+// goroutine, trading the caller's latency for not losing the event.
+func (p *Publisher) enqueue(ctx context.Context, j job) {
+	j.trace = tracing.Inject(ctx)
+	j.enqueued = time.Now()
 	if p.closed.Load() {
+		if p.delivery.stream() {
+			p.publishWithTimeout(j)
+			return
+		}
 		p.dropped.Add(1)
 		p.log.Warn().Str("event", string(j.eventType)).Msg("Gateway publisher closed, event dropped")
 		return
@@ -77,6 +157,10 @@ func (p *Publisher) enqueue(j job) {
 	select {
 	case p.queue <- j:
 	default:
+		if p.delivery.stream() {
+			p.publishWithTimeout(j)
+			return
+		}
 		p.dropped.Add(1)
 		p.log.Warn().Str("event", string(j.eventType)).Msg("Gateway publish queue full, event dropped")
 	}
@@ -86,6 +170,8 @@ func (p *Publisher) enqueue(j job) {
 // remaining items, and waits for all workers to finish. Returns nil on clean shutdown or context.Canceled.
 func (p *Publisher) Run(ctx context.Context) error {
 	var wg sync.WaitGroup
+	p.running.Store(true)
+	defer p.running.Store(false)
 
 	for range p.workers {
 		wg.Go(func() {
@@ -107,29 +193,124 @@ func (p *Publisher) Run(ctx context.Context) error {
 	return ctx.Err()
 }
 
-// publishWithTimeout publishes a single job with a bounded context timeout.
+// publishWithTimeout publishes a single job with a bounded context timeout. A job enqueued within a trace is published
+// under a producer span that starts when the job was enqueued, so the span covers the time spent waiting in the queue.
 func (p *Publisher) publishWithTimeout(j job) {
-	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	ctx := context.Background()
+	if j.trace != nil {
+		var span trace.Span
+		ctx, span = tracing.Tracer().Start(tracing.Extract(ctx, j.trace), "gateway publish "+string(j.eventType),
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithTimestamp(j.enqueued),
+			trace.WithAttributes(semconv.MessagingDestinationName(eventsChannel)),
+		)
+		defer span.End()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
 	defer cancel()
 
-	if err := p.publish(ctx, j.eventType, j.data, j.targets); err != nil {
+	env := envelope{Type: string(j.eventType), Data: j.data, Targets: j.targets, Permission: j.permission}
+	if err := p.publish(ctx, env); err != nil {
+		tracing.RecordError(trace.SpanFromContext(ctx), err)
+		p.failed.Add(1)
 		p.log.Warn().Err(err).Str("event", string(j.eventType)).Msg("Worker publish failed")
+		return
 	}
+	p.published.Add(1)
 }
 
-// Publish serialises the event as JSON and publishes it to the gateway events channel. This is the synchronous
+// Running reports whether the worker pool is consuming the publish queue.
+func (p *Publisher) Running() bool {
+	return p.running.Load()
+}
+
+// Stats returns the current queue depth and the cumulative number of events published, failed, and dropped by the
+// worker pool.
+func (p *Publisher) Stats() PublisherStats {
+	return PublisherStats{
+		QueueDepth:    len(p.queue),
+		QueueCapacity: cap(p.queue),
+		Published:     p.published.Load(),
+		Failed:        p.failed.Load(),
+		Dropped:       p.dropped.Load(),
+	}
+}
+
+// Publish serialises the event as JSON and publishes it to the event's channel topic, or to the gateway events channel
+// if it is not about a channel, or appends it to the event stream with stream delivery. This is the synchronous
 // low-level method used internally by the worker pool and by the gateway Hub.
 func (p *Publisher) Publish(ctx context.Context, eventType events.DispatchEvent, data any) error {
-	return p.publish(ctx, eventType, data, nil)
+	return p.publish(ctx, envelope{Type: string(eventType), Data: data})
 }
 
-func (p *Publisher) publish(ctx context.Context, eventType events.DispatchEvent, data any, targets []uuid.UUID) error {
-	payload, err := json.Marshal(envelope{Type: string(eventType), Data: data, Targets: targets})
+// publish appends env to the event stream with stream delivery. Otherwise it routes env by scope. A targeted event is
+// published once to each target's user topic, carrying only that target, so that a node hosting several of the targets
+// delivers the event to each of them exactly once. Otherwise an event whose data names a channel goes to that channel's
+// topic and any other event to the shared events channel.
+func (p *Publisher) publish(ctx context.Context, env envelope) error {
+	env.Trace = tracing.Inject(ctx)
+	data, err := json.Marshal(env.Data)
 	if err != nil {
 		return fmt.Errorf("marshal gateway event: %w", err)
 	}
-	if err := p.rdb.Publish(ctx, eventsChannel, payload).Err(); err != nil {
+	env.Data = json.RawMessage(data)
+
+	if p.delivery.stream() {
+		payload, err := json.Marshal(env)
+		if err != nil {
+			return fmt.Errorf("marshal gateway event: %w", err)
+		}
+		err = p.rdb.XAdd(ctx, &redis.XAddArgs{
+			Stream: eventStream,
+			MaxLen: p.delivery.StreamMaxLen,
+			Approx: true,
+			Values: []any{streamField, payload},
+		}).Err()
+		if err != nil {
+			return fmt.Errorf("append gateway event: %w", err)
+		}
+		return nil
+	}
+
+	if len(env.Targets) > 0 {
+		targets := env.Targets
+		pipe := p.rdb.Pipeline()
+		for _, target := range targets {
+			env.Targets = []uuid.UUID{target}
+			payload, err := json.Marshal(env)
+			if err != nil {
+				return fmt.Errorf("marshal gateway event: %w", err)
+			}
+			p.publishTo(ctx, pipe, userTopic(target), payload)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("publish gateway event: %w", err)
+		}
+		return nil
+	}
+
+	topic := eventsChannel
+	var scope eventScope
+	if json.Unmarshal(data, &scope) == nil {
+		if channelID, err := uuid.Parse(scope.ChannelID); err == nil {
+			topic = channelTopic(channelID)
+		}
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("marshal gateway event: %w", err)
+	}
+	if err := p.publishTo(ctx, p.rdb, topic, payload).Err(); err != nil {
 		return fmt.Errorf("publish gateway event: %w", err)
 	}
 	return nil
 }
+
+// publishTo publishes payload to topic, using sharded pub/sub for channel and user topics when it is enabled.
+func (p *Publisher) publishTo(ctx context.Context, c redis.Cmdable, topic string, payload []byte) *redis.IntCmd {
+	if p.delivery.Sharded && topic != eventsChannel {
+		return c.SPublish(ctx, topic, payload)
+	}
+	return c.Publish(ctx, topic, payload)
+}
@@ -11,6 +11,11 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"github.com/uncord-chat/uncord-protocol/events"
+	"github.com/uncord-chat/uncord-protocol/permissions"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestPublish_Success(t *testing.T) {
@@ -20,7 +25,7 @@ func TestPublish_Success(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	defer func() { _ = rdb.Close() }()
 
-	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second)
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
 
 	sub := rdb.Subscribe(context.Background(), eventsChannel)
 	defer func() { _ = sub.Close() }()
@@ -61,7 +66,7 @@ func TestPublish_EventType(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	defer func() { _ = rdb.Close() }()
 
-	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second)
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
 
 	sub := rdb.Subscribe(context.Background(), eventsChannel)
 	defer func() { _ = sub.Close() }()
@@ -96,7 +101,7 @@ func TestEnqueue_ProcessedByWorker(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	defer func() { _ = rdb.Close() }()
 
-	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second)
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
 
 	sub := rdb.Subscribe(context.Background(), eventsChannel)
 	defer func() { _ = sub.Close() }()
@@ -112,7 +117,7 @@ func TestEnqueue_ProcessedByWorker(t *testing.T) {
 		close(done)
 	}()
 
-	pub.Enqueue(events.MessageCreate, map[string]string{"id": "msg-1"})
+	pub.Enqueue(context.Background(), events.MessageCreate, map[string]string{"id": "msg-1"})
 
 	msg, err := sub.ReceiveMessage(context.Background())
 	if err != nil {
@@ -129,6 +134,54 @@ func TestEnqueue_ProcessedByWorker(t *testing.T) {
 
 	cancel()
 	<-done
+
+	if got := pub.Stats(); got.Published != 1 || got.Failed != 0 || got.QueueDepth != 0 {
+		t.Errorf("Stats() = %+v, want 1 published and an empty queue", got)
+	}
+}
+
+func TestEnqueuePermitted_CarriesPermission(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = rdb.Close() }()
+
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
+
+	sub := rdb.Subscribe(context.Background(), eventsChannel)
+	defer func() { _ = sub.Close() }()
+	if _, err := sub.Receive(context.Background()); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = pub.Run(ctx)
+		close(done)
+	}()
+
+	pub.EnqueuePermitted(context.Background(), AttachmentQuarantined, map[string]string{"channel_id": "c-1"}, permissions.ManageMessages)
+
+	msg, err := sub.ReceiveMessage(context.Background())
+	if err != nil {
+		t.Fatalf("receive message: %v", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if env.Permission != permissions.ManageMessages {
+		t.Errorf("permission = %d, want %d", env.Permission, permissions.ManageMessages)
+	}
+	if len(env.Targets) != 0 {
+		t.Errorf("targets = %v, want none", env.Targets)
+	}
+
+	cancel()
+	<-done
 }
 
 func TestEnqueue_DropsWhenFull(t *testing.T) {
@@ -139,17 +192,21 @@ func TestEnqueue_DropsWhenFull(t *testing.T) {
 	defer func() { _ = rdb.Close() }()
 
 	// Queue size 1, no workers started so nothing drains.
-	pub := NewPublisher(rdb, zerolog.Nop(), 1, 1, 5*time.Second)
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 1, 5*time.Second, Delivery{})
 
 	// Fill the queue.
-	pub.Enqueue(events.MessageCreate, map[string]string{"id": "msg-1"})
+	pub.Enqueue(context.Background(), events.MessageCreate, map[string]string{"id": "msg-1"})
 
 	// This should be dropped.
-	pub.Enqueue(events.MessageCreate, map[string]string{"id": "msg-2"})
+	pub.Enqueue(context.Background(), events.MessageCreate, map[string]string{"id": "msg-2"})
 
 	if dropped := pub.dropped.Load(); dropped != 1 {
 		t.Errorf("dropped = %d, want 1", dropped)
 	}
+	want := PublisherStats{QueueDepth: 1, QueueCapacity: 1, Dropped: 1}
+	if got := pub.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
 }
 
 func TestRun_DrainsOnShutdown(t *testing.T) {
@@ -159,7 +216,7 @@ func TestRun_DrainsOnShutdown(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	defer func() { _ = rdb.Close() }()
 
-	pub := NewPublisher(rdb, zerolog.Nop(), 2, 64, 5*time.Second)
+	pub := NewPublisher(rdb, zerolog.Nop(), 2, 64, 5*time.Second, Delivery{})
 
 	sub := rdb.Subscribe(context.Background(), eventsChannel)
 	defer func() { _ = sub.Close() }()
@@ -170,7 +227,7 @@ func TestRun_DrainsOnShutdown(t *testing.T) {
 
 	const count = 10
 	for i := range count {
-		pub.Enqueue(events.ChannelCreate, map[string]int{"n": i})
+		pub.Enqueue(context.Background(), events.ChannelCreate, map[string]int{"n": i})
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -205,7 +262,7 @@ func TestRun_PublishTimeout(t *testing.T) {
 	defer func() { _ = rdb.Close() }()
 
 	// Very short timeout to exercise the timeout path.
-	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 10*time.Millisecond)
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 10*time.Millisecond, Delivery{})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	done := make(chan struct{})
@@ -217,7 +274,7 @@ func TestRun_PublishTimeout(t *testing.T) {
 	// Close miniredis so publishes fail (simulates Valkey being unreachable).
 	mr.Close()
 
-	pub.Enqueue(events.MessageCreate, map[string]string{"id": "msg-1"})
+	pub.Enqueue(context.Background(), events.MessageCreate, map[string]string{"id": "msg-1"})
 
 	// No observable counter for failed publishes; a short sleep is the only option to let the worker process the item.
 	time.Sleep(100 * time.Millisecond)
@@ -230,3 +287,110 @@ func TestRun_PublishTimeout(t *testing.T) {
 		t.Fatal("Run() did not return after context cancellation")
 	}
 }
+
+func TestEnqueue_PropagatesTraceToDispatch(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = rdb.Close() }()
+
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
+	sub := rdb.Subscribe(context.Background(), eventsChannel)
+	defer func() { _ = sub.Close() }()
+	if _, err := sub.Receive(context.Background()); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = pub.Run(ctx)
+		close(done)
+	}()
+
+	reqCtx, request := otel.Tracer("test").Start(context.Background(), "request")
+	pub.Enqueue(reqCtx, events.MessageCreate, map[string]string{"id": "msg-1"})
+	request.End()
+
+	msg, err := sub.ReceiveMessage(context.Background())
+	if err != nil {
+		t.Fatalf("receive message: %v", err)
+	}
+	cancel()
+	<-done
+
+	var env envelope
+	if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if env.Trace["traceparent"] == "" {
+		t.Fatalf("envelope trace context = %v, want a traceparent", env.Trace)
+	}
+
+	cfg := testConfig()
+	hub := NewHub(HubDeps{
+		RDB:      rdb,
+		Cfg:      cfg,
+		Sessions: NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize),
+		Logger:   zerolog.Nop(),
+	})
+	hub.handlePubSubEvent(context.Background(), msg.Payload)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	publish, ok := spans["gateway publish MESSAGE_CREATE"]
+	if !ok {
+		t.Fatal("no publish span recorded")
+	}
+	dispatch, ok := spans["gateway dispatch MESSAGE_CREATE"]
+	if !ok {
+		t.Fatal("no dispatch span recorded")
+	}
+	if publish.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("publish span is not parented to the enqueuing request")
+	}
+	if dispatch.Parent().SpanID() != publish.SpanContext().SpanID() {
+		t.Error("dispatch span is not parented to the publish span")
+	}
+}
+
+func TestPublisherRunning(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = rdb.Close() }()
+
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
+	if pub.Running() {
+		t.Fatal("Running() = true before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = pub.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !pub.Running() {
+		if time.Now().After(deadline) {
+			t.Fatal("Running() = false while Run is active")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	if pub.Running() {
+		t.Error("Running() = true after Run returned")
+	}
+}
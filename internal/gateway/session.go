@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +19,42 @@ type sessionData struct {
 	UserID         string `json:"user_id"`
 	LastSeq        int64  `json:"last_seq"`
 	DisconnectedAt int64  `json:"disconnected_at"`
+
+	// Intents is a pointer so that sessions saved before intents existed resume with AllIntents rather than none.
+	Intents      *Intent     `json:"intents,omitempty"`
+	Unsubscribed []uuid.UUID `json:"unsubscribed,omitempty"`
+
+	Stream *StreamCursor `json:"stream,omitempty"`
+}
+
+// StreamMark records that the dispatch with sequence number Seq was produced by the event stream entry ID.
+type StreamMark struct {
+	Seq int64  `json:"s"`
+	ID  string `json:"id"`
+}
+
+// StreamCursor maps a session's sequence numbers to positions in the event stream, so that with stream delivery the
+// events a resuming client missed are read back from the stream rather than from a per-session replay buffer. Base is
+// a position the session had fully caught up to, and Marks lists the stream entries dispatched to it since, in order.
+type StreamCursor struct {
+	Base  StreamMark   `json:"base"`
+	Marks []StreamMark `json:"marks,omitempty"`
+}
+
+// after returns the stream position from which the events following sequence number seq can be read back, or false if
+// seq precedes the cursor.
+func (sc *StreamCursor) after(seq int64) (string, bool) {
+	if seq < sc.Base.Seq {
+		return "", false
+	}
+	id := sc.Base.ID
+	for _, m := range sc.Marks {
+		if m.Seq > seq {
+			break
+		}
+		id = m.ID
+	}
+	return id, true
 }
 
 // SessionStore manages gateway session persistence and replay buffers in Valkey. Sessions are saved when a client
@@ -28,6 +65,18 @@ type SessionStore struct {
 	log       zerolog.Logger
 	ttl       time.Duration
 	maxReplay int
+
+	appended atomic.Int64
+	evicted  atomic.Int64
+	replayed atomic.Int64
+}
+
+// ReplayStats are cumulative totals of replay buffer activity since a SessionStore was created. Evicted counts entries
+// pushed out of a full buffer; a steady rate means sessions that resume late will miss events.
+type ReplayStats struct {
+	Appended int64
+	Evicted  int64
+	Replayed int64
 }
 
 // NewSessionStore creates a new session store backed by the given Valkey client.
@@ -38,13 +87,17 @@ func NewSessionStore(rdb *redis.Client, logger zerolog.Logger, ttl time.Duration
 func sessionKey(sessionID string) string { return "gwsession:" + sessionID }
 func replayKey(sessionID string) string  { return "gwreplay:" + sessionID }
 
-// Save persists a session when a client disconnects. The session and replay buffer share the same TTL so they expire
-// together.
-func (s *SessionStore) Save(ctx context.Context, sessionID string, userID uuid.UUID, lastSeq int64) error {
+// Save persists a session, its event filter, and with stream delivery its stream cursor when a client disconnects. The
+// session and replay buffer share the same TTL so they expire together.
+func (s *SessionStore) Save(ctx context.Context, sessionID string, userID uuid.UUID, lastSeq int64,
+	subs Subscriptions, cursor *StreamCursor) error {
 	data, err := json.Marshal(sessionData{
 		UserID:         userID.String(),
 		LastSeq:        lastSeq,
 		DisconnectedAt: time.Now().Unix(),
+		Intents:        &subs.Intents,
+		Unsubscribed:   subs.Unsubscribed,
+		Stream:         cursor,
 	})
 	if err != nil {
 		return fmt.Errorf("marshal session: %w", err)
@@ -62,8 +115,11 @@ func (s *SessionStore) Save(ctx context.Context, sessionID string, userID uuid.U
 
 // LoadedSession contains the restored state for a resumed session.
 type LoadedSession struct {
-	UserID  uuid.UUID
-	LastSeq int64
+	UserID        uuid.UUID
+	LastSeq       int64
+	Subscriptions Subscriptions
+	// Stream is nil unless the session was saved with stream delivery.
+	Stream *StreamCursor
 }
 
 // Load retrieves a saved session. Returns ErrSessionNotFound if the session does not exist or has expired.
@@ -86,7 +142,11 @@ func (s *SessionStore) Load(ctx context.Context, sessionID string) (*LoadedSessi
 		return nil, fmt.Errorf("parse session user ID: %w", err)
 	}
 
-	return &LoadedSession{UserID: userID, LastSeq: sd.LastSeq}, nil
+	subs := Subscriptions{Intents: AllIntents, Unsubscribed: sd.Unsubscribed}
+	if sd.Intents != nil {
+		subs.Intents = *sd.Intents
+	}
+	return &LoadedSession{UserID: userID, LastSeq: sd.LastSeq, Subscriptions: subs, Stream: sd.Stream}, nil
 }
 
 // Delete removes a session and its replay buffer. This is called after a successful resume.
@@ -113,13 +173,17 @@ func (s *SessionStore) AppendReplay(ctx context.Context, sessionID string, seq i
 
 	key := replayKey(sessionID)
 	pipe := s.rdb.Pipeline()
-	pipe.RPush(ctx, key, entry)
+	length := pipe.RPush(ctx, key, entry)
 	pipe.LTrim(ctx, key, int64(-s.maxReplay), -1)
 	pipe.Expire(ctx, key, s.ttl)
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("append replay: %w", err)
 	}
+	s.appended.Add(1)
+	if over := length.Val() - int64(s.maxReplay); over > 0 {
+		s.evicted.Add(over)
+	}
 	return nil
 }
 
@@ -142,9 +206,21 @@ func (s *SessionStore) Replay(ctx context.Context, sessionID string, afterSeq in
 			result = append(result, entry.Payload)
 		}
 	}
+	s.replayed.Add(int64(len(result)))
 	return result, nil
 }
 
+// recordReplayed adds n events read back from the event stream for a resuming client to the replayed total.
+func (s *SessionStore) recordReplayed(n int) {
+	s.replayed.Add(int64(n))
+}
+
+// ReplayStats returns the cumulative number of replay entries appended, evicted from full buffers, and replayed to
+// resuming clients.
+func (s *SessionStore) ReplayStats() ReplayStats {
+	return ReplayStats{Appended: s.appended.Load(), Evicted: s.evicted.Load(), Replayed: s.replayed.Load()}
+}
+
 // NewSessionID generates a unique session identifier.
 func NewSessionID() string {
 	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + uuid.New().String()[:8]
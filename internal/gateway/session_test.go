@@ -30,7 +30,7 @@ func TestSessionSaveAndLoad(t *testing.T) {
 	userID := uuid.New()
 	sid := "test-session-1"
 
-	if err := store.Save(ctx, sid, userID, 42); err != nil {
+	if err := store.Save(ctx, sid, userID, 42, Subscriptions{Intents: AllIntents}, nil); err != nil {
 		t.Fatalf("Save() error = %v", err)
 	}
 
@@ -46,6 +46,46 @@ func TestSessionSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestSessionSaveAndLoadSubscriptions(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	store := NewSessionStore(rdb, zerolog.Nop(), 5*time.Minute, 100)
+	ctx := context.Background()
+
+	channelID := uuid.New()
+	want := Subscriptions{Intents: IntentMessages | IntentTyping, Unsubscribed: []uuid.UUID{channelID}}
+	if err := store.Save(ctx, "subscribed-session", uuid.New(), 1, want, nil); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := store.Load(ctx, "subscribed-session")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got := loaded.Subscriptions
+	if got.Intents != want.Intents || len(got.Unsubscribed) != 1 || got.Unsubscribed[0] != channelID {
+		t.Errorf("Subscriptions = %+v, want %+v", got, want)
+	}
+}
+
+// TestSessionLoadWithoutIntents verifies that a session saved before intents existed resumes with every intent.
+func TestSessionLoadWithoutIntents(t *testing.T) {
+	t.Parallel()
+	mr, rdb := newTestRedis(t)
+	store := NewSessionStore(rdb, zerolog.Nop(), 5*time.Minute, 100)
+
+	if err := mr.Set(sessionKey("old-session"), `{"user_id":"`+uuid.NewString()+`","last_seq":3}`); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	loaded, err := store.Load(context.Background(), "old-session")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Subscriptions.Intents != AllIntents {
+		t.Errorf("Intents = %#x, want AllIntents", uint32(loaded.Subscriptions.Intents))
+	}
+}
+
 func TestSessionLoadNotFound(t *testing.T) {
 	t.Parallel()
 	_, rdb := newTestRedis(t)
@@ -64,7 +104,7 @@ func TestSessionLoadExpired(t *testing.T) {
 	ctx := context.Background()
 
 	sid := "expiring-session"
-	if err := store.Save(ctx, sid, uuid.New(), 1); err != nil {
+	if err := store.Save(ctx, sid, uuid.New(), 1, Subscriptions{Intents: AllIntents}, nil); err != nil {
 		t.Fatalf("Save() error = %v", err)
 	}
 
@@ -83,7 +123,7 @@ func TestSessionDelete(t *testing.T) {
 	ctx := context.Background()
 
 	sid := "delete-me"
-	if err := store.Save(ctx, sid, uuid.New(), 1); err != nil {
+	if err := store.Save(ctx, sid, uuid.New(), 1, Subscriptions{Intents: AllIntents}, nil); err != nil {
 		t.Fatalf("Save() error = %v", err)
 	}
 	if err := store.Delete(ctx, sid); err != nil {
@@ -164,6 +204,11 @@ func TestSessionReplayBufferCap(t *testing.T) {
 	if len(events) != 3 {
 		t.Fatalf("Replay() returned %d events, want 3", len(events))
 	}
+
+	want := ReplayStats{Appended: 10, Evicted: 7, Replayed: 3}
+	if got := store.ReplayStats(); got != want {
+		t.Errorf("ReplayStats() = %+v, want %+v", got, want)
+	}
 }
 
 func TestSessionReplayEmpty(t *testing.T) {
@@ -187,7 +232,7 @@ func TestSessionDeleteCleansReplayBuffer(t *testing.T) {
 	ctx := context.Background()
 
 	sid := "delete-with-replay"
-	if err := store.Save(ctx, sid, uuid.New(), 5); err != nil {
+	if err := store.Save(ctx, sid, uuid.New(), 5, Subscriptions{Intents: AllIntents}, nil); err != nil {
 		t.Fatalf("Save() error = %v", err)
 	}
 	for i := int64(1); i <= 3; i++ {
@@ -0,0 +1,245 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/uncord-chat/uncord-protocol/models"
+)
+
+const (
+	// streamReadCount caps how many entries one read from the event stream returns.
+	streamReadCount = 100
+	// streamReadBlock is how long a read waits for new entries before it is issued again. It bounds how long Run
+	// takes to notice that its context was cancelled.
+	streamReadBlock = time.Second
+	// streamStart is the position before the first entry of the event stream.
+	streamStart = "0-0"
+)
+
+var (
+	errStreamTrimmed   = errors.New("event stream no longer holds the session's position")
+	errReplayTooLong   = errors.New("session missed more events than the replay limit")
+	errNoStreamSession = errors.New("session was not saved with stream delivery")
+)
+
+// runStream reads the event stream from the Hub's last position and dispatches each entry in order. On the first run
+// the Hub starts at the end of the stream, since it has no clients that could have missed earlier events.
+func (h *Hub) runStream(ctx context.Context) error {
+	h.streamMu.Lock()
+	if h.streamPos == "" {
+		pos, err := h.streamTail(ctx)
+		if err != nil {
+			h.streamMu.Unlock()
+			return fmt.Errorf("read gateway event stream: %w", err)
+		}
+		h.streamPos = pos
+	}
+	pos := h.streamPos
+	h.streamMu.Unlock()
+
+	h.subscribed.Store(true)
+	defer h.subscribed.Store(false)
+	h.log.Info().Str("position", pos).Msg("Gateway hub reading event stream")
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		streams, err := h.rdb.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{eventStream, pos},
+			Count:   streamReadCount,
+			Block:   streamReadBlock,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("read gateway event stream: %w", err)
+		}
+
+		h.streamMu.Lock()
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				h.handleEvent(ctx, streamPayload(msg), msg.ID)
+				h.streamPos = msg.ID
+			}
+		}
+		pos = h.streamPos
+		h.streamMu.Unlock()
+	}
+}
+
+// streamTail returns the ID of the newest entry in the event stream, or streamStart if the stream is empty.
+func (h *Hub) streamTail(ctx context.Context) (string, error) {
+	msgs, err := h.rdb.XRevRangeN(ctx, eventStream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return streamStart, nil
+	}
+	return msgs[0].ID, nil
+}
+
+// registerAtStreamPos registers client and starts its stream cursor at the Hub's position, so that every event it is
+// sent from then on can be read back from the stream if it resumes. The cursor is left unset if Run has not yet found
+// its position, in which case the session cannot be resumed.
+func (h *Hub) registerAtStreamPos(client *Client) error {
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+
+	if h.streamPos != "" {
+		client.mu.Lock()
+		client.stream = &StreamCursor{Base: StreamMark{Seq: client.currentSeq(), ID: h.streamPos}}
+		client.mu.Unlock()
+	}
+	return h.register(client)
+}
+
+// resumeFromStream restores a session saved with stream delivery, reading the events the client missed after seq
+// back from the event stream and renumbering them from data.Seq. Most of the stream is read while dispatch continues;
+// the entries the Hub dispatches meanwhile are read with dispatch held, so that the client is registered without a live
+// event overtaking a replayed one or falling between the two. It returns the number of events replayed.
+func (h *Hub) resumeFromStream(ctx context.Context, client *Client, userID uuid.UUID, data models.ResumeData,
+	session *LoadedSession) (int, error) {
+	if session.Stream == nil {
+		return 0, errNoStreamSession
+	}
+	from, ok := session.Stream.after(data.Seq)
+	if !ok {
+		return 0, ErrInvalidSequence
+	}
+	retained, err := h.streamRetains(ctx, from)
+	if err != nil {
+		return 0, fmt.Errorf("read gateway event stream: %w", err)
+	}
+	if !retained {
+		return 0, errStreamTrimmed
+	}
+
+	client.mu.Lock()
+	client.userID = userID
+	client.sessionID = data.SessionID
+	client.seq.Store(data.Seq)
+	client.setSubscriptionsLocked(session.Subscriptions)
+	client.stream = &StreamCursor{Base: StreamMark{Seq: data.Seq, ID: from}}
+	client.identified = true
+	client.mu.Unlock()
+
+	registered := false
+	defer func() {
+		if !registered {
+			client.mu.Lock()
+			client.identified = false
+			client.stream = nil
+			client.mu.Unlock()
+		}
+	}()
+
+	h.streamMu.Lock()
+	to := h.streamPos
+	h.streamMu.Unlock()
+
+	var frames [][]byte
+	if from, err = h.replayStream(ctx, client, from, to, &frames); err != nil {
+		return 0, err
+	}
+
+	h.streamMu.Lock()
+	defer h.streamMu.Unlock()
+	if _, err := h.replayStream(ctx, client, from, h.streamPos, &frames); err != nil {
+		return 0, err
+	}
+	if err := h.register(client); err != nil {
+		return 0, err
+	}
+	registered = true
+
+	for _, frame := range frames {
+		client.enqueue(frame)
+	}
+	h.sessions.recordReplayed(len(frames))
+	return len(frames), nil
+}
+
+// streamRetains reports whether the event stream still holds every entry after id. An entry that is still present
+// proves it; the start of the stream is only known to be intact while the stream has not reached its length cap.
+func (h *Hub) streamRetains(ctx context.Context, id string) (bool, error) {
+	if id == streamStart {
+		n, err := h.rdb.XLen(ctx, eventStream).Result()
+		return n < int64(h.cfg.GatewayStreamMaxLen), err
+	}
+	msgs, err := h.rdb.XRangeN(ctx, eventStream, id, id, 1).Result()
+	return len(msgs) == 1, err
+}
+
+// replayStream appends to frames the dispatches client would have been sent for the event stream entries after from,
+// up to and including to, numbering them from the client's current sequence number. Ephemeral events are skipped as
+// they are never replayed. It returns the ID of the last entry read.
+func (h *Hub) replayStream(ctx context.Context, client *Client, from, to string, frames *[][]byte) (string, error) {
+	for streamIDLess(from, to) {
+		msgs, err := h.rdb.XRangeN(ctx, eventStream, "("+from, to, streamReadCount).Result()
+		if err != nil {
+			return from, fmt.Errorf("read gateway event stream: %w", err)
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, msg := range msgs {
+			from = msg.ID
+			ev, err := decodeEvent(streamPayload(msg))
+			if err != nil || ephemeralEvent(ev.eventType) || !ev.addressedTo(client) {
+				continue
+			}
+			if len(h.permitted(ctx, ev, []*Client{client})) == 0 {
+				continue
+			}
+			if len(*frames) >= h.cfg.GatewayReplayBufferSize {
+				return from, errReplayTooLong
+			}
+			seq := client.nextSeq()
+			frame, err := NewDispatchFrame(client.encoding, seq, ev.eventType, ev.data)
+			if err != nil {
+				h.log.Warn().Err(err).Msg("Failed to build replay frame")
+				continue
+			}
+			*frames = append(*frames, frame)
+			client.markStream(seq, msg.ID)
+		}
+	}
+	return from, nil
+}
+
+// streamPayload returns the envelope held by an event stream entry.
+func streamPayload(msg redis.XMessage) string {
+	payload, _ := msg.Values[streamField].(string)
+	return payload
+}
+
+// streamIDLess reports whether stream entry ID a sorts before b. IDs are "<milliseconds>-<sequence>"; an ID that does
+// not parse sorts first.
+func streamIDLess(a, b string) bool {
+	aMS, aSeq := parseStreamID(a)
+	bMS, bSeq := parseStreamID(b)
+	if aMS != bMS {
+		return aMS < bMS
+	}
+	return aSeq < bSeq
+}
+
+func parseStreamID(id string) (ms, seq uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ = strconv.ParseUint(msPart, 10, 64)
+	seq, _ = strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
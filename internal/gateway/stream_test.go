@@ -0,0 +1,293 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/events"
+	"github.com/uncord-chat/uncord-protocol/models"
+)
+
+// streamHub returns a Hub and Publisher using stream delivery.
+func streamHub(t *testing.T, rdb *redis.Client) (*Hub, *Publisher) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.GatewayDelivery = "stream"
+	cfg.GatewayStreamMaxLen = 1000
+	hub := NewHub(HubDeps{
+		RDB:      rdb,
+		Cfg:      cfg,
+		Sessions: NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize),
+		Logger:   zerolog.Nop(),
+	})
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, NewDelivery(cfg))
+	return hub, pub
+}
+
+// runHub starts hub.Run and returns a function that stops it and waits for it to return.
+func runHub(t *testing.T, hub *Hub) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = hub.Run(ctx)
+	}()
+	waitFor(t, "hub to start reading", hub.Subscribed)
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+// publishStatus publishes a presence update whose status identifies it.
+func publishStatus(t *testing.T, pub *Publisher, status string) {
+	t.Helper()
+	data := models.PresenceUpdateData{UserID: uuid.NewString(), Status: status}
+	if err := pub.Publish(context.Background(), events.PresenceUpdate, data); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+}
+
+// readStatuses reads n sequenced PRESENCE_UPDATE frames from c and returns their sequence numbers and statuses.
+func readStatuses(t *testing.T, c *Client, n int) ([]int64, []string) {
+	t.Helper()
+	var seqs []int64
+	var statuses []string
+	for range n {
+		select {
+		case msg := <-c.send:
+			var f events.Frame
+			if err := json.Unmarshal(msg.data, &f); err != nil {
+				t.Fatalf("unmarshal frame: %v", err)
+			}
+			var data models.PresenceUpdateData
+			if err := json.Unmarshal(f.Data, &data); err != nil {
+				t.Fatalf("unmarshal presence: %v", err)
+			}
+			if f.Seq == nil {
+				t.Fatalf("frame %s has no sequence number", msg.data)
+			}
+			seqs = append(seqs, *f.Seq)
+			statuses = append(statuses, data.Status)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d frames, want %d", len(seqs), n)
+		}
+	}
+	return seqs, statuses
+}
+
+func TestPublisherStreamDelivery(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 1, 5*time.Second, Delivery{StreamMaxLen: 1000})
+
+	// A targeted event is appended once with all of its targets rather than once per target.
+	alice, bob := uuid.New(), uuid.New()
+	pub.EnqueueTargeted(ctx, events.MessageCreate, map[string]string{"id": "1"}, []uuid.UUID{alice, bob})
+	// The workers are not running, so the queue is now full and later events are published synchronously.
+	pub.Enqueue(ctx, events.MessageCreate, map[string]string{"id": "2"})
+	pub.Enqueue(ctx, events.MessageCreate, map[string]string{"id": "3"})
+
+	if stats := pub.Stats(); stats.Dropped != 0 || stats.Published != 2 {
+		t.Errorf("Stats() = %+v, want 2 published and none dropped", stats)
+	}
+	msgs, err := rdb.XRange(ctx, eventStream, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("stream holds %d entries, want 2", len(msgs))
+	}
+	var env envelope
+	if err := json.Unmarshal([]byte(streamPayload(msgs[0])), &env); err != nil {
+		t.Fatalf("unmarshal envelope: %v", err)
+	}
+	if env.Type != string(events.MessageCreate) || len(env.Targets) != 0 {
+		t.Errorf("first entry = %+v, want an untargeted %s", env, events.MessageCreate)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_ = pub.Run(runCtx)
+	msgs, err = rdb.XRange(ctx, eventStream, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	if err := json.Unmarshal([]byte(streamPayload(msgs[len(msgs)-1])), &env); err != nil {
+		t.Fatalf("unmarshal envelope: %v", err)
+	}
+	if len(msgs) != 3 || len(env.Targets) != 2 {
+		t.Errorf("after draining, stream holds %d entries, last with %d targets; want 3 entries, 2 targets",
+			len(msgs), len(env.Targets))
+	}
+}
+
+func TestHubStreamDeliversAcrossRestart(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	hub, pub := streamHub(t, rdb)
+
+	stop := runHub(t, hub)
+	c := identifiedClient(hub, AllIntents)
+	if err := hub.registerAtStreamPos(c); err != nil {
+		t.Fatalf("registerAtStreamPos() error = %v", err)
+	}
+	publishStatus(t, pub, "first")
+	if _, got := readStatuses(t, c, 1); got[0] != "first" {
+		t.Fatalf("received %q, want first", got[0])
+	}
+
+	// Events published while the Hub is not reading are delivered once it resumes, in order.
+	stop()
+	publishStatus(t, pub, "second")
+	publishStatus(t, pub, "third")
+	runHub(t, hub)
+
+	seqs, got := readStatuses(t, c, 2)
+	if got[0] != "second" || got[1] != "third" || seqs[0] != 2 || seqs[1] != 3 {
+		t.Errorf("received %v with sequences %v, want [second third] with [2 3]", got, seqs)
+	}
+}
+
+func TestResumeFromStream(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	hub, pub := streamHub(t, rdb)
+	runHub(t, hub)
+
+	c := identifiedClient(hub, AllIntents)
+	if err := hub.registerAtStreamPos(c); err != nil {
+		t.Fatalf("registerAtStreamPos() error = %v", err)
+	}
+	for _, status := range []string{"a", "b", "c"} {
+		publishStatus(t, pub, status)
+	}
+	readStatuses(t, c, 3)
+	hub.unregister(c)
+
+	// Events published after the disconnect are read back from the stream too.
+	publishStatus(t, pub, "d")
+	publishStatus(t, pub, "e")
+	waitFor(t, "hub to read the new events", func() bool { return hub.DispatchStats().Events == 5 })
+
+	session, err := hub.sessions.Load(ctx, c.SessionID())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if session.Stream == nil || len(session.Stream.Marks) != 3 {
+		t.Fatalf("session stream cursor = %+v, want 3 marks", session.Stream)
+	}
+
+	// The client only acknowledged the first event, so everything after it is replayed and renumbered from there.
+	resumed := &Client{hub: hub, send: make(chan outbound, 256), done: make(chan struct{}), log: zerolog.Nop()}
+	data := models.ResumeData{SessionID: c.SessionID(), Seq: 1}
+	n, err := hub.resumeFromStream(ctx, resumed, c.UserID(), data, session)
+	if err != nil {
+		t.Fatalf("resumeFromStream() error = %v", err)
+	}
+	if n != 4 {
+		t.Fatalf("resumeFromStream() replayed %d events, want 4", n)
+	}
+	seqs, got := readStatuses(t, resumed, n)
+	if got[0] != "b" || got[3] != "e" || seqs[0] != 2 || seqs[3] != 5 {
+		t.Errorf("replayed %v with sequences %v, want [b c d e] with [2 3 4 5]", got, seqs)
+	}
+
+	// Live events follow the replay without repeating it.
+	publishStatus(t, pub, "f")
+	if seqs, got := readStatuses(t, resumed, 1); got[0] != "f" || seqs[0] != 6 {
+		t.Errorf("live event = %q with sequence %d, want f with 6", got[0], seqs[0])
+	}
+	select {
+	case msg := <-resumed.send:
+		t.Errorf("received an unexpected frame: %s", msg.data)
+	default:
+	}
+}
+
+func TestResumeFromStreamRejectsLostPosition(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	hub, _ := streamHub(t, rdb)
+
+	tests := []struct {
+		name    string
+		session *LoadedSession
+		seq     int64
+		want    error
+	}{
+		{"no cursor", &LoadedSession{}, 0, errNoStreamSession},
+		{"sequence before cursor", &LoadedSession{Stream: &StreamCursor{Base: StreamMark{Seq: 5, ID: "1-0"}}}, 3,
+			ErrInvalidSequence},
+		{"trimmed", &LoadedSession{Stream: &StreamCursor{Base: StreamMark{Seq: 1, ID: "1-0"}}}, 1, errStreamTrimmed},
+	}
+	for _, tt := range tests {
+		c := &Client{hub: hub, send: make(chan outbound, 1), done: make(chan struct{}), log: zerolog.Nop()}
+		data := models.ResumeData{SessionID: "s", Seq: tt.seq}
+		if _, err := hub.resumeFromStream(ctx, c, uuid.New(), data, tt.session); !errors.Is(err, tt.want) {
+			t.Errorf("%s: resumeFromStream() error = %v, want %v", tt.name, err, tt.want)
+		}
+		if c.IsIdentified() {
+			t.Errorf("%s: client is identified after a failed resume", tt.name)
+		}
+	}
+}
+
+func TestStreamCursorAfter(t *testing.T) {
+	t.Parallel()
+
+	cursor := &StreamCursor{
+		Base:  StreamMark{Seq: 1, ID: "10-0"},
+		Marks: []StreamMark{{Seq: 2, ID: "11-0"}, {Seq: 4, ID: "13-0"}},
+	}
+	tests := []struct {
+		seq    int64
+		wantID string
+		wantOK bool
+	}{
+		{0, "", false},
+		{1, "10-0", true},
+		{2, "11-0", true},
+		{3, "11-0", true},
+		{4, "13-0", true},
+		{9, "13-0", true},
+	}
+	for _, tt := range tests {
+		id, ok := cursor.after(tt.seq)
+		if id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("after(%d) = %q, %v; want %q, %v", tt.seq, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestStreamIDLess(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"0-0", "1-0", true},
+		{"1-0", "1-1", true},
+		{"9-5", "10-0", true},
+		{"10-0", "9-5", false},
+		{"1-1", "1-1", false},
+	}
+	for _, tt := range tests {
+		if got := streamIDLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("streamIDLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
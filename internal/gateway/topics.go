@@ -0,0 +1,326 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/permissions"
+
+	"github.com/uncord-chat/uncord-server/internal/permission"
+)
+
+// Events are routed to pub/sub topics by scope: an event about a channel goes to that channel's topic, an event
+// addressed to specific users goes to each user's topic, and everything else goes to eventsChannel. A Hub subscribes to
+// eventsChannel, to the topic of every user connected to it, and to the topic of every channel one of those users can
+// view, so that a node only receives the traffic its own clients may be sent.
+const (
+	channelTopicPrefix = "uncord.gateway.channel."
+	userTopicPrefix    = "uncord.gateway.user."
+)
+
+// topicRefreshDelay is how long the Hub waits after a permission or channel change before recomputing its channel
+// subscriptions. Changes arriving within the delay are handled together, and the delay gives the permission cache
+// invalidation that accompanies the change time to land before visibility is resolved again.
+const topicRefreshDelay = 250 * time.Millisecond
+
+// topicRefreshTimeout bounds one recomputation of the Hub's channel subscriptions.
+const topicRefreshTimeout = 30 * time.Second
+
+func channelTopic(channelID uuid.UUID) string {
+	return channelTopicPrefix + channelID.String()
+}
+
+func userTopic(userID uuid.UUID) string {
+	return userTopicPrefix + userID.String()
+}
+
+// topicRouter tracks the pub/sub topics a Hub's local clients need and keeps the Hub's subscription in step with them.
+// A channel topic stays subscribed while at least one local user can view the channel; a user topic stays subscribed
+// while the user has at least one connection to the Hub.
+type topicRouter struct {
+	mu      sync.Mutex
+	sharded bool
+	// allChannels subscribes to every channel topic by pattern. It is set when the Hub has no permission resolver and
+	// so cannot tell which channels its users can view. Pattern subscriptions do not exist for sharded pub/sub.
+	allChannels bool
+	sub         *redis.PubSub
+	users       map[uuid.UUID]map[uuid.UUID]struct{}
+	channels    map[uuid.UUID]int
+	log         zerolog.Logger
+}
+
+func newTopicRouter(sharded, allChannels bool, logger zerolog.Logger) *topicRouter {
+	return &topicRouter{
+		sharded:     sharded,
+		allChannels: allChannels,
+		users:       make(map[uuid.UUID]map[uuid.UUID]struct{}),
+		channels:    make(map[uuid.UUID]int),
+		log:         logger,
+	}
+}
+
+// attach subscribes sub to every topic currently needed and directs later changes to it.
+func (r *topicRouter) attach(ctx context.Context, sub *redis.PubSub) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sub = sub
+	if r.allChannels {
+		if err := sub.PSubscribe(ctx, channelTopicPrefix+"*"); err != nil {
+			return err
+		}
+	}
+	topics := make([]string, 0, len(r.users)+len(r.channels))
+	for userID := range r.users {
+		topics = append(topics, userTopic(userID))
+	}
+	for channelID := range r.channels {
+		topics = append(topics, channelTopic(channelID))
+	}
+	if len(topics) == 0 {
+		return nil
+	}
+	if r.sharded {
+		return sub.SSubscribe(ctx, topics...)
+	}
+	return sub.Subscribe(ctx, topics...)
+}
+
+// detach stops directing changes to the subscription passed to attach. The topics themselves are kept so that the next
+// attach restores them.
+func (r *topicRouter) detach() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sub = nil
+}
+
+// addUser subscribes to userID's topic. The user's channels are added separately with setChannels once they are
+// resolved.
+func (r *topicRouter) addUser(userID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; ok {
+		return
+	}
+	r.users[userID] = make(map[uuid.UUID]struct{})
+	r.subscribeLocked([]string{userTopic(userID)})
+}
+
+// removeUser unsubscribes from userID's topic and from the topics of channels no other local user can view.
+func (r *topicRouter) removeUser(userID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	visible, ok := r.users[userID]
+	if !ok {
+		return
+	}
+	delete(r.users, userID)
+
+	topics := []string{userTopic(userID)}
+	for channelID := range visible {
+		if r.releaseChannelLocked(channelID) {
+			topics = append(topics, channelTopic(channelID))
+		}
+	}
+	r.unsubscribeLocked(topics)
+}
+
+// setChannels replaces the channels userID can view, subscribing to channels that gained their first local viewer and
+// unsubscribing from channels that lost their last. It does nothing if userID has no connection to the Hub, which
+// happens when the user disconnects while their channels are being resolved.
+func (r *topicRouter) setChannels(userID uuid.UUID, channelIDs []uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, ok := r.users[userID]
+	if !ok {
+		return
+	}
+
+	visible := make(map[uuid.UUID]struct{}, len(channelIDs))
+	var subscribe, unsubscribe []string
+	for _, channelID := range channelIDs {
+		if _, dup := visible[channelID]; dup {
+			continue
+		}
+		visible[channelID] = struct{}{}
+		if _, had := previous[channelID]; had {
+			continue
+		}
+		r.channels[channelID]++
+		if r.channels[channelID] == 1 {
+			subscribe = append(subscribe, channelTopic(channelID))
+		}
+	}
+	for channelID := range previous {
+		if _, kept := visible[channelID]; !kept && r.releaseChannelLocked(channelID) {
+			unsubscribe = append(unsubscribe, channelTopic(channelID))
+		}
+	}
+	r.users[userID] = visible
+
+	r.subscribeLocked(subscribe)
+	r.unsubscribeLocked(unsubscribe)
+}
+
+// localUsers returns the users with at least one connection to the Hub.
+func (r *topicRouter) localUsers() []uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := make([]uuid.UUID, 0, len(r.users))
+	for userID := range r.users {
+		users = append(users, userID)
+	}
+	return users
+}
+
+// watchesChannel reports whether the Hub subscribes to channelID's topic.
+func (r *topicRouter) watchesChannel(channelID uuid.UUID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.allChannels || r.channels[channelID] > 0
+}
+
+// releaseChannelLocked drops one local viewer of channelID and reports whether it was the last. The caller must hold
+// r.mu.
+func (r *topicRouter) releaseChannelLocked(channelID uuid.UUID) bool {
+	r.channels[channelID]--
+	if r.channels[channelID] > 0 {
+		return false
+	}
+	delete(r.channels, channelID)
+	return true
+}
+
+// subscribeLocked subscribes to topics if the router is attached. A failed subscription is still recorded by the
+// client library and retried when it reconnects, so failures are only logged. The caller must hold r.mu.
+func (r *topicRouter) subscribeLocked(topics []string) {
+	if r.sub == nil || len(topics) == 0 {
+		return
+	}
+	var err error
+	if r.sharded {
+		err = r.sub.SSubscribe(context.Background(), topics...)
+	} else {
+		err = r.sub.Subscribe(context.Background(), topics...)
+	}
+	if err != nil {
+		r.log.Warn().Err(err).Int("topics", len(topics)).Msg("Failed to subscribe to gateway topics")
+	}
+}
+
+// unsubscribeLocked unsubscribes from topics if the router is attached. The caller must hold r.mu.
+func (r *topicRouter) unsubscribeLocked(topics []string) {
+	if r.sub == nil || len(topics) == 0 {
+		return
+	}
+	var err error
+	if r.sharded {
+		err = r.sub.SUnsubscribe(context.Background(), topics...)
+	} else {
+		err = r.sub.Unsubscribe(context.Background(), topics...)
+	}
+	if err != nil {
+		r.log.Warn().Err(err).Int("topics", len(topics)).Msg("Failed to unsubscribe from gateway topics")
+	}
+}
+
+// refreshTopics resolves the channels each of userIDs can view and updates the Hub's channel subscriptions to match.
+func (h *Hub) refreshTopics(ctx context.Context, userIDs ...uuid.UUID) {
+	if h.topics.allChannels || len(userIDs) == 0 {
+		return
+	}
+
+	chs, err := h.channels.List(ctx)
+	if err != nil {
+		h.log.Warn().Err(err).Msg("Failed to list channels for gateway topics")
+		return
+	}
+	channelIDs := make([]uuid.UUID, len(chs))
+	for i := range chs {
+		channelIDs[i] = chs[i].ID
+	}
+
+	for _, userID := range userIDs {
+		allowed, err := h.resolver.FilterPermitted(ctx, userID, channelIDs, permissions.ViewChannels)
+		if err != nil {
+			h.log.Warn().Err(err).Stringer("user_id", userID).Msg("Failed to resolve visible channels for gateway topics")
+			continue
+		}
+		visible := make([]uuid.UUID, 0, len(channelIDs))
+		for i, ok := range allowed {
+			if ok {
+				visible = append(visible, channelIDs[i])
+			}
+		}
+		h.topics.setChannels(userID, visible)
+	}
+}
+
+// scheduleTopicRefresh queues a recomputation of the channel subscriptions of userIDs, or of every local user when all
+// is true, to run after topicRefreshDelay.
+func (h *Hub) scheduleTopicRefresh(all bool, userIDs ...uuid.UUID) {
+	if h.topics.allChannels {
+		return
+	}
+
+	h.refreshMu.Lock()
+	defer h.refreshMu.Unlock()
+
+	if all {
+		h.refreshAll = true
+	}
+	for _, userID := range userIDs {
+		h.refreshUsers[userID] = struct{}{}
+	}
+	if h.refreshTimer == nil {
+		h.refreshTimer = time.AfterFunc(topicRefreshDelay, h.runTopicRefresh)
+	}
+}
+
+// runTopicRefresh performs the recomputation queued by scheduleTopicRefresh.
+func (h *Hub) runTopicRefresh() {
+	h.refreshMu.Lock()
+	all, pending := h.refreshAll, h.refreshUsers
+	h.refreshAll, h.refreshUsers, h.refreshTimer = false, make(map[uuid.UUID]struct{}), nil
+	h.refreshMu.Unlock()
+
+	var userIDs []uuid.UUID
+	if all {
+		userIDs = h.topics.localUsers()
+	} else {
+		userIDs = make([]uuid.UUID, 0, len(pending))
+		for userID := range pending {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), topicRefreshTimeout)
+	defer cancel()
+	h.refreshTopics(ctx, userIDs...)
+}
+
+// handleInvalidation schedules a topic refresh for the users whose permissions a cache invalidation message affects.
+// Invalidating a channel or every entry may change what any local user can view.
+func (h *Hub) handleInvalidation(payload string) {
+	var msg permission.InvalidationMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		h.log.Debug().Err(err).Msg("Invalid permission invalidation message")
+		return
+	}
+	if msg.UserID != nil && !msg.All {
+		h.scheduleTopicRefresh(false, *msg.UserID)
+		return
+	}
+	if msg.All || msg.ChannelID != nil {
+		h.scheduleTopicRefresh(true)
+	}
+}
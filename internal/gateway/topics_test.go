@@ -0,0 +1,366 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/uncord-chat/uncord-protocol/events"
+	"github.com/uncord-chat/uncord-protocol/permissions"
+
+	"github.com/uncord-chat/uncord-server/internal/channel"
+	"github.com/uncord-chat/uncord-server/internal/permission"
+)
+
+// fakePermStore grants every user ViewChannels except in the channels they have been denied.
+type fakePermStore struct {
+	mu     sync.Mutex
+	denied map[uuid.UUID][]uuid.UUID
+}
+
+// deny removes userID's access to channelID. Passing uuid.Nil for both restores every user's access everywhere.
+func (s *fakePermStore) deny(channelID, userID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if channelID == uuid.Nil {
+		s.denied = nil
+		return
+	}
+	if s.denied == nil {
+		s.denied = make(map[uuid.UUID][]uuid.UUID)
+	}
+	s.denied[channelID] = append(s.denied[channelID], userID)
+}
+
+func (s *fakePermStore) IsOwner(context.Context, uuid.UUID) (bool, error) { return false, nil }
+func (s *fakePermStore) RolePermissions(context.Context, uuid.UUID) ([]permission.RolePermEntry, error) {
+	return []permission.RolePermEntry{{RoleID: uuid.New(), Permissions: permissions.ViewChannels}}, nil
+}
+func (s *fakePermStore) ChannelInfo(_ context.Context, channelID uuid.UUID) (permission.ChannelInfo, error) {
+	return permission.ChannelInfo{ID: channelID}, nil
+}
+func (s *fakePermStore) Overrides(
+	_ context.Context, targetType permission.TargetType, targetID uuid.UUID,
+) ([]permission.Override, error) {
+	if targetType != permission.TargetChannel {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var overrides []permission.Override
+	for _, userID := range s.denied[targetID] {
+		overrides = append(overrides, permission.Override{
+			PrincipalType: permission.PrincipalUser,
+			PrincipalID:   userID,
+			Deny:          permissions.ViewChannels,
+		})
+	}
+	return overrides, nil
+}
+
+// topicHub returns a running Hub with a permission resolver backed by store, over the given channels.
+func topicHub(t testing.TB, rdb *redis.Client, store permission.Store, channelIDs []uuid.UUID) *Hub {
+	t.Helper()
+	chs := make([]channel.Channel, len(channelIDs))
+	for i, id := range channelIDs {
+		chs[i] = channel.Channel{ID: id}
+	}
+	cfg := testConfig()
+	hub := NewHub(HubDeps{
+		RDB:      rdb,
+		Cfg:      cfg,
+		Sessions: NewSessionStore(rdb, zerolog.Nop(), cfg.GatewaySessionTTL, cfg.GatewayReplayBufferSize),
+		Resolver: permission.NewResolver(store, permission.NewValkeyCache(rdb, zerolog.Nop()), zerolog.Nop()),
+		Channels: &fakeChannelRepo{channels: chs},
+		Logger:   zerolog.Nop(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = hub.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	waitFor(t, "hub subscription", hub.Subscribed)
+	return hub
+}
+
+// connectUser registers an identified client for a new user on hub and resolves its channel topics, as Identify does.
+func connectUser(t testing.TB, hub *Hub) *Client {
+	t.Helper()
+	c := identifiedClient(hub, AllIntents)
+	if err := hub.register(c); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	hub.refreshTopics(context.Background(), c.UserID())
+	return c
+}
+
+// waitFor polls cond until it holds, failing the test after two seconds.
+func waitFor(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// subscribedTo reports whether anything subscribes to topic on mr.
+func subscribedTo(mr *miniredis.Miniredis, topic string) bool {
+	return slices.Contains(mr.PubSubChannels(""), topic)
+}
+
+func TestPublishRoutesByScope(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
+
+	channelID, alice, bob := uuid.New(), uuid.New(), uuid.New()
+	sub := rdb.Subscribe(context.Background(), eventsChannel, channelTopic(channelID), userTopic(alice), userTopic(bob))
+	defer func() { _ = sub.Close() }()
+	for range 4 {
+		if _, err := sub.Receive(context.Background()); err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	if err := pub.Publish(ctx, events.MessageCreate, map[string]string{"channel_id": channelID.String()}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := pub.Publish(ctx, events.RoleCreate, map[string]string{"id": uuid.NewString()}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := pub.publish(ctx, envelope{Type: string(events.MessageAck), Data: map[string]string{
+		"channel_id": channelID.String(),
+	}, Targets: []uuid.UUID{alice, bob}}); err != nil {
+		t.Fatalf("publish() error = %v", err)
+	}
+
+	want := []struct {
+		topic  string
+		typ    events.DispatchEvent
+		target uuid.UUID
+	}{
+		{channelTopic(channelID), events.MessageCreate, uuid.Nil},
+		{eventsChannel, events.RoleCreate, uuid.Nil},
+		{userTopic(alice), events.MessageAck, alice},
+		{userTopic(bob), events.MessageAck, bob},
+	}
+	for _, w := range want {
+		msg, err := sub.ReceiveMessage(ctx)
+		if err != nil {
+			t.Fatalf("receive message: %v", err)
+		}
+		var env envelope
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+			t.Fatalf("unmarshal payload: %v", err)
+		}
+		if msg.Channel != w.topic || env.Type != string(w.typ) {
+			t.Errorf("received %s on %q, want %s on %q", env.Type, msg.Channel, w.typ, w.topic)
+		}
+		if w.target != uuid.Nil && (len(env.Targets) != 1 || env.Targets[0] != w.target) {
+			t.Errorf("targets on %q = %v, want only %v", msg.Channel, env.Targets, w.target)
+		}
+	}
+}
+
+func TestTopicRouterCountsViewers(t *testing.T) {
+	t.Parallel()
+
+	r := newTopicRouter(false, false, zerolog.Nop())
+	alice, bob := uuid.New(), uuid.New()
+	general, secret := uuid.New(), uuid.New()
+
+	r.setChannels(alice, []uuid.UUID{general})
+	if r.watchesChannel(general) {
+		t.Fatal("setChannels() applied to a user that is not connected")
+	}
+
+	r.addUser(alice)
+	r.addUser(bob)
+	r.setChannels(alice, []uuid.UUID{general, secret})
+	r.setChannels(bob, []uuid.UUID{general, general})
+	if !r.watchesChannel(general) || !r.watchesChannel(secret) {
+		t.Fatal("channels visible to a local user are not watched")
+	}
+
+	r.removeUser(alice)
+	if r.watchesChannel(secret) {
+		t.Error("secret is still watched after its only viewer left")
+	}
+	if !r.watchesChannel(general) {
+		t.Error("general is no longer watched while bob can still view it")
+	}
+
+	r.setChannels(bob, nil)
+	if r.watchesChannel(general) {
+		t.Error("general is still watched after bob lost access")
+	}
+	if got := r.localUsers(); len(got) != 1 || got[0] != bob {
+		t.Errorf("localUsers() = %v, want [%v]", got, bob)
+	}
+}
+
+func TestHubSubscribesToVisibleTopics(t *testing.T) {
+	t.Parallel()
+	mr, rdb := newTestRedis(t)
+
+	visible, hidden := uuid.New(), uuid.New()
+	store := &fakePermStore{}
+	hub := topicHub(t, rdb, store, []uuid.UUID{visible, hidden})
+
+	c := identifiedClient(hub, AllIntents)
+	store.deny(hidden, c.UserID())
+	if err := hub.register(c); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	hub.refreshTopics(context.Background(), c.UserID())
+
+	waitFor(t, "user and channel topics", func() bool {
+		return subscribedTo(mr, userTopic(c.UserID())) && subscribedTo(mr, channelTopic(visible))
+	})
+	if subscribedTo(mr, channelTopic(hidden)) {
+		t.Error("hub subscribed to a channel its only user cannot view")
+	}
+
+	hub.unregister(c)
+	waitFor(t, "topics to be released", func() bool {
+		return !subscribedTo(mr, userTopic(c.UserID())) && !subscribedTo(mr, channelTopic(visible))
+	})
+}
+
+func TestHubRefreshesTopicsOnInvalidation(t *testing.T) {
+	t.Parallel()
+	mr, rdb := newTestRedis(t)
+
+	channelID := uuid.New()
+	store := &fakePermStore{}
+	hub := topicHub(t, rdb, store, []uuid.UUID{channelID})
+
+	c := identifiedClient(hub, AllIntents)
+	store.deny(channelID, c.UserID())
+	if err := hub.register(c); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	hub.refreshTopics(context.Background(), c.UserID())
+	if hub.topics.watchesChannel(channelID) {
+		t.Fatal("hub watches a channel its only user cannot view")
+	}
+
+	// Granting access invalidates the user's cached permissions, which the hub hears on the invalidation channel.
+	store.deny(uuid.Nil, uuid.Nil)
+	if err := permission.NewPublisher(rdb).InvalidateChannel(context.Background(), channelID); err != nil {
+		t.Fatalf("InvalidateChannel() error = %v", err)
+	}
+	// No permission.Subscriber runs in this test, so clear the cache it would have cleared.
+	if err := permission.NewValkeyCache(rdb, zerolog.Nop()).DeleteAll(context.Background()); err != nil {
+		t.Fatalf("DeleteAll() error = %v", err)
+	}
+	waitFor(t, "the channel topic after invalidation", func() bool { return subscribedTo(mr, channelTopic(channelID)) })
+}
+
+// TestNodeLoadFollowsLocalTraffic is a load test for topic routing. Two nodes share one Valkey; each hosts a user who
+// can view a different channel. Traffic is published to many channels, and each node must receive exactly the events
+// for its own channel, so the work a node does is proportional to the traffic its clients can see rather than to the
+// total published.
+func TestNodeLoadFollowsLocalTraffic(t *testing.T) {
+	t.Parallel()
+	mr, rdb := newTestRedis(t)
+
+	const channels, perChannel = 20, 50
+	channelIDs := make([]uuid.UUID, channels)
+	for i := range channelIDs {
+		channelIDs[i] = uuid.New()
+	}
+
+	// Each node's channel list holds only the channel its user can view; the rest are hidden from it.
+	nodeA := topicHub(t, rdb, &fakePermStore{}, channelIDs[:1])
+	nodeB := topicHub(t, rdb, &fakePermStore{}, channelIDs[1:2])
+	clientA, clientB := connectUser(t, nodeA), connectUser(t, nodeB)
+	if !nodeA.topics.watchesChannel(channelIDs[0]) || !nodeB.topics.watchesChannel(channelIDs[1]) {
+		t.Fatal("nodes do not watch their users' channels")
+	}
+	waitFor(t, "channel topics", func() bool {
+		return subscribedTo(mr, channelTopic(channelIDs[0])) && subscribedTo(mr, channelTopic(channelIDs[1]))
+	})
+
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
+	for range perChannel {
+		for _, id := range channelIDs {
+			data := map[string]string{"channel_id": id.String()}
+			if err := pub.Publish(context.Background(), events.TypingStart, data); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+		}
+	}
+
+	for _, n := range []*Hub{nodeA, nodeB} {
+		waitFor(t, "local events", func() bool { return n.DispatchStats().Events == perChannel })
+	}
+	// Give any misrouted event time to arrive before checking that none did.
+	time.Sleep(50 * time.Millisecond)
+	for name, n := range map[string]*Hub{"A": nodeA, "B": nodeB} {
+		if got := n.DispatchStats().Events; got != perChannel {
+			t.Errorf("node %s received %d events, want %d of the %d published", name, got, perChannel, channels*perChannel)
+		}
+	}
+	for _, c := range []*Client{clientA, clientB} {
+		if got := len(c.send); got != perChannel {
+			t.Errorf("client received %d frames, want %d", got, perChannel)
+		}
+	}
+}
+
+// BenchmarkNodeLoad publishes events across many channels while one node hosts a single user who can view one of them.
+// node-events/op stays at the fraction of traffic the local user can see however much is published in total.
+func BenchmarkNodeLoad(b *testing.B) {
+	mr := miniredis.RunT(b)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	b.Cleanup(func() { _ = rdb.Close() })
+
+	const channels = 100
+	channelIDs := make([]uuid.UUID, channels)
+	for i := range channelIDs {
+		channelIDs[i] = uuid.New()
+	}
+	node := topicHub(b, rdb, &fakePermStore{}, channelIDs[:1])
+	c := connectUser(b, node)
+	waitFor(b, "channel topic", func() bool { return subscribedTo(mr, channelTopic(channelIDs[0])) })
+	go func() {
+		for range c.send {
+		}
+	}()
+
+	pub := NewPublisher(rdb, zerolog.Nop(), 1, 16, 5*time.Second, Delivery{})
+	data := make([]map[string]string, channels)
+	for i, id := range channelIDs {
+		data[i] = map[string]string{"channel_id": id.String()}
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		if err := pub.Publish(context.Background(), events.TypingStart, data[i%channels]); err != nil {
+			b.Fatalf("Publish() error = %v", err)
+		}
+	}
+	b.StopTimer()
+
+	want := int64((b.N + channels - 1) / channels)
+	waitFor(b, "local events", func() bool { return node.DispatchStats().Events >= want })
+	b.ReportMetric(float64(node.DispatchStats().Events)/float64(b.N), "node-events/op")
+}
@@ -0,0 +1,9 @@
+// Package health runs the server's readiness checks. A Checker probes each registered dependency concurrently under a
+// shared timeout and reports a status, latency, and optional details per component, so that an orchestrator can decide
+// whether to route traffic to the instance and a dashboard can show which dependency is at fault.
+//
+// Checks are either critical or not. A failing critical check makes the whole server unavailable; a failing
+// non-critical check, or any check whose probe returns an error wrapped with Degraded, only marks it degraded. Checks
+// against slow, rate-limited, or side-effecting dependencies such as SMTP and storage can cache their result for a while
+// between probes.
+package health
@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Status is the health of a single component or of the server as a whole.
+type Status string
+
+// Status values, in increasing order of severity.
+const (
+	StatusOK          Status = "ok"
+	StatusDegraded    Status = "degraded"
+	StatusUnavailable Status = "unavailable"
+)
+
+// Probe checks a single dependency. It may return details, such as a queue depth, to include in the report whether or
+// not the check passes. Returning an error wrapped with Degraded marks the component degraded rather than unavailable.
+type Probe func(ctx context.Context) (map[string]any, error)
+
+// Check is a named probe registered with a Checker.
+type Check struct {
+	Name string
+
+	// Critical checks make the server unavailable when they fail. Failures of other checks only degrade it.
+	Critical bool
+
+	// CacheFor reuses the previous result for this long instead of probing on every run. Zero probes every time.
+	CacheFor time.Duration
+
+	Probe Probe
+}
+
+// Component is the result of one check.
+type Component struct {
+	Status    Status         `json:"status"`
+	Critical  bool           `json:"critical"`
+	LatencyMS float64        `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// Report is the result of running every check, keyed by check name.
+type Report struct {
+	Status     Status               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// degradedError marks a probe failure as degrading the component rather than making it unavailable.
+type degradedError struct{ err error }
+
+func (e degradedError) Error() string { return e.err.Error() }
+func (e degradedError) Unwrap() error { return e.err }
+
+// Degraded wraps err so that a probe returning it reports the component as degraded rather than unavailable. Use it for
+// dependencies that still work but are falling behind, such as a growing job backlog.
+func Degraded(err error) error {
+	return degradedError{err: err}
+}
+
+// cachedResult is the last result of a check with a CacheFor duration.
+type cachedResult struct {
+	component Component
+	expires   time.Time
+}
+
+// Checker runs a fixed set of checks. Register every check with Add before the first call to Run.
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedResult
+}
+
+// New creates a checker that gives every probe at most timeout to complete.
+func New(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		now:     time.Now,
+		cache:   make(map[string]cachedResult),
+	}
+}
+
+// Add registers a check. It is not safe to call concurrently with Run.
+func (c *Checker) Add(check Check) {
+	c.checks = append(c.checks, check)
+}
+
+// Run probes every registered check concurrently and returns the combined report.
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]Component, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Go(func() { results[i] = c.run(ctx, check) })
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(c.checks))}
+	for i, check := range c.checks {
+		comp := results[i]
+		report.Components[check.Name] = comp
+		switch {
+		case comp.Status == StatusUnavailable && comp.Critical:
+			report.Status = StatusUnavailable
+		case comp.Status != StatusOK && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// run returns the cached result of check when it is still fresh, and probes the dependency otherwise.
+func (c *Checker) run(ctx context.Context, check Check) Component {
+	if check.CacheFor > 0 {
+		c.mu.Lock()
+		cached, ok := c.cache[check.Name]
+		c.mu.Unlock()
+		if ok && c.now().Before(cached.expires) {
+			return cached.component
+		}
+	}
+
+	start := c.now()
+	details, err := check.Probe(ctx)
+	comp := Component{
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMS: float64(c.now().Sub(start).Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		comp.Status = StatusUnavailable
+		if errors.As(err, new(degradedError)) {
+			comp.Status = StatusDegraded
+		}
+		comp.Error = err.Error()
+	}
+
+	if check.CacheFor > 0 {
+		c.mu.Lock()
+		c.cache[check.Name] = cachedResult{component: comp, expires: c.now().Add(check.CacheFor)}
+		c.mu.Unlock()
+	}
+	return comp
+}
@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func probeReturning(err error) Probe {
+	return func(context.Context) (map[string]any, error) { return nil, err }
+}
+
+func TestCheckerRunStatus(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("connection refused")
+	tests := []struct {
+		name   string
+		checks []Check
+		want   Status
+	}{
+		{
+			name: "all ok",
+			checks: []Check{
+				{Name: "postgres", Critical: true, Probe: probeReturning(nil)},
+				{Name: "smtp", Probe: probeReturning(nil)},
+			},
+			want: StatusOK,
+		},
+		{
+			name: "critical failure",
+			checks: []Check{
+				{Name: "postgres", Critical: true, Probe: probeReturning(failed)},
+				{Name: "smtp", Probe: probeReturning(Degraded(failed))},
+			},
+			want: StatusUnavailable,
+		},
+		{
+			name: "non-critical failure",
+			checks: []Check{
+				{Name: "postgres", Critical: true, Probe: probeReturning(nil)},
+				{Name: "smtp", Probe: probeReturning(failed)},
+			},
+			want: StatusDegraded,
+		},
+		{
+			name: "critical degraded",
+			checks: []Check{
+				{Name: "postgres", Critical: true, Probe: probeReturning(Degraded(failed))},
+			},
+			want: StatusDegraded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := New(time.Second)
+			for _, check := range tt.checks {
+				c.Add(check)
+			}
+			report := c.Run(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("Run().Status = %q, want %q", report.Status, tt.want)
+			}
+			if len(report.Components) != len(tt.checks) {
+				t.Errorf("Run() reported %d components, want %d", len(report.Components), len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestCheckerRunComponent(t *testing.T) {
+	t.Parallel()
+
+	c := New(time.Second)
+	c.Add(Check{Name: "queue", Probe: func(context.Context) (map[string]any, error) {
+		return map[string]any{"depth": 5}, Degraded(errors.New("queue is full"))
+	}})
+	c.Add(Check{Name: "valkey", Critical: true, Probe: probeReturning(nil)})
+
+	report := c.Run(context.Background())
+
+	queue := report.Components["queue"]
+	if queue.Status != StatusDegraded || queue.Critical || queue.Error != "queue is full" || queue.Details["depth"] != 5 {
+		t.Errorf("queue component = %+v", queue)
+	}
+	valkey := report.Components["valkey"]
+	if valkey.Status != StatusOK || !valkey.Critical || valkey.Error != "" {
+		t.Errorf("valkey component = %+v", valkey)
+	}
+}
+
+func TestCheckerRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	c := New(10 * time.Millisecond)
+	c.Add(Check{Name: "slow", Critical: true, Probe: func(ctx context.Context) (map[string]any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}})
+
+	report := c.Run(context.Background())
+	if report.Status != StatusUnavailable {
+		t.Errorf("Run().Status = %q, want %q", report.Status, StatusUnavailable)
+	}
+	if got := report.Components["slow"].Error; got != context.DeadlineExceeded.Error() {
+		t.Errorf("slow component error = %q, want %q", got, context.DeadlineExceeded.Error())
+	}
+}
+
+func TestCheckerRunCachesResult(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	c := New(time.Second)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	c.Add(Check{Name: "smtp", CacheFor: 30 * time.Second, Probe: func(context.Context) (map[string]any, error) {
+		calls.Add(1)
+		return nil, nil
+	}})
+
+	c.Run(context.Background())
+	now = now.Add(29 * time.Second)
+	c.Run(context.Background())
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("probe called %d times within CacheFor, want 1", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	c.Run(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Errorf("probe called %d times after CacheFor expired, want 2", got)
+	}
+}
@@ -0,0 +1,173 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/uncord-chat/uncord-server/internal/gateway"
+	"github.com/uncord-chat/uncord-server/internal/media"
+)
+
+// Pinger checks connectivity to a backing service. Satisfied by *pgxpool.Pool, *email.Client, and
+// *typesense.Indexer.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Storage is the subset of media.StorageProvider used to check that storage is writable.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// HubSource reports whether the gateway hub is receiving events and accepting connections. Satisfied by *gateway.Hub.
+type HubSource interface {
+	Subscribed() bool
+	Draining() bool
+	ClientCount() int
+}
+
+// PublisherSource reports whether the gateway publisher is draining its queue. Satisfied by *gateway.Publisher.
+type PublisherSource interface {
+	Running() bool
+	Stats() gateway.PublisherStats
+}
+
+// BacklogSource reports the number of unfinished entries on a job stream. Satisfied by *media.ThumbnailWorker.
+type BacklogSource interface {
+	Backlog(ctx context.Context) (int64, error)
+}
+
+var (
+	_ Storage         = (media.StorageProvider)(nil)
+	_ HubSource       = (*gateway.Hub)(nil)
+	_ PublisherSource = (*gateway.Publisher)(nil)
+	_ BacklogSource   = (*media.ThumbnailWorker)(nil)
+)
+
+// Ping probes a service by pinging it.
+func Ping(p Pinger) Probe {
+	return func(ctx context.Context) (map[string]any, error) {
+		return nil, p.Ping(ctx)
+	}
+}
+
+// Migrations probes that the database schema is at the version the binary expects. A schema behind the binary makes the
+// component unavailable; a schema ahead of it, as after rolling back a deploy, only degrades it because migrations are
+// written to be backwards compatible with the previous release.
+func Migrations(applied func(ctx context.Context) (int64, error), latest int64) Probe {
+	return func(ctx context.Context) (map[string]any, error) {
+		version, err := applied(ctx)
+		if err != nil {
+			return nil, err
+		}
+		details := map[string]any{"applied": version, "expected": latest}
+		switch {
+		case version < latest:
+			return details, fmt.Errorf("schema version %d is behind expected version %d", version, latest)
+		case version > latest:
+			return details, Degraded(fmt.Errorf("schema version %d is ahead of expected version %d", version, latest))
+		}
+		return details, nil
+	}
+}
+
+// storageProbePrefix namespaces the objects written by the storage probe so they are easy to find if a probe is
+// interrupted before it deletes its object.
+const storageProbePrefix = "health/probe-"
+
+// StorageWritable probes that storage accepts writes by putting a small object, reading it back, and deleting it.
+func StorageWritable(s Storage) Probe {
+	return func(ctx context.Context) (map[string]any, error) {
+		key := storageProbePrefix + uuid.NewString()
+		payload := []byte(key)
+
+		if err := s.Put(ctx, key, bytes.NewReader(payload)); err != nil {
+			return nil, fmt.Errorf("write probe object: %w", err)
+		}
+
+		readErr := readBack(ctx, s, key, payload)
+
+		// Delete even when the read failed so that failed probes do not accumulate objects.
+		delErr := s.Delete(ctx, key)
+		if readErr != nil {
+			return nil, fmt.Errorf("read probe object: %w", readErr)
+		}
+		if delErr != nil {
+			return nil, fmt.Errorf("delete probe object: %w", delErr)
+		}
+		return nil, nil
+	}
+}
+
+// readBack reads the object at key and checks that it holds want.
+func readBack(ctx context.Context, s Storage, key string, want []byte) error {
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return errors.New("content does not match what was written")
+	}
+	return nil
+}
+
+// GatewayHub probes that the gateway hub is subscribed to the events channel. Without the subscription, clients
+// connected to this instance stop receiving events. A draining hub is reported unavailable so that load balancers stop
+// sending it new connections.
+func GatewayHub(h HubSource) Probe {
+	return func(context.Context) (map[string]any, error) {
+		details := map[string]any{"clients": h.ClientCount()}
+		if !h.Subscribed() {
+			return details, errors.New("not subscribed to gateway events")
+		}
+		if h.Draining() {
+			details["draining"] = true
+			return details, errors.New("gateway is draining")
+		}
+		return details, nil
+	}
+}
+
+// GatewayPublisher probes that the gateway publisher's workers are running. A full queue, which makes new events drop,
+// degrades the component.
+func GatewayPublisher(p PublisherSource) Probe {
+	return func(context.Context) (map[string]any, error) {
+		stats := p.Stats()
+		details := map[string]any{"queue_depth": stats.QueueDepth, "queue_capacity": stats.QueueCapacity}
+		if !p.Running() {
+			return details, errors.New("publisher workers are not running")
+		}
+		if stats.QueueDepth >= stats.QueueCapacity {
+			return details, Degraded(errors.New("publish queue is full"))
+		}
+		return details, nil
+	}
+}
+
+// Backlog probes the number of unfinished entries on a job stream. A backlog above threshold degrades the component.
+func Backlog(src BacklogSource, threshold int64) Probe {
+	return func(ctx context.Context) (map[string]any, error) {
+		backlog, err := src.Backlog(ctx)
+		if err != nil {
+			return nil, err
+		}
+		details := map[string]any{"backlog": backlog, "threshold": threshold}
+		if backlog > threshold {
+			return details, Degraded(fmt.Errorf("backlog of %d exceeds %d", backlog, threshold))
+		}
+		return details, nil
+	}
+}
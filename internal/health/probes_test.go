@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uncord-chat/uncord-server/internal/gateway"
+	"github.com/uncord-chat/uncord-server/internal/media"
+)
+
+// isDegraded reports whether err marks a component as degraded rather than unavailable.
+func isDegraded(err error) bool {
+	return errors.As(err, new(degradedError))
+}
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		applied      int64
+		wantErr      bool
+		wantDegraded bool
+	}{
+		{"current", 8, false, false},
+		{"behind", 7, true, false},
+		{"ahead", 9, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			probe := Migrations(func(context.Context) (int64, error) { return tt.applied, nil }, 8)
+			details, err := probe(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("probe error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if isDegraded(err) != tt.wantDegraded {
+				t.Errorf("probe error degraded = %v, want %v", isDegraded(err), tt.wantDegraded)
+			}
+			if details["applied"] != tt.applied || details["expected"] != int64(8) {
+				t.Errorf("details = %v", details)
+			}
+		})
+	}
+}
+
+func TestStorageWritable(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	storage, err := media.NewLocalStorage(dir, "http://localhost/media")
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+
+	if _, err := StorageWritable(storage)(context.Background()); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "health", "*"))
+	if len(leftovers) != 0 {
+		t.Errorf("probe left %d objects behind", len(leftovers))
+	}
+}
+
+// failingStorage accepts writes but fails every read.
+type failingStorage struct{ deleted bool }
+
+func (s *failingStorage) Put(context.Context, string, io.Reader) error { return nil }
+func (s *failingStorage) Get(context.Context, string) (io.ReadCloser, error) {
+	return nil, os.ErrPermission
+}
+func (s *failingStorage) Delete(context.Context, string) error {
+	s.deleted = true
+	return nil
+}
+
+func TestStorageWritableDeletesAfterFailedRead(t *testing.T) {
+	t.Parallel()
+
+	s := &failingStorage{}
+	if _, err := StorageWritable(s)(context.Background()); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("probe error = %v, want %v", err, os.ErrPermission)
+	}
+	if !s.deleted {
+		t.Error("probe did not delete its object after the read failed")
+	}
+}
+
+type fakeHub struct{ subscribed, draining bool }
+
+func (h fakeHub) Subscribed() bool { return h.subscribed }
+func (h fakeHub) Draining() bool   { return h.draining }
+func (h fakeHub) ClientCount() int { return 3 }
+
+func TestGatewayHub(t *testing.T) {
+	t.Parallel()
+
+	details, err := GatewayHub(fakeHub{subscribed: true})(context.Background())
+	if err != nil || details["clients"] != 3 {
+		t.Errorf("subscribed hub: details = %v, err = %v", details, err)
+	}
+	if _, err := GatewayHub(fakeHub{})(context.Background()); err == nil || isDegraded(err) {
+		t.Errorf("unsubscribed hub: err = %v, want an unavailable error", err)
+	}
+	details, err = GatewayHub(fakeHub{subscribed: true, draining: true})(context.Background())
+	if err == nil || isDegraded(err) || details["draining"] != true {
+		t.Errorf("draining hub: details = %v, err = %v; want an unavailable error", details, err)
+	}
+}
+
+type fakePublisher struct {
+	running bool
+	stats   gateway.PublisherStats
+}
+
+func (p fakePublisher) Running() bool                 { return p.running }
+func (p fakePublisher) Stats() gateway.PublisherStats { return p.stats }
+
+func TestGatewayPublisher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		pub          fakePublisher
+		wantErr      bool
+		wantDegraded bool
+	}{
+		{"running", fakePublisher{true, gateway.PublisherStats{QueueDepth: 1, QueueCapacity: 10}}, false, false},
+		{"stopped", fakePublisher{false, gateway.PublisherStats{QueueCapacity: 10}}, true, false},
+		{"full", fakePublisher{true, gateway.PublisherStats{QueueDepth: 10, QueueCapacity: 10}}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			details, err := GatewayPublisher(tt.pub)(context.Background())
+			if (err != nil) != tt.wantErr || isDegraded(err) != tt.wantDegraded {
+				t.Errorf("probe error = %v, wantErr %v, wantDegraded %v", err, tt.wantErr, tt.wantDegraded)
+			}
+			if details["queue_depth"] != tt.pub.stats.QueueDepth {
+				t.Errorf("details = %v", details)
+			}
+		})
+	}
+}
+
+type fakeBacklog struct {
+	n   int64
+	err error
+}
+
+func (b fakeBacklog) Backlog(context.Context) (int64, error) { return b.n, b.err }
+
+func TestBacklog(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Backlog(fakeBacklog{n: 100}, 100)(context.Background()); err != nil {
+		t.Errorf("backlog at threshold: err = %v", err)
+	}
+	if _, err := Backlog(fakeBacklog{n: 101}, 100)(context.Background()); !isDegraded(err) {
+		t.Errorf("backlog above threshold: err = %v, want a degraded error", err)
+	}
+	if _, err := Backlog(fakeBacklog{err: errors.New("no group")}, 100)(context.Background()); err == nil ||
+		isDegraded(err) {
+		t.Errorf("backlog error: err = %v, want an unavailable error", err)
+	}
+}
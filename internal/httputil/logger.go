@@ -10,7 +10,7 @@ import (
 
 // RequestLogger returns Fiber middleware that logs every request through the provided zerolog logger. Paths passed in
 // skipPaths are excluded from logging. It should be registered after the requestid middleware so that the request ID is
-// available in Locals.
+// available in Locals, and after the tracing middleware so that a hook on the logger can read the request span.
 func RequestLogger(logger zerolog.Logger, skipPaths ...string) fiber.Handler {
 	skip := make(map[string]struct{}, len(skipPaths))
 	for _, p := range skipPaths {
@@ -33,6 +33,7 @@ func RequestLogger(logger zerolog.Logger, skipPaths ...string) fiber.Handler {
 		}
 
 		event.
+			Ctx(c.Context()).
 			Str("method", c.Method()).
 			Str("path", c.Path()).
 			Int("status", status).
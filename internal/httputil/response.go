@@ -1,6 +1,7 @@
 package httputil
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 
@@ -46,6 +47,19 @@ func Fail(c fiber.Ctx, status int, code apierrors.Code, message string) error {
 	})
 }
 
+// ResponseStatus returns the status the client will receive for a request whose handler chain returned err. Middleware
+// that runs after c.Next needs this because a returned error has not been written to the response yet; the
+// application's error handler maps it once the middleware returns.
+func ResponseStatus(c fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	if e, ok := errors.AsType[*fiber.Error](err); ok {
+		return e.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
 // ParseUUIDParam parses a UUID route parameter. On success it returns the parsed UUID and true. On failure it writes a
 // 400 JSON error response with the given code and returns (uuid.Nil, false). The error message is derived from the
 // parameter name (e.g. "channelID" produces "Invalid channel ID format"). Callers should return nil when ok is false
@@ -0,0 +1,146 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of each INSTREAM chunk sent to clamd. It must stay well below clamd's StreamMaxLength.
+const clamdChunkSize = 64 * 1024
+
+// ErrScannerUnavailable is returned when the scanning backend cannot be reached or does not return a verdict.
+var ErrScannerUnavailable = errors.New("content scanner is unavailable")
+
+// errScanSource marks a failure to read the file being scanned, as opposed to a failure talking to clamd.
+var errScanSource = errors.New("read file for scanning")
+
+// ClamAVScanner scans files by streaming them to a clamd daemon using the INSTREAM command. Each scan opens a new
+// connection, so the scanner is safe for concurrent use.
+type ClamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the clamd daemon at address. Addresses beginning with "/" are treated as Unix
+// socket paths; anything else is dialled as TCP host:port. timeout bounds an entire scan, including the upload of the
+// file to clamd.
+func NewClamAVScanner(address string, timeout time.Duration) *ClamAVScanner {
+	network := "tcp"
+	if strings.HasPrefix(address, "/") {
+		network = "unix"
+	}
+	return &ClamAVScanner{network: network, address: address, timeout: timeout}
+}
+
+// Ping checks that clamd is reachable and responding.
+func (s *ClamAVScanner) Ping(ctx context.Context) error {
+	reply, err := s.command(ctx, func(w io.Writer) error {
+		_, err := io.WriteString(w, "zPING\x00")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("%w: unexpected PING reply %q", ErrScannerUnavailable, reply)
+	}
+	return nil
+}
+
+// Scan streams r to clamd and returns its verdict. Replies other than a clean or infected verdict (for example when the
+// file exceeds clamd's StreamMaxLength) are returned as errors wrapping ErrScannerUnavailable.
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	reply, err := s.command(ctx, func(w io.Writer) error {
+		if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+			return err
+		}
+		return writeInstream(w, r)
+	})
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return parseClamdReply(reply)
+}
+
+// command dials clamd, sends a request with write, and reads the NUL-terminated reply. When clamd closes the
+// connection mid-request (as it does when a stream exceeds its size limit) the reply it sent before closing is still
+// read so the caller sees clamd's reason rather than a broken pipe.
+func (s *ClamAVScanner) command(ctx context.Context, write func(io.Writer) error) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return "", fmt.Errorf("%w: dial clamd: %w", ErrScannerUnavailable, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	bw := bufio.NewWriter(conn)
+	writeErr := write(bw)
+	if errors.Is(writeErr, errScanSource) {
+		// clamd is still waiting for the rest of the stream, so there is no reply to wait for.
+		return "", writeErr
+	}
+	if writeErr == nil {
+		writeErr = bw.Flush()
+	}
+
+	reply, readErr := bufio.NewReader(conn).ReadString(0)
+	reply = strings.TrimSpace(strings.TrimSuffix(reply, "\x00"))
+	if reply == "" {
+		if writeErr != nil {
+			return "", fmt.Errorf("%w: write to clamd: %w", ErrScannerUnavailable, writeErr)
+		}
+		return "", fmt.Errorf("%w: read clamd reply: %w", ErrScannerUnavailable, readErr)
+	}
+	return reply, nil
+}
+
+// writeInstream sends r as a sequence of length-prefixed chunks followed by the zero-length terminator.
+func writeInstream(w io.Writer, r io.Reader) error {
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, wErr := w.Write(buf[:4+n]); wErr != nil {
+				return wErr
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", errScanSource, err)
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamdReply interprets an INSTREAM reply. clamd answers "stream: OK" for clean files,
+// "stream: <signature> FOUND" for infected files, and "<reason> ERROR" when it could not scan.
+func parseClamdReply(reply string) (ScanResult, error) {
+	body := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case body == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(body, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(body, " FOUND")}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("%w: clamd: %s", ErrScannerUnavailable, reply)
+	}
+}
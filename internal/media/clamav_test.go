@@ -0,0 +1,194 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eicar is the standard antivirus test string. The fake clamd reports it as infected.
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd is a minimal clamd implementation speaking the zPING and zINSTREAM commands over TCP. Streams larger than
+// maxStream are rejected the same way clamd rejects streams over StreamMaxLength.
+type fakeClamd struct {
+	ln        net.Listener
+	maxStream int
+}
+
+func startFakeClamd(t *testing.T, maxStream int) *fakeClamd {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeClamd{ln: ln, maxStream: maxStream}
+	t.Cleanup(func() { _ = ln.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeClamd) addr() string { return f.ln.Addr().String() }
+
+func (f *fakeClamd) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeClamd) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+
+	cmd, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+	switch cmd {
+	case "zPING\x00":
+		_, _ = io.WriteString(conn, "PONG\x00")
+	case "zINSTREAM\x00":
+		var data bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if data.Len()+int(size) > f.maxStream {
+				_, _ = io.WriteString(conn, "INSTREAM size limit exceeded. ERROR\x00")
+				return
+			}
+			if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+				return
+			}
+		}
+		if strings.Contains(data.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+			_, _ = io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+			return
+		}
+		_, _ = io.WriteString(conn, "stream: OK\x00")
+	default:
+		_, _ = io.WriteString(conn, "UNKNOWN COMMAND\x00")
+	}
+}
+
+func TestClamAVScanner_Clean(t *testing.T) {
+	t.Parallel()
+	clamd := startFakeClamd(t, 1<<20)
+	scanner := NewClamAVScanner(clamd.addr(), 5*time.Second)
+
+	// Larger than one INSTREAM chunk so that chunking is exercised.
+	content := bytes.Repeat([]byte("harmless "), clamdChunkSize/4)
+	result, err := scanner.Scan(context.Background(), bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if result.Infected {
+		t.Errorf("Scan() = %+v, want clean", result)
+	}
+}
+
+func TestClamAVScanner_Infected(t *testing.T) {
+	t.Parallel()
+	clamd := startFakeClamd(t, 1<<20)
+	scanner := NewClamAVScanner(clamd.addr(), 5*time.Second)
+
+	result, err := scanner.Scan(context.Background(), strings.NewReader(eicar))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if !result.Infected {
+		t.Fatal("Scan() reported clean, want infected")
+	}
+	if result.Signature != "Eicar-Test-Signature" {
+		t.Errorf("Signature = %q, want %q", result.Signature, "Eicar-Test-Signature")
+	}
+}
+
+func TestClamAVScanner_SizeLimitExceeded(t *testing.T) {
+	t.Parallel()
+	clamd := startFakeClamd(t, 16)
+	scanner := NewClamAVScanner(clamd.addr(), 5*time.Second)
+
+	_, err := scanner.Scan(context.Background(), bytes.NewReader(make([]byte, 4*clamdChunkSize)))
+	if !errors.Is(err, ErrScannerUnavailable) {
+		t.Fatalf("Scan() error = %v, want ErrScannerUnavailable", err)
+	}
+	if !strings.Contains(err.Error(), "size limit exceeded") {
+		t.Errorf("error %q does not include clamd's reason", err)
+	}
+}
+
+func TestClamAVScanner_Unreachable(t *testing.T) {
+	t.Parallel()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	scanner := NewClamAVScanner(addr, time.Second)
+	if _, err := scanner.Scan(context.Background(), strings.NewReader("data")); !errors.Is(err, ErrScannerUnavailable) {
+		t.Errorf("Scan() error = %v, want ErrScannerUnavailable", err)
+	}
+}
+
+func TestClamAVScanner_Ping(t *testing.T) {
+	t.Parallel()
+	clamd := startFakeClamd(t, 1<<20)
+	scanner := NewClamAVScanner(clamd.addr(), 5*time.Second)
+
+	if err := scanner.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		reply     string
+		infected  bool
+		signature string
+		wantErr   bool
+	}{
+		{"stream: OK", false, "", false},
+		{"stream: Win.Test.EICAR_HDB-1 FOUND", true, "Win.Test.EICAR_HDB-1", false},
+		{"INSTREAM size limit exceeded. ERROR", false, "", true},
+		{"garbage", false, "", true},
+	}
+	for _, tt := range tests {
+		result, err := parseClamdReply(tt.reply)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseClamdReply(%q) error = %v, wantErr %v", tt.reply, err, tt.wantErr)
+			continue
+		}
+		if result.Infected != tt.infected || result.Signature != tt.signature {
+			t.Errorf("parseClamdReply(%q) = %+v, want infected=%v signature=%q", tt.reply, result, tt.infected, tt.signature)
+		}
+	}
+}
+
+func TestNoopScanner(t *testing.T) {
+	t.Parallel()
+
+	result, err := NoopScanner{}.Scan(context.Background(), strings.NewReader(eicar))
+	if err != nil || result.Infected {
+		t.Errorf("NoopScanner.Scan() = %+v, %v; want clean", result, err)
+	}
+}
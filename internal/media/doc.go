@@ -1,4 +1,11 @@
 // Package media abstracts file storage behind the StorageProvider interface. Implementations handle Put, Get, Delete,
 // and URL generation for stored files. The package defines allowlists for permitted content types and thumbnail-eligible
-// image types, and provides MIME normalisation and file extension helpers.
+// image types, and provides MIME normalisation and file extension helpers. The ThumbnailWorker consumes media jobs from a
+// Valkey stream to generate thumbnails, video poster frames, durations, and audio waveforms, delegating video and audio
+// decoding to an optional external ffmpeg binary. Uploads are checked for malware through the Scanner interface, which is
+// backed by a clamd daemon when configured and by NoopScanner otherwise. URLSigner issues short-lived HMAC-signed URLs
+// for attachment files so that private channel content is not served to anyone holding an old link, while encrypted DM
+// attachments are never served from /media at all (IsRestrictedKey). VariantStore
+// generates resized and transcoded image renditions, snapped to a few fixed sizes, on demand and caches them in storage
+// under derived keys; DeleteWithVariants removes a file together with its renditions.
 package media
@@ -0,0 +1,269 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	// probeTimeout bounds a single ffprobe invocation. Probing reads only container headers, so a slow probe indicates a
+	// malformed or adversarial file.
+	probeTimeout = 30 * time.Second
+
+	// transcodeTimeout bounds a single ffmpeg invocation that decodes media (poster frames, waveforms, image fallback).
+	transcodeTimeout = 2 * time.Minute
+
+	// posterOffset is the timestamp a video poster frame is extracted from. Many recordings start on a black frame, so
+	// the first second is skipped when the video is long enough.
+	posterOffset = "1"
+
+	// waveformSampleRate is the sample rate audio is downmixed to before computing the waveform. Peak amplitude over a
+	// few hundred buckets does not need more resolution than this.
+	waveformSampleRate = 8000
+
+	// WaveformSamples is the number of amplitude buckets in a generated waveform.
+	WaveformSamples = 256
+)
+
+// ErrFFmpegUnavailable is returned by DiscoverFFmpeg when the ffmpeg or ffprobe binary cannot be found.
+var ErrFFmpegUnavailable = errors.New("ffmpeg is not available")
+
+// FFmpeg wraps the external ffmpeg and ffprobe binaries used to extract metadata, poster frames, and waveforms from
+// video and audio attachments, and to decode image formats that have no pure Go decoder (e.g. AVIF). All methods read
+// from a file path because container formats such as MP4 may store their index at the end of the file, which a pipe
+// cannot seek to.
+type FFmpeg struct {
+	ffmpegPath  string
+	ffprobePath string
+}
+
+// DiscoverFFmpeg resolves the given ffmpeg and ffprobe binary names or paths via PATH lookup. Returns
+// ErrFFmpegUnavailable (wrapped with the lookup error) when either binary is missing so the caller can run without
+// video and audio processing.
+func DiscoverFFmpeg(ffmpegPath, ffprobePath string) (*FFmpeg, error) {
+	resolvedFFmpeg, err := exec.LookPath(ffmpegPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFFmpegUnavailable, err)
+	}
+	resolvedFFprobe, err := exec.LookPath(ffprobePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFFmpegUnavailable, err)
+	}
+	return &FFmpeg{ffmpegPath: resolvedFFmpeg, ffprobePath: resolvedFFprobe}, nil
+}
+
+// ProbeResult holds the stream properties reported by ffprobe. Fields are nil when the container does not report them
+// (e.g. an audio file has no width or height).
+type ProbeResult struct {
+	Width      *int
+	Height     *int
+	DurationMS *int
+}
+
+// Probe runs ffprobe against the file at path and returns its dimensions and duration.
+func (f *FFmpeg) Probe(ctx context.Context, path string) (ProbeResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	out, err := f.run(ctx, f.ffprobePath,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("ffprobe: %w", err)
+	}
+	return parseProbeOutput(out)
+}
+
+// PosterFrame extracts a single frame from the video at path. The frame is taken one second in; videos shorter than
+// that fall back to the first frame.
+func (f *FFmpeg) PosterFrame(ctx context.Context, path string) (image.Image, error) {
+	img, err := f.extractFrame(ctx, path, posterOffset)
+	if err == nil {
+		return img, nil
+	}
+	return f.extractFrame(ctx, path, "")
+}
+
+// DecodeImage decodes the first frame of the image at path. This is the fallback for formats the Go image package
+// cannot decode.
+func (f *FFmpeg) DecodeImage(ctx context.Context, path string) (image.Image, error) {
+	return f.extractFrame(ctx, path, "")
+}
+
+// Waveform decodes the audio track of the file at path to mono PCM and reduces it to WaveformSamples peak amplitudes,
+// each scaled to the range 0-255.
+func (f *FFmpeg) Waveform(ctx context.Context, path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, transcodeTimeout)
+	defer cancel()
+
+	out, err := f.run(ctx, f.ffmpegPath,
+		"-v", "error",
+		"-i", path,
+		"-vn",
+		"-ac", "1",
+		"-ar", strconv.Itoa(waveformSampleRate),
+		"-f", "s16le",
+		"-",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg waveform: %w", err)
+	}
+	return waveformFromPCM(out, WaveformSamples)
+}
+
+// extractFrame runs ffmpeg to decode a single frame from path as PNG, seeking to offset first when it is non-empty.
+func (f *FFmpeg) extractFrame(ctx context.Context, path, offset string) (image.Image, error) {
+	ctx, cancel := context.WithTimeout(ctx, transcodeTimeout)
+	defer cancel()
+
+	args := []string{"-v", "error"}
+	if offset != "" {
+		args = append(args, "-ss", offset)
+	}
+	args = append(args,
+		"-i", path,
+		"-frames:v", "1",
+		"-f", "image2pipe",
+		"-vcodec", "png",
+		"-",
+	)
+
+	out, err := f.run(ctx, f.ffmpegPath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg frame: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("ffmpeg frame: no frame decoded")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("decode ffmpeg frame: %w", err)
+	}
+	return img, nil
+}
+
+// run executes the named binary and returns its standard output. Standard error is included in the returned error so
+// operators can see why ffmpeg rejected a file.
+func (f *FFmpeg) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// probeOutput mirrors the subset of ffprobe's JSON output used by Probe.
+type probeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		Duration  string `json:"duration"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// parseProbeOutput extracts dimensions from the first video stream and the duration from the container, falling back
+// to the first stream that reports one.
+func parseProbeOutput(data []byte) (ProbeResult, error) {
+	var out probeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return ProbeResult{}, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+
+	var result ProbeResult
+	durationStr := out.Format.Duration
+	for _, s := range out.Streams {
+		if s.CodecType == "video" && result.Width == nil && s.Width > 0 && s.Height > 0 {
+			w, h := s.Width, s.Height
+			result.Width = &w
+			result.Height = &h
+		}
+		if durationStr == "" && s.Duration != "" {
+			durationStr = s.Duration
+		}
+	}
+
+	if durationStr != "" {
+		secs, err := strconv.ParseFloat(durationStr, 64)
+		if err == nil && secs >= 0 && !math.IsInf(secs, 0) && !math.IsNaN(secs) {
+			ms := int(math.Round(secs * 1000))
+			result.DurationMS = &ms
+		}
+	}
+	return result, nil
+}
+
+// waveformFromPCM reduces signed 16-bit little-endian mono PCM to the given number of peak amplitude buckets scaled to
+// 0-255. Audio shorter than the bucket count yields one bucket per sample.
+func waveformFromPCM(pcm []byte, buckets int) ([]byte, error) {
+	samples := len(pcm) / 2
+	if samples == 0 {
+		return nil, errors.New("no audio samples decoded")
+	}
+	if samples < buckets {
+		buckets = samples
+	}
+
+	result := make([]byte, buckets)
+	per := float64(samples) / float64(buckets)
+	for b := range buckets {
+		start := int(float64(b) * per)
+		end := int(float64(b+1) * per)
+		var peak int
+		for i := start; i < end; i++ {
+			v := int(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+			if v < 0 {
+				v = -v
+			}
+			peak = max(peak, v)
+		}
+		result[b] = byte(min(peak*255/math.MaxInt16, 255))
+	}
+	return result, nil
+}
+
+// spoolToTemp copies r into a temporary file so it can be handed to ffmpeg by path. The caller must invoke the returned
+// cleanup function to remove the file.
+func spoolToTemp(r io.Reader) (path string, cleanup func(), err error) {
+	f, err := os.CreateTemp("", "uncord-media-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp file: %w", err)
+	}
+	cleanup = func() { _ = os.Remove(f.Name()) }
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("write temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("close temp file: %w", err)
+	}
+	return f.Name(), cleanup, nil
+}
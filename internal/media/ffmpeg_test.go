@@ -0,0 +1,113 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseProbeOutput_Video(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{
+		"streams": [
+			{"codec_type": "audio", "duration": "12.480000"},
+			{"codec_type": "video", "width": 1920, "height": 1080, "duration": "12.500000"}
+		],
+		"format": {"duration": "12.512000"}
+	}`)
+
+	got, err := parseProbeOutput(data)
+	if err != nil {
+		t.Fatalf("parseProbeOutput() error = %v", err)
+	}
+	if got.Width == nil || *got.Width != 1920 {
+		t.Errorf("Width = %v, want 1920", got.Width)
+	}
+	if got.Height == nil || *got.Height != 1080 {
+		t.Errorf("Height = %v, want 1080", got.Height)
+	}
+	if got.DurationMS == nil || *got.DurationMS != 12512 {
+		t.Errorf("DurationMS = %v, want 12512", got.DurationMS)
+	}
+}
+
+func TestParseProbeOutput_AudioStreamDurationFallback(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{"streams": [{"codec_type": "audio", "duration": "3.25"}], "format": {}}`)
+
+	got, err := parseProbeOutput(data)
+	if err != nil {
+		t.Fatalf("parseProbeOutput() error = %v", err)
+	}
+	if got.Width != nil || got.Height != nil {
+		t.Errorf("dimensions = %v x %v, want nil", got.Width, got.Height)
+	}
+	if got.DurationMS == nil || *got.DurationMS != 3250 {
+		t.Errorf("DurationMS = %v, want 3250", got.DurationMS)
+	}
+}
+
+func TestParseProbeOutput_Invalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := parseProbeOutput([]byte("not json")); err == nil {
+		t.Fatal("parseProbeOutput() error = nil, want error")
+	}
+
+	got, err := parseProbeOutput([]byte(`{"streams": [], "format": {"duration": "N/A"}}`))
+	if err != nil {
+		t.Fatalf("parseProbeOutput() error = %v", err)
+	}
+	if got.DurationMS != nil {
+		t.Errorf("DurationMS = %v, want nil for unparseable duration", *got.DurationMS)
+	}
+}
+
+func TestWaveformFromPCM(t *testing.T) {
+	t.Parallel()
+
+	// Four buckets of two samples each: silence, half amplitude, full positive, full negative.
+	samples := []int16{0, 0, 0, math.MaxInt16 / 2, 100, math.MaxInt16, math.MinInt16 + 1, -5}
+	pcm := make([]byte, len(samples)*2)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(v))
+	}
+
+	got, err := waveformFromPCM(pcm, 4)
+	if err != nil {
+		t.Fatalf("waveformFromPCM() error = %v", err)
+	}
+	want := []byte{0, 127, 255, 255}
+	if string(got) != string(want) {
+		t.Errorf("waveformFromPCM() = %v, want %v", got, want)
+	}
+}
+
+func TestWaveformFromPCM_FewerSamplesThanBuckets(t *testing.T) {
+	t.Parallel()
+
+	pcm := make([]byte, 6)
+	got, err := waveformFromPCM(pcm, WaveformSamples)
+	if err != nil {
+		t.Fatalf("waveformFromPCM() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("len = %d, want 3", len(got))
+	}
+
+	if _, err := waveformFromPCM(nil, WaveformSamples); err == nil {
+		t.Error("waveformFromPCM(nil) error = nil, want error")
+	}
+}
+
+func TestDiscoverFFmpeg_Missing(t *testing.T) {
+	t.Parallel()
+
+	_, err := DiscoverFFmpeg("uncord-no-such-ffmpeg", "uncord-no-such-ffprobe")
+	if !errors.Is(err, ErrFFmpegUnavailable) {
+		t.Fatalf("DiscoverFFmpeg() error = %v, want ErrFFmpegUnavailable", err)
+	}
+}
@@ -15,6 +15,7 @@ import (
 type LocalStorage struct {
 	root    *os.Root
 	baseURL string
+	signer  *URLSigner
 }
 
 // NewLocalStorage creates a storage provider that reads and writes files under basePath. The base directory must exist.
@@ -30,6 +31,17 @@ func NewLocalStorage(basePath, baseURL string) (*LocalStorage, error) {
 	}, nil
 }
 
+// SignURLs makes URL return signed, expiring URLs for private keys (see IsPrivateKey). It must be called before the
+// storage is used concurrently.
+func (s *LocalStorage) SignURLs(signer *URLSigner) {
+	s.signer = signer
+}
+
+// Signer returns the URL signer, or nil when URLs are unsigned.
+func (s *LocalStorage) Signer() *URLSigner {
+	return s.signer
+}
+
 // Close releases the underlying root directory handle.
 func (s *LocalStorage) Close() error {
 	return s.root.Close()
@@ -83,7 +95,26 @@ func (s *LocalStorage) Delete(_ context.Context, key string) error {
 	return nil
 }
 
-// URL returns the public URL for the given storage key.
+// DeletePrefix removes every file whose key starts with prefix, which must name a directory (end in "/"). A missing
+// directory is not treated as an error.
+func (s *LocalStorage) DeletePrefix(_ context.Context, prefix string) error {
+	dir := strings.TrimSuffix(prefix, "/")
+	if dir == "" || dir == "." {
+		return fmt.Errorf("refusing to delete storage root")
+	}
+	if err := s.root.RemoveAll(dir); err != nil {
+		return fmt.Errorf("delete storage prefix: %w", err)
+	}
+	return nil
+}
+
+// URL returns the URL for the given storage key. When a signer is configured, private keys get a signed URL that
+// expires; all other keys get a permanent public URL. URL does not check that anyone may read the file: callers must
+// only hand signed URLs to users who can view the channel the file belongs to.
 func (s *LocalStorage) URL(key string) string {
-	return s.baseURL + "/media/" + key
+	u := s.baseURL + "/media/" + key
+	if s.signer != nil && IsPrivateKey(key) {
+		u += "?" + s.signer.Sign(key)
+	}
+	return u
 }
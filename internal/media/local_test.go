@@ -5,9 +5,11 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLocalStorage_PutAndGet(t *testing.T) {
@@ -99,6 +101,29 @@ func TestLocalStorage_URL(t *testing.T) {
 	}
 }
 
+func TestLocalStorage_SignedURL(t *testing.T) {
+	t.Parallel()
+	store := newTestStorage(t)
+	store.SignURLs(NewURLSigner([]byte("0123456789abcdef0123456789abcdef"), time.Hour))
+
+	if got := store.URL("avatars/u/a.webp"); got != "http://localhost:8080/media/avatars/u/a.webp" {
+		t.Errorf("URL(avatar) = %q, want an unsigned public URL", got)
+	}
+
+	u, err := url.Parse(store.URL("attachments/c/f.png"))
+	if err != nil {
+		t.Fatalf("parse URL: %v", err)
+	}
+	if u.Path != "/media/attachments/c/f.png" {
+		t.Errorf("path = %q, want %q", u.Path, "/media/attachments/c/f.png")
+	}
+	q := u.Query()
+	if _, err := store.Signer().Verify("attachments/c/f.png", q.Get(SignedURLExpiresParam),
+		q.Get(SignedURLSignatureParam)); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
 func TestLocalStorage_PutTraversalBlocked(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -2,12 +2,14 @@ package media
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	// Register standard image decoders so image.Decode handles JPEG, PNG, GIF, and WebP input.
 	_ "image/gif"
-	_ "image/jpeg"
-	_ "image/png"
+	"image/jpeg"
+	"image/png"
 	"io"
 
 	"github.com/HugoSmits86/nativewebp"
@@ -49,3 +51,108 @@ func ResizeImage(r io.Reader, maxWidth, maxHeight int) (*bytes.Buffer, error) {
 
 	return &buf, nil
 }
+
+// ImageFormat is an output encoding for transcoded images.
+type ImageFormat string
+
+// Supported output formats for TransformImage.
+const (
+	FormatWebP ImageFormat = "webp"
+	FormatJPEG ImageFormat = "jpeg"
+	FormatPNG  ImageFormat = "png"
+)
+
+// transformJPEGQuality is the quality used when transcoding to JPEG.
+const transformJPEGQuality = 85
+
+// maxTransformPixels bounds the pixel count of a source image that TransformImage will decode, protecting the server
+// from decompression bombs that are small on disk but enormous in memory.
+const maxTransformPixels = 50_000_000
+
+// Sentinel errors for image transformation.
+var (
+	ErrImageTooLarge    = errors.New("image dimensions are too large to transform")
+	ErrImageUndecodable = errors.New("image cannot be decoded")
+)
+
+// ResizableContentTypes maps source MIME types that TransformImage can decode.
+var ResizableContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// IsResizableContentType reports whether TransformImage can decode images of the given MIME type.
+func IsResizableContentType(contentType string) bool {
+	return ResizableContentTypes[normaliseContentType(contentType)]
+}
+
+// ParseImageFormat validates an output format name. "jpg" is accepted as an alias for JPEG.
+func ParseImageFormat(s string) (ImageFormat, bool) {
+	switch s {
+	case "webp":
+		return FormatWebP, true
+	case "jpeg", "jpg":
+		return FormatJPEG, true
+	case "png":
+		return FormatPNG, true
+	default:
+		return "", false
+	}
+}
+
+// Extension returns the file extension, including the leading dot, for the format.
+func (f ImageFormat) Extension() string {
+	if f == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + string(f)
+}
+
+// ContentType returns the MIME type of the format.
+func (f ImageFormat) ContentType() string {
+	return "image/" + string(f)
+}
+
+// TransformImage decodes an image from r, constrains it to fit within maxWidth x maxHeight while preserving aspect ratio
+// (never upscaling), and encodes it in format. Transparent areas are flattened onto white for JPEG output.
+func TransformImage(r io.Reader, maxWidth, maxHeight int, format ImageFormat) (*bytes.Buffer, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImageUndecodable, err)
+	}
+	if cfg.Width*cfg.Height > maxTransformPixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImageUndecodable, err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() > maxWidth || bounds.Dy() > maxHeight {
+		img = imaging.Fit(img, maxWidth, maxHeight, imaging.Lanczos)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatWebP:
+		err = nativewebp.Encode(&buf, img, nil)
+	case FormatJPEG:
+		bg := imaging.New(img.Bounds().Dx(), img.Bounds().Dy(), color.White)
+		err = jpeg.Encode(&buf, imaging.Overlay(bg, img, image.Point{}, 1), &jpeg.Options{Quality: transformJPEGQuality})
+	case FormatPNG:
+		err = png.Encode(&buf, img)
+	default:
+		return nil, fmt.Errorf("unsupported image format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", format, err)
+	}
+	return &buf, nil
+}
@@ -2,6 +2,7 @@ package media
 
 import (
 	"bytes"
+	"errors"
 	"image"
 	"image/color" //nolint:misspell // stdlib package name
 	"image/png"
@@ -125,3 +126,81 @@ func TestIsAvatarContentType(t *testing.T) {
 		})
 	}
 }
+
+func TestTransformImage_Formats(t *testing.T) {
+	t.Parallel()
+	data := createTestPNG(t, 400, 200)
+
+	tests := []struct {
+		format     ImageFormat
+		wantFormat string
+	}{
+		{FormatWebP, "webp"},
+		{FormatJPEG, "jpeg"},
+		{FormatPNG, "png"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			t.Parallel()
+			buf, err := TransformImage(bytes.NewReader(data), 100, 100, tt.format)
+			if err != nil {
+				t.Fatalf("TransformImage() error = %v", err)
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("decode output config: %v", err)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", format, tt.wantFormat)
+			}
+			if cfg.Width != 100 || cfg.Height != 50 {
+				t.Errorf("output dimensions = %dx%d, want 100x50", cfg.Width, cfg.Height)
+			}
+		})
+	}
+}
+
+func TestTransformImage_NoUpscale(t *testing.T) {
+	t.Parallel()
+	buf, err := TransformImage(bytes.NewReader(createTestPNG(t, 40, 30)), 512, 512, FormatPNG)
+	if err != nil {
+		t.Fatalf("TransformImage() error = %v", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("decode output config: %v", err)
+	}
+	if cfg.Width != 40 || cfg.Height != 30 {
+		t.Errorf("output dimensions = %dx%d, want 40x30", cfg.Width, cfg.Height)
+	}
+}
+
+func TestTransformImage_InvalidData(t *testing.T) {
+	t.Parallel()
+	_, err := TransformImage(bytes.NewReader([]byte("not an image")), 64, 64, FormatWebP)
+	if !errors.Is(err, ErrImageUndecodable) {
+		t.Errorf("TransformImage() error = %v, want ErrImageUndecodable", err)
+	}
+}
+
+func TestParseImageFormat(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in     string
+		want   ImageFormat
+		wantOK bool
+	}{
+		{"webp", FormatWebP, true},
+		{"jpeg", FormatJPEG, true},
+		{"jpg", FormatJPEG, true},
+		{"png", FormatPNG, true},
+		{"gif", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseImageFormat(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseImageFormat(%q) = (%q, %v), want (%q, %v)", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
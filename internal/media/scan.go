@@ -0,0 +1,33 @@
+package media
+
+import (
+	"context"
+	"io"
+)
+
+// ScanResult is the verdict of a content scan. Signature names the matched malware signature when Infected is true.
+type ScanResult struct {
+	Infected  bool
+	Signature string
+}
+
+// Scanner inspects uploaded file contents for malware before an attachment is made available for linking to a message.
+// Implementations must read r to completion or return an error; an error means no verdict was reached and the upload
+// must not be treated as clean.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+var (
+	_ Scanner = NoopScanner{}
+	_ Scanner = (*ClamAVScanner)(nil)
+)
+
+// NoopScanner is the default Scanner used when no scanning backend is configured. It reports every file as clean
+// without reading it.
+type NoopScanner struct{}
+
+// Scan reports the file as clean.
+func (NoopScanner) Scan(context.Context, io.Reader) (ScanResult, error) {
+	return ScanResult{}, nil
+}
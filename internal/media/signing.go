@@ -0,0 +1,103 @@
+package media
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters carried by a signed media URL.
+const (
+	SignedURLExpiresParam   = "expires"
+	SignedURLSignatureParam = "signature"
+)
+
+// signingContext separates the media URL key from other uses of the server secret.
+const signingContext = "uncord media url v1"
+
+// privateKeyPrefixes lists the storage key prefixes that hold channel content. Files under these prefixes are only
+// served through signed URLs when signing is enabled; avatars, banners, and custom emoji remain public.
+var privateKeyPrefixes = []string{"attachments/", "thumbnails/"}
+
+// DMAttachmentKeyPrefix is the storage key prefix for end-to-end encrypted DM attachments. See IsRestrictedKey.
+const DMAttachmentKeyPrefix = "dm-attachments/"
+
+// Sentinel errors for signed URL verification.
+var (
+	ErrSignatureInvalid = errors.New("media URL signature is invalid")
+	ErrSignatureExpired = errors.New("media URL has expired")
+)
+
+// IsPrivateKey reports whether key holds channel content that must be served through a signed URL. Cached variants
+// inherit the privacy of the file they were generated from.
+func IsPrivateKey(key string) bool {
+	key = strings.TrimPrefix(key, variantKeyPrefix)
+	for _, prefix := range privateKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRestrictedKey reports whether key holds content that is never served from /media, signed or not. Encrypted DM
+// attachments are only served by the DM attachment endpoint, which checks that the requester is a participant of the
+// DM channel; a signed URL proves only that someone who could see the message once shared it.
+func IsRestrictedKey(key string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(key, variantKeyPrefix), DMAttachmentKeyPrefix)
+}
+
+// URLSigner produces and verifies short-lived HMAC-signed media URLs. A signature covers the storage key and expiry, so
+// a URL cannot be reused for another file or extended.
+type URLSigner struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewURLSigner creates a signer whose URLs stay valid for between ttl/2 and ttl. The signing key is derived from secret
+// so that media signatures cannot be confused with other HMACs made with the same secret.
+func NewURLSigner(secret []byte, ttl time.Duration) *URLSigner {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingContext))
+	return &URLSigner{key: mac.Sum(nil), ttl: ttl, now: time.Now}
+}
+
+// Sign returns the query string (without a leading "?") that authorises access to key. Expiry times are rounded to
+// half the TTL so that repeated requests within the same window produce identical, cacheable URLs.
+func (s *URLSigner) Sign(key string) string {
+	expires := s.now().Truncate(s.ttl / 2).Add(s.ttl).Unix()
+	q := url.Values{}
+	q.Set(SignedURLExpiresParam, strconv.FormatInt(expires, 10))
+	q.Set(SignedURLSignatureParam, s.signature(key, expires))
+	return q.Encode()
+}
+
+// Verify checks the expiry and signature query values for key. It returns the expiry time on success.
+func (s *URLSigner) Verify(key, expires, signature string) (time.Time, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || signature == "" {
+		return time.Time{}, ErrSignatureInvalid
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(key, exp))) {
+		return time.Time{}, ErrSignatureInvalid
+	}
+	expiresAt := time.Unix(exp, 0)
+	if !s.now().Before(expiresAt) {
+		return time.Time{}, ErrSignatureExpired
+	}
+	return expiresAt, nil
+}
+
+func (s *URLSigner) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(key))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
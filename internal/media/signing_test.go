@@ -0,0 +1,128 @@
+package media
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTestSigner(now time.Time) *URLSigner {
+	s := NewURLSigner([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func signedValues(t *testing.T, s *URLSigner, key string) url.Values {
+	t.Helper()
+	q, err := url.ParseQuery(s.Sign(key))
+	if err != nil {
+		t.Fatalf("parse signed query: %v", err)
+	}
+	return q
+}
+
+func TestURLSigner_RoundTrip(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 1, 1, 12, 10, 0, 0, time.UTC)
+	s := newTestSigner(now)
+
+	q := signedValues(t, s, "attachments/a/b.jpg")
+	expiresAt, err := s.Verify("attachments/a/b.jpg", q.Get(SignedURLExpiresParam), q.Get(SignedURLSignatureParam))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	// Expiry is rounded down to the half-TTL window before adding the TTL.
+	if want := time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC); !expiresAt.Equal(want) {
+		t.Errorf("expiresAt = %v, want %v", expiresAt, want)
+	}
+}
+
+func TestURLSigner_StableWithinWindow(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 1, 1, 12, 1, 0, 0, time.UTC)
+	a := newTestSigner(now).Sign("attachments/a/b.jpg")
+	b := newTestSigner(now.Add(20 * time.Minute)).Sign("attachments/a/b.jpg")
+	if a != b {
+		t.Errorf("signatures differ within one window: %q vs %q", a, b)
+	}
+}
+
+func TestURLSigner_WrongKey(t *testing.T) {
+	t.Parallel()
+	s := newTestSigner(time.Now())
+	q := signedValues(t, s, "attachments/a/b.jpg")
+	_, err := s.Verify("attachments/a/c.jpg", q.Get(SignedURLExpiresParam), q.Get(SignedURLSignatureParam))
+	if !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Verify() error = %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestURLSigner_TamperedExpiry(t *testing.T) {
+	t.Parallel()
+	s := newTestSigner(time.Now())
+	q := signedValues(t, s, "attachments/a/b.jpg")
+	_, err := s.Verify("attachments/a/b.jpg", "99999999999", q.Get(SignedURLSignatureParam))
+	if !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Verify() error = %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestURLSigner_Expired(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	q := signedValues(t, newTestSigner(now), "attachments/a/b.jpg")
+
+	later := newTestSigner(now.Add(2 * time.Hour))
+	_, err := later.Verify("attachments/a/b.jpg", q.Get(SignedURLExpiresParam), q.Get(SignedURLSignatureParam))
+	if !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("Verify() error = %v, want ErrSignatureExpired", err)
+	}
+}
+
+func TestURLSigner_DifferentSecret(t *testing.T) {
+	t.Parallel()
+	q := signedValues(t, newTestSigner(time.Now()), "attachments/a/b.jpg")
+	other := NewURLSigner([]byte("another-secret-another-secret-32"), time.Hour)
+	_, err := other.Verify("attachments/a/b.jpg", q.Get(SignedURLExpiresParam), q.Get(SignedURLSignatureParam))
+	if !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Verify() error = %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestIsPrivateKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"attachments/c/f.png", true},
+		{"thumbnails/f.jpg", true},
+		{"avatars/u/a.webp", false},
+		{"banners/u/b.webp", false},
+		{"emoji/e.webp", false},
+	}
+	for _, tt := range tests {
+		if got := IsPrivateKey(tt.key); got != tt.want {
+			t.Errorf("IsPrivateKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestIsRestrictedKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"dm-attachments/c/f", true},
+		{"variants/dm-attachments/c/f_w64.webp", true},
+		{"attachments/c/f.png", false},
+		{"avatars/u/a.webp", false},
+	}
+	for _, tt := range tests {
+		if got := IsRestrictedKey(tt.key); got != tt.want {
+			t.Errorf("IsRestrictedKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
@@ -85,7 +85,8 @@ var AllowedContentTypes = map[string]bool{
 }
 
 // ImageContentTypes maps MIME types eligible for thumbnail generation. SVG is excluded because it is a vector format
-// that does not benefit from raster resizing.
+// that does not benefit from raster resizing. AVIF has no pure Go decoder and is only thumbnailed when ffmpeg is
+// available.
 var ImageContentTypes = map[string]bool{
 	"image/jpeg": true,
 	"image/png":  true,
@@ -93,6 +94,26 @@ var ImageContentTypes = map[string]bool{
 	"image/webp": true,
 	"image/bmp":  true,
 	"image/tiff": true,
+	"image/avif": true,
+}
+
+// VideoContentTypes maps MIME types eligible for poster frame extraction and duration/dimension probing.
+var VideoContentTypes = map[string]bool{
+	"video/mp4":       true,
+	"video/webm":      true,
+	"video/ogg":       true,
+	"video/quicktime": true,
+}
+
+// AudioContentTypes maps MIME types eligible for duration probing and waveform generation.
+var AudioContentTypes = map[string]bool{
+	"audio/mpeg":  true,
+	"audio/ogg":   true,
+	"audio/wav":   true,
+	"audio/webm":  true,
+	"audio/flac":  true,
+	"audio/aac":   true,
+	"audio/x-m4a": true,
 }
 
 // IsAllowedContentType reports whether the given MIME type is accepted for upload.
@@ -105,6 +126,16 @@ func IsImageContentType(contentType string) bool {
 	return ImageContentTypes[normaliseContentType(contentType)]
 }
 
+// IsVideoContentType reports whether the given MIME type is eligible for poster frame extraction.
+func IsVideoContentType(contentType string) bool {
+	return VideoContentTypes[normaliseContentType(contentType)]
+}
+
+// IsAudioContentType reports whether the given MIME type is eligible for waveform generation.
+func IsAudioContentType(contentType string) bool {
+	return AudioContentTypes[normaliseContentType(contentType)]
+}
+
 // ExtensionFromFilename extracts the file extension from a filename, including the leading dot (e.g. ".jpg"). Returns
 // an empty string when the filename has no extension.
 func ExtensionFromFilename(filename string) string {
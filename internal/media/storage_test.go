@@ -54,10 +54,10 @@ func TestIsImageContentType(t *testing.T) {
 		{"webp is image", "image/webp", true},
 		{"bmp is image", "image/bmp", true},
 		{"tiff is image", "image/tiff", true},
+		{"avif is image", "image/avif", true},
 
 		// SVG excluded from thumbnails
 		{"svg excluded", "image/svg+xml", false},
-		{"avif excluded", "image/avif", false},
 
 		// Non-images
 		{"video not image", "video/mp4", false},
@@ -73,6 +73,55 @@ func TestIsImageContentType(t *testing.T) {
 	}
 }
 
+func TestIsVideoContentType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		contentType string
+		want        bool
+	}{
+		{"mp4 is video", "video/mp4", true},
+		{"webm is video", "video/webm", true},
+		{"quicktime is video", "video/quicktime", true},
+		{"uppercase is video", "Video/MP4", true},
+		{"audio not video", "audio/webm", false},
+		{"image not video", "image/gif", false},
+		{"empty string", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsVideoContentType(tt.contentType); got != tt.want {
+				t.Errorf("IsVideoContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAudioContentType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		contentType string
+		want        bool
+	}{
+		{"mpeg is audio", "audio/mpeg", true},
+		{"ogg is audio", "audio/ogg", true},
+		{"m4a is audio", "audio/x-m4a", true},
+		{"with parameter", "audio/ogg; codecs=opus", true},
+		{"video not audio", "video/webm", false},
+		{"empty string", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAudioContentType(tt.contentType); got != tt.want {
+				t.Errorf("IsAudioContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestExtensionFromFilename(t *testing.T) {
 	t.Parallel()
 
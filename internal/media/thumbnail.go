@@ -10,13 +10,22 @@ import (
 	_ "image/gif" // Register GIF decoder for image.Decode
 	"image/jpeg"
 	_ "image/png" // Register PNG decoder for image.Decode
+	"io"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/disintegration/imaging"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	_ "golang.org/x/image/bmp"  // Register BMP decoder for image.Decode
+	_ "golang.org/x/image/tiff" // Register TIFF decoder for image.Decode
+
+	"github.com/uncord-chat/uncord-server/internal/attachment"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
 )
 
 const (
@@ -36,32 +45,77 @@ const (
 // errPermanent wraps an error to indicate that retrying will not help (e.g. corrupt image, invalid UUID).
 var errPermanent = errors.New("permanent")
 
-// ThumbnailJob describes a pending thumbnail generation task.
+// JobKind identifies the processing step a media job performs.
+type JobKind string
+
+// Media job kinds. Jobs enqueued before kinds were introduced carry no kind and are treated as JobImageThumbnail.
+const (
+	JobImageThumbnail JobKind = "image_thumbnail"
+	JobVideoPoster    JobKind = "video_poster"
+	JobMediaProbe     JobKind = "media_probe"
+	JobAudioWaveform  JobKind = "audio_waveform"
+)
+
+// JobsForContentType returns the media jobs to enqueue for a newly uploaded attachment of the given MIME type. Returns
+// nil for types that need no background processing.
+func JobsForContentType(contentType string) []JobKind {
+	switch {
+	case IsImageContentType(contentType):
+		return []JobKind{JobImageThumbnail}
+	case IsVideoContentType(contentType):
+		return []JobKind{JobMediaProbe, JobVideoPoster}
+	case IsAudioContentType(contentType):
+		return []JobKind{JobMediaProbe, JobAudioWaveform}
+	default:
+		return nil
+	}
+}
+
+// ThumbnailJob describes a pending media processing task for a single attachment.
 type ThumbnailJob struct {
-	AttachmentID string `json:"attachment_id"`
-	StorageKey   string `json:"storage_key"`
-	ContentType  string `json:"content_type"`
+	Kind         JobKind `json:"kind,omitempty"`
+	AttachmentID string  `json:"attachment_id"`
+	StorageKey   string  `json:"storage_key"`
+	ContentType  string  `json:"content_type"`
 }
 
-// ThumbnailKeyUpdater records generated thumbnail keys. Satisfied by attachment.Repository.
-type ThumbnailKeyUpdater interface {
+// AttachmentUpdater records the results of media processing. Satisfied by attachment.Repository.
+type AttachmentUpdater interface {
 	SetThumbnailKey(ctx context.Context, id uuid.UUID, thumbnailKey string) error
+	SetMediaMetadata(ctx context.Context, id uuid.UUID, meta attachment.MediaMetadata) error
 }
 
-// ThumbnailWorker consumes thumbnail generation jobs from a Valkey stream and produces JPEG thumbnails.
+// ThumbnailWorker consumes media jobs from a Valkey stream. Image jobs produce JPEG thumbnails using the Go image
+// decoders. Video and audio jobs (poster frames, probing, waveforms) and AVIF decoding require ffmpeg; when ffmpeg is nil
+// those jobs are acknowledged and skipped.
 type ThumbnailWorker struct {
 	rdb     *redis.Client
 	storage StorageProvider
-	updater ThumbnailKeyUpdater
+	updater AttachmentUpdater
+	ffmpeg  *FFmpeg
 	log     zerolog.Logger
+
+	succeeded atomic.Int64
+	retried   atomic.Int64
+	failed    atomic.Int64
+}
+
+// JobCounters are cumulative totals of the media jobs a ThumbnailWorker has processed since it was created. Retried
+// counts failed attempts that were left for redelivery; Failed counts jobs that were discarded, including malformed
+// ones.
+type JobCounters struct {
+	Succeeded int64
+	Retried   int64
+	Failed    int64
 }
 
-// NewThumbnailWorker creates a worker that processes thumbnail jobs.
-func NewThumbnailWorker(rdb *redis.Client, storage StorageProvider, updater ThumbnailKeyUpdater, logger zerolog.Logger) *ThumbnailWorker {
+// NewThumbnailWorker creates a worker that processes media jobs. ffmpeg may be nil.
+func NewThumbnailWorker(rdb *redis.Client, storage StorageProvider, updater AttachmentUpdater, ffmpeg *FFmpeg, logger zerolog.Logger) *ThumbnailWorker {
 	return &ThumbnailWorker{
 		rdb:     rdb,
 		storage: storage,
 		updater: updater,
+		ffmpeg:  ffmpeg,
 		log:     logger,
 	}
 }
@@ -74,7 +128,7 @@ func (w *ThumbnailWorker) EnsureStream(ctx context.Context) {
 	}
 }
 
-// Run reads and processes thumbnail jobs until the context is cancelled. Transient failures leave the message
+// Run reads and processes media jobs until the context is cancelled. Transient failures leave the message
 // unacknowledged so it can be reclaimed on the next iteration. Permanent failures and messages that exceed the maximum
 // retry count are acknowledged and discarded.
 func (w *ThumbnailWorker) Run(ctx context.Context) error {
@@ -132,6 +186,7 @@ func (w *ThumbnailWorker) processJob(ctx context.Context, msg redis.XMessage) {
 	raw, ok := msg.Values["job"]
 	if !ok {
 		w.log.Warn().Str("message_id", msg.ID).Msg("Thumbnail job missing 'job' field")
+		w.failed.Add(1)
 		w.ack(ctx, msg.ID)
 		return
 	}
@@ -139,6 +194,7 @@ func (w *ThumbnailWorker) processJob(ctx context.Context, msg redis.XMessage) {
 	rawStr, ok := raw.(string)
 	if !ok {
 		w.log.Warn().Str("message_id", msg.ID).Msg("Thumbnail job 'job' field is not a string")
+		w.failed.Add(1)
 		w.ack(ctx, msg.ID)
 		return
 	}
@@ -146,37 +202,167 @@ func (w *ThumbnailWorker) processJob(ctx context.Context, msg redis.XMessage) {
 	var job ThumbnailJob
 	if err := json.Unmarshal([]byte(rawStr), &job); err != nil {
 		w.log.Warn().Err(err).Str("message_id", msg.ID).Msg("Failed to unmarshal thumbnail job")
+		w.failed.Add(1)
 		w.ack(ctx, msg.ID)
 		return
 	}
 
-	if err := w.generateThumbnail(ctx, job); err != nil {
+	// Jobs enqueued within a trace are processed under a consumer span of that trace.
+	if tc, ok := msg.Values["tc"].(string); ok {
+		var carrier map[string]string
+		if json.Unmarshal([]byte(tc), &carrier) == nil {
+			var span trace.Span
+			ctx, span = tracing.Tracer().Start(tracing.Extract(ctx, carrier), "media job "+string(job.Kind),
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(semconv.MessagingDestinationName(thumbnailStream)),
+			)
+			defer span.End()
+		}
+	}
+
+	if err := w.process(ctx, job); err != nil {
+		tracing.RecordError(trace.SpanFromContext(ctx), err)
 		if errors.Is(err, errPermanent) || w.deliveryCount(ctx, msg.ID) >= maxRetries {
-			w.log.Warn().Err(err).Str("attachment_id", job.AttachmentID).Msg("Thumbnail generation failed permanently")
+			w.log.Warn().Ctx(ctx).Err(err).Str("attachment_id", job.AttachmentID).Str("kind", string(job.Kind)).
+				Msg("Media job failed permanently")
+			w.failed.Add(1)
 			w.ack(ctx, msg.ID)
 			return
 		}
-		w.log.Warn().Err(err).Str("attachment_id", job.AttachmentID).Msg("Thumbnail generation failed, will retry")
+		w.log.Warn().Ctx(ctx).Err(err).Str("attachment_id", job.AttachmentID).Str("kind", string(job.Kind)).
+			Msg("Media job failed, will retry")
+		w.retried.Add(1)
 		return
 	}
+	w.succeeded.Add(1)
 	w.ack(ctx, msg.ID)
 }
 
-func (w *ThumbnailWorker) generateThumbnail(ctx context.Context, job ThumbnailJob) error {
-	rc, err := w.storage.Get(ctx, job.StorageKey)
+// Counters returns the worker's cumulative job outcomes.
+func (w *ThumbnailWorker) Counters() JobCounters {
+	return JobCounters{Succeeded: w.succeeded.Load(), Retried: w.retried.Load(), Failed: w.failed.Load()}
+}
+
+// Backlog returns the number of thumbnail stream entries the consumer group has yet to finish: entries delivered to a
+// worker but not acknowledged, plus entries not yet delivered. Redis reports the undelivered count as unknown after
+// some stream deletions, in which case only the pending entries are counted.
+func (w *ThumbnailWorker) Backlog(ctx context.Context) (int64, error) {
+	groups, err := w.rdb.XInfoGroups(ctx, thumbnailStream).Result()
 	if err != nil {
-		if errors.Is(err, ErrStorageKeyNotFound) {
-			return fmt.Errorf("read original: %w", errors.Join(err, errPermanent))
+		return 0, fmt.Errorf("read thumbnail consumer groups: %w", err)
+	}
+	for _, g := range groups {
+		if g.Name == consumerGroup {
+			return g.Pending + max(g.Lag, 0), nil
+		}
+	}
+	return 0, fmt.Errorf("consumer group %q not found on %s", consumerGroup, thumbnailStream)
+}
+
+// process dispatches a job to the handler for its kind.
+func (w *ThumbnailWorker) process(ctx context.Context, job ThumbnailJob) error {
+	attachmentID, err := uuid.Parse(job.AttachmentID)
+	if err != nil {
+		return fmt.Errorf("parse attachment id: %w", errors.Join(err, errPermanent))
+	}
+
+	switch job.Kind {
+	case "", JobImageThumbnail:
+		return w.generateThumbnail(ctx, attachmentID, job)
+	case JobVideoPoster, JobMediaProbe, JobAudioWaveform:
+		if w.ffmpeg == nil {
+			w.log.Debug().Str("attachment_id", job.AttachmentID).Str("kind", string(job.Kind)).
+				Msg("Skipping media job because ffmpeg is not available")
+			return nil
 		}
-		return fmt.Errorf("read original: %w", err)
+		return w.withLocalCopy(ctx, job.StorageKey, func(path string) error {
+			switch job.Kind {
+			case JobVideoPoster:
+				return w.generatePoster(ctx, attachmentID, path)
+			case JobMediaProbe:
+				return w.probe(ctx, attachmentID, path)
+			default:
+				return w.generateWaveform(ctx, attachmentID, path)
+			}
+		})
+	default:
+		return fmt.Errorf("unknown job kind %q: %w", job.Kind, errPermanent)
 	}
-	defer func() { _ = rc.Close() }()
+}
 
-	img, _, err := image.Decode(rc)
+// generateThumbnail decodes an image with the Go image decoders and stores a JPEG thumbnail. Formats the Go decoders
+// do not support (e.g. AVIF) are decoded through ffmpeg when it is available.
+func (w *ThumbnailWorker) generateThumbnail(ctx context.Context, attachmentID uuid.UUID, job ThumbnailJob) error {
+	rc, err := w.openOriginal(ctx, job.StorageKey)
 	if err != nil {
-		return fmt.Errorf("decode image: %w", errors.Join(err, errPermanent))
+		return err
+	}
+	img, _, decodeErr := image.Decode(rc)
+	_ = rc.Close()
+
+	if decodeErr != nil {
+		if w.ffmpeg == nil {
+			return fmt.Errorf("decode image: %w", errors.Join(decodeErr, errPermanent))
+		}
+		err := w.withLocalCopy(ctx, job.StorageKey, func(path string) error {
+			var ffErr error
+			img, ffErr = w.ffmpeg.DecodeImage(ctx, path)
+			return ffErr
+		})
+		if err != nil {
+			return fmt.Errorf("decode image: %w", errors.Join(decodeErr, err, errPermanent))
+		}
+	}
+
+	if err := w.storeThumbnail(ctx, attachmentID, img); err != nil {
+		return err
 	}
+	w.log.Debug().Str("attachment_id", job.AttachmentID).Msg("Thumbnail generated")
+	return nil
+}
 
+// generatePoster extracts a poster frame from the video at path and stores it as the attachment's thumbnail.
+func (w *ThumbnailWorker) generatePoster(ctx context.Context, attachmentID uuid.UUID, path string) error {
+	img, err := w.ffmpeg.PosterFrame(ctx, path)
+	if err != nil {
+		return fmt.Errorf("extract poster frame: %w", errors.Join(err, errPermanent))
+	}
+	if err := w.storeThumbnail(ctx, attachmentID, img); err != nil {
+		return err
+	}
+	w.log.Debug().Str("attachment_id", attachmentID.String()).Msg("Video poster frame generated")
+	return nil
+}
+
+// probe records the dimensions and duration of the video or audio file at path.
+func (w *ThumbnailWorker) probe(ctx context.Context, attachmentID uuid.UUID, path string) error {
+	result, err := w.ffmpeg.Probe(ctx, path)
+	if err != nil {
+		return fmt.Errorf("probe media: %w", errors.Join(err, errPermanent))
+	}
+	meta := attachment.MediaMetadata{Width: result.Width, Height: result.Height, DurationMS: result.DurationMS}
+	if err := w.updater.SetMediaMetadata(ctx, attachmentID, meta); err != nil {
+		return fmt.Errorf("update media metadata: %w", err)
+	}
+	w.log.Debug().Str("attachment_id", attachmentID.String()).Msg("Media probed")
+	return nil
+}
+
+// generateWaveform computes the amplitude waveform of the audio file at path.
+func (w *ThumbnailWorker) generateWaveform(ctx context.Context, attachmentID uuid.UUID, path string) error {
+	waveform, err := w.ffmpeg.Waveform(ctx, path)
+	if err != nil {
+		return fmt.Errorf("generate waveform: %w", errors.Join(err, errPermanent))
+	}
+	if err := w.updater.SetMediaMetadata(ctx, attachmentID, attachment.MediaMetadata{Waveform: waveform}); err != nil {
+		return fmt.Errorf("update media metadata: %w", err)
+	}
+	w.log.Debug().Str("attachment_id", attachmentID.String()).Msg("Audio waveform generated")
+	return nil
+}
+
+// storeThumbnail scales img to the thumbnail width, writes it to storage as JPEG, and records the key.
+func (w *ThumbnailWorker) storeThumbnail(ctx context.Context, attachmentID uuid.UUID, img image.Image) error {
 	thumb := imaging.Resize(img, thumbnailWidth, 0, imaging.Lanczos)
 
 	var buf bytes.Buffer
@@ -184,24 +370,45 @@ func (w *ThumbnailWorker) generateThumbnail(ctx context.Context, job ThumbnailJo
 		return fmt.Errorf("encode thumbnail: %w", errors.Join(err, errPermanent))
 	}
 
-	thumbnailKey := "thumbnails/" + job.AttachmentID + ".jpg"
+	thumbnailKey := "thumbnails/" + attachmentID.String() + ".jpg"
 	if err := w.storage.Put(ctx, thumbnailKey, &buf); err != nil {
 		return fmt.Errorf("write thumbnail: %w", err)
 	}
 
-	attachmentID, err := uuid.Parse(job.AttachmentID)
-	if err != nil {
-		return fmt.Errorf("parse attachment id: %w", errors.Join(err, errPermanent))
-	}
-
 	if err := w.updater.SetThumbnailKey(ctx, attachmentID, thumbnailKey); err != nil {
 		return fmt.Errorf("update thumbnail key: %w", err)
 	}
-
-	w.log.Debug().Str("attachment_id", job.AttachmentID).Msg("Thumbnail generated")
 	return nil
 }
 
+// openOriginal opens the uploaded file. A missing key is permanent because the attachment was purged or never stored.
+func (w *ThumbnailWorker) openOriginal(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, err := w.storage.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrStorageKeyNotFound) {
+			return nil, fmt.Errorf("read original: %w", errors.Join(err, errPermanent))
+		}
+		return nil, fmt.Errorf("read original: %w", err)
+	}
+	return rc, nil
+}
+
+// withLocalCopy copies the stored file at key to a temporary file for the duration of fn, because ffmpeg needs a
+// seekable path rather than a stream.
+func (w *ThumbnailWorker) withLocalCopy(ctx context.Context, key string, fn func(path string) error) error {
+	rc, err := w.openOriginal(ctx, key)
+	if err != nil {
+		return err
+	}
+	path, cleanup, err := spoolToTemp(rc)
+	_ = rc.Close()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return fn(path)
+}
+
 // deliveryCount returns how many times the given message has been delivered to a consumer. Returns maxRetries on error
 // so the caller treats it as exhausted rather than retrying indefinitely.
 func (w *ThumbnailWorker) deliveryCount(ctx context.Context, messageID string) int64 {
@@ -224,14 +431,23 @@ func (w *ThumbnailWorker) ack(ctx context.Context, messageID string) {
 	}
 }
 
-// EnqueueThumbnail adds a thumbnail generation job to the stream.
+// EnqueueThumbnail adds a media processing job to the stream. Jobs with an empty Kind are processed as image thumbnails.
+// The trace context of ctx travels with the job so that processing joins the trace of the upload.
 func EnqueueThumbnail(ctx context.Context, rdb *redis.Client, job ThumbnailJob) error {
 	data, err := json.Marshal(job)
 	if err != nil {
-		return fmt.Errorf("marshal thumbnail job: %w", err)
+		return fmt.Errorf("marshal media job: %w", err)
+	}
+	values := map[string]any{"job": string(data)}
+	if carrier := tracing.Inject(ctx); carrier != nil {
+		tc, err := json.Marshal(carrier)
+		if err != nil {
+			return fmt.Errorf("marshal trace context: %w", err)
+		}
+		values["tc"] = string(tc)
 	}
 	return rdb.XAdd(ctx, &redis.XAddArgs{
 		Stream: thumbnailStream,
-		Values: map[string]any{"job": string(data)},
+		Values: values,
 	}).Err()
 }
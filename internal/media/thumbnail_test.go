@@ -4,24 +4,29 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"image"
 	"image/color" //nolint:misspell // Go standard library uses American English
 	"image/png"
+	"slices"
 	"testing"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
+
+	"github.com/uncord-chat/uncord-server/internal/attachment"
 )
 
-// fakeUpdater records SetThumbnailKey calls for test assertions.
+// fakeUpdater records SetThumbnailKey and SetMediaMetadata calls for test assertions.
 type fakeUpdater struct {
 	calls map[uuid.UUID]string
+	meta  map[uuid.UUID]attachment.MediaMetadata
 }
 
 func newFakeUpdater() *fakeUpdater {
-	return &fakeUpdater{calls: make(map[uuid.UUID]string)}
+	return &fakeUpdater{calls: make(map[uuid.UUID]string), meta: make(map[uuid.UUID]attachment.MediaMetadata)}
 }
 
 func (f *fakeUpdater) SetThumbnailKey(_ context.Context, id uuid.UUID, key string) error {
@@ -29,6 +34,11 @@ func (f *fakeUpdater) SetThumbnailKey(_ context.Context, id uuid.UUID, key strin
 	return nil
 }
 
+func (f *fakeUpdater) SetMediaMetadata(_ context.Context, id uuid.UUID, meta attachment.MediaMetadata) error {
+	f.meta[id] = meta
+	return nil
+}
+
 func TestEnqueueThumbnail(t *testing.T) {
 	t.Parallel()
 	mr := miniredis.RunT(t)
@@ -98,15 +108,15 @@ func TestThumbnailWorker_GenerateThumbnail(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
 	defer func() { _ = rdb.Close() }()
 
-	worker := NewThumbnailWorker(rdb, store, updater, zerolog.Nop())
+	worker := NewThumbnailWorker(rdb, store, updater, nil, zerolog.Nop())
 
 	job := ThumbnailJob{
 		AttachmentID: attachmentID.String(),
 		StorageKey:   storageKey,
 		ContentType:  "image/png",
 	}
-	if err := worker.generateThumbnail(ctx, job); err != nil {
-		t.Fatalf("generateThumbnail() error: %v", err)
+	if err := worker.process(ctx, job); err != nil {
+		t.Fatalf("process() error: %v", err)
 	}
 
 	expectedKey := "thumbnails/" + attachmentID.String() + ".jpg"
@@ -134,3 +144,140 @@ func TestThumbnailWorker_GenerateThumbnail(t *testing.T) {
 		t.Errorf("thumbnail width = %d, want %d", bounds.Dx(), thumbnailWidth)
 	}
 }
+
+func TestThumbnailWorker_UndecodableImageWithoutFFmpegIsPermanent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	store, err := NewLocalStorage(t.TempDir(), "http://localhost:8080")
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	storageKey := "attachments/test.avif"
+	if err := store.Put(ctx, storageKey, bytes.NewReader([]byte("not an image"))); err != nil {
+		t.Fatalf("store.Put() error: %v", err)
+	}
+
+	worker := NewThumbnailWorker(nil, store, newFakeUpdater(), nil, zerolog.Nop())
+	err = worker.process(ctx, ThumbnailJob{
+		Kind:         JobImageThumbnail,
+		AttachmentID: uuid.New().String(),
+		StorageKey:   storageKey,
+		ContentType:  "image/avif",
+	})
+	if !errors.Is(err, errPermanent) {
+		t.Fatalf("process() error = %v, want errPermanent", err)
+	}
+}
+
+func TestThumbnailWorker_SkipsMediaJobsWithoutFFmpeg(t *testing.T) {
+	t.Parallel()
+
+	updater := newFakeUpdater()
+	worker := NewThumbnailWorker(nil, nil, updater, nil, zerolog.Nop())
+
+	for _, kind := range []JobKind{JobVideoPoster, JobMediaProbe, JobAudioWaveform} {
+		err := worker.process(context.Background(), ThumbnailJob{
+			Kind:         kind,
+			AttachmentID: uuid.New().String(),
+			StorageKey:   "attachments/clip.mp4",
+			ContentType:  "video/mp4",
+		})
+		if err != nil {
+			t.Errorf("process(%s) error = %v, want nil", kind, err)
+		}
+	}
+	if len(updater.calls) != 0 || len(updater.meta) != 0 {
+		t.Errorf("updater called %d/%d times, want 0", len(updater.calls), len(updater.meta))
+	}
+}
+
+func TestThumbnailWorker_UnknownKindIsPermanent(t *testing.T) {
+	t.Parallel()
+
+	worker := NewThumbnailWorker(nil, nil, newFakeUpdater(), nil, zerolog.Nop())
+	err := worker.process(context.Background(), ThumbnailJob{
+		Kind:         "hologram",
+		AttachmentID: uuid.New().String(),
+	})
+	if !errors.Is(err, errPermanent) {
+		t.Fatalf("process() error = %v, want errPermanent", err)
+	}
+}
+
+func TestJobsForContentType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		contentType string
+		want        []JobKind
+	}{
+		{"image/png", []JobKind{JobImageThumbnail}},
+		{"image/avif", []JobKind{JobImageThumbnail}},
+		{"video/mp4", []JobKind{JobMediaProbe, JobVideoPoster}},
+		{"audio/ogg", []JobKind{JobMediaProbe, JobAudioWaveform}},
+		{"application/pdf", nil},
+		{"image/svg+xml", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := JobsForContentType(tt.contentType); !slices.Equal(got, tt.want) {
+				t.Errorf("JobsForContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnailWorker_Counters(t *testing.T) {
+	t.Parallel()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = rdb.Close() }()
+
+	worker := NewThumbnailWorker(rdb, nil, newFakeUpdater(), nil, zerolog.Nop())
+	worker.EnsureStream(context.Background())
+
+	skipped, _ := json.Marshal(ThumbnailJob{Kind: JobMediaProbe, AttachmentID: uuid.New().String()})
+	unknown, _ := json.Marshal(ThumbnailJob{Kind: "hologram", AttachmentID: uuid.New().String()})
+	for _, values := range []map[string]any{
+		{"job": string(skipped)},
+		{"job": string(unknown)},
+		{"other": "field"},
+	} {
+		worker.processJob(context.Background(), redis.XMessage{ID: "0-1", Values: values})
+	}
+
+	want := JobCounters{Succeeded: 1, Failed: 2}
+	if got := worker.Counters(); got != want {
+		t.Errorf("Counters() = %+v, want %+v", got, want)
+	}
+}
+
+func TestThumbnailWorker_Backlog(t *testing.T) {
+	t.Parallel()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = rdb.Close() }()
+
+	ctx := context.Background()
+	worker := NewThumbnailWorker(rdb, nil, newFakeUpdater(), nil, zerolog.Nop())
+	if _, err := worker.Backlog(ctx); err == nil {
+		t.Fatal("Backlog() before EnsureStream expected an error")
+	}
+	worker.EnsureStream(ctx)
+
+	for range 3 {
+		if err := EnqueueThumbnail(ctx, rdb, ThumbnailJob{AttachmentID: uuid.New().String()}); err != nil {
+			t.Fatalf("EnqueueThumbnail() error: %v", err)
+		}
+	}
+	got, err := worker.Backlog(ctx)
+	if err != nil {
+		t.Fatalf("Backlog() error: %v", err)
+	}
+	if got != 3 {
+		t.Errorf("Backlog() = %d, want 3", got)
+	}
+}
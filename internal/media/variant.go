@@ -0,0 +1,160 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// variantKeyPrefix is the storage key prefix under which generated image variants are cached. A variant of key K lives
+// at variantKeyPrefix + K + "/<size>.<ext>", so all variants of one file share a directory.
+const variantKeyPrefix = "variants/"
+
+// VariantSizes is the fixed set of bounding box edge lengths an image variant may be constrained to. Requested
+// dimensions are snapped up to the next allowed size, and width and height collapse into a single square box, so each
+// file has at most len(VariantSizes) variants per output format. Keep this list short: every entry is another full
+// decode and resize an attacker can trigger per file.
+var VariantSizes = []int{64, 256, 1024}
+
+// ErrInvalidVariant is returned when variant parameters cannot be parsed.
+var ErrInvalidVariant = errors.New("invalid image variant parameters")
+
+// Variant describes a resized and transcoded rendition of a stored image. The image is fitted within a Size x Size box,
+// preserving its aspect ratio.
+type Variant struct {
+	Size   int
+	Format ImageFormat
+}
+
+// ParseVariant builds a Variant from raw width, height, and format query values. The larger of the two dimensions is
+// snapped up to the nearest entry in VariantSizes; empty values default to the largest allowed size and WebP.
+func ParseVariant(width, height, format string) (Variant, error) {
+	w, err := parseVariantDimension(width)
+	if err != nil {
+		return Variant{}, err
+	}
+	h, err := parseVariantDimension(height)
+	if err != nil {
+		return Variant{}, err
+	}
+	f := FormatWebP
+	if format != "" {
+		var ok bool
+		if f, ok = ParseImageFormat(format); !ok {
+			return Variant{}, fmt.Errorf("%w: format must be webp, jpeg, or png", ErrInvalidVariant)
+		}
+	}
+	return Variant{Size: snapVariantSize(max(w, h)), Format: f}, nil
+}
+
+// parseVariantDimension parses a requested dimension. An empty value parses as zero, meaning unconstrained.
+func parseVariantDimension(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: dimensions must be positive integers", ErrInvalidVariant)
+	}
+	return n, nil
+}
+
+// snapVariantSize rounds n up to the nearest allowed size. Zero, and anything above the largest size, yield the largest.
+func snapVariantSize(n int) int {
+	for _, size := range VariantSizes {
+		if n > 0 && n <= size {
+			return size
+		}
+	}
+	return VariantSizes[len(VariantSizes)-1]
+}
+
+// IsVariantKey reports whether key names a cached variant rather than an original file.
+func IsVariantKey(key string) bool {
+	return strings.HasPrefix(key, variantKeyPrefix)
+}
+
+// Key returns the storage key under which the variant of source is cached.
+func (v Variant) Key(source string) string {
+	return fmt.Sprintf("%s%s/%d%s", variantKeyPrefix, source, v.Size, v.Format.Extension())
+}
+
+// VariantStore generates image variants on demand and caches them in a StorageProvider. Concurrent requests for the same
+// variant are coalesced so each variant is generated and written once.
+type VariantStore struct {
+	storage StorageProvider
+	group   singleflight.Group
+}
+
+// NewVariantStore creates a variant store that reads originals from and caches variants in storage.
+func NewVariantStore(storage StorageProvider) *VariantStore {
+	return &VariantStore{storage: storage}
+}
+
+// Get returns the encoded variant of the image at source, generating and caching it on first use. Returns
+// ErrStorageKeyNotFound when the source does not exist.
+func (s *VariantStore) Get(ctx context.Context, source string, v Variant) ([]byte, error) {
+	key := v.Key(source)
+	data, err, _ := s.group.Do(key, func() (any, error) {
+		if cached, err := s.read(ctx, key); err == nil {
+			return cached, nil
+		} else if !errors.Is(err, ErrStorageKeyNotFound) {
+			return nil, err
+		}
+
+		rc, err := s.storage.Get(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rc.Close() }()
+
+		buf, err := TransformImage(rc, v.Size, v.Size, v.Format)
+		if err != nil {
+			return nil, err
+		}
+		out := buf.Bytes()
+		if err := s.storage.Put(ctx, key, bytes.NewReader(out)); err != nil {
+			return nil, fmt.Errorf("cache image variant: %w", err)
+		}
+		return out, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data.([]byte), nil
+}
+
+func (s *VariantStore) read(ctx context.Context, key string) ([]byte, error) {
+	rc, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
+
+// prefixDeleter is implemented by storage providers that can remove every key under a prefix in one call.
+type prefixDeleter interface {
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// DeleteWithVariants deletes the file at key and every cached variant of it. Callers that remove an image, such as a
+// replaced avatar or a deleted emoji, use this so that its variants do not outlive it.
+func DeleteWithVariants(ctx context.Context, storage StorageProvider, key string) error {
+	return errors.Join(storage.Delete(ctx, key), DeleteVariants(ctx, storage, key))
+}
+
+// DeleteVariants removes every cached variant of source. Providers that cannot delete by prefix are left untouched.
+func DeleteVariants(ctx context.Context, storage StorageProvider, source string) error {
+	pd, ok := storage.(prefixDeleter)
+	if !ok {
+		return nil
+	}
+	return pd.DeletePrefix(ctx, variantKeyPrefix+strings.TrimSuffix(source, "/")+"/")
+}
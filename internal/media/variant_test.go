@@ -0,0 +1,138 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestParseVariant(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                  string
+		width, height, format string
+		want                  Variant
+		wantErr               bool
+	}{
+		{"defaults", "", "", "", Variant{1024, FormatWebP}, false},
+		{"exact size", "256", "128", "png", Variant{256, FormatPNG}, false},
+		{"larger dimension wins", "100", "300", "jpg", Variant{1024, FormatJPEG}, false},
+		{"height only", "", "200", "", Variant{256, FormatWebP}, false},
+		{"clamps to largest", "10000", "", "", Variant{1024, FormatWebP}, false},
+		{"tiny", "1", "1", "", Variant{64, FormatWebP}, false},
+		{"zero width", "0", "", "", Variant{}, true},
+		{"non-numeric", "abc", "", "", Variant{}, true},
+		{"bad format", "64", "", "bmp", Variant{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseVariant(tt.width, tt.height, tt.format)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidVariant) {
+					t.Errorf("ParseVariant() error = %v, want ErrInvalidVariant", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseVariant() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVariant() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariantKey(t *testing.T) {
+	t.Parallel()
+	v := Variant{Size: 256, Format: FormatJPEG}
+	if got, want := v.Key("attachments/c/f.png"), "variants/attachments/c/f.png/256.jpg"; got != want {
+		t.Errorf("Key() = %q, want %q", got, want)
+	}
+	if !IsVariantKey(v.Key("attachments/c/f.png")) {
+		t.Error("IsVariantKey() = false for a variant key")
+	}
+	if !IsPrivateKey(v.Key("attachments/c/f.png")) {
+		t.Error("IsPrivateKey() = false for a variant of an attachment")
+	}
+	if IsPrivateKey(v.Key("avatars/u/a.webp")) {
+		t.Error("IsPrivateKey() = true for a variant of an avatar")
+	}
+}
+
+func TestVariantStore_GeneratesAndCaches(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newTestStorage(t)
+	if err := store.Put(ctx, "attachments/c/f.png", bytes.NewReader(createTestPNG(t, 600, 300))); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	variants := NewVariantStore(store)
+	v := Variant{Size: 256, Format: FormatPNG}
+	data, err := variants.Get(ctx, "attachments/c/f.png", v)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode variant: %v", err)
+	}
+	if cfg.Width != 256 || cfg.Height != 128 {
+		t.Errorf("variant dimensions = %dx%d, want 256x128", cfg.Width, cfg.Height)
+	}
+
+	// Removing the original proves the second request is served from the cached variant.
+	if err := store.Delete(ctx, "attachments/c/f.png"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	cached, err := variants.Get(ctx, "attachments/c/f.png", v)
+	if err != nil {
+		t.Fatalf("Get() from cache error = %v", err)
+	}
+	if !bytes.Equal(cached, data) {
+		t.Error("cached variant differs from the generated variant")
+	}
+
+	if err := DeleteVariants(ctx, store, "attachments/c/f.png"); err != nil {
+		t.Fatalf("DeleteVariants() error = %v", err)
+	}
+	if _, err := variants.Get(ctx, "attachments/c/f.png", v); !errors.Is(err, ErrStorageKeyNotFound) {
+		t.Errorf("Get() after DeleteVariants error = %v, want ErrStorageKeyNotFound", err)
+	}
+}
+
+func TestDeleteWithVariants(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newTestStorage(t)
+	if err := store.Put(ctx, "emoji/e.png", bytes.NewReader(createTestPNG(t, 128, 128))); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	variants := NewVariantStore(store)
+	v := Variant{Size: 64, Format: FormatWebP}
+	if _, err := variants.Get(ctx, "emoji/e.png", v); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if err := DeleteWithVariants(ctx, store, "emoji/e.png"); err != nil {
+		t.Fatalf("DeleteWithVariants() error = %v", err)
+	}
+	for _, key := range []string{"emoji/e.png", v.Key("emoji/e.png")} {
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrStorageKeyNotFound) {
+			t.Errorf("Get(%q) after DeleteWithVariants error = %v, want ErrStorageKeyNotFound", key, err)
+		}
+	}
+}
+
+func TestVariantStore_MissingSource(t *testing.T) {
+	t.Parallel()
+	variants := NewVariantStore(newTestStorage(t))
+	_, err := variants.Get(context.Background(), "attachments/c/missing.png", Variant{64, FormatWebP})
+	if !errors.Is(err, ErrStorageKeyNotFound) {
+		t.Errorf("Get() error = %v, want ErrStorageKeyNotFound", err)
+	}
+}
@@ -106,6 +106,106 @@ GROUP BY m.user_id, u.username, u.display_name, u.avatar_key,
 	return m, nil
 }
 
+// Search returns up to limit members whose username, display name or nickname contains query or closely resembles it.
+// Exact matches rank first, then prefix matches, then everything else; within each group the members who posted most
+// recently come first, so the people active in a conversation surface at the top of a mention typeahead.
+func (r *PGRepository) Search(ctx context.Context, query string, limit int) ([]WithProfile, error) {
+	escaped := postgres.EscapeLike(query)
+	rows, err := r.db.Query(ctx,
+		memberQuery+`
+  AND (u.username ILIKE $2 OR u.display_name ILIKE $2 OR m.nickname ILIKE $2
+       OR $3 <% u.username OR $3 <% u.display_name OR $3 <% m.nickname)
+GROUP BY m.user_id, u.username, u.display_name, u.avatar_key,
+         m.nickname, m.status, m.timeout_until, m.joined_at
+ORDER BY CASE
+             WHEN lower(u.username) = lower($3) OR lower(u.display_name) = lower($3)
+                  OR lower(m.nickname) = lower($3) THEN 0
+             WHEN u.username ILIKE $4 OR u.display_name ILIKE $4 OR m.nickname ILIKE $4 THEN 1
+             ELSE 2
+         END,
+         (SELECT max(msg.created_at) FROM messages msg WHERE msg.author_id = m.user_id) DESC NULLS LAST,
+         greatest(word_similarity($3, u.username), word_similarity($3, coalesce(u.display_name, '')),
+                  word_similarity($3, coalesce(m.nickname, ''))) DESC,
+         u.username
+LIMIT $5`, models.MemberStatusPending, "%"+escaped+"%", query, escaped+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("search members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []WithProfile
+	for rows.Next() {
+		m, err := scanWithProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate members: %w", err)
+	}
+	return members, nil
+}
+
+// ListByPrefix returns up to limit members whose username, display name or nickname starts with prefix, ordered by
+// username. An empty prefix matches every member.
+func (r *PGRepository) ListByPrefix(ctx context.Context, prefix string, limit int) ([]WithProfile, error) {
+	rows, err := r.db.Query(ctx,
+		memberQuery+`
+  AND (u.username ILIKE $2 OR u.display_name ILIKE $2 OR m.nickname ILIKE $2)
+GROUP BY m.user_id, u.username, u.display_name, u.avatar_key,
+         m.nickname, m.status, m.timeout_until, m.joined_at
+ORDER BY u.username, m.user_id
+LIMIT $3`, models.MemberStatusPending, postgres.EscapeLike(prefix)+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("list members by prefix: %w", err)
+	}
+	defer rows.Close()
+
+	var members []WithProfile
+	for rows.Next() {
+		m, err := scanWithProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate members: %w", err)
+	}
+	return members, nil
+}
+
+// ListByUserIDs returns the members among userIDs, ordered by username. IDs that do not belong to a member (including
+// pending members) are omitted from the result.
+func (r *PGRepository) ListByUserIDs(ctx context.Context, userIDs []uuid.UUID) ([]WithProfile, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := r.db.Query(ctx,
+		memberQuery+` AND m.user_id = ANY($2)
+GROUP BY m.user_id, u.username, u.display_name, u.avatar_key,
+         m.nickname, m.status, m.timeout_until, m.joined_at
+ORDER BY u.username, m.user_id`, models.MemberStatusPending, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list members by user ids: %w", err)
+	}
+	defer rows.Close()
+
+	var members []WithProfile
+	for rows.Next() {
+		m, err := scanWithProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate members: %w", err)
+	}
+	return members, nil
+}
+
 // UpdateNickname sets or clears a member's nickname and returns the updated profile.
 func (r *PGRepository) UpdateNickname(ctx context.Context, userID uuid.UUID, nickname *string) (*WithProfile, error) {
 	tag, err := r.db.Exec(ctx, "UPDATE members SET nickname = $1 WHERE user_id = $2", nickname, userID)
@@ -3,8 +3,10 @@ package message
 import (
 	"context"
 	"errors"
+	"regexp"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/google/uuid"
@@ -50,6 +52,101 @@ type Message struct {
 	AuthorAvatarKey   *string
 }
 
+// IndexEntry is the subset of a message needed to build the search index. Removed is true when the message has been
+// deleted or encrypted and must be removed from the index rather than upserted.
+type IndexEntry struct {
+	ID        uuid.UUID
+	ChannelID uuid.UUID
+	AuthorID  uuid.UUID
+	Content   string
+	CreatedAt time.Time
+	Removed   bool
+
+	Pinned       bool
+	InThread     bool     // posted inside a thread
+	HasThread    bool     // a thread was started from this message
+	Filenames    []string // names of linked attachments
+	ContentTypes []string // MIME types of linked attachments
+}
+
+// Search tags describe what a message contains, so that searches can filter on them (has:link, has:image, ...).
+const (
+	TagLink   = "link"
+	TagFile   = "file"
+	TagImage  = "image"
+	TagVideo  = "video"
+	TagAudio  = "audio"
+	TagThread = "thread"
+)
+
+var (
+	// linkPattern matches the start of a web link. The PostgreSQL search backend applies the same pattern in SQL.
+	linkPattern = regexp.MustCompile(`(?i)https?://\S`)
+
+	// mentionPattern matches a user mention, which is written <@user-id> in message content.
+	mentionPattern = regexp.MustCompile(`<@([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})>`)
+)
+
+// Tags returns the search tags of the entry in a stable order.
+func (e *IndexEntry) Tags() []string {
+	tags := []string{}
+	if linkPattern.MatchString(e.Content) {
+		tags = append(tags, TagLink)
+	}
+	if len(e.ContentTypes) > 0 {
+		tags = append(tags, TagFile)
+	}
+	for _, kind := range []string{TagImage, TagVideo, TagAudio} {
+		for _, ct := range e.ContentTypes {
+			if strings.HasPrefix(ct, kind+"/") {
+				tags = append(tags, kind)
+				break
+			}
+		}
+	}
+	if e.HasThread {
+		tags = append(tags, TagThread)
+	}
+	return tags
+}
+
+// Mentions returns the users mentioned in content, in order of first appearance and without duplicates.
+func Mentions(content string) []uuid.UUID {
+	ids := []uuid.UUID{}
+	seen := make(map[uuid.UUID]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		id, err := uuid.Parse(m[1])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// FilenameWords splits file names into lower-case words, the runs of letters and digits, in order of first appearance
+// and without duplicates. A filename: search matches when every word of its value starts a word of an attachment's
+// file name; the PostgreSQL search backend applies the same rule in SQL.
+func FilenameWords(names ...string) []string {
+	words := []string{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		for _, word := range strings.FieldsFunc(strings.ToLower(name), isNotWordRune) {
+			if seen[word] {
+				continue
+			}
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
 // CreateParams groups the inputs for creating a new message.
 type CreateParams struct {
 	ChannelID uuid.UUID
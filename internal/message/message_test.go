@@ -2,8 +2,11 @@ package message
 
 import (
 	"errors"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/google/uuid"
 )
 
 func TestValidateContent(t *testing.T) {
@@ -68,3 +71,57 @@ func TestClampLimit(t *testing.T) {
 		})
 	}
 }
+
+func TestIndexEntryTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		entry IndexEntry
+		want  []string
+	}{
+		{"plain", IndexEntry{Content: "hello"}, []string{}},
+		{"link", IndexEntry{Content: "see HTTPS://example.com"}, []string{TagLink}},
+		{"bare scheme", IndexEntry{Content: "http:// alone"}, []string{}},
+		{
+			"attachments",
+			IndexEntry{ContentTypes: []string{"image/png", "image/jpeg", "application/pdf"}},
+			[]string{TagFile, TagImage},
+		},
+		{"thread", IndexEntry{HasThread: true, ContentTypes: []string{"audio/ogg"}}, []string{TagFile, TagAudio, TagThread}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.entry.Tags(); !slices.Equal(got, tt.want) {
+				t.Errorf("Tags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	t.Parallel()
+
+	a, b := uuid.New(), uuid.New()
+	content := "hi <@" + a.String() + "> and <@" + strings.ToUpper(b.String()) + ">, again <@" + a.String() +
+		"> but not <@nobody> or @" + b.String()
+
+	got := Mentions(content)
+	if !slices.Equal(got, []uuid.UUID{a, b}) {
+		t.Errorf("Mentions() = %v, want [%s %s]", got, a, b)
+	}
+}
+
+func TestFilenameWords(t *testing.T) {
+	t.Parallel()
+
+	got := FilenameWords("Q3_Report-final.PDF", "report 2.png", "...")
+	want := []string{"q3", "report", "final", "pdf", "2", "png"}
+	if !slices.Equal(got, want) {
+		t.Errorf("FilenameWords() = %v, want %v", got, want)
+	}
+	if got := FilenameWords(); got == nil || len(got) != 0 {
+		t.Errorf("FilenameWords() with no names = %v, want empty", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -20,7 +21,8 @@ const baseJoin = "FROM messages m JOIN users u ON u.id = m.author_id"
 
 // PGRepository implements Repository using PostgreSQL.
 type PGRepository struct {
-	db *pgxpool.Pool
+	db             *pgxpool.Pool
+	noSearchOutbox bool
 }
 
 // NewPGRepository creates a new PostgreSQL-backed message repository.
@@ -28,6 +30,13 @@ func NewPGRepository(db *pgxpool.Pool) *PGRepository {
 	return &PGRepository{db: db}
 }
 
+// DisableSearchOutbox stops message writes from enqueueing search index updates. It is used when search is served from
+// the messages table itself, where there is no external index to keep in sync. It must be called before the repository
+// is shared.
+func (r *PGRepository) DisableSearchOutbox() {
+	r.noSearchOutbox = true
+}
+
 // Create inserts a new message and returns it with joined author information. When reply_to_id is set, the referenced
 // message must exist, be in the same channel, and not be deleted.
 func (r *PGRepository) Create(ctx context.Context, params CreateParams) (*Message, error) {
@@ -63,6 +72,11 @@ func (r *PGRepository) Create(ctx context.Context, params CreateParams) (*Messag
 		if err := row.Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt); err != nil {
 			return fmt.Errorf("insert message: %w", err)
 		}
+		if !params.Encrypted {
+			if err := r.enqueueIndex(ctx, tx, msg.ID); err != nil {
+				return err
+			}
+		}
 
 		// Fetch author info within the same transaction for consistency.
 		err := tx.QueryRow(ctx,
@@ -141,34 +155,70 @@ func (r *PGRepository) List(ctx context.Context, channelID uuid.UUID, before *uu
 // Update sets new content on a non-deleted message and marks it as edited. Returns the updated message with joined
 // author information.
 func (r *PGRepository) Update(ctx context.Context, id uuid.UUID, content string) (*Message, error) {
-	row := r.db.QueryRow(ctx,
-		`UPDATE messages SET content = $1, edited_at = NOW()
-		 WHERE id = $2 AND deleted_at IS NULL
-		 RETURNING id`, content, id,
-	)
-
-	var updatedID uuid.UUID
-	if err := row.Scan(&updatedID); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotFound
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			"UPDATE messages SET content = $1, edited_at = NOW() WHERE id = $2 AND deleted_at IS NULL", content, id,
+		)
+		if err != nil {
+			return fmt.Errorf("update message: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
 		}
-		return nil, fmt.Errorf("update message: %w", err)
+		return r.enqueueIndex(ctx, tx, id)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return r.GetByID(ctx, updatedID)
+	return r.GetByID(ctx, id)
 }
 
 // SoftDelete marks a message as deleted by recording the deletion timestamp and the actor who performed it. Returns
 // ErrNotFound if the message does not exist or is already deleted.
 func (r *PGRepository) SoftDelete(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
-	tag, err := r.db.Exec(ctx,
-		"UPDATE messages SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL", id, deletedBy,
+	return postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			"UPDATE messages SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL",
+			id, deletedBy,
+		)
+		if err != nil {
+			return fmt.Errorf("soft delete message: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return r.enqueueIndex(ctx, tx, id)
+	})
+}
+
+// enqueueIndex records in the caller's transaction that the search index entry for a message must be refreshed, unless
+// the search outbox is disabled.
+func (r *PGRepository) enqueueIndex(ctx context.Context, tx pgx.Tx, messageID uuid.UUID) error {
+	if r.noSearchOutbox {
+		return nil
+	}
+	return EnqueueIndex(ctx, tx, []uuid.UUID{messageID})
+}
+
+// EnqueueIndex records in tx that the search index entries for the given messages must be refreshed. Direct messages
+// are never indexed, so rows are only written for messages that belong to a server channel.
+func EnqueueIndex(ctx context.Context, tx pgx.Tx, messageIDs []uuid.UUID) error {
+	_, err := tx.Exec(ctx,
+		"INSERT INTO search_outbox (message_id) SELECT m.id "+indexableJoin+" WHERE m.id = ANY($1)",
+		messageIDs,
 	)
 	if err != nil {
-		return fmt.Errorf("soft delete message: %w", err)
+		return fmt.Errorf("enqueue search index update: %w", err)
 	}
-	if tag.RowsAffected() == 0 {
-		return ErrNotFound
+	return nil
+}
+
+// Touch bumps updated_at on the given messages. Repositories that change data embedded in a message's search document
+// from another table (attachments, threads) call it so that the catch-up pass of a running reindex sees the change.
+func Touch(ctx context.Context, tx pgx.Tx, messageIDs []uuid.UUID) error {
+	if _, err := tx.Exec(ctx, "UPDATE messages SET updated_at = NOW() WHERE id = ANY($1)", messageIDs); err != nil {
+		return fmt.Errorf("touch messages: %w", err)
 	}
 	return nil
 }
@@ -176,13 +226,23 @@ func (r *PGRepository) SoftDelete(ctx context.Context, id uuid.UUID, deletedBy u
 // Pin marks a message as pinned. Returns ErrAlreadyPinned if the message is already pinned, or ErrNotFound if the
 // message does not exist or is deleted.
 func (r *PGRepository) Pin(ctx context.Context, id uuid.UUID) (*Message, error) {
-	tag, err := r.db.Exec(ctx,
-		"UPDATE messages SET pinned = true WHERE id = $1 AND deleted_at IS NULL AND pinned = false", id,
-	)
+	var changed bool
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			"UPDATE messages SET pinned = true WHERE id = $1 AND deleted_at IS NULL AND pinned = false", id,
+		)
+		if err != nil {
+			return fmt.Errorf("pin message: %w", err)
+		}
+		if changed = tag.RowsAffected() > 0; !changed {
+			return nil
+		}
+		return r.enqueueIndex(ctx, tx, id)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("pin message: %w", err)
+		return nil, err
 	}
-	if tag.RowsAffected() == 0 {
+	if !changed {
 		// Distinguish "not found / deleted" from "already pinned" by checking whether the message exists.
 		msg, getErr := r.GetByID(ctx, id)
 		if getErr != nil {
@@ -199,13 +259,23 @@ func (r *PGRepository) Pin(ctx context.Context, id uuid.UUID) (*Message, error)
 // Unpin marks a message as unpinned. Returns ErrNotPinned if the message is not currently pinned, or ErrNotFound if the
 // message does not exist or is deleted.
 func (r *PGRepository) Unpin(ctx context.Context, id uuid.UUID) (*Message, error) {
-	tag, err := r.db.Exec(ctx,
-		"UPDATE messages SET pinned = false WHERE id = $1 AND deleted_at IS NULL AND pinned = true", id,
-	)
+	var changed bool
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			"UPDATE messages SET pinned = false WHERE id = $1 AND deleted_at IS NULL AND pinned = true", id,
+		)
+		if err != nil {
+			return fmt.Errorf("unpin message: %w", err)
+		}
+		if changed = tag.RowsAffected() > 0; !changed {
+			return nil
+		}
+		return r.enqueueIndex(ctx, tx, id)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("unpin message: %w", err)
+		return nil, err
 	}
-	if tag.RowsAffected() == 0 {
+	if !changed {
 		// Distinguish "not found / deleted" from "not pinned" by checking whether the message exists.
 		msg, getErr := r.GetByID(ctx, id)
 		if getErr != nil {
@@ -290,6 +360,95 @@ func (r *PGRepository) ListByThread(ctx context.Context, threadID uuid.UUID, bef
 	return messages, nil
 }
 
+// indexableJoin restricts index queries to server channel messages. Direct messages are never indexed, and the join
+// excludes them because dm_channels IDs never appear in channels.
+const indexableJoin = "FROM messages m JOIN channels c ON c.id = m.channel_id"
+
+// indexColumns selects the fields of an IndexEntry, except Removed, which each query supplies last.
+const indexColumns = `m.id, m.channel_id, m.author_id, m.content, m.created_at, m.pinned, m.thread_id IS NOT NULL,
+EXISTS (SELECT 1 FROM threads t WHERE t.parent_message_id = m.id),
+ARRAY(SELECT a.filename FROM message_attachments a WHERE a.message_id = m.id ORDER BY a.created_at),
+ARRAY(SELECT a.content_type FROM message_attachments a WHERE a.message_id = m.id ORDER BY a.created_at)`
+
+// CountIndexable returns the number of messages eligible for the search index.
+func (r *PGRepository) CountIndexable(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRow(ctx,
+		"SELECT count(*) "+indexableJoin+" WHERE m.deleted_at IS NULL AND NOT m.encrypted",
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count indexable messages: %w", err)
+	}
+	return n, nil
+}
+
+// ListIndexable returns up to limit messages eligible for the search index with IDs greater than after, ordered by ID.
+// Passing the last returned ID as after walks the whole table in keyset order; uuid.Nil starts from the beginning.
+func (r *PGRepository) ListIndexable(ctx context.Context, after uuid.UUID, limit int) ([]IndexEntry, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+indexColumns+`, false `+indexableJoin+`
+		 WHERE m.deleted_at IS NULL AND NOT m.encrypted AND m.id > $1
+		 ORDER BY m.id
+		 LIMIT $2`,
+		after, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query indexable messages: %w", err)
+	}
+	return collectIndexEntries(rows)
+}
+
+// ListChangedSince returns up to limit server channel messages updated at or after since with IDs greater than after,
+// ordered by ID. Deleted and encrypted messages are included with Removed set so the caller can drop them from the
+// index.
+func (r *PGRepository) ListChangedSince(ctx context.Context, since time.Time, after uuid.UUID, limit int) ([]IndexEntry, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+indexColumns+`, (m.deleted_at IS NOT NULL OR m.encrypted)
+		 `+indexableJoin+`
+		 WHERE m.updated_at >= $1 AND m.id > $2
+		 ORDER BY m.id
+		 LIMIT $3`,
+		since, after, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query changed messages: %w", err)
+	}
+	return collectIndexEntries(rows)
+}
+
+// GetIndexEntries returns the current index state of the given server channel messages. Deleted and encrypted messages
+// are included with Removed set; IDs that match no server channel message are omitted.
+func (r *PGRepository) GetIndexEntries(ctx context.Context, ids []uuid.UUID) ([]IndexEntry, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+indexColumns+`, (m.deleted_at IS NOT NULL OR m.encrypted)
+		 `+indexableJoin+`
+		 WHERE m.id = ANY($1)`,
+		ids,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query index entries: %w", err)
+	}
+	return collectIndexEntries(rows)
+}
+
+func collectIndexEntries(rows pgx.Rows) ([]IndexEntry, error) {
+	defer rows.Close()
+	var entries []IndexEntry
+	for rows.Next() {
+		var e IndexEntry
+		err := rows.Scan(&e.ID, &e.ChannelID, &e.AuthorID, &e.Content, &e.CreatedAt, &e.Pinned, &e.InThread,
+			&e.HasThread, &e.Filenames, &e.ContentTypes, &e.Removed)
+		if err != nil {
+			return nil, fmt.Errorf("scan index entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate index entries: %w", err)
+	}
+	return entries, nil
+}
+
 // scanMessage scans a single row into a Message struct.
 func scanMessage(row pgx.Row) (*Message, error) {
 	var msg Message
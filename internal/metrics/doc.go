@@ -0,0 +1,7 @@
+// Package metrics exposes the server's Prometheus metrics: HTTP request latency and status per route, gateway
+// connections, dispatch fan-out, publish queue and replay buffer activity, permission cache effectiveness, media job
+// outcomes, search index failures, and PostgreSQL pool statistics.
+//
+// Components keep their own atomic counters and expose them through snapshot methods such as gateway.Hub.DispatchStats;
+// the Registry reads them at scrape time. This keeps the Prometheus client out of every package but this one.
+package metrics
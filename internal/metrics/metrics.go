@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	apierrors "github.com/uncord-chat/uncord-protocol/errors"
+
+	"github.com/uncord-chat/uncord-server/internal/httputil"
+)
+
+// namespace prefixes every metric exported by the server.
+const namespace = "uncord"
+
+// unmatchedRoute labels requests that matched no route, so that scanners probing random paths cannot create an
+// unbounded number of series.
+const unmatchedRoute = "unmatched"
+
+// Registry holds the server's Prometheus metrics. Component metrics are read from the components' own counters when
+// the registry is scraped, so components do not depend on this package.
+type Registry struct {
+	reg      *prometheus.Registry
+	requests *prometheus.HistogramVec
+}
+
+// New creates a registry with the Go runtime and process collectors and the HTTP request histogram registered.
+func New() *Registry {
+	r := &Registry{
+		reg: prometheus.NewRegistry(),
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests by method, route pattern, and response status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+	}
+	r.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		r.requests,
+	)
+	return r
+}
+
+// Middleware returns Fiber middleware that records the latency and status of every request against its route pattern
+// (e.g. /api/v1/channels/:channelID) rather than its raw path. WebSocket upgrades are skipped because the request lasts
+// as long as the connection.
+func (r *Registry) Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") {
+			return c.Next()
+		}
+
+		start := time.Now()
+		err := c.Next()
+
+		route := unmatchedRoute
+		if c.Matched() {
+			route = c.FullPath()
+		}
+		r.requests.WithLabelValues(c.Method(), route, strconv.Itoa(httputil.ResponseStatus(c, err))).
+			Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// Handler returns a Fiber handler that serves the metrics in the Prometheus text format. When token is non-empty,
+// scrapers must present it as a bearer token.
+func (r *Registry) Handler(token string) fiber.Handler {
+	serve := adaptor.HTTPHandler(promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{}))
+	if token == "" {
+		return serve
+	}
+
+	want := []byte("Bearer " + token)
+	return func(c fiber.Ctx) error {
+		if subtle.ConstantTimeCompare([]byte(c.Get(fiber.HeaderAuthorization)), want) != 1 {
+			return httputil.Fail(c, fiber.StatusUnauthorized, apierrors.Unauthorised, "A valid metrics token is required")
+		}
+		return serve(c)
+	}
+}
+
+// counterFunc registers a counter whose value is read from fn at scrape time. Counters sharing a name must differ in
+// their labels.
+func (r *Registry) counterFunc(subsystem, name, help string, labels prometheus.Labels, fn func() float64) {
+	r.reg.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Subsystem:   subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: labels,
+	}, fn))
+}
+
+// gaugeFunc registers a gauge whose value is read from fn at scrape time.
+func (r *Registry) gaugeFunc(subsystem, name, help string, labels prometheus.Labels, fn func() float64) {
+	r.reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: labels,
+	}, fn))
+}
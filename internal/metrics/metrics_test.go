@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/uncord-chat/uncord-server/internal/gateway"
+	"github.com/uncord-chat/uncord-server/internal/media"
+	"github.com/uncord-chat/uncord-server/internal/outbox"
+	"github.com/uncord-chat/uncord-server/internal/permission"
+)
+
+type fakeHub struct{}
+
+func (fakeHub) ClientCount() int { return 3 }
+func (fakeHub) DispatchStats() gateway.DispatchStats {
+	return gateway.DispatchStats{Events: 10, Delivered: 25}
+}
+
+type fakePublisher struct{}
+
+func (fakePublisher) Stats() gateway.PublisherStats {
+	return gateway.PublisherStats{QueueDepth: 7, QueueCapacity: 1024, Published: 100, Failed: 2, Dropped: 1}
+}
+
+type fakeReplay struct{}
+
+func (fakeReplay) ReplayStats() gateway.ReplayStats {
+	return gateway.ReplayStats{Appended: 50, Evicted: 4, Replayed: 9}
+}
+
+type fakeCache struct{}
+
+func (fakeCache) CacheStats() permission.CacheStats {
+	return permission.CacheStats{Hits: 90, Misses: 10}
+}
+
+type fakeJobs struct{}
+
+func (fakeJobs) Counters() media.JobCounters {
+	return media.JobCounters{Succeeded: 5, Retried: 2, Failed: 1}
+}
+
+type fakeOutbox struct{}
+
+func (fakeOutbox) Counters() outbox.Counters {
+	return outbox.Counters{Applied: 40, Failed: 3, DeadLettered: 1}
+}
+
+// gather returns the value of every sample in r keyed by metric name and its sorted labels, e.g.
+// `uncord_media_jobs_total{outcome="failed"}`. Histograms are keyed by their sample count.
+func gather(t *testing.T, r *Registry) map[string]float64 {
+	t.Helper()
+	families, err := r.reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	out := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			out[f.GetName()+labelString(m.GetLabel())] = sampleValue(m)
+		}
+	}
+	return out
+}
+
+func labelString(labels []*dto.LabelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.GetName() + `="` + l.GetValue() + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func sampleValue(m *dto.Metric) float64 {
+	switch {
+	case m.GetCounter() != nil:
+		return m.GetCounter().GetValue()
+	case m.GetGauge() != nil:
+		return m.GetGauge().GetValue()
+	case m.GetHistogram() != nil:
+		return float64(m.GetHistogram().GetSampleCount())
+	default:
+		return 0
+	}
+}
+
+func TestRegisterSources(t *testing.T) {
+	t.Parallel()
+	r := New()
+	r.RegisterGateway(fakeHub{}, fakePublisher{}, fakeReplay{})
+	r.RegisterPermissionCache(fakeCache{})
+	r.RegisterMediaJobs(fakeJobs{})
+	r.RegisterSearchOutbox(fakeOutbox{})
+
+	got := gather(t, r)
+	want := map[string]float64{
+		"uncord_gateway_connected_clients":                           3,
+		"uncord_gateway_events_dispatched_total":                     10,
+		"uncord_gateway_frames_delivered_total":                      25,
+		"uncord_gateway_publish_queue_depth":                         7,
+		"uncord_gateway_publish_queue_capacity":                      1024,
+		`uncord_gateway_publish_events_total{result="published"}`:    100,
+		`uncord_gateway_publish_events_total{result="failed"}`:       2,
+		`uncord_gateway_publish_events_total{result="dropped"}`:      1,
+		`uncord_gateway_replay_entries_total{op="appended"}`:         50,
+		`uncord_gateway_replay_entries_total{op="evicted"}`:          4,
+		`uncord_gateway_replay_entries_total{op="replayed"}`:         9,
+		`uncord_permission_cache_lookups_total{result="hit"}`:        90,
+		`uncord_permission_cache_lookups_total{result="miss"}`:       10,
+		`uncord_media_jobs_total{outcome="succeeded"}`:               5,
+		`uncord_media_jobs_total{outcome="retried"}`:                 2,
+		`uncord_media_jobs_total{outcome="failed"}`:                  1,
+		`uncord_search_index_updates_total{outcome="applied"}`:       40,
+		`uncord_search_index_updates_total{outcome="failed"}`:        3,
+		`uncord_search_index_updates_total{outcome="dead_lettered"}`: 1,
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s = %v, want %v", name, got[name], v)
+		}
+	}
+}
+
+func TestMiddlewareLabelsRoutePattern(t *testing.T) {
+	t.Parallel()
+	r := New()
+	app := fiber.New()
+	app.Use(r.Middleware())
+	app.Get("/channels/:channelID", func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	for _, path := range []string{"/channels/a", "/channels/b", "/nope/1", "/nope/2"} {
+		resp, err := app.Test(httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatalf("app.Test(%s) error = %v", path, err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	got := gather(t, r)
+	matched := `uncord_http_request_duration_seconds{method="GET",route="/channels/:channelID",status="204"}`
+	if got[matched] != 2 {
+		t.Errorf("%s count = %v, want 2", matched, got[matched])
+	}
+	unmatched := `uncord_http_request_duration_seconds{method="GET",route="unmatched",status="404"}`
+	if got[unmatched] != 2 {
+		t.Errorf("%s count = %v, want 2", unmatched, got[unmatched])
+	}
+}
+
+func TestHandlerToken(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		token      string
+		auth       string
+		wantStatus int
+	}{
+		{"no token configured", "", "", fiber.StatusOK},
+		{"valid token", "secret", "Bearer secret", fiber.StatusOK},
+		{"missing token", "secret", "", fiber.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer other", fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app := fiber.New()
+			app.Get("/metrics", New().Handler(tt.token))
+
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/metrics", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == fiber.StatusOK && !strings.Contains(string(body), "go_goroutines") {
+				t.Error("response does not contain the Go runtime metrics")
+			}
+		})
+	}
+}
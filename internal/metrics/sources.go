@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/uncord-chat/uncord-server/internal/gateway"
+	"github.com/uncord-chat/uncord-server/internal/media"
+	"github.com/uncord-chat/uncord-server/internal/outbox"
+	"github.com/uncord-chat/uncord-server/internal/permission"
+)
+
+// HubSource reports gateway connection and dispatch totals. Satisfied by *gateway.Hub.
+type HubSource interface {
+	ClientCount() int
+	DispatchStats() gateway.DispatchStats
+}
+
+// PublisherSource reports the gateway publish queue. Satisfied by *gateway.Publisher.
+type PublisherSource interface {
+	Stats() gateway.PublisherStats
+}
+
+// ReplaySource reports gateway replay buffer activity. Satisfied by *gateway.SessionStore.
+type ReplaySource interface {
+	ReplayStats() gateway.ReplayStats
+}
+
+// PermissionCacheSource reports permission cache effectiveness. Satisfied by *permission.Resolver.
+type PermissionCacheSource interface {
+	CacheStats() permission.CacheStats
+}
+
+// MediaJobSource reports media job outcomes. Satisfied by *media.ThumbnailWorker.
+type MediaJobSource interface {
+	Counters() media.JobCounters
+}
+
+// SearchOutboxSource reports search index update outcomes. Satisfied by *outbox.Worker.
+type SearchOutboxSource interface {
+	Counters() outbox.Counters
+}
+
+var (
+	_ HubSource             = (*gateway.Hub)(nil)
+	_ PublisherSource       = (*gateway.Publisher)(nil)
+	_ ReplaySource          = (*gateway.SessionStore)(nil)
+	_ PermissionCacheSource = (*permission.Resolver)(nil)
+	_ MediaJobSource        = (*media.ThumbnailWorker)(nil)
+	_ SearchOutboxSource    = (*outbox.Worker)(nil)
+)
+
+// RegisterGateway exports connected clients, dispatch fan-out, the publish queue, and replay buffer activity.
+func (r *Registry) RegisterGateway(hub HubSource, publisher PublisherSource, replay ReplaySource) {
+	r.gaugeFunc("gateway", "connected_clients", "Number of connected WebSocket clients.", nil,
+		func() float64 { return float64(hub.ClientCount()) })
+	r.counterFunc("gateway", "events_dispatched_total", "Events received from pub/sub for dispatch to clients.", nil,
+		func() float64 { return float64(hub.DispatchStats().Events) })
+	r.counterFunc("gateway", "frames_delivered_total",
+		"Dispatch frames queued to clients. Divide by events dispatched for the average fan-out.", nil,
+		func() float64 { return float64(hub.DispatchStats().Delivered) })
+
+	r.gaugeFunc("gateway", "publish_queue_depth", "Events waiting in the publish queue.", nil,
+		func() float64 { return float64(publisher.Stats().QueueDepth) })
+	r.gaugeFunc("gateway", "publish_queue_capacity", "Capacity of the publish queue.", nil,
+		func() float64 { return float64(publisher.Stats().QueueCapacity) })
+	const publishHelp = "Events taken off the publish queue, by result. Dropped events never entered the queue."
+	r.counterFunc("gateway", "publish_events_total", publishHelp, prometheus.Labels{"result": "published"},
+		func() float64 { return float64(publisher.Stats().Published) })
+	r.counterFunc("gateway", "publish_events_total", publishHelp, prometheus.Labels{"result": "failed"},
+		func() float64 { return float64(publisher.Stats().Failed) })
+	r.counterFunc("gateway", "publish_events_total", publishHelp, prometheus.Labels{"result": "dropped"},
+		func() float64 { return float64(publisher.Stats().Dropped) })
+
+	const replayHelp = "Replay buffer entries by operation. Evicted entries were pushed out of a full buffer."
+	r.counterFunc("gateway", "replay_entries_total", replayHelp, prometheus.Labels{"op": "appended"},
+		func() float64 { return float64(replay.ReplayStats().Appended) })
+	r.counterFunc("gateway", "replay_entries_total", replayHelp, prometheus.Labels{"op": "evicted"},
+		func() float64 { return float64(replay.ReplayStats().Evicted) })
+	r.counterFunc("gateway", "replay_entries_total", replayHelp, prometheus.Labels{"op": "replayed"},
+		func() float64 { return float64(replay.ReplayStats().Replayed) })
+}
+
+// RegisterPermissionCache exports permission cache hits and misses.
+func (r *Registry) RegisterPermissionCache(src PermissionCacheSource) {
+	const help = "Permission cache lookups by result."
+	r.counterFunc("permission", "cache_lookups_total", help, prometheus.Labels{"result": "hit"},
+		func() float64 { return float64(src.CacheStats().Hits) })
+	r.counterFunc("permission", "cache_lookups_total", help, prometheus.Labels{"result": "miss"},
+		func() float64 { return float64(src.CacheStats().Misses) })
+}
+
+// RegisterMediaJobs exports media job outcomes.
+func (r *Registry) RegisterMediaJobs(src MediaJobSource) {
+	const help = "Media processing job attempts by outcome. Retried attempts are redelivered later."
+	r.counterFunc("media", "jobs_total", help, prometheus.Labels{"outcome": "succeeded"},
+		func() float64 { return float64(src.Counters().Succeeded) })
+	r.counterFunc("media", "jobs_total", help, prometheus.Labels{"outcome": "retried"},
+		func() float64 { return float64(src.Counters().Retried) })
+	r.counterFunc("media", "jobs_total", help, prometheus.Labels{"outcome": "failed"},
+		func() float64 { return float64(src.Counters().Failed) })
+}
+
+// RegisterSearchOutbox exports the outcomes of search index updates applied to Typesense.
+func (r *Registry) RegisterSearchOutbox(src SearchOutboxSource) {
+	const help = "Search index updates by outcome. Failed updates are retried until dead-lettered."
+	r.counterFunc("search", "index_updates_total", help, prometheus.Labels{"outcome": "applied"},
+		func() float64 { return float64(src.Counters().Applied) })
+	r.counterFunc("search", "index_updates_total", help, prometheus.Labels{"outcome": "failed"},
+		func() float64 { return float64(src.Counters().Failed) })
+	r.counterFunc("search", "index_updates_total", help, prometheus.Labels{"outcome": "dead_lettered"},
+		func() float64 { return float64(src.Counters().DeadLettered) })
+}
+
+// RegisterDBPool exports PostgreSQL connection pool statistics.
+func (r *Registry) RegisterDBPool(db *pgxpool.Pool) {
+	const connHelp = "Connections in the database pool by state."
+	r.gaugeFunc("db_pool", "connections", connHelp, prometheus.Labels{"state": "acquired"},
+		func() float64 { return float64(db.Stat().AcquiredConns()) })
+	r.gaugeFunc("db_pool", "connections", connHelp, prometheus.Labels{"state": "idle"},
+		func() float64 { return float64(db.Stat().IdleConns()) })
+	r.gaugeFunc("db_pool", "connections", connHelp, prometheus.Labels{"state": "constructing"},
+		func() float64 { return float64(db.Stat().ConstructingConns()) })
+	r.gaugeFunc("db_pool", "max_connections", "Maximum size of the database pool.", nil,
+		func() float64 { return float64(db.Stat().MaxConns()) })
+	r.counterFunc("db_pool", "acquires_total", "Connections acquired from the database pool.", nil,
+		func() float64 { return float64(db.Stat().AcquireCount()) })
+	r.counterFunc("db_pool", "empty_acquires_total",
+		"Acquires that had to wait for a connection because the pool was empty.", nil,
+		func() float64 { return float64(db.Stat().EmptyAcquireCount()) })
+	r.counterFunc("db_pool", "canceled_acquires_total", "Acquires cancelled by their context.", nil,
+		func() float64 { return float64(db.Stat().CanceledAcquireCount()) })
+	r.counterFunc("db_pool", "acquire_duration_seconds_total", "Total time spent acquiring connections.", nil,
+		func() float64 { return db.Stat().AcquireDuration().Seconds() })
+}
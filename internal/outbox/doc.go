@@ -0,0 +1,5 @@
+// Package outbox keeps the search index in step with message changes through a transactional outbox. The message
+// repository records an outbox row in the same transaction as every create, edit, and delete of a server channel
+// message, and the Worker drains those rows into Typesense with exponential backoff, dead-lettering rows that keep
+// failing so they can be inspected and requeued.
+package outbox
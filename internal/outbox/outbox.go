@@ -0,0 +1,37 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entry is a claimed outbox row. Attempts counts the failed attempts made before this claim.
+type Entry struct {
+	ID        uuid.UUID
+	MessageID uuid.UUID
+	Attempts  int
+}
+
+// Stats summarises the outbox backlog.
+type Stats struct {
+	Pending       int64
+	DeadLettered  int64
+	OldestPending *time.Time
+}
+
+// Repository defines the data-access contract for the search outbox.
+type Repository interface {
+	// Claim leases up to limit due rows for lease and returns them. Leased rows are invisible to other claimers until
+	// the lease expires, so rows claimed by a worker that crashes are retried automatically.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Entry, error)
+	// Complete deletes rows that have been applied to the index.
+	Complete(ctx context.Context, ids []uuid.UUID) error
+	// Fail records a failed attempt and schedules the row for retryAt, or dead-letters it when dead is true.
+	Fail(ctx context.Context, id uuid.UUID, reason string, retryAt time.Time, dead bool) error
+	// Stats returns the size of the backlog and the number of dead-lettered rows.
+	Stats(ctx context.Context) (Stats, error)
+	// RequeueDead makes every dead-lettered row due again with its attempt count reset, and returns how many there were.
+	RequeueDead(ctx context.Context) (int64, error)
+}
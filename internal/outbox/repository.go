@@ -0,0 +1,111 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxErrorLength bounds the stored last_error so a verbose upstream response cannot bloat the table.
+const maxErrorLength = 1024
+
+// PGRepository implements Repository using PostgreSQL.
+type PGRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPGRepository creates a new PostgreSQL-backed search outbox repository.
+func NewPGRepository(db *pgxpool.Pool) *PGRepository {
+	return &PGRepository{db: db}
+}
+
+// Claim leases up to limit due rows, oldest first. SKIP LOCKED lets several workers drain the outbox concurrently
+// without claiming the same rows.
+func (r *PGRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]Entry, error) {
+	rows, err := r.db.Query(ctx,
+		`UPDATE search_outbox SET next_attempt_at = NOW() + make_interval(secs => $2)
+		 WHERE id IN (
+		     SELECT id FROM search_outbox
+		     WHERE dead_at IS NULL AND next_attempt_at <= NOW()
+		     ORDER BY next_attempt_at
+		     LIMIT $1
+		     FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING id, message_id, attempts`,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox rows: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.MessageID, &e.Attempts); err != nil {
+			return nil, fmt.Errorf("scan outbox row: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate outbox rows: %w", err)
+	}
+	return entries, nil
+}
+
+// Complete deletes the given rows.
+func (r *PGRepository) Complete(ctx context.Context, ids []uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, "DELETE FROM search_outbox WHERE id = ANY($1)", ids); err != nil {
+		return fmt.Errorf("complete outbox rows: %w", err)
+	}
+	return nil
+}
+
+// Fail increments the attempt count of a row, records the failure reason, and either reschedules or dead-letters it.
+func (r *PGRepository) Fail(ctx context.Context, id uuid.UUID, reason string, retryAt time.Time, dead bool) error {
+	if len(reason) > maxErrorLength {
+		reason = strings.ToValidUTF8(reason[:maxErrorLength], "")
+	}
+	_, err := r.db.Exec(ctx,
+		`UPDATE search_outbox
+		 SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3,
+		     dead_at = CASE WHEN $4 THEN NOW() END
+		 WHERE id = $1`,
+		id, reason, retryAt, dead,
+	)
+	if err != nil {
+		return fmt.Errorf("record outbox failure: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the number of pending and dead-lettered rows and the creation time of the oldest pending row.
+func (r *PGRepository) Stats(ctx context.Context) (Stats, error) {
+	var s Stats
+	err := r.db.QueryRow(ctx,
+		`SELECT count(*) FILTER (WHERE dead_at IS NULL),
+		        count(*) FILTER (WHERE dead_at IS NOT NULL),
+		        min(created_at) FILTER (WHERE dead_at IS NULL)
+		 FROM search_outbox`,
+	).Scan(&s.Pending, &s.DeadLettered, &s.OldestPending)
+	if err != nil {
+		return Stats{}, fmt.Errorf("query outbox stats: %w", err)
+	}
+	return s, nil
+}
+
+// RequeueDead resets every dead-lettered row so the worker retries it immediately.
+func (r *PGRepository) RequeueDead(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE search_outbox SET dead_at = NULL, attempts = 0, next_attempt_at = NOW()
+		 WHERE dead_at IS NOT NULL`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("requeue dead outbox rows: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,220 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
+	"github.com/uncord-chat/uncord-server/internal/tracing"
+	"github.com/uncord-chat/uncord-server/internal/typesense"
+)
+
+const (
+	// claimLease is how long a claimed row stays invisible to other workers. It must comfortably exceed the time needed
+	// to apply one batch, otherwise a slow batch could be claimed and applied twice (harmless, but wasteful).
+	claimLease = 2 * time.Minute
+
+	// Retry backoff bounds. The delay doubles with every failed attempt.
+	initialBackoff = time.Second
+	maxBackoff     = 10 * time.Minute
+)
+
+// Source loads the current index state of messages. Satisfied by *message.PGRepository.
+type Source interface {
+	GetIndexEntries(ctx context.Context, ids []uuid.UUID) ([]message.IndexEntry, error)
+}
+
+// Index applies document changes to the search index. Satisfied by *typesense.Indexer.
+type Index interface {
+	IndexMessage(ctx context.Context, doc typesense.MessageDocument) error
+	DeleteMessage(ctx context.Context, id string) error
+}
+
+var (
+	_ Source = (*message.PGRepository)(nil)
+	_ Index  = (*typesense.Indexer)(nil)
+)
+
+// Counters are cumulative totals of the work done by a Worker since it was created.
+type Counters struct {
+	Applied      int64 `json:"applied"`
+	Failed       int64 `json:"failed"`
+	DeadLettered int64 `json:"dead_lettered"`
+}
+
+// Worker drains the search outbox into the search index. Each claimed row is applied by reading the message's current
+// state and upserting or deleting its document, so rows are idempotent and may be applied in any order.
+type Worker struct {
+	repo         Repository
+	source       Source
+	index        Index
+	batchSize    int
+	maxAttempts  int
+	pollInterval time.Duration
+	log          zerolog.Logger
+
+	applied      atomic.Int64
+	failed       atomic.Int64
+	deadLettered atomic.Int64
+}
+
+// NewWorker creates a search outbox worker.
+func NewWorker(repo Repository, source Source, index Index, batchSize, maxAttempts int, pollInterval time.Duration,
+	logger zerolog.Logger) *Worker {
+	return &Worker{
+		repo:         repo,
+		source:       source,
+		index:        index,
+		batchSize:    batchSize,
+		maxAttempts:  maxAttempts,
+		pollInterval: pollInterval,
+		log:          logger.With().Str("component", "search-outbox").Logger(),
+	}
+}
+
+// Run drains the outbox until ctx is cancelled. It returns an error when the outbox or message tables cannot be read,
+// leaving claimed rows to be retried once their lease expires.
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := w.Drain(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return err
+			}
+			if n < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain claims and applies one batch of due rows, returning the number of rows claimed. Each non-empty batch is
+// recorded as a trace of its own; empty polls are not traced.
+func (w *Worker) Drain(ctx context.Context) (int, error) {
+	entries, err := w.repo.Claim(ctx, w.batchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "search outbox drain",
+		trace.WithAttributes(attribute.Int("batch_size", len(entries))))
+	defer span.End()
+
+	// Several rows for the same message collapse into a single index update.
+	byMessage := make(map[uuid.UUID][]Entry, len(entries))
+	ids := make([]uuid.UUID, 0, len(entries))
+	for _, e := range entries {
+		if _, ok := byMessage[e.MessageID]; !ok {
+			ids = append(ids, e.MessageID)
+		}
+		byMessage[e.MessageID] = append(byMessage[e.MessageID], e)
+	}
+
+	current, err := w.source.GetIndexEntries(ctx, ids)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	state := make(map[uuid.UUID]*message.IndexEntry, len(current))
+	for i := range current {
+		state[current[i].ID] = &current[i]
+	}
+
+	var done []uuid.UUID
+	for _, id := range ids {
+		if applyErr := w.apply(ctx, id, state[id]); applyErr != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			for _, e := range byMessage[id] {
+				if err := w.fail(ctx, e, applyErr); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+		for _, e := range byMessage[id] {
+			done = append(done, e.ID)
+		}
+	}
+
+	if len(done) > 0 {
+		if err := w.repo.Complete(ctx, done); err != nil {
+			tracing.RecordError(span, err)
+			return 0, err
+		}
+		w.applied.Add(int64(len(done)))
+	}
+	return len(entries), nil
+}
+
+// apply brings the index document for a message in line with its current state. A message that no longer exists (or
+// is not a server channel message) is removed from the index.
+func (w *Worker) apply(ctx context.Context, id uuid.UUID, e *message.IndexEntry) error {
+	if e == nil || e.Removed {
+		return w.index.DeleteMessage(ctx, id.String())
+	}
+	return w.index.IndexMessage(ctx, typesense.NewMessageDocument(e))
+}
+
+// fail reschedules a row with exponential backoff, or dead-letters it once it has used all of its attempts.
+func (w *Worker) fail(ctx context.Context, e Entry, cause error) error {
+	attempts := e.Attempts + 1
+	dead := attempts >= w.maxAttempts
+	retryAt := time.Now().Add(backoff(attempts))
+
+	if err := w.repo.Fail(ctx, e.ID, cause.Error(), retryAt, dead); err != nil {
+		return fmt.Errorf("record failure of outbox row %s: %w", e.ID, err)
+	}
+
+	w.failed.Add(1)
+	if dead {
+		w.deadLettered.Add(1)
+		w.log.Error().Ctx(ctx).Err(cause).Str("message_id", e.MessageID.String()).Int("attempts", attempts).
+			Msg("Search index update dead-lettered")
+		return nil
+	}
+	w.log.Warn().Ctx(ctx).Err(cause).Str("message_id", e.MessageID.String()).Int("attempts", attempts).
+		Time("retry_at", retryAt).Msg("Search index update failed")
+	return nil
+}
+
+// Counters returns the worker's cumulative totals.
+func (w *Worker) Counters() Counters {
+	return Counters{
+		Applied:      w.applied.Load(),
+		Failed:       w.failed.Load(),
+		DeadLettered: w.deadLettered.Load(),
+	}
+}
+
+// backoff returns the delay before the retry that follows the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	d := initialBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
@@ -0,0 +1,288 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
+	"github.com/uncord-chat/uncord-server/internal/typesense"
+)
+
+// fakeRepo is an in-memory outbox. Claim returns due, live rows in map order.
+type fakeRepo struct {
+	mu       sync.Mutex
+	rows     map[uuid.UUID]*fakeRow
+	claimErr error
+}
+
+type fakeRow struct {
+	Entry
+	dueAt  time.Time
+	dead   bool
+	reason string
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{rows: make(map[uuid.UUID]*fakeRow)}
+}
+
+func (r *fakeRepo) add(messageID uuid.UUID, attempts int) uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := uuid.New()
+	r.rows[id] = &fakeRow{Entry: Entry{ID: id, MessageID: messageID, Attempts: attempts}}
+	return id
+}
+
+func (r *fakeRepo) Claim(_ context.Context, limit int, lease time.Duration) ([]Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.claimErr != nil {
+		return nil, r.claimErr
+	}
+	now := time.Now()
+	var out []Entry
+	for _, row := range r.rows {
+		if len(out) == limit {
+			break
+		}
+		if row.dead || row.dueAt.After(now) {
+			continue
+		}
+		row.dueAt = now.Add(lease)
+		out = append(out, row.Entry)
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) Complete(_ context.Context, ids []uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		delete(r.rows, id)
+	}
+	return nil
+}
+
+func (r *fakeRepo) Fail(_ context.Context, id uuid.UUID, reason string, retryAt time.Time, dead bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	row := r.rows[id]
+	row.Attempts++
+	row.reason = reason
+	row.dueAt = retryAt
+	row.dead = dead
+	return nil
+}
+
+func (r *fakeRepo) Stats(context.Context) (Stats, error) {
+	return Stats{}, nil
+}
+
+func (r *fakeRepo) RequeueDead(context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeRepo) row(id uuid.UUID) *fakeRow {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rows[id]
+}
+
+// fakeSource returns the stored entries for the requested IDs.
+type fakeSource struct {
+	entries map[uuid.UUID]message.IndexEntry
+}
+
+func (s *fakeSource) GetIndexEntries(_ context.Context, ids []uuid.UUID) ([]message.IndexEntry, error) {
+	var out []message.IndexEntry
+	for _, id := range ids {
+		if e, ok := s.entries[id]; ok {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// fakeIndex records the operations applied to it. Operations on IDs in failing return an error.
+type fakeIndex struct {
+	mu      sync.Mutex
+	indexed []string
+	deleted []string
+	failing map[string]bool
+}
+
+func (x *fakeIndex) IndexMessage(_ context.Context, doc typesense.MessageDocument) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.failing[doc.ID] {
+		return errors.New("typesense unavailable")
+	}
+	x.indexed = append(x.indexed, doc.ID)
+	return nil
+}
+
+func (x *fakeIndex) DeleteMessage(_ context.Context, id string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.failing[id] {
+		return errors.New("typesense unavailable")
+	}
+	x.deleted = append(x.deleted, id)
+	return nil
+}
+
+func newTestWorker(repo Repository, source Source, index Index, maxAttempts int) *Worker {
+	return NewWorker(repo, source, index, 100, maxAttempts, time.Hour, zerolog.Nop())
+}
+
+func TestDrainAppliesCurrentState(t *testing.T) {
+	t.Parallel()
+	live := message.IndexEntry{ID: uuid.New(), ChannelID: uuid.New(), AuthorID: uuid.New(), Content: "hi"}
+	removed := message.IndexEntry{ID: uuid.New(), Removed: true}
+	missing := uuid.New()
+
+	repo := newFakeRepo()
+	repo.add(live.ID, 0)
+	repo.add(live.ID, 0) // duplicate rows for one message collapse into one update
+	repo.add(removed.ID, 0)
+	repo.add(missing, 0)
+	source := &fakeSource{entries: map[uuid.UUID]message.IndexEntry{live.ID: live, removed.ID: removed}}
+	index := &fakeIndex{}
+	w := newTestWorker(repo, source, index, 5)
+
+	n, err := w.Drain(context.Background())
+	if err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if n != 4 {
+		t.Errorf("Drain() = %d, want 4", n)
+	}
+
+	if !slices.Equal(index.indexed, []string{live.ID.String()}) {
+		t.Errorf("indexed = %v, want only %s once", index.indexed, live.ID)
+	}
+	slices.Sort(index.deleted)
+	wantDeleted := []string{removed.ID.String(), missing.String()}
+	slices.Sort(wantDeleted)
+	if !slices.Equal(index.deleted, wantDeleted) {
+		t.Errorf("deleted = %v, want %v", index.deleted, wantDeleted)
+	}
+	if len(repo.rows) != 0 {
+		t.Errorf("%d rows left in outbox, want 0", len(repo.rows))
+	}
+	if got := w.Counters(); got.Applied != 4 || got.Failed != 0 {
+		t.Errorf("Counters() = %+v, want 4 applied", got)
+	}
+}
+
+func TestDrainReschedulesFailures(t *testing.T) {
+	t.Parallel()
+	ok := message.IndexEntry{ID: uuid.New()}
+	bad := message.IndexEntry{ID: uuid.New()}
+
+	repo := newFakeRepo()
+	repo.add(ok.ID, 0)
+	badRow := repo.add(bad.ID, 2)
+	source := &fakeSource{entries: map[uuid.UUID]message.IndexEntry{ok.ID: ok, bad.ID: bad}}
+	index := &fakeIndex{failing: map[string]bool{bad.ID.String(): true}}
+	w := newTestWorker(repo, source, index, 5)
+
+	before := time.Now()
+	if _, err := w.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+
+	row := repo.row(badRow)
+	if row == nil {
+		t.Fatal("failed row was removed from the outbox")
+	}
+	if row.dead {
+		t.Error("row dead-lettered before exhausting its attempts")
+	}
+	if row.Attempts != 3 || row.reason != "typesense unavailable" {
+		t.Errorf("row = %+v, want 3 attempts and the failure reason", row)
+	}
+	if wantDue := before.Add(backoff(3)); row.dueAt.Before(wantDue) {
+		t.Errorf("retry at %v, want no earlier than %v", row.dueAt, wantDue)
+	}
+	if got := w.Counters(); got.Applied != 1 || got.Failed != 1 || got.DeadLettered != 0 {
+		t.Errorf("Counters() = %+v, want 1 applied and 1 failed", got)
+	}
+}
+
+func TestDrainDeadLettersExhaustedRows(t *testing.T) {
+	t.Parallel()
+	bad := message.IndexEntry{ID: uuid.New()}
+	repo := newFakeRepo()
+	id := repo.add(bad.ID, 4)
+	source := &fakeSource{entries: map[uuid.UUID]message.IndexEntry{bad.ID: bad}}
+	index := &fakeIndex{failing: map[string]bool{bad.ID.String(): true}}
+	w := newTestWorker(repo, source, index, 5)
+
+	if _, err := w.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if row := repo.row(id); row == nil || !row.dead {
+		t.Fatalf("row = %+v, want dead-lettered", row)
+	}
+	if got := w.Counters(); got.DeadLettered != 1 {
+		t.Errorf("DeadLettered = %d, want 1", got.DeadLettered)
+	}
+
+	// Dead-lettered rows are not claimed again.
+	n, err := w.Drain(context.Background())
+	if err != nil {
+		t.Fatalf("second Drain() error = %v", err)
+	}
+	if n != 0 {
+		t.Errorf("second Drain() = %d, want 0", n)
+	}
+}
+
+func TestRunReturnsClaimError(t *testing.T) {
+	t.Parallel()
+	repo := newFakeRepo()
+	repo.claimErr = errors.New("database unavailable")
+	w := newTestWorker(repo, &fakeSource{}, &fakeIndex{}, 5)
+
+	if err := w.Run(context.Background()); !errors.Is(err, repo.claimErr) {
+		t.Fatalf("Run() error = %v, want the claim error", err)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	t.Parallel()
+	w := newTestWorker(newFakeRepo(), &fakeSource{}, &fakeIndex{}, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := w.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{30, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -185,6 +185,10 @@ func (r *fakeServerRepo) ClearBannerKey(context.Context) (*server.Config, error)
 	return &server.Config{OwnerID: uuid.New()}, nil
 }
 
+func (r *fakeServerRepo) SetOwner(_ context.Context, userID uuid.UUID) (*server.Config, error) {
+	return &server.Config{OwnerID: userID}, nil
+}
+
 func testVerifyHandler(t *testing.T) *fiber.App {
 	t.Helper()
 	mr := miniredis.RunT(t)
@@ -3,6 +3,7 @@ package permission
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -11,9 +12,18 @@ import (
 
 // Resolver computes effective permissions for a user in a channel.
 type Resolver struct {
-	store Store
-	cache Cache
-	log   zerolog.Logger
+	store  Store
+	cache  Cache
+	log    zerolog.Logger
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// CacheStats are cumulative totals of permission cache lookups since a Resolver was created. A lookup that fails with a
+// cache error counts as a miss.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
 }
 
 // NewResolver creates a new permission resolver.
@@ -30,8 +40,10 @@ func (r *Resolver) Resolve(ctx context.Context, userID, channelID uuid.UUID) (pe
 		r.log.Warn().Err(err).Msg("Permission cache get failed, falling through to compute")
 	}
 	if ok {
+		r.hits.Add(1)
 		return perm, nil
 	}
+	r.misses.Add(1)
 
 	perm, err = r.compute(ctx, userID, channelID)
 	if err != nil {
@@ -67,8 +79,10 @@ func (r *Resolver) ResolveServer(ctx context.Context, userID uuid.UUID) (permiss
 		r.log.Warn().Err(err).Msg("Server permission cache get failed, falling through to compute")
 	}
 	if ok {
+		r.hits.Add(1)
 		return perm, nil
 	}
+	r.misses.Add(1)
 
 	br, err := r.basePermissions(ctx, userID)
 	if err != nil {
@@ -122,10 +136,12 @@ func (r *Resolver) FilterPermitted(ctx context.Context, userID uuid.UUID, channe
 	toCache := make(map[uuid.UUID]permissions.Permission)
 	for i, chID := range channelIDs {
 		if effective, ok := cached[chID]; ok {
+			r.hits.Add(1)
 			result[i] = effective.Has(perm)
 			continue
 		}
 
+		r.misses.Add(1)
 		effective, computeErr := r.channelOverrides(ctx, br.perm, br.roleIDs, userID, chID)
 		if computeErr != nil {
 			return nil, computeErr
@@ -161,10 +177,12 @@ func (r *Resolver) FilterUsersPermitted(ctx context.Context, userIDs []uuid.UUID
 	toCache := make(map[uuid.UUID]permissions.Permission)
 	for i, uid := range userIDs {
 		if effective, ok := cached[uid]; ok {
+			r.hits.Add(1)
 			result[i] = effective.Has(perm)
 			continue
 		}
 
+		r.misses.Add(1)
 		effective, computeErr := r.compute(ctx, uid, channelID)
 		if computeErr != nil {
 			return nil, computeErr
@@ -183,6 +201,11 @@ func (r *Resolver) FilterUsersPermitted(ctx context.Context, userIDs []uuid.UUID
 	return result, nil
 }
 
+// CacheStats returns the cumulative number of permission cache hits and misses.
+func (r *Resolver) CacheStats() CacheStats {
+	return CacheStats{Hits: r.hits.Load(), Misses: r.misses.Load()}
+}
+
 // baseResult holds the output of the base permission computation (steps 1 and 2 of the algorithm).
 type baseResult struct {
 	perm    permissions.Permission
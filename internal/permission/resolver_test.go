@@ -549,6 +549,14 @@ func TestCacheMissComputesAndCaches(t *testing.T) {
 	if !cache.setCalled {
 		t.Error("Cache.Set should be called on cache miss")
 	}
+
+	// A second lookup is served from the cache.
+	if _, err := r.Resolve(context.Background(), userID, channelID); err != nil {
+		t.Fatalf("second Resolve() error = %v", err)
+	}
+	if got := r.CacheStats(); got != (CacheStats{Hits: 1, Misses: 1}) {
+		t.Errorf("CacheStats() = %+v, want 1 hit and 1 miss", got)
+	}
 }
 
 func TestCacheGetErrorDegradesToDB(t *testing.T) {
@@ -1071,6 +1079,9 @@ func TestFilterPermitted_MixedCacheHitsAndMisses(t *testing.T) {
 	if !result[1] {
 		t.Error("ch2 should be permitted (computed)")
 	}
+	if got := r.CacheStats(); got != (CacheStats{Hits: 1, Misses: 1}) {
+		t.Errorf("CacheStats() = %+v, want 1 hit and 1 miss", got)
+	}
 }
 
 func TestFilterPermitted_CacheGetErrorFallsThrough(t *testing.T) {
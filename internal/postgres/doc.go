@@ -1,5 +1,8 @@
 // Package postgres manages PostgreSQL connectivity, migrations, and transaction helpers. Connect creates a pgxpool
-// connection pool with configurable minimum and maximum connections. Migrate runs embedded goose migrations on startup.
-// WithTx executes a function within a database transaction and handles commit or rollback. IsUniqueViolation detects
-// PostgreSQL unique constraint violations by error code 23505.
+// connection pool with configurable minimum and maximum connections, and records queries made within a traced context
+// as OpenTelemetry client spans. Migrate runs embedded goose migrations on startup, and MigrateDown and MigrationStatus
+// back the operator migrate command. WithTx executes a function within a database transaction and handles commit or
+// rollback. IsUniqueViolation detects PostgreSQL unique constraint violations by error code 23505, and EscapeLike makes
+// user input safe to embed in LIKE patterns. LatestMigration and AppliedMigration report the schema version the binary
+// expects and the one the database is at, for readiness checks.
 package postgres
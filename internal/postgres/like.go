@@ -0,0 +1,12 @@
+package postgres
+
+import "strings"
+
+// likeEscaper escapes the characters that LIKE and ILIKE treat specially, using the default backslash escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// EscapeLike escapes s for use inside a LIKE or ILIKE pattern so that it matches literally. Callers add their own
+// wildcards around the result.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
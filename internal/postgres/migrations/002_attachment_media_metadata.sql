@@ -0,0 +1,14 @@
+-- +goose Up
+
+-- Media metadata extracted by the background media worker. Width and height already exist for images; video
+-- dimensions are stored in the same columns. The waveform holds one peak amplitude byte (0-255) per bucket.
+
+ALTER TABLE message_attachments
+    ADD COLUMN duration_ms INTEGER,
+    ADD COLUMN waveform    BYTEA;
+
+-- +goose Down
+
+ALTER TABLE message_attachments
+    DROP COLUMN IF EXISTS waveform,
+    DROP COLUMN IF EXISTS duration_ms;
@@ -0,0 +1,33 @@
+-- +goose Up
+
+-- Resumable upload sessions. Chunks are appended strictly in order: each chunk must start at received_bytes, which is
+-- advanced atomically when the chunk is recorded. Sessions that stop receiving chunks are purged alongside orphaned
+-- attachments once updated_at falls outside ATTACHMENT_ORPHAN_TTL.
+
+CREATE TABLE attachment_uploads (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel_id      UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    uploader_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename        TEXT NOT NULL,
+    content_type    TEXT NOT NULL,
+    size_bytes      BIGINT NOT NULL,
+    sha256          TEXT NOT NULL,
+    received_bytes  BIGINT NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_attachment_uploads_updated ON attachment_uploads (updated_at);
+
+CREATE TABLE attachment_upload_chunks (
+    upload_id       UUID NOT NULL REFERENCES attachment_uploads(id) ON DELETE CASCADE,
+    offset_bytes    BIGINT NOT NULL,
+    size_bytes      BIGINT NOT NULL,
+    storage_key     TEXT NOT NULL,
+    PRIMARY KEY (upload_id, offset_bytes)
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS attachment_upload_chunks;
+DROP TABLE IF EXISTS attachment_uploads;
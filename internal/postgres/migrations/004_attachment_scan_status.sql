@@ -0,0 +1,15 @@
+-- +goose Up
+
+-- Malware scan verdict for each attachment. Files are scanned before the attachment record is created, so existing rows
+-- (uploaded before scanning existed) default to clean. Quarantined attachments can never be linked to a message; their
+-- files are deleted immediately and the row is kept until the orphan purge so moderators can review the upload.
+
+ALTER TABLE message_attachments
+    ADD COLUMN scan_status    TEXT NOT NULL DEFAULT 'clean' CHECK (scan_status IN ('clean', 'quarantined')),
+    ADD COLUMN scan_signature TEXT;
+
+-- +goose Down
+
+ALTER TABLE message_attachments
+    DROP COLUMN IF EXISTS scan_signature,
+    DROP COLUMN IF EXISTS scan_status;
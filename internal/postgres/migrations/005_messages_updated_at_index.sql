@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- Supports the catch-up pass of a search reindex, which replays every message changed since the reindex started.
+
+CREATE INDEX idx_messages_updated_at ON messages (updated_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_messages_updated_at;
@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- Transactional outbox for the search index. A row is written in the same transaction as every change to a server
+-- channel message and drained by the search outbox worker, so an index update can never be lost to a crash or a
+-- Typesense outage. Rows carry only the message ID: the worker reads the message's current state when it processes the
+-- row, which makes replays idempotent and lets several pending rows for one message collapse into a single update.
+-- Rows that exhaust their attempts are dead-lettered (dead_at is set) and kept for inspection until requeued.
+
+CREATE TABLE search_outbox (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id      UUID NOT NULL,  -- no FK: a row must outlive its message so the removal reaches the index
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+    dead_at         TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_search_outbox_pending ON search_outbox (next_attempt_at) WHERE dead_at IS NULL;
+
+-- +goose Down
+
+DROP TABLE IF EXISTS search_outbox;
@@ -0,0 +1,21 @@
+-- +goose NO TRANSACTION
+
+-- +goose Up
+
+-- Full-text index for the PostgreSQL search backend (SEARCH_BACKEND=postgres). The 'simple' configuration neither stems
+-- nor drops stop words, so matching behaves the same for every language. Encrypted messages carry ciphertext and are
+-- never searchable, so they are left out of the index.
+--
+-- This is an expression index rather than a stored generated column, so adding it does not rewrite the messages table,
+-- and it is built CONCURRENTLY outside a transaction, so messages stay writable while it builds on a large install.
+-- Queries must use the same expression and predicates for the planner to pick it. A failed concurrent build leaves an
+-- invalid index behind, which is dropped first so that rerunning the migration starts over.
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_messages_search_vector;
+
+CREATE INDEX CONCURRENTLY idx_messages_search_vector ON messages USING GIN (to_tsvector('simple', content))
+    WHERE deleted_at IS NULL AND NOT encrypted;
+
+-- +goose Down
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_messages_search_vector;
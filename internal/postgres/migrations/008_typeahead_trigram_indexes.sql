@@ -0,0 +1,25 @@
+-- +goose Up
+
+-- Trigram indexes for the member, channel and emoji typeahead endpoints. They accelerate both substring matching
+-- (ILIKE '%term%') and fuzzy word matching (the <% operator) on every name a user can type to find something.
+-- pg_trgm is a trusted extension, so the database owner can create it without superuser rights.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX idx_users_display_name_trgm ON users USING GIN (display_name gin_trgm_ops);
+CREATE INDEX idx_members_nickname_trgm ON members USING GIN (nickname gin_trgm_ops);
+CREATE INDEX idx_channels_name_trgm ON channels USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_custom_emoji_name_trgm ON custom_emoji USING GIN (name gin_trgm_ops);
+
+-- Ranks emoji by when they were last used as a reaction.
+CREATE INDEX idx_reactions_emoji ON reactions (emoji_id, created_at DESC) WHERE emoji_id IS NOT NULL;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_reactions_emoji;
+DROP INDEX IF EXISTS idx_custom_emoji_name_trgm;
+DROP INDEX IF EXISTS idx_channels_name_trgm;
+DROP INDEX IF EXISTS idx_members_nickname_trgm;
+DROP INDEX IF EXISTS idx_users_display_name_trgm;
+DROP INDEX IF EXISTS idx_users_username_trgm;
@@ -0,0 +1,25 @@
+-- +goose Up
+
+-- Records when each E2EE device last used its keys, so that devices nobody uses any more can be removed instead of
+-- receiving dm_message_keys forever. Existing devices start from the time of the migration rather than from their
+-- creation, so that upgrading does not remove every long-registered device at once.
+ALTER TABLE user_devices ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX idx_user_devices_last_seen ON user_devices (last_seen_at);
+
+-- Recording activity is not a change to the device, so updated_at now only follows the columns a client sets.
+DROP TRIGGER set_updated_at ON user_devices;
+CREATE TRIGGER set_updated_at BEFORE UPDATE OF label, identity_key ON user_devices
+    FOR EACH ROW EXECUTE FUNCTION trigger_set_updated_at();
+
+-- Finds active signed pre-keys that are due for rotation.
+CREATE INDEX idx_spk_active_created ON e2ee_signed_pre_keys (created_at) WHERE active = true;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_spk_active_created;
+DROP TRIGGER IF EXISTS set_updated_at ON user_devices;
+CREATE TRIGGER set_updated_at BEFORE UPDATE ON user_devices
+    FOR EACH ROW EXECUTE FUNCTION trigger_set_updated_at();
+DROP INDEX IF EXISTS idx_user_devices_last_seen;
+ALTER TABLE user_devices DROP COLUMN IF EXISTS last_seen_at;
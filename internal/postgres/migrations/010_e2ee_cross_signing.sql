@@ -0,0 +1,32 @@
+-- +goose Up
+
+-- E2EE cross-signing. Each user may upload one Ed25519 master signing key, with which their clients sign the identity
+-- key of every device the user has confirmed is theirs. Peers that trust the master key can then tell a device the user
+-- added from one the server injected. Replacing the master key deletes the old row and, by cascade, every signature it
+-- made, since the new key has vouched for none of the user's devices yet.
+
+CREATE TABLE e2ee_master_keys (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    public_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_master_key_length CHECK (octet_length(public_key) = 32)
+);
+
+CREATE UNIQUE INDEX idx_master_keys_user ON e2ee_master_keys (user_id);
+
+-- A device's signature covers its identity key, so it is deleted whenever the identity key changes.
+CREATE TABLE e2ee_device_signatures (
+    device_id     UUID PRIMARY KEY REFERENCES user_devices(id) ON DELETE CASCADE,
+    master_key_id UUID NOT NULL REFERENCES e2ee_master_keys(id) ON DELETE CASCADE,
+    signature     BYTEA NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_device_signature_length CHECK (octet_length(signature) = 64)
+);
+
+CREATE INDEX idx_device_signatures_master_key ON e2ee_device_signatures (master_key_id);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS e2ee_device_signatures;
+DROP TABLE IF EXISTS e2ee_master_keys;
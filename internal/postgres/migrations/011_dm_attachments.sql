@@ -0,0 +1,26 @@
+-- +goose Up
+
+-- Attachments for end-to-end encrypted direct messages. Clients encrypt the file before uploading it, so the server
+-- stores an opaque blob and knows only its size: the filename, content type, dimensions, and file key travel inside
+-- encrypted_metadata, which the server returns exactly as it was uploaded. These rows are kept apart from
+-- message_attachments, whose channel_id references server channels and whose contents are scanned, thumbnailed,
+-- indexed for search, and included in community backups.
+CREATE TABLE dm_attachments (
+    id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id         UUID REFERENCES messages(id) ON DELETE CASCADE,
+    dm_channel_id      UUID NOT NULL REFERENCES dm_channels(id) ON DELETE CASCADE,
+    uploader_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    size_bytes         BIGINT NOT NULL,
+    storage_key        TEXT NOT NULL,
+    encrypted_metadata BYTEA NOT NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_dm_attachment_metadata_length CHECK (octet_length(encrypted_metadata) BETWEEN 1 AND 8192)
+);
+
+CREATE INDEX idx_dm_attachments_message ON dm_attachments (message_id) WHERE message_id IS NOT NULL;
+CREATE INDEX idx_dm_attachments_channel ON dm_attachments (dm_channel_id);
+CREATE INDEX idx_dm_attachments_pending ON dm_attachments (created_at) WHERE message_id IS NULL;
+
+-- +goose Down
+
+DROP TABLE IF EXISTS dm_attachments;
@@ -3,7 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"path"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib" // register pgx as database/sql driver for goose migrations
@@ -23,7 +26,8 @@ type gooseLogger struct {
 func (l gooseLogger) Fatalf(format string, v ...any) { l.log.Error().Msgf(format, v...) }
 func (l gooseLogger) Printf(format string, v ...any) { l.log.Info().Msgf(format, v...) }
 
-// Connect creates a pgxpool.Pool from the given DSN with the specified connection limits.
+// Connect creates a pgxpool.Pool from the given DSN with the specified connection limits. Queries made with a traced
+// context are recorded as OpenTelemetry spans.
 func Connect(ctx context.Context, dsn string, maxConns, minConns int) (*pgxpool.Pool, error) {
 	cfg, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
@@ -31,6 +35,7 @@ func Connect(ctx context.Context, dsn string, maxConns, minConns int) (*pgxpool.
 	}
 	cfg.MaxConns = int32(maxConns)
 	cfg.MinConns = int32(minConns)
+	cfg.ConnConfig.Tracer = queryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
@@ -66,3 +71,67 @@ func Migrate(dsn string, logger zerolog.Logger) error {
 
 	return nil
 }
+
+// MigrationState describes one embedded migration and whether it is applied to the database.
+type MigrationState struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time // Zero when the migration is pending.
+}
+
+// MigrationStatus returns the state of every embedded migration, oldest first.
+func MigrationStatus(ctx context.Context, dsn string) ([]MigrationState, error) {
+	var states []MigrationState
+	err := withMigrationProvider(dsn, func(p *goose.Provider) error {
+		statuses, err := p.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("read migration status: %w", err)
+		}
+		states = make([]MigrationState, len(statuses))
+		for i, s := range statuses {
+			states[i] = MigrationState{
+				Version:   s.Source.Version,
+				Name:      path.Base(s.Source.Path),
+				Applied:   s.State == goose.StateApplied,
+				AppliedAt: s.AppliedAt,
+			}
+		}
+		return nil
+	})
+	return states, err
+}
+
+// MigrateDown rolls back the most recently applied migration and returns its version. It returns 0 when no migration
+// is applied.
+func MigrateDown(ctx context.Context, dsn string) (int64, error) {
+	var version int64
+	err := withMigrationProvider(dsn, func(p *goose.Provider) error {
+		res, err := p.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("roll back migration: %w", err)
+		}
+		version = res.Source.Version
+		return nil
+	})
+	return version, err
+}
+
+// withMigrationProvider opens a database/sql connection and calls fn with a goose provider over the embedded
+// migrations. The provider shares its version table with Migrate.
+func withMigrationProvider(dsn string, fn func(p *goose.Provider) error) error {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return fmt.Errorf("open sql connection for migrations: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	p, err := goose.NewProvider(goose.DialectPostgres, db, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("create migration provider: %w", err)
+	}
+	return fn(p)
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/uncord-chat/uncord-server/internal/tracing"
+)
+
+// queryTracer records a client span for every query, batch, and COPY made within a traced context. Queries outside a
+// trace are not recorded, so polling loops do not each start a trace of their own.
+type queryTracer struct{}
+
+var (
+	_ pgx.QueryTracer    = queryTracer{}
+	_ pgx.BatchTracer    = queryTracer{}
+	_ pgx.CopyFromTracer = queryTracer{}
+)
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	op := operationName(data.SQL)
+	return startSpan(ctx, op, op, semconv.DBQueryText(data.SQL))
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(ctx, data.Err)
+}
+
+func (queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return startSpan(ctx, "BATCH", "BATCH", semconv.DBOperationBatchSize(data.Batch.Len()))
+}
+
+func (queryTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+func (queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(ctx, data.Err)
+}
+
+func (queryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return startSpan(ctx, "COPY "+data.TableName.Sanitize(), "COPY", semconv.DBCollectionName(data.TableName.Sanitize()))
+}
+
+func (queryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endSpan(ctx, data.Err)
+}
+
+// startSpan starts a client span when ctx is already part of a trace, and returns ctx unchanged otherwise.
+func startSpan(ctx context.Context, name, op string, attr attribute.KeyValue) context.Context {
+	ctx, _ = tracing.StartChild(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNamePostgreSQL, semconv.DBOperationName(op), attr),
+	)
+	return ctx
+}
+
+// endSpan ends the span started by startSpan. pgx.ErrNoRows is an expected outcome rather than a failure.
+func endSpan(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		tracing.RecordError(span, err)
+	}
+	span.End()
+}
+
+// operationName returns the leading SQL keyword of a statement (e.g. "SELECT"), or "QUERY" for an empty statement.
+func operationName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
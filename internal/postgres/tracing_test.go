@@ -0,0 +1,24 @@
+package postgres
+
+import "testing"
+
+func TestOperationName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT id FROM users WHERE id = $1", "SELECT"},
+		{"\n\t\tinsert into messages (id) values ($1)", "INSERT"},
+		{"WITH claimed AS (SELECT 1) UPDATE search_outbox SET attempts = 0", "WITH"},
+		{"", "QUERY"},
+		{"   ", "QUERY"},
+	}
+
+	for _, tt := range tests {
+		if got := operationName(tt.sql); got != tt.want {
+			t.Errorf("operationName(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
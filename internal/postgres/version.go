@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pressly/goose/v3"
+
+	"github.com/uncord-chat/uncord-server/internal/postgres/migrations"
+)
+
+// appliedVersionQuery returns the newest migration goose records as applied. goose appends a row for every up and down
+// migration, so only the most recent row for each version says whether that version is currently applied.
+const appliedVersionQuery = `
+SELECT COALESCE(MAX(version_id), 0)
+FROM (
+    SELECT DISTINCT ON (version_id) version_id, is_applied
+    FROM goose_db_version
+    ORDER BY version_id, id DESC
+) v
+WHERE is_applied`
+
+// LatestMigration returns the version of the newest migration embedded in the binary.
+func LatestMigration() (int64, error) {
+	return latestVersion(migrations.FS)
+}
+
+// latestVersion returns the highest numeric prefix among the .sql files at the root of fsys.
+func latestVersion(fsys fs.FS) (int64, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return 0, fmt.Errorf("list migrations: %w", err)
+	}
+	var latest int64
+	for _, name := range names {
+		v, err := goose.NumericComponent(name)
+		if err != nil {
+			return 0, fmt.Errorf("parse migration %s: %w", name, err)
+		}
+		latest = max(latest, v)
+	}
+	return latest, nil
+}
+
+// RowQuerier runs a query that returns at most one row. Satisfied by *pgxpool.Pool and pgx.Tx.
+type RowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// AppliedMigration returns the version of the newest migration applied to the database, or 0 when none has been.
+func AppliedMigration(ctx context.Context, db RowQuerier) (int64, error) {
+	var version int64
+	if err := db.QueryRow(ctx, appliedVersionQuery).Scan(&version); err != nil {
+		return 0, fmt.Errorf("query applied migration: %w", err)
+	}
+	return version, nil
+}
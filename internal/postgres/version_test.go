@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLatestVersion(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"001_initial_schema.sql": {},
+		"010_later.sql":          {},
+		"002_second.sql":         {},
+		"README.md":              {},
+	}
+	got, err := latestVersion(fsys)
+	if err != nil {
+		t.Fatalf("latestVersion() error = %v", err)
+	}
+	if got != 10 {
+		t.Errorf("latestVersion() = %d, want 10", got)
+	}
+
+	if _, err := latestVersion(fstest.MapFS{"initial.sql": {}}); err == nil {
+		t.Error("latestVersion() with an unnumbered migration expected an error")
+	}
+}
+
+func TestLatestMigration(t *testing.T) {
+	t.Parallel()
+
+	got, err := LatestMigration()
+	if err != nil {
+		t.Fatalf("LatestMigration() error = %v", err)
+	}
+	if got < 1 {
+		t.Errorf("LatestMigration() = %d, want at least 1", got)
+	}
+}
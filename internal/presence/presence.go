@@ -1,10 +1,12 @@
 // Package presence provides ephemeral presence and typing state backed by Valkey. Presence keys expire after 120
-// seconds and are refreshed by each gateway heartbeat. Typing indicators use a 10-second TTL with SET NX to deduplicate
-// rapid keystrokes.
+// seconds and are refreshed by each gateway heartbeat. A user's rich presence (custom status and activities) is stored
+// as JSON in a second key with the same lifetime, so that the status key stays a plain string readable with MGET.
+// Typing indicators use a 10-second TTL with SET NX to deduplicate rapid keystrokes.
 package presence
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -66,17 +68,58 @@ func (s *Store) Get(ctx context.Context, userID uuid.UUID) (string, error) {
 	return val, nil
 }
 
+// Update replaces the user's presence status and rich presence, both with the standard TTL. An empty rich presence
+// removes the stored one.
+func (s *Store) Update(ctx context.Context, userID uuid.UUID, status string, rich Rich) error {
+	var encoded []byte
+	if !rich.Empty() {
+		var err error
+		if encoded, err = json.Marshal(rich); err != nil {
+			return fmt.Errorf("encode rich presence for %s: %w", userID, err)
+		}
+	}
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, presenceKey(userID), status, presenceTTL)
+		if encoded == nil {
+			pipe.Del(ctx, richKey(userID))
+		} else {
+			pipe.Set(ctx, richKey(userID), encoded, presenceTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update presence for %s: %w", userID, err)
+	}
+	return nil
+}
+
+// GetState returns the user's full presence, including statuses that are hidden from other users. A user without a
+// presence key is offline and has no rich presence.
+func (s *Store) GetState(ctx context.Context, userID uuid.UUID) (State, error) {
+	vals, err := s.rdb.MGet(ctx, presenceKey(userID), richKey(userID)).Result()
+	if err != nil {
+		return State{}, fmt.Errorf("get presence state for %s: %w", userID, err)
+	}
+	state := State{PresenceState: models.PresenceState{UserID: userID.String(), Status: StatusOffline}}
+	if status, ok := vals[0].(string); ok {
+		state.Status = status
+		state.Rich = decodeRich(vals[1]).visible(time.Now())
+	}
+	return state, nil
+}
+
 // GetMany returns the visible presence state for each user. Invisible users are excluded from the result so they
-// appear offline to other clients. The returned slice may be shorter than the input when users are offline or
-// invisible.
-func (s *Store) GetMany(ctx context.Context, userIDs []uuid.UUID) ([]models.PresenceState, error) {
+// appear offline to other clients, and custom statuses that have expired are left out. The returned slice may be
+// shorter than the input when users are offline or invisible.
+func (s *Store) GetMany(ctx context.Context, userIDs []uuid.UUID) ([]State, error) {
 	if len(userIDs) == 0 {
 		return nil, nil
 	}
 
-	keys := make([]string, len(userIDs))
-	for i, id := range userIDs {
-		keys[i] = presenceKey(id)
+	// Status and rich presence keys are interleaved so that one MGET reads both for every user.
+	keys := make([]string, 0, 2*len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, presenceKey(id), richKey(id))
 	}
 
 	vals, err := s.rdb.MGet(ctx, keys...).Result()
@@ -84,41 +127,88 @@ func (s *Store) GetMany(ctx context.Context, userIDs []uuid.UUID) ([]models.Pres
 		return nil, fmt.Errorf("mget presence: %w", err)
 	}
 
-	result := make([]models.PresenceState, 0, len(userIDs))
-	for i, v := range vals {
-		if v == nil {
-			continue
-		}
+	now := time.Now()
+	result := make([]State, 0, len(userIDs))
+	for i, id := range userIDs {
 		// MGet returns []interface{} where each element is nil or a string. The comma-ok assertion guards against
 		// unexpected types from future Redis driver changes or pipeline corruption.
-		status, ok := v.(string)
+		status, ok := vals[2*i].(string)
 		if !ok || status == StatusInvisible {
 			continue
 		}
-		result = append(result, models.PresenceState{
-			UserID: userIDs[i].String(),
-			Status: status,
+		result = append(result, State{
+			PresenceState: models.PresenceState{UserID: id.String(), Status: status},
+			Rich:          decodeRich(vals[2*i+1]).visible(now),
 		})
 	}
 	return result, nil
 }
 
-// Refresh extends the TTL of an existing presence key without changing the stored status.
+// Refresh extends the TTL of an existing presence key, and of the user's rich presence, without changing either.
 func (s *Store) Refresh(ctx context.Context, userID uuid.UUID) error {
-	if err := s.rdb.Expire(ctx, presenceKey(userID), presenceTTL).Err(); err != nil {
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Expire(ctx, presenceKey(userID), presenceTTL)
+		pipe.Expire(ctx, richKey(userID), presenceTTL)
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("refresh presence for %s: %w", userID, err)
 	}
 	return nil
 }
 
-// Delete removes the user's presence key. After deletion the user is considered offline.
+// Delete removes the user's presence and rich presence keys. After deletion the user is considered offline.
 func (s *Store) Delete(ctx context.Context, userID uuid.UUID) error {
-	if err := s.rdb.Del(ctx, presenceKey(userID)).Err(); err != nil {
+	if err := s.rdb.Del(ctx, presenceKey(userID), richKey(userID)).Err(); err != nil {
 		return fmt.Errorf("delete presence for %s: %w", userID, err)
 	}
 	return nil
 }
 
+// ClearExpiredCustomStatus removes the user's custom status if it has expired by now, keeping their activities. It
+// reports whether a custom status was removed. The key is watched so that a concurrent Update is never overwritten.
+func (s *Store) ClearExpiredCustomStatus(ctx context.Context, userID uuid.UUID, now time.Time) (bool, error) {
+	key := richKey(userID)
+	cleared := false
+	err := s.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		raw, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rich := decodeRich(raw)
+		if !rich.customStatusExpired(now) {
+			return nil
+		}
+		rich.CustomStatus = nil
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if rich.Empty() {
+				pipe.Del(ctx, key)
+				return nil
+			}
+			encoded, err := json.Marshal(rich)
+			if err != nil {
+				return err
+			}
+			pipe.SetArgs(ctx, key, encoded, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		cleared = err == nil
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		// The key changed while it was being read, so the custom status it held was replaced or removed already.
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("clear expired custom status for %s: %w", userID, err)
+	}
+	return cleared, nil
+}
+
 // SetTyping records that the user started typing in the given channel. The key uses SET NX so repeated calls within
 // the TTL window are no-ops. Returns true when the key was newly created (meaning a TYPING_START dispatch should be
 // sent), and false when the key already existed (duplicate suppressed).
@@ -155,6 +245,20 @@ func presenceKey(userID uuid.UUID) string {
 	return "presence:" + userID.String()
 }
 
+func richKey(userID uuid.UUID) string {
+	return "presence_rich:" + userID.String()
+}
+
+// decodeRich decodes a rich presence key's value as returned by MGET. A missing or undecodable value yields an empty
+// rich presence, so that a corrupt key degrades to a plain status rather than failing the whole lookup.
+func decodeRich(v any) Rich {
+	var rich Rich
+	if raw, ok := v.(string); ok {
+		_ = json.Unmarshal([]byte(raw), &rich)
+	}
+	return rich
+}
+
 func typingKey(channelID, userID uuid.UUID) string {
 	return "typing:" + channelID.String() + ":" + userID.String()
 }
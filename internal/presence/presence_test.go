@@ -268,3 +268,164 @@ func TestValidStatus(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateAndGetState(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	store := NewStore(rdb)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	rich := Rich{
+		CustomStatus: &CustomStatus{Text: "Deep work", Emoji: "🎧"},
+		Activities:   []Activity{{Type: ActivityPlaying, Name: "Chess"}},
+	}
+	if err := store.Update(ctx, userID, StatusDND, rich); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	state, err := store.GetState(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if state.Status != StatusDND || state.CustomStatus == nil || state.CustomStatus.Text != "Deep work" {
+		t.Errorf("GetState() = %+v, want dnd with the custom status", state)
+	}
+	if len(state.Activities) != 1 || state.Activities[0].Name != "Chess" {
+		t.Errorf("GetState().Activities = %+v, want Chess", state.Activities)
+	}
+
+	// An update without rich presence clears the stored one.
+	if err := store.Update(ctx, userID, StatusOnline, Rich{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if n, _ := rdb.Exists(ctx, richKey(userID)).Result(); n != 0 {
+		t.Error("rich presence key exists after an update without rich presence")
+	}
+}
+
+func TestGetStateOffline(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	store := NewStore(rdb)
+
+	state, err := store.GetState(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if state.Status != StatusOffline || !state.Empty() {
+		t.Errorf("GetState() = %+v, want offline without rich presence", state)
+	}
+}
+
+func TestGetManyRichPresence(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	store := NewStore(rdb)
+	ctx := context.Background()
+
+	expired := time.Now().Add(-time.Minute)
+	alice, bob := uuid.New(), uuid.New()
+	if err := store.Update(ctx, alice, StatusOnline, Rich{CustomStatus: &CustomStatus{Text: "Hi"}}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	bobRich := Rich{
+		CustomStatus: &CustomStatus{Text: "Back soon", ExpiresAt: &expired},
+		Activities:   []Activity{{Type: ActivityListening, Name: "Radio"}},
+	}
+	if err := store.Update(ctx, bob, StatusIdle, bobRich); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	result, err := store.GetMany(ctx, []uuid.UUID{alice, bob})
+	if err != nil {
+		t.Fatalf("GetMany() error = %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("GetMany() returned %d results, want 2", len(result))
+	}
+	if cs := result[0].CustomStatus; cs == nil || cs.Text != "Hi" {
+		t.Errorf("result[0].CustomStatus = %+v, want Hi", cs)
+	}
+	// An expired custom status is hidden even before it is cleared.
+	if result[1].CustomStatus != nil || len(result[1].Activities) != 1 {
+		t.Errorf("result[1] = %+v, want the activity without the expired custom status", result[1].Rich)
+	}
+}
+
+func TestRefreshExtendsRichTTL(t *testing.T) {
+	t.Parallel()
+	mr, rdb := newTestRedis(t)
+	store := NewStore(rdb)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	rich := Rich{Activities: []Activity{{Type: ActivityPlaying, Name: "Chess"}}}
+	if err := store.Update(ctx, userID, StatusOnline, rich); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	mr.FastForward(100 * time.Second)
+	if err := store.Refresh(ctx, userID); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	mr.FastForward(100 * time.Second)
+
+	state, err := store.GetState(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if len(state.Activities) != 1 {
+		t.Errorf("GetState().Activities = %+v after Refresh, want one activity", state.Activities)
+	}
+
+	if err := store.Delete(ctx, userID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if mr.Exists(richKey(userID)) {
+		t.Error("rich presence key exists after Delete")
+	}
+}
+
+func TestClearExpiredCustomStatus(t *testing.T) {
+	t.Parallel()
+	_, rdb := newTestRedis(t)
+	store := NewStore(rdb)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	now := time.Now()
+	expiresAt := now.Add(time.Minute)
+	rich := Rich{
+		CustomStatus: &CustomStatus{Text: "Lunch", ExpiresAt: &expiresAt},
+		Activities:   []Activity{{Type: ActivityPlaying, Name: "Chess"}},
+	}
+	if err := store.Update(ctx, userID, StatusOnline, rich); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	cleared, err := store.ClearExpiredCustomStatus(ctx, userID, now)
+	if err != nil {
+		t.Fatalf("ClearExpiredCustomStatus() error = %v", err)
+	}
+	if cleared {
+		t.Error("ClearExpiredCustomStatus() = true before the expiry, want false")
+	}
+
+	cleared, err = store.ClearExpiredCustomStatus(ctx, userID, expiresAt)
+	if err != nil {
+		t.Fatalf("ClearExpiredCustomStatus() error = %v", err)
+	}
+	if !cleared {
+		t.Error("ClearExpiredCustomStatus() = false at the expiry, want true")
+	}
+	state, err := store.GetState(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if state.CustomStatus != nil || len(state.Activities) != 1 {
+		t.Errorf("GetState() = %+v, want the activity without the custom status", state.Rich)
+	}
+	if ttl := rdb.TTL(ctx, richKey(userID)).Val(); ttl <= 0 {
+		t.Errorf("rich presence TTL = %v after clearing, want it kept", ttl)
+	}
+}
@@ -0,0 +1,168 @@
+package presence
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/uncord-chat/uncord-protocol/models"
+)
+
+const (
+	// ActivityPlaying indicates the user is playing a game.
+	ActivityPlaying = "playing"
+	// ActivityListening indicates the user is listening to audio, such as music or a podcast.
+	ActivityListening = "listening"
+	// ActivityStreaming indicates the user is streaming. Streaming activities carry the URL of the stream.
+	ActivityStreaming = "streaming"
+
+	// MaxCustomStatusLength is the maximum length in characters of custom status text.
+	MaxCustomStatusLength = 128
+	// MaxEmojiLength is the maximum length in bytes of a custom status emoji, which is either a Unicode emoji or the
+	// ID of a server emoji.
+	MaxEmojiLength = 64
+	// MaxActivities is the maximum number of activities a user may show at once.
+	MaxActivities = 5
+	// MaxActivityFieldLength is the maximum length in characters of an activity's name, details and state.
+	MaxActivityFieldLength = 128
+	// MaxActivityURLLength is the maximum length in bytes of a streaming activity's URL.
+	MaxActivityURLLength = 512
+)
+
+// Sentinel errors for rich presence validation.
+var (
+	ErrCustomStatusEmpty   = errors.New("custom status must have text or an emoji")
+	ErrCustomStatusLength  = errors.New("custom status text must be 128 characters or fewer")
+	ErrEmojiLength         = errors.New("custom status emoji must be 64 bytes or fewer")
+	ErrExpiryInPast        = errors.New("custom status expiry must be in the future")
+	ErrTooManyActivities   = errors.New("a user may show at most 5 activities")
+	ErrInvalidActivityType = errors.New("invalid activity type")
+	ErrActivityNameLength  = errors.New("activity name must be between 1 and 128 characters")
+	ErrActivityFieldLength = errors.New("activity details and state must be 128 characters or fewer")
+	ErrInvalidActivityURL  = errors.New("streaming activities require an http or https URL of 512 bytes or fewer")
+	ErrUnexpectedURL       = errors.New("only streaming activities may have a URL")
+	ErrInvalidTimestamps   = errors.New("activity end must be after its start")
+)
+
+// CustomStatus is free-form status text chosen by the user, with an optional emoji. A status with ExpiresAt set is
+// cleared once that time passes.
+type CustomStatus struct {
+	Text      string     `json:"text,omitempty"`
+	Emoji     string     `json:"emoji,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Activity is something the user is doing, such as playing a game or listening to music. StartedAt and EndsAt let
+// clients show elapsed or remaining time.
+type Activity struct {
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	Details   string     `json:"details,omitempty"`
+	State     string     `json:"state,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+}
+
+// Rich is the part of a user's presence beyond their status: a custom status and the activities they are showing.
+type Rich struct {
+	CustomStatus *CustomStatus `json:"custom_status,omitempty"`
+	Activities   []Activity    `json:"activities,omitempty"`
+}
+
+// State is a snapshot of a single user's presence. It is the payload of PRESENCE_UPDATE dispatches and the presence
+// entries of READY and member chunks, extending models.PresenceState with the user's rich presence.
+type State struct {
+	models.PresenceState
+	Rich
+}
+
+// Empty reports whether r has neither a custom status nor any activities.
+func (r Rich) Empty() bool {
+	return r.CustomStatus == nil && len(r.Activities) == 0
+}
+
+// Validate checks a rich presence sent by a client, trimming whitespace from its text fields in place. A custom status
+// expiry must be later than now.
+func (r *Rich) Validate(now time.Time) error {
+	if cs := r.CustomStatus; cs != nil {
+		cs.Text = strings.TrimSpace(cs.Text)
+		cs.Emoji = strings.TrimSpace(cs.Emoji)
+		if cs.Text == "" && cs.Emoji == "" {
+			return ErrCustomStatusEmpty
+		}
+		if utf8.RuneCountInString(cs.Text) > MaxCustomStatusLength {
+			return ErrCustomStatusLength
+		}
+		if len(cs.Emoji) > MaxEmojiLength {
+			return ErrEmojiLength
+		}
+		if cs.ExpiresAt != nil && !cs.ExpiresAt.After(now) {
+			return ErrExpiryInPast
+		}
+	}
+
+	if len(r.Activities) > MaxActivities {
+		return ErrTooManyActivities
+	}
+	for i := range r.Activities {
+		if err := r.Activities[i].validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Activity) validate() error {
+	switch a.Type {
+	case ActivityPlaying, ActivityListening, ActivityStreaming:
+	default:
+		return ErrInvalidActivityType
+	}
+
+	a.Name = strings.TrimSpace(a.Name)
+	a.Details = strings.TrimSpace(a.Details)
+	a.State = strings.TrimSpace(a.State)
+	if n := utf8.RuneCountInString(a.Name); n < 1 || n > MaxActivityFieldLength {
+		return ErrActivityNameLength
+	}
+	if utf8.RuneCountInString(a.Details) > MaxActivityFieldLength || utf8.RuneCountInString(a.State) >
+		MaxActivityFieldLength {
+		return ErrActivityFieldLength
+	}
+
+	if a.Type == ActivityStreaming {
+		if !validStreamURL(a.URL) {
+			return ErrInvalidActivityURL
+		}
+	} else if a.URL != "" {
+		return ErrUnexpectedURL
+	}
+
+	if a.StartedAt != nil && a.EndsAt != nil && !a.EndsAt.After(*a.StartedAt) {
+		return ErrInvalidTimestamps
+	}
+	return nil
+}
+
+func validStreamURL(raw string) bool {
+	if raw == "" || len(raw) > MaxActivityURLLength {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// visible returns r as other users should see it at now, without a custom status that has expired.
+func (r Rich) visible(now time.Time) Rich {
+	if r.customStatusExpired(now) {
+		r.CustomStatus = nil
+	}
+	return r
+}
+
+func (r Rich) customStatusExpired(now time.Time) bool {
+	return r.CustomStatus != nil && r.CustomStatus.ExpiresAt != nil && !r.CustomStatus.ExpiresAt.After(now)
+}
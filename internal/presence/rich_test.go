@@ -0,0 +1,85 @@
+package presence
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRichValidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		rich Rich
+		want error
+	}{
+		{"empty", Rich{}, nil},
+		{"text only", Rich{CustomStatus: &CustomStatus{Text: "Busy"}}, nil},
+		{"emoji only", Rich{CustomStatus: &CustomStatus{Emoji: "🔥"}}, nil},
+		{"blank custom status", Rich{CustomStatus: &CustomStatus{Text: "   "}}, ErrCustomStatusEmpty},
+		{"long text", Rich{CustomStatus: &CustomStatus{Text: strings.Repeat("a", 129)}}, ErrCustomStatusLength},
+		{"long emoji", Rich{CustomStatus: &CustomStatus{Emoji: strings.Repeat("e", 65)}}, ErrEmojiLength},
+		{"future expiry", Rich{CustomStatus: &CustomStatus{Text: "x", ExpiresAt: &future}}, nil},
+		{"past expiry", Rich{CustomStatus: &CustomStatus{Text: "x", ExpiresAt: &past}}, ErrExpiryInPast},
+		{"too many activities", Rich{Activities: make([]Activity, MaxActivities+1)}, ErrTooManyActivities},
+		{"unknown type", Rich{Activities: []Activity{{Type: "dancing", Name: "x"}}}, ErrInvalidActivityType},
+		{"missing name", Rich{Activities: []Activity{{Type: ActivityPlaying, Name: " "}}}, ErrActivityNameLength},
+		{
+			"long details",
+			Rich{Activities: []Activity{{Type: ActivityPlaying, Name: "x", Details: strings.Repeat("d", 129)}}},
+			ErrActivityFieldLength,
+		},
+		{
+			"stream",
+			Rich{Activities: []Activity{{Type: ActivityStreaming, Name: "x", URL: "https://example.com/live"}}},
+			nil,
+		},
+		{"stream without URL", Rich{Activities: []Activity{{Type: ActivityStreaming, Name: "x"}}}, ErrInvalidActivityURL},
+		{
+			"stream with other scheme",
+			Rich{Activities: []Activity{{Type: ActivityStreaming, Name: "x", URL: "javascript:alert(1)"}}},
+			ErrInvalidActivityURL,
+		},
+		{
+			"URL on another type",
+			Rich{Activities: []Activity{{Type: ActivityListening, Name: "x", URL: "https://example.com"}}},
+			ErrUnexpectedURL,
+		},
+		{
+			"timestamps",
+			Rich{Activities: []Activity{{Type: ActivityPlaying, Name: "x", StartedAt: &now, EndsAt: &future}}},
+			nil,
+		},
+		{
+			"end before start",
+			Rich{Activities: []Activity{{Type: ActivityPlaying, Name: "x", StartedAt: &now, EndsAt: &past}}},
+			ErrInvalidTimestamps,
+		},
+	}
+	for _, tt := range tests {
+		if err := tt.rich.Validate(now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRichValidateTrims(t *testing.T) {
+	t.Parallel()
+
+	rich := Rich{
+		CustomStatus: &CustomStatus{Text: "  Lunch  "},
+		Activities:   []Activity{{Type: ActivityPlaying, Name: " Chess ", Details: " Ranked "}},
+	}
+	if err := rich.Validate(time.Now()); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if rich.CustomStatus.Text != "Lunch" || rich.Activities[0].Name != "Chess" || rich.Activities[0].Details != "Ranked" {
+		t.Errorf("Validate() left %+v, %+v; want trimmed text", rich.CustomStatus, rich.Activities[0])
+	}
+}
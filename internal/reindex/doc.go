@@ -0,0 +1,5 @@
+// Package reindex rebuilds the Typesense message index from PostgreSQL. A run streams every indexable message into a
+// new collection in keyset-ordered batches, replays messages that changed while it ran, and then promotes the new
+// collection behind the messages alias so searches never see a partially built index. Progress is checkpointed in
+// Valkey so an interrupted run resumes where it stopped.
+package reindex
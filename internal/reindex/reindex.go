@@ -0,0 +1,399 @@
+package reindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
+	"github.com/uncord-chat/uncord-server/internal/typesense"
+)
+
+// Valkey keys holding the reindex lock and the persisted progress of the most recent run.
+const (
+	lockKey  = "search:reindex:lock"
+	stateKey = "search:reindex:state"
+)
+
+const (
+	// DefaultBatchSize is the number of messages read from PostgreSQL and imported into Typesense per request.
+	DefaultBatchSize = 500
+
+	// lockTTL is how long the reindex lock survives without being refreshed. It is refreshed after every batch, so a
+	// crashed process releases the lock within this window and another run can resume its work.
+	lockTTL = 2 * time.Minute
+
+	// catchUpSlack widens the catch-up window to cover clock skew between the server and PostgreSQL.
+	catchUpSlack = time.Minute
+
+	// maxAttempts bounds the retries of a single batch before the run fails (and can later be resumed).
+	maxAttempts = 5
+)
+
+// ErrAlreadyRunning is returned when another reindex holds the lock.
+var ErrAlreadyRunning = errors.New("a search reindex is already running")
+
+// Source reads messages from the primary database. Satisfied by *message.PGRepository.
+type Source interface {
+	CountIndexable(ctx context.Context) (int64, error)
+	ListIndexable(ctx context.Context, after uuid.UUID, limit int) ([]message.IndexEntry, error)
+	ListChangedSince(ctx context.Context, since time.Time, after uuid.UUID, limit int) ([]message.IndexEntry, error)
+}
+
+// Target builds and promotes search collections. Satisfied by *typesense.Collections.
+type Target interface {
+	Create(ctx context.Context) (string, error)
+	Exists(ctx context.Context, name string) (bool, error)
+	Import(ctx context.Context, collection string, docs []typesense.MessageDocument) error
+	Delete(ctx context.Context, collection string, ids []string) error
+	Promote(ctx context.Context, collection string) error
+	Drop(ctx context.Context, collection string) error
+}
+
+var _ Target = (*typesense.Collections)(nil)
+
+// Options controls a reindex run.
+type Options struct {
+	// Fresh discards the checkpoint of an interrupted run, dropping its partial collection, and starts over in a new one.
+	Fresh bool
+}
+
+// Progress is the persisted state of a reindex run. It doubles as the resume checkpoint: Cursor is the ID of the last
+// message imported by the bulk pass.
+type Progress struct {
+	Collection  string     `json:"collection"`
+	Cursor      uuid.UUID  `json:"cursor"`
+	Indexed     int64      `json:"indexed"`
+	Total       int64      `json:"total"`
+	StartedAt   time.Time  `json:"started_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Running     bool       `json:"running"`
+}
+
+// Runner copies every indexable message from PostgreSQL into a new Typesense collection and then promotes it behind
+// the messages alias, so searches keep working against the old collection until the new one is complete. Runs are
+// serialised across processes by a Valkey lock and resume from their last checkpoint after an interruption.
+type Runner struct {
+	source    Source
+	target    Target
+	rdb       *redis.Client
+	batchSize int
+	log       zerolog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRunner creates a reindex runner. Background runs started with Start are cancelled by Stop.
+func NewRunner(source Source, target Target, rdb *redis.Client, batchSize int, logger zerolog.Logger) *Runner {
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		source:    source,
+		target:    target,
+		rdb:       rdb,
+		batchSize: batchSize,
+		log:       logger.With().Str("component", "reindex").Logger(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Run performs a reindex in the calling goroutine. It returns ErrAlreadyRunning if another run holds the lock.
+// Cancelling ctx stops the run after the current batch; the checkpoint is kept so the next run resumes from it.
+func (r *Runner) Run(ctx context.Context, opts Options) error {
+	token, err := r.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.release(token)
+	return r.run(ctx, token, opts)
+}
+
+// Start acquires the reindex lock and runs the reindex in a background goroutine. It returns ErrAlreadyRunning
+// immediately if another run holds the lock.
+func (r *Runner) Start(opts Options) error {
+	token, err := r.acquire(r.ctx)
+	if err != nil {
+		return err
+	}
+	r.wg.Go(func() {
+		defer r.release(token)
+		if err := r.run(r.ctx, token, opts); err != nil && !errors.Is(err, context.Canceled) {
+			r.log.Error().Err(err).Msg("Search reindex failed")
+		}
+	})
+	return nil
+}
+
+// Stop cancels any background run and waits for it to return.
+func (r *Runner) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// Status returns the progress of the current or most recent run, or nil if no run has been recorded.
+func (r *Runner) Status(ctx context.Context) (*Progress, error) {
+	p, err := r.load(ctx)
+	if err != nil || p == nil {
+		return p, err
+	}
+	held, err := r.rdb.Exists(ctx, lockKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("check reindex lock: %w", err)
+	}
+	p.Running = held == 1 && p.CompletedAt == nil
+	return p, nil
+}
+
+func (r *Runner) run(ctx context.Context, token string, opts Options) error {
+	p, err := r.prepare(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := r.bulk(ctx, token, p); err != nil {
+		return r.fail(p, err)
+	}
+	if err := r.catchUp(ctx, token, p); err != nil {
+		return r.fail(p, err)
+	}
+	if err := r.target.Promote(ctx, p.Collection); err != nil {
+		return r.fail(p, fmt.Errorf("promote collection: %w", err))
+	}
+
+	now := time.Now()
+	p.CompletedAt = &now
+	if err := r.save(context.WithoutCancel(ctx), p); err != nil {
+		return err
+	}
+	r.log.Info().Str("collection", p.Collection).Int64("indexed", p.Indexed).
+		Dur("elapsed", now.Sub(p.StartedAt)).Msg("Search reindex complete")
+	return nil
+}
+
+// prepare resumes the checkpoint of an interrupted run when its collection still exists, or starts a new run in a
+// freshly created collection. A checkpoint discarded with Options.Fresh has its collection dropped, since nothing else
+// would ever remove it.
+func (r *Runner) prepare(ctx context.Context, opts Options) (*Progress, error) {
+	p, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil && p.CompletedAt == nil && p.Collection != "" {
+		exists, err := r.target.Exists(ctx, p.Collection)
+		if err != nil {
+			return nil, fmt.Errorf("check checkpoint collection: %w", err)
+		}
+		switch {
+		case exists && !opts.Fresh:
+			p.Error = ""
+			r.log.Info().Str("collection", p.Collection).Int64("indexed", p.Indexed).Int64("total", p.Total).
+				Msg("Resuming search reindex")
+			return p, r.save(ctx, p)
+		case exists:
+			if err := r.target.Drop(ctx, p.Collection); err != nil {
+				r.log.Warn().Err(err).Str("collection", p.Collection).Msg("Failed to drop discarded reindex collection")
+			} else {
+				r.log.Info().Str("collection", p.Collection).Msg("Dropped discarded reindex collection")
+			}
+		}
+	}
+
+	total, err := r.source.CountIndexable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name, err := r.target.Create(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create collection: %w", err)
+	}
+	p = &Progress{Collection: name, Total: total, StartedAt: time.Now()}
+	r.log.Info().Str("collection", name).Int64("total", total).Msg("Starting search reindex")
+	return p, r.save(ctx, p)
+}
+
+// bulk imports every indexable message in keyset order, checkpointing after each batch.
+func (r *Runner) bulk(ctx context.Context, token string, p *Progress) error {
+	for {
+		entries, err := r.source.ListIndexable(ctx, p.Cursor, r.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		docs := make([]typesense.MessageDocument, len(entries))
+		for i := range entries {
+			docs[i] = typesense.NewMessageDocument(&entries[i])
+		}
+		if err := r.retry(ctx, func() error { return r.target.Import(ctx, p.Collection, docs) }); err != nil {
+			return fmt.Errorf("import batch: %w", err)
+		}
+
+		p.Cursor = entries[len(entries)-1].ID
+		p.Indexed += int64(len(entries))
+		if err := r.checkpoint(ctx, token, p); err != nil {
+			return err
+		}
+		r.log.Info().Int64("indexed", p.Indexed).Int64("total", p.Total).Msg("Search reindex progress")
+	}
+}
+
+// catchUp replays messages created, edited, or deleted since the run started. The bulk pass walks random UUIDs in
+// order, so messages written behind its cursor while it ran would otherwise be missing from the new collection.
+func (r *Runner) catchUp(ctx context.Context, token string, p *Progress) error {
+	since := p.StartedAt.Add(-catchUpSlack)
+	after := uuid.Nil
+	for {
+		entries, err := r.source.ListChangedSince(ctx, since, after, r.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		var docs []typesense.MessageDocument
+		var removed []string
+		for i := range entries {
+			if entries[i].Removed {
+				removed = append(removed, entries[i].ID.String())
+			} else {
+				docs = append(docs, typesense.NewMessageDocument(&entries[i]))
+			}
+		}
+		if err := r.retry(ctx, func() error { return r.target.Import(ctx, p.Collection, docs) }); err != nil {
+			return fmt.Errorf("import changed messages: %w", err)
+		}
+		if err := r.retry(ctx, func() error { return r.target.Delete(ctx, p.Collection, removed) }); err != nil {
+			return fmt.Errorf("delete removed messages: %w", err)
+		}
+
+		after = entries[len(entries)-1].ID
+		if err := r.refresh(ctx, token); err != nil {
+			return err
+		}
+	}
+}
+
+// retry runs fn until it succeeds, backing off exponentially between attempts.
+func (r *Runner) retry(ctx context.Context, fn func() error) error {
+	delay := time.Second
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt == maxAttempts {
+			return err
+		}
+		r.log.Warn().Err(err).Int("attempt", attempt).Dur("retry_in", delay).Msg("Search reindex batch failed")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// fail records err on the checkpoint so operators can see why the run stopped, and returns it.
+func (r *Runner) fail(p *Progress, err error) error {
+	p.Error = err.Error()
+	if saveErr := r.save(context.Background(), p); saveErr != nil {
+		r.log.Warn().Err(saveErr).Msg("Failed to record search reindex error")
+	}
+	return err
+}
+
+// acquire takes the reindex lock with a random token that identifies this run as its holder.
+func (r *Runner) acquire(ctx context.Context) (string, error) {
+	token := uuid.NewString()
+	ok, err := r.rdb.SetNX(ctx, lockKey, token, lockTTL).Result()
+	if err != nil {
+		return "", fmt.Errorf("acquire reindex lock: %w", err)
+	}
+	if !ok {
+		return "", ErrAlreadyRunning
+	}
+	return token, nil
+}
+
+// refreshScript extends the lock only while it is still held by the caller's token.
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock only while it is still held by the caller's token.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (r *Runner) refresh(ctx context.Context, token string) error {
+	n, err := refreshScript.Run(ctx, r.rdb, []string{lockKey}, token, lockTTL.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("refresh reindex lock: %w", err)
+	}
+	if n == 0 {
+		return errors.New("reindex lock was lost")
+	}
+	return nil
+}
+
+func (r *Runner) release(token string) {
+	if err := releaseScript.Run(context.Background(), r.rdb, []string{lockKey}, token).Err(); err != nil {
+		r.log.Warn().Err(err).Msg("Failed to release reindex lock")
+	}
+}
+
+// checkpoint persists progress and extends the lock.
+func (r *Runner) checkpoint(ctx context.Context, token string, p *Progress) error {
+	if err := r.save(ctx, p); err != nil {
+		return err
+	}
+	return r.refresh(ctx, token)
+}
+
+func (r *Runner) save(ctx context.Context, p *Progress) error {
+	p.UpdatedAt = time.Now()
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal reindex progress: %w", err)
+	}
+	if err := r.rdb.Set(ctx, stateKey, data, 0).Err(); err != nil {
+		return fmt.Errorf("save reindex progress: %w", err)
+	}
+	return nil
+}
+
+func (r *Runner) load(ctx context.Context) (*Progress, error) {
+	data, err := r.rdb.Get(ctx, stateKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load reindex progress: %w", err)
+	}
+	var p Progress
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal reindex progress: %w", err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,354 @@
+package reindex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
+	"github.com/uncord-chat/uncord-server/internal/typesense"
+)
+
+// fakeSource serves messages from memory in ID order, mirroring the keyset pagination of the PostgreSQL queries.
+type fakeSource struct {
+	entries []message.IndexEntry
+	changed []message.IndexEntry
+}
+
+func newFakeSource(n int) *fakeSource {
+	s := &fakeSource{}
+	for range n {
+		s.entries = append(s.entries, message.IndexEntry{
+			ID:        uuid.New(),
+			ChannelID: uuid.New(),
+			AuthorID:  uuid.New(),
+			Content:   "hello",
+			CreatedAt: time.Now(),
+		})
+	}
+	slices.SortFunc(s.entries, func(a, b message.IndexEntry) int { return bytes.Compare(a.ID[:], b.ID[:]) })
+	return s
+}
+
+func (s *fakeSource) CountIndexable(context.Context) (int64, error) {
+	return int64(len(s.entries)), nil
+}
+
+func (s *fakeSource) ListIndexable(ctx context.Context, after uuid.UUID, limit int) ([]message.IndexEntry, error) {
+	return page(ctx, s.entries, after, limit)
+}
+
+func (s *fakeSource) ListChangedSince(ctx context.Context, _ time.Time, after uuid.UUID, limit int) ([]message.IndexEntry, error) {
+	return page(ctx, s.changed, after, limit)
+}
+
+func page(ctx context.Context, entries []message.IndexEntry, after uuid.UUID, limit int) ([]message.IndexEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var out []message.IndexEntry
+	for _, e := range entries {
+		if bytes.Compare(e.ID[:], after[:]) > 0 {
+			out = append(out, e)
+		}
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// fakeTarget stores collections in memory. onImport, when set, runs after every successful import.
+type fakeTarget struct {
+	mu          sync.Mutex
+	collections map[string]map[string]typesense.MessageDocument
+	created     int
+	promoted    string
+	onImport    func()
+}
+
+func newFakeTarget() *fakeTarget {
+	return &fakeTarget{collections: make(map[string]map[string]typesense.MessageDocument)}
+}
+
+func (t *fakeTarget) Create(context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.created++
+	name := "messages_" + uuid.NewString()
+	t.collections[name] = make(map[string]typesense.MessageDocument)
+	return name, nil
+}
+
+func (t *fakeTarget) Exists(_ context.Context, name string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.collections[name]
+	return ok, nil
+}
+
+func (t *fakeTarget) Import(_ context.Context, collection string, docs []typesense.MessageDocument) error {
+	t.mu.Lock()
+	for _, d := range docs {
+		t.collections[collection][d.ID] = d
+	}
+	t.mu.Unlock()
+	if t.onImport != nil {
+		t.onImport()
+	}
+	return nil
+}
+
+func (t *fakeTarget) Delete(_ context.Context, collection string, ids []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range ids {
+		delete(t.collections[collection], id)
+	}
+	return nil
+}
+
+func (t *fakeTarget) Promote(_ context.Context, collection string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.promoted = collection
+	return nil
+}
+
+func (t *fakeTarget) Drop(_ context.Context, collection string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if collection != t.promoted {
+		delete(t.collections, collection)
+	}
+	return nil
+}
+
+func (t *fakeTarget) docs(collection string) map[string]typesense.MessageDocument {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.collections[collection]
+}
+
+func newTestRunner(t *testing.T, source Source, target Target, batchSize int) (*Runner, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	r := NewRunner(source, target, rdb, batchSize, zerolog.Nop())
+	t.Cleanup(r.Stop)
+	return r, mr
+}
+
+func TestRunIndexesAllAndPromotes(t *testing.T) {
+	t.Parallel()
+	source := newFakeSource(7)
+	target := newFakeTarget()
+	r, mr := newTestRunner(t, source, target, 3)
+
+	if err := r.Run(context.Background(), Options{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if target.promoted == "" {
+		t.Fatal("collection was not promoted")
+	}
+	if got := len(target.docs(target.promoted)); got != 7 {
+		t.Errorf("promoted collection has %d docs, want 7", got)
+	}
+	if mr.Exists(lockKey) {
+		t.Error("lock was not released")
+	}
+
+	p, err := r.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if p == nil || p.CompletedAt == nil || p.Running {
+		t.Fatalf("Status() = %+v, want completed and not running", p)
+	}
+	if p.Indexed != 7 || p.Total != 7 {
+		t.Errorf("Indexed/Total = %d/%d, want 7/7", p.Indexed, p.Total)
+	}
+	if p.Collection != target.promoted {
+		t.Errorf("Collection = %q, want %q", p.Collection, target.promoted)
+	}
+}
+
+func TestRunAlreadyRunning(t *testing.T) {
+	t.Parallel()
+	r, mr := newTestRunner(t, newFakeSource(1), newFakeTarget(), 10)
+	if err := mr.Set(lockKey, "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Run(context.Background(), Options{}); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("Run() error = %v, want ErrAlreadyRunning", err)
+	}
+	if err := r.Start(Options{}); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("Start() error = %v, want ErrAlreadyRunning", err)
+	}
+	if got, _ := mr.Get(lockKey); got != "other" {
+		t.Errorf("lock holder = %q, want the other run's token to be left intact", got)
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	t.Parallel()
+	source := newFakeSource(10)
+	target := newFakeTarget()
+	r, _ := newTestRunner(t, source, target, 4)
+
+	// Interrupt the first run after its first batch has been imported.
+	ctx, cancel := context.WithCancel(context.Background())
+	target.onImport = cancel
+	if err := r.Run(ctx, Options{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("first Run() error = %v, want context.Canceled", err)
+	}
+	target.onImport = nil
+
+	p, err := r.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if p.CompletedAt != nil || p.Indexed != 4 || p.Error == "" {
+		t.Fatalf("checkpoint = %+v, want 4 indexed, incomplete, with an error", p)
+	}
+	if p.Cursor != source.entries[3].ID {
+		t.Errorf("Cursor = %s, want %s", p.Cursor, source.entries[3].ID)
+	}
+
+	if err := r.Run(context.Background(), Options{}); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	if target.created != 1 {
+		t.Errorf("created %d collections, want the interrupted one to be reused", target.created)
+	}
+	if target.promoted != p.Collection {
+		t.Errorf("promoted %q, want %q", target.promoted, p.Collection)
+	}
+
+	p, err = r.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if p.Indexed != 10 || p.Error != "" || p.CompletedAt == nil {
+		t.Errorf("final progress = %+v, want 10 indexed, complete, without an error", p)
+	}
+}
+
+func TestRunFreshDiscardsCheckpoint(t *testing.T) {
+	t.Parallel()
+	source := newFakeSource(5)
+	target := newFakeTarget()
+	r, _ := newTestRunner(t, source, target, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	target.onImport = cancel
+	_ = r.Run(ctx, Options{})
+	target.onImport = nil
+	p, err := r.Status(context.Background())
+	if err != nil || p == nil {
+		t.Fatalf("Status() = %+v, %v", p, err)
+	}
+	abandoned := p.Collection
+
+	if err := r.Run(context.Background(), Options{Fresh: true}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if target.created != 2 {
+		t.Errorf("created %d collections, want 2", target.created)
+	}
+	if exists, _ := target.Exists(context.Background(), abandoned); exists {
+		t.Errorf("discarded checkpoint collection %s still exists", abandoned)
+	}
+	if got := len(target.docs(target.promoted)); got != 5 {
+		t.Errorf("promoted collection has %d docs, want 5", got)
+	}
+}
+
+func TestRunCatchUpAppliesChanges(t *testing.T) {
+	t.Parallel()
+	source := newFakeSource(3)
+	target := newFakeTarget()
+	r, _ := newTestRunner(t, source, target, 10)
+
+	edited := source.entries[0]
+	edited.Content = "edited"
+	removed := source.entries[1]
+	removed.Removed = true
+	added := message.IndexEntry{ID: uuid.New(), ChannelID: uuid.New(), AuthorID: uuid.New(), Content: "late"}
+	source.changed = []message.IndexEntry{edited, removed, added}
+	slices.SortFunc(source.changed, func(a, b message.IndexEntry) int { return bytes.Compare(a.ID[:], b.ID[:]) })
+
+	if err := r.Run(context.Background(), Options{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	docs := target.docs(target.promoted)
+	if len(docs) != 3 {
+		t.Fatalf("promoted collection has %d docs, want 3", len(docs))
+	}
+	if docs[edited.ID.String()].Content != "edited" {
+		t.Errorf("edited doc content = %q, want %q", docs[edited.ID.String()].Content, "edited")
+	}
+	if _, ok := docs[removed.ID.String()]; ok {
+		t.Error("removed message is still indexed")
+	}
+	if _, ok := docs[added.ID.String()]; !ok {
+		t.Error("message created during the run is not indexed")
+	}
+}
+
+func TestStartRunsInBackground(t *testing.T) {
+	t.Parallel()
+	target := newFakeTarget()
+	r, _ := newTestRunner(t, newFakeSource(4), target, 2)
+
+	if err := r.Start(Options{}); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p, err := r.Status(context.Background())
+		if err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+		if p != nil && p.CompletedAt != nil {
+			if p.Running {
+				t.Error("Running = true for a completed run")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background reindex did not complete")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := len(target.docs(target.promoted)); got != 4 {
+		t.Errorf("promoted collection has %d docs, want 4", got)
+	}
+}
+
+func TestStatusBeforeFirstRun(t *testing.T) {
+	t.Parallel()
+	r, _ := newTestRunner(t, newFakeSource(0), newFakeTarget(), 10)
+
+	p, err := r.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if p != nil {
+		t.Errorf("Status() = %+v, want nil", p)
+	}
+}
@@ -1,4 +1,14 @@
-// Package search provides permission-scoped full-text message search. The Service layer queries a Typesense backend and
+// Package search provides permission-scoped full-text message search. The Service layer queries a Searcher backend and
 // filters results to channels the requesting user has permission to view. Results include pagination metadata and match
 // highlights.
+//
+// Queries may contain inline filters such as has:image or pinned:true (see Filters), which ParseQuery separates from
+// the full-text part before the backend is queried.
+//
+// Two backends are available, selected by SEARCH_BACKEND: TypesenseSearcher queries a Typesense collection kept in sync
+// by the search outbox, and PGSearcher queries the messages table directly through a full-text expression index, so
+// small deployments can offer search without running Typesense.
+//
+// Typeahead serves the member, channel and custom emoji lookups behind autocompletion. It always queries PostgreSQL
+// trigram indexes, ranking prefix matches first and recent activity second, and scopes results by permission.
 package search
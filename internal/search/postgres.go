@@ -0,0 +1,180 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
+)
+
+var _ Searcher = (*PGSearcher)(nil)
+
+// headlineOptions configures ts_headline to wrap matches in the same tags the Typesense backend uses, so clients render
+// highlights identically whichever backend is configured.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>"
+
+// searchVectorMatch matches a message's content against the parsed query. The expression, together with the deleted_at
+// and encrypted predicates that pgSearchFilter always adds, must match idx_messages_search_vector for the index to be
+// used.
+const searchVectorMatch = "to_tsvector('simple', m.content) @@ q.query"
+
+// PGSearcher performs full-text search against the messages table using its full-text expression index. It needs
+// no infrastructure beyond the primary database, at the cost of simpler matching than Typesense: words must match
+// exactly (there is no typo tolerance or prefix expansion). Results are ordered newest first, as with Typesense.
+type PGSearcher struct {
+	db *pgxpool.Pool
+}
+
+// NewPGSearcher creates a new PostgreSQL-backed searcher.
+func NewPGSearcher(db *pgxpool.Pool) *PGSearcher {
+	return &PGSearcher{db: db}
+}
+
+// Search executes a search query against the messages table. The query accepts web search syntax: quoted phrases, OR,
+// and a leading minus to exclude a word.
+func (s *PGSearcher) Search(ctx context.Context, params Params) (*Result, error) {
+	where, args, err := pgSearchFilter(params)
+	if err != nil {
+		return nil, err
+	}
+	offset := (params.Page - 1) * params.PerPage
+
+	// A filter-only search has nothing to highlight.
+	rows, err := s.db.Query(ctx,
+		`SELECT m.id, m.channel_id, m.author_id, m.content, m.created_at,
+		        CASE WHEN $1 = '' THEN NULL ELSE ts_headline('simple', m.content, q.query, '`+headlineOptions+`') END,
+		        count(*) OVER ()
+		 FROM messages m, websearch_to_tsquery('simple', $1) AS q(query)
+		 WHERE `+where+`
+		 ORDER BY m.created_at DESC, m.id DESC
+		 LIMIT `+strconv.Itoa(params.PerPage)+` OFFSET `+strconv.Itoa(offset),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query messages: %w", err)
+	}
+	defer rows.Close()
+
+	result := &Result{Hits: []Hit{}}
+	for rows.Next() {
+		var (
+			id, channelID, authorID uuid.UUID
+			content                 string
+			headline                *string
+			createdAt               time.Time
+		)
+		if err := rows.Scan(&id, &channelID, &authorID, &content, &createdAt, &headline, &result.Found); err != nil {
+			return nil, fmt.Errorf("scan search hit: %w", err)
+		}
+		hit := Hit{
+			Document: Document{
+				ID:        id.String(),
+				Content:   content,
+				AuthorID:  authorID.String(),
+				ChannelID: channelID.String(),
+				CreatedAt: createdAt.Unix(),
+			},
+		}
+		if headline != nil {
+			hit.Highlights = []Highlight{{Field: "content", Snippets: []string{*headline}}}
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate search hits: %w", err)
+	}
+
+	// The windowed count is only available alongside a row, so a page past the end needs a separate count.
+	if len(result.Hits) == 0 && offset > 0 {
+		err := s.db.QueryRow(ctx,
+			`SELECT count(*) FROM messages m, websearch_to_tsquery('simple', $1) AS q(query) WHERE `+where,
+			args...,
+		).Scan(&result.Found)
+		if err != nil {
+			return nil, fmt.Errorf("count search hits: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// hasClauses maps each has: value to the condition that selects matching messages.
+var hasClauses = map[string]string{
+	message.TagLink:   `m.content ~* 'https?://\S'`,
+	message.TagFile:   attachmentExists + ")",
+	message.TagImage:  attachmentExists + " AND a.content_type LIKE 'image/%')",
+	message.TagVideo:  attachmentExists + " AND a.content_type LIKE 'video/%')",
+	message.TagAudio:  attachmentExists + " AND a.content_type LIKE 'audio/%')",
+	message.TagThread: "EXISTS (SELECT 1 FROM threads t WHERE t.parent_message_id = m.id)",
+}
+
+// attachmentExists opens a condition on the message's attachments. Callers append further conditions on a and close
+// the parenthesis.
+const attachmentExists = "EXISTS (SELECT 1 FROM message_attachments a WHERE a.message_id = m.id"
+
+// pgSearchFilter builds the WHERE clause and its arguments for params. The first argument is always the query text,
+// bound to q.query by the caller. Channel and author IDs are parsed here so that malformed values are rejected before
+// they reach the database. The has: tags mirror message.IndexEntry.Tags, which builds the Typesense document.
+func pgSearchFilter(params Params) (string, []any, error) {
+	channelIDs := make([]uuid.UUID, len(params.ChannelIDs))
+	for i, raw := range params.ChannelIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return "", nil, ErrInvalidFilter
+		}
+		channelIDs[i] = id
+	}
+
+	clauses := []string{
+		"m.deleted_at IS NULL",
+		"NOT m.encrypted",
+		"m.channel_id = ANY($2)",
+	}
+	if params.Query != "" {
+		clauses = append([]string{searchVectorMatch}, clauses...)
+	}
+	args := []any{params.Query, channelIDs}
+
+	if params.AuthorID != "" {
+		authorID, err := uuid.Parse(params.AuthorID)
+		if err != nil {
+			return "", nil, ErrInvalidFilter
+		}
+		args = append(args, authorID)
+		clauses = append(clauses, "m.author_id = $"+strconv.Itoa(len(args)))
+	}
+	if params.Before > 0 {
+		args = append(args, time.Unix(params.Before, 0))
+		clauses = append(clauses, "m.created_at < $"+strconv.Itoa(len(args)))
+	}
+	if params.After > 0 {
+		args = append(args, time.Unix(params.After, 0))
+		clauses = append(clauses, "m.created_at > $"+strconv.Itoa(len(args)))
+	}
+	for _, has := range params.Has {
+		clauses = append(clauses, hasClauses[has])
+	}
+	if params.MentionID != "" {
+		args = append(args, "%<@"+params.MentionID+">%")
+		clauses = append(clauses, "m.content ILIKE $"+strconv.Itoa(len(args)))
+	}
+	if params.Pinned != nil {
+		args = append(args, *params.Pinned)
+		clauses = append(clauses, "m.pinned = $"+strconv.Itoa(len(args)))
+	}
+	if params.InThread {
+		clauses = append(clauses, "m.thread_id IS NOT NULL")
+	}
+	// The same word-prefix rule as the Typesense filter: every word of the value must start a word of a file name.
+	// Words hold only letters and digits, so they are safe inside the pattern.
+	for _, word := range message.FilenameWords(params.Filename) {
+		args = append(args, "(^|[^[:alnum:]])"+word)
+		clauses = append(clauses, attachmentExists+" AND a.filename ~* $"+strconv.Itoa(len(args))+")")
+	}
+	return strings.Join(clauses, " AND "), args, nil
+}
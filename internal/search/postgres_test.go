@@ -0,0 +1,143 @@
+package search
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPGSearchFilterDefaults(t *testing.T) {
+	t.Parallel()
+	channelID := uuid.New()
+
+	where, args, err := pgSearchFilter(Params{Query: "hello", ChannelIDs: []string{channelID.String()}})
+	if err != nil {
+		t.Fatalf("pgSearchFilter() error = %v", err)
+	}
+	want := "to_tsvector('simple', m.content) @@ q.query AND m.deleted_at IS NULL AND NOT m.encrypted AND m.channel_id = ANY($2)"
+	if where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if len(args) != 2 || args[0] != "hello" {
+		t.Fatalf("args = %v, want the query and channel IDs", args)
+	}
+	if ids, ok := args[1].([]uuid.UUID); !ok || len(ids) != 1 || ids[0] != channelID {
+		t.Errorf("args[1] = %v, want [%s]", args[1], channelID)
+	}
+}
+
+func TestPGSearchFilterAllFilters(t *testing.T) {
+	t.Parallel()
+	authorID := uuid.New()
+
+	where, args, err := pgSearchFilter(Params{
+		Query:      "hello",
+		ChannelIDs: []string{uuid.NewString()},
+		AuthorID:   authorID.String(),
+		Before:     2000,
+		After:      1000,
+	})
+	if err != nil {
+		t.Fatalf("pgSearchFilter() error = %v", err)
+	}
+	want := "to_tsvector('simple', m.content) @@ q.query AND m.deleted_at IS NULL AND NOT m.encrypted AND m.channel_id = ANY($2)" +
+		" AND m.author_id = $3 AND m.created_at < $4 AND m.created_at > $5"
+	if where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if len(args) != 5 {
+		t.Fatalf("len(args) = %d, want 5", len(args))
+	}
+	if args[2] != authorID {
+		t.Errorf("author arg = %v, want %s", args[2], authorID)
+	}
+	if before, ok := args[3].(time.Time); !ok || before.Unix() != 2000 {
+		t.Errorf("before arg = %v, want unix 2000", args[3])
+	}
+	if after, ok := args[4].(time.Time); !ok || after.Unix() != 1000 {
+		t.Errorf("after arg = %v, want unix 1000", args[4])
+	}
+}
+
+func TestPGSearchFilterInvalidIDs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		params Params
+	}{
+		{"channel", Params{Query: "x", ChannelIDs: []string{"not-a-uuid"}}},
+		{"author", Params{Query: "x", ChannelIDs: []string{uuid.NewString()}, AuthorID: "not-a-uuid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, _, err := pgSearchFilter(tt.params); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("pgSearchFilter() error = %v, want ErrInvalidFilter", err)
+			}
+		})
+	}
+}
+
+func TestPGSearchFilterQueryFilters(t *testing.T) {
+	t.Parallel()
+	mentioned := uuid.New()
+	pinned := true
+
+	where, args, err := pgSearchFilter(Params{
+		ChannelIDs: []string{uuid.NewString()},
+		Filters: Filters{
+			Has:       []string{"link", "image"},
+			MentionID: mentioned.String(),
+			Pinned:    &pinned,
+			InThread:  true,
+			Filename:  "Q3_report",
+		},
+	})
+	if err != nil {
+		t.Fatalf("pgSearchFilter() error = %v", err)
+	}
+	want := "m.deleted_at IS NULL AND NOT m.encrypted AND m.channel_id = ANY($2)" +
+		" AND " + hasClauses["link"] +
+		" AND " + hasClauses["image"] +
+		" AND m.content ILIKE $3 AND m.pinned = $4 AND m.thread_id IS NOT NULL" +
+		" AND " + attachmentExists + " AND a.filename ~* $5)" +
+		" AND " + attachmentExists + " AND a.filename ~* $6)"
+	if where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if args[2] != "%<@"+mentioned.String()+">%" {
+		t.Errorf("mention arg = %v", args[2])
+	}
+	if args[3] != true {
+		t.Errorf("pinned arg = %v, want true", args[3])
+	}
+	// Each filename word must start a word of the file name, matching the Typesense filter.
+	if args[4] != "(^|[^[:alnum:]])q3" || args[5] != "(^|[^[:alnum:]])report" {
+		t.Errorf("filename args = %v, %v, want word-prefix patterns", args[4], args[5])
+	}
+}
+
+func TestPGSearchFilterFilenameWordPrefix(t *testing.T) {
+	t.Parallel()
+
+	_, args, err := pgSearchFilter(Params{ChannelIDs: []string{uuid.NewString()}, Filters: Filters{Filename: "Rep"}})
+	if err != nil {
+		t.Fatalf("pgSearchFilter() error = %v", err)
+	}
+	// ~* is a case-insensitive match; Go's regexp agrees with PostgreSQL on this pattern for ASCII names.
+	pattern := regexp.MustCompile("(?i)" + args[2].(string))
+	for name, want := range map[string]bool{
+		"report.pdf":       true,
+		"Q3_Report-v2.pdf": true,
+		"final report.txt": true,
+		"myreport.pdf":     false,
+		"prep.txt":         false,
+	} {
+		if got := pattern.MatchString(name); got != want {
+			t.Errorf("filename:Rep matches %q = %v, want %v", name, got, want)
+		}
+	}
+}
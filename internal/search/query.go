@@ -0,0 +1,85 @@
+package search
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
+)
+
+// hasValues lists the accepted values of the has: filter.
+var hasValues = []string{
+	message.TagLink, message.TagFile, message.TagImage, message.TagVideo, message.TagAudio, message.TagThread,
+}
+
+// Filters are the structured filters a user can write inline in a search query:
+//
+//	has:link|file|image|video|audio|thread   message contains a link, an attachment of that kind, or started a thread
+//	mentions:<user-id>                       message mentions the user
+//	pinned:true|false                        message is (not) pinned
+//	in:thread                                message was posted inside a thread
+//	filename:<word>                          a word of an attachment's file name starts with the word
+//
+// Repeated has: filters must all match. The remaining words form the full-text query.
+type Filters struct {
+	Has       []string
+	MentionID string
+	Pinned    *bool
+	InThread  bool
+	Filename  string
+}
+
+// ParseQuery splits a raw search query into its full-text part and its filters. Words that look like filters but use an
+// unknown key (such as the scheme of a pasted URL) are kept as text. A known key with an invalid value is rejected with
+// ErrInvalidQueryFilter.
+func ParseQuery(raw string) (string, Filters, error) {
+	var f Filters
+	var words []string
+	for _, word := range strings.Fields(raw) {
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			words = append(words, word)
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "has":
+			value = strings.ToLower(value)
+			if !slices.Contains(hasValues, value) {
+				return "", Filters{}, ErrInvalidQueryFilter
+			}
+			if !slices.Contains(f.Has, value) {
+				f.Has = append(f.Has, value)
+			}
+		case "mentions":
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return "", Filters{}, ErrInvalidQueryFilter
+			}
+			f.MentionID = id.String()
+		case "pinned":
+			pinned, err := strconv.ParseBool(value)
+			if err != nil {
+				return "", Filters{}, ErrInvalidQueryFilter
+			}
+			f.Pinned = &pinned
+		case "in":
+			if !strings.EqualFold(value, "thread") {
+				return "", Filters{}, ErrInvalidQueryFilter
+			}
+			f.InThread = true
+		case "filename":
+			// A value without letters or digits has no words to match (see message.FilenameWords).
+			if len(message.FilenameWords(value)) == 0 {
+				return "", Filters{}, ErrInvalidQueryFilter
+			}
+			f.Filename = value
+		default:
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " "), f, nil
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Parallel()
+
+	userID := uuid.New()
+	text, f, err := ParseQuery("release notes has:LINK has:file has:link mentions:" + userID.String() +
+		" pinned:false in:thread filename:report https://example.com")
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+
+	if text != "release notes https://example.com" {
+		t.Errorf("text = %q, want %q", text, "release notes https://example.com")
+	}
+	if !slices.Equal(f.Has, []string{"link", "file"}) {
+		t.Errorf("Has = %v, want [link file]", f.Has)
+	}
+	if f.MentionID != userID.String() {
+		t.Errorf("MentionID = %q, want %q", f.MentionID, userID)
+	}
+	if f.Pinned == nil || *f.Pinned {
+		t.Errorf("Pinned = %v, want false", f.Pinned)
+	}
+	if !f.InThread {
+		t.Error("InThread = false, want true")
+	}
+	if f.Filename != "report" {
+		t.Errorf("Filename = %q, want %q", f.Filename, "report")
+	}
+}
+
+func TestParseQueryPlainText(t *testing.T) {
+	t.Parallel()
+
+	text, f, err := ParseQuery("  ratio 16:9  trailing: ")
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	if text != "ratio 16:9 trailing:" {
+		t.Errorf("text = %q, want %q", text, "ratio 16:9 trailing:")
+	}
+	if len(f.Has) != 0 || f.MentionID != "" || f.Pinned != nil || f.InThread || f.Filename != "" {
+		t.Errorf("filters = %+v, want none", f)
+	}
+}
+
+func TestParseQueryInvalidFilters(t *testing.T) {
+	t.Parallel()
+
+	for _, q := range []string{
+		"has:banana",
+		"mentions:someone",
+		"pinned:maybe",
+		"in:channel",
+		"filename:`",
+		"filename:._-",
+	} {
+		if _, _, err := ParseQuery(q); !errors.Is(err, ErrInvalidQueryFilter) {
+			t.Errorf("ParseQuery(%q) error = %v, want ErrInvalidQueryFilter", q, err)
+		}
+	}
+}
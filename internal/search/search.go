@@ -19,6 +19,7 @@ import (
 	"github.com/uncord-chat/uncord-protocol/permissions"
 
 	"github.com/uncord-chat/uncord-server/internal/channel"
+	"github.com/uncord-chat/uncord-server/internal/message"
 )
 
 // Sentinel errors for the search package.
@@ -26,6 +27,8 @@ var (
 	ErrSearchUnavailable = errors.New("search service is unavailable")
 	ErrEmptyQuery        = errors.New("search query must not be empty")
 	ErrInvalidFilter     = errors.New("filter parameter is not a valid UUID")
+
+	ErrInvalidQueryFilter = errors.New("search query contains an invalid filter")
 )
 
 // Pagination defaults and limits.
@@ -77,7 +80,7 @@ func ClampPagination(page, perPage int) (int, int) {
 	return page, perPage
 }
 
-// Params groups the parameters sent to the search backend.
+// Params groups the parameters sent to the search backend. Query is empty when the search consists of filters only.
 type Params struct {
 	Query      string
 	ChannelIDs []string
@@ -86,6 +89,7 @@ type Params struct {
 	After      int64
 	Page       int
 	PerPage    int
+	Filters
 }
 
 // Result holds the raw search backend response.
@@ -133,23 +137,16 @@ func NewTypesenseSearcher(baseURL, apiKey string, timeout time.Duration) *Typese
 
 // Search executes a search query against the Typesense messages collection.
 func (ts *TypesenseSearcher) Search(ctx context.Context, params Params) (*Result, error) {
-	filterParts := []string{
-		"channel_id:[" + strings.Join(params.ChannelIDs, ",") + "]",
-	}
-	if params.AuthorID != "" {
-		filterParts = append(filterParts, "author_id:="+params.AuthorID)
-	}
-	if params.Before > 0 {
-		filterParts = append(filterParts, "created_at:<"+strconv.FormatInt(params.Before, 10))
-	}
-	if params.After > 0 {
-		filterParts = append(filterParts, "created_at:>"+strconv.FormatInt(params.After, 10))
+	// A filter-only search matches every document that passes the filters.
+	q := params.Query
+	if q == "" {
+		q = "*"
 	}
 
 	qv := url.Values{}
-	qv.Set("q", params.Query)
+	qv.Set("q", q)
 	qv.Set("query_by", "content")
-	qv.Set("filter_by", strings.Join(filterParts, " && "))
+	qv.Set("filter_by", typesenseFilter(params))
 	qv.Set("sort_by", "created_at:desc")
 	qv.Set("page", strconv.Itoa(params.Page))
 	qv.Set("per_page", strconv.Itoa(params.PerPage))
@@ -185,6 +182,40 @@ func (ts *TypesenseSearcher) Search(ctx context.Context, params Params) (*Result
 	return &result, nil
 }
 
+// typesenseFilter builds the filter_by expression that restricts a search to the requested channels and filters.
+func typesenseFilter(params Params) string {
+	filterParts := []string{
+		"channel_id:[" + strings.Join(params.ChannelIDs, ",") + "]",
+	}
+	if params.AuthorID != "" {
+		filterParts = append(filterParts, "author_id:="+params.AuthorID)
+	}
+	if params.Before > 0 {
+		filterParts = append(filterParts, "created_at:<"+strconv.FormatInt(params.Before, 10))
+	}
+	if params.After > 0 {
+		filterParts = append(filterParts, "created_at:>"+strconv.FormatInt(params.After, 10))
+	}
+	for _, has := range params.Has {
+		filterParts = append(filterParts, "has:="+has)
+	}
+	if params.MentionID != "" {
+		filterParts = append(filterParts, "mentions:="+params.MentionID)
+	}
+	if params.Pinned != nil {
+		filterParts = append(filterParts, "pinned:="+strconv.FormatBool(*params.Pinned))
+	}
+	if params.InThread {
+		filterParts = append(filterParts, "in_thread:=true")
+	}
+	// Each word of a filename: value must start a word of one of the message's file names. Words hold only letters and
+	// digits, so they need no quoting.
+	for _, word := range message.FilenameWords(params.Filename) {
+		filterParts = append(filterParts, "filename_words:"+word+"*")
+	}
+	return strings.Join(filterParts, " && ")
+}
+
 // Service orchestrates permission-scoped message search.
 type Service struct {
 	channels ChannelLister
@@ -199,12 +230,15 @@ func NewService(channels ChannelLister, perms PermissionFilter, searcher Searche
 }
 
 // Search executes a permission-scoped message search. Only messages from channels the user has ViewChannels access to
-// are returned.
+// are returned. The query may contain inline filters (see Filters); a query made only of filters is allowed.
 func (s *Service) Search(ctx context.Context, userID uuid.UUID, query string, opts Options) (*models.SearchResponse, error) {
-	query = strings.TrimSpace(query)
-	if query == "" {
+	if strings.TrimSpace(query) == "" {
 		return nil, ErrEmptyQuery
 	}
+	query, filters, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
 
 	if opts.ChannelID != "" {
 		if _, err := uuid.Parse(opts.ChannelID); err != nil {
@@ -260,6 +294,7 @@ func (s *Service) Search(ctx context.Context, userID uuid.UUID, query string, op
 		After:      opts.After,
 		Page:       opts.Page,
 		PerPage:    opts.PerPage,
+		Filters:    filters,
 	})
 	if err != nil {
 		return nil, err
@@ -123,6 +123,48 @@ func TestService_SearchValidFiltersPassThrough(t *testing.T) {
 	}
 }
 
+func TestService_SearchQueryFiltersPassThrough(t *testing.T) {
+	t.Parallel()
+
+	mentioned := uuid.New()
+	searcher := &fakeSearcher{}
+	svc := NewService(
+		&fakeChannelLister{channels: []channel.Channel{{ID: uuid.New()}}},
+		&fakePermissionFilter{},
+		searcher,
+		zerolog.Nop(),
+	)
+
+	_, err := svc.Search(context.Background(), uuid.New(), "has:image mentions:"+mentioned.String()+" pinned:true",
+		Options{Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+
+	if searcher.params.Query != "" {
+		t.Errorf("searcher received Query = %q, want empty for a filter-only search", searcher.params.Query)
+	}
+	if len(searcher.params.Has) != 1 || searcher.params.Has[0] != "image" {
+		t.Errorf("searcher received Has = %v, want [image]", searcher.params.Has)
+	}
+	if searcher.params.MentionID != mentioned.String() {
+		t.Errorf("searcher received MentionID = %q, want %q", searcher.params.MentionID, mentioned)
+	}
+	if searcher.params.Pinned == nil || !*searcher.params.Pinned {
+		t.Errorf("searcher received Pinned = %v, want true", searcher.params.Pinned)
+	}
+}
+
+func TestService_SearchInvalidQueryFilter(t *testing.T) {
+	t.Parallel()
+
+	svc := NewService(&fakeChannelLister{}, &fakePermissionFilter{}, &fakeSearcher{}, zerolog.Nop())
+	_, err := svc.Search(context.Background(), uuid.New(), "hello pinned:maybe", Options{})
+	if !errors.Is(err, ErrInvalidQueryFilter) {
+		t.Errorf("Search() error = %v, want ErrInvalidQueryFilter", err)
+	}
+}
+
 func TestService_SearchNoPermittedChannels(t *testing.T) {
 	t.Parallel()
 
@@ -162,3 +204,16 @@ func TestService_SearchInjectionBlocked(t *testing.T) {
 		}
 	}
 }
+
+func TestTypesenseFilter(t *testing.T) {
+	t.Parallel()
+
+	got := typesenseFilter(Params{
+		ChannelIDs: []string{"a", "b"},
+		Filters:    Filters{Filename: "Q3_report"},
+	})
+	want := "channel_id:[a,b] && filename_words:q3* && filename_words:report*"
+	if got != want {
+		t.Errorf("typesenseFilter() = %q, want %q", got, want)
+	}
+}
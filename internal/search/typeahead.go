@@ -0,0 +1,174 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/uncord-chat/uncord-protocol/permissions"
+
+	"github.com/uncord-chat/uncord-server/internal/channel"
+	"github.com/uncord-chat/uncord-server/internal/emoji"
+	"github.com/uncord-chat/uncord-server/internal/member"
+)
+
+// Typeahead result limits.
+const (
+	DefaultTypeaheadLimit = 10
+	MaxTypeaheadLimit     = 25
+
+	// memberOverfetch is how many candidates are fetched per requested result when members are scoped to a channel,
+	// to leave room for the members who cannot see it.
+	memberOverfetch = 4
+)
+
+// MemberFinder finds members by name. Satisfied by *member.PGRepository.
+type MemberFinder interface {
+	Search(ctx context.Context, query string, limit int) ([]member.WithProfile, error)
+}
+
+// ChannelFinder finds channels by name among the given IDs. Satisfied by *channel.PGRepository.
+type ChannelFinder interface {
+	Search(ctx context.Context, query string, ids []uuid.UUID, limit int) ([]channel.Channel, error)
+}
+
+// EmojiFinder finds custom emoji by name. Satisfied by *emoji.PGRepository.
+type EmojiFinder interface {
+	Search(ctx context.Context, query string, limit int) ([]emoji.Emoji, error)
+}
+
+// UserPermissionFilter checks channel access for several users at once. Satisfied by *permission.Resolver.
+type UserPermissionFilter interface {
+	FilterUsersPermitted(ctx context.Context, userIDs []uuid.UUID, channelID uuid.UUID,
+		perm permissions.Permission) ([]bool, error)
+}
+
+var (
+	_ MemberFinder  = (*member.PGRepository)(nil)
+	_ ChannelFinder = (*channel.PGRepository)(nil)
+	_ EmojiFinder   = (*emoji.PGRepository)(nil)
+)
+
+// Typeahead serves the name lookups behind autocompletion: members for mentions and the member list, channels, and
+// custom emoji. Lookups run against PostgreSQL trigram indexes, so they work with either search backend.
+type Typeahead struct {
+	members   MemberFinder
+	channels  ChannelFinder
+	emoji     EmojiFinder
+	lister    ChannelLister
+	perms     PermissionFilter
+	userPerms UserPermissionFilter
+}
+
+// NewTypeahead creates a new typeahead service.
+func NewTypeahead(members MemberFinder, channels ChannelFinder, emoji EmojiFinder, lister ChannelLister,
+	perms PermissionFilter, userPerms UserPermissionFilter) *Typeahead {
+	return &Typeahead{
+		members:   members,
+		channels:  channels,
+		emoji:     emoji,
+		lister:    lister,
+		perms:     perms,
+		userPerms: userPerms,
+	}
+}
+
+// ClampTypeaheadLimit normalises a requested result count to [1, MaxTypeaheadLimit], defaulting to
+// DefaultTypeaheadLimit when the input is zero or negative.
+func ClampTypeaheadLimit(limit int) int {
+	if limit < 1 {
+		return DefaultTypeaheadLimit
+	}
+	return min(limit, MaxTypeaheadLimit)
+}
+
+// Members returns members matching query. When channelID is set, results are limited to members who can view that
+// channel, and nothing is returned if the caller cannot view it either.
+func (t *Typeahead) Members(ctx context.Context, userID uuid.UUID, query, channelID string,
+	limit int) ([]member.WithProfile, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptyQuery
+	}
+	if channelID == "" {
+		return t.members.Search(ctx, query, limit)
+	}
+
+	chID, err := uuid.Parse(channelID)
+	if err != nil {
+		return nil, ErrInvalidFilter
+	}
+	visible, err := t.perms.FilterPermitted(ctx, userID, []uuid.UUID{chID}, permissions.ViewChannels)
+	if err != nil {
+		return nil, fmt.Errorf("check channel permission: %w", err)
+	}
+	if !visible[0] {
+		return []member.WithProfile{}, nil
+	}
+
+	candidates, err := t.members.Search(ctx, query, limit*memberOverfetch)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uuid.UUID, len(candidates))
+	for i := range candidates {
+		userIDs[i] = candidates[i].UserID
+	}
+	permitted, err := t.userPerms.FilterUsersPermitted(ctx, userIDs, chID, permissions.ViewChannels)
+	if err != nil {
+		return nil, fmt.Errorf("filter members by channel permission: %w", err)
+	}
+
+	result := make([]member.WithProfile, 0, limit)
+	for i := range candidates {
+		if permitted[i] {
+			result = append(result, candidates[i])
+			if len(result) == limit {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// Channels returns channels matching query among those the caller can view.
+func (t *Typeahead) Channels(ctx context.Context, userID uuid.UUID, query string, limit int) ([]channel.Channel, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	all, err := t.lister.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list channels: %w", err)
+	}
+	ids := make([]uuid.UUID, len(all))
+	for i := range all {
+		ids[i] = all[i].ID
+	}
+	permitted, err := t.perms.FilterPermitted(ctx, userID, ids, permissions.ViewChannels)
+	if err != nil {
+		return nil, fmt.Errorf("filter permitted channels: %w", err)
+	}
+
+	var visible []uuid.UUID
+	for i, ok := range permitted {
+		if ok {
+			visible = append(visible, ids[i])
+		}
+	}
+	if len(visible) == 0 {
+		return []channel.Channel{}, nil
+	}
+	return t.channels.Search(ctx, query, visible, limit)
+}
+
+// Emoji returns custom emoji matching query. Custom emoji are visible to every active member.
+func (t *Typeahead) Emoji(ctx context.Context, query string, limit int) ([]emoji.Emoji, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptyQuery
+	}
+	return t.emoji.Search(ctx, query, limit)
+}
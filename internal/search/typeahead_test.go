@@ -0,0 +1,248 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/uncord-chat/uncord-protocol/permissions"
+
+	"github.com/uncord-chat/uncord-server/internal/channel"
+	"github.com/uncord-chat/uncord-server/internal/emoji"
+	"github.com/uncord-chat/uncord-server/internal/member"
+)
+
+type fakeMemberFinder struct {
+	members []member.WithProfile
+	query   string
+	limit   int
+}
+
+func (f *fakeMemberFinder) Search(_ context.Context, query string, limit int) ([]member.WithProfile, error) {
+	f.query, f.limit = query, limit
+	return f.members[:min(limit, len(f.members))], nil
+}
+
+type fakeChannelFinder struct {
+	ids []uuid.UUID
+}
+
+func (f *fakeChannelFinder) Search(_ context.Context, _ string, ids []uuid.UUID, _ int) ([]channel.Channel, error) {
+	f.ids = ids
+	out := make([]channel.Channel, len(ids))
+	for i, id := range ids {
+		out[i] = channel.Channel{ID: id}
+	}
+	return out, nil
+}
+
+type fakeEmojiFinder struct {
+	query string
+}
+
+func (f *fakeEmojiFinder) Search(_ context.Context, query string, _ int) ([]emoji.Emoji, error) {
+	f.query = query
+	return []emoji.Emoji{{Name: query}}, nil
+}
+
+// fakeUserPermissionFilter permits every user except those in denied.
+type fakeUserPermissionFilter struct {
+	denied map[uuid.UUID]bool
+	err    error
+}
+
+func (f *fakeUserPermissionFilter) FilterUsersPermitted(_ context.Context, userIDs []uuid.UUID, _ uuid.UUID,
+	_ permissions.Permission) ([]bool, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := make([]bool, len(userIDs))
+	for i, id := range userIDs {
+		out[i] = !f.denied[id]
+	}
+	return out, nil
+}
+
+func membersWithIDs(n int) []member.WithProfile {
+	out := make([]member.WithProfile, n)
+	for i := range out {
+		out[i] = member.WithProfile{UserID: uuid.New()}
+	}
+	return out
+}
+
+func TestClampTypeaheadLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in, want int
+	}{
+		{0, DefaultTypeaheadLimit},
+		{-3, DefaultTypeaheadLimit},
+		{5, 5},
+		{MaxTypeaheadLimit + 1, MaxTypeaheadLimit},
+	}
+	for _, tt := range tests {
+		if got := ClampTypeaheadLimit(tt.in); got != tt.want {
+			t.Errorf("ClampTypeaheadLimit(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTypeahead_EmptyQuery(t *testing.T) {
+	t.Parallel()
+	ta := NewTypeahead(&fakeMemberFinder{}, &fakeChannelFinder{}, &fakeEmojiFinder{}, &fakeChannelLister{},
+		&fakePermissionFilter{}, &fakeUserPermissionFilter{})
+	ctx := context.Background()
+
+	if _, err := ta.Members(ctx, uuid.New(), "  ", "", 10); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("Members() error = %v, want ErrEmptyQuery", err)
+	}
+	if _, err := ta.Channels(ctx, uuid.New(), "", 10); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("Channels() error = %v, want ErrEmptyQuery", err)
+	}
+	if _, err := ta.Emoji(ctx, "\t", 10); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("Emoji() error = %v, want ErrEmptyQuery", err)
+	}
+}
+
+func TestTypeahead_MembersUnscoped(t *testing.T) {
+	t.Parallel()
+	finder := &fakeMemberFinder{members: membersWithIDs(3)}
+	ta := NewTypeahead(finder, &fakeChannelFinder{}, &fakeEmojiFinder{}, &fakeChannelLister{},
+		&fakePermissionFilter{}, &fakeUserPermissionFilter{err: errors.New("must not be called")})
+
+	got, err := ta.Members(context.Background(), uuid.New(), " ali ", "", 10)
+	if err != nil {
+		t.Fatalf("Members() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("len = %d, want 3", len(got))
+	}
+	if finder.query != "ali" || finder.limit != 10 {
+		t.Errorf("finder called with (%q, %d), want (\"ali\", 10)", finder.query, finder.limit)
+	}
+}
+
+func TestTypeahead_MembersInvalidChannel(t *testing.T) {
+	t.Parallel()
+	ta := NewTypeahead(&fakeMemberFinder{}, &fakeChannelFinder{}, &fakeEmojiFinder{}, &fakeChannelLister{},
+		&fakePermissionFilter{}, &fakeUserPermissionFilter{})
+
+	if _, err := ta.Members(context.Background(), uuid.New(), "ali", "nope", 10); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Members() error = %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestTypeahead_MembersChannelHiddenFromCaller(t *testing.T) {
+	t.Parallel()
+	finder := &fakeMemberFinder{members: membersWithIDs(2)}
+	ta := NewTypeahead(finder, &fakeChannelFinder{}, &fakeEmojiFinder{}, &fakeChannelLister{},
+		&fakePermissionFilter{results: []bool{false}}, &fakeUserPermissionFilter{})
+
+	got, err := ta.Members(context.Background(), uuid.New(), "ali", uuid.NewString(), 10)
+	if err != nil {
+		t.Fatalf("Members() error = %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("Members() = %v, want an empty non-nil slice", got)
+	}
+	if finder.limit != 0 {
+		t.Error("members were searched for a channel the caller cannot view")
+	}
+}
+
+func TestTypeahead_MembersScopedToChannel(t *testing.T) {
+	t.Parallel()
+	candidates := membersWithIDs(6)
+	finder := &fakeMemberFinder{members: candidates}
+	userPerms := &fakeUserPermissionFilter{denied: map[uuid.UUID]bool{
+		candidates[0].UserID: true,
+		candidates[2].UserID: true,
+	}}
+	ta := NewTypeahead(finder, &fakeChannelFinder{}, &fakeEmojiFinder{}, &fakeChannelLister{},
+		&fakePermissionFilter{}, userPerms)
+
+	got, err := ta.Members(context.Background(), uuid.New(), "ali", uuid.NewString(), 3)
+	if err != nil {
+		t.Fatalf("Members() error = %v", err)
+	}
+	if finder.limit != 3*memberOverfetch {
+		t.Errorf("finder limit = %d, want %d", finder.limit, 3*memberOverfetch)
+	}
+	want := []uuid.UUID{candidates[1].UserID, candidates[3].UserID, candidates[4].UserID}
+	gotIDs := make([]uuid.UUID, len(got))
+	for i := range got {
+		gotIDs[i] = got[i].UserID
+	}
+	if !slices.Equal(gotIDs, want) {
+		t.Errorf("Members() = %v, want %v", gotIDs, want)
+	}
+}
+
+func TestTypeahead_ChannelsFilteredByPermission(t *testing.T) {
+	t.Parallel()
+	channels := []channel.Channel{{ID: uuid.New()}, {ID: uuid.New()}, {ID: uuid.New()}}
+	finder := &fakeChannelFinder{}
+	ta := NewTypeahead(&fakeMemberFinder{}, finder, &fakeEmojiFinder{}, &fakeChannelLister{channels: channels},
+		&fakePermissionFilter{results: []bool{true, false, true}}, &fakeUserPermissionFilter{})
+
+	got, err := ta.Channels(context.Background(), uuid.New(), "gen", 10)
+	if err != nil {
+		t.Fatalf("Channels() error = %v", err)
+	}
+	want := []uuid.UUID{channels[0].ID, channels[2].ID}
+	if !slices.Equal(finder.ids, want) {
+		t.Errorf("searched IDs = %v, want %v", finder.ids, want)
+	}
+	if len(got) != 2 {
+		t.Errorf("len = %d, want 2", len(got))
+	}
+}
+
+func TestTypeahead_ChannelsNoneVisible(t *testing.T) {
+	t.Parallel()
+	finder := &fakeChannelFinder{}
+	ta := NewTypeahead(&fakeMemberFinder{}, finder, &fakeEmojiFinder{},
+		&fakeChannelLister{channels: []channel.Channel{{ID: uuid.New()}}},
+		&fakePermissionFilter{results: []bool{false}}, &fakeUserPermissionFilter{})
+
+	got, err := ta.Channels(context.Background(), uuid.New(), "gen", 10)
+	if err != nil {
+		t.Fatalf("Channels() error = %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("Channels() = %v, want an empty non-nil slice", got)
+	}
+	if finder.ids != nil {
+		t.Error("channels were searched although none are visible")
+	}
+}
+
+func TestTypeahead_ChannelsPermissionError(t *testing.T) {
+	t.Parallel()
+	permErr := errors.New("cache unavailable")
+	ta := NewTypeahead(&fakeMemberFinder{}, &fakeChannelFinder{}, &fakeEmojiFinder{},
+		&fakeChannelLister{channels: []channel.Channel{{ID: uuid.New()}}},
+		&fakePermissionFilter{err: permErr}, &fakeUserPermissionFilter{})
+
+	if _, err := ta.Channels(context.Background(), uuid.New(), "gen", 10); !errors.Is(err, permErr) {
+		t.Errorf("Channels() error = %v, want the permission error", err)
+	}
+}
+
+func TestTypeahead_Emoji(t *testing.T) {
+	t.Parallel()
+	finder := &fakeEmojiFinder{}
+	ta := NewTypeahead(&fakeMemberFinder{}, &fakeChannelFinder{}, finder, &fakeChannelLister{},
+		&fakePermissionFilter{}, &fakeUserPermissionFilter{})
+
+	got, err := ta.Emoji(context.Background(), " party ", 10)
+	if err != nil {
+		t.Fatalf("Emoji() error = %v", err)
+	}
+	if finder.query != "party" || len(got) != 1 {
+		t.Errorf("Emoji() = %v with query %q, want one result for \"party\"", got, finder.query)
+	}
+}
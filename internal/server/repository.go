@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -122,6 +123,21 @@ func (r *PGRepository) ClearBannerKey(ctx context.Context) (*Config, error) {
 	return cfg, nil
 }
 
+// SetOwner makes the given user the server owner and returns the updated config. The caller is responsible for
+// checking that the user is an active member.
+func (r *PGRepository) SetOwner(ctx context.Context, userID uuid.UUID) (*Config, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE server_config SET owner_id = $1 RETURNING `+selectColumns, userID)
+	cfg, err := scanConfig(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("set owner: %w", err)
+	}
+	return cfg, nil
+}
+
 // scanConfig scans a single row into a Config struct.
 func scanConfig(row pgx.Row) (*Config, error) {
 	var cfg Config
@@ -86,6 +86,7 @@ type Repository interface {
 	ClearIconKey(ctx context.Context) (*Config, error)
 	SetBannerKey(ctx context.Context, key string) (*Config, error)
 	ClearBannerKey(ctx context.Context) (*Config, error)
+	SetOwner(ctx context.Context, userID uuid.UUID) (*Config, error)
 }
 
 var _ Repository = (*PGRepository)(nil)
@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/uncord-chat/uncord-server/internal/message"
 	"github.com/uncord-chat/uncord-server/internal/postgres"
 )
 
@@ -16,7 +17,8 @@ const selectColumns = "id, channel_id, parent_message_id, name, archived, locked
 
 // PGRepository implements Repository using PostgreSQL.
 type PGRepository struct {
-	db *pgxpool.Pool
+	db             *pgxpool.Pool
+	noSearchOutbox bool
 }
 
 // NewPGRepository creates a new PostgreSQL-backed thread repository.
@@ -24,8 +26,15 @@ func NewPGRepository(db *pgxpool.Pool) *PGRepository {
 	return &PGRepository{db: db}
 }
 
+// DisableSearchOutbox stops thread creation from enqueueing a search index update for the parent message. See
+// message.PGRepository.DisableSearchOutbox.
+func (r *PGRepository) DisableSearchOutbox() {
+	r.noSearchOutbox = true
+}
+
 // Create inserts a new thread inside a transaction that validates the parent message exists, belongs to the given
-// channel, and is not deleted. A unique constraint on parent_message_id ensures one thread per message.
+// channel, and is not deleted. A unique constraint on parent_message_id ensures one thread per message. The parent
+// message's search document records that it started a thread, so the message is queued for reindexing.
 func (r *PGRepository) Create(ctx context.Context, params CreateParams) (*Thread, error) {
 	var t Thread
 	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
@@ -56,7 +65,15 @@ func (r *PGRepository) Create(ctx context.Context, params CreateParams) (*Thread
 			}
 			return fmt.Errorf("insert thread: %w", scanErr)
 		}
-		return nil
+
+		parent := []uuid.UUID{params.ParentMessageID}
+		if err := message.Touch(ctx, tx, parent); err != nil {
+			return err
+		}
+		if r.noSearchOutbox {
+			return nil
+		}
+		return message.EnqueueIndex(ctx, tx, parent)
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,10 @@
+// Package tracing configures OpenTelemetry tracing for the server. Setup installs a tracer provider that exports spans
+// over OTLP/HTTP with parent-based ratio sampling; until it is called, every span is a no-op, so instrumented code pays
+// almost nothing when tracing is disabled.
+//
+// Middleware starts a server span for every HTTP request. W3C trace context sent by the client is continued only when
+// the deployment trusts it; otherwise the span starts a new, ratio-sampled trace linked to the client's. Inject and
+// Extract carry trace context through message payloads such as the gateway pub/sub envelope and media job stream,
+// and LogHook adds trace_id and span_id to zerolog events logged with a traced context. The PostgreSQL and Valkey
+// clients record their own spans through hooks installed by postgres.Connect and valkey.Connect.
+package tracing
@@ -0,0 +1,16 @@
+package tracing
+
+import "github.com/rs/zerolog"
+
+// LogHook is a zerolog hook that adds trace_id and span_id fields to events whose context (set with Event.Ctx) carries
+// a span, so that log lines can be correlated with the trace that produced them.
+type LogHook struct{}
+
+// Run implements zerolog.Hook.
+func (LogHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	sc := SpanContext(e.GetCtx())
+	if !sc.IsValid() {
+		return
+	}
+	e.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/uncord-chat/uncord-server/internal/httputil"
+)
+
+// unmatchedRoute names spans for requests that matched no route, so that scanners probing random paths do not create
+// an unbounded number of span names.
+const unmatchedRoute = "unmatched"
+
+// Middleware returns Fiber middleware that records a server span for every request, named after its method and route
+// pattern (e.g. "GET /api/v1/channels/:channelID"). The span is stored in the request context, so handlers and the
+// repositories they call attach their own spans to it. WebSocket upgrades are skipped because the request lasts as long
+// as the connection; the gateway traces individual dispatches instead.
+//
+// Incoming trace context is only continued when trustParent is set, meaning every request arrives through a proxy that
+// strips or sets the traceparent header. Otherwise any client could mark its requests as sampled and have every one of
+// them recorded and exported regardless of the sample ratio, so the span starts a new trace, sampled by ratio, and
+// links to the remote context instead of using it as the parent.
+func Middleware(trustParent bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") {
+			return c.Next()
+		}
+
+		ctx := c.Context()
+		opts := []trace.SpanStartOption{
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+			),
+		}
+		remote := otel.GetTextMapPropagator().Extract(ctx, headerCarrier{c})
+		if trustParent {
+			ctx = remote
+		} else if sc := trace.SpanContextFromContext(remote); sc.IsValid() {
+			opts = append(opts, trace.WithNewRoot(), trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
+		ctx, span := Tracer().Start(ctx, c.Method(), opts...)
+		defer span.End()
+		c.SetContext(ctx)
+
+		err := c.Next()
+
+		route := unmatchedRoute
+		if c.Matched() {
+			route = c.FullPath()
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetName(c.Method() + " " + route)
+
+		status := httputil.ResponseStatus(c, err)
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+			if err != nil {
+				span.RecordError(err)
+			}
+		}
+		return err
+	}
+}
+
+// headerCarrier adapts Fiber request headers to the propagation.TextMapCarrier interface.
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string { return h.c.Get(key) }
+func (h headerCarrier) Set(key, value string) { h.c.Request().Header.Set(key, value) }
+
+func (h headerCarrier) Keys() []string {
+	headers := h.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	return keys
+}
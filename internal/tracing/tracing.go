@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the server's own instrumentation in exported spans.
+const instrumentationName = "github.com/uncord-chat/uncord-server"
+
+// Options configures the tracer provider installed by Setup.
+type Options struct {
+	Endpoint       string  // OTLP/HTTP collector URL, e.g. "http://otel-collector:4318".
+	SampleRatio    float64 // Fraction of new traces to record, between 0 and 1. Child spans follow their parent.
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+}
+
+// Setup installs a global tracer provider that batches spans to the OTLP endpoint, and the W3C trace context
+// propagator. The returned function flushes buffered spans and stops the exporter; call it during shutdown.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("create otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(opts.ServiceVersion),
+		semconv.DeploymentEnvironmentName(opts.Environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used by the server's instrumentation. It follows the global provider, so spans started
+// before Setup is called (or when it never is) are no-ops.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// requestContext is implemented by fiber.Ctx. Handlers pass the Fiber context straight to repositories, but its Value
+// method does not see the values stored with SetContext, where Middleware keeps the request span.
+type requestContext interface {
+	Context() context.Context
+}
+
+// SpanContext returns the span context carried by ctx, looking through a Fiber request context to the span stored by
+// Middleware.
+func SpanContext(ctx context.Context) trace.SpanContext {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc
+	}
+	if rc, ok := ctx.(requestContext); ok {
+		return trace.SpanContextFromContext(rc.Context())
+	}
+	return trace.SpanContext{}
+}
+
+// StartChild starts a span as a child of the span carried by ctx and returns ctx with the new span attached. When ctx
+// is not part of a trace it returns ctx unchanged and a no-op span, so that work outside a request, such as a polling
+// loop, does not start a trace of its own.
+func StartChild(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	sc := SpanContext(ctx)
+	if !sc.IsValid() {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	_, span := Tracer().Start(trace.ContextWithSpanContext(context.Background(), sc), name, opts...)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// Detach returns a background context that carries only the span of ctx. Work that outlives a request, such as a
+// goroutine started by a handler, uses it to stay in the request's trace without inheriting its cancellation or values.
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), SpanContext(ctx))
+}
+
+// Inject returns the trace context of ctx as a string map suitable for embedding in a message payload, or nil when ctx
+// carries no span.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(Detach(ctx), carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns ctx with the remote trace context from a carrier produced by Inject. A nil or empty carrier returns
+// ctx unchanged.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// RecordError marks span as failed with err. It is a no-op when err is nil.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,256 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+// recordSpans installs a tracer provider that records every span in memory. Tests using it must not run in parallel
+// because the provider is global.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareRecordsRouteSpan(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var handlerSpan trace.SpanContext
+	app := fiber.New()
+	app.Use(Middleware(true))
+	app.Get("/channels/:channelID", func(c fiber.Ctx) error {
+		handlerSpan = SpanContext(c)
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Get("/boom", func(fiber.Ctx) error { return fiber.ErrServiceUnavailable })
+
+	for _, path := range []string{"/channels/123", "/boom", "/nope"} {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+		req.Header.Set("traceparent", testTraceParent)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test(%s) error = %v", path, err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+
+	tests := []struct {
+		name       string
+		status     int64
+		wantStatus codes.Code
+	}{
+		{"GET /channels/:channelID", fiber.StatusNoContent, codes.Unset},
+		{"GET /boom", fiber.StatusServiceUnavailable, codes.Error},
+		{"GET unmatched", fiber.StatusNotFound, codes.Unset},
+	}
+	for i, tt := range tests {
+		span := spans[i]
+		if span.Name() != tt.name {
+			t.Errorf("span %d name = %q, want %q", i, span.Name(), tt.name)
+		}
+		if got := attr(span, "http.response.status_code").AsInt64(); got != tt.status {
+			t.Errorf("span %q status = %d, want %d", tt.name, got, tt.status)
+		}
+		if span.Status().Code != tt.wantStatus {
+			t.Errorf("span %q status code = %v, want %v", tt.name, span.Status().Code, tt.wantStatus)
+		}
+		if span.Parent().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+			t.Errorf("span %q did not continue the incoming trace", tt.name)
+		}
+	}
+
+	if handlerSpan.SpanID() != spans[0].SpanContext().SpanID() {
+		t.Error("SpanContext(c) in the handler does not return the request span")
+	}
+}
+
+func TestMiddlewareLinksUntrustedParent(t *testing.T) {
+	recorder := recordSpans(t)
+
+	app := fiber.New()
+	app.Use(Middleware(false))
+	app.Get("/", func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", testTraceParent)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Parent().IsValid() {
+		t.Errorf("span parent = %v, want a new root", span.Parent())
+	}
+	if span.SpanContext().TraceID().String() == "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("span continued the untrusted incoming trace")
+	}
+	links := span.Links()
+	if len(links) != 1 || links[0].SpanContext.TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("span links = %+v, want a link to the incoming trace", links)
+	}
+}
+
+func TestMiddlewareSkipsWebSocketUpgrade(t *testing.T) {
+	recorder := recordSpans(t)
+
+	app := fiber.New()
+	app.Use(Middleware(false))
+	app.Get("/ws", func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/ws", nil)
+	req.Header.Set("Upgrade", "websocket")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if n := len(recorder.Ended()); n != 0 {
+		t.Errorf("recorded %d spans for a WebSocket upgrade, want 0", n)
+	}
+}
+
+func TestInjectExtractRoundTrip(t *testing.T) {
+	recordSpans(t)
+
+	if carrier := Inject(context.Background()); carrier != nil {
+		t.Errorf("Inject() without a span = %v, want nil", carrier)
+	}
+
+	ctx, span := Tracer().Start(context.Background(), "publish")
+	defer span.End()
+
+	carrier := Inject(ctx)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("Inject() = %v, want a traceparent", carrier)
+	}
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	if got.TraceID() != span.SpanContext().TraceID() || got.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("Extract() = %v/%v, want %v/%v", got.TraceID(), got.SpanID(),
+			span.SpanContext().TraceID(), span.SpanContext().SpanID())
+	}
+	if !got.IsRemote() {
+		t.Error("extracted span context is not marked remote")
+	}
+}
+
+func TestStartChild(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, span := StartChild(context.Background(), "orphan")
+	span.End()
+	if ctx != context.Background() {
+		t.Error("StartChild() outside a trace changed the context")
+	}
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("StartChild() outside a trace recorded %d spans, want 0", n)
+	}
+
+	parentCtx, parent := Tracer().Start(context.Background(), "parent")
+	_, child := StartChild(parentCtx, "child")
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "child" {
+		t.Fatalf("recorded %d spans, want child then parent", len(spans))
+	}
+	if spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("child span is not parented to the span in the context")
+	}
+}
+
+func TestDetach(t *testing.T) {
+	recordSpans(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, span := Tracer().Start(ctx, "request")
+	defer span.End()
+	cancel()
+
+	detached := Detach(ctx)
+	if detached.Err() != nil {
+		t.Error("Detach() inherited the cancellation of the request")
+	}
+	if SpanContext(detached).SpanID() != span.SpanContext().SpanID() {
+		t.Error("Detach() dropped the request span")
+	}
+}
+
+func TestLogHook(t *testing.T) {
+	recordSpans(t)
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(LogHook{})
+
+	ctx, span := Tracer().Start(context.Background(), "request")
+	defer span.End()
+
+	logger.Info().Ctx(ctx).Msg("traced")
+	logger.Info().Ctx(context.Background()).Msg("untraced")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want 2", len(lines))
+	}
+
+	var traced, untraced map[string]any
+	if err := json.Unmarshal(lines[0], &traced); err != nil {
+		t.Fatalf("unmarshal traced line: %v", err)
+	}
+	if err := json.Unmarshal(lines[1], &untraced); err != nil {
+		t.Fatalf("unmarshal untraced line: %v", err)
+	}
+
+	if traced["trace_id"] != span.SpanContext().TraceID().String() {
+		t.Errorf("trace_id = %v, want %s", traced["trace_id"], span.SpanContext().TraceID())
+	}
+	if traced["span_id"] != span.SpanContext().SpanID().String() {
+		t.Errorf("span_id = %v, want %s", traced["span_id"], span.SpanContext().SpanID())
+	}
+	if _, ok := untraced["trace_id"]; ok {
+		t.Error("untraced line has a trace_id")
+	}
+}
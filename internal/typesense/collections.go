@@ -0,0 +1,198 @@
+package typesense
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// importResponseLineLimit bounds the size of each per-document line in a bulk import response.
+const importResponseLineLimit = 4 << 10 // 4 KiB
+
+// Collections manages the versioned physical collections behind the messages alias. It is used by reindexing to build
+// a replacement collection in the background and promote it once it is fully populated.
+type Collections struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewCollections creates a client for managing messages collections.
+func NewCollections(baseURL, apiKey string, timeout time.Duration) *Collections {
+	return &Collections{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Create creates a new, empty messages collection with the current schema and returns its name. The collection does
+// not receive searches until it is promoted.
+func (c *Collections) Create(ctx context.Context) (string, error) {
+	name := versionedCollectionName(time.Now())
+	if err := createCollection(ctx, c.client, c.baseURL, c.apiKey, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Exists reports whether the named collection exists.
+func (c *Collections) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := getCollection(ctx, c.client, c.baseURL, c.apiKey, name)
+	if errors.Is(err, errCollectionNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Import upserts docs into the named collection with a single bulk import request. Typesense reports success per
+// document, so an error is returned if any document was rejected.
+func (c *Collections) Import(ctx context.Context, collection string, docs []MessageDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for i := range docs {
+		if err := enc.Encode(&docs[i]); err != nil {
+			return fmt.Errorf("marshal message doc: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.baseURL+"/collections/"+collection+"/documents/import?action=upsert", &body)
+	if err != nil {
+		return fmt.Errorf("build import request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-TYPESENSE-API-KEY", c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("import request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		return fmt.Errorf("typesense returned status %d on import: %s", resp.StatusCode, detail)
+	}
+
+	return checkImportResults(io.LimitReader(resp.Body, int64(len(docs))*importResponseLineLimit))
+}
+
+// checkImportResults reads the JSON Lines response of a bulk import and reports the first rejected document.
+func checkImportResults(r io.Reader) error {
+	var failed int
+	var firstErr string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, importResponseLineLimit), importResponseLineLimit)
+	for scanner.Scan() {
+		var line struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("decode import result: %w", err)
+		}
+		if !line.Success {
+			failed++
+			if firstErr == "" {
+				firstErr = line.Error
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read import results: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d documents failed to import: %s", failed, firstErr)
+	}
+	return nil
+}
+
+// Delete removes the documents with the given IDs from the named collection. Missing documents are ignored.
+func (c *Collections) Delete(ctx context.Context, collection string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	q := url.Values{}
+	q.Set("filter_by", "id:["+strings.Join(ids, ",")+"]")
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete,
+		c.baseURL+"/collections/"+collection+"/documents?"+q.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("build delete request: %w", err)
+	}
+	req.Header.Set("X-TYPESENSE-API-KEY", c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("delete request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		return fmt.Errorf("typesense returned status %d on delete: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
+
+// Promote points the messages alias at collection and drops the collection it previously served. A legacy collection
+// named "messages" (created before aliases were used) has to be dropped before the alias can take its name, which
+// leaves search briefly unavailable during that one-time migration.
+func (c *Collections) Promote(ctx context.Context, collection string) error {
+	previous, err := getAlias(ctx, c.client, c.baseURL, c.apiKey, messagesCollection)
+	switch {
+	case errors.Is(err, errAliasNotFound):
+		exists, existsErr := c.Exists(ctx, messagesCollection)
+		if existsErr != nil {
+			return fmt.Errorf("check legacy collection: %w", existsErr)
+		}
+		if exists {
+			if err := deleteCollection(ctx, c.client, c.baseURL, c.apiKey, messagesCollection); err != nil {
+				return fmt.Errorf("drop legacy collection: %w", err)
+			}
+		}
+	case err != nil:
+		return fmt.Errorf("fetch messages alias: %w", err)
+	}
+
+	if err := upsertAlias(ctx, c.client, c.baseURL, c.apiKey, messagesCollection, collection); err != nil {
+		return fmt.Errorf("swap messages alias: %w", err)
+	}
+
+	if previous != "" && previous != collection {
+		if err := deleteCollection(ctx, c.client, c.baseURL, c.apiKey, previous); err != nil {
+			return fmt.Errorf("drop previous collection: %w", err)
+		}
+	}
+	return nil
+}
+
+// Drop deletes the named collection. It is used to discard a partially built collection that will not be promoted. A
+// collection the messages alias points at is left in place, so that discarding a stale checkpoint can never take search
+// offline.
+func (c *Collections) Drop(ctx context.Context, name string) error {
+	live, err := getAlias(ctx, c.client, c.baseURL, c.apiKey, messagesCollection)
+	if err != nil && !errors.Is(err, errAliasNotFound) {
+		return fmt.Errorf("fetch messages alias: %w", err)
+	}
+	if live == name {
+		return nil
+	}
+	return deleteCollection(ctx, c.client, c.baseURL, c.apiKey, name)
+}
@@ -0,0 +1,200 @@
+package typesense
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCollectionsImport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sends documents as JSON lines", func(t *testing.T) {
+		t.Parallel()
+		var got []MessageDocument
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/collections/messages_1/documents/import" || r.URL.Query().Get("action") != "upsert" {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			}
+			sc := bufio.NewScanner(r.Body)
+			for sc.Scan() {
+				var doc MessageDocument
+				if err := json.Unmarshal(sc.Bytes(), &doc); err != nil {
+					t.Errorf("decode line: %v", err)
+				}
+				got = append(got, doc)
+				_, _ = w.Write([]byte("{\"success\":true}\n"))
+			}
+		}))
+		defer srv.Close()
+
+		c := NewCollections(srv.URL, "key", 5*time.Second)
+		docs := []MessageDocument{{ID: "a", Content: "one"}, {ID: "b", Content: "two"}}
+		if err := c.Import(context.Background(), "messages_1", docs); err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		if len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+			t.Errorf("imported %+v, want documents a and b", got)
+		}
+	})
+
+	t.Run("reports rejected documents", func(t *testing.T) {
+		t.Parallel()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("{\"success\":true}\n{\"success\":false,\"error\":\"bad field\"}\n"))
+		}))
+		defer srv.Close()
+
+		c := NewCollections(srv.URL, "key", 5*time.Second)
+		err := c.Import(context.Background(), "messages_1", []MessageDocument{{ID: "a"}, {ID: "b"}})
+		if err == nil || !strings.Contains(err.Error(), "bad field") {
+			t.Fatalf("Import() error = %v, want the rejection reason", err)
+		}
+	})
+
+	t.Run("skips empty batches", func(t *testing.T) {
+		t.Parallel()
+		c := NewCollections("http://127.0.0.1:0", "key", time.Second)
+		if err := c.Import(context.Background(), "messages_1", nil); err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+	})
+}
+
+func TestCollectionsDelete(t *testing.T) {
+	t.Parallel()
+	var filter string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/collections/messages_1/documents" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		filter = r.URL.Query().Get("filter_by")
+		_, _ = w.Write([]byte(`{"num_deleted":2}`))
+	}))
+	defer srv.Close()
+
+	c := NewCollections(srv.URL, "key", 5*time.Second)
+	if err := c.Delete(context.Background(), "messages_1", []string{"a", "b"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if filter != "id:[a,b]" {
+		t.Errorf("filter_by = %q, want %q", filter, "id:[a,b]")
+	}
+}
+
+func TestCollectionsPromote(t *testing.T) {
+	t.Parallel()
+
+	t.Run("swaps alias and drops previous collection", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var calls []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			calls = append(calls, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/aliases/messages":
+				_, _ = w.Write([]byte(`{"name":"messages","collection_name":"messages_1"}`))
+			case r.Method == http.MethodPut && r.URL.Path == "/aliases/messages":
+				var body aliasBody
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				if body.CollectionName != "messages_2" {
+					t.Errorf("alias target = %q, want messages_2", body.CollectionName)
+				}
+			case r.Method == http.MethodDelete && r.URL.Path == "/collections/messages_1":
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+		}))
+		defer srv.Close()
+
+		c := NewCollections(srv.URL, "key", 5*time.Second)
+		if err := c.Promote(context.Background(), "messages_2"); err != nil {
+			t.Fatalf("Promote() error = %v", err)
+		}
+		want := []string{"GET /aliases/messages", "PUT /aliases/messages", "DELETE /collections/messages_1"}
+		if strings.Join(calls, ", ") != strings.Join(want, ", ") {
+			t.Errorf("calls = %v, want %v", calls, want)
+		}
+	})
+
+	t.Run("drops legacy collection before creating alias", func(t *testing.T) {
+		t.Parallel()
+		var calls []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, r.Method+" "+r.URL.Path)
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/aliases/messages":
+				w.WriteHeader(http.StatusNotFound)
+			case r.Method == http.MethodGet && r.URL.Path == "/collections/messages":
+				_, _ = w.Write([]byte(`{"name":"messages","fields":[]}`))
+			case r.Method == http.MethodDelete && r.URL.Path == "/collections/messages":
+			case r.Method == http.MethodPut && r.URL.Path == "/aliases/messages":
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+		}))
+		defer srv.Close()
+
+		c := NewCollections(srv.URL, "key", 5*time.Second)
+		if err := c.Promote(context.Background(), "messages_2"); err != nil {
+			t.Fatalf("Promote() error = %v", err)
+		}
+		want := []string{
+			"GET /aliases/messages", "GET /collections/messages", "DELETE /collections/messages", "PUT /aliases/messages",
+		}
+		if strings.Join(calls, ", ") != strings.Join(want, ", ") {
+			t.Errorf("calls = %v, want %v", calls, want)
+		}
+	})
+}
+
+func TestCollectionsDrop(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		collection string
+		wantDelete bool
+	}{
+		{"drops a collection the alias does not serve", "messages_2", true},
+		{"keeps the live alias target", "messages_1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var mu sync.Mutex
+			var deleted bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/aliases/messages":
+					_, _ = w.Write([]byte(`{"name":"messages","collection_name":"messages_1"}`))
+				case r.Method == http.MethodDelete && r.URL.Path == "/collections/"+tt.collection:
+					mu.Lock()
+					deleted = true
+					mu.Unlock()
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+			}))
+			defer srv.Close()
+
+			c := NewCollections(srv.URL, "key", 5*time.Second)
+			if err := c.Drop(context.Background(), tt.collection); err != nil {
+				t.Fatalf("Drop() error = %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if deleted != tt.wantDelete {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDelete)
+			}
+		})
+	}
+}
@@ -1,3 +1,8 @@
 // Package typesense provides an HTTP client for indexing messages in Typesense. The Indexer retries transient failures
-// (5xx responses) with a fixed delay. Indexing operations are best-effort and designed to run in background workers.
+// (5xx responses) with a fixed delay, and its Ping reports Typesense's own health for readiness checks. Indexing
+// operations are driven by the search outbox worker, which retries longer outages with backoff.
+//
+// Searches and incremental indexing address the "messages" alias rather than a physical collection. Collections manages
+// the versioned collections behind the alias so that a full reindex can build a replacement in the background and swap
+// it in atomically.
 package typesense
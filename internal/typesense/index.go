@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
 )
 
 // Indexer performs document-level CRUD operations against a Typesense messages collection.
@@ -30,13 +32,13 @@ func NewIndexer(baseURL, apiKey string, timeout time.Duration) *Indexer {
 // retrying. All 5xx responses indicate a server-side problem that may resolve on a subsequent attempt.
 const retryableStatusMin = 500
 
-// retryDelay is the fixed pause between the initial attempt and the single retry. Kept short because index operations
-// are best-effort and run in background goroutines.
+// retryDelay is the fixed pause between the initial attempt and the single retry. Kept short because callers such as the
+// search outbox worker apply their own backoff to operations that still fail.
 const retryDelay = 500 * time.Millisecond
 
-// do executes an HTTP request with a single retry on transient failures (network errors or 5xx responses). Index
-// operations are best-effort, so one retry is enough to ride out brief Typesense restarts without adding latency to the
-// happy path.
+// do executes an HTTP request with a single retry on transient failures (network errors or 5xx responses). Longer
+// outages are retried by the caller, so one retry is enough to ride out brief Typesense restarts without adding latency
+// to the happy path.
 func (idx *Indexer) do(req *http.Request) (*http.Response, error) {
 	resp, firstErr := idx.client.Do(req)
 	if firstErr == nil && resp.StatusCode < retryableStatusMin {
@@ -73,39 +75,56 @@ func (idx *Indexer) do(req *http.Request) (*http.Response, error) {
 	return idx.client.Do(req)
 }
 
-// messageDoc is the JSON structure indexed in Typesense.
-type messageDoc struct {
-	ID        string `json:"id"`
-	Content   string `json:"content"`
-	AuthorID  string `json:"author_id"`
-	ChannelID string `json:"channel_id"`
-	CreatedAt int64  `json:"created_at"`
-}
-
-// messageDocUpdate is the subset of messageDoc fields sent during a content update. Kept as a named type so it stays
-// visually coupled to messageDoc and is easy to extend if new updatable fields are added.
-type messageDocUpdate struct {
-	ID      string `json:"id"`
-	Content string `json:"content"`
+// MessageDocument is the JSON structure indexed in Typesense. Has holds the message's search tags (message.TagLink and
+// friends) and Mentions the IDs of the users it mentions.
+type MessageDocument struct {
+	ID        string   `json:"id"`
+	Content   string   `json:"content"`
+	AuthorID  string   `json:"author_id"`
+	ChannelID string   `json:"channel_id"`
+	CreatedAt int64    `json:"created_at"`
+	Has       []string `json:"has"`
+	Mentions  []string `json:"mentions"`
+	Pinned    bool     `json:"pinned"`
+	InThread  bool     `json:"in_thread"`
+
+	// FilenameWords holds the words of the linked attachments' file names, so that filename: searches can match word
+	// prefixes the same way the PostgreSQL backend does.
+	FilenameWords []string `json:"filename_words"`
 }
 
-// IndexMessage adds a message document to the Typesense messages collection.
-func (idx *Indexer) IndexMessage(ctx context.Context, id, content, authorID, channelID string, createdAt int64) error {
-	doc := messageDoc{
-		ID:        id,
-		Content:   content,
-		AuthorID:  authorID,
-		ChannelID: channelID,
-		CreatedAt: createdAt,
+// NewMessageDocument builds the index document for a message.
+func NewMessageDocument(e *message.IndexEntry) MessageDocument {
+	mentions := message.Mentions(e.Content)
+	mentionIDs := make([]string, len(mentions))
+	for i, id := range mentions {
+		mentionIDs[i] = id.String()
+	}
+	return MessageDocument{
+		ID:        e.ID.String(),
+		Content:   e.Content,
+		AuthorID:  e.AuthorID.String(),
+		ChannelID: e.ChannelID.String(),
+		CreatedAt: e.CreatedAt.Unix(),
+		Has:       e.Tags(),
+		Mentions:  mentionIDs,
+		Pinned:    e.Pinned,
+		InThread:  e.InThread,
+
+		FilenameWords: message.FilenameWords(e.Filenames...),
 	}
+}
 
+// IndexMessage creates or replaces a message document in the Typesense messages collection. It is an upsert, so applying
+// the same message state more than once is harmless.
+func (idx *Indexer) IndexMessage(ctx context.Context, doc MessageDocument) error {
 	body, err := json.Marshal(doc)
 	if err != nil {
 		return fmt.Errorf("marshal message doc: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		idx.baseURL+"/collections/"+messagesCollection+"/documents", bytes.NewReader(body))
+		idx.baseURL+"/collections/"+messagesCollection+"/documents?action=upsert", bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("build index request: %w", err)
 	}
@@ -126,56 +145,48 @@ func (idx *Indexer) IndexMessage(ctx context.Context, id, content, authorID, cha
 	return nil
 }
 
-// UpdateMessage upserts a message document in the Typesense messages collection, updating its content.
-func (idx *Indexer) UpdateMessage(ctx context.Context, id, content string) error {
-	doc := messageDocUpdate{ID: id, Content: content}
-
-	body, err := json.Marshal(doc)
-	if err != nil {
-		return fmt.Errorf("marshal update doc: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		idx.baseURL+"/collections/"+messagesCollection+"/documents?action=upsert", bytes.NewReader(body))
+// DeleteMessage removes a message document from the Typesense messages collection.
+func (idx *Indexer) DeleteMessage(ctx context.Context, id string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete,
+		idx.baseURL+"/collections/"+messagesCollection+"/documents/"+id, nil)
 	if err != nil {
-		return fmt.Errorf("build upsert request: %w", err)
+		return fmt.Errorf("build delete request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-TYPESENSE-API-KEY", idx.apiKey)
 
 	resp, err := idx.do(req)
 	if err != nil {
-		return fmt.Errorf("upsert request failed: %w", err)
+		return fmt.Errorf("delete request failed: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode >= 400 {
+	// 404 is acceptable when the document was never indexed or was already removed.
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
 		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
-		return fmt.Errorf("typesense returned status %d on upsert: %s", resp.StatusCode, detail)
+		return fmt.Errorf("typesense returned status %d on delete: %s", resp.StatusCode, detail)
 	}
 
 	return nil
 }
 
-// DeleteMessage removes a message document from the Typesense messages collection.
-func (idx *Indexer) DeleteMessage(ctx context.Context, id string) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete,
-		idx.baseURL+"/collections/"+messagesCollection+"/documents/"+id, nil)
+// Ping checks that Typesense is reachable and reports itself healthy. Unlike document operations it is not retried, so
+// a health check sees a failure as soon as it happens.
+func (idx *Indexer) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, idx.baseURL+"/health", nil)
 	if err != nil {
-		return fmt.Errorf("build delete request: %w", err)
+		return fmt.Errorf("build health request: %w", err)
 	}
 	req.Header.Set("X-TYPESENSE-API-KEY", idx.apiKey)
 
-	resp, err := idx.do(req)
+	resp, err := idx.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("delete request failed: %w", err)
+		return fmt.Errorf("health request failed: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	// 404 is acceptable when the document was never indexed or was already removed.
-	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+	if resp.StatusCode != http.StatusOK {
 		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
-		return fmt.Errorf("typesense returned status %d on delete: %s", resp.StatusCode, detail)
+		return fmt.Errorf("typesense returned status %d on health: %s", resp.StatusCode, detail)
 	}
 
 	return nil
@@ -5,15 +5,20 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/uncord-chat/uncord-server/internal/message"
 )
 
 func TestIndexMessage_Success(t *testing.T) {
 	t.Parallel()
 
-	var received messageDoc
+	var received MessageDocument
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			t.Errorf("method = %s, want POST", r.Method)
@@ -21,6 +26,9 @@ func TestIndexMessage_Success(t *testing.T) {
 		if r.URL.Path != "/collections/messages/documents" {
 			t.Errorf("path = %s, want /collections/messages/documents", r.URL.Path)
 		}
+		if r.URL.Query().Get("action") != "upsert" {
+			t.Errorf("action = %q, want %q", r.URL.Query().Get("action"), "upsert")
+		}
 		if r.Header.Get("X-TYPESENSE-API-KEY") != "test-key" {
 			t.Errorf("api key = %q, want %q", r.Header.Get("X-TYPESENSE-API-KEY"), "test-key")
 		}
@@ -32,7 +40,15 @@ func TestIndexMessage_Success(t *testing.T) {
 	defer srv.Close()
 
 	idx := NewIndexer(srv.URL, "test-key", 5*time.Second)
-	err := idx.IndexMessage(context.Background(), "msg-1", "hello world", "author-1", "chan-1", 1700000000)
+	err := idx.IndexMessage(context.Background(), MessageDocument{
+		ID:        "msg-1",
+		Content:   "hello world",
+		AuthorID:  "author-1",
+		ChannelID: "chan-1",
+		CreatedAt: 1700000000,
+		Has:       []string{"link"},
+		Pinned:    true,
+	})
 	if err != nil {
 		t.Fatalf("IndexMessage() error = %v", err)
 	}
@@ -52,41 +68,70 @@ func TestIndexMessage_Success(t *testing.T) {
 	if received.CreatedAt != 1700000000 {
 		t.Errorf("created_at = %d, want %d", received.CreatedAt, 1700000000)
 	}
+	if len(received.Has) != 1 || received.Has[0] != "link" {
+		t.Errorf("has = %v, want [link]", received.Has)
+	}
+	if !received.Pinned {
+		t.Error("pinned = false, want true")
+	}
 }
 
-func TestIndexMessage_ServerError(t *testing.T) {
+func TestNewMessageDocument(t *testing.T) {
 	t.Parallel()
 
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte("internal error"))
-	}))
-	defer srv.Close()
+	mentioned := uuid.New()
+	e := &message.IndexEntry{
+		ID:           uuid.New(),
+		ChannelID:    uuid.New(),
+		AuthorID:     uuid.New(),
+		Content:      "look <@" + mentioned.String() + "> https://example.com",
+		CreatedAt:    time.Unix(1700000000, 0),
+		Pinned:       true,
+		InThread:     true,
+		Filenames:    []string{"Cat photo.png"},
+		ContentTypes: []string{"image/png"},
+	}
 
-	idx := NewIndexer(srv.URL, "test-key", 5*time.Second)
-	err := idx.IndexMessage(context.Background(), "msg-1", "hello", "a", "c", 0)
-	if err == nil {
-		t.Fatal("IndexMessage() expected error for 500 response")
+	doc := NewMessageDocument(e)
+	if doc.ID != e.ID.String() || doc.ChannelID != e.ChannelID.String() || doc.AuthorID != e.AuthorID.String() {
+		t.Errorf("document IDs = %+v, want the entry's IDs", doc)
+	}
+	if doc.CreatedAt != 1700000000 {
+		t.Errorf("created_at = %d, want %d", doc.CreatedAt, 1700000000)
+	}
+	if !slices.Equal(doc.Has, []string{"link", "file", "image"}) {
+		t.Errorf("has = %v, want [link file image]", doc.Has)
+	}
+	if !slices.Equal(doc.Mentions, []string{mentioned.String()}) {
+		t.Errorf("mentions = %v, want [%s]", doc.Mentions, mentioned)
+	}
+	if !doc.Pinned || !doc.InThread {
+		t.Errorf("pinned = %v, in_thread = %v, want both true", doc.Pinned, doc.InThread)
+	}
+	if !slices.Equal(doc.FilenameWords, []string{"cat", "photo", "png"}) {
+		t.Errorf("filename_words = %v, want [cat photo png]", doc.FilenameWords)
+	}
+
+	// Array fields are always present so that Typesense accepts the document.
+	empty := NewMessageDocument(&message.IndexEntry{})
+	if empty.Has == nil || empty.Mentions == nil || empty.FilenameWords == nil {
+		t.Errorf("empty document = %+v, want non-nil arrays", empty)
 	}
 }
 
-func TestUpdateMessage_Success(t *testing.T) {
+func TestIndexMessage_ServerError(t *testing.T) {
 	t.Parallel()
 
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			t.Errorf("method = %s, want POST", r.Method)
-		}
-		if r.URL.Query().Get("action") != "upsert" {
-			t.Errorf("action = %q, want %q", r.URL.Query().Get("action"), "upsert")
-		}
-		w.WriteHeader(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("internal error"))
 	}))
 	defer srv.Close()
 
 	idx := NewIndexer(srv.URL, "test-key", 5*time.Second)
-	if err := idx.UpdateMessage(context.Background(), "msg-1", "updated content"); err != nil {
-		t.Fatalf("UpdateMessage() error = %v", err)
+	err := idx.IndexMessage(context.Background(), MessageDocument{ID: "msg-1", Content: "hello"})
+	if err == nil {
+		t.Fatal("IndexMessage() expected error for 500 response")
 	}
 }
 
@@ -125,7 +170,7 @@ func TestIndexMessage_RetriesOnTransient500(t *testing.T) {
 	defer srv.Close()
 
 	idx := NewIndexer(srv.URL, "test-key", 5*time.Second)
-	if err := idx.IndexMessage(context.Background(), "msg-1", "hello", "a", "c", 0); err != nil {
+	if err := idx.IndexMessage(context.Background(), MessageDocument{ID: "msg-1", Content: "hello"}); err != nil {
 		t.Fatalf("IndexMessage() error = %v, want success after retry", err)
 	}
 	if got := attempts.Load(); got != 2 {
@@ -145,7 +190,7 @@ func TestIndexMessage_Persistent500ReturnsError(t *testing.T) {
 	defer srv.Close()
 
 	idx := NewIndexer(srv.URL, "test-key", 5*time.Second)
-	if err := idx.IndexMessage(context.Background(), "msg-1", "hello", "a", "c", 0); err == nil {
+	if err := idx.IndexMessage(context.Background(), MessageDocument{ID: "msg-1", Content: "hello"}); err == nil {
 		t.Fatal("IndexMessage() expected error for persistent 500")
 	}
 	if got := attempts.Load(); got != 2 {
@@ -166,3 +211,40 @@ func TestDeleteMessage_NotFoundIsAccepted(t *testing.T) {
 		t.Fatalf("DeleteMessage() should accept 404, got error = %v", err)
 	}
 }
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"healthy", http.StatusOK, false},
+		{"unhealthy", http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if r.URL.Path != "/health" {
+					t.Errorf("path = %s, want /health", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			idx := NewIndexer(srv.URL, "test-key", 5*time.Second)
+			err := idx.Ping(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != 1 {
+				t.Errorf("server called %d times, want 1", calls)
+			}
+		})
+	}
+}
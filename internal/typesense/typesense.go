@@ -16,6 +16,9 @@ import (
 // collection does not exist yet.
 var errCollectionNotFound = errors.New("collection not found")
 
+// errAliasNotFound is returned by getAlias when the alias does not exist.
+var errAliasNotFound = errors.New("alias not found")
+
 // field defines a single field in a Typesense collection schema. Only the properties that matter for structural
 // comparison are included; Typesense returns additional metadata that we intentionally ignore.
 type field struct {
@@ -26,15 +29,23 @@ type field struct {
 }
 
 // messagesFields is the canonical field list for the messages collection. Update this slice when the schema changes and
-// the collection will be recreated on the next startup.
+// a new collection will be built and swapped in by a background reindex on the next startup.
 var messagesFields = []field{
 	{Name: "content", Type: "string"},
 	{Name: "author_id", Type: "string", Facet: true},
 	{Name: "channel_id", Type: "string", Facet: true},
 	{Name: "created_at", Type: "int64", Sort: true},
+	{Name: "has", Type: "string[]", Facet: true},
+	{Name: "mentions", Type: "string[]", Facet: true},
+	{Name: "pinned", Type: "bool"},
+	{Name: "in_thread", Type: "bool"},
+	{Name: "filename_words", Type: "string[]"},
 }
 
 const (
+	// messagesCollection is the alias that all document and search requests address. It points at a versioned
+	// physical collection (messagesCollection + "_" + timestamp) so that a replacement can be built in the background
+	// and swapped in atomically.
 	messagesCollection   = "messages"
 	messagesSortingField = "created_at"
 
@@ -61,48 +72,56 @@ type Result int
 
 // Result values returned by EnsureMessagesCollection.
 const (
-	ResultCreated   Result = iota // Collection was created fresh.
+	ResultCreated   Result = iota // Collection was created fresh and is empty.
 	ResultUnchanged               // Collection already existed with the correct schema.
-	ResultRecreated               // Collection was dropped and recreated due to a schema change.
+	ResultOutdated                // Collection exists but predates the current schema and must be reindexed.
 )
 
-// EnsureMessagesCollection ensures the messages collection exists in Typesense with the correct schema. If the
-// collection exists but the schema differs, it is dropped and recreated. The returned Result indicates what action was
-// taken.
+// EnsureMessagesCollection ensures a messages collection exists in Typesense. When none exists, a versioned collection
+// is created and the messages alias is pointed at it. An existing collection whose schema differs is left in place and
+// keeps serving searches; ResultOutdated tells the caller to build a replacement with a reindex, which swaps the alias
+// once the new collection is fully populated. Existing documents are never deleted here.
 func EnsureMessagesCollection(ctx context.Context, baseURL, apiKey string, timeout time.Duration) (Result, error) {
 	client := &http.Client{Timeout: timeout}
 
-	// Check whether the collection already exists.
-	existing, err := getCollection(ctx, client, baseURL, apiKey)
+	// Typesense resolves aliases when fetching a collection, so this finds either the aliased collection or a legacy
+	// collection created before aliases were used.
+	existing, err := getCollection(ctx, client, baseURL, apiKey, messagesCollection)
 	if err != nil && !errors.Is(err, errCollectionNotFound) {
 		return 0, fmt.Errorf("fetch existing collection: %w", err)
 	}
 
 	if err == nil {
-		if schemasMatch(existing) {
-			return ResultUnchanged, nil
-		}
-
-		// Schema differs; drop the old collection so we can recreate it.
-		if err := deleteCollection(ctx, client, baseURL, apiKey); err != nil {
-			return 0, fmt.Errorf("delete outdated collection: %w", err)
+		if !schemasMatch(existing) {
+			return ResultOutdated, nil
 		}
-
-		if err := createCollection(ctx, client, baseURL, apiKey); err != nil {
-			return 0, fmt.Errorf("recreate collection: %w", err)
+		// A legacy collection has the right fields but must still be rebuilt behind an alias so that future schema
+		// changes can be applied without downtime.
+		if _, err := getAlias(ctx, client, baseURL, apiKey, messagesCollection); errors.Is(err, errAliasNotFound) {
+			return ResultOutdated, nil
+		} else if err != nil {
+			return 0, fmt.Errorf("fetch messages alias: %w", err)
 		}
-
-		return ResultRecreated, nil
+		return ResultUnchanged, nil
 	}
 
-	// Collection does not exist yet; create it.
-	if err := createCollection(ctx, client, baseURL, apiKey); err != nil {
+	// Collection does not exist yet; create it behind the alias.
+	name := versionedCollectionName(time.Now())
+	if err := createCollection(ctx, client, baseURL, apiKey, name); err != nil {
 		return 0, fmt.Errorf("create collection: %w", err)
 	}
+	if err := upsertAlias(ctx, client, baseURL, apiKey, messagesCollection, name); err != nil {
+		return 0, fmt.Errorf("create messages alias: %w", err)
+	}
 
 	return ResultCreated, nil
 }
 
+// versionedCollectionName returns the physical collection name for a messages collection created at t.
+func versionedCollectionName(t time.Time) string {
+	return fmt.Sprintf("%s_%d", messagesCollection, t.UnixNano())
+}
+
 // schemasMatch returns true when the remote collection's fields and sorting field match the desired schema.
 func schemasMatch(remote *remoteCollection) bool {
 	if remote.DefaultSortingField != messagesSortingField {
@@ -124,10 +143,10 @@ func schemasMatch(remote *remoteCollection) bool {
 	return true
 }
 
-// getCollection fetches the messages collection from Typesense. Returns errCollectionNotFound if the collection does
-// not exist (404).
-func getCollection(ctx context.Context, client *http.Client, baseURL, apiKey string) (*remoteCollection, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/collections/"+messagesCollection, nil)
+// getCollection fetches the named collection (or the collection an alias of that name points at) from Typesense.
+// Returns errCollectionNotFound if the collection does not exist (404).
+func getCollection(ctx context.Context, client *http.Client, baseURL, apiKey, name string) (*remoteCollection, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/collections/"+name, nil)
 	if err != nil {
 		return nil, fmt.Errorf("build get request: %w", err)
 	}
@@ -161,9 +180,9 @@ func getCollection(ctx context.Context, client *http.Client, baseURL, apiKey str
 	return &col, nil
 }
 
-// deleteCollection drops the messages collection.
-func deleteCollection(ctx context.Context, client *http.Client, baseURL, apiKey string) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, baseURL+"/collections/"+messagesCollection, nil)
+// deleteCollection drops the named collection.
+func deleteCollection(ctx context.Context, client *http.Client, baseURL, apiKey, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, baseURL+"/collections/"+name, nil)
 	if err != nil {
 		return fmt.Errorf("build delete request: %w", err)
 	}
@@ -183,10 +202,10 @@ func deleteCollection(ctx context.Context, client *http.Client, baseURL, apiKey
 	return nil
 }
 
-// createCollection creates the messages collection with the canonical schema.
-func createCollection(ctx context.Context, client *http.Client, baseURL, apiKey string) error {
+// createCollection creates a messages collection with the canonical schema under the given physical name.
+func createCollection(ctx context.Context, client *http.Client, baseURL, apiKey, name string) error {
 	schema := collectionSchema{
-		Name:                messagesCollection,
+		Name:                name,
 		Fields:              messagesFields,
 		DefaultSortingField: messagesSortingField,
 	}
@@ -216,3 +235,65 @@ func createCollection(ctx context.Context, client *http.Client, baseURL, apiKey
 
 	return nil
 }
+
+// aliasBody is the JSON structure of a Typesense alias.
+type aliasBody struct {
+	CollectionName string `json:"collection_name"`
+}
+
+// getAlias returns the collection the named alias points at. Returns errAliasNotFound if the alias does not exist.
+func getAlias(ctx context.Context, client *http.Client, baseURL, apiKey, alias string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/aliases/"+alias, nil)
+	if err != nil {
+		return "", fmt.Errorf("build alias request: %w", err)
+	}
+	req.Header.Set("X-TYPESENSE-API-KEY", apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("alias request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", errAliasNotFound
+	}
+	if resp.StatusCode >= 400 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		return "", fmt.Errorf("typesense returned status %d on alias: %s", resp.StatusCode, detail)
+	}
+
+	var body aliasBody
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBody)).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode alias: %w", err)
+	}
+	return body.CollectionName, nil
+}
+
+// upsertAlias points the named alias at collection, creating the alias if needed. Typesense applies the change
+// atomically, so searches switch from the old collection to the new one without a gap.
+func upsertAlias(ctx context.Context, client *http.Client, baseURL, apiKey, alias, collection string) error {
+	body, err := json.Marshal(aliasBody{CollectionName: collection})
+	if err != nil {
+		return fmt.Errorf("marshal alias: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, baseURL+"/aliases/"+alias, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build alias upsert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-TYPESENSE-API-KEY", apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("alias upsert request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		return fmt.Errorf("typesense returned status %d on alias upsert: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -14,6 +15,24 @@ import (
 
 // --- schemasMatch tests ---
 
+// reversedFields returns messagesFields in reverse order.
+func reversedFields() []field {
+	fields := slices.Clone(messagesFields)
+	slices.Reverse(fields)
+	return fields
+}
+
+// replaceField returns messagesFields with the field of the same name replaced by f.
+func replaceField(f field) []field {
+	fields := slices.Clone(messagesFields)
+	for i := range fields {
+		if fields[i].Name == f.Name {
+			fields[i] = f
+		}
+	}
+	return fields
+}
+
 func TestSchemasMatch(t *testing.T) {
 	t.Parallel()
 
@@ -33,12 +52,7 @@ func TestSchemasMatch(t *testing.T) {
 		{
 			name: "fields in different order",
 			remote: &remoteCollection{
-				Fields: []field{
-					{Name: "created_at", Type: "int64", Sort: true},
-					{Name: "channel_id", Type: "string", Facet: true},
-					{Name: "content", Type: "string"},
-					{Name: "author_id", Type: "string", Facet: true},
-				},
+				Fields:              reversedFields(),
 				DefaultSortingField: messagesSortingField,
 			},
 			want: true,
@@ -62,10 +76,7 @@ func TestSchemasMatch(t *testing.T) {
 		{
 			name: "fewer fields",
 			remote: &remoteCollection{
-				Fields: []field{
-					{Name: "content", Type: "string"},
-					{Name: "author_id", Type: "string", Facet: true},
-				},
+				Fields:              messagesFields[:2],
 				DefaultSortingField: messagesSortingField,
 			},
 			want: false,
@@ -73,13 +84,7 @@ func TestSchemasMatch(t *testing.T) {
 		{
 			name: "extra field",
 			remote: &remoteCollection{
-				Fields: []field{
-					{Name: "content", Type: "string"},
-					{Name: "author_id", Type: "string", Facet: true},
-					{Name: "channel_id", Type: "string", Facet: true},
-					{Name: "created_at", Type: "int64", Sort: true},
-					{Name: "extra", Type: "string"},
-				},
+				Fields:              append(slices.Clone(messagesFields), field{Name: "extra", Type: "string"}),
 				DefaultSortingField: messagesSortingField,
 			},
 			want: false,
@@ -87,12 +92,7 @@ func TestSchemasMatch(t *testing.T) {
 		{
 			name: "field type mismatch",
 			remote: &remoteCollection{
-				Fields: []field{
-					{Name: "content", Type: "int32"},
-					{Name: "author_id", Type: "string", Facet: true},
-					{Name: "channel_id", Type: "string", Facet: true},
-					{Name: "created_at", Type: "int64", Sort: true},
-				},
+				Fields:              replaceField(field{Name: "content", Type: "int32"}),
 				DefaultSortingField: messagesSortingField,
 			},
 			want: false,
@@ -100,12 +100,7 @@ func TestSchemasMatch(t *testing.T) {
 		{
 			name: "field facet mismatch",
 			remote: &remoteCollection{
-				Fields: []field{
-					{Name: "content", Type: "string"},
-					{Name: "author_id", Type: "string"},
-					{Name: "channel_id", Type: "string", Facet: true},
-					{Name: "created_at", Type: "int64", Sort: true},
-				},
+				Fields:              replaceField(field{Name: "author_id", Type: "string"}),
 				DefaultSortingField: messagesSortingField,
 			},
 			want: false,
@@ -113,12 +108,7 @@ func TestSchemasMatch(t *testing.T) {
 		{
 			name: "field sort mismatch",
 			remote: &remoteCollection{
-				Fields: []field{
-					{Name: "content", Type: "string"},
-					{Name: "author_id", Type: "string", Facet: true},
-					{Name: "channel_id", Type: "string", Facet: true},
-					{Name: "created_at", Type: "int64"},
-				},
+				Fields:              replaceField(field{Name: "created_at", Type: "int64"}),
 				DefaultSortingField: messagesSortingField,
 			},
 			want: false,
@@ -159,7 +149,7 @@ func TestGetCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		got, err := getCollection(context.Background(), srv.Client(), srv.URL, "key")
+		got, err := getCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -178,7 +168,7 @@ func TestGetCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		_, err := getCollection(context.Background(), srv.Client(), srv.URL, "key")
+		_, err := getCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection)
 		if !errors.Is(err, errCollectionNotFound) {
 			t.Fatalf("expected errCollectionNotFound, got %v", err)
 		}
@@ -191,7 +181,7 @@ func TestGetCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		_, err := getCollection(context.Background(), srv.Client(), srv.URL, "key")
+		_, err := getCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection)
 		if err == nil {
 			t.Fatal("expected error for 500 response")
 		}
@@ -204,7 +194,7 @@ func TestGetCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		_, err := getCollection(context.Background(), srv.Client(), srv.URL, "key")
+		_, err := getCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection)
 		if err == nil {
 			t.Fatal("expected error for invalid JSON response")
 		}
@@ -220,7 +210,7 @@ func TestGetCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		_, _ = getCollection(context.Background(), srv.Client(), srv.URL, apiKey)
+		_, _ = getCollection(context.Background(), srv.Client(), srv.URL, apiKey, messagesCollection)
 		if gotKey != apiKey {
 			t.Errorf("API key header = %q, want %q", gotKey, apiKey)
 		}
@@ -239,7 +229,7 @@ func TestDeleteCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		if err := deleteCollection(context.Background(), srv.Client(), srv.URL, "key"); err != nil {
+		if err := deleteCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 	})
@@ -251,7 +241,7 @@ func TestDeleteCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		if err := deleteCollection(context.Background(), srv.Client(), srv.URL, "key"); err == nil {
+		if err := deleteCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection); err == nil {
 			t.Fatal("expected error for 500 response")
 		}
 	})
@@ -266,7 +256,7 @@ func TestDeleteCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		_ = deleteCollection(context.Background(), srv.Client(), srv.URL, apiKey)
+		_ = deleteCollection(context.Background(), srv.Client(), srv.URL, apiKey, messagesCollection)
 		if gotKey != apiKey {
 			t.Errorf("API key header = %q, want %q", gotKey, apiKey)
 		}
@@ -285,7 +275,7 @@ func TestCreateCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		if err := createCollection(context.Background(), srv.Client(), srv.URL, "key"); err != nil {
+		if err := createCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 	})
@@ -297,7 +287,7 @@ func TestCreateCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		if err := createCollection(context.Background(), srv.Client(), srv.URL, "key"); err == nil {
+		if err := createCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection); err == nil {
 			t.Fatal("expected error for 500 response")
 		}
 	})
@@ -313,7 +303,7 @@ func TestCreateCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		if err := createCollection(context.Background(), srv.Client(), srv.URL, apiKey); err != nil {
+		if err := createCollection(context.Background(), srv.Client(), srv.URL, apiKey, messagesCollection); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 		if gotKey != apiKey {
@@ -333,7 +323,7 @@ func TestCreateCollection(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		if err := createCollection(context.Background(), srv.Client(), srv.URL, "key"); err != nil {
+		if err := createCollection(context.Background(), srv.Client(), srv.URL, "key", messagesCollection); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
@@ -367,14 +357,22 @@ func TestEnsureMessagesCollection(t *testing.T) {
 		DefaultSortingField: messagesSortingField,
 	})
 
-	t.Run("creates collection when none exists", func(t *testing.T) {
+	t.Run("creates collection behind alias when none exists", func(t *testing.T) {
 		t.Parallel()
+		var created collectionSchema
+		var alias aliasBody
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			switch r.Method {
-			case http.MethodGet:
+			switch {
+			case r.Method == http.MethodGet:
 				w.WriteHeader(http.StatusNotFound)
-			case http.MethodPost:
+			case r.Method == http.MethodPost && r.URL.Path == "/collections":
+				_ = json.NewDecoder(r.Body).Decode(&created)
 				w.WriteHeader(http.StatusCreated)
+			case r.Method == http.MethodPut && r.URL.Path == "/aliases/messages":
+				_ = json.NewDecoder(r.Body).Decode(&alias)
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
 			}
 		}))
 		defer srv.Close()
@@ -386,11 +384,21 @@ func TestEnsureMessagesCollection(t *testing.T) {
 		if result != ResultCreated {
 			t.Errorf("result = %d, want ResultCreated (%d)", result, ResultCreated)
 		}
+		if !strings.HasPrefix(created.Name, messagesCollection+"_") {
+			t.Errorf("created collection = %q, want a versioned name", created.Name)
+		}
+		if alias.CollectionName != created.Name {
+			t.Errorf("alias target = %q, want %q", alias.CollectionName, created.Name)
+		}
 	})
 
 	t.Run("returns unchanged when schema matches", func(t *testing.T) {
 		t.Parallel()
-		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/aliases/messages" {
+				_, _ = w.Write([]byte(`{"name":"messages","collection_name":"messages_1"}`))
+				return
+			}
 			_, _ = w.Write(matchingBody)
 		}))
 		defer srv.Close()
@@ -404,17 +412,13 @@ func TestEnsureMessagesCollection(t *testing.T) {
 		}
 	})
 
-	t.Run("recreates collection when schema differs", func(t *testing.T) {
+	t.Run("reports outdated without deleting when schema differs", func(t *testing.T) {
 		t.Parallel()
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			switch r.Method {
-			case http.MethodGet:
-				_, _ = w.Write(staleBody)
-			case http.MethodDelete:
-				w.WriteHeader(http.StatusOK)
-			case http.MethodPost:
-				w.WriteHeader(http.StatusCreated)
+			if r.Method != http.MethodGet {
+				t.Errorf("unexpected %s %s; an outdated collection must be left in place", r.Method, r.URL.Path)
 			}
+			_, _ = w.Write(staleBody)
 		}))
 		defer srv.Close()
 
@@ -422,45 +426,44 @@ func TestEnsureMessagesCollection(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if result != ResultRecreated {
-			t.Errorf("result = %d, want ResultRecreated (%d)", result, ResultRecreated)
+		if result != ResultOutdated {
+			t.Errorf("result = %d, want ResultOutdated (%d)", result, ResultOutdated)
 		}
 	})
 
-	t.Run("returns error when get fails", func(t *testing.T) {
+	t.Run("reports outdated for legacy collection without alias", func(t *testing.T) {
 		t.Parallel()
-		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			w.WriteHeader(http.StatusInternalServerError)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/aliases/messages" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(matchingBody)
 		}))
 		defer srv.Close()
 
-		_, err := EnsureMessagesCollection(context.Background(), srv.URL, "key", 30*time.Second)
-		if err == nil {
-			t.Fatal("expected error when get fails")
+		result, err := EnsureMessagesCollection(context.Background(), srv.URL, "key", 30*time.Second)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
 		}
-		if !strings.Contains(err.Error(), "fetch existing collection") {
-			t.Errorf("error = %q, want to contain %q", err.Error(), "fetch existing collection")
+		if result != ResultOutdated {
+			t.Errorf("result = %d, want ResultOutdated (%d)", result, ResultOutdated)
 		}
 	})
 
-	t.Run("returns error when delete fails", func(t *testing.T) {
+	t.Run("returns error when get fails", func(t *testing.T) {
 		t.Parallel()
-		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			switch r.Method {
-			case http.MethodGet:
-				_, _ = w.Write(staleBody)
-			case http.MethodDelete:
-				w.WriteHeader(http.StatusInternalServerError)
-			}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
 		}))
 		defer srv.Close()
 
 		_, err := EnsureMessagesCollection(context.Background(), srv.URL, "key", 30*time.Second)
 		if err == nil {
-			t.Fatal("expected error when delete fails")
+			t.Fatal("expected error when get fails")
 		}
-		if !strings.Contains(err.Error(), "delete outdated collection") {
-			t.Errorf("error = %q, want to contain %q", err.Error(), "delete outdated collection")
+		if !strings.Contains(err.Error(), "fetch existing collection") {
+			t.Errorf("error = %q, want to contain %q", err.Error(), "fetch existing collection")
 		}
 	})
 
@@ -485,15 +488,15 @@ func TestEnsureMessagesCollection(t *testing.T) {
 		}
 	})
 
-	t.Run("returns error when recreate fails", func(t *testing.T) {
+	t.Run("returns error when alias creation fails", func(t *testing.T) {
 		t.Parallel()
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
 			case http.MethodGet:
-				_, _ = w.Write(staleBody)
-			case http.MethodDelete:
-				w.WriteHeader(http.StatusOK)
+				w.WriteHeader(http.StatusNotFound)
 			case http.MethodPost:
+				w.WriteHeader(http.StatusCreated)
+			case http.MethodPut:
 				w.WriteHeader(http.StatusInternalServerError)
 			}
 		}))
@@ -501,10 +504,10 @@ func TestEnsureMessagesCollection(t *testing.T) {
 
 		_, err := EnsureMessagesCollection(context.Background(), srv.URL, "key", 30*time.Second)
 		if err == nil {
-			t.Fatal("expected error when recreate fails")
+			t.Fatal("expected error when alias creation fails")
 		}
-		if !strings.Contains(err.Error(), "recreate collection") {
-			t.Errorf("error = %q, want to contain %q", err.Error(), "recreate collection")
+		if !strings.Contains(err.Error(), "create messages alias") {
+			t.Errorf("error = %q, want to contain %q", err.Error(), "create messages alias")
 		}
 	})
 }
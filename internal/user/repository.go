@@ -68,10 +68,10 @@ func (r *PGRepository) Create(ctx context.Context, params CreateParams) (uuid.UU
 	var userID uuid.UUID
 	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
 		err := tx.QueryRow(ctx,
-			`INSERT INTO users (email, username, password_hash)
-			 VALUES ($1, $2, $3)
+			`INSERT INTO users (email, username, password_hash, email_verified)
+			 VALUES ($1, $2, $3, $4)
 			 RETURNING id`,
-			params.Email, params.Username, params.PasswordHash,
+			params.Email, params.Username, params.PasswordHash, params.EmailVerified,
 		).Scan(&userID)
 		if err != nil {
 			if postgres.IsUniqueViolation(err) {
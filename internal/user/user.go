@@ -98,6 +98,9 @@ type CreateParams struct {
 	PasswordHash string
 	VerifyToken  string
 	VerifyExpiry time.Time
+
+	// EmailVerified creates the user with the email address already verified, for accounts created by an operator.
+	EmailVerified bool
 }
 
 // UpdateParams groups the optional fields for updating a user profile. Image keys (avatar, banner) are managed through
@@ -1,3 +1,4 @@
 // Package valkey establishes connections to a Valkey or Redis instance. Connect parses the provided address, converting
-// the valkey:// URI scheme to redis:// for go-redis compatibility, and verifies the connection with a ping.
+// the valkey:// URI scheme to redis:// for go-redis compatibility, verifies the connection with a ping, and installs a
+// hook that records commands sent within a traced context as OpenTelemetry client spans.
 package valkey
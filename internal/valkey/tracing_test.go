@@ -0,0 +1,65 @@
+package valkey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestConnect_TracesCommandsWithinATrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	mr := miniredis.RunT(t)
+	client, err := Connect(context.Background(), "valkey://"+mr.Addr(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	// Commands outside a trace, including the ping made by Connect, are not recorded.
+	if err := client.Set(context.Background(), "k", "v", 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("recorded %d spans outside a trace, want 0", n)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	if err := client.Get(ctx, "missing").Err(); err == nil {
+		t.Fatal("Get() of a missing key returned no error")
+	}
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "k")
+	pipe.Incr(ctx, "n")
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	if spans[0].Name() != "get" {
+		t.Errorf("command span name = %q, want %q", spans[0].Name(), "get")
+	}
+	if spans[0].Status().Description != "" {
+		t.Errorf("missing key marked as an error: %q", spans[0].Status().Description)
+	}
+	if spans[1].Name() != "pipeline" {
+		t.Errorf("pipeline span name = %q, want %q", spans[1].Name(), "pipeline")
+	}
+	for _, s := range spans[:2] {
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %q is not parented to the request span", s.Name())
+		}
+	}
+}
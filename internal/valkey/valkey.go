@@ -12,7 +12,7 @@ import (
 
 // Connect parses the Valkey URL, connects, and pings to verify the connection. The valkey:// scheme is replaced with
 // redis:// for go-redis compatibility. The dialTimeout parameter controls how long the client waits when establishing
-// new connections.
+// new connections. Commands sent with a traced context are recorded as OpenTelemetry spans.
 func Connect(ctx context.Context, rawURL string, dialTimeout time.Duration) (*redis.Client, error) {
 	// go-redis only understands the redis:// scheme, so replace valkey:// (case-insensitive) before parsing.
 	parsed, err := url.Parse(rawURL)
@@ -30,6 +30,7 @@ func Connect(ctx context.Context, rawURL string, dialTimeout time.Duration) (*re
 	opts.DialTimeout = dialTimeout
 
 	client := redis.NewClient(opts)
+	client.AddHook(tracingHook{})
 
 	if err := client.Ping(ctx).Err(); err != nil {
 		_ = client.Close()